@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"runtime"
@@ -12,6 +13,8 @@ import (
 
 	"github.com/cuong/go-etl/pkg/bucket"
 	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/progress"
+	"github.com/cuong/go-etl/pkg/reconcile"
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -72,7 +75,11 @@ func main() {
 	defer pprof.StopCPUProfile()
 
 	// Create ETL processor
-	userETL := NewUserETL(mongoClient, postgresDB)
+	userETL, err := NewUserETL(mongoClient, postgresDB)
+	if err != nil {
+		fmt.Printf("Failed to create ETL processor: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Configure bucket (matching Rust)
 	numCPUs := runtime.NumCPU()
@@ -87,9 +94,18 @@ func main() {
 		WorkerNum: 1, // Same as Rust
 	}
 
+	// On a terminal, show live progress instead of per-batch logs
+	interactive := progress.IsTerminal(os.Stderr)
+	if interactive {
+		managerConfig.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	}
+
 	// Create manager
 	manager := etl.NewManager(managerConfig, bucketConfig)
-	etl.AddPipelineGeneric(manager, userETL, "user_migration_pipeline")
+	if err := etl.AddPipelineGeneric(manager, userETL, "user_migration_pipeline"); err != nil {
+		fmt.Printf("Failed to add pipeline: %v\n", err)
+		os.Exit(1)
+	}
 
 	fmt.Printf("✓ Adding User ETL Pipeline (MongoDB -> PostgreSQL)\n")
 	fmt.Printf("  - Batch Size: %d\n", bucketConfig.BatchSize)
@@ -98,10 +114,21 @@ func main() {
 
 	fmt.Println("--- Starting ETL pipeline ---\n")
 
+	stopProgress := func() {}
+	if interactive {
+		display := progress.New(manager, progress.Config{})
+		users, err := mongoClient.Database("sample_db").Collection("users").EstimatedDocumentCount(ctx)
+		if err == nil {
+			display.SetTotal("user_migration_pipeline", users)
+		}
+		stopProgress = display.Start()
+	}
+
 	// Run benchmark
 	start := time.Now()
 	err = manager.RunAll(ctx)
 	duration := time.Since(start)
+	stopProgress()
 
 	// Stop CPU profiling
 	pprof.StopCPUProfile()
@@ -147,6 +174,17 @@ func main() {
 	fmt.Printf("- Throughput: %.0f users/second\n", usersPerSec)
 	fmt.Printf("- Record Rate: %.0f records/second\n", recordsPerSec)
 	fmt.Printf("- CPU Cores Used: %d\n", numCPUs)
+	stages := manager.Metrics().Stages
+	fmt.Printf("- Stage Breakdown: extract wait %s, queue wait %s, transform %s, load %s (%s-bound)\n",
+		stages.ExtractWait.Round(time.Millisecond), stages.QueueWait.Round(time.Millisecond),
+		stages.Transform.Round(time.Millisecond), stages.Load.Round(time.Millisecond), stages.Bottleneck())
+
+	// Reconcile after the timed run, so the check isn't part of the
+	// comparison with Rust
+	if err := reconcileUsers(ctx, mongoClient, postgresDB); err != nil {
+		fmt.Printf("\n=== Error reconciling users: %v ===\n", err)
+		os.Exit(1)
+	}
 	fmt.Println("\n✓ CPU profile saved to: cpu.prof")
 	fmt.Println("✓ Memory profile saved to: mem.prof")
 
@@ -154,6 +192,37 @@ func main() {
 	generateComparisonReport(userCount, totalRecords, duration)
 }
 
+// reconcileUsers checks that every user reached PostgreSQL, printing the
+// counts and how long the check took
+func reconcileUsers(ctx context.Context, mongoClient *mongo.Client, postgresDB *gorm.DB) error {
+	sqlDB, err := postgresDB.DB()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	result, err := reconcile.Run(ctx, reconcile.Config{
+		Name:        "user_migration_pipeline",
+		Source:      reconcile.Mongo(mongoClient.Database("sample_db")),
+		Destination: reconcile.SQL(sqlDB),
+		Entities: []reconcile.Entity{
+			{Source: "users", Keys: []string{"_id"}, DestinationKeys: []string{"id"}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("- Reconciliation (%.2fs, not timed above):\n", time.Since(start).Seconds())
+	for _, r := range result.Entities {
+		fmt.Printf("  - %s\n", r)
+	}
+	if !result.Matched {
+		return fmt.Errorf("source and destination differ")
+	}
+	return nil
+}
+
 func connectMongoDB(ctx context.Context, uri string) (*mongo.Client, error) {
 	clientOptions := options.Client().ApplyURI(uri)
 	client, err := mongo.Connect(ctx, clientOptions)
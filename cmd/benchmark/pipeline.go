@@ -6,7 +6,8 @@ import (
 	"fmt"
 
 	"github.com/cuong/go-etl/pkg/etl"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/cuong/go-etl/pkg/sinks/graphsink"
+	"github.com/cuong/go-etl/pkg/sources/mongosource"
 	"go.mongodb.org/mongo-driver/mongo"
 	"gorm.io/gorm"
 )
@@ -34,58 +35,38 @@ type TransformedUser struct {
 type UserETL struct {
 	mongoClient *mongo.Client
 	postgresDB  *gorm.DB
+	sink        *graphsink.Sink[TransformedUser]
 }
 
 // NewUserETL creates a new User ETL processor
-func NewUserETL(mongoClient *mongo.Client, postgresDB *gorm.DB) *UserETL {
+func NewUserETL(mongoClient *mongo.Client, postgresDB *gorm.DB) (*UserETL, error) {
+	sink, err := newUserSink(postgresDB)
+	if err != nil {
+		return nil, err
+	}
 	return &UserETL{
 		mongoClient: mongoClient,
 		postgresDB:  postgresDB,
-	}
+		sink:        sink,
+	}, nil
 }
 
 // PreProcess runs migrations
 func (u *UserETL) PreProcess(ctx context.Context) error {
-	fmt.Println("Starting ETL pipeline...")
+	etl.LoggerFromContext(ctx).Info("Starting ETL pipeline")
 	return AutoMigrateAll(u.postgresDB)
 }
 
 // Extract reads users from MongoDB
 func (u *UserETL) Extract(ctx context.Context) (<-chan etl.Payload[User], error) {
-	ch := make(chan etl.Payload[User], 100)
-
-	collection := u.mongoClient.Database("sample_db").Collection("users")
-
-	go func() {
-		defer close(ch)
-
-		cursor, err := collection.Find(ctx, bson.M{})
-		if err != nil {
-			ch <- etl.Payload[User]{Err: fmt.Errorf("failed to create cursor: %w", err)}
-			return
-		}
-		defer cursor.Close(ctx)
-
-		for cursor.Next(ctx) {
-			var user User
-			if err := cursor.Decode(&user); err != nil {
-				ch <- etl.Payload[User]{Err: fmt.Errorf("failed to decode user: %w", err)}
-				return
-			}
-
-			select {
-			case <-ctx.Done():
-				return
-			case ch <- etl.Payload[User]{Data: user}:
-			}
-		}
-
-		if err := cursor.Err(); err != nil {
-			ch <- etl.Payload[User]{Err: fmt.Errorf("cursor error: %w", err)}
-		}
-	}()
-
-	return ch, nil
+	src, err := mongosource.New[User](mongosource.Config{
+		Database:    u.mongoClient.Database("sample_db"),
+		Collections: []string{"users"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return src.Extract(ctx)
 }
 
 // Transform converts MongoDB User to PostgreSQL models
@@ -292,147 +273,57 @@ func (u *UserETL) Transform(ctx context.Context, user User) TransformedUser {
 	}
 }
 
-// Load inserts transformed data into PostgreSQL in batches
+// Load inserts transformed data into PostgreSQL in batches, in foreign key
+// order
 func (u *UserETL) Load(ctx context.Context, items []TransformedUser) error {
 	if len(items) == 0 {
 		return nil
 	}
-
-	// Collect all entities by table
-	users := make([]PGUser, 0, len(items))
-	addresses := make([]PGAddress, 0, len(items))
-	profiles := make([]PGProfile, 0, len(items))
-	var allEducation []PGEducation
-	var allExperience []PGExperience
-	preferences := make([]PGPreferences, 0, len(items))
-	var allSettings []PGSettings
-	var allActivityLog []PGActivityLog
-	var allTransactions []PGTransactions
-	var allMessages []PGMessages
-	var allAttachments []PGAttachments
-	socialMedia := make([]PGSocialMedia, 0, len(items))
-	var allPosts []PGPosts
-	var allGroups []PGGroups
-	largeData := make([]PGLargeData, 0, len(items))
-
-	for _, item := range items {
-		users = append(users, item.User)
-		addresses = append(addresses, item.Address)
-		profiles = append(profiles, item.Profile)
-		allEducation = append(allEducation, item.Education...)
-		allExperience = append(allExperience, item.Experience...)
-		preferences = append(preferences, item.Preferences)
-		allSettings = append(allSettings, item.Settings...)
-		allActivityLog = append(allActivityLog, item.ActivityLog...)
-		allTransactions = append(allTransactions, item.Transactions...)
-		allMessages = append(allMessages, item.Messages...)
-		allAttachments = append(allAttachments, item.Attachments...)
-		socialMedia = append(socialMedia, item.SocialMedia)
-		allPosts = append(allPosts, item.Posts...)
-		allGroups = append(allGroups, item.Groups...)
-		largeData = append(largeData, item.LargeData)
-	}
-
-	// Batch insert in dependency order
-	fmt.Printf("Batch inserting %d users...\n", len(users))
-	if err := u.postgresDB.CreateInBatches(users, 500).Error; err != nil {
-		return fmt.Errorf("failed to insert users: %w", err)
-	}
-
-	fmt.Printf("Batch inserting %d addresses...\n", len(addresses))
-	if err := u.postgresDB.CreateInBatches(addresses, 500).Error; err != nil {
-		return fmt.Errorf("failed to insert addresses: %w", err)
-	}
-
-	fmt.Printf("Batch inserting %d profiles...\n", len(profiles))
-	if err := u.postgresDB.CreateInBatches(profiles, 500).Error; err != nil {
-		return fmt.Errorf("failed to insert profiles: %w", err)
-	}
-
-	if len(allEducation) > 0 {
-		fmt.Printf("Batch inserting %d education records...\n", len(allEducation))
-		if err := u.postgresDB.CreateInBatches(allEducation, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert education: %w", err)
-		}
-	}
-
-	if len(allExperience) > 0 {
-		fmt.Printf("Batch inserting %d experience records...\n", len(allExperience))
-		if err := u.postgresDB.CreateInBatches(allExperience, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert experience: %w", err)
-		}
-	}
-
-	fmt.Printf("Batch inserting %d preferences...\n", len(preferences))
-	if err := u.postgresDB.CreateInBatches(preferences, 500).Error; err != nil {
-		return fmt.Errorf("failed to insert preferences: %w", err)
-	}
-
-	if len(allSettings) > 0 {
-		fmt.Printf("Batch inserting %d settings...\n", len(allSettings))
-		if err := u.postgresDB.CreateInBatches(allSettings, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert settings: %w", err)
-		}
-	}
-
-	if len(allActivityLog) > 0 {
-		fmt.Printf("Batch inserting %d activity logs...\n", len(allActivityLog))
-		if err := u.postgresDB.CreateInBatches(allActivityLog, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert activity log: %w", err)
-		}
-	}
-
-	if len(allTransactions) > 0 {
-		fmt.Printf("Batch inserting %d transactions...\n", len(allTransactions))
-		if err := u.postgresDB.CreateInBatches(allTransactions, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert transactions: %w", err)
-		}
-	}
-
-	if len(allMessages) > 0 {
-		fmt.Printf("Batch inserting %d messages...\n", len(allMessages))
-		if err := u.postgresDB.CreateInBatches(allMessages, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert messages: %w", err)
-		}
-	}
-
-	if len(allAttachments) > 0 {
-		fmt.Printf("Batch inserting %d attachments...\n", len(allAttachments))
-		if err := u.postgresDB.CreateInBatches(allAttachments, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert attachments: %w", err)
-		}
-	}
-
-	fmt.Printf("Batch inserting %d social media records...\n", len(socialMedia))
-	if err := u.postgresDB.CreateInBatches(socialMedia, 500).Error; err != nil {
-		return fmt.Errorf("failed to insert social media: %w", err)
+	if err := u.sink.Load(ctx, items); err != nil {
+		return err
 	}
+	etl.LoggerFromContext(ctx).Info("Batch inserted users with all related data", "count", len(items))
+	return nil
+}
 
-	if len(allPosts) > 0 {
-		fmt.Printf("Batch inserting %d posts...\n", len(allPosts))
-		if err := u.postgresDB.CreateInBatches(allPosts, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert posts: %w", err)
-		}
-	}
+// newUserSink creates the sink of the 15 tables of transformed users,
+// inserted one table at a time like the Rust version so timings compare
+func newUserSink(db *gorm.DB) (*graphsink.Sink[TransformedUser], error) {
+	return graphsink.New(graphsink.Config[TransformedUser]{
+		Entities: []graphsink.Entity[TransformedUser]{
+			{Name: "users", Sink: graphsink.Rows(func(t TransformedUser) []PGUser { return []PGUser{t.User} }, insert[PGUser](db, "users"))},
+			{Name: "addresses", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGAddress { return []PGAddress{t.Address} }, insert[PGAddress](db, "addresses"))},
+			{Name: "profiles", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGProfile { return []PGProfile{t.Profile} }, insert[PGProfile](db, "profiles"))},
+			{Name: "education", References: []string{"profiles"}, Sink: graphsink.Rows(func(t TransformedUser) []PGEducation { return t.Education }, insert[PGEducation](db, "education"))},
+			{Name: "experience", References: []string{"profiles"}, Sink: graphsink.Rows(func(t TransformedUser) []PGExperience { return t.Experience }, insert[PGExperience](db, "experience"))},
+			{Name: "preferences", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGPreferences { return []PGPreferences{t.Preferences} }, insert[PGPreferences](db, "preferences"))},
+			{Name: "settings", References: []string{"preferences"}, Sink: graphsink.Rows(func(t TransformedUser) []PGSettings { return t.Settings }, insert[PGSettings](db, "settings"))},
+			{Name: "activity_log", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGActivityLog { return t.ActivityLog }, insert[PGActivityLog](db, "activity_log"))},
+			{Name: "transactions", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGTransactions { return t.Transactions }, insert[PGTransactions](db, "transactions"))},
+			{Name: "messages", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGMessages { return t.Messages }, insert[PGMessages](db, "messages"))},
+			{Name: "attachments", References: []string{"messages"}, Sink: graphsink.Rows(func(t TransformedUser) []PGAttachments { return t.Attachments }, insert[PGAttachments](db, "attachments"))},
+			{Name: "social_media", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGSocialMedia { return []PGSocialMedia{t.SocialMedia} }, insert[PGSocialMedia](db, "social_media"))},
+			{Name: "posts", References: []string{"social_media"}, Sink: graphsink.Rows(func(t TransformedUser) []PGPosts { return t.Posts }, insert[PGPosts](db, "posts"))},
+			{Name: "groups", References: []string{"social_media"}, Sink: graphsink.Rows(func(t TransformedUser) []PGGroups { return t.Groups }, insert[PGGroups](db, "groups"))},
+			{Name: "large_data", References: []string{"users"}, Sink: graphsink.Rows(func(t TransformedUser) []PGLargeData { return []PGLargeData{t.LargeData} }, insert[PGLargeData](db, "large_data"))},
+		},
+		Parallel: false, // Same as Rust
+	})
+}
 
-	if len(allGroups) > 0 {
-		fmt.Printf("Batch inserting %d groups...\n", len(allGroups))
-		if err := u.postgresDB.CreateInBatches(allGroups, 500).Error; err != nil {
-			return fmt.Errorf("failed to insert groups: %w", err)
+// insert returns a function inserting rows into table in batches of 500
+func insert[R any](db *gorm.DB, table string) func(ctx context.Context, rows []R) error {
+	return func(ctx context.Context, rows []R) error {
+		etl.LoggerFromContext(ctx).Info("Batch inserting", "table", table, "count", len(rows))
+		if err := db.WithContext(ctx).CreateInBatches(rows, 500).Error; err != nil {
+			return fmt.Errorf("failed to insert %s: %w", table, err)
 		}
+		return nil
 	}
-
-	fmt.Printf("Batch inserting %d large data records...\n", len(largeData))
-	if err := u.postgresDB.CreateInBatches(largeData, 500).Error; err != nil {
-		return fmt.Errorf("failed to insert large data: %w", err)
-	}
-
-	fmt.Printf("✓ Batch inserted %d users with all related data!\n", len(items))
-	return nil
 }
 
 // PostProcess cleanup after ETL
 func (u *UserETL) PostProcess(ctx context.Context) error {
-	fmt.Println("ETL pipeline completed successfully!")
+	etl.LoggerFromContext(ctx).Info("ETL pipeline completed successfully")
 	return nil
 }
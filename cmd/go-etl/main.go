@@ -0,0 +1,8 @@
+// Command go-etl operates go-etl pipelines and their state
+package main
+
+import "github.com/cuong/go-etl/pkg/cli"
+
+func main() {
+	cli.Main()
+}
@@ -0,0 +1,207 @@
+// Package anonymize masks, hashes, replaces and drops the personal data of
+// records, so pipelines copying production data to other environments can
+// strip it field by field
+//
+// Anonymizers are functions of a field value, composed with Chain and
+// applied to the fields of records by Fields:
+//
+//	fakeName, err := anonymize.Fake("name", salt)
+//	...
+//	fields := anonymize.Fields{
+//		"id":          anonymize.Keep,
+//		"customer_id": anonymize.Hash(salt),
+//		"email":       anonymize.MaskEmail,
+//		"name":        fakeName,
+//		"card":        anonymize.Mask(0, 4),
+//		"ssn":         anonymize.Drop,
+//	}
+//	err = fields.Apply(rec, false) // Drops every field not listed
+//
+// Hash and Fake are deterministic for a salt, so anonymized keys still join
+// across tables and runs; keep the salt secret, or they can be reversed by
+// guessing inputs.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Func anonymizes a field value; nil values are passed to it too
+type Func func(value any) (any, error)
+
+// dropped is the value of dropped fields
+type dropped struct{}
+
+// Keep returns value unchanged, listing a field as safe
+func Keep(value any) (any, error) {
+	return value, nil
+}
+
+// Drop removes the field from the record
+func Drop(any) (any, error) {
+	return dropped{}, nil
+}
+
+// Null replaces the value with nil
+func Null(any) (any, error) {
+	return nil, nil
+}
+
+// Redact replaces non-nil values with replacement, "REDACTED" if empty
+func Redact(replacement string) Func {
+	if replacement == "" {
+		replacement = "REDACTED"
+	}
+	return func(value any) (any, error) {
+		if value == nil {
+			return nil, nil
+		}
+		return replacement, nil
+	}
+}
+
+// Chain applies fns in order, each to the result of the previous one,
+// stopping at Drop
+func Chain(fns ...Func) Func {
+	return func(value any) (any, error) {
+		for _, fn := range fns {
+			var err error
+			if value, err = fn(value); err != nil {
+				return nil, err
+			}
+			if _, ok := value.(dropped); ok {
+				break
+			}
+		}
+		return value, nil
+	}
+}
+
+// Hash replaces non-nil values with the hex HMAC-SHA256 of their text under
+// salt; equal values hash equally, whatever their type
+func Hash(salt []byte) Func {
+	return func(value any) (any, error) {
+		if value == nil {
+			return nil, nil
+		}
+		return hex.EncodeToString(sum(salt, value)), nil
+	}
+}
+
+// sum returns the HMAC-SHA256 of the text of value under salt
+func sum(salt []byte, value any) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(text(value)))
+	return mac.Sum(nil)
+}
+
+// text returns the text of a value
+func text(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return x.String()
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return strings.Trim(string(data), `"`)
+	}
+	return fmt.Sprint(v)
+}
+
+// Fields maps the dotted paths of the fields of records, e.g.
+// "address.street", to their anonymizers
+type Fields map[string]Func
+
+// Apply anonymizes the fields of rec in place
+// Top-level fields f does not list are dropped unless keepOthers is set,
+// so fields added to a source later are not copied before being reviewed;
+// nested fields are only changed when listed.
+func (f Fields) Apply(rec map[string]any, keepOthers bool) error {
+	if !keepOthers {
+		listed := make(map[string]bool, len(f))
+		for path := range f {
+			top, _, _ := strings.Cut(path, ".")
+			listed[top] = true
+		}
+		for field := range rec {
+			if !listed[field] {
+				delete(rec, field)
+			}
+		}
+	}
+
+	for path, fn := range f {
+		parent, field := rec, path
+		for {
+			head, rest, nested := strings.Cut(field, ".")
+			if !nested {
+				break
+			}
+			child, ok := parent[head].(map[string]any)
+			if !ok {
+				parent = nil
+				break
+			}
+			parent, field = child, rest
+		}
+		if parent == nil {
+			continue
+		}
+		value, ok := parent[field]
+		if !ok {
+			continue
+		}
+
+		anonymized, err := fn(value)
+		if err != nil {
+			return fmt.Errorf("anonymize: %s: %w", path, err)
+		}
+		if _, ok := anonymized.(dropped); ok {
+			delete(parent, field)
+			continue
+		}
+		parent[field] = anonymized
+	}
+	return nil
+}
+
+// Named returns the anonymizer called name, for declarative pipelines:
+// keep, drop, null, redact, hash, mask (keeping the last 4 characters),
+// mask_email, mask_phone (keeping the last 4 digits) or fake_<kind> with a
+// kind of FakeKinds, e.g. fake_name
+// Hash and Fake use salt.
+func Named(name string, salt []byte) (Func, error) {
+	switch name {
+	case "keep":
+		return Keep, nil
+	case "drop":
+		return Drop, nil
+	case "null":
+		return Null, nil
+	case "redact":
+		return Redact(""), nil
+	case "hash":
+		return Hash(salt), nil
+	case "mask":
+		return Mask(0, 4), nil
+	case "mask_email":
+		return MaskEmail, nil
+	case "mask_phone":
+		return MaskPhone(4), nil
+	}
+	if kind, ok := strings.CutPrefix(name, "fake_"); ok {
+		return Fake(kind, salt)
+	}
+	return nil, fmt.Errorf("anonymize: unknown anonymizer %q", name)
+}
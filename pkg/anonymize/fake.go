@@ -0,0 +1,53 @@
+package anonymize
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// fakers generate the fake values of Fake
+var fakers = map[string]func(f *gofakeit.Faker) string{
+	"name":       (*gofakeit.Faker).Name,
+	"first_name": (*gofakeit.Faker).FirstName,
+	"last_name":  (*gofakeit.Faker).LastName,
+	"email":      (*gofakeit.Faker).Email,
+	"username":   (*gofakeit.Faker).Username,
+	"phone":      (*gofakeit.Faker).PhoneFormatted,
+	"street":     (*gofakeit.Faker).Street,
+	"city":       (*gofakeit.Faker).City,
+	"zip":        (*gofakeit.Faker).Zip,
+	"country":    (*gofakeit.Faker).Country,
+	"company":    (*gofakeit.Faker).Company,
+	"ssn":        (*gofakeit.Faker).SSN,
+	"ipv4":       (*gofakeit.Faker).IPv4Address,
+	"url":        (*gofakeit.Faker).URL,
+}
+
+// FakeKinds returns the kinds of values Fake generates, sorted
+func FakeKinds() []string {
+	return slices.Sorted(maps.Keys(fakers))
+}
+
+// Fake replaces non-nil values with realistic fake values of kind, one of
+// FakeKinds, e.g. "name" or "email"
+// A value is always replaced by the same fake value for a salt, so
+// anonymized keys still join; distinct values may collide.
+func Fake(kind string, salt []byte) (Func, error) {
+	gen, ok := fakers[kind]
+	if !ok {
+		return nil, fmt.Errorf("anonymize: unknown fake kind %q", kind)
+	}
+	return func(value any) (any, error) {
+		if value == nil {
+			return nil, nil
+		}
+		seed := sum(salt, value)
+		src := rand.NewPCG(binary.BigEndian.Uint64(seed), binary.BigEndian.Uint64(seed[8:]))
+		return gen(gofakeit.NewFaker(src, false)), nil
+	}, nil
+}
@@ -0,0 +1,83 @@
+package anonymize
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maskRune replaces masked characters
+const maskRune = '*'
+
+// Mask masks values but for their first keepStart and last keepEnd
+// characters, keeping their length; values too short to hide anything are
+// masked entirely
+// Like the other masks, it keeps nil values and masks the text of values
+// that are not strings, e.g. numbers.
+func Mask(keepStart, keepEnd int) Func {
+	return func(value any) (any, error) {
+		s, ok := str(value)
+		if !ok {
+			return value, nil
+		}
+		return mask(s, keepStart, keepEnd), nil
+	}
+}
+
+// mask masks s but for its first keepStart and last keepEnd characters
+func mask(s string, keepStart, keepEnd int) string {
+	r := []rune(s)
+	if keepStart+keepEnd >= len(r) {
+		keepStart, keepEnd = 0, 0
+	}
+	for i := keepStart; i < len(r)-keepEnd; i++ {
+		r[i] = maskRune
+	}
+	return string(r)
+}
+
+// MaskEmail masks the local part of email addresses but for its first
+// character, keeping the domain, e.g. "j*******@example.com"; values that
+// are not addresses are masked entirely
+func MaskEmail(value any) (any, error) {
+	s, ok := str(value)
+	if !ok {
+		return value, nil
+	}
+	local, domain, found := strings.Cut(s, "@")
+	if !found || local == "" || domain == "" {
+		return mask(s, 0, 0), nil
+	}
+	return mask(local, 1, 0) + "@" + domain, nil
+}
+
+// MaskPhone masks the digits of phone numbers but for the last keep,
+// keeping their formatting, e.g. "+* (***) ***-4567"
+func MaskPhone(keep int) Func {
+	return func(value any) (any, error) {
+		s, ok := str(value)
+		if !ok {
+			return value, nil
+		}
+		r := []rune(s)
+		kept := 0
+		for i := len(r) - 1; i >= 0; i-- {
+			if !unicode.IsDigit(r[i]) {
+				continue
+			}
+			if kept < keep {
+				kept++
+				continue
+			}
+			r[i] = maskRune
+		}
+		return string(r), nil
+	}
+}
+
+// str returns the text of value; ok is false for nil
+func str(value any) (s string, ok bool) {
+	if value == nil {
+		return "", false
+	}
+	return text(value), true
+}
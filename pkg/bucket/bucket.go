@@ -4,29 +4,76 @@ package bucket
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // ProcessFunc processes a batch of items
 type ProcessFunc[T any] func(ctx context.Context, items []T) error
 
+// DeadLetterFunc receives a batch whose processing panicked
+// Returning nil lets the bucket continue with the next batch
+type DeadLetterFunc[T any] func(ctx context.Context, items []T, err error) error
+
+// PanicError is returned when a ProcessFunc panics
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
 // Config configures bucket batching and worker behavior
 type Config struct {
 	BatchSize int           // Number of items per batch
 	Timeout   time.Duration // Max time to wait before flushing partial batch
 	WorkerNum int           // Number of parallel workers
+
+	// ShutdownTimeout is how long buffered and in-flight batches may keep
+	// being processed after the context passed to Run is cancelled
+	ShutdownTimeout time.Duration
+
+	QueueSize int            // Capacity of the queue feeding the dispatcher (defaults to BatchSize)
+	Overflow  OverflowPolicy // What Consume does when the queue is full
+	SpillDir  string         // Directory for OverflowSpill files (defaults to os.TempDir)
+
+	MaxRetries   int           // Retries of a failed batch before the run fails
+	RetryBackoff time.Duration // Delay before the first retry, doubled after each attempt (defaults to 100ms)
+	RetryBudget  *RetryBudget  // Optional cap on retries shared across buckets
+
+	Logger *slog.Logger // Logs retries and dead-lettered batches (defaults to slog.Default)
 }
 
 // Bucket batches items and processes them with multiple workers
 type Bucket[T any] struct {
-	cfg      Config
-	consumer chan T
+	cfg        Config
+	consumer   chan T
+	done       chan struct{} // Closed when Run returns
+	deadLetter DeadLetterFunc[T]
+	failures   bool // Dead-letter failed batches too
+	strategy   Strategy[T]
+
+	spillQ     spillQueue[T]
+	onOverflow OverflowFunc[T]
+	batchSeq   atomic.Int64
+	dropped    atomic.Int64
+	spilled    atomic.Int64
 }
 
 // New creates a new bucket with the given configuration
+// Defaults are applied to a copy, so one Config can be shared by buckets
+// created concurrently.
 func New[T any](cfg *Config) (*Bucket[T], error) {
+	c := *cfg
+	cfg = &c
+
 	if cfg.BatchSize <= 0 {
 		cfg.BatchSize = 100
 	}
@@ -36,35 +83,95 @@ func New[T any](cfg *Config) (*Bucket[T], error) {
 	if cfg.WorkerNum <= 0 {
 		cfg.WorkerNum = 1
 	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 10 * time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 100 * time.Millisecond
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = cfg.BatchSize
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	switch cfg.Overflow {
+	case OverflowBlock, OverflowDropOldest, OverflowSpill:
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %v", cfg.Overflow)
+	}
 
 	return &Bucket[T]{
 		cfg:      *cfg,
-		consumer: make(chan T, cfg.BatchSize),
+		consumer: make(chan T, cfg.QueueSize),
+		done:     make(chan struct{}),
+		spillQ:   spillQueue[T]{dir: cfg.SpillDir},
 	}, nil
 }
 
 // Consume adds an item to the bucket for processing
+// When the queue is full, the configured OverflowPolicy applies. Items
+// consumed after Run has returned are discarded.
 func (b *Bucket[T]) Consume(item T) {
-	b.consumer <- item
+	switch b.cfg.Overflow {
+	case OverflowDropOldest:
+		b.consumeDropOldest(item)
+	case OverflowSpill:
+		b.consumeSpill(item)
+	default:
+		b.send(item)
+	}
+}
+
+// SetDeadLetter registers a handler for batches whose processing panicked,
+// or failed with SetDeadLetterFailures
+// Without a handler, a panic is converted into an error that stops the run
+func (b *Bucket[T]) SetDeadLetter(fn DeadLetterFunc[T]) {
+	b.deadLetter = fn
+}
+
+// SetDeadLetterFailures also hands the dead letter handler the batches
+// whose processing failed once retries are exhausted, instead of stopping
+// the run; batches failing because Run's context was cancelled still stop it
+func (b *Bucket[T]) SetDeadLetterFailures(on bool) {
+	b.failures = on
 }
 
 // Close signals that no more items will be added
 func (b *Bucket[T]) Close() {
+	if b.cfg.Overflow == OverflowSpill && b.spillQ.close() {
+		// The refill goroutine closes the queue once spilled items are read
+		return
+	}
 	close(b.consumer)
 }
 
 // Run starts processing items with multiple workers
-// Each worker accumulates items into batches and calls processFunc
+// Items are accumulated into batches by a single dispatcher. By default each
+// batch is handed to whichever worker is idle, so a worker stuck in a slow
+// processFunc never holds a private half-filled queue that other workers could
+// have processed. With a Strategy set, every worker gets its own queue and
+// the strategy decides which worker each item goes to.
 // Batches are flushed when:
 // - Batch size is reached
 // - Timeout occurs
 // - Channel is closed
 // - Context is cancelled
+//
+// On context cancellation, batches are processed with a shutdown context that
+// stays alive for ShutdownTimeout, so buffered items get a real chance to be
+// written. A worker error aborts the run immediately without flushing.
 func (b *Bucket[T]) Run(ctx context.Context, processFunc ProcessFunc[T]) error {
+	defer close(b.done)
+
 	procCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	errCh := make(chan error, b.cfg.WorkerNum)
+	loadCtx, cancelLoad := shutdownContext(ctx, b.cfg.ShutdownTimeout)
+	defer cancelLoad()
+
+	lanes := b.newLanes()
+	errCh := make(chan error, b.cfg.WorkerNum+1)
 	var wg sync.WaitGroup
 
 	// Start workers
@@ -73,71 +180,203 @@ func (b *Bucket[T]) Run(ctx context.Context, processFunc ProcessFunc[T]) error {
 		go func(workerID int) {
 			defer wg.Done()
 
-			if err := b.worker(procCtx, processFunc); err != nil {
+			workerCtx := context.WithValue(loadCtx, workerIDContextKey{}, workerID)
+			if err := b.worker(workerCtx, lanes.forWorker(workerID), processFunc); err != nil {
 				select {
 				case errCh <- fmt.Errorf("worker %d: %w", workerID, err):
 				default:
 				}
-				cancel() // Cancel other workers on error
+				// Cancel other workers on error
+				cancel()
+				cancelLoad()
 			}
 		}(i)
 	}
 
+	// Dispatch batches until the consumer is closed or the context is done
+	if err := b.dispatch(procCtx, loadCtx, lanes); err != nil {
+		select {
+		case errCh <- fmt.Errorf("dispatcher: %w", err):
+		default:
+		}
+	}
+	lanes.close()
+
 	// Wait for all workers
 	wg.Wait()
 	close(errCh)
 
+	spillErr := b.spillQ.cleanup()
+
 	// Check for errors
 	for err := range errCh {
 		return err
 	}
+	if spillErr != nil {
+		return fmt.Errorf("spill queue: %w", spillErr)
+	}
 
 	return nil
 }
 
-// worker processes items in batches
-func (b *Bucket[T]) worker(ctx context.Context, processFunc ProcessFunc[T]) error {
+// dispatch accumulates consumed items into per-lane batches and hands each
+// full batch to its lane. Once ctx is done, any pending items are handed out
+// under loadCtx instead.
+func (b *Bucket[T]) dispatch(ctx, loadCtx context.Context, lanes *lanes[T]) error {
 	ticker := time.NewTicker(b.cfg.Timeout)
 	defer ticker.Stop()
 
-	queue := make([]T, 0, b.cfg.BatchSize)
-
-	flush := func() error {
-		if len(queue) > 0 {
-			if err := processFunc(ctx, queue); err != nil {
-				return err
-			}
-			queue = queue[:0] // Reset queue
+	// stop flushes what is left unless the run was aborted by a worker error
+	stop := func() error {
+		if loadCtx.Err() != nil {
+			return nil
 		}
-		return nil
+		return b.drain(loadCtx, lanes)
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			// Flush remaining items on context cancellation
-			return flush()
+			return stop()
 
 		case <-ticker.C:
-			// Timeout: flush partial batch
-			if err := flush(); err != nil {
-				return err
+			// Timeout: flush partial batches
+			if !lanes.sendAll(ctx) {
+				return stop()
 			}
 
 		case item, ok := <-b.consumer:
 			if !ok {
 				// Channel closed: flush remaining items
-				return flush()
+				if !lanes.sendAll(ctx) {
+					return stop()
+				}
+				return nil
 			}
 
-			queue = append(queue, item)
-
 			// Flush when batch size is reached
-			if len(queue) >= b.cfg.BatchSize {
-				if err := flush(); err != nil {
-					return err
-				}
+			if lane := lanes.add(item); lanes.full(lane) && !lanes.send(ctx, lane) {
+				return stop()
+			}
+		}
+	}
+}
+
+// drain hands the pending queues and any items still buffered in the
+// consumer channel to the workers during shutdown
+func (b *Bucket[T]) drain(loadCtx context.Context, lanes *lanes[T]) error {
+	for drained := false; !drained; {
+		select {
+		case item, ok := <-b.consumer:
+			if !ok {
+				drained = true
+				continue
+			}
+			if lane := lanes.add(item); lanes.full(lane) && !lanes.send(loadCtx, lane) {
+				return fmt.Errorf("shutdown flush of %d items: %w", lanes.pending(), context.Cause(loadCtx))
 			}
+		default:
+			drained = true
 		}
 	}
+
+	if !lanes.sendAll(loadCtx) {
+		return fmt.Errorf("shutdown flush of %d items: %w", lanes.pending(), context.Cause(loadCtx))
+	}
+	return nil
+}
+
+// worker processes batches handed out by the dispatcher until its lane is
+// closed
+func (b *Bucket[T]) worker(ctx context.Context, lane *lane[T], processFunc ProcessFunc[T]) error {
+	for batch := range lane.batches {
+		id := b.batchSeq.Add(1)
+		batchCtx := context.WithValue(ctx, batchIDContextKey{}, id)
+
+		err := b.processWithRetry(batchCtx, id, batch, processFunc)
+		lane.load.Add(-int64(len(batch)))
+
+		if _, err := b.deadLetterFailure(batchCtx, id, batch, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Process processes batch with processFunc outside of Run the way a worker
+// does, for work a ProcessFunc hands off to finish later, e.g. an
+// asynchronous load: failures are retried as configured, and the batch is
+// dead-lettered if processFunc panicked, or failed with
+// SetDeadLetterFailures while ctx is live
+// It reports whether the batch was dead-lettered, and returns the error
+// that would have stopped Run. ctx should carry the batch ID of the context
+// the work was handed off from.
+func (b *Bucket[T]) Process(ctx context.Context, batch []T, processFunc ProcessFunc[T]) (deadLettered bool, err error) {
+	id, _ := BatchID(ctx)
+	err = b.processWithRetry(ctx, id, batch, processFunc)
+	return b.deadLetterFailure(ctx, id, batch, err)
+}
+
+// deadLetterFailure hands a batch whose processing failed with err to the
+// dead letter handler if it panicked, or failed with SetDeadLetterFailures
+// while ctx is live, reporting whether it did; the error returned stops
+// the run
+func (b *Bucket[T]) deadLetterFailure(ctx context.Context, id int64, batch []T, err error) (bool, error) {
+	var panicErr *PanicError
+	failed := err != nil && b.failures && ctx.Err() == nil
+	if !(errors.As(err, &panicErr) || failed) || b.deadLetter == nil {
+		return false, err
+	}
+	b.cfg.Logger.Error("Dead-lettering batch", "batch", id, "items", len(batch), "error", err)
+	if dlErr := b.deadLetter(ctx, batch, err); dlErr != nil {
+		return false, fmt.Errorf("dead-letter batch after %w: %w", err, dlErr)
+	}
+	return true, nil
+}
+
+// batchIDContextKey is the context key for the batch ID
+type batchIDContextKey struct{}
+
+// BatchID returns the ID of the batch being processed, numbered from 1 in
+// the order workers pick batches up, from the context passed to the
+// ProcessFunc
+func BatchID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(batchIDContextKey{}).(int64)
+	return id, ok
+}
+
+// workerIDContextKey is the context key for the worker ID
+type workerIDContextKey struct{}
+
+// WorkerID returns the ID of the worker processing a batch, from 0 to
+// WorkerNum-1, from the context passed to the ProcessFunc
+func WorkerID(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(workerIDContextKey{}).(int)
+	return id, ok
+}
+
+// process calls processFunc, converting a panic into a *PanicError
+func (b *Bucket[T]) process(ctx context.Context, batch []T, processFunc ProcessFunc[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return processFunc(ctx, batch)
+}
+
+// shutdownContext returns a context that ignores the cancellation of parent
+// and is only cancelled grace after parent is done, or when the returned
+// cancel function is called
+func shutdownContext(parent context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stop := context.AfterFunc(parent, func() {
+		time.AfterFunc(grace, cancel)
+	})
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
 }
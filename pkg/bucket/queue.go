@@ -0,0 +1,250 @@
+package bucket
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// OverflowPolicy decides what Consume does when the bucket queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks Consume until the dispatcher makes room
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest queued item to make room,
+	// counting it in Stats().Dropped
+	// Discarded items are never processed, so the policy suits sources
+	// that can afford to lose records, not checkpointed or acknowledged
+	// ones.
+	OverflowDropOldest
+
+	// OverflowSpill gob-encodes overflowing items to a temporary file in
+	// Config.SpillDir and feeds them back in order once there is room
+	OverflowSpill
+)
+
+// String returns the policy name
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowSpill:
+		return "spill"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// Stats reports queue overflow counters
+type Stats struct {
+	Dropped int64 // Items discarded by OverflowDropOldest
+	Spilled int64 // Items written to disk by OverflowSpill
+}
+
+// OverflowFunc is called by Consume with every item a full queue discards
+// under OverflowDropOldest or spills to disk under OverflowSpill
+type OverflowFunc[T any] func(item T, policy OverflowPolicy)
+
+// SetOnOverflow registers fn to be called with every item discarded or
+// spilled by the overflow policy
+func (b *Bucket[T]) SetOnOverflow(fn OverflowFunc[T]) {
+	b.onOverflow = fn
+}
+
+// Stats returns the bucket's queue overflow counters
+func (b *Bucket[T]) Stats() Stats {
+	return Stats{
+		Dropped: b.dropped.Load(),
+		Spilled: b.spilled.Load(),
+	}
+}
+
+// send blocks until item is queued, reporting false if Run has already
+// returned and nobody will read it
+func (b *Bucket[T]) send(item T) bool {
+	select {
+	case b.consumer <- item:
+		return true
+	case <-b.done:
+		return false
+	}
+}
+
+// consumeDropOldest queues item, discarding the oldest queued items while
+// the queue is full
+func (b *Bucket[T]) consumeDropOldest(item T) {
+	for {
+		select {
+		case b.consumer <- item:
+			return
+		default:
+		}
+
+		select {
+		case old := <-b.consumer:
+			b.dropped.Add(1)
+			if b.onOverflow != nil {
+				b.onOverflow(old, OverflowDropOldest)
+			}
+		default:
+		}
+	}
+}
+
+// spillQueue is a FIFO of items gob-encoded to a temporary file
+// Items are appended by Consume and fed back into the consumer channel by a
+// refill goroutine that runs while anything is pending
+type spillQueue[T any] struct {
+	mu        sync.Mutex
+	dir       string
+	writer    *os.File
+	reader    *os.File
+	enc       *gob.Encoder
+	dec       *gob.Decoder
+	pending   int
+	refilling bool
+	closing   bool
+	err       error
+}
+
+// consumeSpill queues item in memory if possible and spills it otherwise
+// Once anything is spilled, new items spill behind it to preserve order
+func (b *Bucket[T]) consumeSpill(item T) {
+	s := &b.spillQ
+	s.mu.Lock()
+
+	if s.pending == 0 {
+		select {
+		case b.consumer <- item:
+			s.mu.Unlock()
+			return
+		default:
+		}
+	}
+
+	if s.err == nil && s.writer == nil {
+		s.err = s.open()
+	}
+	if s.err == nil {
+		if err := s.enc.Encode(&item); err != nil {
+			s.err = fmt.Errorf("spill item: %w", err)
+		}
+	}
+	if s.err != nil {
+		// Spilling is broken: fall back to blocking on the queue
+		s.mu.Unlock()
+		b.send(item)
+		return
+	}
+
+	s.pending++
+	b.spilled.Add(1)
+	if !s.refilling {
+		s.refilling = true
+		go b.refill()
+	}
+	s.mu.Unlock()
+
+	if b.onOverflow != nil {
+		b.onOverflow(item, OverflowSpill)
+	}
+}
+
+// refill feeds spilled items back into the consumer channel in order
+func (b *Bucket[T]) refill() {
+	s := &b.spillQ
+
+	for {
+		s.mu.Lock()
+		if s.dec == nil {
+			// Cleaned up after Run returned
+			s.mu.Unlock()
+			return
+		}
+		if s.pending == 0 {
+			s.refilling = false
+			if s.closing {
+				close(b.consumer)
+			}
+			s.mu.Unlock()
+			return
+		}
+
+		var item T
+		if err := s.dec.Decode(&item); err != nil {
+			s.err = fmt.Errorf("read spilled item: %w", err)
+			s.pending = 0
+			s.mu.Unlock()
+			continue
+		}
+		s.mu.Unlock()
+
+		if !b.send(item) {
+			return
+		}
+
+		// Only decrement once the item is queued, so that items consumed
+		// in the meantime keep spilling behind it
+		s.mu.Lock()
+		s.pending--
+		s.mu.Unlock()
+	}
+}
+
+// open creates the spill file with one handle for appending and one for
+// reading back
+func (s *spillQueue[T]) open() error {
+	writer, err := os.CreateTemp(s.dir, "bucket-spill-*")
+	if err != nil {
+		return fmt.Errorf("create spill file: %w", err)
+	}
+	reader, err := os.Open(writer.Name())
+	if err != nil {
+		writer.Close()
+		os.Remove(writer.Name())
+		return fmt.Errorf("open spill file: %w", err)
+	}
+
+	s.writer = writer
+	s.reader = reader
+	s.enc = gob.NewEncoder(writer)
+	s.dec = gob.NewDecoder(reader)
+	return nil
+}
+
+// close marks the spill queue as closing, reporting whether the refill
+// goroutine is running and will close the consumer channel once drained
+func (s *spillQueue[T]) close() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closing = true
+	return s.refilling
+}
+
+// cleanup removes the spill file, reporting spill failures and any items
+// that were never read back
+func (s *spillQueue[T]) cleanup() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer != nil {
+		s.writer.Close()
+		s.reader.Close()
+		os.Remove(s.writer.Name())
+		s.writer, s.reader, s.enc, s.dec = nil, nil, nil, nil
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+	if s.pending > 0 {
+		return fmt.Errorf("%d spilled items were not processed", s.pending)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package bucket
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runCollect closes b and runs it, returning the processed items in order
+func runCollect(t *testing.T, b *Bucket[int]) []int {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		items []int
+	)
+	b.Close()
+	err := b.Run(context.Background(), func(_ context.Context, batch []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		items = append(items, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	b, err := New[int](&Config{BatchSize: 1, Timeout: time.Second, QueueSize: 2, Overflow: OverflowDropOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dropped []int
+	b.SetOnOverflow(func(item int, policy OverflowPolicy) {
+		if policy != OverflowDropOldest {
+			t.Errorf("overflow policy %v, want drop-oldest", policy)
+		}
+		dropped = append(dropped, item)
+	})
+
+	for i := 1; i <= 5; i++ {
+		b.Consume(i)
+	}
+
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped %v, want %v", dropped, want)
+	}
+	if got := b.Stats(); got != (Stats{Dropped: 3}) {
+		t.Errorf("Stats() = %+v, want 3 dropped", got)
+	}
+	if got, want := runCollect(t, b), []int{4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("processed %v, want %v", got, want)
+	}
+}
+
+func TestOverflowSpill(t *testing.T) {
+	dir := t.TempDir()
+	b, err := New[int](&Config{BatchSize: 1, Timeout: time.Second, QueueSize: 2, Overflow: OverflowSpill, SpillDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spilled []int
+	b.SetOnOverflow(func(item int, policy OverflowPolicy) {
+		if policy != OverflowSpill {
+			t.Errorf("overflow policy %v, want spill", policy)
+		}
+		spilled = append(spilled, item)
+	})
+
+	// Nothing is read until Run, so all but the first two items spill
+	var want []int
+	for i := 1; i <= 10; i++ {
+		b.Consume(i)
+		want = append(want, i)
+	}
+
+	if !reflect.DeepEqual(spilled, want[2:]) {
+		t.Errorf("spilled %v, want %v", spilled, want[2:])
+	}
+	if got := b.Stats(); got != (Stats{Spilled: 8}) {
+		t.Errorf("Stats() = %+v, want 8 spilled", got)
+	}
+	if got := runCollect(t, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("processed %v, want every item in order %v", got, want)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) > 0 {
+		t.Errorf("spill files left behind: %v", files)
+	}
+}
@@ -0,0 +1,99 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRetryBudgetExhausted is returned when a batch fails after the shared
+// retry budget has been used up
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryBudget caps the total number of batch retries across a run
+// Share one budget between buckets (e.g. through a common Config) so that a
+// systemic sink outage fails fast instead of every batch retrying on its own.
+type RetryBudget struct {
+	mu         sync.Mutex
+	maxBatches int
+	maxRecords int
+	batches    int
+	records    int
+}
+
+// NewRetryBudget creates a budget allowing at most maxBatches retried
+// batches and maxRecords retried records; zero means no limit for that count
+func NewRetryBudget(maxBatches, maxRecords int) *RetryBudget {
+	return &RetryBudget{
+		maxBatches: maxBatches,
+		maxRecords: maxRecords,
+	}
+}
+
+// take reserves one retry of a batch of records items, reporting false if
+// that would exceed the budget
+func (r *RetryBudget) take(records int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxBatches > 0 && r.batches+1 > r.maxBatches {
+		return false
+	}
+	if r.maxRecords > 0 && r.records+records > r.maxRecords {
+		return false
+	}
+	r.batches++
+	r.records += records
+	return true
+}
+
+// Used returns the number of retried batches and records so far
+func (r *RetryBudget) Used() (batches, records int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.batches, r.records
+}
+
+// Reset clears the used counters, e.g. between scheduled runs
+func (r *RetryBudget) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches, r.records = 0, 0
+}
+
+// processWithRetry calls processFunc, retrying failed batches up to
+// MaxRetries times with exponential backoff while the retry budget allows
+// Panics are not retried.
+func (b *Bucket[T]) processWithRetry(ctx context.Context, id int64, batch []T, processFunc ProcessFunc[T]) error {
+	backoff := b.cfg.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := b.process(ctx, batch, processFunc)
+		if err == nil || attempt >= b.cfg.MaxRetries {
+			return err
+		}
+
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			return err
+		}
+
+		if b.cfg.RetryBudget != nil && !b.cfg.RetryBudget.take(len(batch)) {
+			batches, records := b.cfg.RetryBudget.Used()
+			return fmt.Errorf("%w after %d retried batches (%d records): %w",
+				ErrRetryBudgetExhausted, batches, records, err)
+		}
+
+		b.cfg.Logger.Warn("Retrying batch", "batch", id, "items", len(batch), "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,206 @@
+package bucket
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// Strategy assigns consumed items to workers
+// loads holds the number of items assigned to each worker that have not been
+// processed yet. Assign is only called from the dispatcher goroutine.
+type Strategy[T any] interface {
+	Assign(item T, loads []int64) int
+}
+
+// SetStrategy gives every worker its own queue and lets strategy decide which
+// worker each item is batched for
+// Without a strategy, batches go to whichever worker is idle.
+func (b *Bucket[T]) SetStrategy(strategy Strategy[T]) {
+	b.strategy = strategy
+}
+
+// RoundRobin assigns items to workers in turn
+func RoundRobin[T any]() Strategy[T] {
+	return &roundRobin[T]{}
+}
+
+type roundRobin[T any] struct {
+	next int
+}
+
+func (r *roundRobin[T]) Assign(item T, loads []int64) int {
+	worker := r.next % len(loads)
+	r.next = worker + 1
+	return worker
+}
+
+// LeastLoaded assigns each item to the worker with the fewest unprocessed
+// items
+func LeastLoaded[T any]() Strategy[T] {
+	return leastLoaded[T]{}
+}
+
+type leastLoaded[T any] struct{}
+
+func (leastLoaded[T]) Assign(item T, loads []int64) int {
+	return minLoad(loads)
+}
+
+// PartitionHash assigns items by a hash of their key, so items with the same
+// key always land in the same worker's batches
+func PartitionHash[T any](key func(T) string) Strategy[T] {
+	return partitionHash[T]{key: key}
+}
+
+type partitionHash[T any] struct {
+	key func(T) string
+}
+
+func (p partitionHash[T]) Assign(item T, loads []int64) int {
+	h := fnv.New32a()
+	h.Write([]byte(p.key(item)))
+	return int(h.Sum32() % uint32(len(loads)))
+}
+
+// Sticky sends the first item of each key to the least loaded worker and
+// keeps later items of that key on the same worker
+// The key to worker map grows with the number of distinct keys.
+func Sticky[T any](key func(T) string) Strategy[T] {
+	return &sticky[T]{key: key, assigned: make(map[string]int)}
+}
+
+type sticky[T any] struct {
+	key      func(T) string
+	assigned map[string]int
+}
+
+func (s *sticky[T]) Assign(item T, loads []int64) int {
+	k := s.key(item)
+	if worker, ok := s.assigned[k]; ok && worker < len(loads) {
+		return worker
+	}
+	worker := minLoad(loads)
+	s.assigned[k] = worker
+	return worker
+}
+
+func minLoad(loads []int64) int {
+	best := 0
+	for i, load := range loads {
+		if load < loads[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// lane is a batch queue feeding one or more workers
+type lane[T any] struct {
+	batches chan []T
+	queue   []T          // Partial batch being accumulated by the dispatcher
+	load    atomic.Int64 // Items assigned to the lane and not yet processed
+}
+
+// lanes routes items to batch queues: a single lane shared by all workers,
+// or one lane per worker when a Strategy is set
+type lanes[T any] struct {
+	lanes     []*lane[T]
+	strategy  Strategy[T]
+	batchSize int
+	loads     []int64
+	closeOnce sync.Once
+}
+
+func (b *Bucket[T]) newLanes() *lanes[T] {
+	n := 1
+	if b.strategy != nil {
+		n = b.cfg.WorkerNum
+	}
+
+	l := &lanes[T]{
+		lanes:     make([]*lane[T], n),
+		strategy:  b.strategy,
+		batchSize: b.cfg.BatchSize,
+		loads:     make([]int64, n),
+	}
+	for i := range l.lanes {
+		l.lanes[i] = &lane[T]{
+			batches: make(chan []T),
+			queue:   make([]T, 0, b.cfg.BatchSize),
+		}
+	}
+	return l
+}
+
+// forWorker returns the lane a worker reads from
+func (l *lanes[T]) forWorker(workerID int) *lane[T] {
+	return l.lanes[workerID%len(l.lanes)]
+}
+
+// add queues item on the lane chosen by the strategy and returns its index
+func (l *lanes[T]) add(item T) int {
+	i := 0
+	if l.strategy != nil {
+		for j, ln := range l.lanes {
+			l.loads[j] = ln.load.Load()
+		}
+		i = l.strategy.Assign(item, l.loads)
+	}
+
+	ln := l.lanes[i]
+	ln.queue = append(ln.queue, item)
+	ln.load.Add(1)
+	return i
+}
+
+// full reports whether a lane's partial batch reached the batch size
+func (l *lanes[T]) full(i int) bool {
+	return len(l.lanes[i].queue) >= l.batchSize
+}
+
+// send hands a lane's partial batch to its workers, reporting false if ctx
+// was cancelled before a worker became available
+func (l *lanes[T]) send(ctx context.Context, i int) bool {
+	ln := l.lanes[i]
+	if len(ln.queue) == 0 {
+		return true
+	}
+
+	select {
+	case ln.batches <- ln.queue:
+		ln.queue = make([]T, 0, l.batchSize)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sendAll flushes the partial batches of every lane
+func (l *lanes[T]) sendAll(ctx context.Context) bool {
+	for i := range l.lanes {
+		if !l.send(ctx, i) {
+			return false
+		}
+	}
+	return true
+}
+
+// pending counts items accumulated but not handed to a worker
+func (l *lanes[T]) pending() int {
+	n := 0
+	for _, ln := range l.lanes {
+		n += len(ln.queue)
+	}
+	return n
+}
+
+// close closes every lane so idle workers exit
+func (l *lanes[T]) close() {
+	l.closeOnce.Do(func() {
+		for _, ln := range l.lanes {
+			close(ln.batches)
+		}
+	})
+}
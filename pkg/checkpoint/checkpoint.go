@@ -0,0 +1,40 @@
+// Package checkpoint persists per-pipeline progress so runs can be resumed,
+// rewound or seeded from another environment
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a pipeline has no stored checkpoint
+var ErrNotFound = errors.New("checkpoint not found")
+
+// Checkpoint is the committed progress of one pipeline
+// Position is source specific (a resume token, an LSN, a watermark, ...)
+// and kept as JSON so operators can read and edit it.
+type Checkpoint struct {
+	Pipeline  string          `json:"pipeline"`
+	Position  json.RawMessage `json:"position"`
+	UpdatedAt time.Time       `json:"updated_at"`
+
+	// Version of the pipeline logic that wrote the checkpoint, see Versioned
+	Version string `json:"version,omitempty"`
+}
+
+// Store reads and writes checkpoints
+type Store interface {
+	// Get returns the checkpoint of a pipeline, or ErrNotFound
+	Get(ctx context.Context, pipeline string) (*Checkpoint, error)
+
+	// Set stores the checkpoint of cp.Pipeline, replacing any previous one
+	Set(ctx context.Context, cp *Checkpoint) error
+
+	// Delete removes the checkpoint of a pipeline
+	Delete(ctx context.Context, pipeline string) error
+
+	// List returns the names of all pipelines with a checkpoint
+	List(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,93 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Format is a checkpoint export encoding
+type Format string
+
+const (
+	FormatJSON Format = "json" // Human-readable, suitable for manual edits
+	FormatGob  Format = "gob"  // Compact Go binary encoding
+)
+
+// Export reads the checkpoints of the given pipelines (all pipelines when
+// none are given) from store and writes them to w
+func Export(ctx context.Context, store Store, w io.Writer, format Format, pipelines ...string) error {
+	if len(pipelines) == 0 {
+		var err error
+		if pipelines, err = store.List(ctx); err != nil {
+			return err
+		}
+	}
+
+	checkpoints := make([]Checkpoint, 0, len(pipelines))
+	for _, pipeline := range pipelines {
+		cp, err := store.Get(ctx, pipeline)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", pipeline, err)
+		}
+		checkpoints = append(checkpoints, *cp)
+	}
+
+	return Encode(w, format, checkpoints)
+}
+
+// Import decodes checkpoints from r and writes them to store, replacing
+// existing checkpoints of the same pipelines
+func Import(ctx context.Context, store Store, r io.Reader, format Format) ([]Checkpoint, error) {
+	checkpoints, err := Decode(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range checkpoints {
+		if checkpoints[i].Pipeline == "" {
+			return nil, errors.New("import: checkpoint without pipeline name")
+		}
+	}
+	for i := range checkpoints {
+		if err := store.Set(ctx, &checkpoints[i]); err != nil {
+			return nil, fmt.Errorf("import %s: %w", checkpoints[i].Pipeline, err)
+		}
+	}
+	return checkpoints, nil
+}
+
+// Encode writes checkpoints to w in the given format
+func Encode(w io.Writer, format Format, checkpoints []Checkpoint) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(checkpoints)
+	case FormatGob:
+		return gob.NewEncoder(w).Encode(checkpoints)
+	default:
+		return fmt.Errorf("unknown checkpoint format %q", format)
+	}
+}
+
+// Decode reads checkpoints from r in the given format
+func Decode(r io.Reader, format Format) ([]Checkpoint, error) {
+	var checkpoints []Checkpoint
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&checkpoints); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON checkpoints: %w", err)
+		}
+	case FormatGob:
+		if err := gob.NewDecoder(r).Decode(&checkpoints); err != nil {
+			return nil, fmt.Errorf("failed to decode gob checkpoints: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown checkpoint format %q", format)
+	}
+	return checkpoints, nil
+}
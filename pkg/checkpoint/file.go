@@ -0,0 +1,109 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileStore keeps one JSON file per pipeline in a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a file store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Get reads the checkpoint of a pipeline
+func (s *FileStore) Get(ctx context.Context, pipeline string) (*Checkpoint, error) {
+	data, err := os.ReadFile(s.path(pipeline))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", pipeline, err)
+	}
+	return &cp, nil
+}
+
+// Set writes the checkpoint atomically via a temporary file and rename
+func (s *FileStore) Set(ctx context.Context, cp *Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(cp.Pipeline)); err != nil {
+		return fmt.Errorf("failed to commit checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the checkpoint file of a pipeline
+func (s *FileStore) Delete(ctx context.Context, pipeline string) error {
+	err := os.Remove(s.path(pipeline))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// List returns the pipelines with a checkpoint file, sorted by name
+func (s *FileStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if pipeline, err := url.PathUnescape(name); err == nil {
+			names = append(names, pipeline)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// path returns the file of a pipeline, escaping names that contain slashes
+func (s *FileStore) path(pipeline string) string {
+	return filepath.Join(s.dir, url.PathEscape(pipeline)+".json")
+}
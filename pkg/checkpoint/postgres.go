@@ -0,0 +1,105 @@
+package checkpoint
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PostgresStore keeps checkpoints in a PostgreSQL table, one row per
+// pipeline
+type PostgresStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresStore creates a store backed by table (defaults to
+// etl_checkpoints), which is created by Init if it does not exist
+func NewPostgresStore(db *sql.DB, table string) *PostgresStore {
+	if table == "" {
+		table = "etl_checkpoints"
+	}
+	return &PostgresStore{
+		db:    db,
+		table: `"` + strings.ReplaceAll(table, `"`, `""`) + `"`,
+	}
+}
+
+// Init creates the checkpoint table
+func (s *PostgresStore) Init(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.table+` (
+		pipeline   TEXT PRIMARY KEY,
+		position   JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		version    TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint table: %w", err)
+	}
+	// Tables created before checkpoints were versioned
+	_, err = s.db.ExecContext(ctx, `ALTER TABLE `+s.table+` ADD COLUMN IF NOT EXISTS version TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to migrate checkpoint table: %w", err)
+	}
+	return nil
+}
+
+// Get reads the checkpoint row of a pipeline
+func (s *PostgresStore) Get(ctx context.Context, pipeline string) (*Checkpoint, error) {
+	cp := Checkpoint{Pipeline: pipeline}
+	var position []byte
+	err := s.db.QueryRowContext(ctx, `SELECT position, updated_at, version FROM `+s.table+` WHERE pipeline = $1`, pipeline).
+		Scan(&position, &cp.UpdatedAt, &cp.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	cp.Position = position
+	return &cp, nil
+}
+
+// Set upserts the checkpoint row of cp.Pipeline
+func (s *PostgresStore) Set(ctx context.Context, cp *Checkpoint) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO `+s.table+` (pipeline, position, updated_at, version)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (pipeline) DO UPDATE SET position = EXCLUDED.position, updated_at = EXCLUDED.updated_at, version = EXCLUDED.version`,
+		cp.Pipeline, string(cp.Position), cp.UpdatedAt, cp.Version)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the checkpoint row of a pipeline
+func (s *PostgresStore) Delete(ctx context.Context, pipeline string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE pipeline = $1`, pipeline); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// List returns the pipelines with a checkpoint row, sorted by name
+func (s *PostgresStore) List(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT pipeline FROM `+s.table+` ORDER BY pipeline`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	return names, nil
+}
@@ -0,0 +1,75 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps checkpoints as fields of one Redis hash
+// Enable AOF or RDB persistence on the server, otherwise a restart of Redis
+// loses every checkpoint.
+type RedisStore struct {
+	client redis.Cmdable
+	key    string
+}
+
+// NewRedisStore creates a store in the hash at key (defaults to
+// etl:checkpoints)
+func NewRedisStore(client redis.Cmdable, key string) *RedisStore {
+	if key == "" {
+		key = "etl:checkpoints"
+	}
+	return &RedisStore{client: client, key: key}
+}
+
+// Get reads the checkpoint field of a pipeline
+func (s *RedisStore) Get(ctx context.Context, pipeline string) (*Checkpoint, error) {
+	data, err := s.client.HGet(ctx, s.key, pipeline).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint %s: %w", pipeline, err)
+	}
+	return &cp, nil
+}
+
+// Set writes the checkpoint field of cp.Pipeline
+func (s *RedisStore) Set(ctx context.Context, cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.key, cp.Pipeline, data).Err(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the checkpoint field of a pipeline
+func (s *RedisStore) Delete(ctx context.Context, pipeline string) error {
+	if err := s.client.HDel(ctx, s.key, pipeline).Err(); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// List returns the pipelines with a checkpoint field, sorted by name
+func (s *RedisStore) List(ctx context.Context) ([]string, error) {
+	names, err := s.client.HKeys(ctx, s.key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
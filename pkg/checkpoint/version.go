@@ -0,0 +1,77 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionMismatch is matched by a VersionMismatchError
+var ErrVersionMismatch = errors.New("checkpoint version mismatch")
+
+// VersionMismatchError reports a checkpoint written by another version of
+// the pipeline logic
+type VersionMismatchError struct {
+	Pipeline string
+	Stored   string
+	Current  string
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("checkpoint of %s was written by version %q, not %q; reset it to start over",
+		e.Pipeline, e.Stored, e.Current)
+}
+
+func (e *VersionMismatchError) Is(target error) bool {
+	return target == ErrVersionMismatch
+}
+
+// OnVersionChange decides what a versioned store does with a checkpoint
+// written by another version
+type OnVersionChange int
+
+const (
+	// RefuseOnVersionChange fails Get with a *VersionMismatchError, so the
+	// pipeline stops until the checkpoint is reset or the version restored
+	RefuseOnVersionChange OnVersionChange = iota
+
+	// ResetOnVersionChange ignores the checkpoint, so the pipeline starts
+	// over and its first Set replaces it
+	ResetOnVersionChange
+)
+
+// Versioned wraps store so that checkpoints are written with version and
+// only read back by the same version
+// Bump version whenever the pipeline logic changes incompatibly, e.g. a
+// transform that now writes other keys or units, to keep a resumed run from
+// mixing old and new semantics in the destination. Checkpoints written
+// without a version are accepted, so existing pipelines can adopt
+// versioning without starting over.
+func Versioned(store Store, version string, onChange OnVersionChange) Store {
+	return &versionedStore{Store: store, version: version, onChange: onChange}
+}
+
+type versionedStore struct {
+	Store
+	version  string
+	onChange OnVersionChange
+}
+
+// Get returns the checkpoint of a pipeline if this version wrote it
+func (s *versionedStore) Get(ctx context.Context, pipeline string) (*Checkpoint, error) {
+	cp, err := s.Store.Get(ctx, pipeline)
+	if err != nil || cp.Version == "" || cp.Version == s.version {
+		return cp, err
+	}
+	if s.onChange == ResetOnVersionChange {
+		return nil, ErrNotFound
+	}
+	return nil, &VersionMismatchError{Pipeline: pipeline, Stored: cp.Version, Current: s.version}
+}
+
+// Set writes cp stamped with the version
+func (s *versionedStore) Set(ctx context.Context, cp *Checkpoint) error {
+	versioned := *cp
+	versioned.Version = s.version
+	return s.Store.Set(ctx, &versioned)
+}
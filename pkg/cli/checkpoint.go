@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// runCheckpoint dispatches the checkpoint subcommands:
+//
+//	go-etl checkpoint export  STORE [-format json|gob] [-o FILE] [pipeline...]
+//	go-etl checkpoint inspect STORE [pipeline...]
+//	go-etl checkpoint import  STORE [-format json|gob] [-i FILE]
+//	go-etl checkpoint reset   STORE pipeline...
+//
+// where STORE is -dir DIR (the default), -postgres DSN or -redis ADDR.
+func runCheckpoint(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected export, inspect, import or reset", errUsage)
+	}
+
+	fs := flag.NewFlagSet("checkpoint "+args[0], flag.ContinueOnError)
+	dir := fs.String("dir", "checkpoints", "checkpoint store directory")
+	postgres := fs.String("postgres", "", "PostgreSQL connection string of a checkpoint table, instead of -dir")
+	redisAddr := fs.String("redis", "", "Redis address of a checkpoint hash, instead of -dir")
+	format := fs.String("format", string(checkpoint.FormatJSON), "encoding: json or gob")
+	output := fs.String("o", "-", "export destination file, - for stdout")
+	input := fs.String("i", "-", "import source file, - for stdin")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	store, closeStore, err := openStore(ctx, *dir, *postgres, *redisAddr)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	switch args[0] {
+	case "export":
+		w := io.Writer(os.Stdout)
+		if *output != "-" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return checkpoint.Export(ctx, store, w, checkpoint.Format(*format), fs.Args()...)
+
+	case "inspect":
+		return inspectCheckpoints(ctx, store, fs.Args())
+
+	case "import":
+		r := io.Reader(os.Stdin)
+		if *input != "-" {
+			f, err := os.Open(*input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		imported, err := checkpoint.Import(ctx, store, r, checkpoint.Format(*format))
+		if err != nil {
+			return err
+		}
+		for _, cp := range imported {
+			fmt.Printf("✓ Imported checkpoint for %s\n", cp.Pipeline)
+		}
+		return nil
+
+	case "reset":
+		if fs.NArg() == 0 {
+			return fmt.Errorf("%w: checkpoint reset expects at least one pipeline", errUsage)
+		}
+		for _, pipeline := range fs.Args() {
+			if err := store.Delete(ctx, pipeline); err != nil {
+				return fmt.Errorf("%s: %w", pipeline, err)
+			}
+			fmt.Printf("✓ Reset checkpoint of %s\n", pipeline)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown checkpoint command %q", errUsage, args[0])
+	}
+}
+
+// inspectCheckpoints prints a human-readable summary of stored checkpoints
+func inspectCheckpoints(ctx context.Context, store checkpoint.Store, pipelines []string) error {
+	if len(pipelines) == 0 {
+		var err error
+		if pipelines, err = store.List(ctx); err != nil {
+			return err
+		}
+	}
+	if len(pipelines) == 0 {
+		fmt.Println("No checkpoints stored")
+		return nil
+	}
+
+	for _, pipeline := range pipelines {
+		cp, err := store.Get(ctx, pipeline)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pipeline, err)
+		}
+
+		position := string(cp.Position)
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, cp.Position, "    ", "  "); err == nil {
+			position = indented.String()
+		}
+
+		fmt.Printf("%s\n", cp.Pipeline)
+		fmt.Printf("  - Updated: %s\n", cp.UpdatedAt.Format("2006-01-02 15:04:05 MST"))
+		if cp.Version != "" {
+			fmt.Printf("  - Version: %s\n", cp.Version)
+		}
+		fmt.Printf("  - Position: %s\n", position)
+	}
+	return nil
+}
+
+// openStore opens the checkpoint store selected by the flags, returning a
+// function that releases its connections
+func openStore(ctx context.Context, dir, postgres, redisAddr string) (checkpoint.Store, func(), error) {
+	switch {
+	case postgres != "" && redisAddr != "":
+		return nil, nil, fmt.Errorf("%w: -postgres and -redis are mutually exclusive", errUsage)
+
+	case postgres != "":
+		db, err := sql.Open("pgx", postgres)
+		if err != nil {
+			return nil, nil, err
+		}
+		store := checkpoint.NewPostgresStore(db, "")
+		if err := store.Init(ctx); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return store, func() { db.Close() }, nil
+
+	case redisAddr != "":
+		client := redis.NewClient(&redis.Options{Addr: redisAddr})
+		return checkpoint.NewRedisStore(client, ""), func() { client.Close() }, nil
+
+	default:
+		store, err := checkpoint.NewFileStore(dir)
+		return store, func() {}, err
+	}
+}
@@ -0,0 +1,129 @@
+// Package cli implements the go-etl command, which operates pipelines and
+// their state
+// Programs that register their own connectors, e.g. by importing modules
+// that call connector.RegisterSource in init, ship the command with Main:
+//
+//	import (
+//		_ "example.com/etl/connectors"
+//
+//		"github.com/cuong/go-etl/pkg/cli"
+//	)
+//
+//	func main() { cli.Main() }
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/cuong/go-etl/pkg/connector/builtin"
+)
+
+// Exit codes
+const (
+	exitOK          = 0
+	exitFailed      = 1   // The command or a pipeline failed
+	exitUsage       = 2   // Bad command line
+	exitInvalid     = 3   // validate found problems
+	exitInterrupted = 130 // Stopped by SIGINT or SIGTERM
+)
+
+// errUsage marks command line errors
+var errUsage = errors.New("usage")
+
+// errInvalid marks configurations that failed validation
+var errInvalid = errors.New("invalid configuration")
+
+// command is a go-etl subcommand
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "run", usage: "run the pipelines of a config file, once or on their schedules", run: runRun},
+	{name: "resume", usage: "run pipelines from their checkpoints", run: runResume},
+	{name: "validate", usage: "check a config file, its connectivity, tables and fields without moving data", run: runValidate},
+	{name: "list", usage: "list the pipelines of a config file and the connector types", run: runList},
+	{name: "profile", usage: "compute per-field statistics of the records a pipeline extracts", run: runProfile},
+	{name: "status", usage: "show the outcome of the last run from its report", run: runStatus},
+	{name: "replay-dlq", usage: "reload dead-lettered records of a pipeline", run: runReplayDLQ},
+	{name: "checkpoint", usage: "export, inspect, import or reset pipeline checkpoints", run: runCheckpoint},
+	{name: "dlq", usage: "list, inspect or purge dead-lettered records", run: runDLQ},
+	{name: "init", usage: "generate a new pipeline program for a source and sink", run: runInit},
+}
+
+// Main runs the command named by os.Args[1] and exits
+func Main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(exitUsage)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// The first signal stops pipelines gracefully; restoring the default
+	// handling lets a second one kill the process
+	go func() {
+		<-ctx.Done()
+		cancel()
+		fmt.Fprintln(os.Stderr, "Interrupted, shutting down (interrupt again to force)")
+	}()
+
+	name, args := os.Args[1], os.Args[2:]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(ctx, args)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "go-etl %s: %v\n", name, err)
+		}
+		os.Exit(exitCode(ctx, err))
+	}
+
+	fmt.Fprintf(os.Stderr, "go-etl: unknown command %q\n", name)
+	printUsage()
+	os.Exit(exitUsage)
+}
+
+// exitCode maps the result of a command to the exit status
+func exitCode(ctx context.Context, err error) int {
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, errInvalid):
+		return exitInvalid
+	}
+	return exitFailed
+}
+
+// parseFlags parses args into fs, marking errors as usage errors
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	return nil
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: go-etl <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.usage)
+	}
+}
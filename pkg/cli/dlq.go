@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/dlq"
+)
+
+// runDLQ dispatches the dead letter subcommands:
+//
+//	go-etl dlq list    [-dir DIR]
+//	go-etl dlq inspect [-dir DIR] [FILTER] PIPELINE
+//	go-etl dlq purge   [-dir DIR] [FILTER] PIPELINE
+//
+// where FILTER is any of -id ID, -since TIME, -until TIME (RFC 3339),
+// -error TEXT, -replayed and -limit N. Records are replayed by the program
+// that defines the pipeline, with Manager.ReplayDLQ.
+func runDLQ(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected list, inspect or purge", errUsage)
+	}
+
+	fs := flag.NewFlagSet("dlq "+args[0], flag.ContinueOnError)
+	dir := fs.String("dir", "dead-letters", "dead letter store directory")
+	var ids stringList
+	fs.Var(&ids, "id", "entry ID, repeatable")
+	since := fs.String("since", "", "only entries dead-lettered at or after this time")
+	until := fs.String("until", "", "only entries dead-lettered before this time")
+	errorText := fs.String("error", "", "only entries whose error contains this text")
+	replayed := fs.Bool("replayed", false, "include entries already replayed")
+	limit := fs.Int("limit", 0, "maximum entries, 0 for all")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	store, err := dlq.NewFileStore(*dir)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "list" {
+		return listDeadLetters(ctx, store)
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: dlq %s expects one pipeline", errUsage, args[0])
+	}
+	pipeline := fs.Arg(0)
+	filter := dlq.Filter{IDs: ids, ErrorContains: *errorText, Replayed: *replayed, Limit: *limit}
+	if filter.Since, err = parseTime(*since); err != nil {
+		return fmt.Errorf("%w: -since: %w", errUsage, err)
+	}
+	if filter.Until, err = parseTime(*until); err != nil {
+		return fmt.Errorf("%w: -until: %w", errUsage, err)
+	}
+
+	entries, err := store.List(ctx, pipeline, filter)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "inspect":
+		if len(entries) == 0 {
+			fmt.Println("No dead letters selected")
+			return nil
+		}
+		for _, e := range entries {
+			fmt.Printf("%s\n", e.ID)
+			fmt.Printf("  - Dead-lettered: %s\n", e.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+			if e.ReplayedAt != nil {
+				fmt.Printf("  - Replayed: %s\n", e.ReplayedAt.Format("2006-01-02 15:04:05 MST"))
+			}
+			fmt.Printf("  - Error: %s\n", e.Error)
+			fmt.Printf("  - Record: %s\n", e.Record)
+		}
+		return nil
+
+	case "purge":
+		ids := make([]string, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID
+		}
+		if err := store.Delete(ctx, pipeline, ids); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Purged %d dead letters of %s\n", len(ids), pipeline)
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown dlq command %q", errUsage, args[0])
+	}
+}
+
+// listDeadLetters prints the pending and replayed entries per pipeline
+func listDeadLetters(ctx context.Context, store dlq.Store) error {
+	pipelines, err := store.Pipelines(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pipelines) == 0 {
+		fmt.Println("No dead letters stored")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PIPELINE\tPENDING\tREPLAYED\tOLDEST PENDING")
+	for _, pipeline := range pipelines {
+		entries, err := store.List(ctx, pipeline, dlq.Filter{Replayed: true})
+		if err != nil {
+			return fmt.Errorf("%s: %w", pipeline, err)
+		}
+
+		var (
+			pending, replayed int
+			oldest            time.Time
+		)
+		for _, e := range entries {
+			if e.ReplayedAt != nil {
+				replayed++
+				continue
+			}
+			pending++
+			if oldest.IsZero() || e.CreatedAt.Before(oldest) {
+				oldest = e.CreatedAt
+			}
+		}
+
+		age := "-"
+		if !oldest.IsZero() {
+			age = oldest.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", pipeline, pending, replayed, age)
+	}
+	return w.Flush()
+}
+
+// parseTime parses an RFC 3339 time, returning the zero time for ""
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
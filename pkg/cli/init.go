@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/cuong/go-etl/pkg/scaffold"
+)
+
+// runInit generates a new pipeline program:
+//
+//	go-etl init [-source TYPE] [-sink TYPE] [-name NAME] [-module PATH] [-force] DIR
+//
+// DIR gets a processor with typed Extract, Transform and Load stubs for the
+// source and sink, a main package running it, its config.yaml and tests.
+func runInit(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	source := fs.String("source", "csv", "source type: "+strings.Join(scaffold.Sources(), ", "))
+	sink := fs.String("sink", "stdout", "sink type: "+strings.Join(scaffold.Sinks(), ", "))
+	name := fs.String("name", "", "pipeline name, defaults to the name of DIR")
+	module := fs.String("module", "", "write a go.mod for this module path, for use outside an existing module")
+	force := fs.Bool("force", false, "overwrite existing files")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: init expects one directory", errUsage)
+	}
+
+	files, err := scaffold.Generate(scaffold.Options{
+		Dir:    fs.Arg(0),
+		Name:   *name,
+		Source: *source,
+		Sink:   *sink,
+		Module: *module,
+		Force:  *force,
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Println("created", f)
+	}
+	tidy := ""
+	if *module != "" {
+		tidy = "go mod tidy && "
+	}
+	fmt.Printf("\nNext: cd %s && %sgo test . && go run .\n", fs.Arg(0), tidy)
+	return nil
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cuong/go-etl/pkg/config"
+	"github.com/cuong/go-etl/pkg/connector"
+)
+
+// runList prints the pipelines of a config file:
+//
+//	go-etl list [-config FILE|DIR] [-profile NAME]
+//	go-etl list -connectors [-plugin FILE]...
+func runList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	path := fs.String("config", "go-etl.yaml", "pipeline definition file, or directory of them")
+	profile := fs.String("profile", "", "profile of the definitions to apply, e.g. prod")
+	connectors := fs.Bool("connectors", false, "list the registered connector types instead")
+	var plugins stringList
+	fs.Var(&plugins, "plugin", "Go plugin registering connectors, repeatable")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadPlugins(plugins); err != nil {
+		return err
+	}
+
+	if *connectors {
+		fmt.Printf("Sources:    %s\n", strings.Join(connector.DefaultRegistry.Sources(), ", "))
+		fmt.Printf("Sinks:      %s\n", strings.Join(connector.DefaultRegistry.Sinks(), ", "))
+		fmt.Printf("Transforms: %s\n", strings.Join(connector.DefaultRegistry.Transforms(), ", "))
+		return nil
+	}
+
+	file, err := (&config.Loader{Profile: *profile}).LoadPath(ctx, *path)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PIPELINE\tSOURCE\tSINK\tSCHEDULE\tDEPENDS ON")
+	for _, p := range file.Pipelines {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Source.Type, p.Sink.Type,
+			orDash(p.Schedule), orDash(strings.Join(p.DependsOn, ", ")))
+	}
+	return w.Flush()
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
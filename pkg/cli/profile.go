@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cuong/go-etl/pkg/config"
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/profiler"
+)
+
+// runProfile profiles the records the source of a pipeline extracts,
+// before any mapping, to help write the mappings and transforms:
+//
+//	go-etl profile [-config FILE|DIR] [-profile NAME] [-pipeline NAME] [-limit N] [-top N] [-json] [-plugin FILE]...
+//
+// The pipeline may be omitted when the config defines only one. Nothing is
+// loaded, and the source is not resumed from its checkpoint.
+func runProfile(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("profile", flag.ContinueOnError)
+	path := fs.String("config", "go-etl.yaml", "pipeline definition file, or directory of them")
+	profile := fs.String("profile", "", "profile of the definitions to apply, e.g. prod")
+	pipeline := fs.String("pipeline", "", "pipeline whose source to profile")
+	limit := fs.Int64("limit", 10000, "records to profile, 0 for all")
+	top := fs.Int("top", 5, "most frequent values to show per field")
+	asJSON := fs.Bool("json", false, "print the profile as JSON")
+	var plugins stringList
+	fs.Var(&plugins, "plugin", "Go plugin registering connectors, repeatable")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadPlugins(plugins); err != nil {
+		return err
+	}
+
+	file, err := (&config.Loader{Profile: *profile}).LoadPath(ctx, *path)
+	if err != nil {
+		return err
+	}
+	if *pipeline == "" {
+		if len(file.Pipelines) != 1 {
+			return fmt.Errorf("%w: -pipeline is required with %d pipelines", errUsage, len(file.Pipelines))
+		}
+		*pipeline = file.Pipelines[0].Name
+	}
+
+	src, closer, err := file.Source(ctx, connector.DefaultRegistry, *pipeline)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	// Stop the source once enough records were read
+	extractCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	records, err := src.Extract(extractCtx)
+	if err != nil {
+		return err
+	}
+	p := profiler.New(profiler.Config{TopValues: *top})
+	if err := profiler.Records(ctx, records, p, *limit); err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p.Profile())
+	}
+	return p.Profile().WriteText(os.Stdout)
+}
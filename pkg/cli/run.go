@@ -0,0 +1,330 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/config"
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/dlq"
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/quality"
+	"github.com/cuong/go-etl/pkg/report"
+)
+
+// defaultReport is where run and resume write their report, for status
+const defaultReport = "go-etl-report.json"
+
+// pipelineFlags select a config file and the stores of its pipelines
+type pipelineFlags struct {
+	config      string
+	profile     string
+	checkpoints string
+	deadLetters string
+	report      string
+	failFast    bool
+	plugins     stringList
+}
+
+// addPipelineFlags defines the pipeline flags on fs, with the store and
+// report defaults of the command
+func addPipelineFlags(fs *flag.FlagSet, defaults pipelineFlags) *pipelineFlags {
+	f := &pipelineFlags{}
+	fs.StringVar(&f.config, "config", "go-etl.yaml", "pipeline definition file, or directory of them")
+	fs.StringVar(&f.profile, "profile", "", "profile of the definitions to apply, e.g. prod")
+	fs.StringVar(&f.checkpoints, "checkpoints", defaults.checkpoints, "checkpoint store directory; resumable sources continue from it")
+	fs.StringVar(&f.deadLetters, "dlq", defaults.deadLetters, "dead letter store directory")
+	fs.StringVar(&f.report, "report", defaults.report, "run report file, empty for none")
+	fs.BoolVar(&f.failFast, "fail-fast", false, "cancel the other pipelines when one fails")
+	fs.Var(&f.plugins, "plugin", "Go plugin registering connectors, repeatable")
+	return f
+}
+
+// pipelines is a manager built from a config file
+type pipelines struct {
+	file    *config.File
+	manager *etl.Manager
+	quality *quality.Collector // Data quality results, for the report
+	close   func()
+}
+
+// open loads the config file and adds its pipelines to a new manager
+func (f *pipelineFlags) open(ctx context.Context) (*pipelines, error) {
+	p, err := f.openManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	closer, err := p.file.Build(quality.NewContext(ctx, p.quality), p.manager, connector.DefaultRegistry)
+	if err != nil {
+		return nil, err
+	}
+	p.close = func() { closer.Close() }
+	return p, nil
+}
+
+// loader returns the loader of the config file
+func (f *pipelineFlags) loader() *config.Loader {
+	return &config.Loader{Profile: f.profile}
+}
+
+// openManager loads the config file and creates a manager for its
+// pipelines, without adding them
+func (f *pipelineFlags) openManager(ctx context.Context) (*pipelines, error) {
+	if err := loadPlugins(f.plugins); err != nil {
+		return nil, err
+	}
+	file, err := f.loader().LoadPath(ctx, f.config)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := file.ManagerConfig()
+	if f.failFast {
+		cfg.ErrorPolicy = etl.FailFast
+	}
+	if f.checkpoints != "" {
+		if cfg.Checkpoints, err = checkpoint.NewFileStore(f.checkpoints); err != nil {
+			return nil, err
+		}
+	}
+	if f.deadLetters != "" {
+		if cfg.DeadLetters, err = dlq.NewFileStore(f.deadLetters); err != nil {
+			return nil, err
+		}
+	}
+
+	m := etl.NewManager(cfg, file.BucketConfig())
+	collector := quality.NewCollector()
+	if f.report != "" {
+		err := report.Attach(m, report.Config{
+			Path:        f.report,
+			Checkpoints: cfg.Checkpoints,
+			DeadLetters: cfg.DeadLetters,
+			Quality:     collector,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &pipelines{file: file, manager: m, quality: collector, close: func() {}}, nil
+}
+
+// runRun runs pipelines:
+//
+//	go-etl run [-config FILE|DIR] [-profile NAME] [-checkpoints DIR] [-dlq DIR] [-report FILE] [-fail-fast] [-schedule [-watch]] [pipeline...]
+//	go-etl run [-config FILE|DIR] [-profile NAME] [-checkpoints DIR] [-dlq DIR] [-report FILE] [-fail-fast] [-since TIME] [-until TIME] pipeline...
+//
+// Without pipeline names every pipeline runs, in dependency order. With
+// -schedule, scheduled pipelines run on their schedules until interrupted;
+// -watch then applies changes to the definitions as they are saved. With
+// -since or -until, the named pipelines backfill that window, leaving their
+// stored watermarks untouched.
+func runRun(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	pf := addPipelineFlags(fs, pipelineFlags{report: defaultReport})
+	schedule := fs.Bool("schedule", false, "run pipelines on their schedules until interrupted")
+	watch := fs.Bool("watch", false, "with -schedule, add, update and remove pipelines as the definitions change")
+	window := checkpoint.AddWindowFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	w, err := window()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if *watch && !*schedule {
+		return fmt.Errorf("%w: -watch requires -schedule", errUsage)
+	}
+	if !w.IsZero() {
+		if *schedule {
+			return fmt.Errorf("%w: -since and -until cannot be combined with -schedule", errUsage)
+		}
+		if fs.NArg() == 0 {
+			return fmt.Errorf("%w: -since and -until backfill the named pipelines", errUsage)
+		}
+	}
+	if *watch {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("%w: -schedule runs every scheduled pipeline", errUsage)
+		}
+		return serveWatched(ctx, pf)
+	}
+
+	p, err := pf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	if *schedule {
+		if fs.NArg() > 0 {
+			return fmt.Errorf("%w: -schedule runs every scheduled pipeline", errUsage)
+		}
+		return p.manager.RunScheduler(ctx)
+	}
+	if !w.IsZero() {
+		ctx = checkpoint.WithWindow(ctx, w)
+	}
+	return runPipelines(ctx, p.manager, fs.Args())
+}
+
+// serveWatched serves the scheduled pipelines of the config until ctx is
+// cancelled, applying changes to their definitions without a restart
+func serveWatched(ctx context.Context, pf *pipelineFlags) error {
+	p, err := pf.openManager(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	ctx = quality.NewContext(ctx, p.quality)
+	r := &config.Reloader{Manager: p.manager, Path: pf.config, Loader: pf.loader()}
+	if err := r.Apply(ctx, p.file); err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// Stop serving if the definitions cannot be watched
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		defer cancel()
+		errc <- r.Watch(ctx)
+	}()
+
+	serveErr := p.manager.Serve(ctx)
+	if err := <-errc; err != nil {
+		return err
+	}
+	return serveErr
+}
+
+// runResume runs pipelines from their checkpoints:
+//
+//	go-etl resume [-config FILE|DIR] [-profile NAME] [-checkpoints DIR] [-dlq DIR] [-report FILE] [-fail-fast] [pipeline...]
+//
+// Pipelines whose source cannot resume start from the beginning.
+func runResume(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ContinueOnError)
+	pf := addPipelineFlags(fs, pipelineFlags{checkpoints: "checkpoints", report: defaultReport})
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if pf.checkpoints == "" {
+		return fmt.Errorf("%w: resume requires -checkpoints", errUsage)
+	}
+
+	p, err := pf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	store, err := checkpoint.NewFileStore(pf.checkpoints)
+	if err != nil {
+		return err
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		for _, pl := range p.file.Pipelines {
+			names = append(names, pl.Name)
+		}
+	}
+	for _, name := range names {
+		cp, err := store.Get(ctx, name)
+		switch {
+		case errors.Is(err, checkpoint.ErrNotFound):
+			fmt.Printf("%s: no checkpoint, starting from the beginning\n", name)
+		case err != nil:
+			return fmt.Errorf("%s: %w", name, err)
+		default:
+			fmt.Printf("%s: resuming from %s (saved %s)\n", name, cp.Position, cp.UpdatedAt.Format("2006-01-02 15:04:05 MST"))
+		}
+	}
+	return runPipelines(ctx, p.manager, fs.Args())
+}
+
+// runPipelines runs the named pipelines, or all, and prints their outcome
+func runPipelines(ctx context.Context, m *etl.Manager, names []string) error {
+	var err error
+	if len(names) == 0 {
+		err = m.RunAll(ctx)
+	} else {
+		err = m.Run(ctx, names...)
+	}
+
+	metrics := m.Metrics()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PIPELINE\tSTATE\tATTEMPTS\tEXTRACTED\tLOADED\tDURATION")
+	for _, pm := range metrics.Pipelines {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\n", pm.Name, pm.State, pm.Attempts, pm.Extracted, pm.Loaded, pm.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+	return err
+}
+
+// runReplayDLQ reloads dead-lettered records of a pipeline:
+//
+//	go-etl replay-dlq [-config FILE|DIR] [-profile NAME] [-dlq DIR] [FILTER] PIPELINE
+//
+// where FILTER is any of -id ID, -since TIME, -until TIME (RFC 3339),
+// -error TEXT and -limit N, as for go-etl dlq.
+func runReplayDLQ(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay-dlq", flag.ContinueOnError)
+	pf := addPipelineFlags(fs, pipelineFlags{deadLetters: "dead-letters"})
+	var ids stringList
+	fs.Var(&ids, "id", "entry ID, repeatable")
+	since := fs.String("since", "", "only entries dead-lettered at or after this time")
+	until := fs.String("until", "", "only entries dead-lettered before this time")
+	errorText := fs.String("error", "", "only entries whose error contains this text")
+	limit := fs.Int("limit", 0, "maximum entries, 0 for all")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: replay-dlq expects one pipeline", errUsage)
+	}
+	if pf.deadLetters == "" {
+		return fmt.Errorf("%w: replay-dlq requires -dlq", errUsage)
+	}
+
+	filter := dlq.Filter{IDs: ids, ErrorContains: *errorText, Limit: *limit}
+	var err error
+	if filter.Since, err = parseTime(*since); err != nil {
+		return fmt.Errorf("%w: -since: %w", errUsage, err)
+	}
+	if filter.Until, err = parseTime(*until); err != nil {
+		return fmt.Errorf("%w: -until: %w", errUsage, err)
+	}
+
+	p, err := pf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	result, err := p.manager.ReplayDLQ(ctx, fs.Arg(0), filter)
+	fmt.Printf("Replayed %d dead letters of %s, %d failed again\n", result.Replayed, fs.Arg(0), result.Failed)
+	if err == nil && result.Failed > 0 {
+		err = fmt.Errorf("%d records failed again", result.Failed)
+	}
+	return err
+}
+
+// loadPlugins loads Go plugins into the default connector registry
+func loadPlugins(paths []string) error {
+	for _, path := range paths {
+		if err := connector.LoadPlugin(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/report"
+)
+
+// runStatus prints the outcome of the last run or resume from its report:
+//
+//	go-etl status [-report FILE]
+//
+// It exits with status 1 when the run failed.
+func runStatus(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	path := fs.String("report", defaultReport, "run report file")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	rep, err := report.Read(*path)
+	if err != nil {
+		return err
+	}
+
+	outcome := "succeeded"
+	if !rep.Succeeded {
+		outcome = "failed"
+	}
+	took := time.Duration(rep.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+	fmt.Printf("Run %s at %s after %s\n", outcome, rep.FinishedAt.Format("2006-01-02 15:04:05 MST"), took)
+	fmt.Printf("%d of %d pipelines succeeded, %d records loaded, %d dead-lettered\n\n",
+		rep.Totals.Succeeded, rep.Totals.Pipelines, rep.Totals.Loaded, rep.Totals.DeadLettered)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PIPELINE\tSTATE\tATTEMPTS\tEXTRACTED\tLOADED\tDEAD-LETTERED\tLAST ERROR")
+	for _, p := range rep.Pipelines {
+		lastErr := "-"
+		if len(p.Errors) > 0 {
+			lastErr = p.Errors[len(p.Errors)-1]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%s\n", p.Name, p.State, p.Attempts, p.Extracted, p.Loaded, p.DeadLettered, lastErr)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !rep.Succeeded {
+		return fmt.Errorf("last run failed: %s", rep.Error)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/config"
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/transform"
+)
+
+// runValidate checks a config file without moving data, e.g. as a deploy
+// preflight:
+//
+//	go-etl validate [-config FILE|DIR] [-profile NAME] [-timeout D] [-json] [-plugin FILE]...
+//
+// The file is parsed, and every pipeline's derive functions, transforms,
+// source and sink are created, their connectivity checked, and their
+// permissions, tables and fields checked against the mappings where the
+// connector supports it (see config.File.Preflight). With -json the report
+// is printed as JSON. It exits with status 3 when a check fails.
+func runValidate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	path := fs.String("config", "go-etl.yaml", "pipeline definition file, or directory of them")
+	profile := fs.String("profile", "", "profile of the definitions to apply, e.g. prod")
+	timeout := fs.Duration("timeout", 10*time.Second, "time allowed for connectivity checks")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	var plugins stringList
+	fs.Var(&plugins, "plugin", "Go plugin registering connectors, repeatable")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := loadPlugins(plugins); err != nil {
+		return err
+	}
+
+	file, err := (&config.Loader{Profile: *profile}).LoadPath(ctx, *path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalid, err)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	report, err := file.Preflight(checkCtx, connector.DefaultRegistry, transform.DefaultRegistry)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalid, err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printPreflight(report)
+	}
+
+	if !report.OK {
+		failed := 0
+		for _, p := range report.Pipelines {
+			if !p.OK {
+				failed++
+			}
+		}
+		return fmt.Errorf("%w: %d pipelines failed preflight checks", errInvalid, failed)
+	}
+	return nil
+}
+
+// printPreflight prints a preflight report, with the checks of failed
+// pipelines
+func printPreflight(report *config.PreflightReport) {
+	for _, p := range report.Pipelines {
+		if p.OK {
+			fmt.Printf("✓ %s: %s -> %s\n", p.Pipeline, p.Source, p.Sink)
+			continue
+		}
+		fmt.Printf("✗ %s: %s -> %s\n", p.Pipeline, p.Source, p.Sink)
+		for _, c := range p.Checks {
+			switch c.Status {
+			case config.CheckFailed:
+				fmt.Printf("    ✗ %s: %s\n", c.Name, strings.ReplaceAll(c.Error, "\n", "\n      "))
+			case config.CheckSkipped:
+				fmt.Printf("    - %s: skipped\n", c.Name)
+			default:
+				fmt.Printf("    ✓ %s\n", c.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,792 @@
+// Package config builds pipelines from declarative YAML definitions, so
+// routine pipelines need no Go program of their own
+//
+// A definition names a source and a sink registered with a
+// connector.Registry, the mappings from source to output fields, and the
+// batching, retry and schedule settings of each pipeline:
+//
+//	workers: 4
+//	connections:
+//	  users_db:
+//	    type: postgres
+//	    dsn: ${USERS_DSN} # or a secret, e.g. ${vault:secret/data/users#dsn}
+//	    max_open: 10
+//	batch:
+//	  size: 500
+//	  workers: 4
+//	  timeout: 2s
+//	pipelines:
+//	  - name: users
+//	    source:
+//	      type: postgres
+//	      connection: users_db # a named connection, or a connection string
+//	      options:
+//	        query: SELECT * FROM users
+//	    mappings:
+//	      - {target: id, from: id}
+//	      - {target: email, from: contact.email}
+//	      - {target: slug, expr: "slugify(name)"}
+//	      - {target: origin, value: app}
+//	    sink:
+//	      type: file
+//	      options: {dir: out, name: users}
+//	    validate: # relative to the file
+//	      schema: users.schema.json
+//	      on_violation: quarantine
+//	      quarantine: quarantine
+//	    quality:
+//	      - {field: id, check: unique, max_violations: 0}
+//	      - {field: email, check: regex, pattern: "@", max_ratio: 0.01}
+//	      - {field: country, check: lookup, connection: users_db, query: SELECT code FROM countries}
+//	    reconcile: # compare the sink with the source once loaded
+//	      keys: [id]
+//	      on_mismatch: warn
+//	    lineage: # add _lineage_system, _lineage_run_id... columns
+//	      entity: users
+//	      offset: id
+//	    schedule: "@every 1h"
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/connections"
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/dlq"
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/lineage"
+	"github.com/cuong/go-etl/pkg/quality"
+	"github.com/cuong/go-etl/pkg/reconcile"
+	"github.com/cuong/go-etl/pkg/transform"
+	"github.com/cuong/go-etl/pkg/validation"
+)
+
+// File is a set of pipeline definitions
+type File struct {
+	Workers   int        `yaml:"workers,omitempty"` // Concurrent pipelines (see etl.Config.WorkerNum)
+	Batch     Batch      `yaml:"batch,omitempty"`   // Defaults for every pipeline
+	Pipelines []Pipeline `yaml:"pipelines"`
+
+	// Connections are shared by every connector whose connection names
+	// them, see package connections
+	Connections map[string]connections.Spec `yaml:"connections,omitempty"`
+
+	// Plugins are Go plugins loaded into the registry before the pipelines
+	// are built, see connector.Registry.LoadPlugin; Load resolves relative
+	// paths against the directory of the file
+	Plugins []string `yaml:"plugins,omitempty"`
+
+	profiles []string // Defined by the definition, see Loader
+}
+
+// Batch configures the batching of a pipeline
+type Batch struct {
+	Size      int           `yaml:"size,omitempty"`
+	Workers   int           `yaml:"workers,omitempty"`
+	Timeout   time.Duration `yaml:"timeout,omitempty"` // Flush partial batches after this long
+	QueueSize int           `yaml:"queue_size,omitempty"`
+	InFlight  int           `yaml:"in_flight,omitempty"` // Batches each worker loads while transforming the next, see etl.WithAsyncLoad
+}
+
+// Pipeline defines one pipeline
+type Pipeline struct {
+	Name   string         `yaml:"name"`
+	Source connector.Spec `yaml:"source"`
+	Sink   connector.Spec `yaml:"sink"`
+
+	// Mappings build each output record; without them records pass
+	// through unchanged. Derive then adds computed fields to the output,
+	// and Transforms, registered connector transforms, run last in order.
+	Mappings   []Mapping                `yaml:"mappings,omitempty"`
+	Derive     []transform.DerivedField `yaml:"derive,omitempty"`
+	Transforms []connector.Spec         `yaml:"transforms,omitempty"`
+
+	// Validate checks the output records against a JSON Schema before
+	// they are loaded, and Quality checks them against data quality rules
+	Validate *Validation   `yaml:"validate,omitempty"`
+	Quality  []QualityRule `yaml:"quality,omitempty"`
+
+	// Reconcile compares the sink with the source after every run
+	Reconcile *Reconciliation `yaml:"reconcile,omitempty"`
+
+	// Lineage adds the origin of every record to the records loaded
+	Lineage *Lineage `yaml:"lineage,omitempty"`
+
+	Batch     *Batch        `yaml:"batch,omitempty"` // Overrides the file's batch settings
+	Schedule  string        `yaml:"schedule,omitempty"`
+	DependsOn []string      `yaml:"depends_on,omitempty"`
+	Timeout   time.Duration `yaml:"timeout,omitempty"`
+	Retry     *Retry        `yaml:"retry,omitempty"`
+
+	// ErrorBudget skips or dead-letters failed batches until their records
+	// exceed it, instead of failing the run on the first one
+	ErrorBudget *ErrorBudget `yaml:"error_budget,omitempty"`
+
+	// OnPanic is what happens to a record whose transform panicked:
+	// dead_letter (default), skip or fail, see etl.PanicPolicy
+	OnPanic string `yaml:"on_panic,omitempty"`
+
+	// Limit and SampleRate process only some of the extracted records, to
+	// try a pipeline on production data; see etl.WithLimit and
+	// etl.WithSampleRate
+	Limit      int64   `yaml:"limit,omitempty"`
+	SampleRate float64 `yaml:"sample_rate,omitempty"` // Between 0 and 1
+}
+
+// Retry configures the retries of a failed pipeline run
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff,omitempty"`
+	MaxBackoff  time.Duration `yaml:"max_backoff,omitempty"`
+}
+
+// ErrorBudget configures the failed records a pipeline run tolerates, see
+// etl.ErrorBudget
+type ErrorBudget struct {
+	MaxRecords int64   `yaml:"max_records,omitempty"`
+	MaxRatio   float64 `yaml:"max_ratio,omitempty"`   // Of the extracted records, e.g. 0.01
+	MinRecords int64   `yaml:"min_records,omitempty"` // Extracted before max_ratio applies during the run
+}
+
+// Validation configures the validation of the records a pipeline loads,
+// see package validation
+type Validation struct {
+	Schema      string `yaml:"schema"`                 // JSON Schema file
+	OnViolation string `yaml:"on_violation,omitempty"` // fail (default), skip or quarantine
+	Quarantine  string `yaml:"quarantine,omitempty"`   // Store of quarantined records (see dlq.FileStore)
+}
+
+// Reconciliation configures the reconciliation of the sink of a pipeline
+// with its source, see package reconcile
+// Both must implement connector.Reconciler. Counts are always compared,
+// and the checksums of the keys when set.
+type Reconciliation struct {
+	Keys       []string `yaml:"keys,omitempty"`        // Sink fields
+	SourceKeys []string `yaml:"source_keys,omitempty"` // The keys in the source, in the same order, if they differ
+	OnMismatch string   `yaml:"on_mismatch,omitempty"` // fail (default) or warn
+}
+
+// Lineage configures the lineage columns of the records a pipeline loads,
+// see package lineage
+// The source system is the source type, and the entity defaults to the
+// table, collection or topic option of the source.
+type Lineage struct {
+	Prefix string `yaml:"prefix,omitempty"` // Of the columns (defaults to "_lineage_")
+	Entity string `yaml:"entity,omitempty"`
+	Offset string `yaml:"offset,omitempty"` // Source field holding the position of a record, e.g. id
+}
+
+// Mapping sets one output field from exactly one of From, Value, Func or
+// Expr
+type Mapping struct {
+	Target string   `yaml:"target"`
+	From   string   `yaml:"from,omitempty"`  // Source field; dots address nested fields, e.g. "address.city"
+	Value  any      `yaml:"value,omitempty"` // Constant
+	Func   string   `yaml:"func,omitempty"`  // Derive function (see transform.Registry)...
+	Args   []string `yaml:"args,omitempty"`  // ...called with these source fields
+	Expr   string   `yaml:"expr,omitempty"`  // Shorthand for Func and Args, e.g. "full_name(first, last)"
+}
+
+// Load reads, expands and validates a definition file with the default
+// Loader
+func Load(path string) (*File, error) {
+	return (&Loader{}).Load(context.Background(), path)
+}
+
+// Parse expands, decodes and validates a definition with the default
+// Loader, rejecting unknown fields
+func Parse(data []byte) (*File, error) {
+	return (&Loader{}).Parse(context.Background(), data)
+}
+
+// decode decodes an expanded definition
+func decode(data []byte) (*File, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var f File
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i := range f.Pipelines {
+		for j := range f.Pipelines[i].Mappings {
+			if err := f.Pipelines[i].Mappings[j].parseExpr(); err != nil {
+				return nil, fmt.Errorf("pipeline %s: %w", f.Pipelines[i].Name, err)
+			}
+		}
+	}
+	return &f, nil
+}
+
+// resolvePaths resolves relative plugin, schema and quarantine paths
+// against the directory of the file at path
+func (f *File) resolvePaths(path string) {
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(filepath.Dir(path), *p)
+		}
+	}
+	for i := range f.Plugins {
+		resolve(&f.Plugins[i])
+	}
+	for _, p := range f.Pipelines {
+		if p.Validate != nil {
+			resolve(&p.Validate.Schema)
+			resolve(&p.Validate.Quarantine)
+		}
+	}
+}
+
+// validate checks what can be checked without a registry
+func (f *File) validate() error {
+	if len(f.Pipelines) == 0 {
+		return fmt.Errorf("no pipelines defined")
+	}
+
+	for name, c := range f.Connections {
+		if c.Type == "" {
+			return fmt.Errorf("connection %s: type is required", name)
+		}
+	}
+
+	names := make(map[string]bool, len(f.Pipelines))
+	for _, p := range f.Pipelines {
+		if p.Name == "" {
+			return fmt.Errorf("pipeline without a name")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate pipeline %s", p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	for _, p := range f.Pipelines {
+		if p.Source.Type == "" {
+			return fmt.Errorf("pipeline %s: source type is required", p.Name)
+		}
+		if p.Sink.Type == "" {
+			return fmt.Errorf("pipeline %s: sink type is required", p.Name)
+		}
+		for _, dep := range p.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("pipeline %s: depends on unknown pipeline %s", p.Name, dep)
+			}
+		}
+		targets := make(map[string]bool, len(p.Mappings))
+		for _, m := range p.Mappings {
+			if err := m.validate(); err != nil {
+				return fmt.Errorf("pipeline %s: %w", p.Name, err)
+			}
+			if targets[m.Target] {
+				return fmt.Errorf("pipeline %s: field %s mapped twice", p.Name, m.Target)
+			}
+			targets[m.Target] = true
+		}
+		for _, d := range p.Derive {
+			if d.Target == "" || d.Func == "" {
+				return fmt.Errorf("pipeline %s: derived fields need a target and a func", p.Name)
+			}
+		}
+		for _, t := range p.Transforms {
+			if t.Type == "" {
+				return fmt.Errorf("pipeline %s: transform type is required", p.Name)
+			}
+		}
+		if v := p.Validate; v != nil {
+			if v.Schema == "" {
+				return fmt.Errorf("pipeline %s: validation schema is required", p.Name)
+			}
+			policy, ok := violationPolicies[v.OnViolation]
+			if !ok {
+				return fmt.Errorf("pipeline %s: unknown on_violation %q", p.Name, v.OnViolation)
+			}
+			if policy == validation.Quarantine && v.Quarantine == "" {
+				return fmt.Errorf("pipeline %s: on_violation quarantine requires quarantine", p.Name)
+			}
+		}
+		for _, r := range p.Quality {
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("pipeline %s: %w", p.Name, err)
+			}
+		}
+		if b := p.ErrorBudget; b != nil && (b.MaxRecords < 0 || b.MaxRatio < 0 || b.MaxRatio > 1 || b.MinRecords < 0) {
+			return fmt.Errorf("pipeline %s: error_budget needs max_records and min_records of at least 0 and a max_ratio between 0 and 1", p.Name)
+		}
+		if _, ok := panicPolicies[p.OnPanic]; !ok {
+			return fmt.Errorf("pipeline %s: unknown on_panic %q", p.Name, p.OnPanic)
+		}
+		if p.Limit < 0 {
+			return fmt.Errorf("pipeline %s: limit must not be negative", p.Name)
+		}
+		if p.SampleRate < 0 || p.SampleRate > 1 {
+			return fmt.Errorf("pipeline %s: sample_rate must be between 0 and 1", p.Name)
+		}
+		if r := p.Reconcile; r != nil {
+			if _, ok := mismatchPolicies[r.OnMismatch]; !ok {
+				return fmt.Errorf("pipeline %s: unknown on_mismatch %q", p.Name, r.OnMismatch)
+			}
+			if r.SourceKeys != nil && len(r.SourceKeys) != len(r.Keys) {
+				return fmt.Errorf("pipeline %s: reconcile has %d keys but %d source keys", p.Name, len(r.Keys), len(r.SourceKeys))
+			}
+		}
+	}
+	return nil
+}
+
+// panicPolicies are the values of Pipeline.OnPanic
+var panicPolicies = map[string]etl.PanicPolicy{
+	"":            etl.PanicDeadLetter,
+	"dead_letter": etl.PanicDeadLetter,
+	"skip":        etl.PanicSkip,
+	"fail":        etl.PanicFail,
+}
+
+// violationPolicies are the values of Validation.OnViolation
+var violationPolicies = map[string]validation.Policy{
+	"":           validation.Fail,
+	"fail":       validation.Fail,
+	"skip":       validation.Skip,
+	"quarantine": validation.Quarantine,
+}
+
+// mismatchPolicies are the values of Reconciliation.OnMismatch
+var mismatchPolicies = map[string]reconcile.Policy{
+	"":     reconcile.Fail,
+	"fail": reconcile.Fail,
+	"warn": reconcile.Warn,
+}
+
+// ManagerConfig returns the manager settings of the file
+func (f *File) ManagerConfig() *etl.Config {
+	return &etl.Config{WorkerNum: f.Workers}
+}
+
+// BucketConfig returns the default batch settings of the file
+func (f *File) BucketConfig() *bucket.Config {
+	return f.Batch.bucketConfig()
+}
+
+func (b Batch) bucketConfig() *bucket.Config {
+	return &bucket.Config{
+		BatchSize: b.Size,
+		WorkerNum: b.Workers,
+		Timeout:   b.Timeout,
+		QueueSize: b.QueueSize,
+	}
+}
+
+// Build loads the plugins of the file into reg, creates the sources, sinks
+// and transforms of every pipeline from reg, adds the pipelines to m and
+// schedules those with a schedule
+// Pipelines whose source implements connector.Resumer commit their progress
+// to the checkpoint store of m. Derive functions are looked up in
+// transform.DefaultRegistry. Closing the returned closer closes the sources
+// and sinks, and the connections of the file. Connections are defined in the
+// registry carried by ctx if any (see connections.NewContext), which is then
+// left open, or in a new one.
+// On error, the pipelines added before the failing one stay registered in m
+// with their sources and sinks closed, so m should be discarded.
+func (f *File) Build(ctx context.Context, m *etl.Manager, reg *connector.Registry) (io.Closer, error) {
+	return f.BuildWith(ctx, m, reg, transform.DefaultRegistry)
+}
+
+// BuildWith is Build with the derive functions of funcs
+func (f *File) BuildWith(ctx context.Context, m *etl.Manager, reg *connector.Registry, funcs *transform.Registry) (io.Closer, error) {
+	for _, plugin := range f.Plugins {
+		if err := reg.LoadPlugin(plugin); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	ctx, conns, err := f.connections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	built := &builtFile{conns: conns}
+	for _, p := range f.Pipelines {
+		proc, err := p.build(ctx, reg, funcs)
+		if err != nil {
+			built.Close()
+			return nil, fmt.Errorf("config: pipeline %s: %w", p.Name, err)
+		}
+		built.procs = append(built.procs, proc)
+
+		processor, err := p.processor(ctx, proc)
+		if err != nil {
+			built.Close()
+			return nil, fmt.Errorf("config: pipeline %s: %w", p.Name, err)
+		}
+		if err := etl.AddPipelineGeneric(m, processor, p.Name, f.options(p)...); err != nil {
+			built.Close()
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	// Schedule once every pipeline and its dependencies are registered
+	for _, p := range f.Pipelines {
+		if p.Schedule == "" {
+			continue
+		}
+		if err := m.Schedule(p.Name, p.Schedule); err != nil {
+			built.Close()
+			return nil, fmt.Errorf("config: pipeline %s: %w", p.Name, err)
+		}
+	}
+	return built, nil
+}
+
+// Source loads the plugins of the file into reg and creates the source of
+// the named pipeline, e.g. to sample or profile its records
+// Closing the returned closer closes the source, and the connections of
+// the file as with Build.
+func (f *File) Source(ctx context.Context, reg *connector.Registry, pipeline string) (connector.Source, io.Closer, error) {
+	i := slices.IndexFunc(f.Pipelines, func(p Pipeline) bool { return p.Name == pipeline })
+	if i < 0 {
+		return nil, nil, fmt.Errorf("config: unknown pipeline %q", pipeline)
+	}
+	for _, plugin := range f.Plugins {
+		if err := reg.LoadPlugin(plugin); err != nil {
+			return nil, nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	ctx, conns, err := f.connections(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	built := &builtFile{conns: conns}
+	src, err := reg.NewSource(ctx, f.Pipelines[i].Source)
+	if err != nil {
+		built.Close()
+		return nil, nil, fmt.Errorf("config: pipeline %s: source: %w", pipeline, err)
+	}
+	built.procs = append(built.procs, &connector.Processor{Source: src})
+	return src, built, nil
+}
+
+// options returns the pipeline options of p
+func (f *File) options(p Pipeline) []etl.PipelineOption {
+	var opts []etl.PipelineOption
+	if p.Batch != nil {
+		opts = append(opts, etl.WithBucketConfig(f.batch(p).bucketConfig()))
+	}
+	if len(p.DependsOn) > 0 {
+		opts = append(opts, etl.WithDependencies(p.DependsOn...))
+	}
+	if p.Timeout > 0 {
+		opts = append(opts, etl.WithTimeout(p.Timeout))
+	}
+	if p.Retry != nil {
+		opts = append(opts, etl.WithRetryPolicy(etl.RetryPolicy{
+			MaxAttempts: p.Retry.MaxAttempts,
+			Backoff:     p.Retry.Backoff,
+			MaxBackoff:  p.Retry.MaxBackoff,
+		}))
+	}
+	if b := p.ErrorBudget; b != nil {
+		opts = append(opts, etl.WithErrorBudget(etl.ErrorBudget{
+			MaxRecords: b.MaxRecords,
+			MaxRatio:   b.MaxRatio,
+			MinRecords: b.MinRecords,
+		}))
+	}
+	if p.OnPanic != "" {
+		opts = append(opts, etl.WithPanicPolicy(panicPolicies[p.OnPanic]))
+	}
+	if p.Limit > 0 {
+		opts = append(opts, etl.WithLimit(p.Limit))
+	}
+	if p.SampleRate > 0 {
+		opts = append(opts, etl.WithSampleRate(p.SampleRate))
+	}
+	if b := f.batch(p); b.InFlight > 1 {
+		opts = append(opts, etl.WithAsyncLoad(b.InFlight))
+	}
+	return opts
+}
+
+// batch returns the batch settings of p, filled in from the file's
+func (f *File) batch(p Pipeline) Batch {
+	var b Batch
+	if p.Batch != nil {
+		b = *p.Batch
+	}
+	if b.Size == 0 {
+		b.Size = f.Batch.Size
+	}
+	if b.Workers == 0 {
+		b.Workers = f.Batch.Workers
+	}
+	if b.Timeout == 0 {
+		b.Timeout = f.Batch.Timeout
+	}
+	if b.QueueSize == 0 {
+		b.QueueSize = f.Batch.QueueSize
+	}
+	if b.InFlight == 0 {
+		b.InFlight = f.Batch.InFlight
+	}
+	return b
+}
+
+// build creates the processor of p
+func (p Pipeline) build(ctx context.Context, reg *connector.Registry, funcs *transform.Registry) (*connector.Processor, error) {
+	for _, m := range p.Mappings {
+		if m.Func == "" {
+			continue
+		}
+		if _, ok := funcs.Lookup(m.Func); !ok {
+			return nil, fmt.Errorf("mapping %s: unknown derive function %q", m.Target, m.Func)
+		}
+	}
+	for _, d := range p.Derive {
+		if _, ok := funcs.Lookup(d.Func); !ok {
+			return nil, fmt.Errorf("derive %s: unknown derive function %q", d.Target, d.Func)
+		}
+	}
+
+	transforms := make([]connector.Transform, len(p.Transforms))
+	for i, spec := range p.Transforms {
+		t, err := reg.NewTransform(ctx, spec)
+		if err != nil {
+			return nil, fmt.Errorf("transform: %w", err)
+		}
+		transforms[i] = t
+	}
+
+	src, err := reg.NewSource(ctx, p.Source)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	sink, err := reg.NewSink(ctx, p.Sink)
+	if err != nil {
+		if c, ok := src.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, fmt.Errorf("sink: %w", err)
+	}
+
+	proc := &connector.Processor{Source: src, Sink: sink}
+	if len(p.Mappings) > 0 || len(p.Derive) > 0 || len(transforms) > 0 {
+		proc.Map = p.mapper(funcs, transforms)
+	}
+	return proc, nil
+}
+
+// processor returns the ETL processor of p over proc, adding lineage to
+// the records it loads, checking their quality, validating them and
+// reconciling its sink with its source if p says so
+// Quality results go to the collector carried by ctx, if any (see
+// quality.NewContext). Invalid records dropped by the validation are not
+// counted by the quality checks.
+func (p Pipeline) processor(ctx context.Context, proc *connector.Processor) (etl.ETLProcessor[connector.Record, connector.Record], error) {
+	processor := connector.NewProcessor(proc)
+	if p.Lineage != nil {
+		var err error
+		if processor, err = lineage.Wrap(processor, p.lineageConfig()); err != nil {
+			return nil, err
+		}
+	}
+	if p.Reconcile != nil {
+		var err error
+		if processor, err = reconcile.Wrap(processor, p.reconcileConfig(proc)); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.Quality) > 0 {
+		rules, err := p.qualityRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cfg := quality.Config{Name: p.Name, Rules: rules}
+		cfg.Collector, _ = quality.FromContext(ctx)
+		if processor, err = quality.Wrap(processor, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if p.Validate == nil {
+		return processor, nil
+	}
+
+	schema, err := os.ReadFile(p.Validate.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("validation: %w", err)
+	}
+	validator, err := validation.JSONSchema[connector.Record](schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Validate.Schema, err)
+	}
+	cfg := validation.Config[connector.Record]{
+		Validator: validator,
+		Policy:    violationPolicies[p.Validate.OnViolation],
+		Name:      p.Name,
+	}
+	if cfg.Policy == validation.Quarantine {
+		if cfg.Quarantine, err = dlq.NewFileStore(p.Validate.Quarantine); err != nil {
+			return nil, err
+		}
+	}
+	return validation.Wrap(processor, cfg)
+}
+
+// mapper returns the record transformation of p
+// Errors panic, so the batch is dead-lettered or fails like any other
+// panicking Transform.
+func (p Pipeline) mapper(funcs *transform.Registry, transforms []connector.Transform) func(context.Context, connector.Record) connector.Record {
+	mappings, derive := p.Mappings, p.Derive
+	return func(ctx context.Context, rec connector.Record) connector.Record {
+		out := rec
+		if len(mappings) > 0 {
+			out = make(connector.Record, len(mappings))
+			for _, m := range mappings {
+				v, err := m.apply(rec, funcs)
+				if err != nil {
+					panic(fmt.Errorf("mapping %s: %w", m.Target, err))
+				}
+				out[m.Target] = v
+			}
+		}
+		if err := funcs.Apply(out, derive); err != nil {
+			panic(err)
+		}
+		for i, t := range transforms {
+			var err error
+			if out, err = t(ctx, out); err != nil {
+				panic(fmt.Errorf("transform %s: %w", p.Transforms[i].Type, err))
+			}
+		}
+		return out
+	}
+}
+
+// parseExpr splits Expr into Func and Args
+func (m *Mapping) parseExpr() error {
+	if m.Expr == "" {
+		return nil
+	}
+	if m.Func != "" || len(m.Args) > 0 {
+		return fmt.Errorf("mapping %s: expr cannot be combined with func", m.Target)
+	}
+
+	expr := strings.TrimSpace(m.Expr)
+	open := strings.IndexByte(expr, '(')
+	if open <= 0 || !strings.HasSuffix(expr, ")") {
+		return fmt.Errorf("mapping %s: invalid expr %q, want func(field, ...)", m.Target, m.Expr)
+	}
+	m.Func = strings.TrimSpace(expr[:open])
+	if args := strings.TrimSpace(expr[open+1 : len(expr)-1]); args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			arg = strings.TrimSpace(arg)
+			if arg == "" {
+				return fmt.Errorf("mapping %s: empty argument in expr %q", m.Target, m.Expr)
+			}
+			m.Args = append(m.Args, arg)
+		}
+	}
+	return nil
+}
+
+// validate checks that m sets its target in exactly one way
+func (m Mapping) validate() error {
+	if m.Target == "" {
+		return fmt.Errorf("mapping without a target")
+	}
+	kinds := 0
+	for _, set := range []bool{m.From != "", m.Value != nil, m.Func != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("mapping %s: set exactly one of from, value, func or expr", m.Target)
+	}
+	if len(m.Args) > 0 && m.Func == "" {
+		return fmt.Errorf("mapping %s: args without func", m.Target)
+	}
+	return nil
+}
+
+// apply computes the value of m from rec
+func (m Mapping) apply(rec connector.Record, funcs *transform.Registry) (any, error) {
+	switch {
+	case m.From != "":
+		return lookup(rec, m.From), nil
+	case m.Func != "":
+		args := make([]any, len(m.Args))
+		for i, field := range m.Args {
+			args[i] = lookup(rec, field)
+		}
+		return funcs.Call(m.Func, args...)
+	}
+	return m.Value, nil
+}
+
+// lookup returns the field of rec at a dotted path, nil if it is missing
+func lookup(rec connector.Record, path string) any {
+	if v, ok := rec[path]; ok {
+		return v
+	}
+
+	var cur any = rec
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[key]
+	}
+	return cur
+}
+
+// connections defines the connections of f in the registry carried by
+// ctx, or in a new registry it returns to be closed, and returns a context
+// carrying the registry
+func (f *File) connections(ctx context.Context) (context.Context, *connections.Registry, error) {
+	reg, shared := connections.FromContext(ctx)
+	if !shared {
+		reg = connections.NewRegistry()
+		ctx = connections.NewContext(ctx, reg)
+	}
+	for _, name := range slices.Sorted(maps.Keys(f.Connections)) {
+		if err := reg.Define(name, f.Connections[name]); err != nil {
+			if !shared {
+				reg.Close()
+			}
+			return nil, nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	if shared {
+		return ctx, nil, nil
+	}
+	return ctx, reg, nil
+}
+
+// builtFile closes the processors and connections of a built file
+type builtFile struct {
+	procs []*connector.Processor
+	conns *connections.Registry // nil when not owned
+}
+
+func (b *builtFile) Close() error {
+	var errs []error
+	for _, p := range b.procs {
+		errs = append(errs, p.Close())
+	}
+	if b.conns != nil {
+		errs = append(errs, b.conns.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuong/go-etl/pkg/connections"
+	"github.com/cuong/go-etl/pkg/secrets"
+)
+
+// Loader loads definitions, expanding references in their values:
+//
+//	${NAME}            the environment variable NAME, which must be set
+//	${NAME:-default}   NAME, or default when it is unset or empty
+//	${scheme:ref}      a secret, e.g. ${vault:secret/data/db#password}
+//	$${                a literal "${"
+//
+// Unquoted values are typed after expansion, so "port: ${PORT}" sets a
+// number; quote references inside flow collections ({...}), where braces
+// are syntax. Mapping keys are not expanded.
+//
+// A definition can hold profiles, overrides merged over the rest of it when
+// selected by Profile, e.g. per environment:
+//
+//	workers: 2
+//	batch: {size: 100}
+//	pipelines:
+//	  - name: users
+//	    ...
+//	profiles:
+//	  prod:
+//	    workers: 8
+//	    batch: {size: 5000}
+//	    pipelines:
+//	      - {name: users, schedule: "@every 10m"}
+//
+// Mappings merge key by key and pipelines by name; other values are
+// replaced. Only the selected profile is expanded.
+type Loader struct {
+	Secrets   *secrets.Registry                // Defaults to secrets.DefaultRegistry
+	LookupEnv func(name string) (string, bool) // Defaults to os.LookupEnv
+	Profile   string                           // Profile to apply, which must be defined; none if empty
+}
+
+// Load reads, expands and validates a definition file
+func (l *Loader) Load(ctx context.Context, path string) (*File, error) {
+	f, err := l.load(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.checkProfile(f.profiles); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// LoadDir loads every *.yaml and *.yml file of dir as one definition
+// The batch settings of each file apply to its own pipelines, workers is the
+// largest set by any file, and plugins, connections and pipelines add up;
+// pipelines may depend on pipelines and use connections of other files.
+// Each file applies the Profile if it defines it; at least one must.
+func (l *Loader) LoadDir(ctx context.Context, dir string) (*File, error) {
+	var paths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("config: no definition files in %s", dir)
+	}
+	sort.Strings(paths)
+
+	merged := &File{}
+	for _, path := range paths {
+		f, err := l.load(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		merged.profiles = append(merged.profiles, f.profiles...)
+		merged.Workers = max(merged.Workers, f.Workers)
+		merged.Plugins = append(merged.Plugins, f.Plugins...)
+		for name, c := range f.Connections {
+			if _, exists := merged.Connections[name]; exists {
+				return nil, fmt.Errorf("config: %s: duplicate connection %s", path, name)
+			}
+			if merged.Connections == nil {
+				merged.Connections = make(map[string]connections.Spec)
+			}
+			merged.Connections[name] = c
+		}
+		for _, p := range f.Pipelines {
+			if p.Batch != nil || f.Batch != (Batch{}) {
+				b := f.batch(p)
+				p.Batch = &b
+			}
+			merged.Pipelines = append(merged.Pipelines, p)
+		}
+	}
+	if err := l.checkProfile(merged.profiles); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", dir, err)
+	}
+	if err := merged.validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", dir, err)
+	}
+	return merged, nil
+}
+
+// LoadPath loads the definition file at path, or the definition files in
+// it if it is a directory
+func (l *Loader) LoadPath(ctx context.Context, path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if info.IsDir() {
+		return l.LoadDir(ctx, path)
+	}
+	return l.Load(ctx, path)
+}
+
+// Parse expands, decodes and validates a definition
+func (l *Loader) Parse(ctx context.Context, data []byte) (*File, error) {
+	f, err := l.parse(ctx, data)
+	if err == nil {
+		err = l.checkProfile(f.profiles)
+	}
+	if err == nil {
+		err = f.validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return f, nil
+}
+
+// load reads and expands a definition file without validating it
+func (l *Loader) load(ctx context.Context, path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	f, err := l.parse(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	f.resolvePaths(path)
+	return f, nil
+}
+
+func (l *Loader) parse(ctx context.Context, data []byte) (*File, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		return decode(data)
+	}
+	profiles, err := applyProfile(&doc, l.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &expander{loader: l, secrets: make(map[string]string)}
+	if err := exp.node(ctx, &doc); err != nil {
+		return nil, err
+	}
+	expanded, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, err
+	}
+	f, err := decode(expanded)
+	if err != nil {
+		return nil, err
+	}
+	f.profiles = profiles
+	return f, nil
+}
+
+// checkProfile checks that the Profile, if any, is among those defined
+func (l *Loader) checkProfile(defined []string) error {
+	if l.Profile == "" || slices.Contains(defined, l.Profile) {
+		return nil
+	}
+	return fmt.Errorf("unknown profile %q", l.Profile)
+}
+
+// referencePattern matches ${...} and the $${ escape
+var referencePattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// envPattern matches the environment references NAME and NAME:-default
+var envPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(:-(.*))?$`)
+
+// expander expands the values of a document, resolving every secret once
+type expander struct {
+	loader  *Loader
+	secrets map[string]string
+}
+
+func (e *expander) node(ctx context.Context, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return nil
+		}
+		value, err := e.expand(ctx, n.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		n.Value = value
+		if n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			n.Tag = "" // Type the expanded value
+		}
+
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := e.node(ctx, n.Content[i]); err != nil {
+				return err
+			}
+		}
+
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := e.node(ctx, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expand replaces the references in s
+func (e *expander) expand(ctx context.Context, s string) (string, error) {
+	var err error
+	out := referencePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if err != nil {
+			return ""
+		}
+		if match == "$${" {
+			return "${"
+		}
+
+		ref := match[2 : len(match)-1]
+		var value string
+		value, err = e.resolve(ctx, ref)
+		return value
+	})
+	return out, err
+}
+
+// resolve returns the value of a reference
+func (e *expander) resolve(ctx context.Context, ref string) (string, error) {
+	if m := envPattern.FindStringSubmatch(ref); m != nil {
+		lookup := e.loader.LookupEnv
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		value, ok := lookup(m[1])
+		switch {
+		case m[2] != "" && value == "":
+			return m[3], nil
+		case !ok:
+			return "", fmt.Errorf("environment variable %s is not set", m[1])
+		}
+		return value, nil
+	}
+
+	if !strings.Contains(ref, ":") {
+		return "", fmt.Errorf("invalid reference ${%s}", ref)
+	}
+	if value, ok := e.secrets[ref]; ok {
+		return value, nil
+	}
+	reg := e.loader.Secrets
+	if reg == nil {
+		reg = secrets.DefaultRegistry
+	}
+	value, err := reg.Resolve(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	e.secrets[ref] = value
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/lineage"
+)
+
+// defaultLineagePrefix prefixes the lineage columns unless Lineage.Prefix
+// is set
+const defaultLineagePrefix = "_lineage_"
+
+// lineageConfig returns the lineage of the records of p
+func (p Pipeline) lineageConfig() lineage.Config[connector.Record, connector.Record] {
+	l := p.Lineage
+	cfg := lineage.Config[connector.Record, connector.Record]{
+		Pipeline: p.Name,
+		System:   p.Source.Type,
+		Entity:   l.Entity,
+		Attach:   lineage.Columns[connector.Record](l.prefix()),
+	}
+	if cfg.Entity == "" {
+		cfg.Entity = p.sourceEntity()
+	}
+	if field := l.Offset; field != "" {
+		cfg.Offset = func(rec connector.Record) json.RawMessage {
+			v := lookup(rec, field)
+			if v == nil {
+				return nil
+			}
+			data, _ := json.Marshal(v)
+			return data
+		}
+	}
+	return cfg
+}
+
+// prefix returns the prefix of the lineage columns
+func (l *Lineage) prefix() string {
+	if l.Prefix == "" {
+		return defaultLineagePrefix
+	}
+	return l.Prefix
+}
+
+// sourceEntity returns the table, collection or topic the source of p
+// reads, from its options, or ""
+func (p Pipeline) sourceEntity() string {
+	for _, option := range []string{"table", "collection", "topic"} {
+		if s, ok := p.Source.Options[option].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/lineage"
+	"github.com/cuong/go-etl/pkg/transform"
+)
+
+// CheckStatus is the outcome of a preflight check
+type CheckStatus string
+
+const (
+	CheckPassed  CheckStatus = "passed"
+	CheckFailed  CheckStatus = "failed"
+	CheckSkipped CheckStatus = "skipped" // Unsupported by the connector, or an earlier check failed
+)
+
+// Check is one preflight check of a pipeline
+type Check struct {
+	Name   string      `json:"name"`
+	Status CheckStatus `json:"status"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// PipelineChecks are the preflight checks of one pipeline
+type PipelineChecks struct {
+	Pipeline string  `json:"pipeline"`
+	Source   string  `json:"source"`
+	Sink     string  `json:"sink"`
+	OK       bool    `json:"ok"`
+	Checks   []Check `json:"checks"`
+}
+
+// PreflightReport is the outcome of File.Preflight
+type PreflightReport struct {
+	OK        bool             `json:"ok"`
+	Pipelines []PipelineChecks `json:"pipelines"`
+}
+
+// Preflight checks every pipeline of f without moving data: that its
+// derive functions and transforms exist, that its source and sink can be
+// created and reached (see etl.HealthChecker), and that they accept the
+// fields it reads and writes (see connector.Preflighter)
+// Pipelines are checked concurrently, within the deadline of ctx. It only
+// fails when the plugins or connections of f cannot be set up; connections
+// are handled as by Build.
+func (f *File) Preflight(ctx context.Context, reg *connector.Registry, funcs *transform.Registry) (*PreflightReport, error) {
+	for _, plugin := range f.Plugins {
+		if err := reg.LoadPlugin(plugin); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+	ctx, conns, err := f.connections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if conns != nil {
+		defer conns.Close()
+	}
+
+	report := &PreflightReport{OK: true, Pipelines: make([]PipelineChecks, len(f.Pipelines))}
+	var wg sync.WaitGroup
+	for i, p := range f.Pipelines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Pipelines[i] = p.preflight(ctx, reg, funcs)
+		}()
+	}
+	wg.Wait()
+
+	for _, pc := range report.Pipelines {
+		report.OK = report.OK && pc.OK
+	}
+	return report, nil
+}
+
+// preflight runs the checks of p in order, skipping those that depend on
+// a failed one
+func (p Pipeline) preflight(ctx context.Context, reg *connector.Registry, funcs *transform.Registry) PipelineChecks {
+	pc := PipelineChecks{Pipeline: p.Name, Source: p.Source.Type, Sink: p.Sink.Type, OK: true}
+	run := func(name string, check func() error) bool {
+		if err := check(); err != nil {
+			pc.Checks = append(pc.Checks, Check{Name: name, Status: CheckFailed, Error: err.Error()})
+			pc.OK = false
+			return false
+		}
+		pc.Checks = append(pc.Checks, Check{Name: name, Status: CheckPassed})
+		return true
+	}
+	skip := func(names ...string) {
+		for _, name := range names {
+			pc.Checks = append(pc.Checks, Check{Name: name, Status: CheckSkipped})
+		}
+	}
+
+	run("expressions", func() error { return p.checkExpressions(funcs) })
+	if p.Validate != nil || len(p.Quality) > 0 {
+		run("validation", func() error {
+			_, err := p.processor(ctx, &connector.Processor{})
+			return err
+		})
+	}
+	run("transforms", func() error {
+		for _, spec := range p.Transforms {
+			if _, err := reg.NewTransform(ctx, spec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	var src connector.Source
+	var sink connector.Sink
+	sourceOK := run("source", func() (err error) {
+		src, err = reg.NewSource(ctx, p.Source)
+		return err
+	})
+	sinkOK := run("sink", func() (err error) {
+		sink, err = reg.NewSink(ctx, p.Sink)
+		return err
+	})
+	proc := &connector.Processor{Source: src, Sink: sink}
+	defer proc.Close()
+
+	connectorChecks := func(side string, ok bool, c any, fields []string) {
+		if !ok {
+			skip(side+" connectivity", side+" preflight")
+			return
+		}
+		if checker, ok := c.(etl.HealthChecker); !ok {
+			skip(side + " connectivity")
+		} else if !run(side+" connectivity", func() error { return checker.HealthCheck(ctx) }) {
+			skip(side + " preflight")
+			return
+		}
+		if preflighter, ok := c.(connector.Preflighter); ok {
+			run(side+" preflight", func() error { return preflighter.Preflight(ctx, fields) })
+		} else {
+			skip(side + " preflight")
+		}
+	}
+	connectorChecks("source", sourceOK, src, p.readFields())
+	connectorChecks("sink", sinkOK, sink, p.outputFields())
+	if p.Reconcile != nil {
+		if sourceOK && sinkOK {
+			run("reconciliation", func() error { return p.checkReconcile(src, sink) })
+		} else {
+			skip("reconciliation")
+		}
+	}
+	return pc
+}
+
+// checkExpressions checks that the derive functions of p exist and that
+// its field paths are well formed
+func (p Pipeline) checkExpressions(funcs *transform.Registry) error {
+	checkPath := func(target, path string) error {
+		if slices.Contains(strings.Split(path, "."), "") {
+			return fmt.Errorf("mapping %s: invalid field path %q", target, path)
+		}
+		return nil
+	}
+	for _, m := range p.Mappings {
+		if m.From != "" {
+			if err := checkPath(m.Target, m.From); err != nil {
+				return err
+			}
+		}
+		for _, arg := range m.Args {
+			if err := checkPath(m.Target, arg); err != nil {
+				return err
+			}
+		}
+		if m.Func != "" {
+			if _, ok := funcs.Lookup(m.Func); !ok {
+				return fmt.Errorf("mapping %s: unknown derive function %q", m.Target, m.Func)
+			}
+		}
+	}
+	for _, d := range p.Derive {
+		if _, ok := funcs.Lookup(d.Func); !ok {
+			return fmt.Errorf("derive %s: unknown derive function %q", d.Target, d.Func)
+		}
+	}
+	return nil
+}
+
+// readFields returns the top-level source fields p reads, sorted
+func (p Pipeline) readFields() []string {
+	read := make(map[string]bool)
+	add := func(path string) {
+		top, _, _ := strings.Cut(path, ".")
+		read[top] = true
+	}
+	produced := make(map[string]bool)
+	for _, m := range p.Mappings {
+		if m.From != "" {
+			add(m.From)
+		}
+		for _, arg := range m.Args {
+			add(arg)
+		}
+		produced[m.Target] = true
+	}
+	// Derived fields read the output of the mappings, if any
+	for _, d := range p.Derive {
+		for _, arg := range d.Args {
+			if len(p.Mappings) == 0 && !produced[arg] {
+				add(arg)
+			}
+		}
+		produced[d.Target] = true
+	}
+
+	fields := make([]string, 0, len(read))
+	for field := range read {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// outputFields returns the fields of the records p loads, sorted, or nil
+// when they depend on the source records or on transforms
+func (p Pipeline) outputFields() []string {
+	if len(p.Mappings) == 0 || len(p.Transforms) > 0 {
+		return nil
+	}
+	var fields []string
+	for _, m := range p.Mappings {
+		fields = append(fields, m.Target)
+	}
+	for _, d := range p.Derive {
+		if !slices.Contains(fields, d.Target) {
+			fields = append(fields, d.Target)
+		}
+	}
+	if p.Lineage != nil {
+		fields = append(fields, lineage.ColumnNames(p.Lineage.prefix())...)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profilesKey is the top-level key of the profiles of a definition
+const profilesKey = "profiles"
+
+// applyProfile removes the profiles of doc, merging the one named profile,
+// if any, over the rest of the definition; it returns the names of the
+// profiles doc defines
+//
+// Mappings are merged key by key, and sequences of mappings with a name,
+// such as pipelines, item by item, adding the items the base lacks; other
+// values replace those of the base.
+func applyProfile(doc *yaml.Node, profile string) ([]string, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+
+	var profiles *yaml.Node
+	for i := 0; i < len(root.Content); i += 2 {
+		if root.Content[i].Value == profilesKey {
+			profiles = root.Content[i+1]
+			root.Content = append(root.Content[:i:i], root.Content[i+2:]...)
+			break
+		}
+	}
+	if profiles == nil || profiles.Tag == "!!null" {
+		return nil, nil
+	}
+	if profiles.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: profiles must map names to overrides", profiles.Line)
+	}
+
+	names := make([]string, 0, len(profiles.Content)/2)
+	for i := 0; i < len(profiles.Content); i += 2 {
+		name, override := profiles.Content[i].Value, profiles.Content[i+1]
+		names = append(names, name)
+		if name != profile || override.Tag == "!!null" {
+			continue
+		}
+		if override.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: profile %s must be a mapping", override.Line, name)
+		}
+		if err := checkOverride(override); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+		merge(root, override)
+	}
+	return names, nil
+}
+
+// checkOverride rejects overrides that would define profiles
+func checkOverride(override *yaml.Node) error {
+	for i := 0; i < len(override.Content); i += 2 {
+		if override.Content[i].Value == profilesKey {
+			return fmt.Errorf("line %d: profiles cannot be nested", override.Content[i].Line)
+		}
+	}
+	return nil
+}
+
+// merge merges override into base
+func merge(base, override *yaml.Node) {
+	switch {
+	case base.Kind == yaml.MappingNode && override.Kind == yaml.MappingNode:
+		for i := 0; i < len(override.Content); i += 2 {
+			key, value := override.Content[i], override.Content[i+1]
+			if j := mappingIndex(base, key.Value); j >= 0 {
+				if value.Kind == base.Content[j+1].Kind && value.Kind != yaml.ScalarNode {
+					merge(base.Content[j+1], value)
+				} else {
+					base.Content[j+1] = value
+				}
+				continue
+			}
+			base.Content = append(base.Content, key, value)
+		}
+
+	case base.Kind == yaml.SequenceNode && override.Kind == yaml.SequenceNode && named(base) && named(override):
+		for _, item := range override.Content {
+			name := item.Content[mappingIndex(item, "name")+1].Value
+			merged := false
+			for _, b := range base.Content {
+				if b.Content[mappingIndex(b, "name")+1].Value == name {
+					merge(b, item)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				base.Content = append(base.Content, item)
+			}
+		}
+
+	default:
+		*base = *override
+	}
+}
+
+// named reports whether seq holds only mappings with a name
+func named(seq *yaml.Node) bool {
+	for _, item := range seq.Content {
+		if item.Kind != yaml.MappingNode || mappingIndex(item, "name") < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mappingIndex returns the index of key in the content of a mapping, or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cuong/go-etl/pkg/connections"
+	"github.com/cuong/go-etl/pkg/quality"
+)
+
+// QualityRule declares a data quality rule, see package quality
+type QualityRule struct {
+	Name  string `yaml:"name,omitempty"` // Defaults to "<field>:<check>"
+	Field string `yaml:"field"`
+	Check string `yaml:"check"` // not_null, unique, regex, range or lookup
+
+	Pattern string   `yaml:"pattern,omitempty"` // regex
+	Min     *float64 `yaml:"min,omitempty"`     // range, at least one bound
+	Max     *float64 `yaml:"max,omitempty"`
+
+	// Values are the values a lookup allows, or Query returns them from the
+	// named connection (postgres or sql) at the start of every run
+	Values     []any  `yaml:"values,omitempty"`
+	Connection string `yaml:"connection,omitempty"`
+	Query      string `yaml:"query,omitempty"`
+
+	// MaxViolations and MaxRatio fail the run when exceeded (see
+	// quality.Threshold); without them violations are only counted
+	MaxViolations *int64   `yaml:"max_violations,omitempty"`
+	MaxRatio      *float64 `yaml:"max_ratio,omitempty"`
+}
+
+// validate checks what can be checked without building the rule
+func (r QualityRule) validate() error {
+	if r.Field == "" {
+		return fmt.Errorf("quality rule without a field")
+	}
+	switch r.Check {
+	case "not_null", "unique":
+	case "regex":
+		if r.Pattern == "" {
+			return fmt.Errorf("quality rule %s: regex requires a pattern", r.Field)
+		}
+	case "range":
+		if r.Min == nil && r.Max == nil {
+			return fmt.Errorf("quality rule %s: range requires min or max", r.Field)
+		}
+	case "lookup":
+		if (r.Values == nil) == (r.Query == "") || (r.Query == "") != (r.Connection == "") {
+			return fmt.Errorf("quality rule %s: lookup requires values, or a connection and a query", r.Field)
+		}
+	default:
+		return fmt.Errorf("quality rule %s: unknown check %q", r.Field, r.Check)
+	}
+	return nil
+}
+
+// qualityRules builds the quality rules of p; lookup queries use the
+// connections carried by ctx
+func (p Pipeline) qualityRules(ctx context.Context) ([]quality.Rule, error) {
+	rules := make([]quality.Rule, len(p.Quality))
+	for i, r := range p.Quality {
+		rule := quality.Rule{Name: r.Name, Field: r.Field}
+		switch r.Check {
+		case "not_null":
+			rule.Check = quality.NotNull()
+		case "unique":
+			rule.Check = quality.Unique()
+		case "regex":
+			check, err := quality.Regex(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("quality rule %s: %w", r.Field, err)
+			}
+			rule.Check = check
+		case "range":
+			min, max := math.Inf(-1), math.Inf(1)
+			if r.Min != nil {
+				min = *r.Min
+			}
+			if r.Max != nil {
+				max = *r.Max
+			}
+			rule.Check = quality.Range(min, max)
+		case "lookup":
+			if r.Query == "" {
+				rule.Check = quality.In(quality.NewSet(r.Values...))
+				break
+			}
+			reg, ok := connections.FromContext(ctx)
+			if !ok || !reg.Has(r.Connection) {
+				return nil, fmt.Errorf("quality rule %s: unknown connection %s", r.Field, r.Connection)
+			}
+			rule.Check = quality.In(&queryLookup{Set: quality.NewSet(), conns: reg, connection: r.Connection, query: r.Query})
+		}
+
+		if r.MaxViolations != nil || r.MaxRatio != nil {
+			rule.Threshold = &quality.Threshold{}
+			if r.MaxViolations != nil {
+				rule.Threshold.Count = *r.MaxViolations
+			}
+			if r.MaxRatio != nil {
+				rule.Threshold.Ratio = *r.MaxRatio
+			}
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// queryLookup is a lookup loaded from a query of a named connection
+type queryLookup struct {
+	*quality.Set
+	conns      *connections.Registry
+	connection string
+	query      string
+}
+
+// Refresh reloads the values of the lookup
+func (l *queryLookup) Refresh(ctx context.Context) error {
+	client, err := l.conns.Get(ctx, l.connection)
+	if err != nil {
+		return err
+	}
+
+	var values []any
+	switch db := client.(type) {
+	case *pgxpool.Pool:
+		rows, err := db.Query(ctx, l.query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			row, err := rows.Values()
+			if err != nil {
+				return err
+			}
+			values = append(values, row[0])
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+	case *sql.DB:
+		rows, err := db.QueryContext(ctx, l.query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v any
+			if err := rows.Scan(&v); err != nil {
+				return err
+			}
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			values = append(values, v)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("connection %s cannot run lookup queries", l.connection)
+	}
+	l.Replace(values)
+	return nil
+}
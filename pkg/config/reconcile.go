@@ -0,0 +1,67 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/reconcile"
+)
+
+// reconcileConfig returns the reconciliation of the sink of proc with its
+// source
+func (p Pipeline) reconcileConfig(proc *connector.Processor) reconcile.Config {
+	r := p.Reconcile
+	entity := reconcile.Entity{Source: p.Name, Keys: r.SourceKeys, DestinationKeys: r.Keys}
+	if entity.Keys == nil {
+		entity.Keys = r.Keys
+	}
+	return reconcile.Config{
+		Name:        p.Name,
+		Source:      connectorSide{c: proc.Source, spec: p.Source},
+		Destination: connectorSide{c: proc.Sink, spec: p.Sink},
+		Entities:    []reconcile.Entity{entity},
+		Policy:      mismatchPolicies[r.OnMismatch],
+	}
+}
+
+// checkReconcile checks that the source and sink of p can be reconciled
+func (p Pipeline) checkReconcile(src connector.Source, sink connector.Sink) error {
+	for _, side := range []connectorSide{{c: src, spec: p.Source}, {c: sink, spec: p.Sink}} {
+		if _, err := side.reconciler(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectorSide is a source or sink reconciled as the single entity of a
+// pipeline
+type connectorSide struct {
+	c    any
+	spec connector.Spec
+}
+
+func (s connectorSide) reconciler() (connector.Reconciler, error) {
+	r, ok := s.c.(connector.Reconciler)
+	if !ok {
+		return nil, fmt.Errorf("%s connectors cannot be reconciled", s.spec.Type)
+	}
+	return r, nil
+}
+
+func (s connectorSide) Count(ctx context.Context, _ string) (int64, error) {
+	r, err := s.reconciler()
+	if err != nil {
+		return 0, err
+	}
+	return r.Count(ctx)
+}
+
+func (s connectorSide) Checksum(ctx context.Context, _ string, keys []string) (uint64, error) {
+	r, err := s.reconciler()
+	if err != nil {
+		return 0, err
+	}
+	return r.Checksum(ctx, keys)
+}
@@ -0,0 +1,339 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuong/go-etl/pkg/connections"
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/transform"
+)
+
+// Reloader keeps the pipelines of a manager in line with a definition file
+// or directory, so pipelines can be added, changed and removed while the
+// manager serves them (see etl.Manager.Serve)
+// Changed and removed pipelines are drained first: they are unscheduled and
+// their runs in progress finish. The manager's workers and default batch
+// settings are not reloaded.
+type Reloader struct {
+	Manager  *etl.Manager
+	Path     string              // Definition file, or directory of definition files (see Loader.LoadDir)
+	Loader   *Loader             // Defaults to a zero Loader
+	Registry *connector.Registry // Defaults to connector.DefaultRegistry
+	Funcs    *transform.Registry // Defaults to transform.DefaultRegistry
+	Logger   *slog.Logger        // Defaults to slog.Default()
+
+	// Connections receives the connections of the definitions (defaults to
+	// a registry of its own, closed by Close); connections can be added
+	// but not changed while running
+	Connections *connections.Registry
+
+	// Debounce is how long the definitions must stay unchanged before
+	// Watch reloads them (defaults to 500ms)
+	Debounce time.Duration
+
+	mu        sync.Mutex
+	defs      map[string][]byte // Applied definitions by pipeline
+	procs     map[string]*connector.Processor
+	ownsConns bool
+}
+
+// Reload loads the definitions at Path and applies them
+func (r *Reloader) Reload(ctx context.Context) error {
+	loader := r.Loader
+	if loader == nil {
+		loader = &Loader{}
+	}
+	f, err := loader.LoadPath(ctx, r.Path)
+	if err != nil {
+		return err
+	}
+	return r.Apply(ctx, f)
+}
+
+// Apply adds the pipelines of f missing from the manager, replaces those
+// whose definition changed since the last Apply and removes those no longer
+// defined
+// Pipelines the manager got elsewhere are left alone. Nothing is changed
+// when a pipeline of f cannot be built.
+func (r *Reloader) Apply(ctx context.Context, f *File) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.defs == nil {
+		r.defs = make(map[string][]byte)
+		r.procs = make(map[string]*connector.Processor)
+	}
+	reg := r.Registry
+	if reg == nil {
+		reg = connector.DefaultRegistry
+	}
+	funcs := r.Funcs
+	if funcs == nil {
+		funcs = transform.DefaultRegistry
+	}
+
+	for _, plugin := range f.Plugins {
+		if err := reg.LoadPlugin(plugin); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+
+	if r.Connections == nil {
+		r.Connections, r.ownsConns = connections.NewRegistry(), true
+	}
+	for _, name := range slices.Sorted(maps.Keys(f.Connections)) {
+		spec := f.Connections[name]
+		if old, ok := r.Connections.Spec(name); ok {
+			if !reflect.DeepEqual(old, spec) {
+				return fmt.Errorf("config: connection %s changed, restart to apply", name)
+			}
+			continue
+		}
+		if err := r.Connections.Define(name, spec); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	ctx = connections.NewContext(ctx, r.Connections)
+
+	// Build every new and changed pipeline before touching the manager
+	var changed []Pipeline
+	defs := make(map[string][]byte, len(f.Pipelines))
+	built := make(map[string]*connector.Processor)
+	processors := make(map[string]etl.ETLProcessor[connector.Record, connector.Record])
+	for _, p := range f.Pipelines {
+		def, err := yaml.Marshal(f.withBatch(p))
+		if err != nil {
+			closeAll(built)
+			return fmt.Errorf("config: pipeline %s: %w", p.Name, err)
+		}
+		defs[p.Name] = def
+		if old, ok := r.defs[p.Name]; ok && string(old) == string(def) {
+			continue
+		}
+
+		proc, err := p.build(ctx, reg, funcs)
+		if err != nil {
+			closeAll(built)
+			return fmt.Errorf("config: pipeline %s: %w", p.Name, err)
+		}
+		built[p.Name] = proc
+		if processors[p.Name], err = p.processor(ctx, proc); err != nil {
+			closeAll(built)
+			return fmt.Errorf("config: pipeline %s: %w", p.Name, err)
+		}
+		changed = append(changed, p)
+	}
+
+	logger := r.logger()
+	for _, p := range changed {
+		proc := built[p.Name]
+		delete(built, p.Name)
+
+		old, replacing := r.procs[p.Name]
+		if replacing {
+			if err := r.Manager.Drain(ctx, p.Name); err != nil {
+				proc.Close()
+				closeAll(built)
+				return fmt.Errorf("config: %w", err)
+			}
+			err := etl.ReplacePipelineGeneric(r.Manager, processors[p.Name], p.Name, f.options(p)...)
+			if err != nil {
+				proc.Close()
+				closeAll(built)
+				return fmt.Errorf("config: %w", err)
+			}
+			if err := old.Close(); err != nil {
+				logger.Warn("Closing replaced pipeline failed", "pipeline", p.Name, "error", err)
+			}
+		} else {
+			if err := etl.AddPipelineGeneric(r.Manager, processors[p.Name], p.Name, f.options(p)...); err != nil {
+				proc.Close()
+				closeAll(built)
+				return fmt.Errorf("config: %w", err)
+			}
+		}
+		r.defs[p.Name] = defs[p.Name]
+		r.procs[p.Name] = proc
+
+		if replacing {
+			logger.Info("Pipeline updated", "pipeline", p.Name)
+		} else {
+			logger.Info("Pipeline added", "pipeline", p.Name)
+		}
+	}
+
+	if err := r.remove(ctx, defs); err != nil {
+		return err
+	}
+
+	// Schedule once every pipeline and its dependencies are registered
+	for _, p := range changed {
+		if p.Schedule == "" {
+			continue
+		}
+		if err := r.Manager.Schedule(p.Name, p.Schedule); err != nil {
+			return fmt.Errorf("config: pipeline %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// remove removes the applied pipelines missing from defs, dependents first
+// r.mu must be held.
+func (r *Reloader) remove(ctx context.Context, defs map[string][]byte) error {
+	var removed []string
+	for name := range r.defs {
+		if _, ok := defs[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	// A pipeline cannot be removed before the pipelines depending on it
+	for len(removed) > 0 {
+		var (
+			pending []string
+			errs    []error
+		)
+		for _, name := range removed {
+			if err := r.Manager.RemovePipeline(ctx, name); err != nil {
+				pending = append(pending, name)
+				errs = append(errs, err)
+				continue
+			}
+			if err := r.procs[name].Close(); err != nil {
+				r.logger().Warn("Closing removed pipeline failed", "pipeline", name, "error", err)
+			}
+			delete(r.defs, name)
+			delete(r.procs, name)
+			r.logger().Info("Pipeline removed", "pipeline", name)
+		}
+		if len(pending) == len(removed) {
+			return fmt.Errorf("config: %w", errors.Join(errs...))
+		}
+		removed = pending
+	}
+	return nil
+}
+
+// Watch reloads the definitions whenever the files at Path change, until
+// ctx is cancelled
+// Failed reloads are logged and leave the pipelines as they were.
+func (r *Reloader) Watch(ctx context.Context) error {
+	info, err := os.Stat(r.Path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	// Watch the directory of a file too, as editors replace files on save
+	dir, file := r.Path, ""
+	if !info.IsDir() {
+		dir, file = filepath.Dir(r.Path), filepath.Clean(r.Path)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("config: watch %s: %w", dir, err)
+	}
+
+	debounce := r.Debounce
+	if debounce <= 0 {
+		debounce = 500 * time.Millisecond
+	}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if file != "" && filepath.Clean(event.Name) != file {
+				continue
+			}
+			if file == "" && !isDefinition(event.Name) {
+				continue
+			}
+			timer.Reset(debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			r.logger().Warn("Config watch failed", "path", r.Path, "error", err)
+
+		case <-timer.C:
+			if err := r.Reload(ctx); err != nil {
+				r.logger().Error("Config reload failed", "path", r.Path, "error", err)
+			}
+		}
+	}
+}
+
+// Close closes the sources and sinks of the applied pipelines, and the
+// connections unless they were given
+func (r *Reloader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := []error{closeAll(r.procs)}
+	if r.ownsConns {
+		errs = append(errs, r.Connections.Close())
+	}
+	r.defs, r.procs = nil, nil
+	return errors.Join(errs...)
+}
+
+func (r *Reloader) logger() *slog.Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+	return slog.Default()
+}
+
+// withBatch returns p with the batch settings it gets from f
+func (f *File) withBatch(p Pipeline) Pipeline {
+	if p.Batch != nil {
+		b := f.batch(p)
+		p.Batch = &b
+	}
+	return p
+}
+
+// isDefinition reports whether path names a definition file
+func isDefinition(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// closeAll closes processors
+func closeAll(procs map[string]*connector.Processor) error {
+	var errs []error
+	for _, p := range procs {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,252 @@
+// Package connections shares named database and broker connections between
+// pipelines
+// A connection is defined once with its pool limits, opened on first use,
+// shared by every processor asking for it, health checked, and closed with
+// the registry:
+//
+//	reg := connections.NewRegistry()
+//	reg.Define("warehouse", connections.Spec{Type: "postgres", DSN: dsn, MaxOpen: 20})
+//	...
+//	pool, err := connections.Get[*pgxpool.Pool](ctx, reg, "warehouse")
+//
+// Built-in types: postgres (*pgxpool.Pool), sql (*sql.DB), gorm (*gorm.DB),
+// mongo (*mongo.Client) and kafka (*kafka.Client).
+package connections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Spec defines a connection
+type Spec struct {
+	Type        string         `yaml:"type"`                    // Driver name, see Registry.RegisterDriver
+	DSN         string         `yaml:"dsn"`                     // Connection string, URL or address list
+	MaxOpen     int            `yaml:"max_open,omitempty"`      // Pool size limit (0 for the driver default)
+	MaxIdle     int            `yaml:"max_idle,omitempty"`      // Idle connections kept, where supported
+	MaxIdleTime time.Duration  `yaml:"max_idle_time,omitempty"` // Idle connections are closed after this long
+	MaxLifetime time.Duration  `yaml:"max_lifetime,omitempty"`  // Connections are recycled after this long
+	Options     map[string]any `yaml:"options,omitempty"`       // Driver specific settings
+}
+
+// Driver opens, checks and closes the connections of one type
+type Driver interface {
+	Open(ctx context.Context, spec Spec) (any, error)
+	Ping(ctx context.Context, conn any) error
+	Close(conn any) error
+}
+
+// Registry holds named connections
+type Registry struct {
+	mu      sync.Mutex
+	drivers map[string]Driver
+	conns   map[string]*conn
+}
+
+// conn is a defined connection, open once value is set
+type conn struct {
+	spec Spec
+
+	mu    sync.Mutex // Serializes opening
+	value any
+}
+
+// NewRegistry creates a registry with the built-in drivers
+func NewRegistry() *Registry {
+	r := &Registry{
+		drivers: make(map[string]Driver),
+		conns:   make(map[string]*conn),
+	}
+	for name, d := range builtinDrivers {
+		r.drivers[name] = d
+	}
+	return r
+}
+
+// RegisterDriver adds a connection type, failing if the name is taken
+func (r *Registry) RegisterDriver(name string, d Driver) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.drivers[name]; exists {
+		return fmt.Errorf("driver %q already registered", name)
+	}
+	r.drivers[name] = d
+	return nil
+}
+
+// Define adds a named connection without opening it, failing if the name
+// is taken or the type unknown
+func (r *Registry) Define(name string, spec Spec) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.conns[name]; exists {
+		return fmt.Errorf("connection %q already defined", name)
+	}
+	if _, ok := r.drivers[spec.Type]; !ok {
+		return fmt.Errorf("connection %s: unknown type %q", name, spec.Type)
+	}
+	r.conns[name] = &conn{spec: spec}
+	return nil
+}
+
+// Has reports whether a connection is defined
+func (r *Registry) Has(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.conns[name]
+	return ok
+}
+
+// Spec returns the definition of a connection
+func (r *Registry) Spec(name string) (Spec, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.conns[name]
+	if !ok {
+		return Spec{}, false
+	}
+	return c.spec, true
+}
+
+// Names returns the defined connections, sorted
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.conns))
+	for name := range r.conns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the named connection, opening it on first use
+// A failed open is retried by the next Get.
+func (r *Registry) Get(ctx context.Context, name string) (any, error) {
+	c, d, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.value != nil {
+		return c.value, nil
+	}
+	value, err := d.Open(ctx, c.spec)
+	if err != nil {
+		return nil, fmt.Errorf("connection %s: %w", name, err)
+	}
+	c.value = value
+	return value, nil
+}
+
+// Get returns the named connection as a T, e.g. *pgxpool.Pool for a
+// postgres connection
+func Get[T any](ctx context.Context, r *Registry, name string) (T, error) {
+	var zero T
+	value, err := r.Get(ctx, name)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("connection %s is a %T, not a %T", name, value, zero)
+	}
+	return typed, nil
+}
+
+// Ping checks the named connection, opening it if needed
+func (r *Registry) Ping(ctx context.Context, name string) error {
+	value, err := r.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	_, d, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	if err := d.Ping(ctx, value); err != nil {
+		return fmt.Errorf("connection %s: %w", name, err)
+	}
+	return nil
+}
+
+// HealthCheck pings the connections opened so far, so it can be used as an
+// etl.HealthChecker without opening unused connections
+func (r *Registry) HealthCheck(ctx context.Context) error {
+	var errs []error
+	for _, name := range r.Names() {
+		c, d, err := r.lookup(name)
+		if err != nil {
+			continue
+		}
+		c.mu.Lock()
+		value := c.value
+		c.mu.Unlock()
+
+		if value == nil {
+			continue
+		}
+		if err := d.Ping(ctx, value); err != nil {
+			errs = append(errs, fmt.Errorf("connection %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the open connections; they reopen on their next Get
+func (r *Registry) Close() error {
+	var errs []error
+	for _, name := range r.Names() {
+		c, d, err := r.lookup(name)
+		if err != nil {
+			continue
+		}
+		c.mu.Lock()
+		if c.value != nil {
+			if err := d.Close(c.value); err != nil {
+				errs = append(errs, fmt.Errorf("connection %s: %w", name, err))
+			}
+			c.value = nil
+		}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// lookup returns a connection and its driver
+func (r *Registry) lookup(name string) (*conn, Driver, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.conns[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("connection %q is not defined", name)
+	}
+	return c, r.drivers[c.spec.Type], nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying r, for code that receives only a
+// context, such as connector factories
+func NewContext(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// FromContext returns the registry carried by ctx
+func FromContext(ctx context.Context) (*Registry, bool) {
+	r, ok := ctx.Value(contextKey{}).(*Registry)
+	return r, ok
+}
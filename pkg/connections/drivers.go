@@ -0,0 +1,242 @@
+package connections
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/segmentio/kafka-go"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"gopkg.in/yaml.v3"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var builtinDrivers = map[string]Driver{
+	"postgres": postgresDriver{},
+	"sql":      sqlDriver{},
+	"gorm":     gormDriver{},
+	"mongo":    mongoDriver{},
+	"kafka":    kafkaDriver{},
+}
+
+// DriverFuncs adapts functions to the Driver interface
+type DriverFuncs[T any] struct {
+	OpenFunc  func(ctx context.Context, spec Spec) (T, error)
+	PingFunc  func(ctx context.Context, conn T) error // Optional
+	CloseFunc func(conn T) error                      // Optional
+}
+
+func (d DriverFuncs[T]) Open(ctx context.Context, spec Spec) (any, error) {
+	return d.OpenFunc(ctx, spec)
+}
+
+func (d DriverFuncs[T]) Ping(ctx context.Context, conn any) error {
+	if d.PingFunc == nil {
+		return nil
+	}
+	return d.PingFunc(ctx, conn.(T))
+}
+
+func (d DriverFuncs[T]) Close(conn any) error {
+	if d.CloseFunc == nil {
+		return nil
+	}
+	return d.CloseFunc(conn.(T))
+}
+
+// postgresDriver opens *pgxpool.Pool
+type postgresDriver struct{}
+
+func (postgresDriver) Open(ctx context.Context, spec Spec) (any, error) {
+	cfg, err := pgxpool.ParseConfig(spec.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if spec.MaxOpen > 0 {
+		cfg.MaxConns = int32(spec.MaxOpen)
+	}
+	if spec.MaxIdleTime > 0 {
+		cfg.MaxConnIdleTime = spec.MaxIdleTime
+	}
+	if spec.MaxLifetime > 0 {
+		cfg.MaxConnLifetime = spec.MaxLifetime
+	}
+	return pgxpool.NewWithConfig(ctx, cfg)
+}
+
+func (postgresDriver) Ping(ctx context.Context, conn any) error {
+	return conn.(*pgxpool.Pool).Ping(ctx)
+}
+
+func (postgresDriver) Close(conn any) error {
+	conn.(*pgxpool.Pool).Close()
+	return nil
+}
+
+// sqlOptions configures the sql driver
+type sqlOptions struct {
+	Driver string `yaml:"driver"` // Name of an imported database/sql driver, e.g. "pgx"
+}
+
+// sqlDriver opens *sql.DB with a registered database/sql driver
+type sqlDriver struct{}
+
+func (sqlDriver) Open(_ context.Context, spec Spec) (any, error) {
+	var opts sqlOptions
+	if err := decodeOptions(spec, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Driver == "" {
+		return nil, fmt.Errorf("sql options: driver is required")
+	}
+	db, err := sql.Open(opts.Driver, spec.DSN)
+	if err != nil {
+		return nil, err
+	}
+	setPool(db, spec)
+	return db, nil
+}
+
+func (sqlDriver) Ping(ctx context.Context, conn any) error {
+	return conn.(*sql.DB).PingContext(ctx)
+}
+
+func (sqlDriver) Close(conn any) error {
+	return conn.(*sql.DB).Close()
+}
+
+// gormDriver opens *gorm.DB on PostgreSQL
+type gormDriver struct{}
+
+func (gormDriver) Open(_ context.Context, spec Spec) (any, error) {
+	db, err := gorm.Open(gormpostgres.Open(spec.DSN), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	setPool(sqlDB, spec)
+	return db, nil
+}
+
+func (gormDriver) Ping(ctx context.Context, conn any) error {
+	sqlDB, err := conn.(*gorm.DB).DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (gormDriver) Close(conn any) error {
+	sqlDB, err := conn.(*gorm.DB).DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// setPool applies the pool limits of spec to db
+func setPool(db *sql.DB, spec Spec) {
+	if spec.MaxOpen > 0 {
+		db.SetMaxOpenConns(spec.MaxOpen)
+	}
+	if spec.MaxIdle > 0 {
+		db.SetMaxIdleConns(spec.MaxIdle)
+	}
+	if spec.MaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(spec.MaxIdleTime)
+	}
+	if spec.MaxLifetime > 0 {
+		db.SetConnMaxLifetime(spec.MaxLifetime)
+	}
+}
+
+// mongoDriver opens *mongo.Client
+type mongoDriver struct{}
+
+func (mongoDriver) Open(ctx context.Context, spec Spec) (any, error) {
+	opts := options.Client().ApplyURI(spec.DSN)
+	if spec.MaxOpen > 0 {
+		opts.SetMaxPoolSize(uint64(spec.MaxOpen))
+	}
+	if spec.MaxIdle > 0 {
+		opts.SetMinPoolSize(uint64(spec.MaxIdle))
+	}
+	if spec.MaxIdleTime > 0 {
+		opts.SetMaxConnIdleTime(spec.MaxIdleTime)
+	}
+	return mongo.Connect(ctx, opts)
+}
+
+func (mongoDriver) Ping(ctx context.Context, conn any) error {
+	return conn.(*mongo.Client).Ping(ctx, readpref.Primary())
+}
+
+func (mongoDriver) Close(conn any) error {
+	return conn.(*mongo.Client).Disconnect(context.Background())
+}
+
+// kafkaOptions configures the kafka driver
+type kafkaOptions struct {
+	ClientID string `yaml:"client_id"`
+}
+
+// kafkaDriver opens *kafka.Client on a comma separated broker list
+type kafkaDriver struct{}
+
+func (kafkaDriver) Open(_ context.Context, spec Spec) (any, error) {
+	var opts kafkaOptions
+	if err := decodeOptions(spec, &opts); err != nil {
+		return nil, err
+	}
+	if spec.DSN == "" {
+		return nil, fmt.Errorf("kafka: dsn must list the brokers")
+	}
+	brokers := strings.Split(spec.DSN, ",")
+	for i := range brokers {
+		brokers[i] = strings.TrimSpace(brokers[i])
+	}
+
+	transport := &kafka.Transport{ClientID: opts.ClientID}
+	if spec.MaxIdleTime > 0 {
+		transport.IdleTimeout = spec.MaxIdleTime
+	}
+	return &kafka.Client{Addr: kafka.TCP(brokers...), Transport: transport}, nil
+}
+
+func (kafkaDriver) Ping(ctx context.Context, conn any) error {
+	_, err := conn.(*kafka.Client).Metadata(ctx, &kafka.MetadataRequest{Topics: []string{}})
+	return err
+}
+
+func (kafkaDriver) Close(conn any) error {
+	if t, ok := conn.(*kafka.Client).Transport.(*kafka.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+// decodeOptions decodes the options of spec into v, rejecting unknown ones
+func decodeOptions(spec Spec, v any) error {
+	if len(spec.Options) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(spec.Options)
+	if err != nil {
+		return fmt.Errorf("%s options: %w", spec.Type, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%s options: %w", spec.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,562 @@
+// Package builtin registers the bundled sources and sinks with
+// connector.DefaultRegistry
+// Import it for its side effects:
+//
+//	import _ "github.com/cuong/go-etl/pkg/connector/builtin"
+//
+// Sources: jsonl, csv, postgres. Sinks: stdout, file, postgres.
+// Transforms: rename, drop, anonymize. The jsonl, csv and postgres sources and the
+// postgres sink implement connector.Reconciler.
+//
+// The connection of the postgres connectors is a connection string, or the
+// name of a postgres connection of the registry carried by the context (see
+// connections.NewContext), whose pool is then shared.
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuong/go-etl/pkg/anonymize"
+	"github.com/cuong/go-etl/pkg/connections"
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/sinks/filesink"
+	"github.com/cuong/go-etl/pkg/sinks/stdoutsink"
+	"github.com/cuong/go-etl/pkg/sources/csvsource"
+	"github.com/cuong/go-etl/pkg/sources/jsonlsource"
+	"github.com/cuong/go-etl/pkg/sources/pgsource"
+)
+
+func init() {
+	Register(connector.DefaultRegistry)
+}
+
+// Register adds the bundled connectors to r
+func Register(r *connector.Registry) {
+	for name, f := range map[string]connector.SourceFactory{
+		"jsonl":    newJSONL,
+		"csv":      newCSV,
+		"postgres": newPostgres,
+	} {
+		if err := r.RegisterSource(name, f); err != nil {
+			panic(err)
+		}
+	}
+	for name, f := range map[string]connector.SinkFactory{
+		"stdout":   newStdout,
+		"file":     newFile,
+		"postgres": newPostgresSink,
+	} {
+		if err := r.RegisterSink(name, f); err != nil {
+			panic(err)
+		}
+	}
+	for name, f := range map[string]connector.TransformFactory{
+		"rename":    newRename,
+		"drop":      newDrop,
+		"anonymize": newAnonymize,
+	} {
+		if err := r.RegisterTransform(name, f); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// jsonlOptions configures the jsonl source
+type jsonlOptions struct {
+	Paths       []string `yaml:"paths"`
+	MaxLineSize int      `yaml:"max_line_size"`
+}
+
+// jsonlSource is a JSONL source that checks its files in Preflight
+type jsonlSource struct {
+	*jsonlsource.Source[connector.Record]
+	paths []string
+}
+
+func newJSONL(_ context.Context, spec connector.Spec) (connector.Source, error) {
+	var opts jsonlOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	src, err := jsonlsource.New[connector.Record](jsonlsource.Config{
+		Paths:       opts.Paths,
+		MaxLineSize: opts.MaxLineSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlSource{Source: src, paths: opts.Paths}, nil
+}
+
+// csvOptions configures the csv source
+type csvOptions struct {
+	Paths            []string `yaml:"paths"`
+	Comma            string   `yaml:"comma"`
+	Comment          string   `yaml:"comment"`
+	LazyQuotes       bool     `yaml:"lazy_quotes"`
+	TrimLeadingSpace bool     `yaml:"trim_leading_space"`
+	Header           []string `yaml:"header"`
+}
+
+// csvSource converts the rows of a CSV source to records
+type csvSource struct {
+	src *csvsource.Source[map[string]string]
+}
+
+func newCSV(_ context.Context, spec connector.Spec) (connector.Source, error) {
+	var opts csvOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	comma, err := oneRune("comma", opts.Comma)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := oneRune("comment", opts.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := csvsource.New[map[string]string](csvsource.Config{
+		Paths:            opts.Paths,
+		Comma:            comma,
+		Comment:          comment,
+		LazyQuotes:       opts.LazyQuotes,
+		TrimLeadingSpace: opts.TrimLeadingSpace,
+		Header:           opts.Header,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &csvSource{src: src}, nil
+}
+
+func (s *csvSource) Extract(ctx context.Context) (<-chan etl.Payload[connector.Record], error) {
+	rows, err := s.src.Extract(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan etl.Payload[connector.Record])
+	go func() {
+		defer close(out)
+		for row := range rows {
+			var rec connector.Record
+			if row.Err == nil {
+				rec = make(connector.Record, len(row.Data))
+				for k, v := range row.Data {
+					rec[k] = v
+				}
+			}
+			select {
+			case out <- etl.Payload[connector.Record]{Data: rec, Err: row.Err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// oneRune parses a single character option
+func oneRune(name, s string) (rune, error) {
+	r := []rune(s)
+	switch len(r) {
+	case 0:
+		return 0, nil
+	case 1:
+		return r[0], nil
+	}
+	return 0, fmt.Errorf("csv options: %s must be a single character", name)
+}
+
+// postgresOptions configures the postgres source
+type postgresOptions struct {
+	Query     string `yaml:"query"`
+	Args      []any  `yaml:"args"`
+	FetchSize int    `yaml:"fetch_size"`
+}
+
+// postgresSource streams a query over its own or a shared pool
+type postgresSource struct {
+	*pgsource.Source[connector.Record]
+	pool  *pgxpool.Pool
+	owned bool
+	query string
+	args  []any
+}
+
+func newPostgres(ctx context.Context, spec connector.Spec) (connector.Source, error) {
+	var opts postgresOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	if spec.Connection == "" {
+		return nil, fmt.Errorf("postgres source: connection is required")
+	}
+
+	pool, owned, err := postgresPool(ctx, spec.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("postgres source: %w", err)
+	}
+	src, err := pgsource.New[connector.Record](pgsource.Config[connector.Record]{
+		DB:        pool,
+		Query:     opts.Query,
+		Args:      opts.Args,
+		FetchSize: opts.FetchSize,
+		Map:       pgx.RowToMap,
+	})
+	if err != nil {
+		if owned {
+			pool.Close()
+		}
+		return nil, err
+	}
+	return &postgresSource{Source: src, pool: pool, owned: owned, query: opts.Query, args: opts.Args}, nil
+}
+
+func (s *postgresSource) HealthCheck(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *postgresSource) Close() error {
+	if s.owned {
+		s.pool.Close()
+	}
+	return nil
+}
+
+// postgresPool returns the pool of the named connection of the registry
+// carried by ctx, or a new pool for a connection string, which the caller
+// owns
+func postgresPool(ctx context.Context, connection string) (pool *pgxpool.Pool, owned bool, err error) {
+	if reg, ok := connections.FromContext(ctx); ok && reg.Has(connection) {
+		pool, err := connections.Get[*pgxpool.Pool](ctx, reg, connection)
+		return pool, false, err
+	}
+	pool, err = pgxpool.New(ctx, connection)
+	return pool, err == nil, err
+}
+
+// stdoutOptions configures the stdout sink
+type stdoutOptions struct {
+	Format  string   `yaml:"format"`
+	Columns []string `yaml:"columns"`
+	Stderr  bool     `yaml:"stderr"`
+}
+
+// stdoutSink is a stdout sink that checks its columns in Preflight
+type stdoutSink struct {
+	*stdoutsink.Sink[connector.Record]
+	columns []string
+}
+
+func newStdout(_ context.Context, spec connector.Spec) (connector.Sink, error) {
+	var opts stdoutOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	cfg := stdoutsink.Config{Format: stdoutsink.Format(opts.Format), Columns: opts.Columns}
+	if opts.Stderr {
+		cfg.Writer = os.Stderr
+	}
+	sink, err := stdoutsink.New[connector.Record](cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &stdoutSink{Sink: sink, columns: opts.Columns}, nil
+}
+
+// fileOptions configures the file sink
+type fileOptions struct {
+	Dir         string        `yaml:"dir"`
+	Name        string        `yaml:"name"`
+	Format      string        `yaml:"format"`
+	Columns     []string      `yaml:"columns"`
+	Compression string        `yaml:"compression"`
+	MaxSize     int64         `yaml:"max_size"`
+	MaxAge      time.Duration `yaml:"max_age"`
+	Sync        bool          `yaml:"sync"`
+}
+
+// fileSink opens a file sink for every run and completes its file in
+// PostProcess, as a closed file sink cannot be reused
+type fileSink struct {
+	cfg filesink.Config
+
+	mu   sync.Mutex
+	sink *filesink.Sink[connector.Record]
+}
+
+func newFile(_ context.Context, spec connector.Spec) (connector.Sink, error) {
+	var opts fileOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	cfg := filesink.Config{
+		Dir:         opts.Dir,
+		Name:        opts.Name,
+		Format:      filesink.Format(opts.Format),
+		Columns:     opts.Columns,
+		Compression: filesink.Compression(opts.Compression),
+		MaxSize:     opts.MaxSize,
+		MaxAge:      opts.MaxAge,
+		Sync:        opts.Sync,
+	}
+
+	// Validate the settings up front
+	if _, err := filesink.New[connector.Record](cfg); err != nil {
+		return nil, err
+	}
+	return &fileSink{cfg: cfg}, nil
+}
+
+func (s *fileSink) PreProcess(context.Context) error {
+	// A failed run skips PostProcess; complete what it wrote
+	if err := s.Close(); err != nil {
+		return err
+	}
+	sink, err := filesink.New[connector.Record](s.cfg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sink = sink
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *fileSink) Load(ctx context.Context, records []connector.Record) error {
+	s.mu.Lock()
+	sink := s.sink
+	s.mu.Unlock()
+
+	if sink == nil {
+		return fmt.Errorf("file sink: not open")
+	}
+	return sink.Load(ctx, records)
+}
+
+func (s *fileSink) PostProcess(context.Context) error {
+	return s.Close()
+}
+
+// Close completes the file of the current run
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	sink := s.sink
+	s.sink = nil
+	s.mu.Unlock()
+
+	if sink == nil {
+		return nil
+	}
+	return sink.Close()
+}
+
+// postgresSinkOptions configures the postgres sink
+type postgresSinkOptions struct {
+	Table   string   `yaml:"table"`   // Optionally schema-qualified, e.g. "sales.orders"
+	Columns []string `yaml:"columns"` // Defaults to the fields of the first record of each batch
+}
+
+// postgresSink copies batches of records into a table over its own or a
+// shared pool
+type postgresSink struct {
+	pool    *pgxpool.Pool
+	owned   bool
+	table   pgx.Identifier
+	columns []string
+}
+
+func newPostgresSink(ctx context.Context, spec connector.Spec) (connector.Sink, error) {
+	var opts postgresSinkOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	if spec.Connection == "" || opts.Table == "" {
+		return nil, fmt.Errorf("postgres sink: connection and table are required")
+	}
+
+	pool, owned, err := postgresPool(ctx, spec.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("postgres sink: %w", err)
+	}
+	return &postgresSink{
+		pool:    pool,
+		owned:   owned,
+		table:   pgx.Identifier(strings.Split(opts.Table, ".")),
+		columns: opts.Columns,
+	}, nil
+}
+
+// Load copies a batch in one COPY; fields missing from a record are NULL
+func (s *postgresSink) Load(ctx context.Context, records []connector.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	columns := s.columns
+	if columns == nil {
+		for field := range records[0] {
+			columns = append(columns, field)
+		}
+		sort.Strings(columns)
+	}
+
+	rows := make([][]any, len(records))
+	for i, rec := range records {
+		row := make([]any, len(columns))
+		for j, c := range columns {
+			row[j] = rec[c]
+		}
+		rows[i] = row
+	}
+	if _, err := s.pool.CopyFrom(ctx, s.table, columns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("postgres sink: copy into %s: %w", s.table.Sanitize(), err)
+	}
+	return nil
+}
+
+func (s *postgresSink) HealthCheck(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *postgresSink) Close() error {
+	if s.owned {
+		s.pool.Close()
+	}
+	return nil
+}
+
+// renameOptions configures the rename transform
+type renameOptions struct {
+	Fields map[string]string `yaml:"fields"` // Old name -> new name
+}
+
+func newRename(_ context.Context, spec connector.Spec) (connector.Transform, error) {
+	var opts renameOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Fields) == 0 {
+		return nil, fmt.Errorf("rename options: fields is required")
+	}
+	return func(_ context.Context, rec connector.Record) (connector.Record, error) {
+		// Take every value out first, so swaps and chains rename correctly
+		renamed := make(map[string]any, len(opts.Fields))
+		for from, to := range opts.Fields {
+			if v, ok := rec[from]; ok {
+				renamed[to] = v
+				delete(rec, from)
+			}
+		}
+		for to, v := range renamed {
+			rec[to] = v
+		}
+		return rec, nil
+	}, nil
+}
+
+// dropOptions configures the drop transform
+type dropOptions struct {
+	Fields []string `yaml:"fields"`
+}
+
+func newDrop(_ context.Context, spec connector.Spec) (connector.Transform, error) {
+	var opts dropOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Fields) == 0 {
+		return nil, fmt.Errorf("drop options: fields is required")
+	}
+	return func(_ context.Context, rec connector.Record) (connector.Record, error) {
+		for _, f := range opts.Fields {
+			delete(rec, f)
+		}
+		return rec, nil
+	}, nil
+}
+
+// anonymizeOptions configures the anonymize transform, e.g.
+//
+//	options:
+//	  salt: ${ANONYMIZE_SALT}
+//	  fields:
+//	    id: keep
+//	    email: mask_email
+//	    name: fake_name
+//	    customer_id: hash
+//	    notes: [mask, redact]
+//
+// Every field is dropped unless listed, or Others is "keep". See
+// anonymize.Named for the anonymizers; a list chains them.
+type anonymizeOptions struct {
+	Salt   string                 `yaml:"salt"`
+	Fields map[string]anonymizers `yaml:"fields"`
+	Others string                 `yaml:"others"` // drop (default) or keep
+}
+
+// anonymizers names one anonymizer, or a chain of them
+type anonymizers []string
+
+func (a *anonymizers) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*a = anonymizers{node.Value}
+		return nil
+	}
+	return node.Decode((*[]string)(a))
+}
+
+func newAnonymize(_ context.Context, spec connector.Spec) (connector.Transform, error) {
+	var opts anonymizeOptions
+	if err := spec.Decode(&opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Fields) == 0 {
+		return nil, fmt.Errorf("anonymize options: fields is required")
+	}
+	if opts.Others != "" && opts.Others != "drop" && opts.Others != "keep" {
+		return nil, fmt.Errorf("anonymize options: others must be drop or keep")
+	}
+
+	fields := make(anonymize.Fields, len(opts.Fields))
+	salted := false
+	for path, names := range opts.Fields {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("anonymize options: field %s: no anonymizer", path)
+		}
+		fns := make([]anonymize.Func, len(names))
+		for i, name := range names {
+			fn, err := anonymize.Named(name, []byte(opts.Salt))
+			if err != nil {
+				return nil, fmt.Errorf("anonymize options: field %s: %w", path, err)
+			}
+			fns[i] = fn
+			salted = salted || name == "hash" || strings.HasPrefix(name, "fake_")
+		}
+		fields[path] = anonymize.Chain(fns...)
+	}
+	if salted && opts.Salt == "" {
+		return nil, fmt.Errorf("anonymize options: salt is required to hash or fake values")
+	}
+
+	keepOthers := opts.Others == "keep"
+	return func(_ context.Context, rec connector.Record) (connector.Record, error) {
+		if err := fields.Apply(rec, keepOthers); err != nil {
+			return nil, err
+		}
+		return rec, nil
+	}, nil
+}
@@ -0,0 +1,211 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/cuong/go-etl/pkg/connector"
+)
+
+var (
+	_ connector.Preflighter = (*jsonlSource)(nil)
+	_ connector.Preflighter = (*csvSource)(nil)
+	_ connector.Preflighter = (*postgresSource)(nil)
+	_ connector.Preflighter = (*stdoutSink)(nil)
+	_ connector.Preflighter = (*fileSink)(nil)
+	_ connector.Preflighter = (*postgresSink)(nil)
+)
+
+// Preflight checks that the files can be opened; JSONL records have no
+// fixed fields to check
+func (s *jsonlSource) Preflight(context.Context, []string) error {
+	for _, pattern := range s.paths {
+		if pattern == "-" {
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if matches == nil {
+			return fmt.Errorf("no files match %q", pattern)
+		}
+		for _, file := range matches {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			f.Close()
+		}
+	}
+	return nil
+}
+
+// Preflight checks that the files can be opened and have the fields as
+// columns
+func (s *csvSource) Preflight(_ context.Context, fields []string) error {
+	columns, err := s.src.Columns()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range fields {
+		if !slices.Contains(columns, f) {
+			errs = append(errs, fmt.Errorf("field %s is not a CSV column", f))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Preflight describes the query, checking its syntax, its permissions and
+// that it returns the fields, in a read-only transaction that is rolled back
+func (s *postgresSource) Preflight(ctx context.Context, fields []string) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	desc, err := tx.Conn().PgConn().Prepare(ctx, "", s.query, nil)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	// Unlike preparing, planning checks the privileges of the query
+	rows, err := tx.Query(ctx, "EXPLAIN "+s.query, s.args...)
+	if err == nil {
+		rows.Close()
+		err = rows.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	columns := make([]string, len(desc.Fields))
+	for i, f := range desc.Fields {
+		columns[i] = f.Name
+	}
+	var errs []error
+	for _, f := range fields {
+		if !slices.Contains(columns, f) {
+			errs = append(errs, fmt.Errorf("field %s is not a column of the query", f))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Preflight checks that the output records have the CSV columns
+func (s *stdoutSink) Preflight(_ context.Context, fields []string) error {
+	return checkColumns(s.columns, fields)
+}
+
+// Preflight checks that files can be created in the directory and that
+// the output records have the CSV columns
+func (s *fileSink) Preflight(_ context.Context, fields []string) error {
+	if err := os.MkdirAll(s.cfg.Dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.cfg.Dir, ".preflight-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return checkColumns(s.cfg.Columns, fields)
+}
+
+// Preflight checks that the table exists and can be inserted into, and
+// that its columns match the fields: every field is a writable column and
+// every column that requires a value is written
+func (s *postgresSink) Preflight(ctx context.Context, fields []string) error {
+	table := s.table.Sanitize()
+
+	var exists, canInsert bool
+	err := s.pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("table %s does not exist", table)
+	}
+	err = s.pool.QueryRow(ctx, `SELECT has_table_privilege($1::regclass, 'INSERT')`, table).Scan(&canInsert)
+	if err != nil {
+		return err
+	}
+	if !canInsert {
+		return fmt.Errorf("no INSERT privilege on %s", table)
+	}
+
+	if s.columns != nil {
+		if err := checkColumns(s.columns, fields); err != nil {
+			return err
+		}
+		fields = s.columns
+	}
+	if fields == nil {
+		return nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT attname, attgenerated <> '', attnotnull AND NOT atthasdef AND attidentity = ''
+		FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped`, table)
+	if err != nil {
+		return err
+	}
+	type column struct{ generated, required bool }
+	columns := make(map[string]column)
+	for rows.Next() {
+		var (
+			name string
+			c    column
+		)
+		if err := rows.Scan(&name, &c.generated, &c.required); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = c
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, f := range fields {
+		c, ok := columns[f]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("column %s does not exist in %s", f, table))
+		case c.generated:
+			errs = append(errs, fmt.Errorf("column %s of %s is generated", f, table))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(columns)) {
+		if columns[name].required && !slices.Contains(fields, name) {
+			errs = append(errs, fmt.Errorf("column %s of %s requires a value but is not written", name, table))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkColumns checks that records with the fields have every column; it
+// passes when the fields are unknown
+func checkColumns(columns, fields []string) error {
+	if fields == nil {
+		return nil
+	}
+	var errs []error
+	for _, c := range columns {
+		if !slices.Contains(fields, c) {
+			errs = append(errs, fmt.Errorf("column %s is not a field of the output records", c))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cuong/go-etl/pkg/connector"
+	"github.com/cuong/go-etl/pkg/reconcile"
+)
+
+var (
+	_ connector.Reconciler = (*jsonlSource)(nil)
+	_ connector.Reconciler = (*csvSource)(nil)
+	_ connector.Reconciler = (*postgresSource)(nil)
+	_ connector.Reconciler = (*postgresSink)(nil)
+)
+
+// Count reads the files, counting their records
+func (s *jsonlSource) Count(ctx context.Context) (int64, error) {
+	n, _, err := scan(ctx, s, nil)
+	return n, err
+}
+
+// Checksum reads the files, checksumming the top-level fields of their
+// records
+func (s *jsonlSource) Checksum(ctx context.Context, fields []string) (uint64, error) {
+	_, sum, err := scan(ctx, s, fields)
+	return sum, err
+}
+
+// Count reads the files, counting their rows
+func (s *csvSource) Count(ctx context.Context) (int64, error) {
+	n, _, err := scan(ctx, s, nil)
+	return n, err
+}
+
+// Checksum reads the files, checksumming the columns of their rows
+func (s *csvSource) Checksum(ctx context.Context, fields []string) (uint64, error) {
+	_, sum, err := scan(ctx, s, fields)
+	return sum, err
+}
+
+// scan extracts every record of src, returning their count and the
+// checksum of their fields; a record that cannot be read fails the scan
+func scan(ctx context.Context, src connector.Source, fields []string) (int64, uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	records, err := src.Extract(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	var n int64
+	var c reconcile.Checksum
+	values := make([]any, len(fields))
+	for rec := range records {
+		if rec.Err != nil {
+			return 0, 0, rec.Err
+		}
+		n++
+		if fields == nil {
+			continue
+		}
+		for i, f := range fields {
+			values[i] = rec.Data[f]
+		}
+		c.Add(values...)
+	}
+	return n, c.Sum(), ctx.Err()
+}
+
+// Count counts the rows of the query
+func (s *postgresSource) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.pool.QueryRow(ctx, "SELECT count(*) FROM ("+s.query+") q", s.args...).Scan(&n)
+	return n, err
+}
+
+// Checksum checksums the columns of the rows of the query
+func (s *postgresSource) Checksum(ctx context.Context, fields []string) (uint64, error) {
+	return checksumQuery(ctx, s.pool, fields, "("+s.query+") q", s.args...)
+}
+
+// Count counts the rows of the table, including those it held before the
+// pipeline ran
+func (s *postgresSink) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.pool.QueryRow(ctx, "SELECT count(*) FROM "+s.table.Sanitize()).Scan(&n)
+	return n, err
+}
+
+// Checksum checksums the columns of the rows of the table
+func (s *postgresSink) Checksum(ctx context.Context, fields []string) (uint64, error) {
+	return checksumQuery(ctx, s.pool, fields, s.table.Sanitize())
+}
+
+// checksumQuery checksums the columns of the rows of from, a table or a
+// subquery
+func checksumQuery(ctx context.Context, pool *pgxpool.Pool, fields []string, from string, args ...any) (uint64, error) {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = pgx.Identifier{f}.Sanitize()
+	}
+	rows, err := pool.Query(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), from), args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var c reconcile.Checksum
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return 0, err
+		}
+		c.Add(values...)
+	}
+	return c.Sum(), rows.Err()
+}
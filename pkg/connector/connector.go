@@ -0,0 +1,268 @@
+// Package connector registers sources, sinks and transforms of schemaless
+// records by name, so config-driven pipelines can instantiate them
+// Connectors register from init functions of the packages providing them,
+// with explicit Register calls, or from Go plugins (see Registry.LoadPlugin).
+package connector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/transform"
+)
+
+// Record is a schemaless record flowing through a config-driven pipeline
+type Record = transform.Record
+
+// Source extracts records
+// Sources may also implement PreProcess and PostProcess, called around
+// every run, io.Closer, etl.HealthChecker, Preflighter and Reconciler.
+type Source interface {
+	Extract(ctx context.Context) (<-chan etl.Payload[Record], error)
+}
+
+// Sink loads batches of records
+// Sinks may also implement PreProcess and PostProcess, called around every
+// run, io.Closer, etl.HealthChecker, Preflighter and Reconciler.
+type Sink interface {
+	Load(ctx context.Context, records []Record) error
+}
+
+// Preflighter is implemented by sources and sinks that can check, without
+// moving data, that a pipeline will work against them: permissions, tables
+// and the fields it reads or writes
+// Sources get the fields the pipeline reads from their records. Sinks get
+// the fields of the records they will load, or nil when records pass
+// through unchanged and their fields are unknown.
+type Preflighter interface {
+	Preflight(ctx context.Context, fields []string) error
+}
+
+// Reconciler is implemented by sources and sinks that can count and
+// checksum their records, so a pipeline can reconcile its sink with its
+// source once it has run (see package reconcile)
+type Reconciler interface {
+	Count(ctx context.Context) (int64, error)
+
+	// Checksum returns the reconcile.Checksum of the fields of every record
+	Checksum(ctx context.Context, fields []string) (uint64, error)
+}
+
+// Transform rewrites a record; it returns the record to load, which may be
+// rec itself
+type Transform func(ctx context.Context, rec Record) (Record, error)
+
+// Spec configures one connector of a pipeline definition
+type Spec struct {
+	Type       string         `yaml:"type"`
+	Connection string         `yaml:"connection,omitempty"` // DSN or URL, or a named connection (see connections.Registry), if the connector needs one
+	Options    map[string]any `yaml:"options,omitempty"`    // Connector specific settings
+}
+
+// Decode decodes the options into v, a pointer to a struct with yaml tags,
+// rejecting unknown options
+func (s Spec) Decode(v any) error {
+	if len(s.Options) == 0 {
+		return nil
+	}
+	data, err := yaml.Marshal(s.Options)
+	if err != nil {
+		return fmt.Errorf("%s options: %w", s.Type, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%s options: %w", s.Type, err)
+	}
+	return nil
+}
+
+// SourceFactory creates a source from its spec
+type SourceFactory func(ctx context.Context, spec Spec) (Source, error)
+
+// SinkFactory creates a sink from its spec
+type SinkFactory func(ctx context.Context, spec Spec) (Sink, error)
+
+// TransformFactory creates a transform from its spec
+type TransformFactory func(ctx context.Context, spec Spec) (Transform, error)
+
+// Registry maps connector types to factories
+type Registry struct {
+	mu         sync.RWMutex
+	sources    map[string]SourceFactory
+	sinks      map[string]SinkFactory
+	transforms map[string]TransformFactory
+	plugins    map[string]bool // Absolute paths of loaded plugins
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		sources:    make(map[string]SourceFactory),
+		sinks:      make(map[string]SinkFactory),
+		transforms: make(map[string]TransformFactory),
+		plugins:    make(map[string]bool),
+	}
+}
+
+// DefaultRegistry is used by the package-level helpers
+var DefaultRegistry = NewRegistry()
+
+// RegisterSource adds a source type, failing if the name is taken
+func (r *Registry) RegisterSource(name string, factory SourceFactory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sources[name]; exists {
+		return fmt.Errorf("source %q already registered", name)
+	}
+	r.sources[name] = factory
+	return nil
+}
+
+// RegisterSink adds a sink type, failing if the name is taken
+func (r *Registry) RegisterSink(name string, factory SinkFactory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.sinks[name]; exists {
+		return fmt.Errorf("sink %q already registered", name)
+	}
+	r.sinks[name] = factory
+	return nil
+}
+
+// RegisterTransform adds a transform type, failing if the name is taken
+func (r *Registry) RegisterTransform(name string, factory TransformFactory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.transforms[name]; exists {
+		return fmt.Errorf("transform %q already registered", name)
+	}
+	r.transforms[name] = factory
+	return nil
+}
+
+// NewSource creates a source of the type named by spec
+func (r *Registry) NewSource(ctx context.Context, spec Spec) (Source, error) {
+	r.mu.RLock()
+	factory, ok := r.sources[spec.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown source type %q", spec.Type)
+	}
+	return factory(ctx, spec)
+}
+
+// NewSink creates a sink of the type named by spec
+func (r *Registry) NewSink(ctx context.Context, spec Spec) (Sink, error) {
+	r.mu.RLock()
+	factory, ok := r.sinks[spec.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %q", spec.Type)
+	}
+	return factory(ctx, spec)
+}
+
+// NewTransform creates a transform of the type named by spec
+func (r *Registry) NewTransform(ctx context.Context, spec Spec) (Transform, error) {
+	r.mu.RLock()
+	factory, ok := r.transforms[spec.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown transform type %q", spec.Type)
+	}
+	return factory(ctx, spec)
+}
+
+// HasSource reports whether a source type is registered
+func (r *Registry) HasSource(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.sources[name]
+	return ok
+}
+
+// HasSink reports whether a sink type is registered
+func (r *Registry) HasSink(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.sinks[name]
+	return ok
+}
+
+// HasTransform reports whether a transform type is registered
+func (r *Registry) HasTransform(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.transforms[name]
+	return ok
+}
+
+// Sources returns the registered source types, sorted
+func (r *Registry) Sources() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sortedKeys(r.sources)
+}
+
+// Sinks returns the registered sink types, sorted
+func (r *Registry) Sinks() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sortedKeys(r.sinks)
+}
+
+// Transforms returns the registered transform types, sorted
+func (r *Registry) Transforms() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sortedKeys(r.transforms)
+}
+
+// RegisterSource adds a source type to the default registry
+func RegisterSource(name string, factory SourceFactory) error {
+	return DefaultRegistry.RegisterSource(name, factory)
+}
+
+// RegisterSink adds a sink type to the default registry
+func RegisterSink(name string, factory SinkFactory) error {
+	return DefaultRegistry.RegisterSink(name, factory)
+}
+
+// RegisterTransform adds a transform type to the default registry
+func RegisterTransform(name string, factory TransformFactory) error {
+	return DefaultRegistry.RegisterTransform(name, factory)
+}
+
+// LoadPlugin loads a Go plugin into the default registry
+func LoadPlugin(path string) error {
+	return DefaultRegistry.LoadPlugin(path)
+}
+
+// sortedKeys returns the keys of m, sorted
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
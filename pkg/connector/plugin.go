@@ -0,0 +1,61 @@
+package connector
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+)
+
+// PluginSymbol is the function a Go plugin exports to register its
+// connectors, of type func(*connector.Registry) error
+const PluginSymbol = "Register"
+
+// LoadPlugin opens the Go plugin at path and calls its Register function
+// with r
+// A plugin is a main package built with -buildmode=plugin against the same
+// go-etl version and Go toolchain as the program loading it. Loading the
+// same path again is a no-op.
+func (r *Registry) LoadPlugin(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("connector: plugin %s: %w", path, err)
+	}
+
+	// Mark the path first, so concurrent loads register the plugin once
+	r.mu.Lock()
+	if r.plugins[abs] {
+		r.mu.Unlock()
+		return nil
+	}
+	r.plugins[abs] = true
+	r.mu.Unlock()
+
+	register, err := openPlugin(abs)
+	if err == nil {
+		err = register(r)
+	}
+	if err != nil {
+		r.mu.Lock()
+		delete(r.plugins, abs)
+		r.mu.Unlock()
+		return fmt.Errorf("connector: plugin %s: %w", path, err)
+	}
+	return nil
+}
+
+// openPlugin opens a plugin and looks up its register function
+func openPlugin(path string) (func(*Registry) error, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	register, ok := sym.(func(*Registry) error)
+	if !ok {
+		return nil, fmt.Errorf("%s is %T, want func(*connector.Registry) error", PluginSymbol, sym)
+	}
+	return register, nil
+}
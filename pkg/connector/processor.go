@@ -0,0 +1,124 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Processor joins a source, a record transformation and a sink into an
+// etl.ETLProcessor
+type Processor struct {
+	Source Source
+	Sink   Sink
+	Map    func(ctx context.Context, rec Record) Record // nil passes records through
+}
+
+var _ etl.ETLProcessor[Record, Record] = (*Processor)(nil)
+
+// Resumer is implemented by sources that can continue from a checkpoint
+// position, see etl.Resumer
+type Resumer interface {
+	Resume(ctx context.Context, position json.RawMessage) error
+	Position(rec Record) json.RawMessage
+}
+
+// preProcessor and postProcessor are the optional run hooks of sources
+// and sinks
+type preProcessor interface {
+	PreProcess(ctx context.Context) error
+}
+
+type postProcessor interface {
+	PostProcess(ctx context.Context) error
+}
+
+func (p *Processor) Extract(ctx context.Context) (<-chan etl.Payload[Record], error) {
+	return p.Source.Extract(ctx)
+}
+
+func (p *Processor) Transform(ctx context.Context, rec Record) Record {
+	if p.Map == nil {
+		return rec
+	}
+	return p.Map(ctx, rec)
+}
+
+func (p *Processor) Load(ctx context.Context, records []Record) error {
+	return p.Sink.Load(ctx, records)
+}
+
+// PreProcess runs the PreProcess hooks of the sink, then the source
+func (p *Processor) PreProcess(ctx context.Context) error {
+	for _, c := range []any{p.Sink, p.Source} {
+		if hook, ok := c.(preProcessor); ok {
+			if err := hook.PreProcess(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PostProcess runs the PostProcess hooks of the source, then the sink
+func (p *Processor) PostProcess(ctx context.Context) error {
+	for _, c := range []any{p.Source, p.Sink} {
+		if hook, ok := c.(postProcessor); ok {
+			if err := hook.PostProcess(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HealthCheck checks the source and sink, if they implement
+// etl.HealthChecker
+func (p *Processor) HealthCheck(ctx context.Context) error {
+	var errs []error
+	for _, c := range []any{p.Source, p.Sink} {
+		if checker, ok := c.(etl.HealthChecker); ok {
+			errs = append(errs, checker.HealthCheck(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the source and sink if they implement io.Closer
+func (p *Processor) Close() error {
+	var errs []error
+	for _, c := range []any{p.Source, p.Sink} {
+		if closer, ok := c.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ResumableProcessor is a Processor whose source implements Resumer, so
+// its progress is committed to the manager's checkpoint store
+type ResumableProcessor struct {
+	*Processor
+}
+
+var _ etl.Resumer[Record] = ResumableProcessor{}
+
+// NewProcessor returns p, as a ResumableProcessor if its source implements
+// Resumer
+func NewProcessor(p *Processor) etl.ETLProcessor[Record, Record] {
+	if _, ok := p.Source.(Resumer); ok {
+		return ResumableProcessor{p}
+	}
+	return p
+}
+
+func (p ResumableProcessor) Resume(ctx context.Context, position json.RawMessage) error {
+	return p.Source.(Resumer).Resume(ctx, position)
+}
+
+func (p ResumableProcessor) Position(rec Record) json.RawMessage {
+	return p.Source.(Resumer).Position(rec)
+}
@@ -0,0 +1,5 @@
+// Package controlplanepb contains the generated gRPC bindings of the
+// control-plane service
+package controlplanepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative controlplane.proto
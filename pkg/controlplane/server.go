@@ -0,0 +1,429 @@
+// Package controlplane exposes an etl.Manager over gRPC so that ETL workers
+// can be controlled from a central orchestrator
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/cuong/go-etl/pkg/controlplane/controlplanepb"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// eventBuffer is how many events a slow StreamEvents client may fall behind
+// before further events are dropped for it
+const eventBuffer = 256
+
+// Defaults of how long and how many finished runs are kept, see
+// SetRunRetention
+const (
+	defaultRunRetention = time.Hour
+	defaultMaxRuns      = 100
+)
+
+// Server implements the ControlPlane gRPC service on top of a Manager
+type Server struct {
+	controlplanepb.UnimplementedControlPlaneServer
+
+	manager *etl.Manager
+
+	mu          sync.Mutex
+	meta        map[string]*controlplanepb.PipelineInfo
+	runs        map[string]*run
+	nextRun     int
+	retention   time.Duration // How long finished runs are kept
+	maxRuns     int           // How many finished runs are kept
+	subscribers map[chan *controlplanepb.Event]struct{}
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+// run is a manager run started through the control plane
+type run struct {
+	seq       int
+	id        string
+	pipelines []string
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error     // Only read after done is closed
+	finished  time.Time // Set under Server.mu before done is closed
+}
+
+// NewServer creates a control-plane server for m
+// Events from m are forwarded to StreamEvents clients from then on.
+func NewServer(m *etl.Manager) *Server {
+	s := &Server{
+		manager:     m,
+		meta:        make(map[string]*controlplanepb.PipelineInfo),
+		runs:        make(map[string]*run),
+		retention:   defaultRunRetention,
+		maxRuns:     defaultMaxRuns,
+		subscribers: make(map[chan *controlplanepb.Event]struct{}),
+		closed:      make(chan struct{}),
+	}
+	m.OnEvent(s.broadcast)
+	return s
+}
+
+// Register registers the server on a gRPC server
+func (s *Server) Register(g *grpc.Server) {
+	controlplanepb.RegisterControlPlaneServer(g, s)
+}
+
+// SetRunRetention keeps finished runs in Status, and cancellable by ID, for
+// retention after they return, and at most maxRuns of them, evicting the
+// oldest first; zero values keep the defaults of an hour and 100 runs
+func (s *Server) SetRunRetention(retention time.Duration, maxRuns int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retention, s.maxRuns = defaultRunRetention, defaultMaxRuns
+	if retention > 0 {
+		s.retention = retention
+	}
+	if maxRuns > 0 {
+		s.maxRuns = maxRuns
+	}
+	s.evictRuns(time.Now())
+}
+
+// Close cancels every run started through the server, waits for them to
+// return and ends all event streams
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	runs := make([]*run, 0, len(s.runs))
+	for _, r := range s.runs {
+		runs = append(runs, r)
+	}
+	s.mu.Unlock()
+
+	for _, r := range runs {
+		r.cancel()
+		<-r.done
+	}
+}
+
+// RegisterPipeline attaches orchestrator metadata to a registered pipeline
+func (s *Server) RegisterPipeline(ctx context.Context, req *controlplanepb.RegisterPipelineRequest) (*controlplanepb.PipelineInfo, error) {
+	if err := s.checkPipelines(req.GetName()); err != nil {
+		return nil, err
+	}
+
+	info := &controlplanepb.PipelineInfo{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Labels:      req.GetLabels(),
+	}
+
+	s.mu.Lock()
+	s.meta[info.Name] = info
+	s.mu.Unlock()
+
+	return info, nil
+}
+
+// ListPipelines returns every pipeline of the manager with its metadata
+func (s *Server) ListPipelines(ctx context.Context, req *controlplanepb.ListPipelinesRequest) (*controlplanepb.ListPipelinesResponse, error) {
+	names := s.pipelineNames()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &controlplanepb.ListPipelinesResponse{}
+	for _, name := range names {
+		info, ok := s.meta[name]
+		if !ok {
+			info = &controlplanepb.PipelineInfo{Name: name}
+		}
+		resp.Pipelines = append(resp.Pipelines, info)
+	}
+	return resp, nil
+}
+
+// Run starts the requested pipelines in the background
+// It fails with FailedPrecondition if one of them is already running,
+// through the control plane or otherwise.
+func (s *Server) Run(ctx context.Context, req *controlplanepb.RunRequest) (*controlplanepb.RunResponse, error) {
+	names := req.GetPipelines()
+	if err := s.checkPipelines(names...); err != nil {
+		return nil, err
+	}
+	all, targets := s.pipelineNames(), names
+	if len(targets) == 0 {
+		targets = all
+	}
+	running := make(map[string]bool)
+	for _, ps := range s.manager.Status() {
+		if ps.State == etl.StateRunning || ps.State == etl.StateRetrying {
+			running[ps.Name] = true
+		}
+	}
+
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+		// Checked under the lock, so Close waits for every registered run
+		s.mu.Unlock()
+		return nil, status.Error(codes.Unavailable, "control plane is shutting down")
+	default:
+	}
+
+	// Runs of the control plane count from their start, before the
+	// manager reports their pipelines as running
+	for _, r := range s.runs {
+		select {
+		case <-r.done:
+			continue
+		default:
+		}
+		pipelines := r.pipelines
+		if len(pipelines) == 0 {
+			pipelines = all
+		}
+		for _, name := range pipelines {
+			running[name] = true
+		}
+	}
+	for _, name := range targets {
+		if running[name] {
+			s.mu.Unlock()
+			return nil, status.Errorf(codes.FailedPrecondition, "pipeline %s is already running", name)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.evictRuns(time.Now())
+	s.nextRun++
+	r := &run{
+		seq:       s.nextRun,
+		id:        fmt.Sprintf("run-%d-%d", time.Now().Unix(), s.nextRun),
+		pipelines: names,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	s.runs[r.id] = r
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		if len(names) == 0 {
+			r.err = s.manager.RunAll(runCtx)
+		} else {
+			r.err = s.manager.Run(runCtx, names...)
+		}
+
+		s.mu.Lock()
+		r.finished = time.Now()
+		s.mu.Unlock()
+		close(r.done)
+	}()
+
+	return &controlplanepb.RunResponse{RunId: r.id}, nil
+}
+
+// Status returns pipeline states and the runs started through the server
+// Finished runs are reported until evicted, see SetRunRetention.
+func (s *Server) Status(ctx context.Context, req *controlplanepb.StatusRequest) (*controlplanepb.StatusResponse, error) {
+	names := req.GetPipelines()
+	if err := s.checkPipelines(names...); err != nil {
+		return nil, err
+	}
+
+	resp := &controlplanepb.StatusResponse{}
+	for _, ps := range s.manager.Status() {
+		if len(names) > 0 && !slices.Contains(names, ps.Name) {
+			continue
+		}
+		resp.Pipelines = append(resp.Pipelines, pipelineStatus(ps))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictRuns(time.Now())
+	for _, r := range s.sortedRuns() {
+		info := &controlplanepb.RunInfo{RunId: r.id, Pipelines: r.pipelines}
+		select {
+		case <-r.done:
+			info.Done = true
+			if r.err != nil {
+				info.Error = r.err.Error()
+			}
+		default:
+		}
+		resp.Runs = append(resp.Runs, info)
+	}
+	return resp, nil
+}
+
+// Cancel cancels a run and waits for it to return
+func (s *Server) Cancel(ctx context.Context, req *controlplanepb.CancelRequest) (*controlplanepb.CancelResponse, error) {
+	s.mu.Lock()
+	r, ok := s.runs[req.GetRunId()]
+	s.mu.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "run %s not found", req.GetRunId())
+	}
+
+	r.cancel()
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	return &controlplanepb.CancelResponse{}, nil
+}
+
+// StreamEvents streams manager events until the client goes away or the
+// server is closed
+// Events that a client is too slow to receive are dropped.
+func (s *Server) StreamEvents(req *controlplanepb.StreamEventsRequest, stream grpc.ServerStreamingServer[controlplanepb.Event]) error {
+	names := req.GetPipelines()
+	if err := s.checkPipelines(names...); err != nil {
+		return err
+	}
+
+	events := make(chan *controlplanepb.Event, eventBuffer)
+	s.mu.Lock()
+	s.subscribers[events] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, events)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.closed:
+			return nil
+		case e := <-events:
+			if len(names) > 0 && e.Pipeline != "" && !slices.Contains(names, e.Pipeline) {
+				continue
+			}
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// evictRuns forgets the runs that finished more than the retention period
+// before now, then the oldest finished runs past the maximum
+// Callers hold s.mu.
+func (s *Server) evictRuns(now time.Time) {
+	finished := 0
+	for id, r := range s.runs {
+		switch {
+		case r.finished.IsZero():
+		case now.Sub(r.finished) > s.retention:
+			delete(s.runs, id)
+		default:
+			finished++
+		}
+	}
+
+	for _, r := range s.sortedRuns() {
+		if finished <= s.maxRuns {
+			break
+		}
+		if !r.finished.IsZero() {
+			delete(s.runs, r.id)
+			finished--
+		}
+	}
+}
+
+// sortedRuns returns the runs in the order they were started
+// Callers hold s.mu.
+func (s *Server) sortedRuns() []*run {
+	runs := make([]*run, 0, len(s.runs))
+	for _, r := range s.runs {
+		runs = append(runs, r)
+	}
+	slices.SortFunc(runs, func(a, b *run) int { return a.seq - b.seq })
+	return runs
+}
+
+// broadcast forwards a manager event to all subscribers without blocking
+func (s *Server) broadcast(e etl.Event) {
+	msg := &controlplanepb.Event{
+		Type:     e.Type.String(),
+		Pipeline: e.Pipeline,
+		Time:     timestamppb.New(e.Time),
+		Attempt:  int32(e.Attempt),
+		Records:  int64(e.Records),
+	}
+	if e.Err != nil {
+		msg.Error = e.Err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for events := range s.subscribers {
+		select {
+		case events <- msg:
+		default:
+		}
+	}
+}
+
+// checkPipelines returns a NotFound error for names unknown to the manager
+func (s *Server) checkPipelines(names ...string) error {
+	known := s.pipelineNames()
+
+	for _, name := range names {
+		if !slices.Contains(known, name) {
+			return status.Errorf(codes.NotFound, "pipeline %s is not registered", name)
+		}
+	}
+	return nil
+}
+
+// pipelineNames returns the manager's pipeline names in registration order
+func (s *Server) pipelineNames() []string {
+	pipelines := s.manager.Pipelines()
+	names := make([]string, len(pipelines))
+	for i, p := range pipelines {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// pipelineStatus converts a manager status to its wire form
+func pipelineStatus(ps etl.PipelineStatus) *controlplanepb.PipelineStatus {
+	msg := &controlplanepb.PipelineStatus{
+		Name:      ps.Name,
+		State:     ps.State.String(),
+		Attempt:   int32(ps.Attempt),
+		Extracted: ps.Progress.Extracted,
+		Loaded:    ps.Progress.Loaded,
+		Batches:   ps.Progress.Batches,
+	}
+	if !ps.StartedAt.IsZero() {
+		msg.StartedAt = timestamppb.New(ps.StartedAt)
+	}
+	if !ps.FinishedAt.IsZero() {
+		msg.FinishedAt = timestamppb.New(ps.FinishedAt)
+	}
+	if ps.LastError != nil {
+		msg.LastError = ps.LastError.Error()
+	}
+	return msg
+}
@@ -0,0 +1,142 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/controlplane/controlplanepb"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// blockingProcessor extracts nothing until its run is cancelled
+type blockingProcessor struct {
+	started chan struct{} // Receives when Extract is called
+}
+
+func (p *blockingProcessor) Extract(ctx context.Context) (<-chan etl.Payload[int], error) {
+	p.started <- struct{}{}
+	ch := make(chan etl.Payload[int])
+	go func() {
+		defer close(ch)
+		<-ctx.Done()
+	}()
+	return ch, nil
+}
+
+func (p *blockingProcessor) Transform(_ context.Context, item int) int { return item }
+func (p *blockingProcessor) Load(context.Context, []int) error         { return nil }
+func (p *blockingProcessor) PreProcess(context.Context) error          { return nil }
+func (p *blockingProcessor) PostProcess(context.Context) error         { return nil }
+
+// eventStream collects the events of a StreamEvents call
+type eventStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *controlplanepb.Event
+}
+
+func (s *eventStream) Context() context.Context { return s.ctx }
+
+func (s *eventStream) Send(e *controlplanepb.Event) error {
+	s.events <- e
+	return nil
+}
+
+// waitEvent returns the next event of type typ, failing the test after a
+// few seconds
+func (s *eventStream) waitEvent(t *testing.T, typ string) *controlplanepb.Event {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-s.events:
+			if e.GetType() == typ {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", typ)
+		}
+	}
+}
+
+func TestServerRunCancelAndEvents(t *testing.T) {
+	m := etl.NewManager(&etl.Config{}, &bucket.Config{BatchSize: 1, Timeout: time.Second})
+	p := &blockingProcessor{started: make(chan struct{}, 1)}
+	if err := etl.AddPipelineGeneric[int, int](m, p, "blocking"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(m)
+	ctx := context.Background()
+
+	streamCtx, stopStream := context.WithCancel(ctx)
+	defer stopStream()
+	stream := &eventStream{ctx: streamCtx, events: make(chan *controlplanepb.Event, eventBuffer)}
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- s.StreamEvents(&controlplanepb.StreamEventsRequest{Pipelines: []string{"blocking"}}, stream)
+	}()
+	for subscribed := false; !subscribed; time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		subscribed = len(s.subscribers) > 0
+		s.mu.Unlock()
+	}
+
+	run, err := s.Run(ctx, &controlplanepb.RunRequest{Pipelines: []string{"blocking"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-p.started
+	if e := stream.waitEvent(t, etl.PipelineStarted.String()); e.GetPipeline() != "blocking" {
+		t.Errorf("started event of pipeline %q", e.GetPipeline())
+	}
+
+	for _, req := range []*controlplanepb.RunRequest{{Pipelines: []string{"blocking"}}, {}} {
+		if _, err := s.Run(ctx, req); status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("Run(%v) while running = %v, want FailedPrecondition", req.GetPipelines(), err)
+		}
+	}
+
+	if _, err := s.Cancel(ctx, &controlplanepb.CancelRequest{RunId: run.GetRunId()}); err != nil {
+		t.Fatal(err)
+	}
+	stream.waitEvent(t, etl.ManagerDone.String())
+	resp, err := s.Status(ctx, &controlplanepb.StatusRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs := resp.GetRuns(); len(runs) != 1 || !runs[0].GetDone() {
+		t.Errorf("runs after Cancel = %v, want the one run done", runs)
+	}
+
+	// The cancelled run no longer blocks another
+	if _, err := s.Run(ctx, &controlplanepb.RunRequest{}); err != nil {
+		t.Fatalf("Run() after Cancel = %v", err)
+	}
+	<-p.started
+	s.Close()
+	if err := <-streamErr; err != nil {
+		t.Errorf("StreamEvents() = %v", err)
+	}
+	if _, err := s.Run(ctx, &controlplanepb.RunRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Run() after Close = %v, want Unavailable", err)
+	}
+}
+
+func TestServerUnknownPipeline(t *testing.T) {
+	s := NewServer(etl.NewManager(&etl.Config{}, &bucket.Config{BatchSize: 1, Timeout: time.Second}))
+	ctx := context.Background()
+
+	if _, err := s.Run(ctx, &controlplanepb.RunRequest{Pipelines: []string{"missing"}}); status.Code(err) != codes.NotFound {
+		t.Errorf("Run() = %v, want NotFound", err)
+	}
+	if _, err := s.Cancel(ctx, &controlplanepb.CancelRequest{RunId: "run-1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Cancel() = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,268 @@
+// Package convert converts the loosely typed values of extracted records:
+// numbers written with locale separators, integers, floats, decimals and
+// booleans in their many spellings, and times as strings in common layouts
+// or epochs in several units, normalized to a time zone
+//
+// The functions convert one value; a Converter converts the fields of a
+// record, accumulating the errors so a Transform checks them once:
+//
+//	c := convert.Converter{Locale: convert.German, Location: berlin}
+//	out := Order{
+//		ID:     c.Int64("id", rec["id"]),
+//		Total:  c.Decimal("total", rec["total"]), // "1.234,50"
+//		Paid:   c.Bool("paid", rec["paid"]),      // "yes"
+//		Placed: c.Time("placed", rec["placed"]),  // 1700000000000
+//	}
+//	if err := c.Err(); err != nil {
+//		panic(err) // Dead-letters the batch
+//	}
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Error reports a value that could not be converted
+type Error struct {
+	Field string // Set by Converter
+	Value any
+	To    string // Target type, e.g. "int64"
+	Err   error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("convert: cannot convert %v (%T) to %s", e.Value, e.Value, e.To)
+	if e.Field != "" {
+		msg = fmt.Sprintf("convert: %s: cannot convert %v (%T) to %s", e.Field, e.Value, e.Value, e.To)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errFraction = errors.New("has a fraction")
+	errRange    = errors.New("out of range")
+	errNil      = errors.New("is nil")
+)
+
+// Int64 converts integers, floats without a fraction, decimals, and
+// strings of them without group separators, e.g. "42", " 42 " or "4.2e1";
+// see Locale for others
+func Int64(v any) (int64, error) {
+	return toInt64(v, plain)
+}
+
+func toInt64(v any, loc Locale) (int64, error) {
+	fail := func(err error) (int64, error) { return 0, &Error{Value: v, To: "int64", Err: err} }
+	switch n := v.(type) {
+	case nil:
+		return fail(errNil)
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint:
+		return fromUint(v, uint64(n))
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		return fromUint(v, n)
+	case float32:
+		return fromFloat(v, float64(n))
+	case float64:
+		return fromFloat(v, n)
+	case decimal.Decimal:
+		if !n.IsInteger() {
+			return fail(errFraction)
+		}
+		if !n.BigInt().IsInt64() {
+			return fail(errRange)
+		}
+		return n.IntPart(), nil
+	case json.Number:
+		return toInt64(string(n), plain)
+	case string:
+		s := loc.normalize(n)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			return fail(errors.New("not a number"))
+		}
+		i, err := toInt64(d, plain)
+		if err != nil {
+			return fail(errors.Unwrap(err))
+		}
+		return i, nil
+	case []byte:
+		return toInt64(string(n), loc)
+	}
+	return fail(nil)
+}
+
+func fromUint(v any, n uint64) (int64, error) {
+	if n > math.MaxInt64 {
+		return 0, &Error{Value: v, To: "int64", Err: errRange}
+	}
+	return int64(n), nil
+}
+
+func fromFloat(v any, f float64) (int64, error) {
+	switch {
+	case math.IsNaN(f) || math.IsInf(f, 0) || f < math.MinInt64 || f >= math.MaxInt64:
+		return 0, &Error{Value: v, To: "int64", Err: errRange}
+	case f != math.Trunc(f):
+		return 0, &Error{Value: v, To: "int64", Err: errFraction}
+	}
+	return int64(f), nil
+}
+
+// Float64 converts numbers, decimals and strings of them, e.g. "4.2" or
+// "1e-3"
+func Float64(v any) (float64, error) {
+	return toFloat64(v, plain)
+}
+
+func toFloat64(v any, loc Locale) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case decimal.Decimal:
+		return n.InexactFloat64(), nil
+	case json.Number:
+		return toFloat64(string(n), plain)
+	case string:
+		f, err := strconv.ParseFloat(loc.normalize(n), 64)
+		if err != nil {
+			return 0, &Error{Value: v, To: "float64", Err: errors.New("not a number")}
+		}
+		return f, nil
+	case []byte:
+		return toFloat64(string(n), loc)
+	case bool, nil:
+		return 0, &Error{Value: v, To: "float64"}
+	}
+	i, err := toInt64(v, loc)
+	if err != nil {
+		return 0, &Error{Value: v, To: "float64", Err: errors.Unwrap(err)}
+	}
+	return float64(i), nil
+}
+
+// Decimal converts numbers and strings of them exactly, e.g. "19.99";
+// floats convert to the shortest decimal that represents them
+func Decimal(v any) (decimal.Decimal, error) {
+	return toDecimal(v, plain)
+}
+
+func toDecimal(v any, loc Locale) (decimal.Decimal, error) {
+	switch n := v.(type) {
+	case decimal.Decimal:
+		return n, nil
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return decimal.Decimal{}, &Error{Value: v, To: "decimal", Err: errRange}
+		}
+		return decimal.NewFromFloat(n), nil
+	case float32:
+		return decimal.NewFromFloat32(n), nil
+	case json.Number:
+		return toDecimal(string(n), plain)
+	case string:
+		d, err := decimal.NewFromString(loc.normalize(n))
+		if err != nil {
+			return decimal.Decimal{}, &Error{Value: v, To: "decimal", Err: errors.New("not a number")}
+		}
+		return d, nil
+	case []byte:
+		return toDecimal(string(n), loc)
+	case bool, nil:
+		return decimal.Decimal{}, &Error{Value: v, To: "decimal"}
+	}
+	i, err := toInt64(v, loc)
+	if err != nil {
+		if n, ok := v.(uint64); ok {
+			return decimal.NewFromUint64(n), nil
+		}
+		return decimal.Decimal{}, &Error{Value: v, To: "decimal", Err: errors.Unwrap(err)}
+	}
+	return decimal.NewFromInt(i), nil
+}
+
+// Bool converts booleans, the numbers 0 and 1, and strings spelling them,
+// case-insensitively: true, t, yes, y, on and 1, or false, f, no, n, off
+// and 0
+func Bool(v any) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(b)) {
+		case "true", "t", "yes", "y", "on", "1":
+			return true, nil
+		case "false", "f", "no", "n", "off", "0":
+			return false, nil
+		}
+		return false, &Error{Value: v, To: "bool"}
+	case []byte:
+		return Bool(string(b))
+	}
+	if i, err := Int64(v); err == nil && (i == 0 || i == 1) {
+		return i == 1, nil
+	}
+	return false, &Error{Value: v, To: "bool"}
+}
+
+// String formats values: numbers in their shortest form without exponent,
+// times in RFC 3339 with nanoseconds, nil as ""
+func String(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case fmt.Stringer: // Decimals, json.Number
+		return x.String()
+	}
+	if i, err := Int64(v); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,131 @@
+package convert
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Converter converts the fields of records, accumulating the errors of its
+// conversions; failed conversions return the zero value
+// The zero Converter reads numbers without group separators and times
+// without a zone in UTC. A Converter is not safe for concurrent use: use
+// one per record or per goroutine.
+type Converter struct {
+	Locale   Locale         // Of numbers in strings
+	Location *time.Location // Of times without a zone, and of the times returned; UTC if nil
+	Unit     Unit           // Of epoch timestamps
+
+	errs []error
+}
+
+// Int64 converts the value of field, see Int64
+func (c *Converter) Int64(field string, v any) int64 {
+	i, err := toInt64(v, c.locale())
+	c.fail(field, err)
+	return i
+}
+
+// Float64 converts the value of field, see Float64
+func (c *Converter) Float64(field string, v any) float64 {
+	f, err := toFloat64(v, c.locale())
+	c.fail(field, err)
+	return f
+}
+
+// Decimal converts the value of field, see Decimal
+func (c *Converter) Decimal(field string, v any) decimal.Decimal {
+	d, err := toDecimal(v, c.locale())
+	c.fail(field, err)
+	return d
+}
+
+// Bool converts the value of field, see Bool
+func (c *Converter) Bool(field string, v any) bool {
+	b, err := Bool(v)
+	c.fail(field, err)
+	return b
+}
+
+// String formats the value of field, see String
+func (c *Converter) String(field string, v any) string {
+	return String(v)
+}
+
+// Time converts the value of field, see TimeIn; numbers are epoch
+// timestamps of c.Unit
+func (c *Converter) Time(field string, v any) time.Time {
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	var t time.Time
+	var err error
+	switch {
+	case c.Unit == Auto:
+		t, err = toTime(v, loc)
+	default:
+		t, err = c.epoch(v)
+	}
+	c.fail(field, err)
+	return t.In(loc)
+}
+
+// epoch converts a number, or a string of one, to the time of the
+// timestamp it is in c.Unit
+func (c *Converter) epoch(v any) (time.Time, error) {
+	if t, ok := v.(time.Time); ok {
+		return t, nil
+	}
+	if n, err := toInt64(v, plain); err == nil {
+		return FromEpoch(n, c.Unit), nil
+	}
+	f, err := toFloat64(v, plain)
+	if err != nil {
+		return time.Time{}, &Error{Value: v, To: "time", Err: errors.Unwrap(err)}
+	}
+	return fromEpochFloat(f, c.Unit), nil
+}
+
+// Optional is true when v is nil or an empty string, so optional fields
+// can be skipped before converting them:
+//
+//	if !convert.Optional(rec["discount"]) {
+//		out.Discount = c.Decimal("discount", rec["discount"])
+//	}
+func Optional(v any) bool {
+	s, ok := v.(string)
+	return v == nil || ok && s == ""
+}
+
+// Err returns the errors of the conversions so far, joined, or nil
+func (c *Converter) Err() error {
+	return errors.Join(c.errs...)
+}
+
+// Reset forgets the errors, e.g. to convert the next record
+func (c *Converter) Reset() {
+	c.errs = c.errs[:0]
+}
+
+// fail records the error of converting field, if any
+func (c *Converter) fail(field string, err error) {
+	if err == nil {
+		return
+	}
+	var convErr *Error
+	if errors.As(err, &convErr) {
+		e := *convErr
+		e.Field = field
+		err = &e
+	}
+	c.errs = append(c.errs, err)
+}
+
+func (c *Converter) locale() Locale {
+	if c.Locale.Decimal == 0 {
+		return plain
+	}
+	return c.Locale
+}
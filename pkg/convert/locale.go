@@ -0,0 +1,67 @@
+package convert
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// Locale is how numbers are written: their decimal separator, and the
+// separator grouping the digits of their integer part
+type Locale struct {
+	Decimal rune
+	Group   rune // 0 for none; a space also matches no-break spaces
+}
+
+// plain is the locale of the package-level functions
+var plain = Locale{Decimal: '.'}
+
+// Common locales
+var (
+	US     = Locale{Decimal: '.', Group: ','}  // 1,234.5
+	German = Locale{Decimal: ',', Group: '.'}  // 1.234,5
+	French = Locale{Decimal: ',', Group: ' '}  // 1 234,5
+	Swiss  = Locale{Decimal: '.', Group: '\''} // 1'234.5
+)
+
+// normalize rewrites a number written in l as Go parses it, trimming
+// surrounding spaces
+// Group separators are removed wherever they are; a string that is still
+// not a number after that fails to parse.
+func (l Locale) normalize(s string) string {
+	s = strings.TrimSpace(s)
+	if l.Decimal == 0 {
+		l.Decimal = '.'
+	}
+	if l.Decimal == '.' && l.Group == 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == l.Group, l.Group == ' ' && unicode.Is(unicode.Zs, r):
+			return -1
+		case r == l.Decimal:
+			return '.'
+		case r == '.': // Not the decimal separator, nor a group one
+			return ','
+		}
+		return r
+	}, s)
+}
+
+// ParseFloat parses a number written in l, e.g. "1.234,5" in German
+func (l Locale) ParseFloat(s string) (float64, error) {
+	return toFloat64(s, l)
+}
+
+// ParseInt parses an integer written in l, e.g. "1,234" in US
+func (l Locale) ParseInt(s string) (int64, error) {
+	return toInt64(s, l)
+}
+
+// ParseDecimal parses a decimal number written in l, e.g. "1 234,50" in
+// French
+func (l Locale) ParseDecimal(s string) (decimal.Decimal, error) {
+	return toDecimal(s, l)
+}
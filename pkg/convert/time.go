@@ -0,0 +1,169 @@
+package convert
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Unit is the unit of an epoch timestamp
+type Unit int
+
+const (
+	// Auto guesses the unit from the magnitude of the timestamp: seconds
+	// below 1e11, milliseconds below 1e14, microseconds below 1e17, then
+	// nanoseconds; it dates timestamps between 1973 and 5138 correctly
+	Auto Unit = iota
+	Seconds
+	Milliseconds
+	Microseconds
+	Nanoseconds
+)
+
+// guess returns the unit of the timestamp n for Auto
+func (u Unit) guess(n float64) Unit {
+	if u != Auto {
+		return u
+	}
+	switch abs := math.Abs(n); {
+	case abs < 1e11:
+		return Seconds
+	case abs < 1e14:
+		return Milliseconds
+	case abs < 1e17:
+		return Microseconds
+	}
+	return Nanoseconds
+}
+
+// nanos returns the nanoseconds in one u
+func (u Unit) nanos() int64 {
+	switch u {
+	case Milliseconds:
+		return int64(time.Millisecond)
+	case Microseconds:
+		return int64(time.Microsecond)
+	case Nanoseconds:
+		return 1
+	}
+	return int64(time.Second)
+}
+
+// FromEpoch returns the UTC time of the timestamp n in unit
+func FromEpoch(n int64, unit Unit) time.Time {
+	nanos := unit.guess(float64(n)).nanos()
+	return time.Unix(n/(int64(time.Second)/nanos), n%(int64(time.Second)/nanos)*nanos).UTC()
+}
+
+// ToEpoch returns the timestamp of t in unit, Auto meaning seconds,
+// truncated to whole units
+func ToEpoch(t time.Time, unit Unit) int64 {
+	switch unit {
+	case Milliseconds:
+		return t.UnixMilli()
+	case Microseconds:
+		return t.UnixMicro()
+	case Nanoseconds:
+		return t.UnixNano()
+	}
+	return t.Unix()
+}
+
+// fromEpochFloat returns the UTC time of the timestamp f in unit, keeping
+// its fraction, e.g. 1700000000.5 seconds
+func fromEpochFloat(f float64, unit Unit) time.Time {
+	nanos := float64(unit.guess(f).nanos())
+	sec, frac := math.Modf(f * nanos / float64(time.Second))
+	return time.Unix(int64(sec), int64(math.Round(frac*float64(time.Second)))).UTC()
+}
+
+// Layouts are the layouts Time tries in order on strings
+var Layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999", // ISO 8601 without zone
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999 -0700 MST", // time.Time.String
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// Time converts times, epoch timestamps of Auto unit as numbers or
+// strings, and strings in one of Layouts; times without a zone are UTC
+func Time(v any) (time.Time, error) {
+	return toTime(v, time.UTC)
+}
+
+// TimeIn is Time, but reads times without a zone in loc and returns times
+// in loc
+func TimeIn(v any, loc *time.Location) (time.Time, error) {
+	t, err := toTime(v, loc)
+	return t.In(loc), err
+}
+
+func toTime(v any, loc *time.Location) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case string:
+		s := strings.TrimSpace(t)
+		for _, layout := range Layouts {
+			if parsed, err := time.ParseInLocation(layout, s, loc); err == nil {
+				return parsed, nil
+			}
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return FromEpoch(n, Auto), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return toTime(f, loc)
+		}
+		return time.Time{}, &Error{Value: v, To: "time", Err: errors.New("unknown layout")}
+	case []byte:
+		return toTime(string(t), loc)
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			break
+		}
+		return fromEpochFloat(t, Auto), nil
+	case float32:
+		return toTime(float64(t), loc)
+	case bool, nil:
+		return time.Time{}, &Error{Value: v, To: "time"}
+	default:
+		if n, err := Int64(v); err == nil {
+			return FromEpoch(n, Auto), nil
+		}
+	}
+	return time.Time{}, &Error{Value: v, To: "time"}
+}
+
+// locations caches the locations of InZone
+var locations sync.Map // string -> *time.Location
+
+// InZone returns t in the IANA time zone name, e.g. "Europe/Berlin", so
+// times read from sources in different zones compare and format alike
+func InZone(t time.Time, name string) (time.Time, error) {
+	if loc, ok := locations.Load(name); ok {
+		return t.In(loc.(*time.Location)), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Time{}, &Error{Value: name, To: "time zone", Err: err}
+	}
+	locations.Store(name, loc)
+	return t.In(loc), nil
+}
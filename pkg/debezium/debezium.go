@@ -0,0 +1,230 @@
+// Package debezium decodes Debezium change event envelopes from Kafka
+// topics into upsert and delete operations
+package debezium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/cuong/go-etl/pkg/schemaregistry"
+	"github.com/cuong/go-etl/pkg/sources/kafkasource"
+)
+
+// Action is what a sink should do with a change
+type Action string
+
+const (
+	Upsert   Action = "upsert"   // Insert or update Row
+	Delete   Action = "delete"   // Delete the row identified by Row
+	Truncate Action = "truncate" // Empty the table
+	// Tombstone is the empty message Debezium writes after a delete so log
+	// compaction can drop the key; it carries no row and can be ignored
+	Tombstone Action = "tombstone"
+)
+
+// Debezium operation codes
+const (
+	OpCreate   = "c"
+	OpUpdate   = "u"
+	OpDelete   = "d"
+	OpRead     = "r" // Snapshot read
+	OpTruncate = "t"
+	OpMessage  = "m"
+)
+
+// Source is the origin of a change, from the envelope's source block
+type Source struct {
+	Connector string // "postgresql", "mysql", "mongodb", ...
+	Name      string // Logical server name (topic prefix)
+	DB        string
+	Schema    string
+	Table     string
+	Snapshot  string // "true", "last", "incremental" or "false"
+	Time      time.Time
+}
+
+// Change is a decoded change event
+// Row is the after image for upserts and the before image for deletes;
+// with the default replica identity, a Postgres delete's before image only
+// holds the primary key columns.
+type Change[T any] struct {
+	Action Action
+	Op     string // The Debezium operation code
+	Row    T
+	Before *T
+	After  *T
+	Source Source
+	Time   time.Time // When the connector processed the event
+}
+
+// envelope is the JSON payload of a change event
+type envelope[T any] struct {
+	Before *T           `json:"before"`
+	After  *T           `json:"after"`
+	Op     string       `json:"op"`
+	TsMs   int64        `json:"ts_ms"`
+	Source sourceFields `json:"source"`
+}
+
+type sourceFields struct {
+	Connector string          `json:"connector"`
+	Name      string          `json:"name"`
+	DB        string          `json:"db"`
+	Schema    *string         `json:"schema"`
+	Table     *string         `json:"table"`
+	Snapshot  json.RawMessage `json:"snapshot"` // Bool in old connector versions, string in new ones
+	TsMs      int64           `json:"ts_ms"`
+}
+
+// JSON decodes envelopes written by the JSON converter, with or without
+// schemas.enable
+func JSON[T any]() kafkasource.Deserializer[Change[T]] {
+	return func(_ context.Context, value []byte) (Change[T], error) {
+		if len(bytes.TrimSpace(value)) == 0 || bytes.Equal(value, []byte("null")) {
+			return Change[T]{Action: Tombstone}, nil
+		}
+
+		// With schemas.enable the envelope is wrapped with its schema
+		var wrapped struct {
+			Schema  json.RawMessage `json:"schema"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(value, &wrapped); err != nil {
+			return Change[T]{}, fmt.Errorf("debezium: %w", err)
+		}
+		if wrapped.Schema != nil && wrapped.Payload != nil {
+			value = wrapped.Payload
+			if bytes.Equal(value, []byte("null")) {
+				return Change[T]{Action: Tombstone}, nil
+			}
+		}
+
+		var env envelope[T]
+		if err := json.Unmarshal(value, &env); err != nil {
+			return Change[T]{}, fmt.Errorf("debezium: %w", err)
+		}
+		src := sourceFromFields(env.Source)
+		src.Snapshot = snapshotString(env.Source.Snapshot)
+		return newChange(env.Op, env.Before, env.After, src, env.TsMs)
+	}
+}
+
+// avroEnvelope is the Avro value record of a change event
+type avroEnvelope[T any] struct {
+	Before *T              `avro:"before"`
+	After  *T              `avro:"after"`
+	Op     string          `avro:"op"`
+	TsMs   *int64          `avro:"ts_ms"`
+	Source avroSourceBlock `avro:"source"`
+}
+
+// Connectors differ in which source fields are nullable, so those are
+// decoded into any
+type avroSourceBlock struct {
+	Connector string `avro:"connector"`
+	Name      string `avro:"name"`
+	DB        string `avro:"db"`
+	Schema    any    `avro:"schema"`
+	Table     any    `avro:"table"`
+	Snapshot  any    `avro:"snapshot"`
+	TsMs      int64  `avro:"ts_ms"`
+}
+
+// Avro decodes envelopes written by the Confluent Avro converter, fetching
+// writer schemas from the registry
+// T is decoded from the before and after records by its `avro` tags.
+func Avro[T any](client *schemaregistry.Client) kafkasource.Deserializer[Change[T]] {
+	return func(ctx context.Context, value []byte) (Change[T], error) {
+		if len(value) == 0 {
+			return Change[T]{Action: Tombstone}, nil
+		}
+
+		id, data, err := schemaregistry.Decode(value)
+		if err != nil {
+			return Change[T]{}, fmt.Errorf("debezium: %w", err)
+		}
+		schema, err := client.Schema(ctx, id)
+		if err != nil {
+			return Change[T]{}, fmt.Errorf("debezium: %w", err)
+		}
+
+		var env avroEnvelope[T]
+		if err := avro.Unmarshal(schema, data, &env); err != nil {
+			return Change[T]{}, fmt.Errorf("debezium: decode with schema %d: %w", id, err)
+		}
+
+		src := sourceFromFields(sourceFields{
+			Connector: env.Source.Connector,
+			Name:      env.Source.Name,
+			DB:        env.Source.DB,
+			TsMs:      env.Source.TsMs,
+		})
+		src.Schema, _ = env.Source.Schema.(string)
+		src.Table, _ = env.Source.Table.(string)
+		src.Snapshot, _ = env.Source.Snapshot.(string)
+		var tsMs int64
+		if env.TsMs != nil {
+			tsMs = *env.TsMs
+		}
+		return newChange(env.Op, env.Before, env.After, src, tsMs)
+	}
+}
+
+// newChange maps a Debezium operation to an action
+func newChange[T any](op string, before, after *T, src Source, tsMs int64) (Change[T], error) {
+	c := Change[T]{Op: op, Before: before, After: after, Source: src}
+	if tsMs > 0 {
+		c.Time = time.UnixMilli(tsMs)
+	}
+
+	switch op {
+	case OpCreate, OpUpdate, OpRead:
+		if after == nil {
+			return c, fmt.Errorf("debezium: %q event without after image", op)
+		}
+		c.Action, c.Row = Upsert, *after
+	case OpDelete:
+		if before == nil {
+			return c, fmt.Errorf("debezium: delete event without before image")
+		}
+		c.Action, c.Row = Delete, *before
+	case OpTruncate:
+		c.Action = Truncate
+	default:
+		return c, fmt.Errorf("debezium: unsupported operation %q", op)
+	}
+	return c, nil
+}
+
+func sourceFromFields(f sourceFields) Source {
+	src := Source{Connector: f.Connector, Name: f.Name, DB: f.DB}
+	if f.Schema != nil {
+		src.Schema = *f.Schema
+	}
+	if f.Table != nil {
+		src.Table = *f.Table
+	}
+	if f.TsMs > 0 {
+		src.Time = time.UnixMilli(f.TsMs)
+	}
+	return src
+}
+
+// snapshotString normalizes the snapshot flag, which older connectors
+// write as a boolean
+func snapshotString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var b bool
+	if json.Unmarshal(raw, &b) == nil && b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,63 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// bloom is a Bloom filter of record IDs, safe for concurrent use
+// A negative answer is definite, so records never seen skip the store.
+type bloom struct {
+	bits []atomic.Uint64
+	m    uint64 // Number of bits
+	k    uint64 // Number of hash functions
+}
+
+// newBloom sizes a filter for n IDs at false positive rate p
+func newBloom(n int, p float64) *bloom {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	k = max(k, 1)
+	return &bloom{bits: make([]atomic.Uint64, (m+63)/64), m: m, k: k}
+}
+
+// add sets the bits of id
+func (b *bloom) add(id string) {
+	h1, h2 := hashes(id)
+	for i := range b.k {
+		bit := (h1 + i*h2) % b.m
+		word := &b.bits[bit/64]
+		mask := uint64(1) << (bit % 64)
+		for {
+			old := word.Load()
+			if old&mask != 0 || word.CompareAndSwap(old, old|mask) {
+				break
+			}
+		}
+	}
+}
+
+// test reports whether id may have been added
+func (b *bloom) test(id string) bool {
+	h1, h2 := hashes(id)
+	for i := range b.k {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64].Load()&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes returns the two hashes combined into the k bit positions
+func hashes(id string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	h1 := h.Sum64()
+
+	h = fnv.New64()
+	h.Write([]byte(id))
+	return h1, h.Sum64() | 1
+}
@@ -0,0 +1,111 @@
+package dedup
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltBucket = []byte("seen")
+
+// BoltStore keeps seen IDs in a local bbolt file, with the time each was
+// first loaded
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the store file at path
+// The file is locked, so only one process can use it at a time.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("dedup: open %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("dedup: init %s: %w", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Contains looks the IDs up in one read transaction
+func (s *BoltStore) Contains(ctx context.Context, ids []string) ([]bool, error) {
+	found := make([]bool, len(ids))
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for i, id := range ids {
+			found[i] = b.Get([]byte(id)) != nil
+		}
+		return nil
+	})
+	return found, err
+}
+
+// Add records the IDs in one write transaction, keeping the first time an
+// ID was added
+func (s *BoltStore) Add(ctx context.Context, ids []string) error {
+	now := binary.BigEndian.AppendUint64(nil, uint64(time.Now().UnixNano()))
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for _, id := range ids {
+			if b.Get([]byte(id)) != nil {
+				continue
+			}
+			if err := b.Put([]byte(id), now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Range calls fn with every stored ID
+func (s *BoltStore) Range(ctx context.Context, fn func(id string) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, _ []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(string(k))
+		})
+	})
+}
+
+// Prune removes the IDs added before cutoff, once the source can no longer
+// redeliver them, and returns how many were removed
+func (s *BoltStore) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if len(v) == 8 && int64(binary.BigEndian.Uint64(v)) < cutoff.UnixNano() {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+	return removed, err
+}
+
+// Close closes the file
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
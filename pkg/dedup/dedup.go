@@ -0,0 +1,253 @@
+// Package dedup drops records that were already loaded, so that replays
+// and redeliveries of at-least-once sources don't create duplicates
+// downstream
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Store remembers the IDs of loaded records
+type Store interface {
+	// Contains reports which IDs were added
+	Contains(ctx context.Context, ids []string) ([]bool, error)
+
+	// Add records IDs as loaded
+	Add(ctx context.Context, ids []string) error
+
+	// Range calls fn with every stored ID, to warm the Bloom filter
+	Range(ctx context.Context, fn func(id string) error) error
+}
+
+// Config configures deduplication
+type Config[E any] struct {
+	Store Store
+	Key   func(E) string // Unique ID of a record, e.g. an event ID or primary key
+
+	// Capacity is the number of IDs the Bloom filter is sized for (defaults
+	// to 1,000,000); past it, more lookups reach the store
+	Capacity          int
+	FalsePositiveRate float64 // Of the Bloom filter (defaults to 0.01)
+}
+
+// Wrap drops the records of processor's source whose ID was already loaded
+// An ID is recorded once its batch has loaded (see etl.BatchCommitter), and
+// records whose ID is still in flight are dropped too. IDs are kept in
+// memory in a Bloom filter, warmed from the store on the first Extract: a
+// record the filter has never seen skips the store, so only repeated or
+// false positive IDs cost a lookup.
+//
+// The optional interfaces of processor, such as BatchCommitter and
+// DeadLetterHandler, are kept (see etl.Wrapper); dead-lettered records are
+// not recorded, so they can be loaded later. As it commits batches, the
+// wrapper cannot be combined with a load queue.
+func Wrap[E, T any](processor etl.ETLProcessor[E, T], cfg Config[E]) (etl.ETLProcessor[E, T], error) {
+	if cfg.Store == nil || cfg.Key == nil {
+		return nil, fmt.Errorf("dedup: Store and Key are required")
+	}
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 1_000_000
+	}
+	if cfg.FalsePositiveRate <= 0 || cfg.FalsePositiveRate >= 1 {
+		cfg.FalsePositiveRate = 0.01
+	}
+
+	return &deduplicator[E, T]{
+		processor: processor,
+		cfg:       cfg,
+		bloom:     newBloom(cfg.Capacity, cfg.FalsePositiveRate),
+		inFlight:  make(map[string]int),
+	}, nil
+}
+
+// deduplicator implements Wrap
+type deduplicator[E, T any] struct {
+	processor etl.ETLProcessor[E, T]
+	cfg       Config[E]
+	bloom     *bloom
+
+	warmMu sync.Mutex
+	warmed bool
+
+	mu       sync.Mutex
+	inFlight map[string]int // Emitted and not yet loaded or dead-lettered
+}
+
+func (d *deduplicator[E, T]) PreProcess(ctx context.Context) error {
+	return d.processor.PreProcess(ctx)
+}
+
+// Extract warms the Bloom filter, then forwards the records of the wrapped
+// processor whose ID is new
+func (d *deduplicator[E, T]) Extract(ctx context.Context) (<-chan etl.Payload[E], error) {
+	if err := d.warm(ctx); err != nil {
+		return nil, err
+	}
+
+	// Records in flight when an earlier run failed were not loaded
+	d.mu.Lock()
+	d.inFlight = make(map[string]int)
+	d.mu.Unlock()
+
+	in, err := d.processor.Extract(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan etl.Payload[E], cap(in))
+	go func() {
+		defer close(out)
+
+		var dropped int64
+		defer func() {
+			if dropped > 0 {
+				etl.LoggerFromContext(ctx).Info("Dropped duplicate records", "records", dropped)
+			}
+		}()
+
+		for p := range in {
+			if p.Err == nil {
+				dup, err := d.claim(ctx, d.cfg.Key(p.Data))
+				if err != nil {
+					p = etl.Payload[E]{Err: err}
+				} else if dup {
+					dropped++
+					continue
+				}
+			}
+
+			select {
+			case out <- p:
+			case <-ctx.Done():
+				return
+			}
+			if p.Err != nil && !etl.IsRecordError(p.Err) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// warm adds the stored IDs to the Bloom filter once per process
+func (d *deduplicator[E, T]) warm(ctx context.Context) error {
+	d.warmMu.Lock()
+	defer d.warmMu.Unlock()
+	if d.warmed {
+		return nil
+	}
+
+	n := 0
+	err := d.cfg.Store.Range(ctx, func(id string) error {
+		d.bloom.add(id)
+		n++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("dedup: warm Bloom filter: %w", err)
+	}
+	d.warmed = true
+	etl.LoggerFromContext(ctx).Info("Warmed dedup filter", "ids", n)
+	return nil
+}
+
+// claim reports whether id was loaded or is in flight, and otherwise marks
+// it in flight
+func (d *deduplicator[E, T]) claim(ctx context.Context, id string) (bool, error) {
+	d.mu.Lock()
+	if d.inFlight[id] > 0 {
+		d.mu.Unlock()
+		return true, nil
+	}
+	d.mu.Unlock()
+
+	if d.bloom.test(id) {
+		found, err := d.cfg.Store.Contains(ctx, []string{id})
+		if err != nil {
+			return false, fmt.Errorf("dedup: %w", err)
+		}
+		if found[0] {
+			return true, nil
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight[id]++
+	return false, nil
+}
+
+// release removes the IDs of settled records from the in-flight set
+func (d *deduplicator[E, T]) release(ids []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, id := range ids {
+		if d.inFlight[id] <= 1 {
+			delete(d.inFlight, id)
+		} else {
+			d.inFlight[id]--
+		}
+	}
+}
+
+func (d *deduplicator[E, T]) Transform(ctx context.Context, e E) T {
+	return d.processor.Transform(ctx, e)
+}
+
+func (d *deduplicator[E, T]) Load(ctx context.Context, data []T) error {
+	return d.processor.Load(ctx, data)
+}
+
+// Commit records the IDs of a loaded batch, then commits it to the wrapped
+// processor, if it is a BatchCommitter
+func (d *deduplicator[E, T]) Commit(ctx context.Context, items []E) error {
+	ids := d.ids(items)
+	if err := d.cfg.Store.Add(ctx, ids); err != nil {
+		return fmt.Errorf("dedup: %w", err)
+	}
+	for _, id := range ids {
+		d.bloom.add(id)
+	}
+	d.release(ids)
+
+	if committer, ok := etl.As[etl.BatchCommitter[E]](d.processor); ok {
+		return committer.Commit(ctx, items)
+	}
+	return nil
+}
+
+// DeadLetter hands the batch to the wrapped processor's DeadLetterHandler
+// without recording its IDs
+func (d *deduplicator[E, T]) DeadLetter(ctx context.Context, items []E, err error) error {
+	d.release(d.ids(items))
+
+	handler, ok := etl.As[etl.DeadLetterHandler[E]](d.processor)
+	if !ok {
+		return err
+	}
+	return handler.DeadLetter(ctx, items, err)
+}
+
+// Unwrap returns the wrapped processor, whose other optional interfaces are
+// kept (see etl.Wrapper)
+func (d *deduplicator[E, T]) Unwrap() any {
+	return d.processor
+}
+
+func (d *deduplicator[E, T]) PostProcess(ctx context.Context) error {
+	return d.processor.PostProcess(ctx)
+}
+
+// ids returns the IDs of items
+func (d *deduplicator[E, T]) ids(items []E) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = d.cfg.Key(item)
+	}
+	return ids
+}
@@ -0,0 +1,72 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps seen IDs as Redis keys under a prefix, so several
+// processes can share them
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisStore creates a store of keys named prefix+id (prefix defaults to
+// "etl:dedup:") that expire after ttl, 0 for never
+// Set ttl to the longest time the source may redeliver a record.
+func NewRedisStore(client redis.Cmdable, prefix string, ttl time.Duration) *RedisStore {
+	if prefix == "" {
+		prefix = "etl:dedup:"
+	}
+	return &RedisStore{client: client, prefix: prefix, ttl: ttl}
+}
+
+// Contains looks the IDs up in one round trip
+func (s *RedisStore) Contains(ctx context.Context, ids []string) ([]bool, error) {
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.Exists(ctx, s.prefix+id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("dedup: look up IDs: %w", err)
+	}
+
+	found := make([]bool, len(ids))
+	for i, cmd := range cmds {
+		found[i] = cmd.Val() > 0
+	}
+	return found, nil
+}
+
+// Add records the IDs in one round trip
+func (s *RedisStore) Add(ctx context.Context, ids []string) error {
+	pipe := s.client.Pipeline()
+	for _, id := range ids {
+		pipe.SetNX(ctx, s.prefix+id, 1, s.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("dedup: record IDs: %w", err)
+	}
+	return nil
+}
+
+// Range scans the keys under the prefix
+func (s *RedisStore) Range(ctx context.Context, fn func(id string) error) error {
+	iter := s.client.Scan(ctx, 0, s.prefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if err := fn(strings.TrimPrefix(iter.Val(), s.prefix)); err != nil {
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("dedup: scan IDs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package dlq keeps dead-lettered records so they can be inspected and
+// replayed once the cause of their failure is fixed
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Entry is one dead-lettered record
+type Entry struct {
+	ID         string          `json:"id"`
+	Pipeline   string          `json:"pipeline"`
+	Record     json.RawMessage `json:"record"` // Extracted item, JSON encoded
+	Error      string          `json:"error"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ReplayedAt *time.Time      `json:"replayed_at,omitempty"` // Set once the record was loaded by a replay
+}
+
+// Filter selects entries
+type Filter struct {
+	IDs           []string  // Only these entries, all when empty
+	Since         time.Time // Dead-lettered at or after, zero for no bound
+	Until         time.Time // Dead-lettered before, zero for no bound
+	ErrorContains string    // Substring of the error
+	Replayed      bool      // Include entries already replayed
+	Limit         int       // Maximum entries returned, 0 for all
+}
+
+// Match reports whether e is selected by f, ignoring Limit
+func (f Filter) Match(e *Entry) bool {
+	if e.ReplayedAt != nil && !f.Replayed {
+		return false
+	}
+	if !f.Since.IsZero() && e.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.CreatedAt.Before(f.Until) {
+		return false
+	}
+	if f.ErrorContains != "" && !strings.Contains(e.Error, f.ErrorContains) {
+		return false
+	}
+	if len(f.IDs) > 0 {
+		for _, id := range f.IDs {
+			if id == e.ID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Store keeps dead-lettered records per pipeline
+type Store interface {
+	// Add stores entries; their IDs must be unique
+	Add(ctx context.Context, entries []*Entry) error
+
+	// List returns the entries of a pipeline selected by filter, oldest
+	// first
+	List(ctx context.Context, pipeline string, filter Filter) ([]*Entry, error)
+
+	// MarkReplayed records that entries were loaded by a replay
+	MarkReplayed(ctx context.Context, pipeline string, ids []string, at time.Time) error
+
+	// Delete removes entries
+	Delete(ctx context.Context, pipeline string, ids []string) error
+
+	// Pipelines returns the names of the pipelines with entries
+	Pipelines(ctx context.Context) ([]string, error)
+}
+
+// selectEntries returns the entries matching filter, up to its limit
+func selectEntries(entries []*Entry, filter Filter) []*Entry {
+	var out []*Entry
+	for _, e := range entries {
+		if !filter.Match(e) {
+			continue
+		}
+		c := *e
+		out = append(out, &c)
+		if filter.Limit > 0 && len(out) == filter.Limit {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,207 @@
+package dlq
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore keeps one JSON lines file per pipeline in a directory
+// Entries are appended as they are dead-lettered; marking or deleting
+// entries rewrites the file atomically.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a file store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Add appends entries to their pipelines' files
+func (s *FileStore) Add(ctx context.Context, entries []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byPipeline := make(map[string][]byte)
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode dead letter: %w", err)
+		}
+		byPipeline[e.Pipeline] = append(append(byPipeline[e.Pipeline], data...), '\n')
+	}
+
+	for pipeline, data := range byPipeline {
+		f, err := os.OpenFile(s.path(pipeline), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to write dead letters: %w", err)
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write dead letters: %w", err)
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync dead letters: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write dead letters: %w", err)
+		}
+	}
+	return nil
+}
+
+// List reads the entries of a pipeline selected by filter
+func (s *FileStore) List(ctx context.Context, pipeline string, filter Filter) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return selectEntries(entries, filter), nil
+}
+
+// MarkReplayed sets the replay time of entries
+func (s *FileStore) MarkReplayed(ctx context.Context, pipeline string, ids []string, at time.Time) error {
+	return s.update(pipeline, func(entries []*Entry) []*Entry {
+		for _, e := range entries {
+			if slices.Contains(ids, e.ID) {
+				at := at
+				e.ReplayedAt = &at
+			}
+		}
+		return entries
+	})
+}
+
+// Delete removes entries, and the file of a pipeline left without any
+func (s *FileStore) Delete(ctx context.Context, pipeline string, ids []string) error {
+	return s.update(pipeline, func(entries []*Entry) []*Entry {
+		return slices.DeleteFunc(entries, func(e *Entry) bool {
+			return slices.Contains(ids, e.ID)
+		})
+	})
+}
+
+// Pipelines returns the pipelines with a dead letter file, sorted by name
+func (s *FileStore) Pipelines(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if pipeline, err := url.PathUnescape(name); err == nil {
+			names = append(names, pipeline)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// read decodes the file of a pipeline
+// Callers hold s.mu.
+func (s *FileStore) read(pipeline string) ([]*Entry, error) {
+	f, err := os.Open(s.path(pipeline))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	defer f.Close()
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to decode dead letter of %s: %w", pipeline, err)
+		}
+		entries = append(entries, &e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	return entries, nil
+}
+
+// update rewrites the file of a pipeline with the entries returned by fn
+func (s *FileStore) update(pipeline string, fn func([]*Entry) []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read(pipeline)
+	if err != nil || entries == nil {
+		return err
+	}
+	entries = fn(entries)
+	if len(entries) == 0 {
+		if err := os.Remove(s.path(pipeline)); err != nil {
+			return fmt.Errorf("failed to delete dead letters: %w", err)
+		}
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".dlq-*")
+	if err != nil {
+		return fmt.Errorf("failed to write dead letters: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write dead letters: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write dead letters: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync dead letters: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write dead letters: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(pipeline)); err != nil {
+		return fmt.Errorf("failed to commit dead letters: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of a pipeline, escaping names that contain slashes
+func (s *FileStore) path(pipeline string) string {
+	return filepath.Join(s.dir, url.PathEscape(pipeline)+".jsonl")
+}
@@ -0,0 +1,76 @@
+package dlq
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps entries in memory, e.g. for tests
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string][]*Entry
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string][]*Entry)}
+}
+
+func (s *MemoryStore) Add(ctx context.Context, entries []*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range entries {
+		c := *e
+		s.entries[e.Pipeline] = append(s.entries[e.Pipeline], &c)
+	}
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, pipeline string, filter Filter) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return selectEntries(s.entries[pipeline], filter), nil
+}
+
+func (s *MemoryStore) MarkReplayed(ctx context.Context, pipeline string, ids []string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.entries[pipeline] {
+		if slices.Contains(ids, e.ID) {
+			at := at
+			e.ReplayedAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, pipeline string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[pipeline] = slices.DeleteFunc(s.entries[pipeline], func(e *Entry) bool {
+		return slices.Contains(ids, e.ID)
+	})
+	if len(s.entries[pipeline]) == 0 {
+		delete(s.entries, pipeline)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Pipelines(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
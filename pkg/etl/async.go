@@ -0,0 +1,267 @@
+package etl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+)
+
+// LoadKeyer can optionally be implemented by an ETLProcessor to keep the
+// loads of records with the same key in order when loads are asynchronous
+// (see SetAsyncLoad)
+// A batch is only loaded once the earlier loads of its worker sharing a key
+// with it are done. With more than one worker, the processor must also be a
+// StrategyProvider whose strategy keeps each key on one worker, e.g.
+// bucket.PartitionHash on the same key, or the run fails at start.
+type LoadKeyer[T any] interface {
+	LoadKey(item T) string
+}
+
+// SetAsyncLoad lets each bucket worker hand its batches to up to inFlight
+// concurrent loads and go on transforming the next, overlapping transform
+// CPU time with sink IO; inFlight of 1 or less loads synchronously
+// Batches are still committed and checkpointed once loaded, in the order
+// each worker picked them up. Failed loads are retried, dead-lettered and
+// counted against the ErrorBudget like synchronous ones; a load that still
+// fails aborts the run, and batches handed over after it are not loaded.
+// Async loads cannot be combined with a load queue.
+func (e *ETL[E, T]) SetAsyncLoad(inFlight int) {
+	e.inFlight = inFlight
+}
+
+// WithAsyncLoad loads up to inFlight batches of each worker of the pipeline
+// concurrently; see ETL.SetAsyncLoad
+func WithAsyncLoad(inFlight int) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.inFlight = inFlight
+	}
+}
+
+// asyncLoads runs the loads of a run's batches in the background
+type asyncLoads[T any] struct {
+	inFlight int
+	key      func(T) string     // Of a LoadKeyer processor, nil without
+	abort    context.CancelFunc // Cancels the run on the first failure
+
+	// ctx outlives the bucket's contexts so loads can finish after their
+	// worker moved on; it is cancelled on the first failure, or the
+	// shutdown timeout after the run is cancelled
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	stop   func() bool
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	workers map[int]*asyncWorker
+	err     error
+}
+
+// asyncWorker holds the in-flight loads of a bucket worker
+type asyncWorker struct {
+	slots   chan struct{} // One per in-flight load
+	settled chan struct{} // Closed once the last batch handed over is settled
+
+	mu   sync.Mutex
+	keys map[string]chan struct{} // Closed once the last load of the key is done
+}
+
+// newAsyncLoads prepares the async loads of a run cancelled by abort
+func (e *ETL[E, T]) newAsyncLoads(runCtx context.Context, abort context.CancelFunc, grace time.Duration) *asyncLoads[T] {
+	if grace <= 0 {
+		grace = 10 * time.Second // The bucket's default
+	}
+	a := &asyncLoads[T]{
+		inFlight: e.inFlight,
+		abort:    abort,
+		workers:  make(map[int]*asyncWorker),
+	}
+	if keyer, ok := As[LoadKeyer[T]](e.processor); ok {
+		a.key = keyer.LoadKey
+	}
+	a.ctx, a.cancel = context.WithCancelCause(context.WithoutCancel(runCtx))
+	a.stop = context.AfterFunc(runCtx, func() {
+		time.AfterFunc(grace, func() { a.cancel(context.Cause(runCtx)) })
+	})
+	return a
+}
+
+// load loads items, the transformed records of the batch of ctx, with load
+// once a slot of its worker is free, then calls settle once the batch and
+// those its worker picked up before are settled, unless load reported the
+// batch as dead-lettered
+// It returns at once, without loading, after a failed load.
+func (a *asyncLoads[T]) load(ctx context.Context, items []T, load func(context.Context) (bool, error), settle func(context.Context) error) error {
+	if a.failed() {
+		return nil
+	}
+	w := a.worker(ctx)
+	select {
+	case w.slots <- struct{}{}:
+	case <-a.ctx.Done():
+		if a.failed() {
+			return nil
+		}
+		return context.Cause(a.ctx) // Shutdown timeout
+	}
+
+	keys := a.keys(items)
+	loaded := make(chan struct{})
+	after := w.follow(keys, loaded)
+	prev, settled := w.settled, make(chan struct{})
+	w.settled = settled
+
+	loadCtx, cancel := a.detach(ctx)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer cancel()
+
+		var deadLettered bool
+		err := waitLoads(loadCtx, after)
+		if err == nil {
+			deadLettered, err = load(loadCtx)
+		}
+		close(loaded)
+		w.release(keys, loaded)
+
+		<-prev
+		if err == nil && !deadLettered && !a.failed() {
+			err = settle(loadCtx)
+		}
+		if err != nil {
+			a.fail(err) // Before later batches can settle
+		}
+		close(settled)
+		<-w.slots
+	}()
+	return nil
+}
+
+// wait waits for the in-flight loads and returns the first failure, or nil
+// if runErr, the error of the bucket, is set as it takes precedence
+func (a *asyncLoads[T]) wait(runErr error) error {
+	if runErr != nil {
+		a.cancel(runErr)
+	}
+	a.wg.Wait()
+	a.stop()
+	a.cancel(nil)
+
+	if runErr != nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// worker returns the in-flight loads of the bucket worker of ctx
+func (a *asyncLoads[T]) worker(ctx context.Context) *asyncWorker {
+	id, _ := bucket.WorkerID(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	w, ok := a.workers[id]
+	if !ok {
+		settled := make(chan struct{})
+		close(settled)
+		w = &asyncWorker{
+			slots:   make(chan struct{}, a.inFlight),
+			settled: settled,
+			keys:    make(map[string]chan struct{}),
+		}
+		a.workers[id] = w
+	}
+	return w
+}
+
+// keys returns the distinct keys of items, nil without a LoadKeyer
+func (a *asyncLoads[T]) keys(items []T) []string {
+	if a.key == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(items))
+	var keys []string
+	for _, item := range items {
+		if k := a.key(item); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// detach returns a context with the values of ctx that is cancelled with
+// a.ctx rather than ctx
+func (a *asyncLoads[T]) detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(a.ctx, func() { cancel(context.Cause(a.ctx)) })
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// failed reports whether a load failed
+func (a *asyncLoads[T]) failed() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err != nil
+}
+
+// fail records the first failure and cancels the loads and the run
+func (a *asyncLoads[T]) fail(err error) {
+	a.mu.Lock()
+	first := a.err == nil
+	if first {
+		a.err = err
+	}
+	a.mu.Unlock()
+
+	if first {
+		a.cancel(err)
+		a.abort()
+	}
+}
+
+// follow records loaded as the last load of keys and returns the loads the
+// batch must wait for
+func (w *asyncWorker) follow(keys []string, loaded chan struct{}) []chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var after []chan struct{}
+	for _, k := range keys {
+		if prev, ok := w.keys[k]; ok {
+			after = append(after, prev)
+		}
+		w.keys[k] = loaded
+	}
+	return after
+}
+
+// release forgets the keys whose last load is loaded
+func (w *asyncWorker) release(keys []string, loaded chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, k := range keys {
+		if w.keys[k] == loaded {
+			delete(w.keys, k)
+		}
+	}
+}
+
+// waitLoads waits until the loads are done or ctx is
+func waitLoads(ctx context.Context, loads []chan struct{}) error {
+	for _, done := range loads {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package etl
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// keyedProcessor keys loads by item parity
+type keyedProcessor struct {
+	*testProcessor
+	strategy bucket.Strategy[int]
+}
+
+func (p *keyedProcessor) LoadKey(item int) string { return strconv.Itoa(item % 2) }
+
+// routedProcessor also routes items to workers by their load key
+type routedProcessor struct{ *keyedProcessor }
+
+func (p *routedProcessor) Strategy() bucket.Strategy[int] { return p.strategy }
+
+func TestAsyncLoadKeepsKeyOrder(t *testing.T) {
+	p := &keyedProcessor{testProcessor: &testProcessor{
+		items: []int{1, 2, 3, 4, 5, 6, 7, 8},
+		loadFn: func(_ context.Context, data []int) error {
+			// Earlier batches load slower, so unordered loads land reversed
+			time.Sleep(time.Duration(10-data[0]) * 2 * time.Millisecond)
+			return nil
+		},
+	}}
+
+	e := NewETL[int, int](p)
+	e.SetAsyncLoad(4)
+	err := e.Run(context.Background(), &bucket.Config{BatchSize: 1, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := make(map[string]int)
+	loaded := p.loadedItems()
+	for _, item := range loaded {
+		key := p.LoadKey(item)
+		if item < last[key] {
+			t.Fatalf("loaded %v: %d after %d of key %s", loaded, item, last[key], key)
+		}
+		last[key] = item
+	}
+	if len(loaded) != len(p.items) {
+		t.Errorf("loaded %v, want all %d items", loaded, len(p.items))
+	}
+}
+
+func TestAsyncLoadKeyerWorkersRequireStrategy(t *testing.T) {
+	keyed := &keyedProcessor{testProcessor: &testProcessor{items: []int{1, 2}}}
+	store, err := checkpoint.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &bucket.Config{BatchSize: 1, Timeout: time.Second, WorkerNum: 2}
+
+	tests := []struct {
+		name      string
+		processor ETLProcessor[Sequenced[int], int]
+		wantErr   bool
+	}{
+		// Resumable always provides a strategy, nil without the wrapped one's
+		{"unrouted", Resumable[int, int](keyed, store, "unrouted"), true},
+		{"nil strategy", Resumable[int, int](&routedProcessor{keyed}, store, "nil"), true},
+		{"routed", Resumable[int, int](&routedProcessor{&keyedProcessor{
+			testProcessor: &testProcessor{items: []int{1, 2}},
+			strategy:      bucket.PartitionHash(keyed.LoadKey),
+		}}, store, "routed"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewETL(tt.processor)
+			e.SetAsyncLoad(2)
+			err := e.Run(context.Background(), cfg)
+			if tt.wantErr != (err != nil && strings.Contains(err.Error(), "require a StrategyProvider")) {
+				t.Errorf("Run() = %v, want the strategy error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
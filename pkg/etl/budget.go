@@ -0,0 +1,90 @@
+package etl
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrorBudget tolerates failed records in a run, between failing on the
+// first failed batch and dead-lettering every failed batch
+// Within the budget, batches whose Transform or Load failed once retries
+// are exhausted, or panicked, are dead-lettered (see SetDeadLetters and
+// DeadLetterHandler) or, without a dead letter store or handler, skipped,
+// and records the source could not decode (see RecordError) count too;
+// the run aborts with an *ErrorBudgetError as soon as the failed records
+// exceed it. With a load queue, failures of the load stage still stop the
+// run.
+type ErrorBudget struct {
+	// MaxRecords is the number of failed records tolerated, 0 for no limit
+	MaxRecords int64
+
+	// MaxRatio is the share of the extracted records that may fail, e.g.
+	// 0.01 for 1%, 0 for no limit
+	// It is enforced during the run once MinRecords were extracted, and on
+	// all the records of the run once extraction is done.
+	MaxRatio   float64
+	MinRecords int64 // Defaults to 1000
+}
+
+// ErrorBudgetError reports a run whose failed records exceeded its
+// ErrorBudget
+type ErrorBudgetError struct {
+	Failed    int64 // Records of failed batches
+	Extracted int64 // Records extracted when the run aborted
+	Budget    ErrorBudget
+}
+
+func (e *ErrorBudgetError) Error() string {
+	var limits []string
+	if e.Budget.MaxRecords > 0 {
+		limits = append(limits, fmt.Sprintf("%d records", e.Budget.MaxRecords))
+	}
+	if e.Budget.MaxRatio > 0 {
+		limits = append(limits, fmt.Sprintf("%g%%", e.Budget.MaxRatio*100))
+	}
+	return fmt.Sprintf("error budget exceeded: %d of %d records failed (max %s)",
+		e.Failed, e.Extracted, strings.Join(limits, ", "))
+}
+
+// errorBudget counts the failed records of a run against an ErrorBudget
+type errorBudget struct {
+	cfg    ErrorBudget
+	failed atomic.Int64
+}
+
+// SetErrorBudget tolerates failed records within budget instead of failing
+// the run on the first failed batch; see ErrorBudget
+func (e *ETL[E, T]) SetErrorBudget(budget ErrorBudget) {
+	if budget.MinRecords <= 0 {
+		budget.MinRecords = 1000
+	}
+	e.budget = &errorBudget{cfg: budget}
+}
+
+// WithErrorBudget tolerates failed records of the pipeline within budget
+// See ETL.SetErrorBudget
+func WithErrorBudget(budget ErrorBudget) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.errorBudget = &budget
+	}
+}
+
+// fail counts the records of a failed batch and reports whether the
+// budget is exceeded with extracted records extracted so far, or once
+// extraction is done
+func (b *errorBudget) fail(records int, extracted int64, done bool) error {
+	return b.check(b.failed.Add(int64(records)), extracted, done)
+}
+
+// check reports whether failed records exceed the budget
+func (b *errorBudget) check(failed, extracted int64, done bool) error {
+	exceeded := b.cfg.MaxRecords > 0 && failed > b.cfg.MaxRecords
+	if b.cfg.MaxRatio > 0 && (done || extracted >= b.cfg.MinRecords) {
+		exceeded = exceeded || float64(failed) > b.cfg.MaxRatio*float64(extracted)
+	}
+	if !exceeded {
+		return nil
+	}
+	return &ErrorBudgetError{Failed: failed, Extracted: extracted, Budget: b.cfg}
+}
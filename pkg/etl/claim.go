@@ -0,0 +1,176 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	// ErrClaimHeld is returned by Claimer.Claim while another owner holds a
+	// live lease on the run
+	ErrClaimHeld = errors.New("run claimed by another instance")
+
+	// ErrRunCompleted is returned by Claimer.Claim when another owner has
+	// already completed the run
+	ErrRunCompleted = errors.New("run already completed")
+
+	// ErrLeaseLost is returned by Lease.Renew when the lease expired and may
+	// have been taken over
+	ErrLeaseLost = errors.New("lease lost")
+)
+
+// Claimer coordinates pipeline runs between replicas of a service so that
+// each run is executed by exactly one of them
+type Claimer interface {
+	// Claim acquires a lease on key for owner, valid for ttl
+	// It fails with ErrClaimHeld or ErrRunCompleted when the run belongs to
+	// another owner.
+	Claim(ctx context.Context, key, owner string, ttl time.Duration) (Lease, error)
+}
+
+// Lease is a claim on a run held by one owner
+type Lease interface {
+	// Renew extends the lease by ttl, failing with ErrLeaseLost if it
+	// already expired
+	Renew(ctx context.Context, ttl time.Duration) error
+
+	// Release gives up the lease; completed leaves a marker so the run is
+	// not executed again by another owner
+	Release(ctx context.Context, completed bool) error
+}
+
+// ClaimConfig configures distributed run claims
+type ClaimConfig struct {
+	Claimer       Claimer
+	Owner         string        // Identifies this instance (defaults to hostname and pid)
+	TTL           time.Duration // Lease duration (defaults to 30s)
+	RenewInterval time.Duration // How often a held lease is renewed (defaults to TTL/3)
+	PollInterval  time.Duration // How often a held claim is retried for takeover (defaults to TTL/2)
+}
+
+// SetClaims makes every pipeline run claim a lease before it starts
+// A run whose claim is held elsewhere waits for the holder: if it completes
+// the run is reported as succeeded without executing here; if its lease
+// expires, e.g. because the holder crashed, this instance takes over.
+// Runs are identified by the run key from WithRunKey, which the scheduler
+// sets to the due time; runs without a key are skipped when claimed
+// elsewhere, since their completion cannot be told apart from a new run.
+func (m *Manager) SetClaims(cfg ClaimConfig) {
+	if cfg.Owner == "" {
+		host, _ := os.Hostname()
+		cfg.Owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 30 * time.Second
+	}
+	if cfg.RenewInterval <= 0 {
+		cfg.RenewInterval = cfg.TTL / 3
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = cfg.TTL / 2
+	}
+
+	m.claims = &cfg
+}
+
+// runKeyContextKey is the context key for the run key
+type runKeyContextKey struct{}
+
+// WithRunKey identifies the run for distributed claims, so that replicas
+// starting the same logical run (e.g. the 02:00 nightly load) contend for
+// one lease
+func WithRunKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, runKeyContextKey{}, key)
+}
+
+// RunKeyFromContext returns the run key set by WithRunKey
+func RunKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(runKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// claimRun acquires the lease for a pipeline run, waiting for takeover
+// while another instance holds it
+// A nil lease with a nil error means the run belongs to another instance.
+func (m *Manager) claimRun(ctx context.Context, pipeline string) (Lease, error) {
+	runKey, keyed := RunKeyFromContext(ctx)
+	key := pipeline
+	if keyed {
+		key += "@" + runKey
+	}
+
+	for {
+		lease, err := m.claims.Claimer.Claim(ctx, key, m.claims.Owner, m.claims.TTL)
+		switch {
+		case err == nil:
+			return lease, nil
+		case errors.Is(err, ErrRunCompleted):
+			return nil, nil
+		case errors.Is(err, ErrClaimHeld):
+			if !keyed {
+				return nil, nil
+			}
+		default:
+			return nil, fmt.Errorf("claim run of %s: %w", pipeline, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("claim run of %s: %w", pipeline, ctx.Err())
+		case <-time.After(m.claims.PollInterval):
+		}
+	}
+}
+
+// holdLease renews lease until the returned stop function is called,
+// cancelling ctx with ErrLeaseLost if renewal fails
+// stop releases the lease, marking the run completed if err is nil.
+func (m *Manager) holdLease(ctx context.Context, pipeline string, lease Lease) (context.Context, func(err error)) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stopped := make(chan struct{})
+	renewed := make(chan struct{})
+
+	go func() {
+		defer close(renewed)
+
+		ticker := time.NewTicker(m.claims.RenewInterval)
+		defer ticker.Stop()
+
+		renewedAt := time.Now()
+		for {
+			select {
+			case <-stopped:
+				return
+			case <-ticker.C:
+			}
+
+			err := lease.Renew(ctx, m.claims.TTL)
+			if err == nil {
+				renewedAt = time.Now()
+				continue
+			}
+
+			// Transient failures are retried until the lease may have expired
+			if errors.Is(err, ErrLeaseLost) || time.Since(renewedAt) >= m.claims.TTL {
+				cancel(fmt.Errorf("%w: %w", ErrLeaseLost, err))
+				return
+			}
+			m.cfg.Logger.Warn("Failed to renew claim", "pipeline", pipeline, "error", err)
+		}
+	}()
+
+	_, keyed := RunKeyFromContext(ctx)
+	return ctx, func(err error) {
+		close(stopped)
+		<-renewed
+		cancel(nil)
+
+		// Keyless runs leave no marker, so the next run can claim the key
+		if relErr := lease.Release(context.WithoutCancel(ctx), keyed && err == nil); relErr != nil {
+			m.cfg.Logger.Warn("Failed to release claim", "pipeline", pipeline, "error", relErr)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package etl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PostgresClaimer stores run leases in a PostgreSQL table
+// Leases expire by time rather than with a session, so a crashed holder is
+// taken over once its TTL passes, and completion markers outlive the holder.
+type PostgresClaimer struct {
+	db    *sql.DB
+	table string
+}
+
+// NewPostgresClaimer creates a claimer backed by table, which is created by
+// Init if it does not exist
+func NewPostgresClaimer(db *sql.DB, table string) *PostgresClaimer {
+	if table == "" {
+		table = "etl_claims"
+	}
+	return &PostgresClaimer{
+		db:    db,
+		table: `"` + strings.ReplaceAll(table, `"`, `""`) + `"`,
+	}
+}
+
+// Init creates the lease table
+func (c *PostgresClaimer) Init(ctx context.Context) error {
+	_, err := c.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+c.table+` (
+		key        TEXT PRIMARY KEY,
+		owner      TEXT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		completed  BOOLEAN NOT NULL DEFAULT FALSE
+	)`)
+	if err != nil {
+		return fmt.Errorf("create claims table: %w", err)
+	}
+	return nil
+}
+
+// Claim takes the lease on key if it is free, expired or already owned by
+// owner
+func (c *PostgresClaimer) Claim(ctx context.Context, key, owner string, ttl time.Duration) (Lease, error) {
+	var holder string
+	err := c.db.QueryRowContext(ctx, `INSERT INTO `+c.table+` AS t (key, owner, expires_at)
+		VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+		ON CONFLICT (key) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+		WHERE NOT t.completed AND (t.expires_at < now() OR t.owner = EXCLUDED.owner)
+		RETURNING owner`, key, owner, ttl.Milliseconds()).Scan(&holder)
+	if err == nil {
+		return &postgresLease{claimer: c, key: key, owner: owner}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("claim %s: %w", key, err)
+	}
+
+	// The conflicting row was not updated: find out why
+	var completed bool
+	err = c.db.QueryRowContext(ctx, `SELECT completed FROM `+c.table+` WHERE key = $1`, key).Scan(&completed)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Released in the meantime
+		return nil, ErrClaimHeld
+	case err != nil:
+		return nil, fmt.Errorf("claim %s: %w", key, err)
+	case completed:
+		return nil, ErrRunCompleted
+	default:
+		return nil, ErrClaimHeld
+	}
+}
+
+// Prune deletes completion markers older than age
+func (c *PostgresClaimer) Prune(ctx context.Context, age time.Duration) error {
+	_, err := c.db.ExecContext(ctx, `DELETE FROM `+c.table+`
+		WHERE completed AND expires_at < now() - $1 * interval '1 millisecond'`, age.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("prune claims: %w", err)
+	}
+	return nil
+}
+
+// postgresLease is a lease row held by one owner
+type postgresLease struct {
+	claimer *PostgresClaimer
+	key     string
+	owner   string
+}
+
+// Renew extends the lease if it is still held by its owner
+func (l *postgresLease) Renew(ctx context.Context, ttl time.Duration) error {
+	res, err := l.claimer.db.ExecContext(ctx, `UPDATE `+l.claimer.table+`
+		SET expires_at = now() + $3 * interval '1 millisecond'
+		WHERE key = $1 AND owner = $2 AND NOT completed`, l.key, l.owner, ttl.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("renew %s: %w", l.key, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release marks the run completed or deletes the lease
+func (l *postgresLease) Release(ctx context.Context, completed bool) error {
+	query := `DELETE FROM ` + l.claimer.table + ` WHERE key = $1 AND owner = $2`
+	if completed {
+		query = `UPDATE ` + l.claimer.table + ` SET completed = TRUE, expires_at = now()
+			WHERE key = $1 AND owner = $2`
+	}
+
+	if _, err := l.claimer.db.ExecContext(ctx, query, l.key, l.owner); err != nil {
+		return fmt.Errorf("release %s: %w", l.key, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package etl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// topoOrder validates the pipeline dependency graph and returns pipeline
+// names in an order where every pipeline comes after its dependencies
+// Unknown dependencies and cycles are reported as errors.
+func (m *Manager) topoOrder() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registered := make(map[string]bool, len(m.pipelines))
+	for _, p := range m.pipelines {
+		registered[p.Name()] = true
+	}
+
+	indegree := make(map[string]int, len(registered))
+	dependents := make(map[string][]string)
+	for name := range registered {
+		indegree[name] = 0
+	}
+	for name, deps := range m.deps {
+		if !registered[name] {
+			continue
+		}
+		for _, dep := range deps {
+			if !registered[dep] {
+				return nil, fmt.Errorf("pipeline %s depends on unknown pipeline %s", name, dep)
+			}
+			indegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	// Kahn's algorithm, sorted at each step for a deterministic order
+	var ready []string
+	for name, n := range indegree {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+
+	order := make([]string, 0, len(indegree))
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+
+		for _, dependent := range dependents[name] {
+			indegree[dependent]--
+			if indegree[dependent] == 0 {
+				ready = append(ready, dependent)
+				sort.Strings(ready)
+			}
+		}
+	}
+
+	if len(order) < len(indegree) {
+		var cyclic []string
+		for name, n := range indegree {
+			if n > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		sort.Strings(cyclic)
+		return nil, fmt.Errorf("pipeline dependency cycle among: %s", strings.Join(cyclic, ", "))
+	}
+
+	return order, nil
+}
@@ -0,0 +1,109 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Drain unschedules the named pipeline and waits until none of its runs is
+// in progress, e.g. before replacing it with ReplacePipelineGeneric
+// Runs triggered or started with RunPipeline after Drain returns are not
+// prevented.
+func (m *Manager) Drain(ctx context.Context, name string) error {
+	if _, err := m.pipeline(name); err != nil {
+		return err
+	}
+	m.Unschedule(name)
+	if err := m.waitIdle(ctx, name); err != nil {
+		return fmt.Errorf("drain pipeline %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemovePipeline drains the named pipeline and unregisters it with its
+// settings and status
+// It fails while other pipelines depend on it.
+func (m *Manager) RemovePipeline(ctx context.Context, name string) error {
+	m.mu.Lock()
+	for dependent, deps := range m.deps {
+		if m.indexOf(dependent) >= 0 && slices.Contains(deps, name) {
+			m.mu.Unlock()
+			return fmt.Errorf("pipeline %s is a dependency of %s", name, dependent)
+		}
+	}
+	m.mu.Unlock()
+
+	if err := m.Drain(ctx, name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := m.indexOf(name); i >= 0 {
+		m.pipelines = slices.Delete(m.pipelines, i, i+1)
+	}
+	m.clearSettings(name)
+	delete(m.status, name)
+	delete(m.progressMarks, name)
+	return nil
+}
+
+// startActive counts a run of the named pipeline as in progress until the
+// returned function is called
+func (m *Manager) startActive(name string) (done func()) {
+	m.mu.Lock()
+	m.active[name]++
+	m.mu.Unlock()
+
+	return func() { m.endActive(name) }
+}
+
+// startIdle is startActive for a pipeline with no run in progress; it
+// reports false and counts nothing otherwise
+func (m *Manager) startIdle(name string) (done func(), ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active[name] > 0 {
+		return nil, false
+	}
+	m.active[name]++
+	return func() { m.endActive(name) }, true
+}
+
+// endActive ends a run counted by startActive or startIdle
+func (m *Manager) endActive(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.active[name]--
+	if m.active[name] > 0 {
+		return
+	}
+	delete(m.active, name)
+	for _, ch := range m.idleWaiters[name] {
+		close(ch)
+	}
+	delete(m.idleWaiters, name)
+}
+
+// waitIdle waits until the named pipeline has no runs in progress
+func (m *Manager) waitIdle(ctx context.Context, name string) error {
+	m.mu.Lock()
+	if m.active[name] == 0 {
+		m.mu.Unlock()
+		return nil
+	}
+	idle := make(chan struct{})
+	m.idleWaiters[name] = append(m.idleWaiters[name], idle)
+	m.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -5,8 +5,16 @@ package etl
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/dlq"
 )
 
 // ETLProcessor defines the interface for ETL operations
@@ -29,7 +37,34 @@ type ETLProcessor[E, T any] interface {
 	PostProcess(ctx context.Context) error
 }
 
+// DeadLetterHandler can optionally be implemented by an ETLProcessor to
+// receive extracted batches whose load panicked, and records whose
+// transform panicked on their own (see PanicPolicy)
+// Returning nil lets the pipeline continue with the next batch
+type DeadLetterHandler[E any] interface {
+	DeadLetter(ctx context.Context, items []E, err error) error
+}
+
+// BatchCommitter can optionally be implemented by an ETLProcessor to
+// acknowledge extracted items once they have landed in the sink
+// Commit is called with a batch's extracted items after its Load succeeded,
+// so sources that track offsets or deliveries never acknowledge records that
+// were not loaded. It cannot be combined with a load queue, which re-batches
+// transformed items independently of the extracted batches.
+type BatchCommitter[E any] interface {
+	Commit(ctx context.Context, items []E) error
+}
+
+// StrategyProvider can optionally be implemented by an ETLProcessor to
+// choose how extracted items are assigned to bucket workers
+// Strategy is called at the start of every run.
+type StrategyProvider[E any] interface {
+	Strategy() bucket.Strategy[E]
+}
+
 // Payload wraps extracted data with error handling
+// An error fails the run, unless it is a RecordError about a single record,
+// which is skipped.
 type Payload[E any] struct {
 	Data E
 	Err  error
@@ -38,6 +73,32 @@ type Payload[E any] struct {
 // ETL orchestrates the extract-transform-load process
 type ETL[E, T any] struct {
 	processor ETLProcessor[E, T]
+	loadQueue *bucket.Config
+	verifyCfg *VerifyConfig
+	verifier  *verifier[T]
+	sampler   *sampler
+	slice     slice
+	budget    *errorBudget
+	progress  progressCounters
+	logger    *slog.Logger
+
+	panicPolicy PanicPolicy // Of records whose Transform panicked
+	inFlight    int         // Async loads per worker, see SetAsyncLoad
+
+	checkpoints     checkpoint.Store // Commits the progress of a Resumer processor
+	checkpointName  string
+	onVersionChange checkpoint.OnVersionChange
+
+	deadLetters    dlq.Store // Keeps the records of dead-lettered batches for replay
+	deadLetterName string
+
+	tracerProvider trace.TracerProvider // nil for the global provider
+	traceName      string
+
+	onBatchLoaded  func(records int, took time.Duration) // Set by the manager to emit BatchLoaded events
+	onDeadLettered func(records int)                     // Set by the manager to emit BatchDeadLettered events
+	onRejected     func(rule string, records int)        // Set by the manager to emit RecordsRejected events
+	onDropped      func(records int)                     // Set by the manager to emit RecordsDropped events
 }
 
 // NewETL creates a new ETL instance with the given processor
@@ -47,66 +108,366 @@ func NewETL[E, T any](processor ETLProcessor[E, T]) *ETL[E, T] {
 	}
 }
 
+// SetLoadQueue puts a queue between the transform and load stages
+// Transformed items are re-batched by a second bucket configured by cfg, so
+// the load stage gets its own queue capacity, overflow policy and workers.
+// Without a load queue, each batch is loaded by the worker that transformed it.
+func (e *ETL[E, T]) SetLoadQueue(cfg *bucket.Config) {
+	e.loadQueue = cfg
+}
+
 // Run executes the complete ETL pipeline:
 // 1. PreProcess
 // 2. Extract -> Bucket (batching) -> Transform -> Load
 // 3. PostProcess
 func (e *ETL[E, T]) Run(ctx context.Context, bucketCfg *bucket.Config) error {
+	ctx = ensureRunID(ctx)
+	runID, _ := RunID(ctx)
+	ctx, span := e.startSpan(ctx, "etl.run", trace.WithAttributes(attribute.String("etl.run.id", runID)))
+	err := e.run(ctx, bucketCfg)
+	progress := e.progress.snapshot()
+	span.SetAttributes(
+		attribute.Int64("etl.records.extracted", progress.Extracted),
+		attribute.Int64("etl.records.loaded", progress.Loaded),
+	)
+	endSpan(span, err)
+	return err
+}
+
+// run implements Run
+func (e *ETL[E, T]) run(ctx context.Context, bucketCfg *bucket.Config) error {
+	ctx = e.rejectContext(WithLogger(ctx, e.log()))
+
 	// Pre-processing (setup, migrations, etc.)
 	if err := e.processor.PreProcess(ctx); err != nil {
 		return fmt.Errorf("failed to pre-process: %w", err)
 	}
 
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	e.progress.reset()
+
+	// Optional read-back verification of loaded batches
+	e.verifier = nil
+	if e.verifyCfg != nil {
+		readBack, ok := As[WriteVerifier[T]](e.processor)
+		if !ok {
+			return fmt.Errorf("write verification requires the processor to implement WriteVerifier")
+		}
+		e.verifier = &verifier[T]{cfg: *e.verifyCfg, readBack: readBack}
+	}
+
+	committer, _ := As[BatchCommitter[E]](e.processor)
+	if e.slice.active() {
+		committer = nil // Records left out must not be acknowledged
+	}
+	if committer != nil && e.loadQueue != nil {
+		return fmt.Errorf("batch commits cannot be combined with a load queue")
+	}
+	if e.inFlight > 1 && e.loadQueue != nil {
+		return fmt.Errorf("async loads cannot be combined with a load queue")
+	}
+	var strategy bucket.Strategy[E] // nil for idle-worker dispatch
+	if provider, ok := As[StrategyProvider[E]](e.processor); ok {
+		strategy = provider.Strategy()
+	}
+	if e.inFlight > 1 && bucketCfg.WorkerNum > 1 {
+		if _, keyed := As[LoadKeyer[T]](e.processor); keyed && strategy == nil {
+			return fmt.Errorf("async loads of a LoadKeyer with several workers require a StrategyProvider keeping each key on one worker")
+		}
+	}
+	if committer != nil && bucketCfg.Overflow == bucket.OverflowDropOldest {
+		return fmt.Errorf("batch commits cannot be combined with the drop-oldest overflow policy")
+	}
+
+	// Resume from the last committed position
+	var resume *resumeTracker[E]
+	if resumer, ok := As[Resumer[E]](e.processor); ok && e.checkpoints != nil {
+		if e.loadQueue != nil {
+			return fmt.Errorf("checkpoints cannot be combined with a load queue")
+		}
+		if bucketCfg.Overflow == bucket.OverflowDropOldest {
+			return fmt.Errorf("checkpoints cannot be combined with the drop-oldest overflow policy")
+		}
+		store := e.checkpoints
+		if e.slice.active() {
+			store = checkpoint.ReadOnly(store)
+		}
+		if v, ok := As[Versioner](e.processor); ok {
+			store = checkpoint.Versioned(store, v.Version(), e.onVersionChange)
+		}
+		tracker, err := newResumeTracker(ctx, resumer, store, e.checkpointName)
+		if err != nil {
+			return err
+		}
+		resume = tracker
+	}
+
 	// Create bucket for batching
-	b, err := bucket.New[E](bucketCfg)
+	b, err := bucket.New[E](e.bucketConfig(bucketCfg))
 	if err != nil {
 		return fmt.Errorf("failed to create bucket: %w", err)
 	}
+	b.SetOnOverflow(func(_ E, policy bucket.OverflowPolicy) { e.overflowed(policy) })
+	var extractDone atomic.Bool
+	if e.budget != nil {
+		e.budget.failed.Store(0)
+		b.SetDeadLetterFailures(true)
+	}
+	var deadLetter bucket.DeadLetterFunc[E]
+	if handler, ok := As[DeadLetterHandler[E]](e.processor); ok || e.deadLetters != nil || e.budget != nil {
+		deadLetter = func(ctx context.Context, items []E, err error) error {
+			if e.budget != nil {
+				if err := e.budget.fail(len(items), e.progress.extracted.Load(), extractDone.Load()); err != nil {
+					return err
+				}
+			}
+			if e.onDeadLettered != nil {
+				e.onDeadLettered(len(items))
+			}
+			if e.deadLetters != nil {
+				if err := e.storeDeadLetters(ctx, items, err); err != nil {
+					return err
+				}
+			}
+			if handler != nil {
+				if err := handler.DeadLetter(ctx, items, err); err != nil {
+					return err
+				}
+			}
+			if resume != nil {
+				return resume.settled(ctx, items)
+			}
+			return nil
+		}
+		b.SetDeadLetter(deadLetter)
+	}
+	b.SetStrategy(strategy)
+
+	// Optional queue between transform and load
+	var (
+		loadBucket *bucket.Bucket[T]
+		loadErr    = make(chan error, 1)
+	)
+	if e.loadQueue != nil {
+		loadBucket, err = bucket.New[T](e.bucketConfig(e.loadQueue))
+		if err != nil {
+			return fmt.Errorf("failed to create load queue: %w", err)
+		}
+		loadBucket.SetOnOverflow(func(_ T, policy bucket.OverflowPolicy) { e.overflowed(policy) })
+
+		go func() {
+			err := loadBucket.Run(ctx, func(ctx context.Context, items []T) error {
+				return e.load(e.batchContext(ctx), items)
+			})
+			if err != nil {
+				cancel() // Stop extracting and transforming
+			}
+			loadErr <- err
+		}()
+	}
+
+	// Optional async loads, overlapping the next transform
+	var async *asyncLoads[T]
+	if e.inFlight > 1 {
+		async = e.newAsyncLoads(runCtx, cancel, bucketCfg.ShutdownTimeout)
+	}
 
-	// Extract data
-	extractor, err := e.processor.Extract(ctx)
+	// Commit and checkpoint a batch once loaded
+	settle := func(ctx context.Context, items []E) error {
+		if committer != nil {
+			if err := committer.Commit(ctx, items); err != nil {
+				return fmt.Errorf("failed to commit batch: %w", err)
+			}
+		}
+		if resume != nil {
+			return resume.settled(ctx, items)
+		}
+		return nil
+	}
+
+	// Extract data; the extraction is cancelled on its own at the limit
+	extractCtx, stopExtract := context.WithCancel(runCtx)
+	defer stopExtract()
+	extractCtx, extractSpan := e.startSpan(extractCtx, "etl.extract")
+	extractor, err := e.processor.Extract(extractCtx)
 	if err != nil {
+		endSpan(extractSpan, err)
+		if loadBucket != nil {
+			loadBucket.Close()
+			<-loadErr
+		}
 		return fmt.Errorf("failed to extract: %w", err)
 	}
 
 	// Feed extractor into bucket
+	extractFailed := make(chan error, 1) // Stopped extraction, failing the run
 	go func() {
+		var extractErr error
+		defer func() {
+			extractSpan.SetAttributes(attribute.Int64("etl.records", e.progress.extracted.Load()))
+			endSpan(extractSpan, extractErr)
+		}()
+
+		var kept int64
 		for {
+			waitStart := time.Now()
 			select {
-			case <-ctx.Done():
+			case <-runCtx.Done():
 				b.Close()
 				return
 			case payload, ok := <-extractor:
+				e.progress.stages.extractWait.Add(int64(time.Since(waitStart)))
 				if !ok {
+					extractDone.Store(true)
 					b.Close()
 					return
 				}
+				if payload.Err != nil && IsRecordError(payload.Err) {
+					e.progress.extracted.Add(1)
+					if err := e.skipRecord(runCtx, payload.Err); err != nil {
+						e.log().Error("Failed to skip record", "error", err)
+						extractErr = err
+						extractFailed <- err
+						b.Close()
+						return
+					}
+					continue
+				}
 				if payload.Err != nil {
-					fmt.Printf("ERROR: Failed to extract: %v\n", payload.Err)
+					e.log().Error("Failed to extract", "error", payload.Err)
+					extractErr = payload.Err
+					extractFailed <- payload.Err
 					b.Close()
 					return
 				}
+				e.progress.extracted.Add(1)
+				if !e.slice.keep() {
+					continue
+				}
+				if resume != nil {
+					resume.extracted(payload.Data)
+				}
+				consumeStart := time.Now()
 				b.Consume(payload.Data)
+				e.progress.stages.queueWait.Add(int64(time.Since(consumeStart)))
+				if kept++; e.slice.limit > 0 && kept >= e.slice.limit {
+					e.log().Info("Extraction limit reached", "limit", e.slice.limit)
+					stopExtract()
+					b.Close()
+					return
+				}
 			}
 		}
 	}()
 
 	// Process batches: Transform -> Load
-	err = b.Run(ctx, func(ctx context.Context, items []E) error {
-		// Transform each item
-		transformed := make([]T, 0, len(items))
-		for _, item := range items {
-			t := e.processor.Transform(ctx, item)
-			transformed = append(transformed, t)
+	transforms := &transformedBatches[T]{}
+	err = b.Run(runCtx, func(ctx context.Context, items []E) (err error) {
+		ctx = e.batchContext(ctx)
+		ctx, span := e.startBatchSpan(ctx, items, extractSpan.SpanContext())
+		defer func() { endSpan(span, err) }()
+
+		// Transform each item, once across the retries of the batch
+		batch := transforms.get(ctx, len(items))
+		defer func() {
+			if err == nil {
+				transforms.done(ctx)
+			}
+		}()
+		transformCtx, transformSpan := e.startSpan(ctx, "etl.transform")
+		transformStart := time.Now()
+		before := len(batch.transformed)
+		for ; batch.next < len(items); batch.next++ {
+			item := items[batch.next]
+			t, err := e.transform(transformCtx, item)
+			if err != nil {
+				deadLettered, err := e.isolate(ctx, item, err, deadLetter)
+				if err != nil {
+					transformSpan.End()
+					return err
+				}
+				if deadLettered {
+					if batch.isolated == nil {
+						batch.isolated = make(map[int]bool)
+					}
+					batch.isolated[batch.next] = true
+				}
+				continue
+			}
+			if e.sampler != nil {
+				if n, ok := e.sampler.next(); ok {
+					e.sampler.log(ctx, n, item, t)
+				}
+			}
+			batch.transformed = append(batch.transformed, t)
+		}
+		e.progress.stages.transform.Add(int64(time.Since(transformStart)))
+		transformSpan.End()
+		e.progress.transformed.Add(int64(len(batch.transformed) - before))
+		transformed, isolated := batch.transformed, batch.isolated
+
+		// Hand off to the load queue
+		if loadBucket != nil {
+			for _, t := range transformed {
+				loadBucket.Consume(t)
+			}
+			return nil
 		}
 
 		// Load batch
-		return e.processor.Load(ctx, transformed)
+		rest := items
+		if len(isolated) > 0 {
+			rest = make([]E, 0, len(items)-len(isolated))
+			for i, item := range items {
+				if !isolated[i] {
+					rest = append(rest, item)
+				}
+			}
+		}
+		if async != nil {
+			load := func(ctx context.Context) (bool, error) {
+				return b.Process(ctx, rest, func(ctx context.Context, _ []E) error {
+					return e.load(ctx, transformed)
+				})
+			}
+			return async.load(ctx, transformed, load, func(ctx context.Context) error {
+				return settle(ctx, rest)
+			})
+		}
+		if err := e.load(ctx, transformed); err != nil {
+			return err
+		}
+		return settle(ctx, rest)
 	})
 
+	if async != nil {
+		if aerr := async.wait(err); aerr != nil {
+			return fmt.Errorf("failed to load: %w", aerr)
+		}
+	}
+
+	if loadBucket != nil {
+		loadBucket.Close()
+		if lerr := <-loadErr; lerr != nil {
+			return fmt.Errorf("failed to load: %w", lerr)
+		}
+	}
+
 	if err != nil {
 		return fmt.Errorf("failed to run ETL: %w", err)
 	}
+	select {
+	case err := <-extractFailed:
+		return fmt.Errorf("failed to extract: %w", err)
+	default:
+	}
+	if e.budget != nil {
+		if err := e.budget.check(e.budget.failed.Load(), e.progress.extracted.Load(), true); err != nil {
+			return fmt.Errorf("failed to run ETL: %w", err)
+		}
+	}
 
 	// Post-processing (cleanup, sync tracking, etc.)
 	if err := e.processor.PostProcess(ctx); err != nil {
@@ -116,6 +477,49 @@ func (e *ETL[E, T]) Run(ctx context.Context, bucketCfg *bucket.Config) error {
 	return nil
 }
 
+// overflowed counts a record a full queue discarded or spilled
+func (e *ETL[E, T]) overflowed(policy bucket.OverflowPolicy) {
+	if policy == bucket.OverflowSpill {
+		e.progress.spilled.Add(1)
+		return
+	}
+	e.progress.dropped.Add(1)
+	if e.onDropped != nil {
+		e.onDropped(1)
+	}
+}
+
+// Progress returns the record counters of the current or last run
+func (e *ETL[E, T]) Progress() Progress {
+	return e.progress.snapshot()
+}
+
+// load loads a batch and verifies a sample of it when enabled
+func (e *ETL[E, T]) load(ctx context.Context, items []T) (err error) {
+	ctx, span := e.startSpan(ctx, "etl.load", trace.WithAttributes(attribute.Int("etl.batch.records", len(items))))
+	defer func() { endSpan(span, err) }()
+
+	var rejected atomic.Int64
+	start := time.Now()
+	err = e.processor.Load(countRejections(ctx, &rejected), items)
+	took := time.Since(start)
+	e.progress.stages.load.Add(int64(took))
+	if err != nil {
+		return err
+	}
+	loaded := max(len(items)-int(rejected.Load()), 0)
+	e.progress.loaded.Add(int64(loaded))
+	e.progress.batches.Add(1)
+	if e.onBatchLoaded != nil {
+		e.onBatchLoaded(loaded, took)
+	}
+
+	if e.verifier != nil {
+		return e.verifier.verify(ctx, items)
+	}
+	return nil
+}
+
 // PreProcess calls the processor's pre-process hook
 func (e *ETL[E, T]) PreProcess(ctx context.Context) error {
 	return e.processor.PreProcess(ctx)
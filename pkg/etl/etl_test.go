@@ -0,0 +1,77 @@
+package etl
+
+import (
+	"context"
+	"sync"
+)
+
+// testProcessor extracts items and loads them through the optional
+// transform and load functions, recording what it loaded and dead-lettered
+type testProcessor struct {
+	items     []int
+	transform func(item int) int                          // Defaults to the identity
+	loadFn    func(ctx context.Context, data []int) error // Called before recording the batch
+
+	mu           sync.Mutex
+	loaded       [][]int
+	deadLettered []int
+}
+
+func (p *testProcessor) Extract(ctx context.Context) (<-chan Payload[int], error) {
+	ch := make(chan Payload[int])
+	go func() {
+		defer close(ch)
+		for _, item := range p.items {
+			select {
+			case ch <- Payload[int]{Data: item}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (p *testProcessor) Transform(_ context.Context, item int) int {
+	if p.transform == nil {
+		return item
+	}
+	return p.transform(item)
+}
+
+func (p *testProcessor) Load(ctx context.Context, data []int) error {
+	if p.loadFn != nil {
+		if err := p.loadFn(ctx, data); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.loaded = append(p.loaded, append([]int(nil), data...))
+	return nil
+}
+
+func (p *testProcessor) DeadLetter(_ context.Context, items []int, _ error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.deadLettered = append(p.deadLettered, items...)
+	return nil
+}
+
+func (p *testProcessor) PreProcess(context.Context) error  { return nil }
+func (p *testProcessor) PostProcess(context.Context) error { return nil }
+
+// loadedItems returns the loaded items in load order
+func (p *testProcessor) loadedItems() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var items []int
+	for _, batch := range p.loaded {
+		items = append(items, batch...)
+	}
+	return items
+}
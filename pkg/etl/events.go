@@ -0,0 +1,87 @@
+package etl
+
+import (
+	"time"
+)
+
+// EventType identifies a manager lifecycle event
+type EventType int
+
+const (
+	PipelineStarted   EventType = iota // An attempt of a pipeline started running
+	BatchLoaded                        // A batch was loaded by a pipeline
+	PipelineRetrying                   // An attempt failed and will be retried
+	PipelineFailed                     // A pipeline run failed or was skipped
+	PipelineFinished                   // A pipeline run completed successfully
+	ManagerDone                        // RunAll or Run returned
+	RecordsDropped                     // A full queue discarded records, see bucket.OverflowDropOldest
+	BatchDeadLettered                  // A batch was handed to the processor's DeadLetter
+	StallDetected                      // A running pipeline moved no records for Config.StallThreshold
+	RecordsRejected                    // A processor left records out of the load, see RejectRecords
+)
+
+// String returns the event type name
+func (t EventType) String() string {
+	switch t {
+	case PipelineStarted:
+		return "PipelineStarted"
+	case BatchLoaded:
+		return "BatchLoaded"
+	case PipelineRetrying:
+		return "PipelineRetrying"
+	case PipelineFailed:
+		return "PipelineFailed"
+	case PipelineFinished:
+		return "PipelineFinished"
+	case ManagerDone:
+		return "ManagerDone"
+	case RecordsDropped:
+		return "RecordsDropped"
+	case BatchDeadLettered:
+		return "BatchDeadLettered"
+	case StallDetected:
+		return "StallDetected"
+	case RecordsRejected:
+		return "RecordsRejected"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event describes something that happened during a run
+type Event struct {
+	Type     EventType
+	Pipeline string // Empty for ManagerDone
+	Time     time.Time
+	Attempt  int           // Attempt number for pipeline events
+	Records  int           // Records in the batch for BatchLoaded and BatchDeadLettered, rejected for RecordsRejected, dropped for RecordsDropped
+	Rule     string        // Rule the records violated for RecordsRejected
+	Duration time.Duration // Load latency for BatchLoaded, run time for PipelineFinished and PipelineFailed, idle time for StallDetected
+	Err      error         // Failure for PipelineRetrying, PipelineFailed and ManagerDone
+}
+
+// OnEvent subscribes fn to lifecycle events
+// Listeners are called synchronously from pipeline goroutines, possibly
+// concurrently, so they must be safe for concurrent use and return quickly.
+func (m *Manager) OnEvent(fn func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.listeners = append(m.listeners, fn)
+}
+
+// emit delivers an event to all listeners
+func (m *Manager) emit(e Event) {
+	m.mu.Lock()
+	listeners := m.listeners
+	m.mu.Unlock()
+
+	if len(listeners) == 0 {
+		return
+	}
+
+	e.Time = time.Now()
+	for _, fn := range listeners {
+		fn(e)
+	}
+}
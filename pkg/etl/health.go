@@ -0,0 +1,142 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthChecker can optionally be implemented by an ETLProcessor or
+// ETLRunner to report whether its source and sink are reachable
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthReport is the result of a liveness or readiness probe
+type HealthReport struct {
+	OK       bool              `json:"ok"`
+	InFlight int               `json:"in_flight"` // Running or retrying pipelines
+	Pending  int               `json:"pending"`   // Pipelines waiting to start
+	Stalled  []string          `json:"stalled,omitempty"`
+	Checks   map[string]string `json:"checks,omitempty"` // Failed health checks by pipeline
+}
+
+// progressMark is the last observed progress of a running pipeline
+type progressMark struct {
+	progress  Progress
+	changedAt time.Time
+}
+
+// Healthz reports whether the manager is live: it fails while a pipeline
+// has been running without progress for Config.StallThreshold
+// Progress is sampled on each call, so stalls are detected with the
+// granularity of the probe interval.
+func (m *Manager) Healthz() HealthReport {
+	report := HealthReport{OK: true}
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.pipelines {
+		s, ok := m.status[p.Name()]
+		if !ok {
+			continue
+		}
+
+		switch s.State {
+		case StatePending:
+			report.Pending++
+			continue
+		case StateRunning, StateRetrying:
+			report.InFlight++
+		default:
+			delete(m.progressMarks, p.Name())
+			continue
+		}
+
+		reporter, ok := p.(ProgressReporter)
+		if !ok || m.cfg.StallThreshold <= 0 {
+			continue
+		}
+
+		progress := reporter.Progress()
+		mark, ok := m.progressMarks[p.Name()]
+		if !ok || mark.changedAt.Before(s.StartedAt) {
+			mark = progressMark{progress: progress, changedAt: s.StartedAt}
+		}
+		if progress != mark.progress {
+			mark = progressMark{progress: progress, changedAt: now}
+		}
+		m.progressMarks[p.Name()] = mark
+
+		if now.Sub(mark.changedAt) >= m.cfg.StallThreshold {
+			report.Stalled = append(report.Stalled, p.Name())
+			report.OK = false
+		}
+	}
+	return report
+}
+
+// Readyz reports whether the manager is ready to run pipelines: it fails
+// when the health check of any pipeline implementing HealthChecker fails
+// Checks run concurrently and are bounded by ctx.
+func (m *Manager) Readyz(ctx context.Context) HealthReport {
+	report := m.Healthz()
+	report.OK = true
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, p := range m.Pipelines() {
+		checker, ok := p.(HealthChecker)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			if err := checker.HealthCheck(ctx); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if report.Checks == nil {
+					report.Checks = make(map[string]string)
+				}
+				report.Checks[name] = err.Error()
+				report.OK = false
+			}
+		}(p.Name())
+	}
+	wg.Wait()
+
+	return report
+}
+
+// HealthzHandler serves Healthz as JSON, with status 503 when not live
+func (m *Manager) HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, m.Healthz())
+	})
+}
+
+// ReadyzHandler serves Readyz as JSON, with status 503 when not ready
+func (m *Manager) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, m.Readyz(r.Context()))
+	})
+}
+
+// writeHealth writes a health report response
+func writeHealth(w http.ResponseWriter, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	if !report.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
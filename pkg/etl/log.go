@@ -0,0 +1,61 @@
+package etl
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+)
+
+// loggerContextKey is the context key for the pipeline logger
+type loggerContextKey struct{}
+
+// WithLogger returns a context carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext returns the logger carried by ctx, or slog.Default
+// Processors receive a logger with the pipeline name and, within Transform
+// and Load, the batch ID attached.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// SetLogger sets the logger of the ETL and of the buckets it creates
+// Defaults to slog.Default.
+func (e *ETL[E, T]) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// log returns the ETL logger
+func (e *ETL[E, T]) log() *slog.Logger {
+	if e.logger != nil {
+		return e.logger
+	}
+	return slog.Default()
+}
+
+// bucketConfig returns cfg with the ETL logger unless it sets its own
+func (e *ETL[E, T]) bucketConfig(cfg *bucket.Config) *bucket.Config {
+	if cfg.Logger != nil {
+		return cfg
+	}
+
+	c := *cfg
+	c.Logger = e.log()
+	return &c
+}
+
+// batchContext attaches a logger with the batch ID to a bucket worker's
+// context
+func (e *ETL[E, T]) batchContext(ctx context.Context) context.Context {
+	logger := e.log()
+	if id, ok := bucket.BatchID(ctx); ok {
+		logger = logger.With("batch", id)
+	}
+	return WithLogger(ctx, logger)
+}
@@ -4,10 +4,19 @@ package etl
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/dlq"
 )
 
 // ETLRunner interface for objects that can be run as ETL pipelines
@@ -18,14 +27,86 @@ type ETLRunner interface {
 
 // Config configures the manager's behavior
 type Config struct {
-	WorkerNum int // Maximum number of concurrent pipelines
+	WorkerNum   int           // Maximum number of concurrent pipelines
+	ErrorPolicy ErrorPolicy   // How RunAll reacts to a failed pipeline
+	RunTimeout  time.Duration // Wall-clock budget for RunAll, 0 for none
+	RunDeadline time.Time     // Absolute deadline for RunAll, zero for none
+
+	// PipelineTimeout is the default maximum run time of each pipeline,
+	// 0 for none. See Manager.SetTimeout.
+	PipelineTimeout time.Duration
+
+	// StallThreshold is how long a running pipeline may go without progress
+	// before Healthz reports it as stalled and a StallDetected event is
+	// emitted, 0 to disable
+	StallThreshold time.Duration
+
+	// StallCancel cancels a stalled attempt, which fails with
+	// ErrPipelineStalled and is retried according to the retry policy
+	// Sources blocked in calls that ignore the context still hang.
+	StallCancel bool
+
+	ReadinessInterval time.Duration // How often readiness checks are polled (defaults to 30s)
+	ReadinessTimeout  time.Duration // Max time to wait for readiness, 0 waits until ctx is done
+
+	// Logger receives manager and pipeline logs, with the pipeline name
+	// attached (defaults to slog.Default)
+	Logger *slog.Logger
+
+	// Checkpoints commits the progress of pipelines whose processor
+	// implements Resumer, under the pipeline name; nil disables resuming
+	// unless set per pipeline with WithCheckpoints
+	Checkpoints checkpoint.Store
+
+	// DeadLetters keeps the records of dead-lettered batches of pipelines
+	// added with AddPipelineGeneric, under the pipeline name, for ReplayDLQ;
+	// nil unless set per pipeline with WithDeadLetters
+	DeadLetters dlq.Store
+
+	// TracerProvider traces the runs of pipelines added with
+	// AddPipelineGeneric (defaults to the global provider of otel); see
+	// ETL.SetTracerProvider
+	TracerProvider trace.TracerProvider
 }
 
+// ErrorPolicy decides how RunAll handles pipeline failures
+type ErrorPolicy int
+
+const (
+	// ContinueOnError lets the remaining pipelines run to completion and
+	// returns every failure joined with errors.Join
+	ContinueOnError ErrorPolicy = iota
+
+	// FailFast cancels all other pipelines on the first failure and returns
+	// that failure only
+	FailFast
+)
+
 // Manager manages and runs multiple ETL pipelines concurrently
 type Manager struct {
 	pipelines    []ETLRunner
+	deps         map[string][]string // Pipeline name -> names it depends on
+	readiness    map[string][]ReadinessCheck
+	retry        map[string]RetryPolicy
+	timeouts     map[string]time.Duration
+	priority     map[string]int
+	claims       *ClaimConfig
+	schedules    []*schedule
+	scheduler    *scheduler // Set while RunScheduler or Serve runs
 	cfg          Config
 	bucketConfig *bucket.Config
+	sem          *semaphore  // Limits concurrent pipeline execution
+	triggers     chan string // Pipeline names queued by TriggerRun
+
+	mu            sync.Mutex
+	active        map[string]int             // Runs in progress per pipeline
+	idleWaiters   map[string][]chan struct{} // Closed when a pipeline has no runs in progress
+	status        map[string]*PipelineStatus
+	listeners     []func(Event)
+	progressMarks map[string]progressMark
+
+	metricsReporter MetricsReporter
+	lastMetrics     RunMetrics
 }
 
 // NewManager creates a new ETL manager
@@ -33,95 +114,586 @@ func NewManager(cfg *Config, bucketConfig *bucket.Config) *Manager {
 	if cfg.WorkerNum <= 0 {
 		cfg.WorkerNum = 4
 	}
+	if cfg.ReadinessInterval <= 0 {
+		cfg.ReadinessInterval = 30 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
 
 	return &Manager{
-		pipelines:    make([]ETLRunner, 0),
-		cfg:          *cfg,
-		bucketConfig: bucketConfig,
+		pipelines:     make([]ETLRunner, 0),
+		deps:          make(map[string][]string),
+		readiness:     make(map[string][]ReadinessCheck),
+		retry:         make(map[string]RetryPolicy),
+		timeouts:      make(map[string]time.Duration),
+		priority:      make(map[string]int),
+		cfg:           *cfg,
+		bucketConfig:  bucketConfig,
+		sem:           newSemaphore(cfg.WorkerNum),
+		triggers:      make(chan string, triggerQueueSize),
+		active:        make(map[string]int),
+		idleWaiters:   make(map[string][]chan struct{}),
+		status:        make(map[string]*PipelineStatus),
+		progressMarks: make(map[string]progressMark),
 	}
 }
 
-// AddPipeline adds an ETL pipeline to the manager
-// Use AddPipelineGeneric function instead
-func (m *Manager) addPipelineInternal(runner ETLRunner) {
-	m.pipelines = append(m.pipelines, runner)
+// AddRunner adds a custom ETL runner to the manager
+// It fails with ErrDuplicatePipeline if the name is already registered.
+func (m *Manager) AddRunner(runner ETLRunner) error {
+	return m.register(runner)
 }
 
-// AddRunner adds a custom ETL runner to the manager
-func (m *Manager) AddRunner(runner ETLRunner) {
-	m.pipelines = append(m.pipelines, runner)
+// AddPipelineWithDeps adds a custom ETL runner that only starts once every
+// pipeline named in dependsOn has completed successfully
+// It fails with ErrDuplicatePipeline if the name is already registered.
+func (m *Manager) AddPipelineWithDeps(runner ETLRunner, dependsOn ...string) error {
+	if err := m.register(runner); err != nil {
+		return err
+	}
+	if len(dependsOn) > 0 {
+		m.mu.Lock()
+		m.deps[runner.Name()] = append(m.deps[runner.Name()], dependsOn...)
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// PipelineOption customizes a single pipeline added with AddPipelineGeneric
+type PipelineOption func(*pipelineOptions)
+
+// pipelineOptions holds per-pipeline settings
+type pipelineOptions struct {
+	bucketConfig *bucket.Config
+	loadQueue    *bucket.Config
+	dependsOn    []string
+	readiness    []ReadinessCheck
+	retry        *RetryPolicy
+	verify       *VerifyConfig
+	sampling     *SampleConfig
+	limit        int64
+	sampleRate   float64
+	errorBudget  *ErrorBudget
+	panicPolicy  *PanicPolicy
+	inFlight     int
+	timeout      time.Duration
+	priority     *int
+	checkpoints  checkpoint.Store
+	deadLetters  dlq.Store
+
+	onVersionChange checkpoint.OnVersionChange
+	tracerProvider  trace.TracerProvider
+}
+
+// WithBucketConfig overrides the manager's bucket config for one pipeline,
+// e.g. to give it a different queue size or overflow policy
+func WithBucketConfig(cfg *bucket.Config) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.bucketConfig = cfg
+	}
+}
+
+// WithLoadQueue adds a queue between the pipeline's transform and load stages
+// See ETL.SetLoadQueue
+func WithLoadQueue(cfg *bucket.Config) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.loadQueue = cfg
+	}
+}
+
+// WithDependencies makes the pipeline wait for the named pipelines to
+// complete successfully before it starts
+func WithDependencies(names ...string) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.dependsOn = append(o.dependsOn, names...)
+	}
 }
 
 // AddPipelineGeneric adds an ETL pipeline with type parameters
 // E: Extract type, T: Transform/Load type
-func AddPipelineGeneric[E, T any](m *Manager, processor ETLProcessor[E, T], name string) {
-	adapter := &pipelineAdapter[E, T]{
-		etl:  NewETL(processor),
-		name: name,
+// It fails with ErrDuplicatePipeline if the name is already registered.
+func AddPipelineGeneric[E, T any](m *Manager, processor ETLProcessor[E, T], name string, opts ...PipelineOption) error {
+	o := newPipelineOptions(opts)
+	if err := m.AddPipelineWithDeps(newPipelineAdapter(m, processor, name, o), o.dependsOn...); err != nil {
+		return err
+	}
+	m.applyOptions(name, o)
+	return nil
+}
+
+// newPipelineOptions applies opts to empty pipeline options
+func newPipelineOptions(opts []PipelineOption) pipelineOptions {
+	var o pipelineOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// newPipelineAdapter wraps processor in an ETL configured from o
+func newPipelineAdapter[E, T any](m *Manager, processor ETLProcessor[E, T], name string, o pipelineOptions) *pipelineAdapter[E, T] {
+	e := NewETL(processor)
+	e.SetLogger(m.cfg.Logger.With("pipeline", name))
+	if o.loadQueue != nil {
+		e.SetLoadQueue(o.loadQueue)
+	}
+	if o.verify != nil {
+		e.SetVerification(*o.verify)
+	}
+	if o.sampling != nil {
+		e.SetSampling(*o.sampling)
+	}
+	if o.errorBudget != nil {
+		e.SetErrorBudget(*o.errorBudget)
+	}
+	if o.panicPolicy != nil {
+		e.SetPanicPolicy(*o.panicPolicy)
+	}
+	e.SetAsyncLoad(o.inFlight)
+	e.SetLimit(o.limit)
+	e.SetSampleRate(o.sampleRate)
+	if o.checkpoints != nil {
+		e.SetCheckpoints(o.checkpoints, name)
+	} else if m.cfg.Checkpoints != nil {
+		e.SetCheckpoints(m.cfg.Checkpoints, name)
+	}
+	e.SetOnVersionChange(o.onVersionChange)
+	if o.tracerProvider != nil {
+		e.SetTracerProvider(o.tracerProvider, name)
+	} else {
+		e.SetTracerProvider(m.cfg.TracerProvider, name)
+	}
+	if o.deadLetters != nil {
+		e.SetDeadLetters(o.deadLetters, name)
+	} else if m.cfg.DeadLetters != nil {
+		e.SetDeadLetters(m.cfg.DeadLetters, name)
+	}
+
+	e.onBatchLoaded = func(records int, took time.Duration) {
+		m.emit(Event{Type: BatchLoaded, Pipeline: name, Records: records, Duration: took})
+	}
+	e.onDropped = func(records int) {
+		m.emit(Event{Type: RecordsDropped, Pipeline: name, Records: records})
+	}
+	e.onDeadLettered = func(records int) {
+		m.emit(Event{Type: BatchDeadLettered, Pipeline: name, Records: records})
+	}
+	e.onRejected = func(rule string, records int) {
+		m.emit(Event{Type: RecordsRejected, Pipeline: name, Rule: rule, Records: records})
+	}
+
+	return &pipelineAdapter[E, T]{
+		etl:          e,
+		name:         name,
+		bucketConfig: o.bucketConfig,
+	}
+}
+
+// applyOptions records the manager-level settings in o for the named
+// pipeline
+func (m *Manager) applyOptions(name string, o pipelineOptions) {
+	if len(o.readiness) > 0 {
+		m.AddReadinessChecks(name, o.readiness...)
+	}
+	if o.retry != nil {
+		m.SetRetryPolicy(name, *o.retry)
+	}
+	if o.timeout > 0 {
+		m.SetTimeout(name, o.timeout)
+	}
+	if o.priority != nil {
+		m.SetPriority(name, *o.priority)
 	}
-	m.addPipelineInternal(adapter)
 }
 
 // RunAll executes all pipelines concurrently with semaphore-limited parallelism
 // Inspired by Rust's ETLPipelineManager with semaphore + channel pattern
+// Pipelines with dependencies start only after all of their prerequisites
+// succeed; if a prerequisite fails, its dependents are skipped with an error.
+// Pipelines with readiness checks then wait until every check reports ready.
+// Failures are handled according to Config.ErrorPolicy; per-pipeline
+// outcomes are available from Results afterwards.
+// Pipelines exceeding their timeout or Config.RunDeadline are cancelled,
+// their buckets drain within bucket.Config.ShutdownTimeout, and they fail
+// with a TimeoutError.
 func (m *Manager) RunAll(ctx context.Context) error {
-	if len(m.pipelines) == 0 {
+	pipelines := m.Pipelines()
+	if len(pipelines) == 0 {
 		return fmt.Errorf("no pipelines registered")
 	}
 
-	// Semaphore to limit concurrent pipeline execution
-	sem := make(chan struct{}, m.cfg.WorkerNum)
+	return m.run(ctx, pipelines)
+}
+
+// Run executes only the named pipelines, e.g. to re-run the one that failed
+// in a nightly batch, with the same semantics as RunAll
+// Dependencies on pipelines outside names are treated as satisfied.
+func (m *Manager) Run(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("no pipelines named")
+	}
+
+	pipelines := make([]ETLRunner, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		p, err := m.pipeline(name)
+		if err != nil {
+			return err
+		}
+		pipelines = append(pipelines, p)
+	}
+
+	return m.run(ctx, pipelines)
+}
+
+// run executes pipelines concurrently, then records metrics and emits
+// ManagerDone
+func (m *Manager) run(ctx context.Context, pipelines []ETLRunner) error {
+	startedAt := time.Now()
+	err := m.runPipelines(ctx, pipelines)
+	m.recordMetrics(pipelines, startedAt)
+	m.emit(Event{Type: ManagerDone, Err: err})
+	return err
+}
+
+// runPipelines executes pipelines concurrently, honouring dependencies
+// among them
+func (m *Manager) runPipelines(ctx context.Context, pipelines []ETLRunner) error {
+	if _, err := m.topoOrder(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if !m.cfg.RunDeadline.IsZero() {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadlineCause(ctx, m.cfg.RunDeadline, ErrRunDeadlineExceeded)
+		defer cancelDeadline()
+	}
+
+	// Enforce the run budget, remembering which pipelines it cut short
+	var (
+		cutShort     []string
+		cutShortDone = make(chan struct{})
+	)
+	if m.cfg.RunTimeout > 0 {
+		var cancelBudget context.CancelCauseFunc
+		ctx, cancelBudget = context.WithCancelCause(ctx)
+		defer cancelBudget(nil)
+
+		// Snapshot unfinished pipelines before they observe the cancellation
+		timer := time.AfterFunc(m.cfg.RunTimeout, func() {
+			cutShort = m.unfinished(pipelines)
+			close(cutShortDone)
+			cancelBudget(ErrRunBudgetExceeded)
+		})
+		defer timer.Stop()
+	}
 
-	// Channel to collect results
-	results := make(chan error, len(m.pipelines))
+	// Completion state per pipeline, used to gate dependents
+	states := make(map[string]*pipelineState, len(pipelines))
+	for _, p := range pipelines {
+		states[p.Name()] = &pipelineState{done: make(chan struct{})}
+		m.markPending(p.Name())
+	}
+	m.reserveSlots(pipelines, states)
 
-	var wg sync.WaitGroup
+	var (
+		wg        sync.WaitGroup
+		firstOnce sync.Once
+		firstErr  error
+	)
 
 	// Launch all pipelines
-	for _, pipeline := range m.pipelines {
+	for _, pipeline := range pipelines {
 		wg.Add(1)
 
 		go func(p ETLRunner) {
 			defer wg.Done()
 
-			// Acquire semaphore slot
-			sem <- struct{}{}
-			defer func() { <-sem }()
+			state := states[p.Name()]
+			defer close(state.done)
 
-			// Run pipeline
-			if err := p.Run(ctx, m.bucketConfig); err != nil {
-				results <- fmt.Errorf("pipeline %s failed: %w", p.Name(), err)
-			} else {
-				results <- nil
+			state.err = m.runPipeline(ctx, p, states)
+			m.markFinished(p.Name(), state.err)
+			if state.err != nil && m.cfg.ErrorPolicy == FailFast {
+				firstOnce.Do(func() {
+					firstErr = state.err
+					cancel() // Stop sibling pipelines
+				})
 			}
 		}(pipeline)
 	}
 
 	// Wait for all pipelines to complete
 	wg.Wait()
-	close(results)
 
-	// Collect and return first error if any
-	for err := range results {
+	var errs []error
+	for _, p := range pipelines {
+		if err := states[p.Name()].err; err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if context.Cause(ctx) == ErrRunBudgetExceeded {
+		<-cutShortDone
+		return &BudgetExceededError{
+			Budget:   m.cfg.RunTimeout,
+			CutShort: cutShort,
+			Err:      errors.Join(errs...),
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return errors.Join(errs...)
+}
+
+// ErrRunBudgetExceeded is the cause of cancellation when RunAll exceeds
+// Config.RunTimeout
+var ErrRunBudgetExceeded = errors.New("run budget exceeded")
+
+// BudgetExceededError reports the pipelines cut short by Config.RunTimeout
+type BudgetExceededError struct {
+	Budget   time.Duration
+	CutShort []string // Pipelines pending or running when the budget ran out
+	Err      error    // Pipeline failures, including those caused by the cancellation
+}
+
+func (e *BudgetExceededError) Error() string {
+	msg := fmt.Sprintf("run budget of %s exceeded, cut short: %s", e.Budget, strings.Join(e.CutShort, ", "))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *BudgetExceededError) Unwrap() []error {
+	return []error{ErrRunBudgetExceeded, e.Err}
+}
+
+// unfinished returns the pipelines that have not completed their run
+func (m *Manager) unfinished(pipelines []ETLRunner) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for _, p := range pipelines {
+		if s, ok := m.status[p.Name()]; ok && !s.State.finished() {
+			names = append(names, p.Name())
+		}
+	}
+	return names
+}
+
+// Results returns the outcome of the last completed run of each pipeline
+// A nil error means the pipeline succeeded.
+func (m *Manager) Results() map[string]error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make(map[string]error, len(m.status))
+	for name, s := range m.status {
+		if s.State.finished() {
+			results[name] = s.LastError
+		}
+	}
+	return results
+}
+
+// RunPipeline runs a single registered pipeline, honouring its readiness
+// checks, retry policy and the manager's concurrency limit but not its
+// dependencies
+func (m *Manager) RunPipeline(ctx context.Context, name string) error {
+	p, err := m.pipeline(name)
+	if err != nil {
+		return err
+	}
+
+	m.markPending(name)
+	err = m.execute(ctx, p, nil)
+	m.markFinished(name, err)
+
+	return err
+}
+
+// Backfill runs a single pipeline over a historical window, overriding its
+// stored watermark for this run only
+// Incremental sources read the window with checkpoint.WindowFromContext and
+// leave the saved state untouched.
+func (m *Manager) Backfill(ctx context.Context, name string, w checkpoint.Window) error {
+	if w.IsZero() {
+		return fmt.Errorf("backfill of %s: empty window", name)
+	}
+	return m.RunPipeline(checkpoint.WithWindow(ctx, w), name)
+}
+
+// runPipeline waits for the pipeline's dependencies, then executes it
+func (m *Manager) runPipeline(ctx context.Context, p ETLRunner, states map[string]*pipelineState) error {
+	// Wait for prerequisites before taking a semaphore slot
+	for _, dep := range m.settings(p.Name()).deps {
+		depState, ok := states[dep]
+		if !ok {
+			// Not part of this run
+			continue
+		}
+		<-depState.done
+		if depState.err != nil {
+			return fmt.Errorf("pipeline %s skipped: dependency %s failed", p.Name(), dep)
+		}
+	}
+
+	return m.execute(ctx, p, states[p.Name()].slot)
+}
+
+// reserveSlots queues pipelines that can start right away for a semaphore
+// slot in priority order, so that the order does not depend on which
+// goroutine gets scheduled first
+func (m *Manager) reserveSlots(pipelines []ETLRunner, states map[string]*pipelineState) {
+	var ready []ETLRunner
+	for _, p := range pipelines {
+		settings := m.settings(p.Name())
+		if len(settings.readiness) > 0 {
+			continue
+		}
+		blocked := false
+		for _, dep := range settings.deps {
+			if _, ok := states[dep]; ok {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			ready = append(ready, p)
+		}
+	}
+
+	priorities := make(map[string]int, len(ready))
+	for _, p := range ready {
+		priorities[p.Name()] = m.settings(p.Name()).priority
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		return priorities[ready[i].Name()] > priorities[ready[j].Name()]
+	})
+	for _, p := range ready {
+		states[p.Name()].slot = m.sem.enqueue(priorities[p.Name()], m.order(p.Name()))
+	}
+}
+
+// execute waits for the pipeline's readiness checks, then runs it within a
+// semaphore slot
+// slot is a slot reserved by reserveSlots, or nil to queue for one here.
+func (m *Manager) execute(ctx context.Context, p ETLRunner, slot *waiter) (err error) {
+	done := m.startActive(p.Name())
+	defer done()
+	settings := m.settings(p.Name())
+
+	// Wait for external readiness gates
+	if err := m.waitReady(ctx, p.Name(), settings.readiness); err != nil {
+		return timeoutError(ctx, p.Name(), err)
+	}
+
+	// Acquire semaphore slot, highest priority first
+	if slot == nil {
+		slot = m.sem.enqueue(settings.priority, m.order(p.Name()))
+	}
+	if err := slot.wait(ctx); err != nil {
+		return timeoutError(ctx, p.Name(), fmt.Errorf("pipeline %s not started: %w", p.Name(), err))
+	}
+	defer m.sem.release()
+
+	// Claim the run when coordinating with other instances
+	if m.claims != nil {
+		lease, err := m.claimRun(ctx, p.Name())
 		if err != nil {
-			return err
+			return timeoutError(ctx, p.Name(), err)
+		}
+		if lease == nil {
+			return nil // Executed by another instance
 		}
+
+		var release func(error)
+		ctx, release = m.holdLease(ctx, p.Name(), lease)
+		defer func() { release(err) }()
+	}
+
+	if timeout := settings.timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrPipelineTimeout)
+		defer cancel()
 	}
 
+	// Run pipeline, retrying according to its policy
+	run := func(attempt int) error {
+		m.updateStatus(p.Name(), func(s *PipelineStatus) {
+			s.State = StateRunning
+			s.Attempt = attempt
+			if attempt == 1 {
+				s.StartedAt = time.Now()
+			}
+		})
+		m.emit(Event{Type: PipelineStarted, Pipeline: p.Name(), Attempt: attempt})
+
+		ctx, stop := m.watchStall(ctx, p, attempt)
+		defer stop()
+		return stallError(ctx, p.Run(ctx, m.bucketConfig))
+	}
+	onRetry := func(attempt int, err error) {
+		m.updateStatus(p.Name(), func(s *PipelineStatus) {
+			s.State = StateRetrying
+			s.LastError = err
+		})
+		m.emit(Event{Type: PipelineRetrying, Pipeline: p.Name(), Attempt: attempt, Err: err})
+	}
+	if err := settings.retry.runWithRetry(ctx, run, onRetry); err != nil {
+		return timeoutError(ctx, p.Name(), fmt.Errorf("pipeline %s failed: %w", p.Name(), err))
+	}
 	return nil
 }
 
+// pipelineState tracks the outcome of one pipeline during RunAll
+// err must only be read after done is closed
+type pipelineState struct {
+	done chan struct{}
+	err  error
+	slot *waiter // Semaphore slot reserved before launch, if any
+}
+
 // pipelineAdapter adapts ETL[E,T] to ETLRunner interface
 type pipelineAdapter[E, T any] struct {
-	etl  *ETL[E, T]
-	name string
+	etl          *ETL[E, T]
+	name         string
+	bucketConfig *bucket.Config // Overrides the manager's config when set
 }
 
 func (a *pipelineAdapter[E, T]) Name() string {
 	return a.name
 }
 
+func (a *pipelineAdapter[E, T]) Progress() Progress {
+	return a.etl.Progress()
+}
+
+func (a *pipelineAdapter[E, T]) HealthCheck(ctx context.Context) error {
+	if checker, ok := As[HealthChecker](a.etl.processor); ok {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
+}
+
 func (a *pipelineAdapter[E, T]) Run(ctx context.Context, cfg *bucket.Config) error {
+	if a.bucketConfig != nil {
+		cfg = a.bucketConfig
+	}
+	ctx = ensureRunID(ctx)
+
 	// Run pre-process
 	if err := a.etl.PreProcess(ctx); err != nil {
 		return fmt.Errorf("pre-process failed: %w", err)
@@ -0,0 +1,121 @@
+package etl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+)
+
+// FromSlice emits every item of items on a Payload channel, closing it when
+// all items are sent or ctx is cancelled
+func FromSlice[E any](ctx context.Context, items []E) <-chan Payload[E] {
+	ch := make(chan Payload[E], 100)
+
+	go func() {
+		defer close(ch)
+
+		for _, item := range items {
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- Payload[E]{Data: item}:
+			}
+		}
+	}()
+
+	return ch
+}
+
+// SliceSource extracts items from an in-memory slice
+type SliceSource[E any] struct {
+	items []E
+}
+
+// NewSliceSource creates a source that extracts the given items
+func NewSliceSource[E any](items []E) *SliceSource[E] {
+	return &SliceSource[E]{items: items}
+}
+
+// Extract emits the source items
+func (s *SliceSource[E]) Extract(ctx context.Context) (<-chan Payload[E], error) {
+	return FromSlice(ctx, s.items), nil
+}
+
+// SliceSink collects loaded items in memory
+// Load is safe to call from multiple bucket workers
+type SliceSink[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+// NewSliceSink creates an empty in-memory sink
+func NewSliceSink[T any]() *SliceSink[T] {
+	return &SliceSink[T]{}
+}
+
+// Load appends a batch to the sink
+func (s *SliceSink[T]) Load(ctx context.Context, data []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items = append(s.items, data...)
+	return nil
+}
+
+// Items returns a copy of everything loaded so far
+// Batches are appended in completion order, not extraction order
+func (s *SliceSink[T]) Items() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make([]T, len(s.items))
+	copy(items, s.items)
+	return items
+}
+
+// MemoryProcessor implements ETLProcessor over a slice source, a transform
+// function and a slice sink, for in-memory data processing
+type MemoryProcessor[E, T any] struct {
+	*SliceSource[E]
+	*SliceSink[T]
+	transform func(ctx context.Context, e E) T
+}
+
+// NewMemoryProcessor creates an in-memory processor
+func NewMemoryProcessor[E, T any](items []E, transform func(ctx context.Context, e E) T) *MemoryProcessor[E, T] {
+	return &MemoryProcessor[E, T]{
+		SliceSource: NewSliceSource(items),
+		SliceSink:   NewSliceSink[T](),
+		transform:   transform,
+	}
+}
+
+// Transform applies the transform function
+func (p *MemoryProcessor[E, T]) Transform(ctx context.Context, e E) T {
+	return p.transform(ctx, e)
+}
+
+// PreProcess is a no-op
+func (p *MemoryProcessor[E, T]) PreProcess(ctx context.Context) error {
+	return nil
+}
+
+// PostProcess is a no-op
+func (p *MemoryProcessor[E, T]) PostProcess(ctx context.Context) error {
+	return nil
+}
+
+// Collect runs items through transform using the bucket batching and worker
+// machinery and returns the transformed results
+// Results are not guaranteed to be in the order of items when more than one
+// bucket worker is configured
+func Collect[E, T any](ctx context.Context, items []E, transform func(ctx context.Context, e E) T, cfg *bucket.Config) ([]T, error) {
+	processor := NewMemoryProcessor(items, transform)
+
+	if err := NewETL[E, T](processor).Run(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	return processor.Items(), nil
+}
@@ -0,0 +1,126 @@
+package etl
+
+import (
+	"time"
+)
+
+// PipelineMetrics summarizes one pipeline in a run
+type PipelineMetrics struct {
+	Name      string
+	State     PipelineState
+	Attempts  int
+	Errors    int           // Failed attempts, including a final failure
+	Duration  time.Duration // From the first attempt to completion, 0 if never started
+	Extracted int64         // Counters of the last attempt
+	Loaded    int64
+	Batches   int64
+	Rejected  int64
+	Dropped   int64
+	Spilled   int64
+	Stages    StageTimings // Of the last attempt
+}
+
+// RunMetrics summarizes a RunAll or Run call across its pipelines
+type RunMetrics struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+	Pipelines  []PipelineMetrics
+
+	Succeeded int
+	Failed    int // Includes timed out and skipped pipelines
+	Errors    int
+	Extracted int64
+	Loaded    int64
+	Batches   int64
+	Rejected  int64
+	Dropped   int64
+	Spilled   int64
+	Stages    StageTimings // Summed across pipelines
+}
+
+// MetricsReporter receives the summary of every completed run, e.g. to
+// emit it to a metrics backend
+type MetricsReporter interface {
+	ReportRun(metrics RunMetrics)
+}
+
+// MetricsReporterFunc adapts a function to the MetricsReporter interface
+type MetricsReporterFunc func(metrics RunMetrics)
+
+// ReportRun calls f
+func (f MetricsReporterFunc) ReportRun(metrics RunMetrics) {
+	f(metrics)
+}
+
+// SetMetricsReporter reports the metrics of every run to r once it returns
+func (m *Manager) SetMetricsReporter(r MetricsReporter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metricsReporter = r
+}
+
+// Metrics returns the metrics of the last completed run
+func (m *Manager) Metrics() RunMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lastMetrics
+}
+
+// recordMetrics aggregates the status of pipelines after a run, stores the
+// result for Metrics and hands it to the reporter
+func (m *Manager) recordMetrics(pipelines []ETLRunner, startedAt time.Time) {
+	metrics := RunMetrics{
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Pipelines:  make([]PipelineMetrics, 0, len(pipelines)),
+	}
+	metrics.Duration = metrics.FinishedAt.Sub(startedAt)
+
+	m.mu.Lock()
+	for _, p := range pipelines {
+		pm := PipelineMetrics{Name: p.Name()}
+		if s, ok := m.status[p.Name()]; ok {
+			pm.State = s.State
+			pm.Attempts = s.Attempt
+			if !s.StartedAt.IsZero() {
+				pm.Duration = s.FinishedAt.Sub(s.StartedAt)
+			}
+			pm.Errors = s.Attempt - 1
+			if s.State != StateSucceeded {
+				pm.Errors++
+			}
+		}
+		if reporter, ok := p.(ProgressReporter); ok {
+			progress := reporter.Progress()
+			pm.Extracted, pm.Loaded, pm.Batches = progress.Extracted, progress.Loaded, progress.Batches
+			pm.Rejected = progress.Rejected
+			pm.Dropped, pm.Spilled = progress.Dropped, progress.Spilled
+			pm.Stages = progress.Stages
+		}
+
+		if pm.State == StateSucceeded {
+			metrics.Succeeded++
+		} else {
+			metrics.Failed++
+		}
+		metrics.Errors += pm.Errors
+		metrics.Extracted += pm.Extracted
+		metrics.Loaded += pm.Loaded
+		metrics.Batches += pm.Batches
+		metrics.Rejected += pm.Rejected
+		metrics.Dropped += pm.Dropped
+		metrics.Spilled += pm.Spilled
+		metrics.Stages = metrics.Stages.add(pm.Stages)
+		metrics.Pipelines = append(metrics.Pipelines, pm)
+	}
+	m.lastMetrics = metrics
+	reporter := m.metricsReporter
+	m.mu.Unlock()
+
+	if reporter != nil {
+		reporter.ReportRun(metrics)
+	}
+}
@@ -0,0 +1,125 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+)
+
+// RecordPanicError reports a record whose Transform panicked
+type RecordPanicError struct {
+	Record any    // The extracted record
+	Value  any    // Value passed to panic
+	Stack  []byte // Stack trace of the panicking goroutine
+}
+
+func (e *RecordPanicError) Error() string {
+	return fmt.Sprintf("transform panicked: %v", e.Value)
+}
+
+// PanicPolicy decides what happens to a record whose Transform panicked,
+// so that one malformed record does not fail its batch
+type PanicPolicy int
+
+const (
+	// PanicDeadLetter dead-letters the record alone, with its
+	// *RecordPanicError, when the pipeline has a dead letter store, a
+	// DeadLetterHandler or an ErrorBudget, and fails the batch otherwise
+	PanicDeadLetter PanicPolicy = iota
+
+	// PanicSkip logs the error and leaves the record out of the load,
+	// rejected under the rule "transform_panic" (see RejectRecords)
+	PanicSkip
+
+	// PanicFail fails the batch with the *RecordPanicError
+	PanicFail
+)
+
+// SetPanicPolicy sets what happens to records whose Transform panicked;
+// defaults to PanicDeadLetter
+func (e *ETL[E, T]) SetPanicPolicy(policy PanicPolicy) {
+	e.panicPolicy = policy
+}
+
+// WithPanicPolicy sets what happens to records of the pipeline whose
+// Transform panicked; see ETL.SetPanicPolicy
+func WithPanicPolicy(policy PanicPolicy) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.panicPolicy = &policy
+	}
+}
+
+// transform transforms item, converting a panic into a *RecordPanicError
+func (e *ETL[E, T]) transform(ctx context.Context, item E) (t T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &RecordPanicError{Record: item, Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return e.processor.Transform(ctx, item), nil
+}
+
+// isolate applies the panic policy to a record whose Transform panicked,
+// dead-lettering it with deadLetter if set, which also settles it, and
+// returns whether it did and the error failing its batch, if any
+func (e *ETL[E, T]) isolate(ctx context.Context, item E, err error, deadLetter func(context.Context, []E, error) error) (bool, error) {
+	switch {
+	case e.panicPolicy == PanicSkip:
+		LoggerFromContext(ctx).Error("Skipped record whose transform panicked", "error", err)
+		RejectRecords(ctx, 1, "transform_panic")
+		return false, nil
+	case e.panicPolicy == PanicDeadLetter && deadLetter != nil:
+		LoggerFromContext(ctx).Error("Dead-lettering record whose transform panicked", "error", err)
+		return true, deadLetter(ctx, []E{item}, err)
+	}
+	return false, err
+}
+
+// transformedBatch is the transform of a batch so far, kept across the
+// retries of the batch so that its records are transformed, and those whose
+// Transform panicked isolated, only once
+type transformedBatch[T any] struct {
+	next        int // Index of the next record to transform
+	transformed []T
+	isolated    map[int]bool // Dead-lettered on their own, so already settled
+}
+
+// transformedBatches holds the transforms of the batches of a run by batch
+// ID until their processing succeeds; those of batches that failed for good
+// go with the run
+type transformedBatches[T any] struct {
+	mu      sync.Mutex
+	batches map[int64]*transformedBatch[T]
+}
+
+// get returns the transform of the batch processed under ctx, new on its
+// first attempt
+func (b *transformedBatches[T]) get(ctx context.Context, size int) *transformedBatch[T] {
+	id, _ := bucket.BatchID(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	batch, ok := b.batches[id]
+	if !ok {
+		if b.batches == nil {
+			b.batches = make(map[int64]*transformedBatch[T])
+		}
+		batch = &transformedBatch[T]{transformed: make([]T, 0, size)}
+		b.batches[id] = batch
+	}
+	return batch
+}
+
+// done forgets the transform of the batch processed under ctx
+func (b *transformedBatches[T]) done(ctx context.Context) {
+	id, _ := bucket.BatchID(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.batches, id)
+}
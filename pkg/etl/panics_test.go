@@ -0,0 +1,55 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+)
+
+func TestIsolateOnceAcrossLoadRetries(t *testing.T) {
+	var transforms, loads int
+	p := &testProcessor{
+		items: []int{1, 2, 3, 4},
+		transform: func(item int) int {
+			transforms++
+			if item == 2 {
+				panic("malformed record")
+			}
+			return item
+		},
+		loadFn: func(context.Context, []int) error {
+			if loads++; loads < 3 {
+				return errors.New("sink unavailable")
+			}
+			return nil
+		},
+	}
+
+	e := NewETL[int, int](p)
+	err := e.Run(context.Background(), &bucket.Config{
+		BatchSize:    4,
+		Timeout:      time.Second,
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(p.deadLettered, []int{2}) {
+		t.Errorf("dead-lettered %v, want the panicking record once", p.deadLettered)
+	}
+	if got, want := p.loadedItems(), []int{1, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+	if transforms != 4 {
+		t.Errorf("transformed %d times across 3 load attempts, want 4", transforms)
+	}
+	if got := e.Progress().Transformed; got != 3 {
+		t.Errorf("Progress().Transformed = %d, want 3", got)
+	}
+}
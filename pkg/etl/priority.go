@@ -0,0 +1,145 @@
+package etl
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// SetPriority sets the priority of the named pipeline
+// When more pipelines are ready than Config.WorkerNum allows, free slots go
+// to higher priorities first and then to earlier registered pipelines.
+// The default priority is 0; negative values run after everything else.
+func (m *Manager) SetPriority(pipeline string, priority int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.priority[pipeline] = priority
+}
+
+// WithPriority sets the pipeline's priority
+// See Manager.SetPriority
+func WithPriority(priority int) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.priority = &priority
+	}
+}
+
+// order returns the registration index of the named pipeline
+func (m *Manager) order(pipeline string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := m.indexOf(pipeline); i >= 0 {
+		return i
+	}
+	return len(m.pipelines)
+}
+
+// semaphore limits concurrent pipelines, handing free slots to waiters by
+// priority rather than arrival order
+type semaphore struct {
+	mu      sync.Mutex
+	free    int
+	waiters waiterQueue
+}
+
+// waiter is a pipeline waiting for a slot
+type waiter struct {
+	sem      *semaphore
+	priority int
+	order    int
+	ready    chan struct{} // Closed when the slot is granted
+	index    int           // Position in the queue, -1 once granted
+}
+
+// newSemaphore creates a semaphore with n slots
+func newSemaphore(n int) *semaphore {
+	return &semaphore{free: n}
+}
+
+// enqueue registers a waiter for a slot, granting it immediately if one is
+// free
+func (s *semaphore) enqueue(priority, order int) *waiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := &waiter{sem: s, priority: priority, order: order, ready: make(chan struct{})}
+	if s.free > 0 {
+		s.free--
+		w.index = -1
+		close(w.ready)
+		return w
+	}
+
+	heap.Push(&s.waiters, w)
+	return w
+}
+
+// wait blocks until the waiter is granted its slot or ctx is done
+func (w *waiter) wait(ctx context.Context) error {
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s := w.sem
+	s.mu.Lock()
+	if w.index < 0 {
+		// Granted concurrently: pass the slot on
+		s.mu.Unlock()
+		s.release()
+	} else {
+		heap.Remove(&s.waiters, w.index)
+		s.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+// release returns a slot, granting it to the highest priority waiter
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiters.Len() == 0 {
+		s.free++
+		return
+	}
+
+	w := heap.Pop(&s.waiters).(*waiter)
+	close(w.ready)
+}
+
+// waiterQueue is a heap of waiters ordered by priority, then registration
+type waiterQueue []*waiter
+
+func (q waiterQueue) Len() int { return len(q) }
+
+func (q waiterQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].order < q[j].order
+}
+
+func (q waiterQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waiterQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waiterQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
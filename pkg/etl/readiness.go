@@ -0,0 +1,163 @@
+package etl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ReadinessCheck gates a pipeline on an external condition, such as
+// upstream data for today being present
+// Ready returns false while the pipeline should keep waiting; an error is
+// treated as not ready and retried on the next poll.
+type ReadinessCheck interface {
+	Name() string
+	Ready(ctx context.Context) (bool, error)
+}
+
+// ReadinessFunc adapts a function to the ReadinessCheck interface
+type ReadinessFunc struct {
+	CheckName string
+	Fn        func(ctx context.Context) (bool, error)
+}
+
+// Name returns the check name
+func (f ReadinessFunc) Name() string {
+	return f.CheckName
+}
+
+// Ready calls the check function
+func (f ReadinessFunc) Ready(ctx context.Context) (bool, error) {
+	return f.Fn(ctx)
+}
+
+// HTTPCheck is ready when a GET to URL returns a 2xx status
+func HTTPCheck(url string) ReadinessCheck {
+	return ReadinessFunc{
+		CheckName: "http " + url,
+		Fn: func(ctx context.Context) (bool, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return false, err
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return false, err
+			}
+			defer resp.Body.Close()
+
+			return resp.StatusCode >= 200 && resp.StatusCode < 300, nil
+		},
+	}
+}
+
+// SQLCheck is ready when query returns a truthy first column in its first
+// row: true, a non-zero number, or a non-empty string other than "f"/"false"
+// Example: SELECT EXISTS (SELECT 1 FROM batches WHERE day = CURRENT_DATE)
+func SQLCheck(db *sql.DB, query string, args ...any) ReadinessCheck {
+	return ReadinessFunc{
+		CheckName: "sql " + query,
+		Fn: func(ctx context.Context) (bool, error) {
+			var value any
+			err := db.QueryRowContext(ctx, query, args...).Scan(&value)
+			if err == sql.ErrNoRows {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+
+			return truthy(value), nil
+		},
+	}
+}
+
+// truthy interprets a scanned SQL value as a boolean
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case []byte:
+		return truthy(string(v))
+	case string:
+		s := strings.ToLower(strings.TrimSpace(v))
+		return s != "" && s != "0" && s != "f" && s != "false"
+	default:
+		return true
+	}
+}
+
+// AddReadinessChecks gates the named pipeline on checks
+// RunAll polls the checks after the pipeline's dependencies succeed and
+// starts it only once all of them report ready.
+func (m *Manager) AddReadinessChecks(pipeline string, checks ...ReadinessCheck) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.readiness[pipeline] = append(m.readiness[pipeline], checks...)
+}
+
+// WithReadinessChecks gates the pipeline on external readiness checks
+// See Manager.AddReadinessChecks
+func WithReadinessChecks(checks ...ReadinessCheck) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.readiness = append(o.readiness, checks...)
+	}
+}
+
+// waitReady polls checks until all are ready, the readiness timeout
+// elapses or ctx is cancelled
+func (m *Manager) waitReady(ctx context.Context, pipeline string, checks []ReadinessCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+
+	if m.cfg.ReadinessTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.cfg.ReadinessTimeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(m.cfg.ReadinessInterval)
+	defer ticker.Stop()
+
+	pending := checks
+	for {
+		var (
+			notReady []ReadinessCheck
+			lastErr  error
+		)
+		for _, check := range pending {
+			ready, err := check.Ready(ctx)
+			if err != nil {
+				lastErr = fmt.Errorf("%s: %w", check.Name(), err)
+			}
+			if !ready {
+				notReady = append(notReady, check)
+			}
+		}
+		if len(notReady) == 0 {
+			return nil
+		}
+		pending = notReady
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("pipeline %s not ready: %w (last check error: %v)", pipeline, ctx.Err(), lastErr)
+			}
+			return fmt.Errorf("pipeline %s not ready: %s: %w", pipeline, pending[0].Name(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package etl
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDuplicatePipeline is returned when registering a pipeline under a name
+// that is already taken
+var ErrDuplicatePipeline = errors.New("duplicate pipeline name")
+
+// register adds runner unless its name is already registered
+func (m *Manager) register(runner ETLRunner) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.indexOf(runner.Name()) >= 0 {
+		return fmt.Errorf("pipeline %s: %w", runner.Name(), ErrDuplicatePipeline)
+	}
+	m.pipelines = append(m.pipelines, runner)
+	return nil
+}
+
+// Pipelines returns the registered pipelines in registration order
+func (m *Manager) Pipelines() []ETLRunner {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pipelines := make([]ETLRunner, len(m.pipelines))
+	copy(pipelines, m.pipelines)
+	return pipelines
+}
+
+// Lookup returns the pipeline registered under name
+func (m *Manager) Lookup(name string) (ETLRunner, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := m.indexOf(name); i >= 0 {
+		return m.pipelines[i], true
+	}
+	return nil, false
+}
+
+// Replace swaps the pipeline registered under runner's name for runner,
+// keeping its position, dependencies and other settings
+// Runs already in progress keep using the old pipeline.
+func (m *Manager) Replace(runner ETLRunner) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.indexOf(runner.Name())
+	if i < 0 {
+		return fmt.Errorf("pipeline %s is not registered", runner.Name())
+	}
+	m.pipelines[i] = runner
+	return nil
+}
+
+// ReplacePipelineGeneric swaps the pipeline registered under name for a new
+// one built like AddPipelineGeneric
+// Unlike Replace, the pipeline's dependencies, readiness checks, retry
+// policy, timeout and priority are reset to the ones given in opts.
+func ReplacePipelineGeneric[E, T any](m *Manager, processor ETLProcessor[E, T], name string, opts ...PipelineOption) error {
+	o := newPipelineOptions(opts)
+	if err := m.Replace(newPipelineAdapter(m, processor, name, o)); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.clearSettings(name)
+	if len(o.dependsOn) > 0 {
+		m.deps[name] = o.dependsOn
+	}
+	m.mu.Unlock()
+
+	m.applyOptions(name, o)
+	return nil
+}
+
+// pipelineSettings are the manager-level settings of a pipeline
+type pipelineSettings struct {
+	deps      []string
+	readiness []ReadinessCheck
+	retry     RetryPolicy
+	timeout   time.Duration
+	priority  int
+}
+
+// settings returns a snapshot of the settings of the named pipeline
+func (m *Manager) settings(name string) pipelineSettings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := pipelineSettings{
+		deps:      m.deps[name],
+		readiness: m.readiness[name],
+		retry:     m.retry[name],
+		timeout:   m.timeouts[name],
+		priority:  m.priority[name],
+	}
+	if s.timeout <= 0 {
+		s.timeout = m.cfg.PipelineTimeout
+	}
+	return s
+}
+
+// clearSettings forgets the settings of the named pipeline
+// m.mu must be held.
+func (m *Manager) clearSettings(name string) {
+	delete(m.deps, name)
+	delete(m.readiness, name)
+	delete(m.retry, name)
+	delete(m.timeouts, name)
+	delete(m.priority, name)
+}
+
+// pipeline returns the registered pipeline with the given name
+func (m *Manager) pipeline(name string) (ETLRunner, error) {
+	if p, ok := m.Lookup(name); ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("pipeline %s is not registered", name)
+}
+
+// indexOf returns the position of the named pipeline, or -1
+// m.mu must be held.
+func (m *Manager) indexOf(name string) int {
+	for i, p := range m.pipelines {
+		if p.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,50 @@
+package etl
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// rejectContextKey is the context key for the rejection counter of a run
+type rejectContextKey struct{}
+
+// RejectRecords counts records a processor left out of the load for
+// violating rules, e.g. invalid records skipped or quarantined by a
+// validation stage
+// The records show in Progress.Rejected, are not counted as loaded when
+// rejected within Load, and each rule gets a RecordsRejected event of the
+// manager. It does nothing outside the Extract, Transform and Load calls
+// of a run.
+func RejectRecords(ctx context.Context, records int, rules ...string) {
+	if records <= 0 {
+		return
+	}
+	if reject, ok := ctx.Value(rejectContextKey{}).(func(int, []string)); ok {
+		reject(records, rules)
+	}
+}
+
+// rejectContext returns a context counting the rejections of a run
+func (e *ETL[E, T]) rejectContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rejectContextKey{}, func(records int, rules []string) {
+		e.progress.rejected.Add(int64(records))
+		if e.onRejected != nil {
+			for _, rule := range rules {
+				e.onRejected(rule, records)
+			}
+		}
+	})
+}
+
+// countRejections returns a context also adding the records rejected
+// through it to n
+func countRejections(ctx context.Context, n *atomic.Int64) context.Context {
+	parent, ok := ctx.Value(rejectContextKey{}).(func(int, []string))
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, rejectContextKey{}, func(records int, rules []string) {
+		n.Add(int64(records))
+		parent(records, rules)
+	})
+}
@@ -0,0 +1,170 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/dlq"
+)
+
+// SetDeadLetters stores the records of dead-lettered batches in store under
+// name, so they can be replayed with ReplayDLQ
+// Extracted items are stored JSON encoded and must decode back into E. The
+// processor's DeadLetterHandler, if any, is still called.
+func (e *ETL[E, T]) SetDeadLetters(store dlq.Store, name string) {
+	e.deadLetters = store
+	e.deadLetterName = name
+}
+
+// WithDeadLetters overrides the manager's dead letter store for one pipeline
+func WithDeadLetters(store dlq.Store) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.deadLetters = store
+	}
+}
+
+// ReplayResult counts the outcome of a replay
+type ReplayResult struct {
+	Replayed int // Records loaded and marked as replayed
+	Failed   int // Records that failed to decode or transform again, left in place
+}
+
+// storeDeadLetters adds the items of a dead-lettered batch to the store
+func (e *ETL[E, T]) storeDeadLetters(ctx context.Context, items []E, cause error) error {
+	now := time.Now()
+	entries := make([]*dlq.Entry, len(items))
+	for i, item := range items {
+		record, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to encode dead letter: %w", err)
+		}
+		entries[i] = &dlq.Entry{
+			ID:        uuid.NewString(),
+			Pipeline:  e.deadLetterName,
+			Record:    record,
+			Error:     cause.Error(),
+			CreatedAt: now,
+		}
+	}
+	if err := e.deadLetters.Add(ctx, entries); err != nil {
+		return fmt.Errorf("failed to store dead letters: %w", err)
+	}
+	return nil
+}
+
+// ReplayDLQ re-runs the dead-lettered records selected by filter through
+// Transform and Load, in batches of bucketCfg.BatchSize, and marks the
+// loaded ones as replayed
+// PreProcess, PostProcess and the processor's BatchCommitter are not
+// called: the records are not re-extracted from the source. Records whose
+// transform panics again are counted as failed and left for a later replay;
+// a failed load stops the replay.
+func (e *ETL[E, T]) ReplayDLQ(ctx context.Context, bucketCfg *bucket.Config, filter dlq.Filter) (ReplayResult, error) {
+	var result ReplayResult
+	if e.deadLetters == nil {
+		return result, fmt.Errorf("replay requires a dead letter store")
+	}
+	ctx = WithLogger(ctx, e.log())
+
+	entries, err := e.deadLetters.List(ctx, e.deadLetterName, filter)
+	if err != nil {
+		return result, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	batchSize := 100
+	if bucketCfg != nil && bucketCfg.BatchSize > 0 {
+		batchSize = bucketCfg.BatchSize
+	}
+
+	for start := 0; start < len(entries); start += batchSize {
+		batch := entries[start:min(start+batchSize, len(entries))]
+
+		ids := make([]string, 0, len(batch))
+		items := make([]E, 0, len(batch))
+		for _, entry := range batch {
+			var item E
+			if err := json.Unmarshal(entry.Record, &item); err != nil {
+				e.log().Error("Failed to decode dead letter", "id", entry.ID, "error", err)
+				result.Failed++
+				continue
+			}
+			ids = append(ids, entry.ID)
+			items = append(items, item)
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		transformed, err := e.transformAll(ctx, items)
+		if err != nil {
+			e.log().Error("Replayed batch failed again", "records", len(items), "error", err)
+			result.Failed += len(items)
+			continue
+		}
+		if err := e.processor.Load(ctx, transformed); err != nil {
+			return result, fmt.Errorf("failed to load replayed batch: %w", err)
+		}
+		if err := e.deadLetters.MarkReplayed(ctx, e.deadLetterName, ids, time.Now()); err != nil {
+			return result, fmt.Errorf("failed to mark dead letters replayed: %w", err)
+		}
+		result.Replayed += len(items)
+	}
+
+	e.log().Info("Replayed dead letters", "replayed", result.Replayed, "failed", result.Failed)
+	return result, nil
+}
+
+// transformAll transforms items, converting a panic into a
+// *bucket.PanicError
+func (e *ETL[E, T]) transformAll(ctx context.Context, items []E) (out []T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &bucket.PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	out = make([]T, 0, len(items))
+	for _, item := range items {
+		out = append(out, e.processor.Transform(ctx, item))
+	}
+	return out, nil
+}
+
+// dlqReplayer is implemented by pipelines added with AddPipelineGeneric
+type dlqReplayer interface {
+	replayDLQ(ctx context.Context, cfg *bucket.Config, filter dlq.Filter) (ReplayResult, error)
+}
+
+// ReplayDLQ replays the dead-lettered records of a pipeline selected by
+// filter; see ETL.ReplayDLQ
+// The pipeline must have been added with AddPipelineGeneric and have a dead
+// letter store, from Config.DeadLetters or WithDeadLetters.
+func (m *Manager) ReplayDLQ(ctx context.Context, pipeline string, filter dlq.Filter) (ReplayResult, error) {
+	p, err := m.pipeline(pipeline)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+	r, ok := p.(dlqReplayer)
+	if !ok {
+		return ReplayResult{}, fmt.Errorf("pipeline %s does not support replay", pipeline)
+	}
+
+	result, err := r.replayDLQ(ctx, m.bucketConfig, filter)
+	if err != nil {
+		return result, fmt.Errorf("replay of %s: %w", pipeline, err)
+	}
+	return result, nil
+}
+
+func (a *pipelineAdapter[E, T]) replayDLQ(ctx context.Context, cfg *bucket.Config, filter dlq.Filter) (ReplayResult, error) {
+	if a.bucketConfig != nil {
+		cfg = a.bucketConfig
+	}
+	return a.etl.ReplayDLQ(ctx, cfg, filter)
+}
@@ -0,0 +1,253 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// Sequenced is an extracted item numbered by its place in the source's
+// output
+type Sequenced[E any] struct {
+	Offset int64
+	Item   E
+}
+
+// Resumable wraps a processor so that a failed or crashed run is resumed by
+// the next one instead of starting over
+// Offsets of loaded batches are saved to store under name as they land; on
+// restart, Extract skips the records the previous run loaded. The source
+// must emit the same records in the same order on every run, e.g. a query
+// with ORDER BY. Once a run completes, its checkpoint is deleted so the next
+// run starts from the beginning. During a backfill (see
+// checkpoint.WithWindow) nothing is skipped or saved. If processor is a
+// Versioner, a checkpoint written by another version fails the run.
+//
+// The DeadLetterHandler, BatchCommitter, StrategyProvider, WriteVerifier,
+// HealthChecker and LoadKeyer implementations of processor are kept. As it
+// commits batches, the wrapper cannot be combined with a load queue.
+func Resumable[E, T any](processor ETLProcessor[E, T], store checkpoint.Store, name string) ETLProcessor[Sequenced[E], T] {
+	if v, ok := As[Versioner](processor); ok {
+		store = checkpoint.Versioned(store, v.Version(), checkpoint.RefuseOnVersionChange)
+	}
+	return &resumable[E, T]{processor: processor, store: store, name: name}
+}
+
+// resumable implements Resumable
+type resumable[E, T any] struct {
+	processor ETLProcessor[E, T]
+	store     checkpoint.Store
+	name      string
+
+	mu      sync.Mutex
+	active  checkpoint.Store // store, or a read-only view of it during a backfill
+	settled int64            // Records before this offset are loaded
+	done    map[int64]int64  // Loaded offset ranges past settled: start -> end
+}
+
+// offsetPosition is the checkpoint of a resumable run
+type offsetPosition struct {
+	Offset int64 `json:"offset"` // Records loaded from the start of the source
+}
+
+func (r *resumable[E, T]) PreProcess(ctx context.Context) error {
+	return r.processor.PreProcess(ctx)
+}
+
+// Extract reads the saved offset and skips that many records of the
+// wrapped processor's stream
+func (r *resumable[E, T]) Extract(ctx context.Context) (<-chan Payload[Sequenced[E]], error) {
+	active := r.store
+	if _, ok := checkpoint.WindowFromContext(ctx); ok {
+		active = checkpoint.ReadOnly(r.store)
+	}
+
+	var skip int64
+	cp, err := active.Get(ctx, r.name)
+	switch {
+	case errors.Is(err, checkpoint.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	case active != r.store:
+		// A backfill re-reads everything
+	default:
+		var pos offsetPosition
+		if err := json.Unmarshal(cp.Position, &pos); err != nil {
+			return nil, fmt.Errorf("failed to decode checkpoint %s: %w", r.name, err)
+		}
+		skip = pos.Offset
+		LoggerFromContext(ctx).Info("Resuming after loaded records", "offset", skip, "updated_at", cp.UpdatedAt)
+	}
+
+	r.mu.Lock()
+	r.active, r.settled, r.done = active, skip, make(map[int64]int64)
+	r.mu.Unlock()
+
+	in, err := r.processor.Extract(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Payload[Sequenced[E]], cap(in))
+	go func() {
+		defer close(out)
+
+		var offset int64
+		for p := range in {
+			if p.Err == nil && offset < skip {
+				offset++
+				continue
+			}
+
+			seq := Payload[Sequenced[E]]{Data: Sequenced[E]{Offset: offset, Item: p.Data}, Err: p.Err}
+			if p.Err == nil {
+				offset++
+			}
+			select {
+			case out <- seq:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *resumable[E, T]) Transform(ctx context.Context, e Sequenced[E]) T {
+	return r.processor.Transform(ctx, e.Item)
+}
+
+func (r *resumable[E, T]) Load(ctx context.Context, data []T) error {
+	return r.processor.Load(ctx, data)
+}
+
+// Commit commits the batch to the wrapped processor, if it is a
+// BatchCommitter, then saves the offset the run can resume from
+func (r *resumable[E, T]) Commit(ctx context.Context, items []Sequenced[E]) error {
+	if committer, ok := As[BatchCommitter[E]](r.processor); ok {
+		if err := committer.Commit(ctx, unwrap(items)); err != nil {
+			return err
+		}
+	}
+	return r.settle(ctx, items)
+}
+
+// DeadLetter hands the batch to the wrapped processor's DeadLetterHandler;
+// dead-lettered records count as loaded
+// Without a handler, the panic fails the run as it would unwrapped.
+func (r *resumable[E, T]) DeadLetter(ctx context.Context, items []Sequenced[E], err error) error {
+	handler, ok := As[DeadLetterHandler[E]](r.processor)
+	if !ok {
+		return err
+	}
+	if err := handler.DeadLetter(ctx, unwrap(items), err); err != nil {
+		return err
+	}
+	return r.settle(ctx, items)
+}
+
+// Strategy adapts the wrapped processor's strategy, if any
+func (r *resumable[E, T]) Strategy() bucket.Strategy[Sequenced[E]] {
+	provider, ok := As[StrategyProvider[E]](r.processor)
+	if !ok {
+		return nil
+	}
+	strategy := provider.Strategy()
+	if strategy == nil {
+		return nil
+	}
+	return sequencedStrategy[E]{strategy}
+}
+
+type sequencedStrategy[E any] struct {
+	bucket.Strategy[E]
+}
+
+func (s sequencedStrategy[E]) Assign(item Sequenced[E], loads []int64) int {
+	return s.Strategy.Assign(item.Item, loads)
+}
+
+// Unwrap returns the wrapped processor, whose WriteVerifier, HealthChecker
+// and LoadKeyer implementations are kept
+func (r *resumable[E, T]) Unwrap() any {
+	return r.processor
+}
+
+// PostProcess runs the wrapped processor's PostProcess and, as the run is
+// complete, deletes its checkpoint
+func (r *resumable[E, T]) PostProcess(ctx context.Context) error {
+	if err := r.processor.PostProcess(ctx); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	active := r.active
+	r.mu.Unlock()
+	if active == nil {
+		return nil
+	}
+	if err := active.Delete(ctx, r.name); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// settle records the offsets of a loaded batch and saves the new resume
+// offset once the loaded records form a longer prefix
+// A batch holds consecutive offsets unless a Strategy spreads records over
+// workers, so offsets are tracked as ranges.
+func (r *resumable[E, T]) settle(ctx context.Context, items []Sequenced[E]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := 0; i < len(items); {
+		start := items[i].Offset
+		end := start + 1
+		for i++; i < len(items) && items[i].Offset == end; i++ {
+			end++
+		}
+		r.done[start] = end
+	}
+
+	before := r.settled
+	for {
+		end, ok := r.done[r.settled]
+		if !ok {
+			break
+		}
+		delete(r.done, r.settled)
+		r.settled = end
+	}
+	if r.settled == before {
+		return nil
+	}
+
+	data, err := json.Marshal(offsetPosition{Offset: r.settled})
+	if err != nil {
+		return err
+	}
+	err = r.active.Set(ctx, &checkpoint.Checkpoint{
+		Pipeline:  r.name,
+		Position:  data,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// unwrap returns the items of a sequenced batch
+func unwrap[E any](items []Sequenced[E]) []E {
+	out := make([]E, len(items))
+	for i, s := range items {
+		out[i] = s.Item
+	}
+	return out
+}
@@ -0,0 +1,166 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// Resumer can optionally be implemented by an ETLProcessor whose source can
+// continue from where an earlier run stopped
+// With a checkpoint store set (see ETL.SetCheckpoints), Resume is called
+// before Extract with the last committed position, and the position of an
+// item is committed once it and every item extracted before it has been
+// loaded, so a crashed run resumes without skipping records.
+type Resumer[E any] interface {
+	// Resume prepares extraction to continue after position, which is nil
+	// when the pipeline has no checkpoint yet
+	Resume(ctx context.Context, position json.RawMessage) error
+
+	// Position returns where extraction continues after item, e.g. its key
+	// or offset; items with equal positions are settled in extraction order
+	Position(item E) json.RawMessage
+}
+
+// Versioner can optionally be implemented by an ETLProcessor whose
+// checkpoints must not be resumed by a different version of its logic
+// Checkpoints are written with the version, and a run resuming from a
+// checkpoint of another version fails with a checkpoint.VersionMismatchError
+// unless the pipeline resets on version change (see ETL.SetOnVersionChange).
+type Versioner interface {
+	Version() string
+}
+
+// SetCheckpoints commits the progress of a Resumer processor to store
+// under name
+func (e *ETL[E, T]) SetCheckpoints(store checkpoint.Store, name string) {
+	e.checkpoints = store
+	e.checkpointName = name
+}
+
+// SetOnVersionChange decides how a run of a Versioner processor treats a
+// checkpoint written by another version; the default refuses to resume
+func (e *ETL[E, T]) SetOnVersionChange(onChange checkpoint.OnVersionChange) {
+	e.onVersionChange = onChange
+}
+
+// WithOnVersionChange sets how the pipeline treats a checkpoint written by
+// another version of its processor; see ETL.SetOnVersionChange
+func WithOnVersionChange(onChange checkpoint.OnVersionChange) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.onVersionChange = onChange
+	}
+}
+
+// WithCheckpoints overrides the manager's checkpoint store for one pipeline
+func WithCheckpoints(store checkpoint.Store) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.checkpoints = store
+	}
+}
+
+// resumeTracker commits the position of the longest prefix of extracted
+// items that has been loaded
+type resumeTracker[E any] struct {
+	resumer Resumer[E]
+	store   checkpoint.Store
+	name    string
+
+	mu      sync.Mutex
+	base    int64              // Sequence number of pending[0]
+	pending []pendingPosition  // Unsettled items in extraction order
+	index   map[string][]int64 // Position -> sequence numbers of its pending items
+}
+
+type pendingPosition struct {
+	position json.RawMessage
+	loaded   bool
+}
+
+// newResumeTracker reads the stored position and hands it to the resumer
+// During a backfill the store is read but never written.
+func newResumeTracker[E any](ctx context.Context, resumer Resumer[E], store checkpoint.Store, name string) (*resumeTracker[E], error) {
+	if _, ok := checkpoint.WindowFromContext(ctx); ok {
+		store = checkpoint.ReadOnly(store)
+	}
+
+	var position json.RawMessage
+	cp, err := store.Get(ctx, name)
+	switch {
+	case errors.Is(err, checkpoint.ErrNotFound):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	default:
+		position = cp.Position
+		LoggerFromContext(ctx).Info("Resuming from checkpoint", "position", string(position), "updated_at", cp.UpdatedAt)
+	}
+
+	if err := resumer.Resume(ctx, position); err != nil {
+		return nil, fmt.Errorf("failed to resume: %w", err)
+	}
+	return &resumeTracker[E]{resumer: resumer, store: store, name: name, index: make(map[string][]int64)}, nil
+}
+
+// extracted registers an item handed to the bucket
+func (t *resumeTracker[E]) extracted(item E) {
+	position := t.resumer.Position(item)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seq := t.base + int64(len(t.pending))
+	t.pending = append(t.pending, pendingPosition{position: position})
+	t.index[string(position)] = append(t.index[string(position)], seq)
+}
+
+// settled marks items as loaded and commits the position of the last item
+// of the loaded prefix if it moved
+func (t *resumeTracker[E]) settled(ctx context.Context, items []E) error {
+	positions := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		positions[i] = t.resumer.Position(item)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, position := range positions {
+		key := string(position)
+		seqs := t.index[key]
+		if len(seqs) == 0 {
+			continue
+		}
+		t.pending[seqs[0]-t.base].loaded = true
+		if len(seqs) == 1 {
+			delete(t.index, key)
+		} else {
+			t.index[key] = seqs[1:]
+		}
+	}
+
+	var last json.RawMessage
+	for len(t.pending) > 0 && t.pending[0].loaded {
+		last = t.pending[0].position
+		t.pending[0] = pendingPosition{}
+		t.pending = t.pending[1:]
+		t.base++
+	}
+	if last == nil {
+		return nil
+	}
+
+	err := t.store.Set(ctx, &checkpoint.Checkpoint{
+		Pipeline:  t.name,
+		Position:  last,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy retries a failed pipeline before RunAll reports the failure
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first run
+	Backoff     time.Duration // Delay before the first retry (defaults to 1s)
+	MaxBackoff  time.Duration // Upper bound for the doubling delay, 0 for none
+
+	// Retryable reports whether err is worth retrying
+	// Defaults to retrying everything not marked with Permanent.
+	Retryable func(err error) bool
+
+	// BeforeRetry runs before each retry, e.g. to truncate partially
+	// loaded tables. An error stops retrying.
+	BeforeRetry func(ctx context.Context, attempt int, lastErr error) error
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the default RetryPolicy does not retry it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// SetRetryPolicy sets the retry policy of the named pipeline
+func (m *Manager) SetRetryPolicy(pipeline string, policy RetryPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.retry[pipeline] = policy
+}
+
+// WithRetryPolicy retries the pipeline according to policy
+// See Manager.SetRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.retry = &policy
+	}
+}
+
+// runWithRetry calls run until it succeeds or the policy gives up
+// onRetry is called after each failed attempt that will be retried.
+func (p RetryPolicy) runWithRetry(ctx context.Context, run func(attempt int) error, onRetry func(attempt int, err error)) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = func(err error) bool { return !IsPermanent(err) }
+	}
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var (
+		err     error
+		attempt int
+	)
+	for attempt = 1; ; attempt++ {
+		if err = run(attempt); err == nil {
+			return nil
+		}
+		if attempt >= p.MaxAttempts || !retryable(err) {
+			break
+		}
+		onRetry(attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+
+		if p.BeforeRetry != nil {
+			if hookErr := p.BeforeRetry(ctx, attempt+1, err); hookErr != nil {
+				return fmt.Errorf("before retry: %w (last error: %w)", hookErr, err)
+			}
+		}
+	}
+
+	if attempt > 1 {
+		return fmt.Errorf("after %d attempts: %w", attempt, err)
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package etl
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type runIDContextKey struct{}
+
+// WithRunID returns ctx carrying the ID of a pipeline run
+// Runs started by a Manager or ETL.Run get a random one unless ctx already
+// carries one, so a run can be given the ID of an external job.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDContextKey{}, id)
+}
+
+// RunID returns the ID of the pipeline run ctx belongs to
+// Every attempt of a retried pipeline is a run of its own.
+func RunID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(runIDContextKey{}).(string)
+	return id, ok
+}
+
+// ensureRunID returns ctx carrying a new run ID if it carries none
+func ensureRunID(ctx context.Context) context.Context {
+	if _, ok := RunID(ctx); ok {
+		return ctx
+	}
+	return WithRunID(ctx, uuid.NewString())
+}
@@ -0,0 +1,79 @@
+package etl
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// SampleConfig controls the logging of sampled records around Transform,
+// for investigating mapping bugs on production volumes
+type SampleConfig struct {
+	Every int        // Log the first record and every Nth after it (defaults to 1000)
+	Level slog.Level // Of the sample logs (defaults to Info)
+
+	// Redact returns what is logged for a record, called with the extracted
+	// record before Transform and the transformed one after it; nil logs
+	// records as they are. See RedactFields.
+	Redact func(record any) any
+}
+
+// sampler counts transformed records and logs every Nth
+type sampler struct {
+	cfg  SampleConfig
+	seen atomic.Int64
+}
+
+// SetSampling logs every Nth record of the pipeline before and after
+// Transform, under the message "Sampled record"
+func (e *ETL[E, T]) SetSampling(cfg SampleConfig) {
+	if cfg.Every <= 0 {
+		cfg.Every = 1000
+	}
+	e.sampler = &sampler{cfg: cfg}
+}
+
+// WithSampling logs sampled records of the pipeline around Transform
+// See ETL.SetSampling
+func WithSampling(cfg SampleConfig) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.sampling = &cfg
+	}
+}
+
+// next counts a record and reports whether it is sampled, with its number
+func (s *sampler) next() (int64, bool) {
+	n := s.seen.Add(1)
+	return n, (n-1)%int64(s.cfg.Every) == 0
+}
+
+// log logs the nth record and its transformation
+func (s *sampler) log(ctx context.Context, n int64, before, after any) {
+	if s.cfg.Redact != nil {
+		before, after = s.cfg.Redact(before), s.cfg.Redact(after)
+	}
+	LoggerFromContext(ctx).Log(ctx, s.cfg.Level, "Sampled record",
+		"record", n, "before", before, "after", after)
+}
+
+// RedactFields returns a redaction hook that masks the named fields of
+// map[string]any records, leaving other records unchanged
+func RedactFields(fields ...string) func(record any) any {
+	return func(record any) any {
+		m, ok := record.(map[string]any)
+		if !ok {
+			return record
+		}
+
+		redacted := make(map[string]any, len(m))
+		for k, v := range m {
+			redacted[k] = v
+		}
+		for _, f := range fields {
+			if _, ok := redacted[f]; ok {
+				redacted[f] = "[REDACTED]"
+			}
+		}
+		return redacted
+	}
+}
@@ -0,0 +1,228 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// OverlapPolicy decides what happens when a scheduled run is due while the
+// previous run of the same pipeline is still in progress
+type OverlapPolicy int
+
+const (
+	// OverlapSkip drops the due run
+	OverlapSkip OverlapPolicy = iota
+
+	// OverlapQueue starts the due run as soon as the previous one finishes
+	// Several due runs while one is in progress collapse into one.
+	OverlapQueue
+
+	// OverlapCancelPrevious cancels the previous run and starts a new one
+	OverlapCancelPrevious
+)
+
+// schedule is a pipeline registered for recurring runs
+type schedule struct {
+	pipeline string
+	spec     string
+	cron     cron.Schedule
+	overlap  OverlapPolicy
+
+	// Set while the scheduler runs the schedule
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Schedule runs the named pipeline on a standard five-field cron
+// expression (e.g. "*/15 * * * *") once RunScheduler or Serve is started,
+// or right away if one is, skipping runs that would overlap a run still in
+// progress
+func (m *Manager) Schedule(pipeline, spec string) error {
+	return m.ScheduleWithPolicy(pipeline, spec, OverlapSkip)
+}
+
+// ScheduleWithPolicy is Schedule with an explicit overlap policy
+func (m *Manager) ScheduleWithPolicy(pipeline, spec string, overlap OverlapPolicy) error {
+	if _, err := m.pipeline(pipeline); err != nil {
+		return err
+	}
+
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q for pipeline %s: %w", spec, pipeline, err)
+	}
+
+	s := &schedule{
+		pipeline: pipeline,
+		spec:     spec,
+		cron:     sched,
+		overlap:  overlap,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.schedules = append(m.schedules, s)
+	if m.scheduler != nil {
+		m.scheduler.start(m, s)
+	}
+	return nil
+}
+
+// Unschedule removes the schedules of the named pipeline, waiting for a
+// scheduled run in progress to finish
+func (m *Manager) Unschedule(pipeline string) {
+	m.mu.Lock()
+	var removed []*schedule
+	kept := m.schedules[:0]
+	for _, s := range m.schedules {
+		if s.pipeline == pipeline {
+			removed = append(removed, s)
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	clear(m.schedules[len(kept):])
+	m.schedules = kept
+	m.mu.Unlock()
+
+	for _, s := range removed {
+		if s.cancel != nil {
+			s.cancel()
+			<-s.done
+		}
+	}
+}
+
+// RunScheduler runs scheduled pipelines until ctx is cancelled, then waits
+// for in-progress runs to finish
+// Pipelines scheduled while it runs start right away.
+func (m *Manager) RunScheduler(ctx context.Context) error {
+	m.mu.Lock()
+	scheduled := len(m.schedules)
+	m.mu.Unlock()
+
+	if scheduled == 0 {
+		return fmt.Errorf("no pipelines scheduled")
+	}
+	return m.runScheduler(ctx)
+}
+
+// scheduler runs the schedules of a manager
+type scheduler struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+// start runs s until it is unscheduled or the scheduler stops
+// m.mu must be held.
+func (sc *scheduler) start(m *Manager, s *schedule) {
+	ctx, cancel := context.WithCancel(sc.ctx)
+	s.cancel, s.done = cancel, make(chan struct{})
+
+	sc.wg.Add(1)
+	go func() {
+		defer sc.wg.Done()
+		defer close(s.done)
+		m.runSchedule(ctx, s)
+	}()
+}
+
+// runScheduler runs the schedules, including those added later, until ctx
+// is cancelled
+func (m *Manager) runScheduler(ctx context.Context) error {
+	sc := &scheduler{ctx: ctx}
+
+	m.mu.Lock()
+	if m.scheduler != nil {
+		m.mu.Unlock()
+		return fmt.Errorf("scheduler already running")
+	}
+	m.scheduler = sc
+	for _, s := range m.schedules {
+		sc.start(m, s)
+	}
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	m.scheduler = nil
+	m.mu.Unlock()
+	sc.wg.Wait()
+
+	return nil
+}
+
+// runSchedule triggers one schedule at each due time, applying its overlap
+// policy
+func (m *Manager) runSchedule(ctx context.Context, s *schedule) {
+	var (
+		cancelRun context.CancelFunc
+		done      chan struct{} // Closed when the current run ends, nil when idle
+		queued    bool
+		queuedDue time.Time
+	)
+
+	// The due time identifies the run across replicas for distributed claims
+	start := func(due time.Time) {
+		runCtx, cancel := context.WithCancel(WithRunKey(ctx, due.UTC().Format(time.RFC3339)))
+		finished := make(chan struct{})
+		cancelRun, done = cancel, finished
+
+		go func() {
+			defer close(finished)
+			defer cancel()
+
+			if err := m.RunPipeline(runCtx, s.pipeline); err != nil {
+				m.cfg.Logger.Error("Scheduled run failed", "pipeline", s.pipeline, "error", err)
+			}
+		}()
+	}
+
+	next := s.cron.Next(time.Now())
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if done != nil {
+				<-done
+			}
+			return
+
+		case <-done:
+			done = nil
+			if queued {
+				queued = false
+				start(queuedDue)
+			}
+
+		case <-timer.C:
+			due := next
+			next = s.cron.Next(time.Now())
+			timer.Reset(time.Until(next))
+
+			if done == nil {
+				start(due)
+				continue
+			}
+
+			switch s.overlap {
+			case OverlapQueue:
+				queued, queuedDue = true, due
+			case OverlapCancelPrevious:
+				cancelRun()
+				<-done
+				start(due)
+			default:
+				m.cfg.Logger.Warn("Skipping scheduled run: previous run still in progress", "pipeline", s.pipeline)
+			}
+		}
+	}
+}
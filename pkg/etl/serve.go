@@ -0,0 +1,96 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// triggerQueueSize bounds TriggerRun requests waiting for Serve
+const triggerQueueSize = 64
+
+// TriggerRun asks a serving manager to run the named pipeline
+// Triggers for a pipeline that is already running, whether triggered,
+// scheduled or started with RunPipeline, are collapsed into one follow-up
+// run once it is idle. Triggers sent before Serve starts are processed once
+// it does.
+func (m *Manager) TriggerRun(name string) error {
+	if _, err := m.pipeline(name); err != nil {
+		return err
+	}
+
+	select {
+	case m.triggers <- name:
+		return nil
+	default:
+		return fmt.Errorf("trigger queue full, dropping run of %s", name)
+	}
+}
+
+// Serve keeps the manager running as a daemon until ctx is cancelled,
+// executing pipelines on TriggerRun calls and on their cron schedules,
+// including pipelines added and scheduled while it runs
+// On shutdown it waits for in-progress runs to finish.
+func (m *Manager) Serve(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := m.runScheduler(ctx); err != nil {
+			m.cfg.Logger.Error("Scheduler not started", "error", err)
+		}
+	}()
+
+	var (
+		queued = make(map[string]bool) // Follow-up runs waiting for the pipeline to be idle
+		idle   = make(chan string)
+	)
+
+	// trigger runs the pipeline, or queues a follow-up run while it is active
+	trigger := func(name string) {
+		// Counted as active before it starts, so triggers in between collapse
+		done, ok := m.startIdle(name)
+		if !ok {
+			queued[name] = true
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if m.waitIdle(ctx, name) != nil {
+					return
+				}
+				select {
+				case idle <- name:
+				case <-ctx.Done():
+				}
+			}()
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer done()
+			if err := m.RunPipeline(ctx, name); err != nil {
+				m.cfg.Logger.Error("Triggered run failed", "pipeline", name, "error", err)
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+
+		case name := <-m.triggers:
+			if !queued[name] {
+				trigger(name)
+			}
+
+		case name := <-idle:
+			delete(queued, name)
+			trigger(name)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Shard identifies one slice of a source split across Count workers
+type Shard struct {
+	Index int // Zero-based shard number
+	Count int // Total number of shards
+}
+
+// AllShards returns every shard of a source split n ways
+func AllShards(n int) []Shard {
+	shards := make([]Shard, n)
+	for i := range shards {
+		shards[i] = Shard{Index: i, Count: n}
+	}
+	return shards
+}
+
+// ParseShard parses "index/count", e.g. "3/8", as given to one process of
+// a fleet
+func ParseShard(s string) (Shard, error) {
+	index, count, ok := strings.Cut(s, "/")
+	if !ok {
+		return Shard{}, fmt.Errorf("invalid shard %q: want index/count", s)
+	}
+
+	var (
+		shard Shard
+		err   error
+	)
+	if shard.Index, err = strconv.Atoi(index); err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q: %w", s, err)
+	}
+	if shard.Count, err = strconv.Atoi(count); err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q: %w", s, err)
+	}
+	if err := shard.validate(); err != nil {
+		return Shard{}, err
+	}
+	return shard, nil
+}
+
+// String returns the shard as "index/count"
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// validate reports an index outside [0, Count)
+func (s Shard) validate() error {
+	if s.Count <= 0 || s.Index < 0 || s.Index >= s.Count {
+		return fmt.Errorf("invalid shard %d/%d", s.Index, s.Count)
+	}
+	return nil
+}
+
+// Owns reports whether a numeric key such as an auto-increment id belongs
+// to the shard, i.e. key % Count == Index
+func (s Shard) Owns(key uint64) bool {
+	return key%uint64(s.Count) == uint64(s.Index)
+}
+
+// OwnsString reports whether a string key belongs to the shard by its
+// FNV-1a hash, for keys such as ObjectIDs or UUIDs
+func (s Shard) OwnsString(key string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return s.Owns(h.Sum64())
+}
+
+// Range returns the shard's part [lo, hi) of the key range [min, max), for
+// sources that can query contiguous ranges efficiently
+func (s Shard) Range(min, max int64) (lo, hi int64) {
+	span := max - min
+	lo = min + span*int64(s.Index)/int64(s.Count)
+	hi = min + span*int64(s.Index+1)/int64(s.Count)
+	return lo, hi
+}
+
+// ShardName returns the pipeline name used for one shard, so that each
+// shard keeps its own checkpoint and status
+func ShardName(pipeline string, shard Shard) string {
+	return fmt.Sprintf("%s[%d/%d]", pipeline, shard.Index, shard.Count)
+}
+
+// FilterShard passes on only the payloads whose key belongs to shard, for
+// sources that cannot filter server-side
+// Payloads carrying an error are always passed on.
+func FilterShard[E any](ctx context.Context, in <-chan Payload[E], shard Shard, key func(E) string) <-chan Payload[E] {
+	out := make(chan Payload[E], cap(in))
+
+	go func() {
+		defer close(out)
+
+		for p := range in {
+			if p.Err == nil && !shard.OwnsString(key(p.Data)) {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- p:
+			}
+		}
+	}()
+
+	return out
+}
+
+// AddShardedPipeline adds one pipeline per shard in shards, each built by
+// newProcessor for its shard and named with ShardName
+// A single process can run all shards as goroutine groups with
+// AllShards(n), or each process of a fleet can add only its own shard.
+// opts apply to every shard; the shards run concurrently within
+// Config.WorkerNum.
+func AddShardedPipeline[E, T any](m *Manager, newProcessor func(Shard) ETLProcessor[E, T], name string, shards []Shard, opts ...PipelineOption) error {
+	if len(shards) == 0 {
+		return fmt.Errorf("pipeline %s: no shards", name)
+	}
+	for _, shard := range shards {
+		if err := shard.validate(); err != nil {
+			return fmt.Errorf("pipeline %s: %w", name, err)
+		}
+	}
+
+	for _, shard := range shards {
+		if err := AddPipelineGeneric(m, newProcessor(shard), ShardName(name, shard), opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
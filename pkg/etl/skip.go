@@ -0,0 +1,78 @@
+package etl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cuong/go-etl/pkg/dlq"
+)
+
+// RecordError is implemented by Payload errors reporting a single record
+// the source could not read or decode, such as a malformed line, after
+// which it carries on with the next record
+// The ETL skips such records: they are rejected under the rule "extract"
+// (see RejectRecords), kept in the dead letter store if any, as a JSON
+// string of their raw content that ReplayDLQ leaves in place, and counted
+// against the ErrorBudget. Any other Payload error fails the run.
+type RecordError interface {
+	error
+
+	// RawRecord returns the record as read, nil if unknown, and whether the
+	// error is about that record alone
+	RawRecord() (raw []byte, ok bool)
+}
+
+// IsRecordError reports whether err is or wraps a RecordError about a
+// single record
+func IsRecordError(err error) bool {
+	var r RecordError
+	if !errors.As(err, &r) {
+		return false
+	}
+	_, ok := r.RawRecord()
+	return ok
+}
+
+// skipRecord skips the record a RecordError is about, returning an error
+// failing the run if the error budget is exceeded or the record cannot be
+// dead-lettered
+func (e *ETL[E, T]) skipRecord(ctx context.Context, cause error) error {
+	var recErr RecordError
+	errors.As(cause, &recErr)
+	raw, _ := recErr.RawRecord()
+
+	e.log().Warn("Skipped record that could not be extracted", "error", cause)
+	RejectRecords(ctx, 1, "extract")
+	if e.budget != nil {
+		if err := e.budget.fail(1, e.progress.extracted.Load(), false); err != nil {
+			return err
+		}
+	}
+	if e.deadLetters == nil {
+		return nil
+	}
+
+	record, err := json.Marshal(string(raw))
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+	entry := &dlq.Entry{
+		ID:        uuid.NewString(),
+		Pipeline:  e.deadLetterName,
+		Record:    record,
+		Error:     cause.Error(),
+		CreatedAt: time.Now(),
+	}
+	if err := e.deadLetters.Add(ctx, []*dlq.Entry{entry}); err != nil {
+		return fmt.Errorf("failed to store dead letters: %w", err)
+	}
+	if e.onDeadLettered != nil {
+		e.onDeadLettered(1)
+	}
+	return nil
+}
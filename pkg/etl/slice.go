@@ -0,0 +1,54 @@
+package etl
+
+import (
+	"math/rand/v2"
+)
+
+// slice selects the extracted records a run processes, for exercising a
+// pipeline on part of production data
+type slice struct {
+	limit int64   // Records to process, 0 for all
+	rate  float64 // Probability of keeping a record, 0 for all
+}
+
+// active reports whether the slice leaves records out
+func (s slice) active() bool {
+	return s.limit > 0 || (s.rate > 0 && s.rate < 1)
+}
+
+// keep reports whether a record is processed
+func (s slice) keep() bool {
+	return s.rate <= 0 || s.rate >= 1 || rand.Float64() < s.rate
+}
+
+// SetLimit stops extracting once n records were handed to the pipeline,
+// whatever the source; the extraction context is cancelled so the source
+// stops reading. 0 removes the limit.
+// A limited run does not commit checkpoints or batches (see Resumer and
+// BatchCommitter), so the next full run still processes every record.
+func (e *ETL[E, T]) SetLimit(n int64) {
+	e.slice.limit = max(n, 0)
+}
+
+// SetSampleRate processes each extracted record with probability p and
+// leaves the others out, e.g. 0.01 for about 1%; 0 or 1 processes all
+// Like SetLimit, a sampled run does not commit checkpoints or batches.
+func (e *ETL[E, T]) SetSampleRate(p float64) {
+	e.slice.rate = min(max(p, 0), 1)
+}
+
+// WithLimit processes only the first n extracted records of the pipeline
+// See ETL.SetLimit
+func WithLimit(n int64) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.limit = n
+	}
+}
+
+// WithSampleRate processes a random sample of the extracted records of the
+// pipeline; see ETL.SetSampleRate
+func WithSampleRate(p float64) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.sampleRate = p
+	}
+}
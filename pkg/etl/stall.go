@@ -0,0 +1,76 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPipelineStalled is the cause of cancellation when an attempt moves no
+// records for Config.StallThreshold and Config.StallCancel is set
+var ErrPipelineStalled = errors.New("pipeline stalled")
+
+// watchStall watches the progress of an attempt of p until stop is called
+// Progress is sampled four times per Config.StallThreshold; every change
+// updates PipelineStatus.LastProgressAt, and an attempt that moved no
+// records for the threshold emits StallDetected once, then is cancelled
+// with ErrPipelineStalled if Config.StallCancel is set. Pipelines that
+// don't implement ProgressReporter are not watched.
+func (m *Manager) watchStall(ctx context.Context, p ETLRunner, attempt int) (watched context.Context, stop func()) {
+	threshold := m.cfg.StallThreshold
+	reporter, ok := p.(ProgressReporter)
+	if threshold <= 0 || !ok {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(max(threshold/4, 10*time.Millisecond))
+		defer ticker.Stop()
+
+		last, changedAt, reported := reporter.Progress(), time.Now(), false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if progress := reporter.Progress(); progress != last {
+					last, changedAt, reported = progress, now, false
+					m.updateStatus(p.Name(), func(s *PipelineStatus) {
+						s.LastProgressAt = now
+					})
+					continue
+				}
+
+				idle := now.Sub(changedAt)
+				if reported || idle < threshold {
+					continue
+				}
+				reported = true
+				m.cfg.Logger.Warn("Pipeline stalled", "pipeline", p.Name(), "idle", idle.Round(time.Millisecond), "progress", last)
+				m.emit(Event{Type: StallDetected, Pipeline: p.Name(), Attempt: attempt, Duration: idle})
+				if m.cfg.StallCancel {
+					cancel(ErrPipelineStalled)
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(done)
+		cancel(nil)
+	}
+}
+
+// stallError marks err as caused by a stall if the watchdog cancelled ctx
+func stallError(ctx context.Context, err error) error {
+	if err != nil && context.Cause(ctx) == ErrPipelineStalled {
+		return fmt.Errorf("%w: %w", ErrPipelineStalled, err)
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package etl
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PipelineState is the lifecycle state of a pipeline in the manager
+type PipelineState int
+
+const (
+	StatePending   PipelineState = iota // Registered or waiting for dependencies, readiness or a slot
+	StateRunning                        // Currently executing
+	StateRetrying                       // Failed an attempt and waiting to retry
+	StateSucceeded                      // Last run completed successfully
+	StateFailed                         // Last run failed or was skipped
+	StateTimedOut                       // Last run was stopped by its timeout or the run deadline
+)
+
+// String returns the state name
+func (s PipelineState) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateRunning:
+		return "running"
+	case StateRetrying:
+		return "retrying"
+	case StateSucceeded:
+		return "succeeded"
+	case StateFailed:
+		return "failed"
+	case StateTimedOut:
+		return "timed out"
+	default:
+		return "unknown"
+	}
+}
+
+// finished reports whether the state is the outcome of a completed run
+func (s PipelineState) finished() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateTimedOut
+}
+
+// Progress counts the records moved by a pipeline in its current or last run
+type Progress struct {
+	Extracted   int64 // Records received from the source
+	Transformed int64 // Records transformed, including those waiting in a load queue
+	Loaded      int64 // Records written to the destination
+	Batches     int64 // Batches written to the destination
+	Rejected    int64 // Records left out of the load by the processor, see RejectRecords
+	Dropped     int64 // Records discarded by a full queue, see bucket.OverflowDropOldest
+	Spilled     int64 // Records a full queue spilled to disk, see bucket.OverflowSpill
+
+	Stages StageTimings // Where the run spent its time
+}
+
+// ProgressReporter can be implemented by an ETLRunner to expose live
+// progress counters in Manager.Status
+type ProgressReporter interface {
+	Progress() Progress
+}
+
+// PipelineStatus is a snapshot of one pipeline as seen by the manager
+type PipelineStatus struct {
+	Name       string
+	State      PipelineState
+	Attempt    int // Current or last attempt, starting at 1
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Progress   Progress
+	LastError  error
+
+	// LastProgressAt is when the watchdog last saw the pipeline move records,
+	// zero unless Config.StallThreshold is set
+	LastProgressAt time.Time
+}
+
+// Status returns a snapshot of every registered pipeline, in registration
+// order
+func (m *Manager) Status() []PipelineStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]PipelineStatus, 0, len(m.pipelines))
+	for _, p := range m.pipelines {
+		status := PipelineStatus{Name: p.Name()}
+		if s, ok := m.status[p.Name()]; ok {
+			status = *s
+		}
+		if reporter, ok := p.(ProgressReporter); ok {
+			status.Progress = reporter.Progress()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// updateStatus applies fn to the status of the named pipeline
+func (m *Manager) updateStatus(name string, fn func(s *PipelineStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.status[name]
+	if !ok {
+		s = &PipelineStatus{Name: name}
+		m.status[name] = s
+	}
+	fn(s)
+}
+
+// markPending resets a pipeline's status at the start of a run
+func (m *Manager) markPending(name string) {
+	m.updateStatus(name, func(s *PipelineStatus) {
+		*s = PipelineStatus{Name: name, State: StatePending}
+	})
+}
+
+// markFinished records the outcome of a pipeline run
+func (m *Manager) markFinished(name string, err error) {
+	var (
+		attempt int
+		took    time.Duration
+	)
+	m.updateStatus(name, func(s *PipelineStatus) {
+		s.FinishedAt = time.Now()
+		if !s.StartedAt.IsZero() {
+			took = s.FinishedAt.Sub(s.StartedAt)
+		}
+		s.LastError = err
+		s.State = StateSucceeded
+		if IsTimeout(err) {
+			s.State = StateTimedOut
+		} else if err != nil {
+			s.State = StateFailed
+		}
+		attempt = s.Attempt
+	})
+
+	if err != nil {
+		m.emit(Event{Type: PipelineFailed, Pipeline: name, Attempt: attempt, Duration: took, Err: err})
+	} else {
+		m.emit(Event{Type: PipelineFinished, Pipeline: name, Attempt: attempt, Duration: took})
+	}
+}
+
+// progressCounters are the atomic counters behind Progress
+type progressCounters struct {
+	extracted   atomic.Int64
+	transformed atomic.Int64
+	loaded      atomic.Int64
+	batches     atomic.Int64
+	rejected    atomic.Int64
+	dropped     atomic.Int64
+	spilled     atomic.Int64
+	stages      stageCounters
+}
+
+func (c *progressCounters) reset() {
+	c.extracted.Store(0)
+	c.transformed.Store(0)
+	c.loaded.Store(0)
+	c.batches.Store(0)
+	c.rejected.Store(0)
+	c.dropped.Store(0)
+	c.spilled.Store(0)
+	c.stages.reset()
+}
+
+func (c *progressCounters) snapshot() Progress {
+	return Progress{
+		Extracted:   c.extracted.Load(),
+		Transformed: c.transformed.Load(),
+		Loaded:      c.loaded.Load(),
+		Batches:     c.batches.Load(),
+		Rejected:    c.rejected.Load(),
+		Dropped:     c.dropped.Load(),
+		Spilled:     c.spilled.Load(),
+		Stages:      c.stages.snapshot(),
+	}
+}
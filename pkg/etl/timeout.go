@@ -0,0 +1,72 @@
+package etl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrPipelineTimeout is the cause of cancellation when a pipeline runs
+	// longer than its timeout
+	ErrPipelineTimeout = errors.New("pipeline timeout exceeded")
+
+	// ErrRunDeadlineExceeded is the cause of cancellation when RunAll passes
+	// Config.RunDeadline
+	ErrRunDeadlineExceeded = errors.New("run deadline exceeded")
+)
+
+// TimeoutError reports a pipeline stopped by its timeout or the run deadline,
+// as opposed to failing on its own
+type TimeoutError struct {
+	Pipeline string
+	Cause    error // ErrPipelineTimeout or ErrRunDeadlineExceeded
+	Err      error // Error returned by the pipeline after cancellation
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%v (%v)", e.Err, e.Cause)
+}
+
+func (e *TimeoutError) Unwrap() []error {
+	return []error{e.Cause, e.Err}
+}
+
+// IsTimeout reports whether err is or wraps a TimeoutError
+func IsTimeout(err error) bool {
+	var t *TimeoutError
+	return errors.As(err, &t)
+}
+
+// SetTimeout limits how long the named pipeline may run, including retries
+// but not time spent waiting for dependencies, readiness or a slot
+// It overrides Config.PipelineTimeout; 0 falls back to it.
+func (m *Manager) SetTimeout(pipeline string, timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.timeouts[pipeline] = timeout
+}
+
+// WithTimeout limits how long the pipeline may run
+// See Manager.SetTimeout
+func WithTimeout(timeout time.Duration) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.timeout = timeout
+	}
+}
+
+// timeoutError wraps err in a TimeoutError if ctx was cancelled by a
+// pipeline timeout or the run deadline
+func timeoutError(ctx context.Context, pipeline string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	cause := context.Cause(ctx)
+	if cause == ErrPipelineTimeout || cause == ErrRunDeadlineExceeded {
+		return &TimeoutError{Pipeline: pipeline, Cause: cause, Err: err}
+	}
+	return err
+}
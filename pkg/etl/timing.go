@@ -0,0 +1,67 @@
+package etl
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// StageTimings breaks down where a pipeline spent its time
+// Transform and Load are summed across the bucket workers, so with several
+// workers they can exceed the run time.
+type StageTimings struct {
+	ExtractWait time.Duration // Waiting for records from the source
+	QueueWait   time.Duration // Waiting for room in the full bucket queue
+	Transform   time.Duration // Transforming batches
+	Load        time.Duration // Loading batches into the destination
+}
+
+// Bottleneck names the stage that limited throughput: "extract" when
+// extraction mostly waited for the source, otherwise the slower of
+// "transform" and "load"; empty before any batch completed
+func (t StageTimings) Bottleneck() string {
+	switch {
+	case t.Transform == 0 && t.Load == 0:
+		return ""
+	case t.ExtractWait >= t.QueueWait:
+		return "extract"
+	case t.Transform > t.Load:
+		return "transform"
+	default:
+		return "load"
+	}
+}
+
+// add sums two breakdowns
+func (t StageTimings) add(o StageTimings) StageTimings {
+	return StageTimings{
+		ExtractWait: t.ExtractWait + o.ExtractWait,
+		QueueWait:   t.QueueWait + o.QueueWait,
+		Transform:   t.Transform + o.Transform,
+		Load:        t.Load + o.Load,
+	}
+}
+
+// stageCounters are the atomic counters behind StageTimings, in
+// nanoseconds
+type stageCounters struct {
+	extractWait atomic.Int64
+	queueWait   atomic.Int64
+	transform   atomic.Int64
+	load        atomic.Int64
+}
+
+func (c *stageCounters) reset() {
+	c.extractWait.Store(0)
+	c.queueWait.Store(0)
+	c.transform.Store(0)
+	c.load.Store(0)
+}
+
+func (c *stageCounters) snapshot() StageTimings {
+	return StageTimings{
+		ExtractWait: time.Duration(c.extractWait.Load()),
+		QueueWait:   time.Duration(c.queueWait.Load()),
+		Transform:   time.Duration(c.transform.Load()),
+		Load:        time.Duration(c.load.Load()),
+	}
+}
@@ -0,0 +1,81 @@
+package etl
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+)
+
+// tracerName identifies the spans of the ETL engine
+const tracerName = "github.com/cuong/go-etl/pkg/etl"
+
+// SetTracerProvider traces the runs of the pipeline name with tp; without
+// it, or with a nil tp, the global provider of otel is used
+// A run is traced as an etl.run span with an etl.extract child spanning the
+// whole extraction, and an etl.batch child per batch holding its
+// etl.transform and etl.load spans. Batch spans link to the extract span
+// and, for Resumer processors, carry the positions of their first and last
+// records as etl.offset.first and etl.offset.last.
+func (e *ETL[E, T]) SetTracerProvider(tp trace.TracerProvider, name string) {
+	e.tracerProvider = tp
+	e.traceName = name
+}
+
+// WithTracerProvider overrides the manager's tracer provider for one
+// pipeline; see ETL.SetTracerProvider
+func WithTracerProvider(tp trace.TracerProvider) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.tracerProvider = tp
+	}
+}
+
+// tracer returns the tracer of the pipeline's spans
+func (e *ETL[E, T]) tracer() trace.Tracer {
+	tp := e.tracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// startSpan starts a span of the pipeline, tagged with its name
+func (e *ETL[E, T]) startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if e.traceName != "" {
+		opts = append(opts, trace.WithAttributes(attribute.String("etl.pipeline", e.traceName)))
+	}
+	return e.tracer().Start(ctx, name, opts...)
+}
+
+// startBatchSpan starts the span of a batch of items
+func (e *ETL[E, T]) startBatchSpan(ctx context.Context, items []E, extract trace.SpanContext) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attribute.Int("etl.batch.records", len(items))}
+	if id, ok := bucket.BatchID(ctx); ok {
+		attrs = append(attrs, attribute.Int64("etl.batch.id", id))
+	}
+	if resumer, ok := As[Resumer[E]](e.processor); ok && len(items) > 0 {
+		attrs = append(attrs,
+			attribute.String("etl.offset.first", string(resumer.Position(items[0]))),
+			attribute.String("etl.offset.last", string(resumer.Position(items[len(items)-1]))),
+		)
+	}
+
+	opts := []trace.SpanStartOption{trace.WithAttributes(attrs...)}
+	if extract.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: extract}))
+	}
+	return e.startSpan(ctx, "etl.batch", opts...)
+}
+
+// endSpan ends span, recording err as its status
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
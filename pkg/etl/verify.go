@@ -0,0 +1,237 @@
+package etl
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriteVerifier can optionally be implemented by an ETLProcessor to read
+// loaded records back from the sink for verification
+type WriteVerifier[T any] interface {
+	// VerifyKey identifies a record, e.g. by its primary key
+	VerifyKey(item T) string
+
+	// ReadBack fetches the stored version of the sampled records
+	// Records missing from the result are reported as missing.
+	ReadBack(ctx context.Context, sample []T) ([]T, error)
+}
+
+// VerifyConfig controls write verification sampling
+type VerifyConfig struct {
+	SampleRate     float64 // Fraction of loaded records to read back, 0-1
+	MaxPerBatch    int     // Cap on sampled records per batch, 0 for none
+	FailOnMismatch bool    // Fail the batch instead of only reporting mismatches
+}
+
+// Mismatch describes a sampled record that differs from what was loaded
+type Mismatch struct {
+	Key    string
+	Field  string // Dotted path of the differing field, empty if missing
+	Want   any
+	Got    any
+	Reason string
+}
+
+func (m Mismatch) String() string {
+	if m.Field == "" {
+		return fmt.Sprintf("%s: %s", m.Key, m.Reason)
+	}
+	return fmt.Sprintf("%s.%s: wrote %v, read back %v", m.Key, m.Field, m.Want, m.Got)
+}
+
+// VerifyStats summarizes write verification for a run
+type VerifyStats struct {
+	Sampled    int64
+	Mismatched int64
+	Mismatches []Mismatch // First mismatches found, capped at maxRecordedMismatches
+}
+
+// maxRecordedMismatches bounds the mismatches kept in VerifyStats
+const maxRecordedMismatches = 100
+
+// verifier samples loaded batches and compares them with the sink
+type verifier[T any] struct {
+	cfg      VerifyConfig
+	readBack WriteVerifier[T]
+
+	mu    sync.Mutex
+	stats VerifyStats
+}
+
+// SetVerification reads back a sample of every loaded batch and compares
+// it field by field with the transformed records
+// The processor must implement WriteVerifier, otherwise Run fails.
+func (e *ETL[E, T]) SetVerification(cfg VerifyConfig) {
+	e.verifyCfg = &cfg
+}
+
+// Verification returns the write verification results of the last run
+func (e *ETL[E, T]) Verification() VerifyStats {
+	if e.verifier == nil {
+		return VerifyStats{}
+	}
+
+	e.verifier.mu.Lock()
+	defer e.verifier.mu.Unlock()
+
+	stats := e.verifier.stats
+	stats.Mismatches = append([]Mismatch(nil), stats.Mismatches...)
+	return stats
+}
+
+// WithVerification enables write verification sampling for the pipeline
+// See ETL.SetVerification
+func WithVerification(cfg VerifyConfig) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.verify = &cfg
+	}
+}
+
+// verify reads back a random sample of a loaded batch and reports
+// differences
+func (v *verifier[T]) verify(ctx context.Context, loaded []T) error {
+	sample := v.sample(loaded)
+	if len(sample) == 0 {
+		return nil
+	}
+
+	stored, err := v.readBack.ReadBack(ctx, sample)
+	if err != nil {
+		return fmt.Errorf("failed to read back sample: %w", err)
+	}
+
+	byKey := make(map[string]T, len(stored))
+	for _, item := range stored {
+		byKey[v.readBack.VerifyKey(item)] = item
+	}
+
+	var mismatches []Mismatch
+	for _, want := range sample {
+		key := v.readBack.VerifyKey(want)
+		got, ok := byKey[key]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Key: key, Reason: "missing from sink"})
+			continue
+		}
+		mismatches = append(mismatches, compareFields(key, "", reflect.ValueOf(want), reflect.ValueOf(got))...)
+	}
+
+	v.mu.Lock()
+	v.stats.Sampled += int64(len(sample))
+	v.stats.Mismatched += int64(len(mismatches))
+	for _, m := range mismatches {
+		if len(v.stats.Mismatches) < maxRecordedMismatches {
+			v.stats.Mismatches = append(v.stats.Mismatches, m)
+		}
+	}
+	v.mu.Unlock()
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	logger := LoggerFromContext(ctx)
+	for _, m := range mismatches {
+		logger.Warn("Write verification mismatch", "key", m.Key, "mismatch", m.String())
+	}
+	if v.cfg.FailOnMismatch {
+		return fmt.Errorf("write verification found %d mismatches, first: %s", len(mismatches), mismatches[0])
+	}
+	return nil
+}
+
+// sample picks each record with probability SampleRate; past MaxPerBatch,
+// reservoir sampling keeps any picked record as likely as the first ones
+func (v *verifier[T]) sample(loaded []T) []T {
+	var sample []T
+	picked := 0
+	for _, item := range loaded {
+		if rand.Float64() >= v.cfg.SampleRate {
+			continue
+		}
+		picked++
+		if v.cfg.MaxPerBatch <= 0 || len(sample) < v.cfg.MaxPerBatch {
+			sample = append(sample, item)
+		} else if i := rand.IntN(picked); i < len(sample) {
+			sample[i] = item
+		}
+	}
+	return sample
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compareFields walks exported struct fields and reports differing leaves
+// Invalid values stand for nil interfaces and compare equal to each other.
+func compareFields(key, path string, want, got reflect.Value) []Mismatch {
+	for want.Kind() == reflect.Pointer || want.Kind() == reflect.Interface {
+		if got.Kind() != want.Kind() {
+			return []Mismatch{mismatch(key, path, want, got)}
+		}
+		if want.IsNil() || got.IsNil() {
+			if want.IsNil() != got.IsNil() {
+				return []Mismatch{mismatch(key, path, want, got)}
+			}
+			return nil
+		}
+		want, got = want.Elem(), got.Elem()
+	}
+
+	switch {
+	case !want.IsValid() || !got.IsValid():
+		if want.IsValid() != got.IsValid() {
+			return []Mismatch{mismatch(key, path, want, got)}
+		}
+		return nil
+
+	case want.Type() != got.Type():
+		return []Mismatch{mismatch(key, path, want, got)}
+
+	case want.Type() == timeType:
+		// Compare instants, ignoring location and monotonic readings
+		if !want.Interface().(time.Time).Equal(got.Interface().(time.Time)) {
+			return []Mismatch{mismatch(key, path, want, got)}
+		}
+		return nil
+
+	case want.Kind() == reflect.Struct:
+		var out []Mismatch
+		for i := 0; i < want.NumField(); i++ {
+			field := want.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			out = append(out, compareFields(key, joinPath(path, field.Name), want.Field(i), got.Field(i))...)
+		}
+		return out
+
+	default:
+		if !reflect.DeepEqual(want.Interface(), got.Interface()) {
+			return []Mismatch{mismatch(key, path, want, got)}
+		}
+		return nil
+	}
+}
+
+func mismatch(key, path string, want, got reflect.Value) Mismatch {
+	m := Mismatch{Key: key, Field: path, Reason: "value differs"}
+	if want.IsValid() && want.CanInterface() {
+		m.Want = want.Interface()
+	}
+	if got.IsValid() && got.CanInterface() {
+		m.Got = got.Interface()
+	}
+	if m.Field == "" {
+		m.Field = "(value)"
+	}
+	return m
+}
+
+func joinPath(path, name string) string {
+	return strings.TrimPrefix(path+"."+name, ".")
+}
@@ -0,0 +1,64 @@
+package etl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareFields(t *testing.T) {
+	type inner struct{ N int }
+	type record struct {
+		Value any
+		Ptr   *inner
+	}
+
+	tests := []struct {
+		name      string
+		want, got any
+		fields    []string
+	}{
+		{"equal", record{Value: "a", Ptr: &inner{1}}, record{Value: "a", Ptr: &inner{1}}, nil},
+		{"nested field", record{Ptr: &inner{1}}, record{Ptr: &inner{2}}, []string{"Ptr.N"}},
+		{"nil pointer", record{Ptr: &inner{1}}, record{}, []string{"Ptr"}},
+		{"nil interfaces", record{}, record{}, nil},
+		{"nil interface read back as a value", record{}, record{Value: "a"}, []string{"Value"}},
+		{"value read back as nil interface", record{Value: "a"}, record{}, []string{"Value"}},
+		{"pointer read back as a string", record{Value: &inner{1}}, record{Value: "a"}, []string{"Value"}},
+		{"string read back as a pointer", record{Value: "a"}, record{Value: &inner{1}}, []string{"Value"}},
+		{"different types", record{Value: 1}, record{Value: "1"}, []string{"Value"}},
+		{"nil top-level interface", nil, 1, []string{"(value)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, m := range compareFields("k", "", reflect.ValueOf(tt.want), reflect.ValueOf(tt.got)) {
+				fields = append(fields, m.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("mismatched fields %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestVerifierSampleSpreadsOverBatch(t *testing.T) {
+	v := &verifier[int]{cfg: VerifyConfig{SampleRate: 1, MaxPerBatch: 2}}
+	loaded := make([]int, 10)
+	for i := range loaded {
+		loaded[i] = i
+	}
+
+	seen := make(map[int]bool)
+	for range 1000 {
+		sample := v.sample(loaded)
+		if len(sample) != 2 {
+			t.Fatalf("sampled %d records, want MaxPerBatch 2", len(sample))
+		}
+		for _, item := range sample {
+			seen[item] = true
+		}
+	}
+	if len(seen) != len(loaded) {
+		t.Errorf("sampled only records %v of a batch of %d", seen, len(loaded))
+	}
+}
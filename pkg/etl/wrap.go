@@ -0,0 +1,28 @@
+package etl
+
+// Wrapper is implemented by processors wrapping another processor, such as
+// validation.Wrap or Resumable
+// The optional interfaces of the wrapped processor (DeadLetterHandler,
+// BatchCommitter, Resumer, StrategyProvider, WriteVerifier, HealthChecker,
+// Versioner and LoadKeyer) keep working through the wrapper without it
+// forwarding them; the wrapper's own implementations take precedence.
+type Wrapper interface {
+	Unwrap() any // The wrapped processor
+}
+
+// As returns the first of processor and the processors it wraps (see
+// Wrapper) implementing I, e.g. As[BatchCommitter[E]](processor)
+func As[I any](processor any) (I, bool) {
+	for processor != nil {
+		if i, ok := processor.(I); ok {
+			return i, true
+		}
+		w, ok := processor.(Wrapper)
+		if !ok {
+			break
+		}
+		processor = w.Unwrap()
+	}
+	var zero I
+	return zero, false
+}
@@ -0,0 +1,67 @@
+// Package dbfields maps struct fields to database columns by their `db`
+// tag or snake_cased name
+package dbfields
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Field is an exported struct field and the column it maps to
+type Field struct {
+	Column string
+	Index  []int // Field index path, for reflect.Value.FieldByIndex
+}
+
+// Of returns the column fields of struct type t in declaration order,
+// descending into embedded structs without a tag; fields tagged `db:"-"`
+// are skipped
+func Of(t reflect.Type) []Field {
+	var fields []Field
+	var collect func(t reflect.Type, path []int)
+	collect = func(t reflect.Type, path []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			index := append(append([]int(nil), path...), i)
+			tag, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+			if tag == "-" {
+				continue
+			}
+			if f.Anonymous && f.Type.Kind() == reflect.Struct && tag == "" {
+				collect(f.Type, index)
+				continue
+			}
+
+			name := tag
+			if name == "" {
+				name = SnakeCase(f.Name)
+			}
+			fields = append(fields, Field{Column: name, Index: index})
+		}
+	}
+	collect(t, nil)
+	return fields
+}
+
+// SnakeCase converts a Go field name such as UserID to user_id
+func SnakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if i > 0 && (prevLower || (nextLower && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+// Package lineage attaches the origin of every record to what a pipeline
+// loads: the source system and table or collection it came from, its
+// offset in the source, and the run that loaded it, so consumers of the
+// destination can trace any row back to its origin and run
+//
+// Records get their lineage in Transform, through an Attach hook that sets
+// it on the loaded type, e.g. as extra columns of map records:
+//
+//	p, err := lineage.Wrap(processor, lineage.Config[E, map[string]any]{
+//		Pipeline: "users",
+//		System:   "postgres",
+//		Entity:   "public.users",
+//		Attach:   lineage.Columns[map[string]any]("_lineage_"),
+//	})
+//
+// Run IDs come from etl.RunID; every attempt of a retried pipeline is a
+// run of its own.
+package lineage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Info is the origin of a loaded record
+type Info struct {
+	Pipeline string          `json:"pipeline,omitempty"`
+	System   string          `json:"system"`           // Source system, e.g. "postgres"
+	Entity   string          `json:"entity,omitempty"` // Table, collection, topic or file
+	RunID    string          `json:"run_id"`
+	Offset   json.RawMessage `json:"offset,omitempty"` // Position of the record in the source
+}
+
+// Config configures the lineage of the records of a pipeline
+type Config[E, T any] struct {
+	Pipeline string
+	System   string
+	Entity   string
+
+	// Offset returns the position of an extracted record in the source;
+	// nil uses the processor's etl.Resumer, if any
+	Offset func(E) json.RawMessage
+
+	// Attach sets info on a transformed record and returns it; see Columns
+	Attach func(t T, info Info) T
+}
+
+// Columns returns an Attach hook setting the lineage of map records as the
+// fields prefix+"pipeline", "system", "entity", "run_id" and "offset",
+// the offset as its JSON text; empty values are left out
+func Columns[T ~map[string]any](prefix string) func(T, Info) T {
+	return func(t T, info Info) T {
+		if t == nil {
+			return t
+		}
+		set := func(name, value string) {
+			if value != "" {
+				t[prefix+name] = value
+			}
+		}
+		set("pipeline", info.Pipeline)
+		set("system", info.System)
+		set("entity", info.Entity)
+		set("run_id", info.RunID)
+		set("offset", string(info.Offset))
+		return t
+	}
+}
+
+// ColumnNames returns the names of the fields Columns sets with prefix
+func ColumnNames(prefix string) []string {
+	return []string{prefix + "pipeline", prefix + "system", prefix + "entity", prefix + "run_id", prefix + "offset"}
+}
+
+// Wrap attaches the lineage of cfg to the records processor transforms
+//
+// The optional interfaces of processor, such as BatchCommitter and Resumer,
+// are kept (see etl.Wrapper).
+func Wrap[E, T any](processor etl.ETLProcessor[E, T], cfg Config[E, T]) (etl.ETLProcessor[E, T], error) {
+	if cfg.Attach == nil {
+		return nil, fmt.Errorf("lineage: Attach is required")
+	}
+	if cfg.System == "" {
+		return nil, fmt.Errorf("lineage: System is required")
+	}
+	if cfg.Offset == nil {
+		if resumer, ok := etl.As[etl.Resumer[E]](processor); ok {
+			cfg.Offset = resumer.Position
+		}
+	}
+
+	t := &tracer[E, T]{processor: processor, cfg: cfg}
+	return t, nil
+}
+
+// tracer implements Wrap
+type tracer[E, T any] struct {
+	processor etl.ETLProcessor[E, T]
+	cfg       Config[E, T]
+}
+
+func (t *tracer[E, T]) PreProcess(ctx context.Context) error {
+	return t.processor.PreProcess(ctx)
+}
+
+func (t *tracer[E, T]) Extract(ctx context.Context) (<-chan etl.Payload[E], error) {
+	return t.processor.Extract(ctx)
+}
+
+// Transform transforms e with the wrapped processor and attaches its
+// lineage, read before the transformation as it may change e
+func (t *tracer[E, T]) Transform(ctx context.Context, e E) T {
+	info := Info{Pipeline: t.cfg.Pipeline, System: t.cfg.System, Entity: t.cfg.Entity}
+	info.RunID, _ = etl.RunID(ctx)
+	if t.cfg.Offset != nil {
+		info.Offset = t.cfg.Offset(e)
+	}
+	return t.cfg.Attach(t.processor.Transform(ctx, e), info)
+}
+
+func (t *tracer[E, T]) Load(ctx context.Context, data []T) error {
+	return t.processor.Load(ctx, data)
+}
+
+func (t *tracer[E, T]) PostProcess(ctx context.Context) error {
+	return t.processor.PostProcess(ctx)
+}
+
+// Unwrap returns the wrapped processor, whose optional interfaces are kept
+// (see etl.Wrapper)
+func (t *tracer[E, T]) Unwrap() any {
+	return t.processor
+}
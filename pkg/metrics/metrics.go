@@ -0,0 +1,257 @@
+// Package metrics exports the record counters, batch and run timings and
+// errors of a manager's pipelines to Prometheus
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Stages of the records and errors counters
+const (
+	StageExtract   = "extract"
+	StageTransform = "transform"
+	StageLoad      = "load"
+	StageRun       = "run" // Failed pipeline attempts
+)
+
+// Config configures the exporter
+type Config struct {
+	Namespace string // Prefix of the metric names (defaults to "etl")
+
+	// Registerer the metrics are registered with; defaults to a private
+	// registry served by Handler
+	Registerer prometheus.Registerer
+
+	BatchSizeBuckets []float64 // Records per loaded batch (defaults to 1, 4, ... 65536)
+	LatencyBuckets   []float64 // Batch load latency in seconds (defaults to prometheus.DefBuckets)
+	DurationBuckets  []float64 // Pipeline run time in seconds (defaults to 1s ... ~4.5h)
+}
+
+// Exporter collects the metrics of a manager
+//
+// Exported metrics, prefixed with the namespace:
+//
+//	records_total{pipeline,stage}        records extracted, transformed and loaded
+//	errors_total{pipeline,stage}         dead-lettered batches (load) and failed attempts (run)
+//	queue_depth{pipeline}                records extracted and waiting to be transformed
+//	overflow_total{pipeline,policy}      records full queues dropped (drop-oldest) or spilled (spill)
+//	batch_size{pipeline}                 records per loaded batch
+//	batch_duration_seconds{pipeline}     load latency of batches
+//	pipeline_duration_seconds{pipeline}  run time of completed pipeline runs
+type Exporter struct {
+	manager  *etl.Manager
+	gatherer prometheus.Gatherer
+
+	records    *prometheus.Desc
+	queueDepth *prometheus.Desc
+	overflow   *prometheus.Desc
+	errors     *prometheus.CounterVec
+	batchSize  *prometheus.HistogramVec
+	batchTime  *prometheus.HistogramVec
+	runTime    *prometheus.HistogramVec
+
+	mu       sync.Mutex
+	counters map[string]*counters
+}
+
+// counters accumulates the per-attempt progress of a pipeline across
+// attempts and runs, as etl.Progress restarts at zero with every attempt
+type counters struct {
+	total   etl.Progress // Progress of ended attempts
+	last    etl.Progress // Final progress of the last ended attempt
+	running bool         // An attempt started and has not ended
+}
+
+// Attach registers an exporter of m's metrics with cfg.Registerer
+func Attach(m *etl.Manager, cfg Config) (*Exporter, error) {
+	if cfg.Namespace == "" {
+		cfg.Namespace = "etl"
+	}
+	if len(cfg.BatchSizeBuckets) == 0 {
+		cfg.BatchSizeBuckets = prometheus.ExponentialBuckets(1, 4, 9)
+	}
+	if len(cfg.LatencyBuckets) == 0 {
+		cfg.LatencyBuckets = prometheus.DefBuckets
+	}
+	if len(cfg.DurationBuckets) == 0 {
+		cfg.DurationBuckets = prometheus.ExponentialBuckets(1, 2, 15)
+	}
+
+	x := &Exporter{
+		manager:  m,
+		counters: make(map[string]*counters),
+		records: prometheus.NewDesc(prometheus.BuildFQName(cfg.Namespace, "", "records_total"),
+			"Records extracted, transformed and loaded.", []string{"pipeline", "stage"}, nil),
+		queueDepth: prometheus.NewDesc(prometheus.BuildFQName(cfg.Namespace, "", "queue_depth"),
+			"Records extracted and waiting to be transformed.", []string{"pipeline"}, nil),
+		overflow: prometheus.NewDesc(prometheus.BuildFQName(cfg.Namespace, "", "overflow_total"),
+			"Records full queues dropped or spilled to disk, by overflow policy.", []string{"pipeline", "policy"}, nil),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.Namespace,
+			Name:      "errors_total",
+			Help:      "Dead-lettered batches (stage load) and failed pipeline attempts (stage run).",
+		}, []string{"pipeline", "stage"}),
+		batchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Name:      "batch_size",
+			Help:      "Records per loaded batch.",
+			Buckets:   cfg.BatchSizeBuckets,
+		}, []string{"pipeline"}),
+		batchTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Name:      "batch_duration_seconds",
+			Help:      "Load latency of batches.",
+			Buckets:   cfg.LatencyBuckets,
+		}, []string{"pipeline"}),
+		runTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.Namespace,
+			Name:      "pipeline_duration_seconds",
+			Help:      "Run time of completed pipeline runs, including retries.",
+			Buckets:   cfg.DurationBuckets,
+		}, []string{"pipeline"}),
+	}
+
+	reg := cfg.Registerer
+	if reg == nil {
+		private := prometheus.NewRegistry()
+		reg, x.gatherer = private, private
+	} else if g, ok := reg.(prometheus.Gatherer); ok {
+		x.gatherer = g
+	} else {
+		x.gatherer = prometheus.DefaultGatherer
+	}
+	if err := reg.Register(x); err != nil {
+		return nil, fmt.Errorf("metrics: register: %w", err)
+	}
+
+	m.OnEvent(x.handle)
+	return x, nil
+}
+
+// Handler serves the metrics of the exporter's registry, e.g. on /metrics
+func (x *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(x.gatherer, promhttp.HandlerOpts{})
+}
+
+// handle records the metrics carried by manager events
+func (x *Exporter) handle(e etl.Event) {
+	switch e.Type {
+	case etl.PipelineStarted:
+		x.mu.Lock()
+		x.counter(e.Pipeline).running = true
+		x.mu.Unlock()
+
+	case etl.BatchLoaded:
+		x.batchSize.WithLabelValues(e.Pipeline).Observe(float64(e.Records))
+		x.batchTime.WithLabelValues(e.Pipeline).Observe(e.Duration.Seconds())
+
+	case etl.BatchDeadLettered:
+		x.errors.WithLabelValues(e.Pipeline, StageLoad).Inc()
+
+	case etl.PipelineRetrying:
+		x.errors.WithLabelValues(e.Pipeline, StageRun).Inc()
+		x.endAttempt(e.Pipeline)
+
+	case etl.PipelineFailed, etl.PipelineFinished:
+		if e.Type == etl.PipelineFailed {
+			x.errors.WithLabelValues(e.Pipeline, StageRun).Inc()
+		}
+		x.endAttempt(e.Pipeline)
+		if e.Duration > 0 { // Skipped pipelines never started
+			x.runTime.WithLabelValues(e.Pipeline).Observe(e.Duration.Seconds())
+		}
+	}
+}
+
+// endAttempt adds the final progress of a pipeline's attempt to its totals
+// Failures of pipelines that never started add nothing.
+func (x *Exporter) endAttempt(name string) {
+	for _, s := range x.manager.Status() {
+		if s.Name != name {
+			continue
+		}
+
+		x.mu.Lock()
+		c := x.counter(name)
+		if c.running {
+			c.total = add(c.total, s.Progress)
+			c.last, c.running = s.Progress, false
+		}
+		x.mu.Unlock()
+		return
+	}
+}
+
+// counter returns the counters of a pipeline
+// Callers hold x.mu.
+func (x *Exporter) counter(name string) *counters {
+	c, ok := x.counters[name]
+	if !ok {
+		c = &counters{}
+		x.counters[name] = c
+	}
+	return c
+}
+
+// Describe implements prometheus.Collector
+func (x *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- x.records
+	ch <- x.queueDepth
+	ch <- x.overflow
+	x.errors.Describe(ch)
+	x.batchSize.Describe(ch)
+	x.batchTime.Describe(ch)
+	x.runTime.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (x *Exporter) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range x.manager.Status() {
+		x.mu.Lock()
+		c := x.counter(s.Name)
+		total := c.total
+		// Until the new attempt moves, the live progress is the last one's
+		if c.running && s.Progress != c.last {
+			total = add(total, s.Progress)
+		}
+		x.mu.Unlock()
+
+		ch <- prometheus.MustNewConstMetric(x.records, prometheus.CounterValue, float64(total.Extracted), s.Name, StageExtract)
+		ch <- prometheus.MustNewConstMetric(x.records, prometheus.CounterValue, float64(total.Transformed), s.Name, StageTransform)
+		ch <- prometheus.MustNewConstMetric(x.records, prometheus.CounterValue, float64(total.Loaded), s.Name, StageLoad)
+		ch <- prometheus.MustNewConstMetric(x.overflow, prometheus.CounterValue, float64(total.Dropped), s.Name, bucket.OverflowDropOldest.String())
+		ch <- prometheus.MustNewConstMetric(x.overflow, prometheus.CounterValue, float64(total.Spilled), s.Name, bucket.OverflowSpill.String())
+
+		var depth int64
+		if s.State == etl.StateRunning {
+			depth = max(s.Progress.Extracted-s.Progress.Transformed, 0)
+		}
+		ch <- prometheus.MustNewConstMetric(x.queueDepth, prometheus.GaugeValue, float64(depth), s.Name)
+	}
+
+	x.errors.Collect(ch)
+	x.batchSize.Collect(ch)
+	x.batchTime.Collect(ch)
+	x.runTime.Collect(ch)
+}
+
+// add sums two progress counters
+func add(a, b etl.Progress) etl.Progress {
+	return etl.Progress{
+		Extracted:   a.Extracted + b.Extracted,
+		Transformed: a.Transformed + b.Transformed,
+		Loaded:      a.Loaded + b.Loaded,
+		Batches:     a.Batches + b.Batches,
+		Rejected:    a.Rejected + b.Rejected,
+		Dropped:     a.Dropped + b.Dropped,
+		Spilled:     a.Spilled + b.Spilled,
+	}
+}
@@ -0,0 +1,218 @@
+// Package notify alerts operators through Slack, SNS or email when
+// pipelines fail, dead-letter too many records or finish a run
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Severity ranks a message
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+// String returns the severity name
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Message is a notification
+type Message struct {
+	Severity Severity
+	Title    string // One line, used as the email or SNS subject
+	Text     string // Plain text body, may span lines
+	Pipeline string // Empty for run summaries
+	Time     time.Time
+}
+
+// Notifier delivers messages to a channel
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(ctx context.Context, msg Message) error
+
+// Notify calls f
+func (f NotifierFunc) Notify(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// Config configures the alerts of a manager
+type Config struct {
+	Notifiers []Notifier
+
+	// PipelineFailures sends an alert as soon as a pipeline fails, rather
+	// than only in the run summary
+	PipelineFailures bool
+
+	// Success sends the run summary after successful runs too; failed runs
+	// always send it
+	Success bool
+
+	// DeadLetterThreshold alerts once a pipeline dead-letters more than this
+	// many records in a run; 0 disables the alert
+	DeadLetterThreshold int
+
+	Timeout time.Duration // Per notification (defaults to 10s)
+	Logger  *slog.Logger  // Logs failed deliveries (defaults to slog.Default())
+}
+
+// Attach subscribes the alerts configured by cfg to m's events
+// Alerts raised during a run are sent in the background; the run summary is
+// sent before RunAll or Run returns, after any alert still in flight, so a
+// process exiting right after a run loses nothing.
+func Attach(m *etl.Manager, cfg Config) error {
+	if len(cfg.Notifiers) == 0 {
+		return fmt.Errorf("notify: at least one notifier is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	a := &alerter{cfg: cfg, manager: m, deadLettered: make(map[string]int)}
+	m.OnEvent(a.handle)
+	return nil
+}
+
+// alerter turns manager events into messages
+type alerter struct {
+	cfg      Config
+	manager  *etl.Manager
+	inFlight sync.WaitGroup
+
+	mu           sync.Mutex
+	deadLettered map[string]int // Records dead-lettered per pipeline in the current run
+}
+
+func (a *alerter) handle(e etl.Event) {
+	switch e.Type {
+	case etl.PipelineStarted:
+		if e.Attempt == 1 {
+			a.mu.Lock()
+			delete(a.deadLettered, e.Pipeline)
+			a.mu.Unlock()
+		}
+
+	case etl.BatchDeadLettered:
+		if a.cfg.DeadLetterThreshold <= 0 {
+			return
+		}
+		a.mu.Lock()
+		before := a.deadLettered[e.Pipeline]
+		a.deadLettered[e.Pipeline] = before + e.Records
+		crossed := before <= a.cfg.DeadLetterThreshold && before+e.Records > a.cfg.DeadLetterThreshold
+		a.mu.Unlock()
+
+		if crossed {
+			a.sendAsync(Message{
+				Severity: Warning,
+				Title:    fmt.Sprintf("Pipeline %s dead-lettered over %d records", e.Pipeline, a.cfg.DeadLetterThreshold),
+				Text:     fmt.Sprintf("%d records of pipeline %s were dead-lettered in this run.", before+e.Records, e.Pipeline),
+				Pipeline: e.Pipeline,
+				Time:     e.Time,
+			})
+		}
+
+	case etl.PipelineFailed:
+		if a.cfg.PipelineFailures {
+			a.sendAsync(Message{
+				Severity: Error,
+				Title:    fmt.Sprintf("Pipeline %s failed", e.Pipeline),
+				Text:     fmt.Sprintf("Pipeline %s failed on attempt %d: %v", e.Pipeline, e.Attempt, e.Err),
+				Pipeline: e.Pipeline,
+				Time:     e.Time,
+			})
+		}
+
+	case etl.ManagerDone:
+		a.inFlight.Wait()
+		if e.Err != nil || a.cfg.Success {
+			a.send(summary(a.manager.Metrics(), e))
+		}
+	}
+}
+
+// sendAsync sends msg without blocking the pipeline that raised it
+func (a *alerter) sendAsync(msg Message) {
+	a.inFlight.Add(1)
+	go func() {
+		defer a.inFlight.Done()
+		a.send(msg)
+	}()
+}
+
+// send delivers msg to every notifier, logging failures
+func (a *alerter) send(msg Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Timeout)
+	defer cancel()
+
+	var errs []error
+	for _, n := range a.cfg.Notifiers {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		a.cfg.Logger.Error("Failed to send notification", "title", msg.Title, "error", err)
+	}
+}
+
+// summary describes a completed run
+func summary(metrics etl.RunMetrics, e etl.Event) Message {
+	msg := Message{Severity: Info, Time: e.Time}
+	total := len(metrics.Pipelines)
+	switch {
+	case e.Err != nil && metrics.Failed > 0:
+		msg.Severity = Error
+		msg.Title = fmt.Sprintf("Run failed: %d of %d pipelines failed", metrics.Failed, total)
+	case e.Err != nil:
+		msg.Severity = Error
+		msg.Title = "Run failed"
+	default:
+		msg.Title = fmt.Sprintf("Run succeeded: %d pipelines", total)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Duration %s, %d records loaded in %d batches\n",
+		metrics.Duration.Round(time.Millisecond), metrics.Loaded, metrics.Batches)
+	if e.Err != nil {
+		fmt.Fprintf(&b, "Error: %v\n", e.Err)
+	}
+	if total > 0 {
+		b.WriteString("\n")
+		w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PIPELINE\tSTATE\tATTEMPTS\tLOADED\tDURATION\tBOUND")
+		for _, p := range metrics.Pipelines {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", p.Name, p.State, p.Attempts, p.Loaded, p.Duration.Round(time.Millisecond), p.Stages.Bottleneck())
+		}
+		w.Flush()
+	}
+	msg.Text = strings.TrimSuffix(b.String(), "\n")
+	return msg
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Slack posts messages to a Slack incoming webhook
+type Slack struct {
+	WebhookURL string
+	HTTPClient *http.Client // Defaults to http.DefaultClient
+}
+
+// colors of the message attachment by severity
+var slackColors = map[Severity]string{
+	Info:    "good",
+	Warning: "warning",
+	Error:   "danger",
+}
+
+// Notify posts msg as a colored attachment, with the body in a code block
+// so summary tables stay aligned
+func (s *Slack) Notify(ctx context.Context, msg Message) error {
+	payload := map[string]any{
+		"text": msg.Title,
+		"attachments": []map[string]any{{
+			"color":     slackColors[msg.Severity],
+			"text":      "```" + msg.Text + "```",
+			"mrkdwn_in": []string{"text"},
+			"ts":        msg.Time.Unix(),
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("slack: webhook returned %s: %s", resp.Status, bytes.TrimSpace(text))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP emails messages through an SMTP server
+// The connection is upgraded with STARTTLS when the server offers it.
+type SMTP struct {
+	Addr string    // host:port, e.g. smtp.example.com:587
+	Auth smtp.Auth // e.g. smtp.PlainAuth, nil for none
+	From string
+	To   []string
+}
+
+// Notify sends msg as a plain text email
+// net/smtp takes no context, so ctx only stops a send that has not started.
+func (s *SMTP) Notify(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	if err := smtp.SendMail(s.Addr, s.Auth, s.From, s.To, []byte(b.String())); err != nil {
+		return fmt.Errorf("smtp: send to %s: %w", strings.Join(s.To, ", "), err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// SNSClient is the subset of *sns.Client the notifier uses
+type SNSClient interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SNS publishes messages to an SNS topic, e.g. one that pages on-call
+type SNS struct {
+	Client   SNSClient
+	TopicARN string
+}
+
+// maxSubject is the longest subject SNS accepts
+const maxSubject = 100
+
+// Notify publishes msg with its title as subject and its severity and
+// pipeline as message attributes, for subscription filter policies
+func (s *SNS) Notify(ctx context.Context, msg Message) error {
+	subject := msg.Title
+	if len(subject) > maxSubject {
+		subject = subject[:maxSubject-3] + "..."
+	}
+
+	attrs := map[string]types.MessageAttributeValue{
+		"severity": {DataType: aws.String("String"), StringValue: aws.String(msg.Severity.String())},
+	}
+	if msg.Pipeline != "" {
+		attrs["pipeline"] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(msg.Pipeline)}
+	}
+
+	_, err := s.Client.Publish(ctx, &sns.PublishInput{
+		TopicArn:          aws.String(s.TopicARN),
+		Subject:           aws.String(subject),
+		Message:           aws.String(msg.Title + "\n\n" + msg.Text),
+		MessageAttributes: attrs,
+	})
+	if err != nil {
+		return fmt.Errorf("sns: publish to %s: %w", s.TopicARN, err)
+	}
+	return nil
+}
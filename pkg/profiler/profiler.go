@@ -0,0 +1,200 @@
+// Package profiler computes per-field statistics over a stream of records:
+// null ratios, distinct count estimates, minimums and maximums, length
+// distributions and top values, to help design the mappings and
+// transforms of a pipeline before writing it
+//
+//	p := profiler.New(profiler.Config{})
+//	if err := profiler.Records(ctx, records, p, 10000); err != nil {
+//		return err
+//	}
+//	p.Profile().WriteText(os.Stdout)
+package profiler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/maphash"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a Profiler
+type Config struct {
+	// TopValues is how many of the most frequent values are reported per
+	// field (defaults to 5)
+	TopValues int
+
+	// MaxFields bounds the fields tracked, so records with unbounded keys
+	// cannot exhaust memory; further fields are ignored (defaults to 1000)
+	MaxFields int
+}
+
+// Profiler accumulates the statistics of records
+// A Profiler is not safe for concurrent use.
+type Profiler struct {
+	cfg     Config
+	seed    maphash.Seed
+	records int64
+	fields  map[string]*fieldStats
+}
+
+// New returns an empty profiler
+func New(cfg Config) *Profiler {
+	if cfg.TopValues <= 0 {
+		cfg.TopValues = 5
+	}
+	if cfg.MaxFields <= 0 {
+		cfg.MaxFields = 1000
+	}
+	return &Profiler{cfg: cfg, seed: maphash.MakeSeed(), fields: make(map[string]*fieldStats)}
+}
+
+// Add adds a record: a map, or a value encoded to a JSON object, such as a
+// struct
+// Nested objects are profiled field by field under dotted paths, e.g.
+// "address.city"; arrays are values of their own.
+func (p *Profiler) Add(record any) error {
+	m, ok := record.(map[string]any)
+	if !ok {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("profiler: %w", err)
+		}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("profiler: records must encode to JSON objects: %w", err)
+		}
+	}
+	p.records++
+	p.add("", m)
+	return nil
+}
+
+// add adds the fields of an object under prefix
+func (p *Profiler) add(prefix string, m map[string]any) {
+	for k, v := range m {
+		path := prefix + k
+		if nested, ok := v.(map[string]any); ok {
+			p.add(path+".", nested)
+			continue
+		}
+		fs, ok := p.fields[path]
+		if !ok {
+			if len(p.fields) >= p.cfg.MaxFields {
+				continue
+			}
+			fs = newFieldStats(p.cfg.TopValues)
+			p.fields[path] = fs
+		}
+		fs.add(p.seed, v)
+	}
+}
+
+// Profile returns the statistics of the records added so far
+func (p *Profiler) Profile() Profile {
+	prof := Profile{Records: p.records, Fields: make([]Field, 0, len(p.fields))}
+	for _, name := range slices.Sorted(maps.Keys(p.fields)) {
+		prof.Fields = append(prof.Fields, p.fields[name].field(name, p.records, p.cfg.TopValues))
+	}
+	return prof
+}
+
+// Records adds the records received from records to p until the channel
+// is closed or limit records were added, if limit is positive; a record
+// that cannot be read or profiled fails the run
+func Records[E any](ctx context.Context, records <-chan etl.Payload[E], p *Profiler, limit int64) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case rec, ok := <-records:
+			if !ok {
+				return nil
+			}
+			if rec.Err != nil {
+				return rec.Err
+			}
+			if err := p.Add(rec.Data); err != nil {
+				return err
+			}
+			if limit > 0 && p.records >= limit {
+				return nil
+			}
+		}
+	}
+}
+
+// Profile is the statistics of a set of records
+type Profile struct {
+	Records int64   `json:"records"`
+	Fields  []Field `json:"fields"` // Sorted by name
+}
+
+// Field is the statistics of one field
+type Field struct {
+	Name      string           `json:"name"`
+	Types     map[string]int64 `json:"types"`   // Values by type: string, number, bool, time, array or null
+	Missing   int64            `json:"missing"` // Records without the field
+	Nulls     int64            `json:"nulls"`
+	NullRatio float64          `json:"null_ratio"` // Share of the records missing the field or with a null value
+	Distinct  int64            `json:"distinct"`   // Estimated distinct non-null values, within about 2%
+
+	// Min and Max are over the values of the most frequent type among
+	// numbers, times and strings, strings comparing bytewise
+	Min any `json:"min,omitempty"`
+	Max any `json:"max,omitempty"`
+
+	// Length is the distribution of the lengths of strings, in characters,
+	// and arrays, in elements
+	Length *Length `json:"length,omitempty"`
+
+	Top []Value `json:"top,omitempty"` // Most frequent values, most frequent first
+}
+
+// Length is a distribution of lengths
+type Length struct {
+	Min     int      `json:"min"`
+	Max     int      `json:"max"`
+	Mean    float64  `json:"mean"`
+	Buckets []Bucket `json:"buckets"` // Non-empty buckets, by increasing bound
+}
+
+// Bucket counts the lengths up to UpTo, and above the bound of the bucket
+// before it; bounds are 0, 1, 3, 7, 15 and so on
+type Bucket struct {
+	UpTo  int   `json:"up_to"`
+	Count int64 `json:"count"`
+}
+
+// Value is a frequent value with its count
+// Counts are exact until a field has more distinct values than are
+// tracked, ten times the top values reported, and lower bounds after.
+type Value struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// typeOf returns the type of v as reported by Field.Types
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string, []byte:
+		return "string"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "time"
+	}
+	if _, ok := number(v); ok {
+		return "number"
+	}
+	if reflect.ValueOf(v).Kind() == reflect.Slice {
+		return "array"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,277 @@
+package profiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// fieldStats accumulates the statistics of a field
+type fieldStats struct {
+	present int64
+	nulls   int64
+	types   map[string]int64
+	hll     hll
+	top     *spaceSaving
+
+	numMin, numMax float64
+	strMin, strMax string
+	timeMin        time.Time
+	timeMax        time.Time
+
+	lengths   int64 // Values with a length
+	lengthSum int64
+	lengthMin int
+	lengthMax int
+	buckets   [33]int64 // By bits.Len of the length, see Bucket
+}
+
+func newFieldStats(topValues int) *fieldStats {
+	return &fieldStats{types: make(map[string]int64), top: newSpaceSaving(max(10*topValues, 100))}
+}
+
+// add adds a value of the field
+func (s *fieldStats) add(seed maphash.Seed, v any) {
+	s.present++
+	typ := typeOf(v)
+	s.types[typ]++
+	if typ == "null" {
+		s.nulls++
+		return
+	}
+
+	key := display(v)
+	s.hll.add(maphash.String(seed, typ+":"+key))
+	s.top.add(key)
+
+	switch typ {
+	case "number":
+		f, _ := number(v)
+		if s.types[typ] == 1 || f < s.numMin {
+			s.numMin = f
+		}
+		if s.types[typ] == 1 || f > s.numMax {
+			s.numMax = f
+		}
+	case "time":
+		t := v.(time.Time)
+		if s.types[typ] == 1 || t.Before(s.timeMin) {
+			s.timeMin = t
+		}
+		if s.types[typ] == 1 || t.After(s.timeMax) {
+			s.timeMax = t
+		}
+	case "string":
+		if s.types[typ] == 1 || key < s.strMin {
+			s.strMin = key
+		}
+		if s.types[typ] == 1 || key > s.strMax {
+			s.strMax = key
+		}
+		s.length(utf8.RuneCountInString(key))
+	case "array":
+		s.length(reflect.ValueOf(v).Len())
+	}
+}
+
+// length adds the length of a value
+func (s *fieldStats) length(n int) {
+	if s.lengths == 0 || n < s.lengthMin {
+		s.lengthMin = n
+	}
+	if s.lengths == 0 || n > s.lengthMax {
+		s.lengthMax = n
+	}
+	s.lengths++
+	s.lengthSum += int64(n)
+	s.buckets[min(bits.Len(uint(n)), len(s.buckets)-1)]++
+}
+
+// field returns the statistics of the field name over records
+func (s *fieldStats) field(name string, records int64, topValues int) Field {
+	f := Field{
+		Name:     name,
+		Types:    s.types,
+		Missing:  records - s.present,
+		Nulls:    s.nulls,
+		Distinct: s.hll.estimate(),
+		Top:      s.top.top(topValues),
+	}
+	if records > 0 {
+		f.NullRatio = float64(f.Missing+f.Nulls) / float64(records)
+	}
+
+	// Min and max of the most frequent ordered type
+	var best string
+	for _, typ := range []string{"number", "time", "string"} {
+		if s.types[typ] > s.types[best] {
+			best = typ
+		}
+	}
+	switch best {
+	case "number":
+		f.Min, f.Max = s.numMin, s.numMax
+	case "time":
+		f.Min, f.Max = s.timeMin, s.timeMax
+	case "string":
+		f.Min, f.Max = s.strMin, s.strMax
+	}
+
+	if s.lengths > 0 {
+		l := &Length{Min: s.lengthMin, Max: s.lengthMax, Mean: float64(s.lengthSum) / float64(s.lengths)}
+		for i, n := range s.buckets {
+			if n == 0 {
+				continue
+			}
+			upTo := 0
+			if i > 0 {
+				upTo = 1<<i - 1
+			}
+			l.Buckets = append(l.Buckets, Bucket{UpTo: upTo, Count: n})
+		}
+		f.Length = l
+	}
+	return f
+}
+
+// number returns v as a float64 if it is a number
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// display returns the text of a value in top values and distinct counts
+func display(v any) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	if f, ok := number(v); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if data, err := json.Marshal(v); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// hll is a HyperLogLog distinct count estimator with 2^12 registers, for
+// a standard error of about 1.6%
+type hll struct {
+	registers *[1 << hllBits]uint8
+}
+
+const hllBits = 12
+
+func (h *hll) add(hash uint64) {
+	if h.registers == nil {
+		h.registers = new([1 << hllBits]uint8)
+	}
+	i := hash >> (64 - hllBits)
+	rank := uint8(bits.LeadingZeros64(hash<<hllBits|1<<(hllBits-1)) + 1)
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+func (h *hll) estimate() int64 {
+	if h.registers == nil {
+		return 0
+	}
+	const m = 1 << hllBits
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// Linear counting is more accurate for small cardinalities
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(float64(m)/float64(zeros))
+	}
+	return int64(math.Round(e))
+}
+
+// spaceSaving keeps the approximate most frequent values of a stream in
+// bounded memory, see Metwally et al., "Efficient Computation of Frequent
+// and Top-k Elements in Data Streams"
+type spaceSaving struct {
+	capacity int
+	counts   map[string]*counter
+}
+
+// counter counts a value; count overestimates it by at most err, the count
+// of the value it replaced
+type counter struct {
+	count, err int64
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{capacity: capacity, counts: make(map[string]*counter, capacity)}
+}
+
+func (s *spaceSaving) add(key string) {
+	if c, ok := s.counts[key]; ok {
+		c.count++
+		return
+	}
+	if len(s.counts) < s.capacity {
+		s.counts[key] = &counter{count: 1}
+		return
+	}
+	// Replace the least frequent value, inheriting its count
+	var minKey string
+	var minCounter *counter
+	for k, c := range s.counts {
+		if minCounter == nil || c.count < minCounter.count {
+			minKey, minCounter = k, c
+		}
+	}
+	delete(s.counts, minKey)
+	s.counts[key] = &counter{count: minCounter.count + 1, err: minCounter.count}
+}
+
+// top returns the n most frequent values by their guaranteed count, ties
+// broken by value
+func (s *spaceSaving) top(n int) []Value {
+	values := make([]Value, 0, len(s.counts))
+	for k, c := range s.counts {
+		values = append(values, Value{Value: k, Count: c.count - c.err})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if len(values) > n {
+		values = values[:n]
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package profiler
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+// WriteText writes p as a table, one line per field
+func (p Profile) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%d records\n\n", p.Records)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tTYPES\tNULLS\tDISTINCT\tMIN\tMAX\tLENGTH\tTOP VALUES")
+	for _, f := range p.Fields {
+		length := "-"
+		if l := f.Length; l != nil {
+			length = fmt.Sprintf("%d..%d (avg %.1f)", l.Min, l.Max, l.Mean)
+		}
+		top := make([]string, len(f.Top))
+		for i, v := range f.Top {
+			top[i] = fmt.Sprintf("%s (%d)", shorten(v.Value), v.Count)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t~%d\t%s\t%s\t%s\t%s\n", f.Name, types(f.Types), 100*f.NullRatio,
+			f.Distinct, bound(f.Min), bound(f.Max), length, orDash(strings.Join(top, ", ")))
+	}
+	return tw.Flush()
+}
+
+// types lists the types of a field, most frequent first
+func types(counts map[string]int64) string {
+	names := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return strings.Join(names, ",")
+}
+
+// bound formats a minimum or maximum
+func bound(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "-"
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case string:
+		return shorten(x)
+	}
+	return display(v)
+}
+
+// shorten truncates long values for display
+func shorten(s string) string {
+	const maxLen = 24
+	if utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+	return string([]rune(s)[:maxLen-1]) + "…"
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
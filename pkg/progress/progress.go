@@ -0,0 +1,261 @@
+// Package progress renders the live progress of a manager's pipelines on a
+// terminal: a bar, rate, error count and ETA per pipeline, refreshed in
+// place
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures the display
+type Config struct {
+	Writer   io.Writer     // Defaults to os.Stderr
+	Interval time.Duration // Refresh interval (defaults to 500ms)
+	Width    int           // Bar width in characters (defaults to 30)
+
+	// Totals are the expected records per pipeline, for bars and ETAs;
+	// pipelines without one show counts and rates only
+	Totals map[string]int64
+}
+
+// Display renders the progress of a manager's pipelines
+type Display struct {
+	cfg     Config
+	manager *etl.Manager
+	tty     bool
+
+	mu     sync.Mutex
+	totals map[string]int64
+	errors map[string]int64 // Dead-lettered records and failed attempts
+	rates  map[string]*rate
+	lines  int // Lines of the last frame, to redraw in place
+}
+
+// rate tracks the smoothed load rate of a pipeline
+type rate struct {
+	loaded int64
+	at     time.Time
+	perSec float64
+}
+
+// New creates a display of m's pipelines
+// Rendering starts with Start. On writers that are not terminals, frames are
+// not redrawn and only the final one is written.
+func New(m *etl.Manager, cfg Config) *Display {
+	if cfg.Writer == nil {
+		cfg.Writer = os.Stderr
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 500 * time.Millisecond
+	}
+	if cfg.Width <= 0 {
+		cfg.Width = 30
+	}
+
+	d := &Display{
+		cfg:     cfg,
+		manager: m,
+		tty:     IsTerminal(cfg.Writer),
+		totals:  make(map[string]int64, len(cfg.Totals)),
+		errors:  make(map[string]int64),
+		rates:   make(map[string]*rate),
+	}
+	for name, total := range cfg.Totals {
+		d.totals[name] = total
+	}
+	m.OnEvent(d.handle)
+	return d
+}
+
+// IsTerminal reports whether w is a terminal
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetTotal sets the expected records of a pipeline, e.g. once a count of
+// the source is known
+func (d *Display) SetTotal(pipeline string, total int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.totals[pipeline] = total
+}
+
+// Start refreshes the display in the background until stop is called,
+// which renders the final frame
+// Logs written to the same terminal while the display runs break the
+// redrawing, so lower the log level of the manager's Logger meanwhile.
+func (d *Display) Start() (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(d.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				d.render(true)
+				return
+			case <-ticker.C:
+				if d.tty {
+					d.render(false)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// handle counts errors from manager events
+func (d *Display) handle(e etl.Event) {
+	var n int64
+	switch e.Type {
+	case etl.BatchDeadLettered:
+		n = int64(e.Records)
+	case etl.PipelineRetrying, etl.PipelineFailed:
+		n = 1
+	case etl.PipelineStarted:
+		if e.Attempt == 1 {
+			d.mu.Lock()
+			d.errors[e.Pipeline] = 0
+			delete(d.rates, e.Pipeline)
+			d.mu.Unlock()
+		}
+		return
+	default:
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.errors[e.Pipeline] += n
+}
+
+// render writes a frame, over the last one on terminals
+func (d *Display) render(final bool) {
+	statuses := d.manager.Status()
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	nameWidth := 0
+	for _, s := range statuses {
+		nameWidth = max(nameWidth, len(s.Name))
+	}
+
+	var b strings.Builder
+	if d.tty && d.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", d.lines) // Back to the first line of the last frame
+	}
+	for _, s := range statuses {
+		if d.tty {
+			b.WriteString("\x1b[2K") // Clear the line
+		}
+		fmt.Fprintf(&b, "%-*s  %s\n", nameWidth, s.Name, d.line(s, now))
+	}
+	d.lines = len(statuses)
+	if final {
+		d.lines = 0
+	}
+
+	io.WriteString(d.cfg.Writer, b.String())
+}
+
+// line formats the progress of one pipeline
+// Callers hold d.mu.
+func (d *Display) line(s etl.PipelineStatus, now time.Time) string {
+	loaded := s.Progress.Loaded
+	perSec := d.rate(s, now)
+
+	var parts []string
+	if total := d.totals[s.Name]; total > 0 {
+		done := min(float64(loaded)/float64(total), 1)
+		filled := int(done * float64(d.cfg.Width))
+		bar := strings.Repeat("=", filled)
+		if filled < d.cfg.Width {
+			bar += ">" + strings.Repeat(" ", d.cfg.Width-filled-1)
+		}
+		parts = append(parts,
+			fmt.Sprintf("[%s] %5.1f%%", bar, done*100),
+			fmt.Sprintf("%s/%s", compact(float64(loaded)), compact(float64(total))),
+		)
+	} else {
+		parts = append(parts, fmt.Sprintf("%s loaded", compact(float64(loaded))))
+	}
+
+	parts = append(parts, fmt.Sprintf("%s rec/s", compact(perSec)))
+	if total := d.totals[s.Name]; total > loaded && perSec > 0 && s.State == etl.StateRunning {
+		eta := time.Duration(float64(total-loaded) / perSec * float64(time.Second))
+		parts = append(parts, "ETA "+eta.Round(time.Second).String())
+	}
+	parts = append(parts, fmt.Sprintf("errors %d", d.errors[s.Name]), s.State.String())
+	if s.State == etl.StateRunning || s.State == etl.StateRetrying {
+		parts = append(parts, fmt.Sprintf("attempt %d", s.Attempt))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// rate returns the smoothed load rate of a pipeline, and its average rate
+// once it finished
+// Callers hold d.mu.
+func (d *Display) rate(s etl.PipelineStatus, now time.Time) float64 {
+	if s.State != etl.StateRunning {
+		if elapsed := s.FinishedAt.Sub(s.StartedAt).Seconds(); s.State != etl.StatePending && elapsed > 0 {
+			return float64(s.Progress.Loaded) / elapsed
+		}
+		return 0
+	}
+
+	r, ok := d.rates[s.Name]
+	if !ok || s.Progress.Loaded < r.loaded { // A new attempt started over
+		d.rates[s.Name] = &rate{loaded: s.Progress.Loaded, at: now}
+		return 0
+	}
+	if elapsed := now.Sub(r.at).Seconds(); elapsed > 0 {
+		instant := float64(s.Progress.Loaded-r.loaded) / elapsed
+		if r.perSec == 0 {
+			r.perSec = instant
+		} else {
+			r.perSec = 0.7*r.perSec + 0.3*instant
+		}
+		r.loaded, r.at = s.Progress.Loaded, now
+	}
+	return r.perSec
+}
+
+// compact formats n with a k, M or G suffix
+func compact(n float64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.1fG", n/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", n/1e6)
+	case n >= 1e4:
+		return fmt.Sprintf("%.1fk", n/1e3)
+	default:
+		return fmt.Sprintf("%.0f", n)
+	}
+}
@@ -0,0 +1,227 @@
+package quality
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// Check tests the values of a field
+// Checks other than NotNull pass missing and nil values.
+type Check interface {
+	// Kind names the check in rule names, e.g. "not_null"
+	Kind() string
+
+	// Test reports whether value passes; an error fails the run
+	Test(ctx context.Context, value any) (bool, error)
+}
+
+// resetter is implemented by checks keeping state over a run
+type resetter interface {
+	reset(ctx context.Context) error
+}
+
+// NotNull fails missing and nil values, and empty strings
+func NotNull() Check {
+	return notNull{}
+}
+
+type notNull struct{}
+
+func (notNull) Kind() string { return "not_null" }
+
+func (notNull) Test(_ context.Context, value any) (bool, error) {
+	if s, ok := value.(string); ok {
+		return s != "", nil
+	}
+	return !isNil(value), nil
+}
+
+// Unique fails values seen earlier in the run
+// Every distinct value of the run is kept in memory.
+func Unique() Check {
+	return &unique{}
+}
+
+type unique struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func (*unique) Kind() string { return "unique" }
+
+func (u *unique) Test(_ context.Context, value any) (bool, error) {
+	key := fmt.Sprintf("%T:%v", value, value)
+	// Numbers decoded from JSON and typed numbers compare equal
+	if f, ok := number(value); ok {
+		key = "number:" + strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.seen == nil {
+		u.seen = make(map[string]struct{})
+	}
+	if _, dup := u.seen[key]; dup {
+		return false, nil
+	}
+	u.seen[key] = struct{}{}
+	return true, nil
+}
+
+func (u *unique) reset(context.Context) error {
+	u.mu.Lock()
+	u.seen = nil
+	u.mu.Unlock()
+	return nil
+}
+
+// Regex fails string values not matching pattern, and values that are not
+// strings
+func Regex(pattern string) (Check, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("quality: %w", err)
+	}
+	return regex{re: re}, nil
+}
+
+type regex struct {
+	re *regexp.Regexp
+}
+
+func (regex) Kind() string { return "regex" }
+
+func (r regex) Test(_ context.Context, value any) (bool, error) {
+	s, ok := value.(string)
+	return ok && r.re.MatchString(s), nil
+}
+
+// Range fails numbers outside [min, max] and values that are not numbers;
+// use math.Inf for an open bound
+func Range(min, max float64) Check {
+	return numRange{min: min, max: max}
+}
+
+type numRange struct {
+	min, max float64
+}
+
+func (numRange) Kind() string { return "range" }
+
+func (r numRange) Test(_ context.Context, value any) (bool, error) {
+	f, ok := number(value)
+	return ok && !math.IsNaN(f) && f >= r.min && f <= r.max, nil
+}
+
+// Lookup holds the values a field may reference, e.g. the keys of a
+// dimension table
+type Lookup interface {
+	Contains(ctx context.Context, value any) (bool, error)
+}
+
+// Refresher can optionally be implemented by a Lookup reloading its values
+// at the start of every run
+type Refresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// In fails values missing from lookup
+func In(lookup Lookup) Check {
+	return in{lookup: lookup}
+}
+
+type in struct {
+	lookup Lookup
+}
+
+func (in) Kind() string { return "lookup" }
+
+func (c in) Test(ctx context.Context, value any) (bool, error) {
+	return c.lookup.Contains(ctx, value)
+}
+
+func (c in) reset(ctx context.Context) error {
+	if r, ok := c.lookup.(Refresher); ok {
+		return r.Refresh(ctx)
+	}
+	return nil
+}
+
+// Set is a Lookup of fixed values; numbers match whatever their type
+type Set struct {
+	mu     sync.RWMutex
+	values map[string]struct{}
+}
+
+// NewSet returns a set of values
+func NewSet(values ...any) *Set {
+	s := &Set{}
+	s.Replace(values)
+	return s
+}
+
+// Replace replaces the values of s
+func (s *Set) Replace(values []any) {
+	m := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		m[setKey(v)] = struct{}{}
+	}
+	s.mu.Lock()
+	s.values = m
+	s.mu.Unlock()
+}
+
+// Contains reports whether value is in s
+func (s *Set) Contains(_ context.Context, value any) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.values[setKey(value)]
+	return ok, nil
+}
+
+// setKey returns the key of a value in a Set
+func setKey(v any) string {
+	if f, ok := number(v); ok {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// number returns v as a float64 if it is a number
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case interface{ Float64() (float64, error) }: // json.Number
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// isNil reports whether v is nil or a nil pointer, map, slice or interface
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package quality
+
+import (
+	"context"
+	"sync"
+)
+
+// Collector keeps the latest result of every pipeline, e.g. for the run
+// report (see report.Config)
+type Collector struct {
+	mu      sync.Mutex
+	results map[string]Result
+}
+
+// NewCollector returns an empty collector
+func NewCollector() *Collector {
+	return &Collector{results: make(map[string]Result)}
+}
+
+// Result returns the latest result of pipeline
+func (c *Collector) Result(pipeline string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.results[pipeline]
+	return r, ok
+}
+
+// Reset forgets the result of pipeline, e.g. when a new run starts
+func (c *Collector) Reset(pipeline string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.results, pipeline)
+}
+
+func (c *Collector) add(r Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results[r.Pipeline] = r
+}
+
+// collectorContextKey is the context key for a collector
+type collectorContextKey struct{}
+
+// NewContext returns a context carrying c, for code that builds pipelines
+// from declarations, such as package config
+func NewContext(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, collectorContextKey{}, c)
+}
+
+// FromContext returns the collector carried by ctx
+func FromContext(ctx context.Context) (*Collector, bool) {
+	c, ok := ctx.Value(collectorContextKey{}).(*Collector)
+	return c, ok
+}
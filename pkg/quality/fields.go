@@ -0,0 +1,58 @@
+package quality
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field returns the value at a dotted path of a record, and whether it is
+// present
+// Records are maps with string keys or structs, whose fields are named
+// after their json tag, or their Go name without one; a key holding the
+// whole path takes precedence over nested ones.
+func Field(record any, path string) (any, bool) {
+	rv := reflect.ValueOf(record)
+	if v, ok := child(rv, path); ok {
+		return v.Interface(), true
+	}
+	for _, name := range strings.Split(path, ".") {
+		var ok bool
+		if rv, ok = child(rv, name); !ok {
+			return nil, false
+		}
+	}
+	return rv.Interface(), true
+}
+
+// child returns the field or map entry name of v
+func child(v reflect.Value, name string) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		e := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		return e, e.IsValid()
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if tag == name || (tag == "" && f.Name == name) {
+				return v.Field(i), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,303 @@
+// Package quality checks the records a pipeline loads against declared
+// data quality rules, counting and sampling their violations and failing
+// the run when a rule exceeds its threshold
+package quality
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Rule applies a check to a field of every record
+type Rule struct {
+	Name  string // Defaults to "<field>:<check kind>"
+	Field string // Dotted path, see Field
+	Check Check
+
+	// Threshold fails the run when the rule is violated too often; nil
+	// only counts violations
+	Threshold *Threshold
+}
+
+// name returns the name of r
+func (r Rule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Field + ":" + r.Check.Kind()
+}
+
+// Threshold is how often a rule may be violated in a run
+// Without Ratio, the run fails as soon as violations exceed Count, before
+// the batch with the extra violation is loaded; the zero Threshold allows
+// no violation. With Ratio, it fails once complete if violations exceed
+// that share of the records, and early if Count is set and exceeded.
+type Threshold struct {
+	Count int64
+	Ratio float64
+}
+
+// exceeded reports whether violations out of records exceed t, counting
+// ratios only once the run is complete
+func (t *Threshold) exceeded(violations, records int64, complete bool) bool {
+	if t.Ratio <= 0 {
+		return violations > t.Count
+	}
+	if t.Count > 0 && violations > t.Count {
+		return true
+	}
+	return complete && records > 0 && float64(violations)/float64(records) > t.Ratio
+}
+
+// Config configures the quality checks of a pipeline
+type Config struct {
+	Name  string // Pipeline name, for the Collector
+	Rules []Rule
+
+	// Samples is how many violating records are kept per rule (defaults
+	// to 5)
+	Samples int
+
+	// Collector receives the result of every run; optional
+	Collector *Collector
+}
+
+// Result is the outcome of the quality checks of a run
+type Result struct {
+	Pipeline string       `json:"pipeline"`
+	Records  int64        `json:"records"` // Records checked
+	Passed   bool         `json:"passed"`  // No rule exceeded its threshold
+	Rules    []RuleResult `json:"rules"`
+}
+
+// RuleResult is the outcome of one rule
+type RuleResult struct {
+	Rule       string   `json:"rule"`
+	Field      string   `json:"field"`
+	Check      string   `json:"check"`
+	Violations int64    `json:"violations"`
+	Ratio      float64  `json:"ratio"`  // Share of the records violating the rule
+	Failed     bool     `json:"failed"` // Exceeded its threshold
+	Samples    []Sample `json:"samples,omitempty"`
+}
+
+// Sample is a record violating a rule
+type Sample struct {
+	Value  any             `json:"value"`
+	Record json.RawMessage `json:"record,omitempty"`
+}
+
+// failed returns the results of the rules exceeding their threshold
+func (r Result) failed() []RuleResult {
+	var failed []RuleResult
+	for _, rr := range r.Rules {
+		if rr.Failed {
+			failed = append(failed, rr)
+		}
+	}
+	return failed
+}
+
+// ThresholdError reports the rules that exceeded their threshold
+type ThresholdError struct {
+	Pipeline string
+	Rules    []RuleResult
+}
+
+func (e *ThresholdError) Error() string {
+	msg := fmt.Sprintf("quality: %s:", e.Pipeline)
+	for i, r := range e.Rules {
+		if i > 0 {
+			msg += ","
+		}
+		msg += fmt.Sprintf(" %s violated by %d records (%.2f%%)", r.Rule, r.Violations, 100*r.Ratio)
+	}
+	return msg
+}
+
+// Wrap checks the transformed records of processor against the rules of
+// cfg before they are loaded
+// Violations are counted and sampled, and logged with the result of every
+// run; violating records are still loaded unless a threshold fails the run.
+//
+// The optional interfaces of processor, such as BatchCommitter and Resumer,
+// are kept (see etl.Wrapper).
+func Wrap[E, T any](processor etl.ETLProcessor[E, T], cfg Config) (etl.ETLProcessor[E, T], error) {
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("quality: no rules")
+	}
+	names := make(map[string]bool, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		if r.Field == "" || r.Check == nil {
+			return nil, fmt.Errorf("quality: rules need a field and a check")
+		}
+		if names[r.name()] {
+			return nil, fmt.Errorf("quality: duplicate rule %s", r.name())
+		}
+		names[r.name()] = true
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 5
+	}
+
+	c := &checker[E, T]{processor: processor, cfg: cfg}
+	c.rules = make([]*ruleState, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		c.rules[i] = &ruleState{Rule: r}
+	}
+	return c, nil
+}
+
+// checker implements Wrap
+type checker[E, T any] struct {
+	processor etl.ETLProcessor[E, T]
+	cfg       Config
+	rules     []*ruleState
+	records   atomic.Int64
+	published atomic.Bool // The result of the run was published
+}
+
+// ruleState counts the violations of a rule over a run
+type ruleState struct {
+	Rule
+	violations atomic.Int64
+
+	mu      sync.Mutex
+	samples []Sample
+}
+
+// PreProcess resets the counters and the state of the checks, then runs
+// the wrapped processor's PreProcess
+func (c *checker[E, T]) PreProcess(ctx context.Context) error {
+	if c.cfg.Collector != nil {
+		c.cfg.Collector.Reset(c.cfg.Name)
+	}
+	c.records.Store(0)
+	c.published.Store(false)
+	for _, r := range c.rules {
+		r.violations.Store(0)
+		r.mu.Lock()
+		r.samples = nil
+		r.mu.Unlock()
+		if res, ok := r.Check.(resetter); ok {
+			if err := res.reset(ctx); err != nil {
+				return fmt.Errorf("quality: rule %s: %w", r.name(), err)
+			}
+		}
+	}
+	return c.processor.PreProcess(ctx)
+}
+
+func (c *checker[E, T]) Extract(ctx context.Context) (<-chan etl.Payload[E], error) {
+	return c.processor.Extract(ctx)
+}
+
+func (c *checker[E, T]) Transform(ctx context.Context, e E) T {
+	return c.processor.Transform(ctx, e)
+}
+
+// Load checks data, then loads it unless a count threshold was exceeded
+func (c *checker[E, T]) Load(ctx context.Context, data []T) error {
+	c.records.Add(int64(len(data)))
+	for _, record := range data {
+		for _, r := range c.rules {
+			value, _ := Field(record, r.Field)
+			if _, notNull := r.Check.(notNull); !notNull && isNil(value) {
+				continue
+			}
+			ok, err := r.Check.Test(ctx, value)
+			if err != nil {
+				return fmt.Errorf("quality: rule %s: %w", r.name(), err)
+			}
+			if !ok {
+				r.violate(record, value, c.cfg.Samples)
+			}
+		}
+	}
+
+	if result := c.result(false); !result.Passed {
+		c.publish(ctx, result)
+		return etl.Permanent(&ThresholdError{Pipeline: c.cfg.Name, Rules: result.failed()})
+	}
+	return c.processor.Load(ctx, data)
+}
+
+// violate counts a violation of r, sampling the record
+func (r *ruleState) violate(record, value any, samples int) {
+	r.violations.Add(1)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.samples) >= samples {
+		return
+	}
+	data, _ := json.Marshal(record)
+	r.samples = append(r.samples, Sample{Value: value, Record: data})
+}
+
+// PostProcess publishes the result of the run and fails it if a rule
+// exceeded its threshold, before the wrapped processor's PostProcess can
+// finalize the load
+func (c *checker[E, T]) PostProcess(ctx context.Context) error {
+	if c.published.Swap(true) {
+		return c.processor.PostProcess(ctx)
+	}
+	result := c.result(true)
+	c.publish(ctx, result)
+	if !result.Passed {
+		return &ThresholdError{Pipeline: c.cfg.Name, Rules: result.failed()}
+	}
+	return c.processor.PostProcess(ctx)
+}
+
+// result returns the result of the run so far
+func (c *checker[E, T]) result(complete bool) Result {
+	records := c.records.Load()
+	res := Result{Pipeline: c.cfg.Name, Records: records, Passed: true, Rules: make([]RuleResult, len(c.rules))}
+	for i, r := range c.rules {
+		rr := RuleResult{
+			Rule:       r.name(),
+			Field:      r.Field,
+			Check:      r.Check.Kind(),
+			Violations: r.violations.Load(),
+		}
+		if records > 0 {
+			rr.Ratio = float64(rr.Violations) / float64(records)
+		}
+		if r.Threshold != nil {
+			rr.Failed = r.Threshold.exceeded(rr.Violations, records, complete)
+		}
+		r.mu.Lock()
+		rr.Samples = append([]Sample(nil), r.samples...)
+		r.mu.Unlock()
+
+		res.Passed = res.Passed && !rr.Failed
+		res.Rules[i] = rr
+	}
+	return res
+}
+
+// publish logs a result and hands it to the collector
+func (c *checker[E, T]) publish(ctx context.Context, result Result) {
+	logger := etl.LoggerFromContext(ctx)
+	for _, rr := range result.Rules {
+		if rr.Violations > 0 {
+			logger.Warn("Data quality rule violated", "rule", rr.Rule, "violations", rr.Violations,
+				"records", result.Records, "failed", rr.Failed)
+		}
+	}
+	if c.cfg.Collector != nil {
+		c.cfg.Collector.add(result)
+	}
+}
+
+// Unwrap returns the wrapped processor, whose optional interfaces are kept
+// (see etl.Wrapper)
+func (c *checker[E, T]) Unwrap() any {
+	return c.processor
+}
@@ -0,0 +1,109 @@
+package reconcile
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Checksum accumulates an order-independent checksum of rows
+// Values are compared by their text form, so the same row read from two
+// systems sums the same: 42 matches int64(42), float64(42) and "42", times
+// match in UTC whatever their zone, and 16-byte arrays and values with a
+// Hex method, such as UUIDs and MongoDB ObjectIDs, match their string
+// form. Rows are summed, so duplicates count.
+type Checksum struct {
+	sum uint64
+}
+
+// Add adds a row of key values to c
+func (c *Checksum) Add(values ...any) {
+	h := fnv.New64a()
+	for i, v := range values {
+		if i > 0 {
+			h.Write([]byte{0x1f})
+		}
+		h.Write([]byte(canonical(v)))
+	}
+	c.sum += h.Sum64()
+}
+
+// Sum returns the checksum of the rows added so far
+func (c *Checksum) Sum() uint64 {
+	return c.sum
+}
+
+// canonical returns the text form of v that Checksum hashes
+func canonical(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "\x00"
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case bool:
+		return strconv.FormatBool(x)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	case interface{ Time() time.Time }: // e.g. MongoDB dates
+		return x.Time().UTC().Format(time.RFC3339Nano)
+	case interface{ Hex() string }:
+		return x.Hex()
+	case json.Number:
+		return number(x.String())
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+	case reflect.String:
+		return rv.String()
+	case reflect.Array:
+		if rv.Len() == 16 && rv.Type().Elem().Kind() == reflect.Uint8 {
+			var b [16]byte
+			reflect.Copy(reflect.ValueOf(&b).Elem(), rv)
+			return uuidString(b)
+		}
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return "\x00"
+		}
+		return canonical(rv.Elem().Interface())
+	}
+	// Driver types such as numerics encode as JSON numbers or strings
+	if data, err := json.Marshal(v); err == nil {
+		s := string(data)
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+		return number(s)
+	}
+	return fmt.Sprint(v)
+}
+
+// number returns the JSON number s in the form of strconv.FormatFloat, so
+// 1e3, 1000.0 and 1000 match
+func number(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// uuidString formats a UUID
+func uuidString(b [16]byte) string {
+	s := hex.EncodeToString(b[:])
+	return strings.Join([]string{s[:8], s[8:12], s[12:16], s[16:20], s[20:]}, "-")
+}
@@ -0,0 +1,280 @@
+// Package reconcile compares the source and destination of a pipeline once
+// it has run, counting the rows of every entity on both sides and,
+// optionally, checksumming their key columns, and fails or warns on a
+// mismatch
+//
+// Reconciliation compares whole entities, so it suits full loads into
+// destinations holding nothing else, not incremental loads or appends.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Side is the source or destination of a reconciliation
+type Side interface {
+	// Count returns the number of rows of entity
+	Count(ctx context.Context, entity string) (int64, error)
+}
+
+// Checksummer can optionally be implemented by a Side to reconcile the
+// keys of entities, not only their counts
+type Checksummer interface {
+	// Checksum returns the checksum of the key columns of the rows of
+	// entity, see Checksum
+	Checksum(ctx context.Context, entity string, keys []string) (uint64, error)
+}
+
+// Entity is an entity compared on both sides, e.g. a collection and the
+// table it is loaded into
+type Entity struct {
+	Source      string
+	Destination string // Defaults to Source
+
+	// Keys are checksummed on both sides when set; DestinationKeys name
+	// them in the destination, in the same order, if they differ
+	Keys            []string
+	DestinationKeys []string
+}
+
+// destination returns the destination entity and keys of e
+func (e Entity) destination() (string, []string) {
+	entity, keys := e.Destination, e.DestinationKeys
+	if entity == "" {
+		entity = e.Source
+	}
+	if keys == nil {
+		keys = e.Keys
+	}
+	return entity, keys
+}
+
+// Policy decides what a mismatch does to a run
+type Policy int
+
+const (
+	// Fail fails the run with a *MismatchError
+	Fail Policy = iota
+
+	// Warn logs the mismatch and lets the run succeed
+	Warn
+)
+
+// Config configures a reconciliation
+type Config struct {
+	Name        string // Pipeline name, for errors and logs
+	Source      Side
+	Destination Side
+	Entities    []Entity
+	Policy      Policy
+}
+
+// Result is the outcome of a reconciliation
+type Result struct {
+	Pipeline string         `json:"pipeline"`
+	Matched  bool           `json:"matched"`
+	Entities []EntityResult `json:"entities"`
+}
+
+// EntityResult is the outcome of one entity
+type EntityResult struct {
+	Source           string `json:"source"`
+	Destination      string `json:"destination"`
+	SourceRows       int64  `json:"source_rows"`
+	DestinationRows  int64  `json:"destination_rows"`
+	ChecksumsChecked bool   `json:"checksums_checked"`
+	ChecksumsMatched bool   `json:"checksums_matched"`
+	Matched          bool   `json:"matched"`
+}
+
+func (r EntityResult) String() string {
+	s := fmt.Sprintf("%s: %d source rows, %d destination rows", r.Source, r.SourceRows, r.DestinationRows)
+	if r.ChecksumsChecked && !r.ChecksumsMatched {
+		s += ", keys differ"
+	}
+	return s
+}
+
+// MismatchError reports the entities whose source and destination differ
+type MismatchError struct {
+	Pipeline string
+	Entities []EntityResult
+}
+
+func (e *MismatchError) Error() string {
+	msgs := make([]string, len(e.Entities))
+	for i, r := range e.Entities {
+		msgs[i] = r.String()
+	}
+	return fmt.Sprintf("reconcile: %s: mismatch: %s", e.Pipeline, strings.Join(msgs, "; "))
+}
+
+// check validates cfg
+func (cfg Config) check() error {
+	if cfg.Source == nil || cfg.Destination == nil {
+		return fmt.Errorf("reconcile: Source and Destination are required")
+	}
+	if len(cfg.Entities) == 0 {
+		return fmt.Errorf("reconcile: no entities")
+	}
+	for _, e := range cfg.Entities {
+		if e.Source == "" {
+			return fmt.Errorf("reconcile: entities need a source")
+		}
+		if len(e.Keys) == 0 {
+			continue
+		}
+		if _, keys := e.destination(); len(keys) != len(e.Keys) {
+			return fmt.Errorf("reconcile: entity %s: %d keys but %d destination keys", e.Source, len(e.Keys), len(keys))
+		}
+		_, src := cfg.Source.(Checksummer)
+		_, dst := cfg.Destination.(Checksummer)
+		if !src || !dst {
+			return fmt.Errorf("reconcile: entity %s: keys require both sides to implement Checksummer", e.Source)
+		}
+	}
+	return nil
+}
+
+// Run reconciles the entities of cfg
+// Mismatches are reported by the result, not as errors; the policy of cfg
+// is ignored.
+func Run(ctx context.Context, cfg Config) (Result, error) {
+	if err := cfg.check(); err != nil {
+		return Result{}, err
+	}
+	res := Result{Pipeline: cfg.Name, Matched: true, Entities: make([]EntityResult, len(cfg.Entities))}
+	for i, e := range cfg.Entities {
+		r, err := cfg.entity(ctx, e)
+		if err != nil {
+			return Result{}, err
+		}
+		res.Matched = res.Matched && r.Matched
+		res.Entities[i] = r
+	}
+	return res, nil
+}
+
+// entity reconciles e
+func (cfg Config) entity(ctx context.Context, e Entity) (EntityResult, error) {
+	dst, dstKeys := e.destination()
+	r := EntityResult{Source: e.Source, Destination: dst}
+
+	var err error
+	if r.SourceRows, err = cfg.Source.Count(ctx, e.Source); err != nil {
+		return r, fmt.Errorf("reconcile: count source %s: %w", e.Source, err)
+	}
+	if r.DestinationRows, err = cfg.Destination.Count(ctx, dst); err != nil {
+		return r, fmt.Errorf("reconcile: count destination %s: %w", dst, err)
+	}
+	r.Matched = r.SourceRows == r.DestinationRows
+	if len(e.Keys) == 0 {
+		return r, nil
+	}
+
+	srcSum, err := cfg.Source.(Checksummer).Checksum(ctx, e.Source, e.Keys)
+	if err != nil {
+		return r, fmt.Errorf("reconcile: checksum source %s: %w", e.Source, err)
+	}
+	dstSum, err := cfg.Destination.(Checksummer).Checksum(ctx, dst, dstKeys)
+	if err != nil {
+		return r, fmt.Errorf("reconcile: checksum destination %s: %w", dst, err)
+	}
+	r.ChecksumsChecked = true
+	r.ChecksumsMatched = srcSum == dstSum
+	r.Matched = r.Matched && r.ChecksumsMatched
+	return r, nil
+}
+
+// mismatched returns the results of the entities that did not match
+func (r Result) mismatched() []EntityResult {
+	var mismatched []EntityResult
+	for _, er := range r.Entities {
+		if !er.Matched {
+			mismatched = append(mismatched, er)
+		}
+	}
+	return mismatched
+}
+
+// Wrap reconciles the entities of cfg once processor has run, after its
+// PostProcess
+// A mismatch fails the run with a *MismatchError under the Fail policy, and
+// is logged under Warn; errors counting or checksumming fail the run under
+// either policy.
+//
+// The optional interfaces of processor, such as BatchCommitter and Resumer,
+// are kept (see etl.Wrapper).
+func Wrap[E, T any](processor etl.ETLProcessor[E, T], cfg Config) (etl.ETLProcessor[E, T], error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	r := &reconciler[E, T]{processor: processor, cfg: cfg}
+	return r, nil
+}
+
+// reconciler implements Wrap
+type reconciler[E, T any] struct {
+	processor etl.ETLProcessor[E, T]
+	cfg       Config
+	done      atomic.Bool // The run was reconciled
+}
+
+func (r *reconciler[E, T]) PreProcess(ctx context.Context) error {
+	r.done.Store(false)
+	return r.processor.PreProcess(ctx)
+}
+
+func (r *reconciler[E, T]) Extract(ctx context.Context) (<-chan etl.Payload[E], error) {
+	return r.processor.Extract(ctx)
+}
+
+func (r *reconciler[E, T]) Transform(ctx context.Context, e E) T {
+	return r.processor.Transform(ctx, e)
+}
+
+func (r *reconciler[E, T]) Load(ctx context.Context, data []T) error {
+	return r.processor.Load(ctx, data)
+}
+
+// PostProcess runs the wrapped processor's PostProcess, then reconciles
+// the run
+func (r *reconciler[E, T]) PostProcess(ctx context.Context) error {
+	if err := r.processor.PostProcess(ctx); err != nil {
+		return err
+	}
+	if r.done.Swap(true) {
+		return nil
+	}
+
+	result, err := Run(ctx, r.cfg)
+	if err != nil {
+		return err
+	}
+	logger := etl.LoggerFromContext(ctx)
+	if result.Matched {
+		logger.Info("Reconciled source and destination", "entities", len(result.Entities))
+		return nil
+	}
+	mismatch := &MismatchError{Pipeline: r.cfg.Name, Entities: result.mismatched()}
+	if r.cfg.Policy == Fail {
+		return mismatch
+	}
+	for _, er := range mismatch.Entities {
+		logger.Warn("Source and destination differ", "source", er.Source, "destination", er.Destination,
+			"source_rows", er.SourceRows, "destination_rows", er.DestinationRows,
+			"keys_checked", er.ChecksumsChecked, "keys_matched", er.ChecksumsMatched)
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped processor, whose optional interfaces are kept
+// (see etl.Wrapper)
+func (r *reconciler[E, T]) Unwrap() any {
+	return r.processor
+}
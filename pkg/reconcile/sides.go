@@ -0,0 +1,150 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Postgres returns the side of the tables of a PostgreSQL database
+// Entities are table names, optionally schema-qualified, and keys are
+// column names.
+func Postgres(pool *pgxpool.Pool) Side {
+	return postgresSide{pool: pool}
+}
+
+type postgresSide struct {
+	pool *pgxpool.Pool
+}
+
+func (s postgresSide) Count(ctx context.Context, entity string) (int64, error) {
+	var n int64
+	err := s.pool.QueryRow(ctx, "SELECT count(*) FROM "+pgx.Identifier(strings.Split(entity, ".")).Sanitize()).Scan(&n)
+	return n, err
+}
+
+func (s postgresSide) Checksum(ctx context.Context, entity string, keys []string) (uint64, error) {
+	columns := make([]string, len(keys))
+	for i, k := range keys {
+		columns[i] = pgx.Identifier{k}.Sanitize()
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), pgx.Identifier(strings.Split(entity, ".")).Sanitize())
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var c Checksum
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return 0, err
+		}
+		c.Add(values...)
+	}
+	return c.Sum(), rows.Err()
+}
+
+// SQL returns the side of the tables of a database/sql database
+// Entities are table names, optionally schema-qualified, and keys are
+// column names, both quoted with double quotes as in standard SQL; set
+// ANSI_QUOTES on MySQL.
+func SQL(db *sql.DB) Side {
+	return sqlSide{db: db}
+}
+
+type sqlSide struct {
+	db *sql.DB
+}
+
+func (s sqlSide) Count(ctx context.Context, entity string) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quote(strings.Split(entity, ".")...)).Scan(&n)
+	return n, err
+}
+
+func (s sqlSide) Checksum(ctx context.Context, entity string, keys []string) (uint64, error) {
+	columns := make([]string, len(keys))
+	for i, k := range keys {
+		columns[i] = quote(k)
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), quote(strings.Split(entity, ".")...)))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var c Checksum
+	values := make([]any, len(keys))
+	ptrs := make([]any, len(keys))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return 0, err
+		}
+		c.Add(values...)
+	}
+	return c.Sum(), rows.Err()
+}
+
+// quote quotes the parts of an identifier with double quotes
+func quote(parts ...string) string {
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// Mongo returns the side of the collections of a MongoDB database
+// Entities are collection names, and keys are field names; dots address
+// nested fields, e.g. "address.city".
+func Mongo(db *mongo.Database) Side {
+	return mongoSide{db: db}
+}
+
+type mongoSide struct {
+	db *mongo.Database
+}
+
+func (s mongoSide) Count(ctx context.Context, entity string) (int64, error) {
+	return s.db.Collection(entity).CountDocuments(ctx, bson.D{})
+}
+
+func (s mongoSide) Checksum(ctx context.Context, entity string, keys []string) (uint64, error) {
+	projection := bson.D{}
+	for _, k := range keys {
+		projection = append(projection, bson.E{Key: k, Value: 1})
+	}
+	cursor, err := s.db.Collection(entity).Find(ctx, bson.D{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var c Checksum
+	values := make([]any, len(keys))
+	for cursor.Next(ctx) {
+		for i, k := range keys {
+			values[i] = nil
+			if v, err := cursor.Current.LookupErr(strings.Split(k, ".")...); err == nil {
+				var decoded any
+				if err := v.Unmarshal(&decoded); err != nil {
+					return 0, err
+				}
+				values[i] = decoded
+			}
+		}
+		c.Add(values...)
+	}
+	return c.Sum(), cursor.Err()
+}
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": func(s float64) string {
+		return time.Duration(s * float64(time.Second)).Round(time.Millisecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ETL run {{.StartedAt.Format "2006-01-02 15:04:05"}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.succeeded { color: #2e7d32; }
+.failed, .error { color: #c62828; }
+</style>
+</head>
+<body>
+<h1>ETL run {{if .Succeeded}}<span class="succeeded">succeeded</span>{{else}}<span class="failed">failed</span>{{end}}</h1>
+<p>{{.StartedAt.Format "2006-01-02 15:04:05 MST"}}, {{seconds .DurationSeconds}}:
+{{.Totals.Succeeded}} of {{.Totals.Pipelines}} pipelines succeeded,
+{{.Totals.Loaded}} records loaded in {{.Totals.Batches}} batches,
+{{.Totals.DeadLettered}} dead-lettered, {{.Totals.Rejected}} rejected.</p>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+<table>
+<tr><th>Pipeline</th><th>State</th><th>Attempts</th><th>Duration</th><th>Extracted</th><th>Loaded</th><th>Dead-lettered</th><th>Rejected</th><th>Quality</th><th>Bottleneck</th><th>Checkpoint</th><th>Errors</th></tr>
+{{range .Pipelines}}<tr>
+<td>{{.Name}}</td>
+<td>{{.State}}</td>
+<td>{{.Attempts}}</td>
+<td>{{seconds .DurationSeconds}}</td>
+<td>{{.Extracted}}</td>
+<td>{{.Loaded}}</td>
+<td>{{.DeadLettered}}{{with .PendingDeadLetters}} ({{.}} pending){{end}}</td>
+<td>{{.Rejected}}{{range $rule, $n := .Violations}}<div>{{$rule}}: {{$n}}</div>{{end}}</td>
+<td>{{with .Quality}}{{if .Passed}}<span class="succeeded">passed</span>{{else}}<span class="failed">failed</span>{{end}}{{range .Rules}}{{if .Violations}}<div{{if .Failed}} class="failed"{{end}}>{{.Rule}}: {{.Violations}}</div>{{end}}{{end}}{{end}}</td>
+<td>{{.Stages.Bottleneck}}</td>
+<td>{{with .Checkpoint}}<code>{{printf "%s" .Position}}</code>{{end}}</td>
+<td>{{range .Errors}}<div class="error">{{.}}</div>{{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML renders rep as a standalone HTML page
+func WriteHTML(w io.Writer, rep *Report) error {
+	return htmlTemplate.Execute(w, rep)
+}
@@ -0,0 +1,309 @@
+// Package report writes a machine-readable summary of every manager run,
+// for archiving and for automated verification of migrations
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/dlq"
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/quality"
+)
+
+// Report summarizes a RunAll or Run call
+type Report struct {
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Succeeded       bool      `json:"succeeded"`
+	Error           string    `json:"error,omitempty"`
+
+	Totals    Totals     `json:"totals"`
+	Pipelines []Pipeline `json:"pipelines"`
+}
+
+// Totals sums the pipelines of a run
+type Totals struct {
+	Pipelines    int   `json:"pipelines"`
+	Succeeded    int   `json:"succeeded"`
+	Failed       int   `json:"failed"`
+	Errors       int   `json:"errors"`
+	Extracted    int64 `json:"extracted"`
+	Loaded       int64 `json:"loaded"`
+	Batches      int64 `json:"batches"`
+	DeadLettered int64 `json:"dead_lettered"`
+	Rejected     int64 `json:"rejected"`
+}
+
+// Pipeline summarizes one pipeline of a run
+type Pipeline struct {
+	Name            string  `json:"name"`
+	State           string  `json:"state"`
+	Attempts        int     `json:"attempts"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Extracted       int64   `json:"extracted"` // Counters of the last attempt
+	Loaded          int64   `json:"loaded"`
+	Batches         int64   `json:"batches"`
+
+	// DeadLettered counts the records dead-lettered in the run, and
+	// PendingDeadLetters the unreplayed entries of the dead letter store
+	DeadLettered       int64 `json:"dead_lettered"`
+	PendingDeadLetters *int  `json:"pending_dead_letters,omitempty"`
+
+	// Rejected counts the records the pipeline left out of the load, see
+	// etl.RejectRecords, and Violations the records of the run that broke
+	// each rule
+	Rejected   int64            `json:"rejected"`
+	Violations map[string]int64 `json:"violations,omitempty"`
+
+	Errors     []string               `json:"errors,omitempty"` // Failed attempts, oldest first
+	Stages     Stages                 `json:"stages"`
+	Checkpoint *checkpoint.Checkpoint `json:"checkpoint,omitempty"`
+	Quality    *quality.Result        `json:"quality,omitempty"` // Data quality checks of the run
+}
+
+// Stages is the time breakdown of a pipeline's last attempt, see
+// etl.StageTimings
+type Stages struct {
+	ExtractWaitSeconds float64 `json:"extract_wait_seconds"`
+	QueueWaitSeconds   float64 `json:"queue_wait_seconds"`
+	TransformSeconds   float64 `json:"transform_seconds"`
+	LoadSeconds        float64 `json:"load_seconds"`
+	Bottleneck         string  `json:"bottleneck,omitempty"`
+}
+
+// Config configures the reports of a manager
+type Config struct {
+	Path     string // JSON report file, replaced after every run
+	HTMLPath string // Optional HTML rendering of the report
+
+	// Checkpoints and DeadLetters add the checkpoint and the number of
+	// unreplayed dead letters of each pipeline; use the manager's stores
+	Checkpoints checkpoint.Store
+	DeadLetters dlq.Store
+
+	// Quality adds the data quality results of the pipelines checked with
+	// it (see quality.Config)
+	Quality *quality.Collector
+
+	Timeout time.Duration // For reading the stores (defaults to 10s)
+	Logger  *slog.Logger  // Logs failures to write a report (defaults to slog.Default())
+}
+
+// Attach writes a report when RunAll or Run of m returns
+// The report is written before they return, so a process exiting right
+// after a run keeps it.
+func Attach(m *etl.Manager, cfg Config) error {
+	if cfg.Path == "" && cfg.HTMLPath == "" {
+		return fmt.Errorf("report: Path or HTMLPath is required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	r := &reporter{
+		cfg:          cfg,
+		manager:      m,
+		errors:       make(map[string][]string),
+		deadLettered: make(map[string]int64),
+		violations:   make(map[string]map[string]int64),
+	}
+	m.OnEvent(r.handle)
+	return nil
+}
+
+// reporter collects the errors, dead letters and violations of a run from
+// events
+type reporter struct {
+	cfg     Config
+	manager *etl.Manager
+
+	mu           sync.Mutex
+	errors       map[string][]string
+	deadLettered map[string]int64
+	violations   map[string]map[string]int64 // By pipeline and rule
+}
+
+func (r *reporter) handle(e etl.Event) {
+	switch e.Type {
+	case etl.PipelineStarted:
+		if e.Attempt == 1 {
+			r.mu.Lock()
+			delete(r.errors, e.Pipeline)
+			delete(r.deadLettered, e.Pipeline)
+			delete(r.violations, e.Pipeline)
+			r.mu.Unlock()
+		}
+
+	case etl.BatchDeadLettered:
+		r.mu.Lock()
+		r.deadLettered[e.Pipeline] += int64(e.Records)
+		r.mu.Unlock()
+
+	case etl.RecordsRejected:
+		r.mu.Lock()
+		if r.violations[e.Pipeline] == nil {
+			r.violations[e.Pipeline] = make(map[string]int64)
+		}
+		r.violations[e.Pipeline][e.Rule] += int64(e.Records)
+		r.mu.Unlock()
+
+	case etl.PipelineRetrying, etl.PipelineFailed:
+		r.mu.Lock()
+		r.errors[e.Pipeline] = append(r.errors[e.Pipeline], fmt.Sprintf("attempt %d: %v", e.Attempt, e.Err))
+		r.mu.Unlock()
+
+	case etl.ManagerDone:
+		ctx, cancel := context.WithTimeout(context.Background(), r.cfg.Timeout)
+		defer cancel()
+
+		if err := r.write(r.build(ctx, r.manager.Metrics(), e.Err)); err != nil {
+			r.cfg.Logger.Error("Failed to write run report", "error", err)
+		}
+	}
+}
+
+// build assembles the report of a run
+func (r *reporter) build(ctx context.Context, metrics etl.RunMetrics, runErr error) *Report {
+	rep := &Report{
+		StartedAt:       metrics.StartedAt,
+		FinishedAt:      metrics.FinishedAt,
+		DurationSeconds: metrics.Duration.Seconds(),
+		Succeeded:       runErr == nil,
+		Pipelines:       make([]Pipeline, 0, len(metrics.Pipelines)),
+		Totals: Totals{
+			Pipelines: len(metrics.Pipelines),
+			Succeeded: metrics.Succeeded,
+			Failed:    metrics.Failed,
+			Errors:    metrics.Errors,
+			Extracted: metrics.Extracted,
+			Loaded:    metrics.Loaded,
+			Batches:   metrics.Batches,
+			Rejected:  metrics.Rejected,
+		},
+	}
+	if runErr != nil {
+		rep.Error = runErr.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, pm := range metrics.Pipelines {
+		p := Pipeline{
+			Name:            pm.Name,
+			State:           pm.State.String(),
+			Attempts:        pm.Attempts,
+			DurationSeconds: pm.Duration.Seconds(),
+			Extracted:       pm.Extracted,
+			Loaded:          pm.Loaded,
+			Batches:         pm.Batches,
+			DeadLettered:    r.deadLettered[pm.Name],
+			Rejected:        pm.Rejected,
+			Violations:      maps.Clone(r.violations[pm.Name]),
+			Errors:          r.errors[pm.Name],
+			Stages: Stages{
+				ExtractWaitSeconds: pm.Stages.ExtractWait.Seconds(),
+				QueueWaitSeconds:   pm.Stages.QueueWait.Seconds(),
+				TransformSeconds:   pm.Stages.Transform.Seconds(),
+				LoadSeconds:        pm.Stages.Load.Seconds(),
+				Bottleneck:         pm.Stages.Bottleneck(),
+			},
+		}
+
+		if r.cfg.Checkpoints != nil {
+			cp, err := r.cfg.Checkpoints.Get(ctx, pm.Name)
+			if err == nil {
+				p.Checkpoint = cp
+			} else if !errors.Is(err, checkpoint.ErrNotFound) {
+				r.cfg.Logger.Warn("Failed to read checkpoint for run report", "pipeline", pm.Name, "error", err)
+			}
+		}
+		if r.cfg.Quality != nil {
+			if result, ok := r.cfg.Quality.Result(pm.Name); ok {
+				p.Quality = &result
+			}
+		}
+		if r.cfg.DeadLetters != nil {
+			entries, err := r.cfg.DeadLetters.List(ctx, pm.Name, dlq.Filter{})
+			if err == nil {
+				pending := len(entries)
+				p.PendingDeadLetters = &pending
+			} else {
+				r.cfg.Logger.Warn("Failed to read dead letters for run report", "pipeline", pm.Name, "error", err)
+			}
+		}
+
+		rep.Totals.DeadLettered += p.DeadLettered
+		rep.Pipelines = append(rep.Pipelines, p)
+	}
+	return rep
+}
+
+// write writes the configured report files
+func (r *reporter) write(rep *Report) error {
+	if r.cfg.Path != "" {
+		err := writeFile(r.cfg.Path, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(rep)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if r.cfg.HTMLPath != "" {
+		return writeFile(r.cfg.HTMLPath, func(w io.Writer) error {
+			return WriteHTML(w, rep)
+		})
+	}
+	return nil
+}
+
+// Read loads a JSON report, e.g. to verify a migration in CI
+func Read(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	var rep Report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return nil, fmt.Errorf("report: decode %s: %w", path, err)
+	}
+	return &rep, nil
+}
+
+// writeFile replaces path atomically with what fn writes
+func writeFile(path string, fn func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".report-*")
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := fn(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("report: write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("report: write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	return nil
+}
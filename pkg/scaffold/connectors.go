@@ -0,0 +1,107 @@
+package scaffold
+
+// connector is the generated code of a source or sink type
+type connector struct {
+	Type     string
+	Imports  []string
+	GoType   string   // Type of the processor field holding the connector
+	Settings string   // Fields of its settings struct
+	Open     string   // Body of a function returning the connector, its close function or nil, and an error
+	YAML     string   // Its settings in config.yaml
+	Env      []string // Environment variables config.yaml refers to
+
+	// SampleFile, if set, is generated with the Sample content, so the
+	// pipeline runs out of the box
+	SampleFile string
+	Sample     string
+}
+
+var sources = map[string]connector{
+	"csv": {
+		Type:     "csv",
+		Imports:  []string{"github.com/cuong/go-etl/pkg/sources/csvsource"},
+		GoType:   "*csvsource.Source[Input]",
+		Settings: "Paths []string `yaml:\"paths\"` // Files or glob patterns",
+		Open:     "c, err := csvsource.New[Input](csvsource.Config{Paths: cfg.Paths})\nreturn c, nil, err",
+		YAML:     "paths: [input.csv]",
+
+		SampleFile: "input.csv",
+		Sample:     "id,name\n1, Ada Lovelace\n2,Alan Turing \n",
+	},
+	"jsonl": {
+		Type:     "jsonl",
+		Imports:  []string{"github.com/cuong/go-etl/pkg/sources/jsonlsource"},
+		GoType:   "*jsonlsource.Source[Input]",
+		Settings: "Paths []string `yaml:\"paths\"` // Files or glob patterns",
+		Open:     "c, err := jsonlsource.New[Input](jsonlsource.Config{Paths: cfg.Paths})\nreturn c, nil, err",
+		YAML:     "paths: [input.jsonl]",
+
+		SampleFile: "input.jsonl",
+		Sample:     "{\"id\": 1, \"name\": \" Ada Lovelace\"}\n{\"id\": 2, \"name\": \"Alan Turing \"}\n",
+	},
+	"postgres": {
+		Type: "postgres",
+		Imports: []string{
+			"github.com/cuong/go-etl/pkg/sources/pgsource",
+			"github.com/jackc/pgx/v5/pgxpool",
+		},
+		GoType:   "*pgsource.Source[Input]",
+		Settings: "DSN string `yaml:\"dsn\"`\nQuery string `yaml:\"query\"`",
+		Open: `pool, err := pgxpool.New(ctx, cfg.DSN)
+if err != nil {
+	return nil, nil, err
+}
+c, err := pgsource.New[Input](pgsource.Config[Input]{DB: pool, Query: cfg.Query})
+if err != nil {
+	pool.Close()
+	return nil, nil, err
+}
+return c, func() error { pool.Close(); return nil }, nil`,
+		YAML: "dsn: ${SOURCE_DSN}\n  query: SELECT id, name FROM people",
+		Env:  []string{"SOURCE_DSN"},
+	},
+}
+
+var sinks = map[string]connector{
+	"stdout": {
+		Type:     "stdout",
+		Imports:  []string{"github.com/cuong/go-etl/pkg/sinks/stdoutsink"},
+		GoType:   "*stdoutsink.Sink[Output]",
+		Settings: "Format string `yaml:\"format\"` // jsonl or csv",
+		Open:     "c, err := stdoutsink.New[Output](stdoutsink.Config{Format: stdoutsink.Format(cfg.Format)})\nreturn c, nil, err",
+		YAML:     "format: jsonl",
+	},
+	"file": {
+		Type:     "file",
+		Imports:  []string{"github.com/cuong/go-etl/pkg/sinks/filesink"},
+		GoType:   "*filesink.Sink[Output]",
+		Settings: "Dir string `yaml:\"dir\"`\nName string `yaml:\"name\"` // File name prefix\nFormat string `yaml:\"format\"` // jsonl, csv or parquet",
+		Open: `c, err := filesink.New[Output](filesink.Config{Dir: cfg.Dir, Name: cfg.Name, Format: filesink.Format(cfg.Format)})
+if err != nil {
+	return nil, nil, err
+}
+return c, c.Close, nil`,
+		YAML: "dir: out\n  name: output\n  format: jsonl",
+	},
+	"postgres": {
+		Type: "postgres",
+		Imports: []string{
+			"github.com/cuong/go-etl/pkg/sinks/pgsink",
+			"github.com/jackc/pgx/v5/pgxpool",
+		},
+		GoType:   "*pgsink.Sink[Output]",
+		Settings: "DSN string `yaml:\"dsn\"`\nTable string `yaml:\"table\"`",
+		Open: `pool, err := pgxpool.New(ctx, cfg.DSN)
+if err != nil {
+	return nil, nil, err
+}
+c, err := pgsink.New[Output](pgsink.Config{DB: pool, Table: cfg.Table})
+if err != nil {
+	pool.Close()
+	return nil, nil, err
+}
+return c, func() error { pool.Close(); return nil }, nil`,
+		YAML: "dsn: ${SINK_DSN}\n  table: people",
+		Env:  []string{"SINK_DSN"},
+	},
+}
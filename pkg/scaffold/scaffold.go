@@ -0,0 +1,196 @@
+// Package scaffold generates the skeleton of a new pipeline program: a
+// processor with typed Extract, Transform and Load stubs for a chosen source
+// and sink, its settings file and tests, as a starting point that builds and
+// runs before it is filled in
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+
+// Options select what to generate
+type Options struct {
+	Dir    string // Directory to generate into, created if missing
+	Name   string // Pipeline name (defaults to the base name of Dir)
+	Source string // Source type, see Sources (defaults to "csv")
+	Sink   string // Sink type, see Sinks (defaults to "stdout")
+
+	// Module writes a go.mod declaring this module path; leave it empty to
+	// generate into an existing module
+	Module string
+
+	// Force overwrites existing files
+	Force bool
+}
+
+// defaultGoVersion is the go.mod language version when the toolchain's is
+// unknown
+const defaultGoVersion = "1.25"
+
+// namePattern matches valid pipeline names
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// Generate writes the skeleton and returns the paths of the files written
+// It fails without writing anything if a file exists, unless opts.Force is
+// set.
+func Generate(opts Options) ([]string, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("scaffold: directory is required")
+	}
+	if opts.Name == "" {
+		abs, err := filepath.Abs(opts.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("scaffold: %w", err)
+		}
+		opts.Name = strings.ToLower(filepath.Base(abs))
+	}
+	if !namePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf("scaffold: invalid pipeline name %q, use lowercase letters, digits, - and _", opts.Name)
+	}
+	if opts.Source == "" {
+		opts.Source = "csv"
+	}
+	if opts.Sink == "" {
+		opts.Sink = "stdout"
+	}
+	src, ok := sources[opts.Source]
+	if !ok {
+		return nil, fmt.Errorf("scaffold: unknown source type %q, want one of %s", opts.Source, strings.Join(Sources(), ", "))
+	}
+	sink, ok := sinks[opts.Sink]
+	if !ok {
+		return nil, fmt.Errorf("scaffold: unknown sink type %q, want one of %s", opts.Sink, strings.Join(Sinks(), ", "))
+	}
+
+	data := templateData{Name: opts.Name, Module: opts.Module, Source: src, Sink: sink}
+	files := map[string]string{
+		"main.go":           "main.go.tmpl",
+		"processor.go":      "processor.go.tmpl",
+		"processor_test.go": "processor_test.go.tmpl",
+		"config.yaml":       "config.yaml.tmpl",
+	}
+	if opts.Module != "" {
+		files["go.mod"] = "go.mod.tmpl"
+	}
+	for _, c := range []connector{src, sink} {
+		if c.Sample != "" {
+			files[c.SampleFile] = ""
+		}
+	}
+
+	// Render everything before writing anything
+	rendered := make(map[string][]byte, len(files))
+	for name, tmpl := range files {
+		path := filepath.Join(opts.Dir, name)
+		if _, err := os.Stat(path); err == nil && !opts.Force {
+			return nil, fmt.Errorf("scaffold: %s already exists", path)
+		}
+
+		if tmpl == "" {
+			for _, c := range []connector{src, sink} {
+				if c.SampleFile == name {
+					rendered[name] = []byte(c.Sample)
+				}
+			}
+			continue
+		}
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, tmpl, data); err != nil {
+			return nil, fmt.Errorf("scaffold: %s: %w", name, err)
+		}
+		out := buf.Bytes()
+		if filepath.Ext(name) == ".go" {
+			formatted, err := format.Source(out)
+			if err != nil {
+				return nil, fmt.Errorf("scaffold: %s: %w", name, err)
+			}
+			out = formatted
+		}
+		rendered[name] = out
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	var written []string
+	for name, content := range rendered {
+		path := filepath.Join(opts.Dir, name)
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return written, fmt.Errorf("scaffold: %w", err)
+		}
+		written = append(written, path)
+	}
+	sort.Strings(written)
+	return written, nil
+}
+
+// Sources returns the source types Generate supports, sorted
+func Sources() []string {
+	return sortedKeys(sources)
+}
+
+// Sinks returns the sink types Generate supports, sorted
+func Sinks() []string {
+	return sortedKeys(sinks)
+}
+
+// templateData is the data of the templates
+type templateData struct {
+	Name   string
+	Module string
+	Source connector
+	Sink   connector
+}
+
+// EnvVars returns the environment variables config.yaml refers to
+func (d templateData) EnvVars() []string {
+	return append(append([]string(nil), d.Source.Env...), d.Sink.Env...)
+}
+
+// GoVersion returns the language version of the generated go.mod, that of
+// the running toolchain
+func (d templateData) GoVersion() string {
+	parts := strings.SplitN(strings.TrimPrefix(runtime.Version(), "go"), ".", 3)
+	if len(parts) < 2 {
+		return defaultGoVersion
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// Imports returns the imports of the connectors, sorted and deduplicated
+func (d templateData) Imports() []string {
+	seen := make(map[string]bool)
+	var imports []string
+	for _, imp := range append(d.Source.Imports, d.Sink.Imports...) {
+		if !seen[imp] {
+			seen[imp] = true
+			imports = append(imports, imp)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+func sortedKeys(m map[string]connector) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,120 @@
+package schemadrift
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Field is a top-level field of the records and the kind of its values
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // "bool", "int", "float", "string", "time", "bytes", "object", "array" or a Go type name
+}
+
+// FieldChange is a field whose values changed kind
+type FieldChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Change is the drift of the source schema from the tracked one
+type Change struct {
+	Added   []Field       `json:"added,omitempty"`
+	Removed []Field       `json:"removed,omitempty"`
+	Changed []FieldChange `json:"changed,omitempty"`
+}
+
+// IsZero reports whether c holds no change
+func (c Change) IsZero() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+func (c Change) String() string {
+	var parts []string
+	for _, f := range c.Added {
+		parts = append(parts, fmt.Sprintf("+%s %s", f.Name, f.Type))
+	}
+	for _, f := range c.Removed {
+		parts = append(parts, fmt.Sprintf("-%s %s", f.Name, f.Type))
+	}
+	for _, f := range c.Changed {
+		parts = append(parts, fmt.Sprintf("~%s %s->%s", f.Name, f.From, f.To))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DriftError reports a schema change under the Fail policy
+type DriftError struct {
+	Pipeline string
+	Change   Change
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("schemadrift: schema of %s changed: %s", e.Pipeline, e.Change)
+}
+
+// RecordFields returns the fields of a schemaless record, skipping nil
+// values, whose kind is unknown
+func RecordFields(rec map[string]any) []Field {
+	fields := make([]Field, 0, len(rec))
+	for name, v := range rec {
+		if t := TypeOf(v); t != "" {
+			fields = append(fields, Field{Name: name, Type: t})
+		}
+	}
+	return fields
+}
+
+// TypeOf returns the kind of a field value, or "" for nil
+func TypeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return ""
+	case time.Time, *time.Time:
+		return "time"
+	case []byte:
+		return "bytes"
+	case json.Number:
+		return "float"
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return rv.Type().String()
+	}
+}
+
+// sortedFields returns the fields of a name -> type map sorted by name
+func sortedFields(types map[string]string) []Field {
+	fields := make([]Field, 0, len(types))
+	for name, t := range types {
+		fields = append(fields, Field{Name: name, Type: t})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
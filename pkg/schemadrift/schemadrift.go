@@ -0,0 +1,298 @@
+// Package schemadrift tracks the fields of a pipeline's source records
+// between runs and applies a policy when upstream adds, removes or retypes
+// fields
+package schemadrift
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Policy decides what a schema change does to a run
+type Policy int
+
+const (
+	// Fail stops the run with a *DriftError; the tracked schema is kept
+	// until an operator migrates the destination and resets it
+	Fail Policy = iota
+
+	// Ignore logs the change and keeps loading; new fields are tracked and
+	// fields missing from a run are kept, as sparse records may omit them
+	Ignore
+
+	// Migrate calls Config.Migrate with the change, e.g. to add a nullable
+	// column, and tracks the new schema once it succeeded
+	Migrate
+)
+
+// Config configures schema tracking
+type Config[E any] struct {
+	// Store keeps the tracked schema as the checkpoint "schema:<Name>"
+	Store checkpoint.Store
+	Name  string // Pipeline name
+
+	// Fields returns the fields of a record; optional when E is
+	// map[string]any, where RecordFields is used
+	Fields func(E) []Field
+
+	Policy Policy
+
+	// Migrate adapts the destination to a change under the Migrate policy
+	// New and retyped fields are migrated before the first record carrying
+	// them is handed to the bucket; removed fields, known only once the
+	// source is exhausted, are migrated in PostProcess. Migrate must be
+	// idempotent, as a failed run migrates again.
+	Migrate func(ctx context.Context, change Change) error
+}
+
+// Wrap tracks the schema of processor's records
+// The first run records the schema without applying the policy. Later runs
+// compare every record to it: added and retyped fields are detected before
+// the record is loaded, and tracked fields no record of a run carried are
+// reported as removed after the run. An int field seen where a float is
+// tracked is not a change.
+//
+// The optional interfaces of processor, such as BatchCommitter and Resumer,
+// are kept (see etl.Wrapper).
+func Wrap[E, T any](processor etl.ETLProcessor[E, T], cfg Config[E]) (etl.ETLProcessor[E, T], error) {
+	if cfg.Store == nil || cfg.Name == "" {
+		return nil, fmt.Errorf("schemadrift: Store and Name are required")
+	}
+	if cfg.Fields == nil {
+		var zero E
+		if _, ok := any(zero).(map[string]any); !ok {
+			return nil, fmt.Errorf("schemadrift: Fields is required for %T records", zero)
+		}
+		cfg.Fields = func(e E) []Field {
+			return RecordFields(any(e).(map[string]any))
+		}
+	}
+	if cfg.Policy == Migrate && cfg.Migrate == nil {
+		return nil, fmt.Errorf("schemadrift: the Migrate policy requires Migrate")
+	}
+
+	return &tracker[E, T]{processor: processor, cfg: cfg}, nil
+}
+
+// tracker implements Wrap
+type tracker[E, T any] struct {
+	processor etl.ETLProcessor[E, T]
+	cfg       Config[E]
+
+	mu       sync.Mutex
+	known    map[string]string // Tracked schema, nil before the first run
+	baseline bool              // The run records the first schema
+	seen     map[string]bool   // Fields carried by records of this run
+	err      error             // Change that stopped the run
+}
+
+func (t *tracker[E, T]) PreProcess(ctx context.Context) error {
+	return t.processor.PreProcess(ctx)
+}
+
+// Extract loads the tracked schema, then checks every record of the
+// wrapped processor against it
+func (t *tracker[E, T]) Extract(ctx context.Context) (<-chan etl.Payload[E], error) {
+	known, err := t.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.known, t.baseline, t.err = known, known == nil, nil
+	if t.known == nil {
+		t.known = make(map[string]string)
+	}
+	t.seen = make(map[string]bool)
+	t.mu.Unlock()
+
+	in, err := t.processor.Extract(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan etl.Payload[E], cap(in))
+	go func() {
+		defer close(out)
+
+		for p := range in {
+			if p.Err == nil {
+				if err := t.check(ctx, t.cfg.Fields(p.Data)); err != nil {
+					p = etl.Payload[E]{Err: err}
+				}
+			}
+
+			select {
+			case out <- p:
+			case <-ctx.Done():
+				return
+			}
+			if p.Err != nil && !etl.IsRecordError(p.Err) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// check compares the fields of a record to the tracked schema
+func (t *tracker[E, T]) check(ctx context.Context, fields []Field) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var change Change
+	for _, f := range fields {
+		t.seen[f.Name] = true
+		known, ok := t.known[f.Name]
+		switch {
+		case !ok:
+			change.Added = append(change.Added, f)
+		case known != f.Type && !(known == "float" && f.Type == "int"):
+			change.Changed = append(change.Changed, FieldChange{Name: f.Name, From: known, To: f.Type})
+		}
+	}
+	if change.IsZero() {
+		return nil
+	}
+	if t.baseline {
+		for _, f := range change.Added {
+			t.known[f.Name] = f.Type
+		}
+		for _, f := range change.Changed {
+			t.known[f.Name] = f.To // The first record decides
+		}
+		return nil
+	}
+	return t.apply(ctx, change)
+}
+
+// apply handles a change according to the policy
+// Callers hold t.mu.
+func (t *tracker[E, T]) apply(ctx context.Context, change Change) error {
+	log := etl.LoggerFromContext(ctx)
+	switch t.cfg.Policy {
+	case Ignore:
+		log.Warn("Source schema changed", "change", change.String())
+
+	case Migrate:
+		log.Info("Migrating destination for schema change", "change", change.String())
+		if err := t.cfg.Migrate(ctx, change); err != nil {
+			t.err = fmt.Errorf("schemadrift: migrate %s: %w", change, err)
+			return t.err
+		}
+
+	default:
+		t.err = &DriftError{Pipeline: t.cfg.Name, Change: change}
+		return t.err
+	}
+
+	for _, f := range change.Added {
+		t.known[f.Name] = f.Type
+	}
+	for _, f := range change.Changed {
+		t.known[f.Name] = f.To
+	}
+	for _, f := range change.Removed {
+		delete(t.known, f.Name)
+	}
+	return t.save(ctx)
+}
+
+func (t *tracker[E, T]) Transform(ctx context.Context, e E) T {
+	return t.processor.Transform(ctx, e)
+}
+
+func (t *tracker[E, T]) Load(ctx context.Context, data []T) error {
+	return t.processor.Load(ctx, data)
+}
+
+// Unwrap returns the wrapped processor, whose optional interfaces are kept
+// (see etl.Wrapper)
+func (t *tracker[E, T]) Unwrap() any {
+	return t.processor
+}
+
+// PostProcess runs the wrapped processor's PostProcess, then handles the
+// fields no record of the run carried and saves the schema
+// A change that stopped extraction is returned here too, so the schema is
+// never saved past it.
+func (t *tracker[E, T]) PostProcess(ctx context.Context) error {
+	if err := t.processor.PostProcess(ctx); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return t.err
+	}
+	if t.known == nil || len(t.seen) == 0 {
+		return nil
+	}
+	if t.baseline {
+		etl.LoggerFromContext(ctx).Info("Recorded source schema", "fields", len(t.known))
+		return t.save(ctx)
+	}
+
+	var change Change
+	for _, f := range sortedFields(t.known) {
+		if !t.seen[f.Name] {
+			change.Removed = append(change.Removed, f)
+		}
+	}
+	if change.IsZero() || t.cfg.Policy == Ignore {
+		return t.save(ctx)
+	}
+	return t.apply(ctx, change)
+}
+
+// key is the checkpoint the schema is stored as
+func (t *tracker[E, T]) key() string {
+	return "schema:" + t.cfg.Name
+}
+
+// load reads the tracked schema, nil if there is none yet
+func (t *tracker[E, T]) load(ctx context.Context) (map[string]string, error) {
+	cp, err := t.cfg.Store.Get(ctx, t.key())
+	if errors.Is(err, checkpoint.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("schemadrift: read schema: %w", err)
+	}
+
+	var fields []Field
+	if err := json.Unmarshal(cp.Position, &fields); err != nil {
+		return nil, fmt.Errorf("schemadrift: decode schema of %s: %w", t.cfg.Name, err)
+	}
+	known := make(map[string]string, len(fields))
+	for _, f := range fields {
+		known[f.Name] = f.Type
+	}
+	return known, nil
+}
+
+// save writes the tracked schema
+// Callers hold t.mu.
+func (t *tracker[E, T]) save(ctx context.Context) error {
+	data, err := json.Marshal(sortedFields(t.known))
+	if err != nil {
+		return err
+	}
+	err = t.cfg.Store.Set(ctx, &checkpoint.Checkpoint{
+		Pipeline:  t.key(),
+		Position:  data,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("schemadrift: save schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package schemaregistry talks to a Confluent-compatible schema registry and
+// encodes Avro records in its wire format
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// Config configures a schema registry client
+type Config struct {
+	URL      string // Base URL, e.g. http://localhost:8081
+	Username string // Basic auth, optional
+	Password string
+
+	HTTPClient *http.Client // Defaults to a client with a 10s timeout
+}
+
+// Client fetches and registers schemas, caching them by ID and subject
+type Client struct {
+	cfg Config
+
+	mu       sync.RWMutex
+	byID     map[int]avro.Schema
+	subjects map[string]int // subject + "\x00" + schema -> ID
+}
+
+// NewClient creates a schema registry client
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("schemaregistry: URL is required")
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Client{
+		cfg:      cfg,
+		byID:     make(map[int]avro.Schema),
+		subjects: make(map[string]int),
+	}, nil
+}
+
+// schemaResponse is the subset of the registry's schema payload we use
+type schemaResponse struct {
+	ID         int    `json:"id"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+// Schema returns the schema registered under id
+func (c *Client) Schema(ctx context.Context, id int) (avro.Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.byID[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var resp schemaResponse
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.SchemaType != "" && resp.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schemaregistry: schema %d is %s, not AVRO", id, resp.SchemaType)
+	}
+
+	schema, err := avro.Parse(resp.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("schemaregistry: parse schema %d: %w", id, err)
+	}
+
+	c.mu.Lock()
+	c.byID[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// Register registers schema under subject and returns its ID
+// Registering a schema the subject already has returns the existing ID.
+func (c *Client) Register(ctx context.Context, subject string, schema avro.Schema) (int, error) {
+	key := subject + "\x00" + schema.String()
+
+	c.mu.RLock()
+	id, ok := c.subjects[key]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	body := map[string]string{"schema": schema.String()}
+	var resp schemaResponse
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := c.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.subjects[key] = resp.ID
+	c.byID[resp.ID] = schema
+	c.mu.Unlock()
+	return resp.ID, nil
+}
+
+// Latest returns the ID and schema of the latest version of subject
+func (c *Client) Latest(ctx context.Context, subject string) (int, avro.Schema, error) {
+	var resp schemaResponse
+	path := "/subjects/" + url.PathEscape(subject) + "/versions/latest"
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return 0, nil, err
+	}
+
+	schema, err := avro.Parse(resp.Schema)
+	if err != nil {
+		return 0, nil, fmt.Errorf("schemaregistry: parse schema %d: %w", resp.ID, err)
+	}
+
+	c.mu.Lock()
+	c.byID[resp.ID] = schema
+	c.mu.Unlock()
+	return resp.ID, schema, nil
+}
+
+// do sends a request to the registry and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("schemaregistry: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("schemaregistry: %s %s: %s (code %d)", method, path, apiErr.Message, apiErr.ErrorCode)
+		}
+		return fmt.Errorf("schemaregistry: %s %s: %s", method, path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
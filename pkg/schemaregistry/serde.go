@@ -0,0 +1,88 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+)
+
+// magicByte prefixes every message in the registry wire format
+const magicByte = 0
+
+// ErrNotWireFormat is returned for messages without the wire format header
+var ErrNotWireFormat = errors.New("schemaregistry: message is not in wire format")
+
+// Encode frames Avro-encoded data with the wire format header for id
+func Encode(id int, data []byte) []byte {
+	msg := make([]byte, 5, 5+len(data))
+	msg[0] = magicByte
+	binary.BigEndian.PutUint32(msg[1:], uint32(id))
+	return append(msg, data...)
+}
+
+// Decode splits a wire format message into its schema ID and Avro data
+func Decode(msg []byte) (int, []byte, error) {
+	if len(msg) < 5 || msg[0] != magicByte {
+		return 0, nil, ErrNotWireFormat
+	}
+	return int(binary.BigEndian.Uint32(msg[1:5])), msg[5:], nil
+}
+
+// Deserializer decodes wire format messages into T using the writer schema
+// looked up in the registry
+type Deserializer[T any] struct {
+	client *Client
+}
+
+// NewDeserializer creates a deserializer backed by client
+func NewDeserializer[T any](client *Client) *Deserializer[T] {
+	return &Deserializer[T]{client: client}
+}
+
+// Deserialize decodes one message
+func (d *Deserializer[T]) Deserialize(ctx context.Context, msg []byte) (T, error) {
+	var v T
+
+	id, data, err := Decode(msg)
+	if err != nil {
+		return v, err
+	}
+	schema, err := d.client.Schema(ctx, id)
+	if err != nil {
+		return v, err
+	}
+	if err := avro.Unmarshal(schema, data, &v); err != nil {
+		return v, fmt.Errorf("schemaregistry: decode with schema %d: %w", id, err)
+	}
+	return v, nil
+}
+
+// Serializer encodes T in the wire format, registering its schema under a
+// subject on first use
+type Serializer[T any] struct {
+	client  *Client
+	subject string
+	schema  avro.Schema
+}
+
+// NewSerializer creates a serializer that writes schema under subject
+// Kafka pipelines conventionally use "<topic>-value" as the subject.
+func NewSerializer[T any](client *Client, subject string, schema avro.Schema) *Serializer[T] {
+	return &Serializer[T]{client: client, subject: subject, schema: schema}
+}
+
+// Serialize encodes one value
+func (s *Serializer[T]) Serialize(ctx context.Context, v T) ([]byte, error) {
+	id, err := s.client.Register(ctx, s.subject, s.schema)
+	if err != nil {
+		return nil, err
+	}
+	data, err := avro.Marshal(s.schema, v)
+	if err != nil {
+		return nil, fmt.Errorf("schemaregistry: encode: %w", err)
+	}
+	return Encode(id, data), nil
+}
@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// SecretsManagerClient is the subset of *secretsmanager.Client the provider
+// uses
+type SecretsManagerClient interface {
+	GetSecretValue(ctx context.Context, in *secretsmanager.GetSecretValueInput, opts ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// AWSSecretsManager reads secrets from AWS Secrets Manager
+// References are "name" or "name#key": without a key the secret string is
+// returned as is, with one the secret string is decoded as a JSON object
+// and the field is returned.
+type AWSSecretsManager struct {
+	Client SecretsManagerClient
+}
+
+func (a *AWSSecretsManager) Secret(ctx context.Context, ref string) (string, error) {
+	if a.Client == nil {
+		return "", fmt.Errorf("awssm: Client is required")
+	}
+
+	name, key := splitKey(ref)
+	out, err := a.Client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &name})
+	if err != nil {
+		return "", fmt.Errorf("awssm: %w", err)
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("awssm: %s has no secret string", name)
+	}
+	if key == "" {
+		return *out.SecretString, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(*out.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("awssm: %s is not a JSON object: %w", name, err)
+	}
+	return pickField(fields, key)
+}
@@ -0,0 +1,121 @@
+// Package secrets resolves secret references, such as
+// vault:secret/data/db#password, so credentials stay out of config files
+//
+// References name a provider by scheme. DefaultRegistry has a Vault
+// provider configured from VAULT_ADDR and VAULT_TOKEN under "vault";
+// programs register other providers, e.g. AWS Secrets Manager under
+// "awssm":
+//
+//	secrets.Register("awssm", &secrets.AWSSecretsManager{Client: secretsmanager.NewFromConfig(awsCfg)})
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Provider looks up secrets
+type Provider interface {
+	// Secret returns the secret at ref, the part of a reference after the
+	// scheme, e.g. "secret/data/db#password"
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+// ProviderFunc adapts a function to Provider
+type ProviderFunc func(ctx context.Context, ref string) (string, error)
+
+func (f ProviderFunc) Secret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// Registry maps schemes to providers
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// DefaultRegistry is used by the package-level helpers
+var DefaultRegistry = func() *Registry {
+	r := NewRegistry()
+	r.providers["vault"] = &Vault{}
+	return r
+}()
+
+// Register adds a provider for scheme, failing if the scheme is taken
+func (r *Registry) Register(scheme string, p Provider) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[scheme]; exists {
+		return fmt.Errorf("secret provider %q already registered", scheme)
+	}
+	r.providers[scheme] = p
+	return nil
+}
+
+// Has reports whether a provider is registered for scheme
+func (r *Registry) Has(scheme string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.providers[scheme]
+	return ok
+}
+
+// Schemes returns the registered schemes, sorted
+func (r *Registry) Schemes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemes := make([]string, 0, len(r.providers))
+	for s := range r.providers {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Resolve looks up a reference of the form scheme:ref
+func (r *Registry) Resolve(ctx context.Context, reference string) (string, error) {
+	scheme, ref, ok := strings.Cut(reference, ":")
+	if !ok {
+		return "", fmt.Errorf("secrets: %q is not a scheme:ref reference", reference)
+	}
+
+	r.mu.RLock()
+	p, ok := r.providers[scheme]
+	r.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("secrets: no provider registered for %q", scheme)
+	}
+	secret, err := p.Secret(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("secrets: %s: %w", reference, err)
+	}
+	return secret, nil
+}
+
+// Register adds a provider to the default registry
+func Register(scheme string, p Provider) error {
+	return DefaultRegistry.Register(scheme, p)
+}
+
+// Resolve looks up a reference with the default registry
+func Resolve(ctx context.Context, reference string) (string, error) {
+	return DefaultRegistry.Resolve(ctx, reference)
+}
+
+// splitKey splits "path#key" into its path and key
+func splitKey(ref string) (path, key string) {
+	path, key, _ = strings.Cut(ref, "#")
+	return path, key
+}
@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault reads secrets from HashiCorp Vault over its HTTP API
+// References are "path#key", e.g. "secret/data/db#password"; KV version 2
+// paths include the data/ segment. The key selects a field of the secret
+// and may be omitted when it has exactly one.
+type Vault struct {
+	Addr       string       // Defaults to VAULT_ADDR
+	Token      string       // Defaults to VAULT_TOKEN
+	Namespace  string       // Defaults to VAULT_NAMESPACE, for Vault Enterprise
+	HTTPClient *http.Client // Defaults to a client with a 10s timeout
+}
+
+func (v *Vault) Secret(ctx context.Context, ref string) (string, error) {
+	addr := firstNonEmpty(v.Addr, os.Getenv("VAULT_ADDR"))
+	token := firstNonEmpty(v.Token, os.Getenv("VAULT_TOKEN"))
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("vault: address and token are required (VAULT_ADDR, VAULT_TOKEN)")
+	}
+	client := v.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	path, key := splitKey(ref)
+	endpoint, err := url.JoinPath(addr, "v1", strings.TrimPrefix(path, "/"))
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := firstNonEmpty(v.Namespace, os.Getenv("VAULT_NAMESPACE")); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("vault: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault: decode response: %w", err)
+	}
+
+	// KV version 2 nests the fields under data.data
+	fields := body.Data
+	if nested, ok := fields["data"].(map[string]any); ok {
+		if _, hasMeta := fields["metadata"]; hasMeta {
+			fields = nested
+		}
+	}
+	return pickField(fields, key)
+}
+
+// pickField returns the field key of a secret, or its only field when key
+// is empty
+func pickField(fields map[string]any, key string) (string, error) {
+	if key == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("secret has %d fields, select one with #key", len(fields))
+		}
+		for _, v := range fields {
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
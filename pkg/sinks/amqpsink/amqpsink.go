@@ -0,0 +1,197 @@
+// Package amqpsink publishes records to a RabbitMQ exchange
+package amqpsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Serializer encodes a record as a message body
+type Serializer[T any] func(ctx context.Context, v T) ([]byte, error)
+
+// JSON encodes records as JSON
+func JSON[T any]() Serializer[T] {
+	return func(_ context.Context, v T) ([]byte, error) {
+		return json.Marshal(v)
+	}
+}
+
+// Config configures an AMQP sink
+type Config[T any] struct {
+	URL      string
+	Exchange string // "" publishes through the default exchange
+
+	// RoutingKey picks each record's routing key; defaults to Key
+	RoutingKey func(T) string
+	Key        string
+
+	// Serializer encodes records (defaults to JSON)
+	Serializer  Serializer[T]
+	ContentType string // Defaults to application/json
+
+	Persistent bool // Publish with delivery mode 2 so messages survive broker restarts
+	Mandatory  bool // Fail records that no queue is bound to receive
+}
+
+// Sink publishes each batch and waits for the broker to confirm all of it
+type Sink[T any] struct {
+	cfg Config[T]
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+	ch   *amqp.Channel
+
+	returnsMu sync.Mutex
+	returns   []amqp.Return // Unroutable messages of the current batch
+}
+
+// New creates an AMQP sink; it connects on the first Load
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("amqpsink: URL is required")
+	}
+	if cfg.RoutingKey == nil {
+		key := cfg.Key
+		cfg.RoutingKey = func(T) string { return key }
+	}
+	if cfg.Serializer == nil {
+		cfg.Serializer = JSON[T]()
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/json"
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load publishes a batch with publisher confirms
+// It fails if the broker nacks any message or, with Mandatory set, returns
+// one as unroutable; the whole batch is then safe to retry.
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, err := s.channel()
+	if err != nil {
+		return err
+	}
+
+	mode := amqp.Transient
+	if s.cfg.Persistent {
+		mode = amqp.Persistent
+	}
+
+	s.takeReturns()
+
+	confirms := make([]*amqp.DeferredConfirmation, 0, len(items))
+	for _, item := range items {
+		body, err := s.cfg.Serializer(ctx, item)
+		if err != nil {
+			return fmt.Errorf("amqpsink: serialize: %w", err)
+		}
+
+		dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, s.cfg.Exchange, s.cfg.RoutingKey(item), s.cfg.Mandatory, false, amqp.Publishing{
+			ContentType:  s.cfg.ContentType,
+			DeliveryMode: mode,
+			Body:         body,
+		})
+		if err != nil {
+			s.reset()
+			return fmt.Errorf("amqpsink: publish: %w", err)
+		}
+		confirms = append(confirms, dc)
+	}
+
+	nacked := 0
+	for _, dc := range confirms {
+		acked, err := dc.WaitContext(ctx)
+		if err != nil {
+			s.reset()
+			return fmt.Errorf("amqpsink: wait for confirms: %w", err)
+		}
+		if !acked {
+			nacked++
+		}
+	}
+	if nacked > 0 {
+		return fmt.Errorf("amqpsink: broker nacked %d of %d messages", nacked, len(items))
+	}
+
+	// The broker sends a message's return before its confirm
+	if returns := s.takeReturns(); len(returns) > 0 {
+		return fmt.Errorf("amqpsink: %d messages unroutable (first: %s %s)", len(returns), returns[0].ReplyText, returns[0].RoutingKey)
+	}
+	return nil
+}
+
+// collectReturns records returned messages until the channel closes
+func (s *Sink[T]) collectReturns(returned <-chan amqp.Return) {
+	for r := range returned {
+		s.returnsMu.Lock()
+		s.returns = append(s.returns, r)
+		s.returnsMu.Unlock()
+	}
+}
+
+// takeReturns returns and clears the recorded returns
+func (s *Sink[T]) takeReturns() []amqp.Return {
+	s.returnsMu.Lock()
+	defer s.returnsMu.Unlock()
+
+	returns := s.returns
+	s.returns = nil
+	return returns
+}
+
+// channel returns the confirm-mode channel, connecting if needed
+func (s *Sink[T]) channel() (*amqp.Channel, error) {
+	if s.ch != nil && !s.ch.IsClosed() {
+		return s.ch, nil
+	}
+	if s.conn == nil || s.conn.IsClosed() {
+		conn, err := amqp.Dial(s.cfg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("amqpsink: dial: %w", err)
+		}
+		s.conn = conn
+	}
+
+	ch, err := s.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("amqpsink: open channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("amqpsink: enable confirms: %w", err)
+	}
+	if s.cfg.Mandatory {
+		go s.collectReturns(ch.NotifyReturn(make(chan amqp.Return, 1)))
+	}
+	s.ch = ch
+	return ch, nil
+}
+
+// reset drops the channel after a failure so the next Load opens a new one
+func (s *Sink[T]) reset() {
+	if s.ch != nil {
+		s.ch.Close()
+		s.ch = nil
+	}
+}
+
+// Close closes the channel and connection
+func (s *Sink[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
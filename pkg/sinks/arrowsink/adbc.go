@@ -0,0 +1,45 @@
+package arrowsink
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// Statement is the part of an ADBC statement (see
+// github.com/apache/arrow-adbc/go/adbc) that ingests record batches, set
+// up for bulk ingestion, e.g. with the "adbc.ingest.target_table" option
+type Statement interface {
+	Bind(ctx context.Context, values arrow.RecordBatch) error
+	ExecuteUpdate(ctx context.Context) (int64, error)
+}
+
+// ADBC returns a writer ingesting record batches with stmt, one at a time
+// as statements are not safe for concurrent use
+func ADBC(stmt Statement) Writer {
+	return &adbcWriter{stmt: stmt}
+}
+
+type adbcWriter struct {
+	mu   sync.Mutex
+	stmt Statement
+}
+
+func (w *adbcWriter) Write(ctx context.Context, rec arrow.RecordBatch) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.stmt.Bind(ctx, rec); err != nil {
+		return err
+	}
+	_, err := w.stmt.ExecuteUpdate(ctx)
+	return err
+}
+
+// Close closes the statement, if it is an io.Closer
+func (w *adbcWriter) Close() error {
+	if c, ok := w.stmt.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
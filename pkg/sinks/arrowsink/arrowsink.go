@@ -0,0 +1,124 @@
+// Package arrowsink loads batches as Apache Arrow record batches, for
+// columnar sinks such as Parquet files, ClickHouse and ADBC databases
+//
+// The records of a batch are appended column by column to a reused
+// builder, and the writer gets the columns as contiguous arrays, so it can
+// write them without going back to rows:
+//
+//	f, _ := os.Create("orders.parquet")
+//	schema, appendOrder, err := arrowsink.Struct[Order]()
+//	...
+//	pw, err := arrowsink.NewParquet(f, schema)
+//	...
+//	sink, err := arrowsink.New(arrowsink.Config[Order]{Schema: schema, Append: appendOrder, Writer: pw})
+//	defer sink.Close() // Completes the Parquet file
+//
+// Transforms writing straight into the columns avoid building a value per
+// record: implement Append for the extracted type and use the sink in a
+// processor whose Transform returns its input.
+package arrowsink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// Writer writes record batches to a columnar destination
+// Load calls Write concurrently from the workers of a pipeline; the record
+// is released once Write returns.
+type Writer interface {
+	Write(ctx context.Context, rec arrow.RecordBatch) error
+}
+
+// Config configures an Arrow sink
+type Config[T any] struct {
+	Writer Writer
+
+	// Schema and Append, appending a record to the builder of the schema,
+	// default to those of Struct
+	Schema *arrow.Schema
+	Append func(b *array.RecordBuilder, item T)
+
+	Allocator memory.Allocator // Defaults to memory.DefaultAllocator
+}
+
+// Sink converts each batch into one record batch
+// Close closes the writer, if it is an io.Closer, and must be called at
+// the end of a run, e.g. in PostProcess.
+type Sink[T any] struct {
+	cfg      Config[T]
+	builders sync.Pool // *array.RecordBuilder
+}
+
+// New creates an Arrow sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Writer == nil {
+		return nil, fmt.Errorf("arrowsink: writer is required")
+	}
+	if (cfg.Schema == nil) != (cfg.Append == nil) {
+		return nil, fmt.Errorf("arrowsink: schema and append must be set together")
+	}
+	if cfg.Schema == nil {
+		var err error
+		if cfg.Schema, cfg.Append, err = Struct[T](); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Allocator == nil {
+		cfg.Allocator = memory.DefaultAllocator
+	}
+
+	s := &Sink[T]{cfg: cfg}
+	s.builders.New = func() any {
+		return array.NewRecordBuilder(cfg.Allocator, cfg.Schema)
+	}
+	return s, nil
+}
+
+// Schema returns the schema of the record batches
+func (s *Sink[T]) Schema() *arrow.Schema {
+	return s.cfg.Schema
+}
+
+// Load writes a batch as one record batch
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	rec := s.build(items)
+	defer rec.Release()
+
+	if err := s.cfg.Writer.Write(ctx, rec); err != nil {
+		return fmt.Errorf("arrowsink: write: %w", err)
+	}
+	return nil
+}
+
+// build appends items to a builder of the pool and returns their record
+// batch; a builder left half-filled by a panicking Append is dropped
+func (s *Sink[T]) build(items []T) arrow.RecordBatch {
+	b := s.builders.Get().(*array.RecordBuilder)
+	b.Reserve(len(items))
+	for _, item := range items {
+		s.cfg.Append(b, item)
+	}
+	rec := b.NewRecordBatch()
+	s.builders.Put(b)
+	return rec
+}
+
+// Close closes the writer, if it is an io.Closer
+func (s *Sink[T]) Close() error {
+	if c, ok := s.cfg.Writer.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("arrowsink: close: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package arrowsink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// ClickHouse returns a writer inserting record batches into table as
+// native blocks, appending each column at once
+// Columns are matched by name; columns with nulls need Nullable types.
+func ClickHouse(conn driver.Conn, table string) Writer {
+	return &clickHouseWriter{conn: conn, table: table}
+}
+
+type clickHouseWriter struct {
+	conn  driver.Conn
+	table string
+}
+
+func (w *clickHouseWriter) Write(ctx context.Context, rec arrow.RecordBatch) error {
+	schema := rec.Schema()
+	names := make([]string, schema.NumFields())
+	for i, f := range schema.Fields() {
+		names[i] = f.Name
+	}
+	batch, err := w.conn.PrepareBatch(ctx, "INSERT INTO "+w.table+" ("+strings.Join(names, ", ")+")")
+	if err != nil {
+		return fmt.Errorf("clickhouse: prepare insert: %w", err)
+	}
+	defer batch.Close()
+
+	for i, col := range rec.Columns() {
+		values, err := columnValues(col)
+		if err != nil {
+			return fmt.Errorf("clickhouse: column %s: %w", names[i], err)
+		}
+		if err := batch.Column(i).Append(values); err != nil {
+			return fmt.Errorf("clickhouse: append column %s: %w", names[i], err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("clickhouse: send: %w", err)
+	}
+	return nil
+}
+
+// columnValues returns the values of an array as a slice, e.g. []int64,
+// sharing its memory where possible, or a slice of pointers, e.g.
+// []*int64, when it has nulls
+func columnValues(a arrow.Array) (any, error) {
+	switch a := a.(type) {
+	case *array.Boolean:
+		return values(a, a.Value, nil), nil
+	case *array.Int8:
+		return values(a, a.Value, a.Int8Values()), nil
+	case *array.Int16:
+		return values(a, a.Value, a.Int16Values()), nil
+	case *array.Int32:
+		return values(a, a.Value, a.Int32Values()), nil
+	case *array.Int64:
+		return values(a, a.Value, a.Int64Values()), nil
+	case *array.Uint8:
+		return values(a, a.Value, a.Uint8Values()), nil
+	case *array.Uint16:
+		return values(a, a.Value, a.Uint16Values()), nil
+	case *array.Uint32:
+		return values(a, a.Value, a.Uint32Values()), nil
+	case *array.Uint64:
+		return values(a, a.Value, a.Uint64Values()), nil
+	case *array.Float32:
+		return values(a, a.Value, a.Float32Values()), nil
+	case *array.Float64:
+		return values(a, a.Value, a.Float64Values()), nil
+	case *array.String:
+		return values(a, a.Value, nil), nil
+	case *array.Binary:
+		// Copied, as the block outlives the record batch
+		return values(a, func(i int) string { return string(a.Value(i)) }, nil), nil
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return values(a, func(i int) time.Time { return a.Value(i).ToTime(unit) }, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", a.DataType())
+}
+
+// values returns the values of a as a slice: direct when a has no nulls
+// and it is set, value(i) for each row otherwise, and pointers to them
+// when a has nulls
+func values[V any](a arrow.Array, value func(int) V, direct []V) any {
+	if a.NullN() == 0 {
+		if direct != nil {
+			return direct
+		}
+		out := make([]V, a.Len())
+		for i := range out {
+			out[i] = value(i)
+		}
+		return out
+	}
+	out := make([]*V, a.Len())
+	for i := range out {
+		if a.IsValid(i) {
+			v := value(i)
+			out[i] = &v
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package arrowsink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// ParquetWriter writes record batches to a Parquet file, one row group
+// per batch
+type ParquetWriter struct {
+	mu sync.Mutex
+	fw *pqarrow.FileWriter
+}
+
+// NewParquet creates a Parquet writer of w, compressed with Snappy
+// The file is complete once the writer is closed, which also closes w if
+// it is an io.Closer.
+func NewParquet(w io.Writer, schema *arrow.Schema) (*ParquetWriter, error) {
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	fw, err := pqarrow.NewFileWriter(schema, w, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		return nil, fmt.Errorf("arrowsink: parquet: %w", err)
+	}
+	return &ParquetWriter{fw: fw}, nil
+}
+
+// Write writes rec as a row group
+func (p *ParquetWriter) Write(ctx context.Context, rec arrow.RecordBatch) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fw.Write(rec)
+}
+
+// Close completes the file
+func (p *ParquetWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fw.Close()
+}
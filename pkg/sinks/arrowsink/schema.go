@@ -0,0 +1,173 @@
+package arrowsink
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// column appends a field of structs to the builder of its column
+type column struct {
+	index    []int
+	nullable bool // A pointer field, nil appending a null
+	append   func(b array.Builder, v reflect.Value)
+}
+
+// Struct returns the schema of the exported fields of the struct type T,
+// or of the struct T points to, and a function appending a T to a builder
+// of the schema
+// Columns are named by `arrow` tags, then `json` tags, then field names;
+// a tag of "-" skips the field. Booleans, integers, floats, strings, byte
+// slices and times (as UTC microsecond timestamps) are supported, pointers
+// to them making nullable columns. With a pointer T, nil items are skipped.
+func Struct[T any]() (*arrow.Schema, func(b *array.RecordBuilder, item T), error) {
+	typ := reflect.TypeFor[T]()
+	ptr := typ.Kind() == reflect.Pointer
+	if ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("arrowsink: %s is not a struct", reflect.TypeFor[T]())
+	}
+
+	var (
+		fields  []arrow.Field
+		columns []column
+	)
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := columnName(f)
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		nullable := ft.Kind() == reflect.Pointer
+		if nullable {
+			ft = ft.Elem()
+		}
+		dt, appendFn, ok := arrowType(ft)
+		if !ok {
+			return nil, nil, fmt.Errorf("arrowsink: field %s: unsupported type %s", f.Name, f.Type)
+		}
+		binary := ft.Kind() == reflect.Slice // nil slices are null
+		fields = append(fields, arrow.Field{Name: name, Type: dt, Nullable: nullable || binary})
+		columns = append(columns, column{index: f.Index, nullable: nullable, append: appendFn})
+	}
+	if len(fields) == 0 {
+		return nil, nil, fmt.Errorf("arrowsink: %s has no exported fields", typ)
+	}
+
+	appendItem := func(b *array.RecordBuilder, item T) {
+		v := reflect.ValueOf(&item).Elem()
+		if ptr {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+		for i, c := range columns {
+			fv := v.FieldByIndex(c.index)
+			if c.nullable {
+				if fv.IsNil() {
+					b.Field(i).AppendNull()
+					continue
+				}
+				fv = fv.Elem()
+			}
+			c.append(b.Field(i), fv)
+		}
+	}
+	return arrow.NewSchema(fields, nil), appendItem, nil
+}
+
+// columnName returns the column name of a field
+func columnName(f reflect.StructField) string {
+	for _, key := range []string{"arrow", "json"} {
+		if tag, ok := f.Tag.Lookup(key); ok {
+			if name, _, _ := strings.Cut(tag, ","); name != "" {
+				return name
+			}
+		}
+	}
+	return f.Name
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// arrowType returns the Arrow type of t and a function appending values
+// of t to its builder
+func arrowType(t reflect.Type) (arrow.DataType, func(array.Builder, reflect.Value), bool) {
+	if t == timeType {
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, func(b array.Builder, v reflect.Value) {
+			b.(*array.TimestampBuilder).Append(arrow.Timestamp(v.Interface().(time.Time).UnixMicro()))
+		}, true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return arrow.FixedWidthTypes.Boolean, func(b array.Builder, v reflect.Value) {
+			b.(*array.BooleanBuilder).Append(v.Bool())
+		}, true
+	case reflect.Int8:
+		return arrow.PrimitiveTypes.Int8, func(b array.Builder, v reflect.Value) {
+			b.(*array.Int8Builder).Append(int8(v.Int()))
+		}, true
+	case reflect.Int16:
+		return arrow.PrimitiveTypes.Int16, func(b array.Builder, v reflect.Value) {
+			b.(*array.Int16Builder).Append(int16(v.Int()))
+		}, true
+	case reflect.Int32:
+		return arrow.PrimitiveTypes.Int32, func(b array.Builder, v reflect.Value) {
+			b.(*array.Int32Builder).Append(int32(v.Int()))
+		}, true
+	case reflect.Int, reflect.Int64:
+		return arrow.PrimitiveTypes.Int64, func(b array.Builder, v reflect.Value) {
+			b.(*array.Int64Builder).Append(v.Int())
+		}, true
+	case reflect.Uint8:
+		return arrow.PrimitiveTypes.Uint8, func(b array.Builder, v reflect.Value) {
+			b.(*array.Uint8Builder).Append(uint8(v.Uint()))
+		}, true
+	case reflect.Uint16:
+		return arrow.PrimitiveTypes.Uint16, func(b array.Builder, v reflect.Value) {
+			b.(*array.Uint16Builder).Append(uint16(v.Uint()))
+		}, true
+	case reflect.Uint32:
+		return arrow.PrimitiveTypes.Uint32, func(b array.Builder, v reflect.Value) {
+			b.(*array.Uint32Builder).Append(uint32(v.Uint()))
+		}, true
+	case reflect.Uint, reflect.Uint64:
+		return arrow.PrimitiveTypes.Uint64, func(b array.Builder, v reflect.Value) {
+			b.(*array.Uint64Builder).Append(v.Uint())
+		}, true
+	case reflect.Float32:
+		return arrow.PrimitiveTypes.Float32, func(b array.Builder, v reflect.Value) {
+			b.(*array.Float32Builder).Append(float32(v.Float()))
+		}, true
+	case reflect.Float64:
+		return arrow.PrimitiveTypes.Float64, func(b array.Builder, v reflect.Value) {
+			b.(*array.Float64Builder).Append(v.Float())
+		}, true
+	case reflect.String:
+		return arrow.BinaryTypes.String, func(b array.Builder, v reflect.Value) {
+			b.(*array.StringBuilder).Append(v.String())
+		}, true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return arrow.BinaryTypes.Binary, func(b array.Builder, v reflect.Value) {
+				if v.IsNil() {
+					b.AppendNull()
+					return
+				}
+				b.(*array.BinaryBuilder).Append(v.Bytes())
+			}, true
+		}
+	}
+	return nil, nil, false
+}
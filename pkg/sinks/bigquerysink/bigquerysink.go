@@ -0,0 +1,141 @@
+// Package bigquerysink loads records into BigQuery with load jobs
+package bigquerysink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/storage"
+)
+
+// Config configures a BigQuery sink
+type Config[T any] struct {
+	Client *bigquery.Client
+	Table  *bigquery.Table
+
+	// Staging holds each batch while its load job runs; when nil the batch
+	// is uploaded inline with the job, which suits batches up to a few MB
+	Staging *storage.BucketHandle
+	// StagingPrefix prefixes staged object names (defaults to "go-etl/")
+	StagingPrefix string
+
+	// CreateTable creates the table from T's schema if it does not exist
+	CreateTable bool
+	// Truncate replaces the table's rows with each batch instead of
+	// appending; only useful with a single batch
+	Truncate bool
+}
+
+// Sink runs one load job per batch, with the schema inferred from T
+// Fields map to columns like the bigquery package does: by `bigquery` tag or
+// by name, with nested structs as RECORD columns.
+type Sink[T any] struct {
+	cfg    Config[T]
+	schema bigquery.Schema
+	seq    atomic.Int64
+}
+
+// New creates a BigQuery sink; T must be a struct
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Client == nil || cfg.Table == nil {
+		return nil, fmt.Errorf("bigquerysink: client and table are required")
+	}
+	if cfg.StagingPrefix == "" {
+		cfg.StagingPrefix = "go-etl/"
+	}
+
+	var zero T
+	schema, err := bigquery.InferSchema(zero)
+	if err != nil {
+		return nil, fmt.Errorf("bigquerysink: infer schema: %w", err)
+	}
+	return &Sink[T]{cfg: cfg, schema: schema}, nil
+}
+
+// Schema returns the schema inferred from T
+func (s *Sink[T]) Schema() bigquery.Schema {
+	return s.schema
+}
+
+// Load stages a batch as newline-delimited JSON and waits for its load job
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	data, err := s.encode(items)
+	if err != nil {
+		return fmt.Errorf("bigquerysink: %w", err)
+	}
+
+	var src bigquery.LoadSource
+	if s.cfg.Staging == nil {
+		rs := bigquery.NewReaderSource(bytes.NewReader(data))
+		rs.SourceFormat = bigquery.JSON
+		rs.Schema = s.schema
+		src = rs
+	} else {
+		name := fmt.Sprintf("%s%s-%d-%d.json", s.cfg.StagingPrefix, s.cfg.Table.TableID, time.Now().UnixNano(), s.seq.Add(1))
+		obj := s.cfg.Staging.Object(name)
+		if err := upload(ctx, obj, data); err != nil {
+			return fmt.Errorf("bigquerysink: stage %s: %w", name, err)
+		}
+		defer obj.Delete(context.WithoutCancel(ctx))
+
+		ref := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", obj.BucketName(), name))
+		ref.SourceFormat = bigquery.JSON
+		ref.Schema = s.schema
+		src = ref
+	}
+
+	loader := s.cfg.Table.LoaderFrom(src)
+	loader.WriteDisposition = bigquery.WriteAppend
+	if s.cfg.Truncate {
+		loader.WriteDisposition = bigquery.WriteTruncate
+	}
+	loader.CreateDisposition = bigquery.CreateNever
+	if s.cfg.CreateTable {
+		loader.CreateDisposition = bigquery.CreateIfNeeded
+	}
+
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("bigquerysink: start load job: %w", err)
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return fmt.Errorf("bigquerysink: wait for load job %s: %w", job.ID(), err)
+	}
+	if err := status.Err(); err != nil {
+		return fmt.Errorf("bigquerysink: load job %s: %w", job.ID(), err)
+	}
+	return nil
+}
+
+// encode converts items to newline-delimited JSON keyed by column name
+func (s *Sink[T]) encode(items []T) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, item := range items {
+		row, _, err := (&bigquery.StructSaver{Struct: item, Schema: s.schema}).Save()
+		if err != nil {
+			return nil, fmt.Errorf("convert row: %w", err)
+		}
+		if err := enc.Encode(row); err != nil {
+			return nil, fmt.Errorf("encode row: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// upload writes data to obj
+func upload(ctx context.Context, obj *storage.ObjectHandle, data []byte) error {
+	w := obj.NewWriter(ctx)
+	w.ContentType = "application/x-ndjson"
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,109 @@
+// Package clickhousesink inserts records into ClickHouse as native blocks
+package clickhousesink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Config configures a ClickHouse sink
+type Config[T any] struct {
+	Conn  driver.Conn
+	Table string
+
+	// Columns lists the inserted columns in order; required with
+	// ColumnValues, otherwise all columns matched by T's `ch` tags are used
+	Columns []string
+	// ColumnValues converts a batch into one slice per column (e.g.
+	// []uint64, []string), which is appended to the block without any
+	// per-row reflection. Without it rows are appended with AppendStruct.
+	ColumnValues func(items []T) []any
+
+	// AsyncInsert lets the server buffer small inserts and write them in
+	// the background (async_insert=1); WaitForAsyncInsert makes Load return
+	// only once the data is written (defaults to true when async)
+	AsyncInsert        bool
+	WaitForAsyncInsert *bool
+	// Settings are applied to every insert
+	Settings clickhouse.Settings
+}
+
+// Sink inserts each batch as one native protocol block
+type Sink[T any] struct {
+	cfg      Config[T]
+	query    string
+	settings clickhouse.Settings
+}
+
+// New creates a ClickHouse sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Conn == nil || cfg.Table == "" {
+		return nil, fmt.Errorf("clickhousesink: connection and table are required")
+	}
+	if cfg.ColumnValues != nil && len(cfg.Columns) == 0 {
+		return nil, fmt.Errorf("clickhousesink: column values require the column list")
+	}
+
+	query := "INSERT INTO " + cfg.Table
+	if len(cfg.Columns) > 0 {
+		query += " (" + strings.Join(cfg.Columns, ", ") + ")"
+	}
+
+	settings := clickhouse.Settings{}
+	for k, v := range cfg.Settings {
+		settings[k] = v
+	}
+	if cfg.AsyncInsert {
+		settings["async_insert"] = 1
+		wait := cfg.WaitForAsyncInsert == nil || *cfg.WaitForAsyncInsert
+		settings["wait_for_async_insert"] = 0
+		if wait {
+			settings["wait_for_async_insert"] = 1
+		}
+	}
+
+	return &Sink[T]{cfg: cfg, query: query, settings: settings}, nil
+}
+
+// Load inserts a batch
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	if len(s.settings) > 0 {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(s.settings))
+	}
+
+	batch, err := s.cfg.Conn.PrepareBatch(ctx, s.query)
+	if err != nil {
+		return fmt.Errorf("clickhousesink: prepare insert: %w", err)
+	}
+	defer batch.Close()
+
+	if s.cfg.ColumnValues != nil {
+		columns := s.cfg.ColumnValues(items)
+		if len(columns) != len(s.cfg.Columns) {
+			return fmt.Errorf("clickhousesink: got %d column values for %d columns", len(columns), len(s.cfg.Columns))
+		}
+		for i, values := range columns {
+			if err := batch.Column(i).Append(values); err != nil {
+				return fmt.Errorf("clickhousesink: append column %s: %w", s.cfg.Columns[i], err)
+			}
+		}
+	} else {
+		for i := range items {
+			if err := batch.AppendStruct(&items[i]); err != nil {
+				return fmt.Errorf("clickhousesink: append row: %w", err)
+			}
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("clickhousesink: send: %w", err)
+	}
+	return nil
+}
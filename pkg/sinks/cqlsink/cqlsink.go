@@ -0,0 +1,82 @@
+// Package cqlsink writes records to Cassandra and ScyllaDB
+package cqlsink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"golang.org/x/sync/errgroup"
+)
+
+// Config configures a CQL sink
+type Config[T any] struct {
+	Session *gocql.Session
+
+	// Statement is the write, e.g. INSERT INTO ks.t (id, name) VALUES (?, ?);
+	// it is prepared once per connection by the driver
+	Statement string
+	// Values returns the bind values of a record, in statement order
+	Values func(T) []any
+
+	// BatchSize groups this many writes into one UNLOGGED batch; 0 sends
+	// every write as its own statement, which is usually faster unless the
+	// records of a batch share a partition
+	BatchSize int
+	// Concurrency bounds the statements or batches in flight (defaults to 16)
+	Concurrency int
+	Consistency gocql.Consistency
+}
+
+// Sink executes the prepared statement for every record
+type Sink[T any] struct {
+	cfg Config[T]
+}
+
+// New creates a CQL sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Session == nil || cfg.Statement == "" || cfg.Values == nil {
+		return nil, fmt.Errorf("cqlsink: session, statement and values are required")
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 16
+	}
+	if cfg.Consistency == 0 {
+		cfg.Consistency = gocql.LocalQuorum
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load writes a batch
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.cfg.Concurrency)
+
+	if s.cfg.BatchSize <= 0 {
+		for _, item := range items {
+			values := s.cfg.Values(item)
+			g.Go(func() error {
+				return s.cfg.Session.Query(s.cfg.Statement, values...).
+					WithContext(ctx).
+					Consistency(s.cfg.Consistency).
+					Exec()
+			})
+		}
+	} else {
+		for start := 0; start < len(items); start += s.cfg.BatchSize {
+			batch := s.cfg.Session.NewBatch(gocql.UnloggedBatch).WithContext(ctx)
+			batch.SetConsistency(s.cfg.Consistency)
+			for _, item := range items[start:min(start+s.cfg.BatchSize, len(items))] {
+				batch.Query(s.cfg.Statement, s.cfg.Values(item)...)
+			}
+			g.Go(func() error {
+				return s.cfg.Session.ExecuteBatch(batch)
+			})
+		}
+	}
+
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("cqlsink: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+// Package deltasink appends batches to a Delta Lake table, writing Parquet
+// data files and committing them to the table's transaction log
+// Tables are readable by Spark, Trino, DuckDB, delta-rs and other Delta
+// engines as soon as a Load returns. Apache Iceberg tables are not
+// supported yet.
+package deltasink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/sinks/internal/encode"
+)
+
+// logDir is the transaction log directory under the table root
+const logDir = "_delta_log/"
+
+// Config configures a Delta sink
+// The table schema is derived from the Parquet schema of T, so fields are
+// named and typed with `parquet` tags; time.Time fields need
+// `parquet:",timestamp(microsecond)"` as Delta engines cannot read
+// nanosecond timestamps.
+type Config[T any] struct {
+	Storage Storage
+	Table   string // Table root within Storage, e.g. "warehouse/events"
+
+	// PartitionColumns are top-level columns whose values split the data
+	// files into Hive-style directories, e.g. dt=2024-01-31/; they must
+	// match the table's when it already exists
+	PartitionColumns []string
+
+	Name          string            // Table name recorded on creation, optional
+	Description   string            // Table description recorded on creation, optional
+	Configuration map[string]string // Table properties recorded on creation, e.g. delta.appendOnly
+
+	// MaxCommitRetries bounds how often a commit is retried at the next
+	// version after losing it to a concurrent writer (defaults to 10)
+	MaxCommitRetries int
+}
+
+// Sink appends each batch to the table as one commit
+// Appends never conflict with each other, so a commit that loses its
+// version to another writer is retried at the next one. Vacuum, compaction
+// and checkpoints are left to a Delta engine.
+type Sink[T any] struct {
+	cfg        Config[T]
+	schema     *parquet.Schema
+	schemaJSON string
+	partitions []parquet.Field
+
+	mu      sync.Mutex
+	version int64 // Latest known version, -1 before the table exists
+	listed  bool
+}
+
+// New creates a Delta sink; the table is created by the first Load if it
+// does not exist
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Storage == nil {
+		return nil, fmt.Errorf("deltasink: Storage is required")
+	}
+	if reflect.TypeFor[T]().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("deltasink: %s is not a struct", reflect.TypeFor[T]())
+	}
+	if cfg.Table != "" {
+		cfg.Table = strings.TrimSuffix(cfg.Table, "/") + "/"
+	}
+	if cfg.MaxCommitRetries <= 0 {
+		cfg.MaxCommitRetries = 10
+	}
+
+	s := &Sink[T]{cfg: cfg, schema: parquet.SchemaOf(new(T)), version: -1}
+
+	var err error
+	if s.schemaJSON, err = tableSchema(s.schema); err != nil {
+		return nil, fmt.Errorf("deltasink: %w", err)
+	}
+	for _, name := range cfg.PartitionColumns {
+		f, err := partitionColumn(s.schema, name)
+		if err != nil {
+			return nil, fmt.Errorf("deltasink: %w", err)
+		}
+		s.partitions = append(s.partitions, f)
+	}
+	return s, nil
+}
+
+// Version returns the latest table version the sink has seen, -1 when it
+// has not seen the table yet
+func (s *Sink[T]) Version() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.version
+}
+
+// Load writes a data file per partition and commits them as one version
+// Data files of a failed commit are left unreferenced, invisible to readers
+// until a vacuum removes them.
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	groups, err := s.group(items)
+	if err != nil {
+		return err
+	}
+
+	adds := make([]addAction, 0, len(groups))
+	for _, g := range groups {
+		add, err := s.writeFile(ctx, g)
+		if err != nil {
+			return err
+		}
+		adds = append(adds, add)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, err := s.commit(ctx, adds)
+	if err != nil {
+		return err
+	}
+	etl.LoggerFromContext(ctx).Debug("Committed Delta version",
+		"table", s.cfg.Storage.URL(s.cfg.Table), "version", version, "files", len(adds), "records", len(items))
+	return nil
+}
+
+// partition is the records sharing a set of partition values
+type partition[T any] struct {
+	values map[string]*string
+	dir    string // Hive-style directory, e.g. "dt=2024-01-31/"
+	items  []T
+}
+
+// group splits items by their partition values, in order of appearance
+func (s *Sink[T]) group(items []T) ([]*partition[T], error) {
+	if len(s.partitions) == 0 {
+		return []*partition[T]{{values: map[string]*string{}, items: items}}, nil
+	}
+
+	var (
+		order []*partition[T]
+		byDir = make(map[string]*partition[T])
+	)
+	for _, item := range items {
+		base := reflect.ValueOf(&item).Elem()
+		values := make(map[string]*string, len(s.partitions))
+		var dir strings.Builder
+		for _, f := range s.partitions {
+			v, err := partitionValue(f.Value(base))
+			if err != nil {
+				return nil, fmt.Errorf("deltasink: partition column %s: %w", f.Name(), err)
+			}
+			values[f.Name()] = v
+			dir.WriteString(escapePartition(f.Name()) + "=")
+			if v == nil {
+				dir.WriteString("__HIVE_DEFAULT_PARTITION__")
+			} else {
+				dir.WriteString(escapePartition(*v))
+			}
+			dir.WriteByte('/')
+		}
+
+		p, ok := byDir[dir.String()]
+		if !ok {
+			p = &partition[T]{values: values, dir: dir.String()}
+			byDir[p.dir] = p
+			order = append(order, p)
+		}
+		p.items = append(p.items, item)
+	}
+	return order, nil
+}
+
+// writeFile writes a partition's records to a new data file
+func (s *Sink[T]) writeFile(ctx context.Context, p *partition[T]) (addAction, error) {
+	var buf bytes.Buffer
+	enc, err := encode.NewWriter[T](&buf, encode.Parquet, nil)
+	if err != nil {
+		return addAction{}, fmt.Errorf("deltasink: %w", err)
+	}
+	if err := enc.Write(p.items); err != nil {
+		return addAction{}, fmt.Errorf("deltasink: encode: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return addAction{}, fmt.Errorf("deltasink: encode: %w", err)
+	}
+
+	rel := p.dir + "part-" + uuid.NewString() + encode.Parquet.Ext()
+	key := s.cfg.Table + rel
+	if err := s.cfg.Storage.Put(ctx, key, buf.Bytes()); err != nil {
+		return addAction{}, fmt.Errorf("deltasink: write %s: %w", s.cfg.Storage.URL(key), err)
+	}
+
+	stats, _ := json.Marshal(map[string]int{"numRecords": len(p.items)})
+	return addAction{
+		Path:             pathURI(rel),
+		PartitionValues:  p.values,
+		Size:             int64(buf.Len()),
+		ModificationTime: time.Now().UnixMilli(),
+		DataChange:       true,
+		Stats:            string(stats),
+	}, nil
+}
+
+// commit writes the log entry of the next version, creating the table at
+// version 0
+// Callers hold s.mu.
+func (s *Sink[T]) commit(ctx context.Context, adds []addAction) (int64, error) {
+	if !s.listed {
+		if err := s.refresh(ctx); err != nil {
+			return 0, err
+		}
+		s.listed = true
+	}
+
+	for attempt := 0; ; attempt++ {
+		version := s.version + 1
+		entry, err := s.entry(version, adds)
+		if err != nil {
+			return 0, fmt.Errorf("deltasink: %w", err)
+		}
+
+		key := s.cfg.Table + logDir + fmt.Sprintf("%020d.json", version)
+		err = s.cfg.Storage.PutIfAbsent(ctx, key, entry)
+		if err == nil {
+			s.version = version
+			return version, nil
+		}
+		if !errors.Is(err, ErrExists) {
+			return 0, fmt.Errorf("deltasink: commit %s: %w", s.cfg.Storage.URL(key), err)
+		}
+		if attempt >= s.cfg.MaxCommitRetries {
+			return 0, fmt.Errorf("deltasink: commit %s: lost %d times to concurrent writers", s.cfg.Storage.URL(key), attempt+1)
+		}
+
+		// Another writer took the version, catch up and try the next one
+		if err := s.refresh(ctx); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// refresh finds the latest version in the transaction log
+// Callers hold s.mu.
+func (s *Sink[T]) refresh(ctx context.Context) error {
+	prefix := s.cfg.Table + logDir
+	keys, err := s.cfg.Storage.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("deltasink: list %s: %w", s.cfg.Storage.URL(prefix), err)
+	}
+
+	for _, key := range keys {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(key, prefix), ".json")
+		if !ok || len(name) != 20 {
+			continue // Checkpoints, CRC files and temporary files
+		}
+		if v, err := strconv.ParseInt(name, 10, 64); err == nil && v > s.version {
+			s.version = v
+		}
+	}
+	return nil
+}
+
+// entry renders the newline-delimited actions of a version
+func (s *Sink[T]) entry(version int64, adds []addAction) ([]byte, error) {
+	now := time.Now().UnixMilli()
+
+	var actions []any
+	if version == 0 {
+		partitionColumns := make([]string, 0, len(s.partitions))
+		for _, f := range s.partitions {
+			partitionColumns = append(partitionColumns, f.Name())
+		}
+		configuration := s.cfg.Configuration
+		if configuration == nil {
+			configuration = map[string]string{}
+		}
+
+		actions = append(actions,
+			map[string]any{"protocol": protocolAction{MinReaderVersion: 1, MinWriterVersion: 2}},
+			map[string]any{"metaData": metadataAction{
+				ID:               uuid.NewString(),
+				Name:             s.cfg.Name,
+				Description:      s.cfg.Description,
+				Format:           formatSpec{Provider: "parquet", Options: map[string]string{}},
+				SchemaString:     s.schemaJSON,
+				PartitionColumns: partitionColumns,
+				Configuration:    configuration,
+				CreatedTime:      now,
+			}},
+		)
+	}
+	for _, add := range adds {
+		actions = append(actions, map[string]any{"add": add})
+	}
+
+	operation, mode := "WRITE", "Append"
+	if version == 0 {
+		operation, mode = "CREATE TABLE AS SELECT", "ErrorIfExists"
+	}
+	partitionBy, _ := json.Marshal(s.cfg.PartitionColumns)
+	actions = append(actions, map[string]any{"commitInfo": map[string]any{
+		"timestamp":           now,
+		"operation":           operation,
+		"operationParameters": map[string]string{"mode": mode, "partitionBy": string(partitionBy)},
+		"isBlindAppend":       true,
+		"engineInfo":          "go-etl",
+	}})
+
+	var b strings.Builder
+	for _, action := range actions {
+		line, err := json.Marshal(action)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+type protocolAction struct {
+	MinReaderVersion int `json:"minReaderVersion"`
+	MinWriterVersion int `json:"minWriterVersion"`
+}
+
+type metadataAction struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Format           formatSpec        `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+	CreatedTime      int64             `json:"createdTime"`
+}
+
+type formatSpec struct {
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
+}
+
+type addAction struct {
+	Path             string             `json:"path"`
+	PartitionValues  map[string]*string `json:"partitionValues"`
+	Size             int64              `json:"size"`
+	ModificationTime int64              `json:"modificationTime"`
+	DataChange       bool               `json:"dataChange"`
+	Stats            string             `json:"stats"`
+}
+
+// escapePartition escapes a partition name or value for a directory name,
+// as Hive and Spark do
+func escapePartition(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune("\"#%'*/:=?\\{[]^", r) {
+			fmt.Fprintf(&b, "%%%02X", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pathURI encodes a relative file path as the URI the log stores
+func pathURI(rel string) string {
+	segments := strings.Split(rel, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return path.Join(segments...)
+}
@@ -0,0 +1,215 @@
+package deltasink
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// structType is a Delta struct type, the form of the table schema
+type structType struct {
+	Type   string        `json:"type"` // Always "struct"
+	Fields []structField `json:"fields"`
+}
+
+type structField struct {
+	Name     string         `json:"name"`
+	Type     any            `json:"type"` // Primitive name or a nested type
+	Nullable bool           `json:"nullable"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+type arrayType struct {
+	Type         string `json:"type"` // Always "array"
+	ElementType  any    `json:"elementType"`
+	ContainsNull bool   `json:"containsNull"`
+}
+
+type mapType struct {
+	Type              string `json:"type"` // Always "map"
+	KeyType           any    `json:"keyType"`
+	ValueType         any    `json:"valueType"`
+	ValueContainsNull bool   `json:"valueContainsNull"`
+}
+
+// tableSchema derives the Delta schema of the Parquet files written for T
+func tableSchema(schema *parquet.Schema) (string, error) {
+	st, err := structOf(schema.Fields())
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(st)
+	return string(data), err
+}
+
+func structOf(fields []parquet.Field) (structType, error) {
+	st := structType{Type: "struct", Fields: make([]structField, 0, len(fields))}
+	for _, f := range fields {
+		typ, err := typeOf(f)
+		if err != nil {
+			return st, fmt.Errorf("column %s: %w", f.Name(), err)
+		}
+		st.Fields = append(st.Fields, structField{
+			Name:     f.Name(),
+			Type:     typ,
+			Nullable: f.Optional(),
+			Metadata: map[string]any{},
+		})
+	}
+	return st, nil
+}
+
+// typeOf maps a Parquet node to its Delta type
+func typeOf(n parquet.Node) (any, error) {
+	if n.Repeated() {
+		elem, err := elementTypeOf(n)
+		if err != nil {
+			return nil, err
+		}
+		return arrayType{Type: "array", ElementType: elem}, nil
+	}
+	return elementTypeOf(n)
+}
+
+// elementTypeOf maps a node ignoring its repetition
+func elementTypeOf(n parquet.Node) (any, error) {
+	var lt format.LogicalTypeValue
+	if t := n.Type().LogicalType(); t != nil {
+		lt = t.Value
+	}
+
+	if !n.Leaf() {
+		switch lt.(type) {
+		case *format.ListType:
+			// list (repeated) -> element
+			elem := n.Fields()[0].Fields()[0]
+			typ, err := typeOf(elem)
+			if err != nil {
+				return nil, err
+			}
+			return arrayType{Type: "array", ElementType: typ, ContainsNull: elem.Optional()}, nil
+		case *format.MapType:
+			// key_value (repeated) -> key, value
+			kv := n.Fields()[0].Fields()
+			key, err := typeOf(kv[0])
+			if err != nil {
+				return nil, err
+			}
+			value, err := typeOf(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			return mapType{Type: "map", KeyType: key, ValueType: value, ValueContainsNull: kv[1].Optional()}, nil
+		default:
+			return structOf(n.Fields())
+		}
+	}
+
+	switch lt := lt.(type) {
+	case *format.StringType, *format.EnumType, *format.JsonType:
+		return "string", nil
+	case *format.UUIDType:
+		return "binary", nil
+	case *format.DecimalType:
+		return fmt.Sprintf("decimal(%d,%d)", lt.Precision, lt.Scale), nil
+	case *format.DateType:
+		return "date", nil
+	case *format.TimestampType:
+		if _, nanos := lt.Unit.Value.(*format.NanoSeconds); nanos {
+			return nil, fmt.Errorf("nanosecond timestamps are not readable by Delta engines, tag the field `parquet:\",timestamp(microsecond)\"`")
+		}
+		return "timestamp", nil
+	case *format.IntType:
+		return integerType(lt)
+	case *format.TimeType:
+		return nil, fmt.Errorf("times of day have no Delta type")
+	}
+
+	switch n.Type().Kind() {
+	case parquet.Boolean:
+		return "boolean", nil
+	case parquet.Int32:
+		return "integer", nil
+	case parquet.Int64:
+		return "long", nil
+	case parquet.Int96:
+		return "timestamp", nil
+	case parquet.Float:
+		return "float", nil
+	case parquet.Double:
+		return "double", nil
+	default:
+		return "binary", nil
+	}
+}
+
+// integerType maps a sized integer; unsigned ones widen to the next signed
+// type since Delta has none
+func integerType(t *format.IntType) (string, error) {
+	bits := t.BitWidth
+	if !t.IsSigned {
+		bits *= 2
+	}
+	switch bits {
+	case 8:
+		return "byte", nil
+	case 16:
+		return "short", nil
+	case 32:
+		return "integer", nil
+	case 64:
+		return "long", nil
+	}
+	return "", fmt.Errorf("unsigned 64-bit integers do not fit a Delta type")
+}
+
+// partitionColumn resolves a top-level column that partitions the table
+func partitionColumn(schema *parquet.Schema, name string) (parquet.Field, error) {
+	for _, f := range schema.Fields() {
+		if f.Name() != name {
+			continue
+		}
+		if !f.Leaf() || f.Repeated() {
+			return nil, fmt.Errorf("partition column %s is not a primitive column", name)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("partition column %s is not a column of %s", name, schema.Name())
+}
+
+// partitionValue formats a partition column's value as the Delta protocol
+// serializes it, nil for null
+func partitionValue(v reflect.Value) (*string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Bool:
+		s = strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits())
+	default:
+		t, ok := v.Interface().(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("cannot partition by %s", v.Type())
+		}
+		s = t.UTC().Format("2006-01-02 15:04:05.000000")
+	}
+	return &s, nil
+}
@@ -0,0 +1,211 @@
+package deltasink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// ErrExists is returned by Storage.PutIfAbsent when the key is taken
+var ErrExists = errors.New("object already exists")
+
+// Storage holds a table's data files and transaction log
+type Storage interface {
+	// Put writes an object, replacing any existing one
+	Put(ctx context.Context, key string, data []byte) error
+
+	// PutIfAbsent writes an object atomically unless the key exists, in
+	// which case it returns ErrExists; commits rely on it to never overwrite
+	// another writer's log entry
+	PutIfAbsent(ctx context.Context, key string, data []byte) error
+
+	// List returns the keys under prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// URL returns the URL of a key, for logs and errors
+	URL(key string) string
+}
+
+// LocalStorage keeps tables in a local or mounted directory
+type LocalStorage struct {
+	Dir string
+}
+
+// Put writes a file through a temporary file, so readers never see it
+// half written
+func (s *LocalStorage) Put(ctx context.Context, key string, data []byte) error {
+	tmp, err := s.temp(key, data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(key)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// PutIfAbsent links a temporary file to key, which fails if it exists
+func (s *LocalStorage) PutIfAbsent(ctx context.Context, key string, data []byte) error {
+	tmp, err := s.temp(key, data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	err = os.Link(tmp, s.path(key))
+	if errors.Is(err, fs.ErrExist) {
+		return ErrExists
+	}
+	return err
+}
+
+// List walks the directory of prefix
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	dir := s.path(prefix)
+	if !strings.HasSuffix(prefix, "/") {
+		dir = filepath.Dir(dir)
+	}
+
+	var keys []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// URL returns the file:// URL of a key
+func (s *LocalStorage) URL(key string) string {
+	abs, err := filepath.Abs(s.path(key))
+	if err != nil {
+		abs = s.path(key)
+	}
+	return "file://" + filepath.ToSlash(abs)
+}
+
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+// temp writes data to a hidden file next to key
+func (s *LocalStorage) temp(key string, data []byte) (string, error) {
+	dir := filepath.Dir(s.path(key))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(key)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// S3Client is the subset of *s3.Client S3Storage uses
+type S3Client interface {
+	s3.ListObjectsV2APIClient
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3Storage keeps tables in an S3 bucket
+// Commits use conditional writes (If-None-Match), which S3 has supported
+// since August 2024; S3-compatible stores without them can lose commits to
+// concurrent writers.
+type S3Storage struct {
+	client S3Client
+	bucket string
+}
+
+// NewS3Storage creates a storage for bucket
+func NewS3Storage(client S3Client, bucket string) (*S3Storage, error) {
+	if client == nil || bucket == "" {
+		return nil, fmt.Errorf("deltasink: client and bucket are required")
+	}
+	return &S3Storage{client: client, bucket: bucket}, nil
+}
+
+// Put uploads an object
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+// PutIfAbsent uploads an object with If-None-Match: *
+func (s *S3Storage) PutIfAbsent(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		IfNoneMatch: aws.String("*"),
+	})
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return ErrExists
+		}
+	}
+	return err
+}
+
+// List lists the keys under prefix
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
+// URL returns the s3:// URL of a key
+func (s *S3Storage) URL(key string) string {
+	return "s3://" + s.bucket + "/" + key
+}
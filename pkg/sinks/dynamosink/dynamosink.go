@@ -0,0 +1,102 @@
+// Package dynamosink writes records to DynamoDB with BatchWriteItem
+package dynamosink
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchWrite is the most items BatchWriteItem accepts per request
+const maxBatchWrite = 25
+
+// Client is the subset of *dynamodb.Client the sink uses
+type Client interface {
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// Config configures a DynamoDB sink
+type Config[T any] struct {
+	Client Client
+	Table  string
+
+	// MaxRetries bounds the resubmissions of unprocessed items (defaults
+	// to 8); RetryBackoff is the first delay, doubled per retry (defaults to
+	// 50ms)
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+// Sink puts each record as an item, encoded with the `dynamodbav` tags of T
+type Sink[T any] struct {
+	cfg Config[T]
+}
+
+// New creates a DynamoDB sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Client == nil || cfg.Table == "" {
+		return nil, fmt.Errorf("dynamosink: client and table are required")
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 8
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = 50 * time.Millisecond
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load writes a batch in requests of 25 items
+// Items DynamoDB leaves unprocessed (throttling, capacity) are resubmitted
+// with exponential backoff.
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	requests := make([]types.WriteRequest, 0, len(items))
+	for _, item := range items {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return fmt.Errorf("dynamosink: encode item: %w", err)
+		}
+		requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+	}
+
+	for start := 0; start < len(requests); start += maxBatchWrite {
+		end := min(start+maxBatchWrite, len(requests))
+		if err := s.write(ctx, requests[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write sends one request and resubmits its unprocessed items
+func (s *Sink[T]) write(ctx context.Context, requests []types.WriteRequest) error {
+	backoff := s.cfg.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		out, err := s.cfg.Client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{s.cfg.Table: requests},
+		})
+		if err != nil {
+			return fmt.Errorf("dynamosink: batch write: %w", err)
+		}
+
+		requests = out.UnprocessedItems[s.cfg.Table]
+		if len(requests) == 0 {
+			return nil
+		}
+		if attempt >= s.cfg.MaxRetries {
+			return fmt.Errorf("dynamosink: %d items still unprocessed after %d retries", len(requests), attempt)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,236 @@
+// Package elasticsink writes records to Elasticsearch or OpenSearch with the
+// _bulk API
+package elasticsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Action is the bulk operation used for each record
+type Action string
+
+const (
+	Index  Action = "index"  // Create or replace the document
+	Create Action = "create" // Fail if the document exists
+	Upsert Action = "update" // Merge into the document, creating it if needed
+)
+
+// Config configures an Elasticsearch sink
+type Config[T any] struct {
+	URL        string
+	Header     http.Header
+	HTTPClient *http.Client // Defaults to a client with a 60s timeout
+
+	Index  string
+	Action Action // Defaults to Index
+
+	// ID picks each document's _id; without it the cluster generates ids,
+	// so retried batches create duplicates
+	ID func(T) string
+	// Refresh is passed as the refresh parameter, e.g. "wait_for"
+	Refresh string
+
+	// MaxRetries bounds the retries of items rejected as throttled or
+	// unavailable (429 and 5xx; defaults to 3)
+	MaxRetries   int
+	RetryBackoff time.Duration // Defaults to 500ms, doubled per retry
+
+	// DeadLetter receives the items the cluster rejected for good (mapping
+	// errors, version conflicts, ...). Without it they fail the batch.
+	DeadLetter func(ctx context.Context, items []T, errs []*ItemError) error
+}
+
+// ItemError is a bulk item the cluster rejected
+type ItemError struct {
+	ID     string
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("document %q: %d %s: %s", e.ID, e.Status, e.Type, e.Reason)
+}
+
+// retryable reports whether the item may succeed if sent again
+func (e *ItemError) retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// BulkError reports the items of a batch that could not be written
+type BulkError struct {
+	Errors []*ItemError
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d bulk items failed, first: %v", len(e.Errors), e.Errors[0])
+}
+
+// Sink writes each batch with one bulk request, resending only the items
+// that failed transiently
+type Sink[T any] struct {
+	cfg Config[T]
+}
+
+// New creates an Elasticsearch sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.URL == "" || cfg.Index == "" {
+		return nil, fmt.Errorf("elasticsink: URL and index are required")
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	if cfg.Action == "" {
+		cfg.Action = Index
+	}
+	if cfg.Action == Upsert && cfg.ID == nil {
+		return nil, fmt.Errorf("elasticsink: upserts require an ID function")
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load writes a batch
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	pending := items
+	backoff := s.cfg.RetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		failed, errs, err := s.bulk(ctx, pending)
+		if err != nil {
+			return fmt.Errorf("elasticsink: %w", err)
+		}
+
+		var retry, rejected []T
+		var rejectedErrs []*ItemError
+		for i, item := range failed {
+			if errs[i].retryable() && attempt < s.cfg.MaxRetries {
+				retry = append(retry, item)
+				continue
+			}
+			rejected = append(rejected, item)
+			rejectedErrs = append(rejectedErrs, errs[i])
+		}
+
+		if len(rejected) > 0 {
+			if s.cfg.DeadLetter == nil {
+				return fmt.Errorf("elasticsink: %w", &BulkError{Errors: rejectedErrs})
+			}
+			if err := s.cfg.DeadLetter(ctx, rejected, rejectedErrs); err != nil {
+				return fmt.Errorf("elasticsink: dead letter: %w", err)
+			}
+		}
+		if len(retry) == 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		pending = retry
+	}
+}
+
+type bulkResponse struct {
+	Errors bool                        `json:"errors"`
+	Items  []map[string]bulkItemResult `json:"items"`
+}
+
+type bulkItemResult struct {
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// bulk sends items and returns those that failed with their errors
+func (s *Sink[T]) bulk(ctx context.Context, items []T) ([]T, []*ItemError, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, item := range items {
+		meta := map[string]string{"_index": s.cfg.Index}
+		if s.cfg.ID != nil {
+			meta["_id"] = s.cfg.ID(item)
+		}
+		if err := enc.Encode(map[Action]any{s.cfg.Action: meta}); err != nil {
+			return nil, nil, err
+		}
+
+		var doc any = item
+		if s.cfg.Action == Upsert {
+			doc = map[string]any{"doc": item, "doc_as_upsert": true}
+		}
+		if err := enc.Encode(doc); err != nil {
+			return nil, nil, fmt.Errorf("encode document: %w", err)
+		}
+	}
+
+	path := "/_bulk"
+	if s.cfg.Refresh != "" {
+		path += "?refresh=" + s.cfg.Refresh
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL+path, &body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range s.cfg.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("bulk: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil, fmt.Errorf("decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil, nil, nil
+	}
+	if len(result.Items) != len(items) {
+		return nil, nil, errors.New("bulk response does not match the request")
+	}
+
+	var (
+		failed []T
+		errs   []*ItemError
+	)
+	for i, entry := range result.Items {
+		for _, r := range entry { // One key: the action
+			if r.Error == nil {
+				continue
+			}
+			failed = append(failed, items[i])
+			errs = append(errs, &ItemError{ID: r.ID, Status: r.Status, Type: r.Error.Type, Reason: r.Error.Reason})
+		}
+	}
+	return failed, errs, nil
+}
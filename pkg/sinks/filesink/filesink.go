@@ -0,0 +1,225 @@
+// Package filesink writes batches to local CSV, JSONL or Parquet files,
+// rotating them by size and age
+package filesink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/sinks/internal/encode"
+)
+
+// Format is the file format
+type Format = encode.Format
+
+const (
+	JSONL   = encode.JSONL
+	CSV     = encode.CSV
+	Parquet = encode.Parquet
+)
+
+// Compression is the codec of JSONL and CSV files
+type Compression = encode.Compression
+
+const (
+	Gzip = encode.Gzip
+	Zstd = encode.Zstd
+)
+
+// Config configures a file sink
+type Config struct {
+	Dir  string // Created if missing
+	Name string // File name prefix, e.g. "orders" for orders-20240501T120000.000-0001.csv
+
+	Format      Format      // Defaults to JSONL
+	Columns     []string    // CSV column order; the header is written at the top of every file
+	Compression Compression // For JSONL and CSV
+
+	MaxSize int64         // Rotate once a file reaches this many bytes, 0 for no limit
+	MaxAge  time.Duration // Rotate a file this long after it was opened, 0 for no limit
+
+	// InProgressSuffix marks the file being written; it is renamed to its
+	// final name when rotated, so readers only ever see complete files
+	// (defaults to ".inprogress")
+	InProgressSuffix string
+
+	// Sync fsyncs the file after every batch, so a loaded batch survives a
+	// crash; completed files are always synced
+	Sync bool
+}
+
+// Sink appends batches to the current file
+// Close completes the current file and must be called at the end of a run,
+// e.g. in PostProcess.
+type Sink[T any] struct {
+	cfg Config
+
+	mu       sync.Mutex
+	cur      *file[T]
+	seq      int
+	closed   bool
+	timerErr error // Failed rotation by MaxAge, reported by the next Load or Close
+}
+
+// file is the file being written
+type file[T any] struct {
+	path  string // Final path
+	f     *os.File
+	count *encode.CountingWriter
+	comp  io.WriteCloser
+	enc   *encode.Writer[T]
+	timer *time.Timer
+}
+
+// New creates a file sink
+func New[T any](cfg Config) (*Sink[T], error) {
+	if cfg.Dir == "" || cfg.Name == "" {
+		return nil, fmt.Errorf("filesink: Dir and Name are required")
+	}
+	if cfg.Format == "" {
+		cfg.Format = JSONL
+	}
+	if cfg.Format == Parquet && cfg.Compression != "" {
+		return nil, fmt.Errorf("filesink: Parquet files cannot be compressed as a whole")
+	}
+	if cfg.InProgressSuffix == "" {
+		cfg.InProgressSuffix = ".inprogress"
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("filesink: %w", err)
+	}
+
+	// Probe the format and compression settings
+	if _, err := encode.Compress(nil, cfg.Compression); err != nil {
+		return nil, fmt.Errorf("filesink: %w", err)
+	}
+	if _, err := encode.NewWriter[T](nil, cfg.Format, cfg.Columns); err != nil {
+		return nil, fmt.Errorf("filesink: %w", err)
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load appends a batch, rotating the file once it reaches MaxSize
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("filesink: sink is closed")
+	}
+	if err := s.timerErr; err != nil {
+		s.timerErr = nil
+		return err
+	}
+	if s.cur == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.cur.write(items, s.cfg.Sync); err != nil {
+		return fmt.Errorf("filesink: write %s: %w", s.cur.path, err)
+	}
+	if s.cfg.MaxSize > 0 && s.cur.count.N >= s.cfg.MaxSize {
+		return s.rotate()
+	}
+	return nil
+}
+
+// Close completes the current file
+func (s *Sink[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if err := s.timerErr; err != nil {
+		s.timerErr = nil
+		return err
+	}
+	return s.rotate()
+}
+
+// open starts a new file
+// Callers hold s.mu.
+func (s *Sink[T]) open() error {
+	s.seq++
+	name := fmt.Sprintf("%s-%s-%04d%s%s", s.cfg.Name, time.Now().UTC().Format("20060102T150405.000"), s.seq,
+		s.cfg.Format.Ext(), s.cfg.Compression.Ext())
+	path := filepath.Join(s.cfg.Dir, name)
+
+	f, err := os.OpenFile(path+s.cfg.InProgressSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("filesink: %w", err)
+	}
+
+	cur := &file[T]{path: path, f: f, count: &encode.CountingWriter{W: f}}
+	cur.comp, _ = encode.Compress(cur.count, s.cfg.Compression)
+	cur.enc, _ = encode.NewWriter[T](cur.comp, s.cfg.Format, s.cfg.Columns)
+
+	if s.cfg.MaxAge > 0 {
+		cur.timer = time.AfterFunc(s.cfg.MaxAge, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.cur == cur {
+				s.timerErr = s.rotate()
+			}
+		})
+	}
+	s.cur = cur
+	return nil
+}
+
+// rotate completes the current file and renames it to its final name
+// Callers hold s.mu.
+func (s *Sink[T]) rotate() error {
+	cur := s.cur
+	if cur == nil {
+		return nil
+	}
+	s.cur = nil
+	if cur.timer != nil {
+		cur.timer.Stop()
+	}
+
+	err := cur.enc.Close()
+	if err == nil {
+		err = cur.comp.Close()
+	}
+	if err == nil {
+		err = cur.f.Sync()
+	}
+	if cerr := cur.f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(cur.path+s.cfg.InProgressSuffix, cur.path)
+	}
+	if err != nil {
+		return fmt.Errorf("filesink: complete %s: %w", cur.path, err)
+	}
+	return nil
+}
+
+// write appends records and pushes them to the file
+func (f *file[T]) write(items []T, sync bool) error {
+	if err := f.enc.Write(items); err != nil {
+		return err
+	}
+	if err := f.enc.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := f.comp.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+	if sync {
+		return f.f.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Package gcssink writes partitioned files to Google Cloud Storage
+package gcssink
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/cuong/go-etl/pkg/sinks/objectsink"
+)
+
+// Config configures a GCS sink
+// The embedded objectsink.Config sets paths, format and rolling; its Store
+// is filled in by New.
+type Config[T any] struct {
+	Client    *storage.Client
+	Bucket    string
+	ChunkSize int // Resumable upload chunk size (defaults to the client's 16MiB)
+
+	objectsink.Config[T]
+}
+
+// New creates an object sink writing to a GCS bucket
+func New[T any](cfg Config[T]) (*objectsink.Sink[T], error) {
+	store, err := NewStore(cfg.Client, cfg.Bucket, cfg.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Config.Store = store
+	return objectsink.New(cfg.Config)
+}
+
+// Store is the objectsink.Store of a GCS bucket
+// Objects are sent as resumable uploads in ChunkSize pieces; an object
+// appears only when its writer is closed.
+type Store struct {
+	bucket    *storage.BucketHandle
+	name      string
+	chunkSize int
+}
+
+// NewStore creates a store for bucket
+func NewStore(client *storage.Client, bucket string, chunkSize int) (*Store, error) {
+	if client == nil || bucket == "" {
+		return nil, fmt.Errorf("gcssink: client and bucket are required")
+	}
+	return &Store{bucket: client.Bucket(bucket), name: bucket, chunkSize: chunkSize}, nil
+}
+
+// Create starts an upload; cancelling ctx aborts it
+func (s *Store) Create(ctx context.Context, key string) (io.WriteCloser, error) {
+	w := s.bucket.Object(key).NewWriter(ctx)
+	if s.chunkSize > 0 {
+		w.ChunkSize = s.chunkSize
+	}
+	return w, nil
+}
+
+// URL returns the gs:// URL of a key
+func (s *Store) URL(key string) string {
+	return "gs://" + s.name + "/" + key
+}
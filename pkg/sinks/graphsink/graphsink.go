@@ -0,0 +1,295 @@
+// Package graphsink loads records spanning several related destination
+// entities, e.g. a user and its addresses, orders and order lines, in
+// foreign key order
+//
+// Entities declare the entities they reference; the sink sorts them into
+// levels so that every entity is written after the entities it references,
+// and deleted before them:
+//
+//	sink, err := graphsink.New(graphsink.Config[Customer]{
+//		Entities: []graphsink.Entity[Customer]{
+//			{Name: "customers", Sink: graphsink.Rows(customerRows, insertCustomers)},
+//			{Name: "orders", References: []string{"customers"}, Sink: graphsink.Rows(orderRows, insertOrders)},
+//			{Name: "order_lines", References: []string{"orders"}, Sink: graphsink.Rows(lineRows, insertLines)},
+//		},
+//		Parallel: true, // Load the entities of a level concurrently
+//	})
+package graphsink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Loader is a sink
+type Loader[T any] interface {
+	Load(ctx context.Context, items []T) error
+}
+
+// LoaderFunc is a Loader function
+type LoaderFunc[T any] func(ctx context.Context, items []T) error
+
+func (f LoaderFunc[T]) Load(ctx context.Context, items []T) error {
+	return f(ctx, items)
+}
+
+// Rows returns a Loader calling load with the rows of an entity that rows
+// returns for each record of a batch, in record order; batches without any
+// row are skipped
+func Rows[T, R any](rows func(item T) []R, load func(ctx context.Context, rows []R) error) Loader[T] {
+	return LoaderFunc[T](func(ctx context.Context, items []T) error {
+		var all []R
+		for _, item := range items {
+			all = append(all, rows(item)...)
+		}
+		if len(all) == 0 {
+			return nil
+		}
+		return load(ctx, all)
+	})
+}
+
+// Entity is a destination table or collection written from the records
+type Entity[T any] struct {
+	Name       string
+	References []string // Entities it has foreign keys to
+
+	// Sink writes the rows of the entity of a batch of records
+	Sink Loader[T]
+
+	// Delete deletes the rows of the entity of a batch of records to
+	// delete (see Config.Delete); nil leaves them, e.g. to rely on
+	// ON DELETE CASCADE
+	Delete Loader[T]
+}
+
+// Config configures a graph sink
+type Config[T any] struct {
+	// Entities in any order; those of a level are loaded in this order
+	// unless Parallel is set
+	Entities []Entity[T]
+
+	// Delete reports whether a record is a deletion, e.g. a CDC delete
+	// event; nil writes every record
+	Delete func(item T) bool
+
+	// Parallel loads the entities of a level concurrently
+	Parallel bool
+}
+
+// CycleError reports entities referencing each other, directly or not,
+// which cannot be ordered
+type CycleError struct {
+	Entities []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("graphsink: reference cycle between %s", strings.Join(e.Entities, ", "))
+}
+
+// Sink loads the entities of each batch level by level
+// A Load succeeds once every entity has loaded its rows; a failing entity
+// fails the batch, leaving the entities loaded before it, so entity sinks
+// should upsert for retries to succeed.
+type Sink[T any] struct {
+	cfg    Config[T]
+	levels [][]Entity[T] // Referenced entities first
+}
+
+// New creates a graph sink, failing if an entity references an unknown
+// entity or the references form a cycle
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if len(cfg.Entities) == 0 {
+		return nil, fmt.Errorf("graphsink: at least one entity is required")
+	}
+	levels, err := sortEntities(cfg.Entities)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink[T]{cfg: cfg, levels: levels}, nil
+}
+
+// Levels returns the names of the entities by level, in write order
+func (s *Sink[T]) Levels() [][]string {
+	names := make([][]string, len(s.levels))
+	for i, level := range s.levels {
+		for _, e := range level {
+			names[i] = append(names[i], e.Name)
+		}
+	}
+	return names
+}
+
+// Load writes the records of a batch level by level, referenced entities
+// first, and deletes the records to delete level by level in reverse
+// Consecutive writes and deletes are loaded in batch order, so a record
+// deleted and then written again in a batch ends up written.
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	for len(items) > 0 {
+		deleting := s.cfg.Delete != nil && s.cfg.Delete(items[0])
+		n := 1
+		for n < len(items) && (s.cfg.Delete != nil && s.cfg.Delete(items[n])) == deleting {
+			n++
+		}
+
+		var err error
+		if deleting {
+			err = s.delete(ctx, items[:n])
+		} else {
+			err = s.write(ctx, items[:n])
+		}
+		if err != nil {
+			return err
+		}
+		items = items[n:]
+	}
+	return nil
+}
+
+// write writes items level by level
+func (s *Sink[T]) write(ctx context.Context, items []T) error {
+	for _, level := range s.levels {
+		err := s.loadLevel(ctx, level, items, func(e Entity[T]) Loader[T] { return e.Sink })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delete deletes items level by level in reverse
+func (s *Sink[T]) delete(ctx context.Context, items []T) error {
+	for _, level := range slices.Backward(s.levels) {
+		err := s.loadLevel(ctx, level, items, func(e Entity[T]) Loader[T] { return e.Delete })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLevel loads items with the loader of each entity of a level
+func (s *Sink[T]) loadLevel(ctx context.Context, level []Entity[T], items []T, loader func(Entity[T]) Loader[T]) error {
+	g, gctx := errgroup.WithContext(ctx)
+	if !s.cfg.Parallel {
+		g.SetLimit(1)
+	}
+	for _, e := range level {
+		l := loader(e)
+		if l == nil {
+			continue
+		}
+		g.Go(func() error {
+			if err := l.Load(gctx, items); err != nil {
+				return fmt.Errorf("graphsink: %s: %w", e.Name, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// Close closes the entity loaders that have a Close method, once each even
+// when several entities share them
+func (s *Sink[T]) Close() error {
+	var (
+		errs   []error
+		closed = make(map[any]bool)
+	)
+	closeLoader := func(name string, l Loader[T]) {
+		if l == nil {
+			return
+		}
+		if reflect.TypeOf(l).Comparable() {
+			if closed[l] {
+				return
+			}
+			closed[l] = true
+		}
+		if c, ok := l.(interface{ Close() error }); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("graphsink: close %s: %w", name, err))
+			}
+		}
+	}
+	for _, e := range s.cfg.Entities {
+		closeLoader(e.Name, e.Sink)
+		closeLoader(e.Name, e.Delete)
+	}
+	return errors.Join(errs...)
+}
+
+// sortEntities sorts entities into levels, each entity one level after
+// the deepest entity it references, keeping the order of entities within
+// a level
+func sortEntities[T any](entities []Entity[T]) ([][]Entity[T], error) {
+	index := make(map[string]int, len(entities))
+	for i, e := range entities {
+		if e.Name == "" || e.Sink == nil {
+			return nil, fmt.Errorf("graphsink: entity %d (%s) needs a Name and a Sink", i, e.Name)
+		}
+		if _, ok := index[e.Name]; ok {
+			return nil, fmt.Errorf("graphsink: entity %q already registered", e.Name)
+		}
+		index[e.Name] = i
+	}
+	for _, e := range entities {
+		for _, ref := range e.References {
+			if _, ok := index[ref]; !ok {
+				return nil, fmt.Errorf("graphsink: entity %s references unknown entity %s", e.Name, ref)
+			}
+		}
+	}
+
+	// Depth of every entity, by depth-first search
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(entities))
+	depth := make([]int, len(entities))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, entities[i].Name)
+			return &CycleError{Entities: append(slices.Clone(path[start:]), entities[i].Name)}
+		}
+		state[i] = visiting
+		path = append(path, entities[i].Name)
+		for _, ref := range entities[i].References {
+			j := index[ref]
+			if j == i {
+				continue // Self references, e.g. a parent_id, don't order entities
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+			depth[i] = max(depth[i], depth[j]+1)
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range entities {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	levels := make([][]Entity[T], slices.Max(depth)+1)
+	for i, e := range entities {
+		levels[depth[i]] = append(levels[depth[i]], e)
+	}
+	return levels, nil
+}
@@ -0,0 +1,214 @@
+// Package httpsink pushes batches to an HTTP endpoint, such as a webhook or
+// a SaaS bulk API
+package httpsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"text/template"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Config configures an HTTP sink
+type Config[T any] struct {
+	URL        string
+	Method     string      // Defaults to POST
+	Header     http.Header // Extra request headers
+	HTTPClient *http.Client
+
+	// Body is a text/template of the request body, executed with .Records;
+	// the json function encodes a value. Defaults to a JSON array of the
+	// records. Example:
+	//   {"events": {{json .Records}}, "source": "go-etl"}
+	Body        string
+	ContentType string // Defaults to application/json
+
+	BearerToken        string // Sent as "Authorization: Bearer <token>"
+	Username, Password string // Basic authentication, when Username is set
+
+	BatchSize   int // Records per request, 0 sends each Load as one request
+	Concurrency int // Requests in flight per Load (defaults to 1)
+
+	// MaxRetries is how many times a request answered with 429 or 5xx, or
+	// failing in transport, is retried: 0 sends it once, a negative value
+	// retries 5 times. RetryBackoff is the first delay, doubled on every
+	// retry (defaults to 500ms). A Retry-After header takes precedence.
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+// StatusError reports a request the endpoint rejected
+type StatusError struct {
+	StatusCode int
+	Body       string // Start of the response body
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether the request may succeed if sent again
+func (e *StatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Sink sends each batch as one or more requests
+type Sink[T any] struct {
+	cfg  Config[T]
+	body *template.Template
+}
+
+// New creates an HTTP sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("httpsink: URL is required")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "application/json"
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+
+	s := &Sink[T]{cfg: cfg}
+	if cfg.Body != "" {
+		tmpl, err := template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("httpsink: body template: %w", err)
+		}
+		s.body = tmpl
+	}
+	return s, nil
+}
+
+// Load sends a batch, BatchSize records per request
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	size := s.cfg.BatchSize
+	if size <= 0 {
+		size = max(len(items), 1)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.cfg.Concurrency)
+	for chunk := range slices.Chunk(items, size) {
+		g.Go(func() error {
+			body, err := s.encode(chunk)
+			if err != nil {
+				return fmt.Errorf("httpsink: encode: %w", err)
+			}
+			if err := s.send(ctx, body); err != nil {
+				return fmt.Errorf("httpsink: %s %s: %w", s.cfg.Method, s.cfg.URL, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// encode renders the request body of records
+func (s *Sink[T]) encode(records []T) ([]byte, error) {
+	if s.body == nil {
+		return json.Marshal(records)
+	}
+	var buf bytes.Buffer
+	if err := s.body.Execute(&buf, struct{ Records []T }{records}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// send posts a body, retrying throttled and failed requests
+func (s *Sink[T]) send(ctx context.Context, body []byte) error {
+	backoff := s.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		wait, err := s.do(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if se, ok := err.(*StatusError); ok && !se.retryable() || attempt >= s.cfg.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// do sends one request and returns the Retry-After delay of a rejection
+func (s *Sink[T]) do(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, s.cfg.Method, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range s.cfg.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", s.cfg.ContentType)
+	switch {
+	case s.cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+s.cfg.BearerToken)
+	case s.cfg.Username != "":
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body) // Lets the connection be reused
+		return 0, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return retryAfter(resp.Header.Get("Retry-After")), &StatusError{StatusCode: resp.StatusCode, Body: string(msg)}
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date,
+// returning 0 when absent
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// toJSON is the template function encoding a value as JSON
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,129 @@
+package httpsink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// endpoint answers requests with the given statuses in turn, then 200, and
+// records the bodies it received
+type endpoint struct {
+	statuses   []int
+	retryAfter string // Sent with 429 answers
+
+	mu       sync.Mutex
+	requests int
+	bodies   []string
+	headers  []http.Header
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.requests++
+	e.bodies = append(e.bodies, string(body))
+	e.headers = append(e.headers, r.Header)
+	if len(e.statuses) == 0 {
+		return
+	}
+	status := e.statuses[0]
+	e.statuses = e.statuses[1:]
+	if status == http.StatusTooManyRequests && e.retryAfter != "" {
+		w.Header().Set("Retry-After", e.retryAfter)
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// load sends records to a test server of e with cfg
+func load(t *testing.T, e *endpoint, cfg Config[int], records []int) error {
+	t.Helper()
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	cfg.URL = server.URL
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.Load(context.Background(), records)
+}
+
+func TestRetryAfter(t *testing.T) {
+	e := &endpoint{statuses: []int{http.StatusTooManyRequests}, retryAfter: "1"}
+	start := time.Now()
+	if err := load(t, e, Config[int]{RetryBackoff: time.Millisecond, MaxRetries: -1}, []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < time.Second {
+		t.Errorf("retried after %s, want the Retry-After of 1s", took)
+	}
+	if e.requests != 2 {
+		t.Errorf("%d requests, want 2", e.requests)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxRetries   int
+		statuses     []int
+		wantRequests int
+		wantStatus   int // Of the error, 0 for success
+	}{
+		{"server errors", 3, []int{http.StatusServiceUnavailable, http.StatusBadGateway}, 3, 0},
+		{"default retries", -1, []int{500, 500, 500, 500, 500}, 6, 0},
+		{"retries exhausted", 1, []int{500, 500}, 2, 500},
+		{"retries disabled", 0, []int{http.StatusServiceUnavailable}, 1, http.StatusServiceUnavailable},
+		{"client error", 3, []int{http.StatusBadRequest}, 1, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &endpoint{statuses: tt.statuses}
+			err := load(t, e, Config[int]{MaxRetries: tt.maxRetries, RetryBackoff: time.Millisecond}, []int{1})
+
+			var se *StatusError
+			switch {
+			case tt.wantStatus == 0 && err != nil:
+				t.Errorf("Load() = %v", err)
+			case tt.wantStatus != 0 && (!errors.As(err, &se) || se.StatusCode != tt.wantStatus):
+				t.Errorf("Load() = %v, want status %d", err, tt.wantStatus)
+			}
+			if e.requests != tt.wantRequests {
+				t.Errorf("%d requests, want %d", e.requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestBodyTemplate(t *testing.T) {
+	e := &endpoint{}
+	cfg := Config[int]{
+		Body:        `{"events": {{json .Records}}, "source": "go-etl"}`,
+		BearerToken: "token",
+		BatchSize:   2,
+	}
+	if err := load(t, e, cfg, []int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{`{"events": [1,2], "source": "go-etl"}`, `{"events": [3], "source": "go-etl"}`}
+	if !slices.Equal(e.bodies, want) {
+		t.Errorf("bodies %q, want %q", e.bodies, want)
+	}
+	for _, h := range e.headers {
+		if h.Get("Authorization") != "Bearer token" || h.Get("Content-Type") != "application/json" {
+			t.Errorf("headers %v, want the bearer token and JSON content type", h)
+		}
+	}
+}
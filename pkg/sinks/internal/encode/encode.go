@@ -0,0 +1,272 @@
+// Package encode writes records as JSON Lines, CSV or Parquet, for the
+// sinks that produce files or streams
+package encode
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format is a record file format
+type Format string
+
+const (
+	JSONL   Format = "jsonl"
+	CSV     Format = "csv"
+	Parquet Format = "parquet"
+)
+
+// Ext returns the file extension of the format, e.g. ".jsonl"
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// Writer encodes records to an underlying writer
+// Writes are buffered until Flush; a Parquet file is complete only after
+// Close.
+type Writer[T any] struct {
+	format  Format
+	buf     *bufio.Writer
+	json    *json.Encoder
+	csv     *csv.Writer
+	parquet *parquet.GenericWriter[T]
+	columns []string
+	fields  map[string][]int // CSV column -> struct field index path
+	header  bool             // The CSV header has been written
+}
+
+// NewWriter creates a writer of format
+// For CSV, columns fixes the column order; when empty it is taken from the
+// `csv` tags (or names) of T's fields, or from the keys of the first map
+// record, sorted.
+func NewWriter[T any](w io.Writer, format Format, columns []string) (*Writer[T], error) {
+	buf := bufio.NewWriter(w)
+	enc := &Writer[T]{format: format, buf: buf, columns: columns}
+
+	switch format {
+	case JSONL:
+		enc.json = json.NewEncoder(buf)
+	case CSV:
+		enc.csv = csv.NewWriter(buf)
+		if t := reflect.TypeFor[T](); t.Kind() == reflect.Struct {
+			enc.fields = structColumns(t)
+			if len(enc.columns) == 0 {
+				enc.columns = sortedByIndex(enc.fields)
+			}
+		}
+	case Parquet:
+		if reflect.TypeFor[T]().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("parquet requires a struct type, not %s", reflect.TypeFor[T]())
+		}
+		enc.parquet = parquet.NewGenericWriter[T](buf)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return enc, nil
+}
+
+// Write encodes items
+func (w *Writer[T]) Write(items []T) error {
+	if w.parquet != nil {
+		_, err := w.parquet.Write(items)
+		return err
+	}
+
+	for _, item := range items {
+		if w.json != nil {
+			if err := w.json.Encode(item); err != nil {
+				return err
+			}
+			continue
+		}
+
+		row, err := w.row(item)
+		if err != nil {
+			return err
+		}
+		if !w.header {
+			if err := w.csv.Write(w.columns); err != nil {
+				return err
+			}
+			w.header = true
+		}
+		if err := w.csv.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes buffered data to the underlying writer
+// For Parquet it ends the current row group.
+func (w *Writer[T]) Flush() error {
+	if w.parquet != nil {
+		if err := w.parquet.Flush(); err != nil {
+			return err
+		}
+	}
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return w.buf.Flush()
+}
+
+// Close flushes the writer and, for Parquet, writes the file footer
+func (w *Writer[T]) Close() error {
+	if w.parquet != nil {
+		if err := w.parquet.Close(); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Reset starts writing to a new underlying writer, e.g. a new file, and
+// writes the CSV header again
+func (w *Writer[T]) Reset(dst io.Writer) {
+	w.buf.Reset(dst)
+	w.header = false
+	if w.parquet != nil {
+		w.parquet.Reset(w.buf)
+	}
+}
+
+// Buffered returns the number of bytes not flushed yet
+func (w *Writer[T]) Buffered() int {
+	return w.buf.Buffered()
+}
+
+// row converts a record to CSV cells
+func (w *Writer[T]) row(item T) ([]string, error) {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct && w.fields != nil:
+		row := make([]string, len(w.columns))
+		for i, col := range w.columns {
+			if path, ok := w.fields[col]; ok {
+				row[i] = cell(v.FieldByIndex(path))
+			}
+		}
+		return row, nil
+
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if len(w.columns) == 0 {
+			for _, k := range v.MapKeys() {
+				w.columns = append(w.columns, k.String())
+			}
+			sort.Strings(w.columns)
+		}
+		row := make([]string, len(w.columns))
+		for i, col := range w.columns {
+			if val := v.MapIndex(reflect.ValueOf(col).Convert(v.Type().Key())); val.IsValid() {
+				row[i] = cell(val)
+			}
+		}
+		return row, nil
+	}
+	return nil, fmt.Errorf("cannot write %s as CSV", v.Type())
+}
+
+// cell formats a value as a CSV cell
+func cell(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch val := v.Interface().(type) {
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case encoding.TextMarshaler:
+		text, err := val.MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	case []byte:
+		return string(val)
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return ""
+		}
+		fallthrough
+	case reflect.Struct, reflect.Array:
+		data, err := json.Marshal(v.Interface())
+		if err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// structColumns maps the column names of t's fields to their index paths,
+// descending into embedded structs
+func structColumns(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	var collect func(t reflect.Type, path []int)
+	collect = func(t reflect.Type, path []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			index := append(append([]int(nil), path...), i)
+			tag, _, _ := strings.Cut(f.Tag.Get("csv"), ",")
+			if tag == "-" {
+				continue
+			}
+			if f.Anonymous && f.Type.Kind() == reflect.Struct && tag == "" {
+				collect(f.Type, index)
+				continue
+			}
+			if tag == "" {
+				tag = f.Name
+			}
+			fields[tag] = index
+		}
+	}
+	collect(t, nil)
+	return fields
+}
+
+// sortedByIndex returns the column names in field declaration order
+func sortedByIndex(fields map[string][]int) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := fields[names[i]], fields[names[j]]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return names
+}
@@ -0,0 +1,184 @@
+// Package mongosink writes batches to a MongoDB collection with BulkWrite
+package mongosink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mode is the write model used for each document
+type Mode int
+
+const (
+	Insert  Mode = iota // InsertOne; an existing _id is a write error
+	Upsert              // UpdateOne with $set and upsert, keeping fields not in the document
+	Replace             // ReplaceOne with upsert, dropping fields not in the document
+)
+
+// Config configures a MongoDB sink
+type Config[T any] struct {
+	Collection *mongo.Collection
+	Mode       Mode
+
+	// Key returns the filter matching an item's existing document in the
+	// Upsert and Replace modes; defaults to the item's _id
+	Key func(item T) any
+
+	// Ordered stops a batch at its first failing document, so documents are
+	// applied in order; unordered writes (the default) apply every valid
+	// document and are faster
+	Ordered bool
+
+	// DeadLetter receives the documents the server rejected (duplicate keys,
+	// validation failures, ...). Without it they fail the batch.
+	DeadLetter func(ctx context.Context, items []T, errs []*WriteError) error
+}
+
+// WriteError is a document the server rejected
+type WriteError struct {
+	Index   int // Position in the batch
+	Code    int
+	Message string
+}
+
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("document %d: code %d: %s", e.Index, e.Code, e.Message)
+}
+
+// BulkError reports the documents of a batch that could not be written
+type BulkError struct {
+	Errors []*WriteError
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d documents failed, first: %v", len(e.Errors), e.Errors[0])
+}
+
+// Sink writes each batch with one BulkWrite
+// In ordered mode, the documents after a rejected one are written again
+// once it has been handed to DeadLetter.
+type Sink[T any] struct {
+	cfg Config[T]
+}
+
+// New creates a MongoDB sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Collection == nil {
+		return nil, fmt.Errorf("mongosink: Collection is required")
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load writes a batch
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	models := make([]mongo.WriteModel, len(items))
+	for i, item := range items {
+		model, err := s.model(item)
+		if err != nil {
+			return fmt.Errorf("mongosink: document %d: %w", i, err)
+		}
+		models[i] = model
+	}
+
+	var (
+		rejected    []T
+		rejectedErr []*WriteError
+	)
+	for start := 0; start < len(models); {
+		failed, err := s.write(ctx, models[start:])
+		if err != nil {
+			return fmt.Errorf("mongosink: %w", err)
+		}
+		if len(failed) == 0 {
+			break
+		}
+
+		for _, we := range failed {
+			we.Index += start
+			rejected = append(rejected, items[we.Index])
+			rejectedErr = append(rejectedErr, we)
+		}
+		if !s.cfg.Ordered || s.cfg.DeadLetter == nil {
+			break
+		}
+		start = failed[0].Index + 1 // The rest of the batch was not attempted
+	}
+
+	if len(rejected) == 0 {
+		return nil
+	}
+	if s.cfg.DeadLetter == nil {
+		return fmt.Errorf("mongosink: %w", &BulkError{Errors: rejectedErr})
+	}
+	if err := s.cfg.DeadLetter(ctx, rejected, rejectedErr); err != nil {
+		return fmt.Errorf("mongosink: dead letter: %w", err)
+	}
+	return nil
+}
+
+// write runs one BulkWrite and returns its per-document errors
+// Any other failure, such as a write concern error, is returned as err.
+func (s *Sink[T]) write(ctx context.Context, models []mongo.WriteModel) ([]*WriteError, error) {
+	_, err := s.cfg.Collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(s.cfg.Ordered))
+	if err == nil {
+		return nil, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+		return nil, err
+	}
+	failed := make([]*WriteError, len(bulkErr.WriteErrors))
+	for i, we := range bulkErr.WriteErrors {
+		failed[i] = &WriteError{Index: we.Index, Code: we.Code, Message: we.Message}
+	}
+	return failed, nil
+}
+
+// model returns the write model of an item
+func (s *Sink[T]) model(item T) (mongo.WriteModel, error) {
+	if s.cfg.Mode == Insert {
+		return mongo.NewInsertOneModel().SetDocument(item), nil
+	}
+
+	filter, err := s.filter(item)
+	if err != nil {
+		return nil, err
+	}
+	switch s.cfg.Mode {
+	case Upsert:
+		return mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$set": item}).
+			SetUpsert(true), nil
+	case Replace:
+		return mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(item).
+			SetUpsert(true), nil
+	}
+	return nil, fmt.Errorf("unknown mode %d", s.cfg.Mode)
+}
+
+// filter returns the filter matching an item's document: Key, or the _id
+// the item marshals to
+func (s *Sink[T]) filter(item T) (any, error) {
+	if s.cfg.Key != nil {
+		return s.cfg.Key(item), nil
+	}
+
+	doc, err := bson.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	id, err := bson.Raw(doc).LookupErr("_id")
+	if err != nil {
+		return nil, fmt.Errorf("no _id to match on, set Config.Key")
+	}
+	return bson.D{{Key: "_id", Value: id}}, nil
+}
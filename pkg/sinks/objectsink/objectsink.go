@@ -0,0 +1,248 @@
+// Package objectsink writes batches as files to an object store under
+// templated, partitioned paths
+package objectsink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/sinks/internal/encode"
+)
+
+// Store creates objects
+type Store interface {
+	// Create starts writing an object, which exists once the writer is
+	// closed; cancelling ctx before then aborts the upload
+	Create(ctx context.Context, key string) (io.WriteCloser, error)
+
+	// URL returns the URL of a key, for logs and errors
+	URL(key string) string
+}
+
+// Format is the file format
+type Format = encode.Format
+
+const (
+	JSONL   = encode.JSONL
+	CSV     = encode.CSV
+	Parquet = encode.Parquet
+)
+
+// Compression is the codec of JSONL and CSV files
+type Compression = encode.Compression
+
+const (
+	Gzip = encode.Gzip
+	Zstd = encode.Zstd
+)
+
+// PathData is what a Path template is executed with
+type PathData[T any] struct {
+	Record T         // The record being written
+	Time   time.Time // When the batch is loaded, in UTC
+	Run    string    // Identifies the sink instance, so runs never overwrite each other's files
+	Part   string    // Zero-padded file number within the partition, e.g. "0001"
+}
+
+// Config configures an object sink
+type Config[T any] struct {
+	Store  Store
+	Prefix string // Prepended to every key
+
+	// Path is a text/template of a file's key, without extension; records
+	// of a batch rendering the same path apart from Part share files. For
+	// example
+	//   dt={{.Time.Format "2006-01-02"}}/hour={{.Time.Format "15"}}/part-{{.Part}}
+	// Defaults to "part-{{.Run}}-{{.Part}}".
+	Path string
+
+	Format      Format      // Defaults to JSONL
+	Columns     []string    // CSV column order (see encode.NewWriter)
+	Compression Compression // For JSONL and CSV; Parquet compresses its pages itself
+
+	// MaxFileSize rolls a file over to the next part once this many bytes
+	// were uploaded (defaults to 128MiB)
+	MaxFileSize int64
+}
+
+// partMarker stands in for Part when grouping records by partition
+const partMarker = "\x00part\x00"
+
+// rollCheckRecords is how many records are written between checks of the
+// file size against MaxFileSize
+const rollCheckRecords = 1000
+
+// Sink writes each batch to new files of its partitions, rolling to a new
+// part at MaxFileSize
+// Load completes the files of a batch before returning, so a loaded batch is
+// in the store even if the run fails or crashes later, and can be committed
+// or checkpointed.
+type Sink[T any] struct {
+	cfg  Config[T]
+	path *template.Template
+	run  string
+
+	mu    sync.Mutex
+	parts map[string]int // Last part number by partition path
+}
+
+// file is an object being uploaded
+type file[T any] struct {
+	key    string
+	upload io.WriteCloser
+	cancel context.CancelFunc
+	count  *encode.CountingWriter
+	comp   io.WriteCloser
+	enc    *encode.Writer[T]
+}
+
+// New creates an object sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("objectsink: Store is required")
+	}
+	if cfg.Path == "" {
+		cfg.Path = "part-{{.Run}}-{{.Part}}"
+	}
+	if cfg.Format == "" {
+		cfg.Format = JSONL
+	}
+	if cfg.Format == Parquet && cfg.Compression != "" {
+		return nil, fmt.Errorf("objectsink: Parquet files cannot be compressed as a whole")
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = 128 << 20
+	}
+
+	path, err := template.New("path").Option("missingkey=error").Parse(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("objectsink: path template: %w", err)
+	}
+	if !strings.Contains(cfg.Path, ".Part") {
+		return nil, fmt.Errorf("objectsink: path template must contain {{.Part}}")
+	}
+
+	return &Sink[T]{
+		cfg:   cfg,
+		path:  path,
+		run:   time.Now().UTC().Format("20060102T150405.000Z"),
+		parts: make(map[string]int),
+	}, nil
+}
+
+// Load writes a batch to new files of its partitions and completes them
+// A failed Load aborts the file being written; the files it completed keep
+// their records, which a retry writes again.
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	now := time.Now().UTC()
+
+	var (
+		order  []string
+		groups = make(map[string][]T)
+		buf    bytes.Buffer
+	)
+	for _, item := range items {
+		buf.Reset()
+		data := PathData[T]{Record: item, Time: now, Run: s.run, Part: partMarker}
+		if err := s.path.Execute(&buf, data); err != nil {
+			return fmt.Errorf("objectsink: path: %w", err)
+		}
+		p := buf.String()
+		if _, ok := groups[p]; !ok {
+			order = append(order, p)
+		}
+		groups[p] = append(groups[p], item)
+	}
+
+	for _, p := range order {
+		if err := s.write(ctx, p, groups[p]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the records of a partition path to as many parts as
+// MaxFileSize requires
+func (s *Sink[T]) write(ctx context.Context, p string, items []T) error {
+	for len(items) > 0 {
+		f, err := s.create(ctx, p)
+		if err != nil {
+			return err
+		}
+		for len(items) > 0 && f.count.N < s.cfg.MaxFileSize {
+			n := min(len(items), rollCheckRecords)
+			if err := f.write(items[:n]); err != nil {
+				f.cancel()
+				return fmt.Errorf("objectsink: write %s: %w", s.cfg.Store.URL(f.key), err)
+			}
+			items = items[n:]
+		}
+		if err := f.close(); err != nil {
+			return fmt.Errorf("objectsink: close %s: %w", s.cfg.Store.URL(f.key), err)
+		}
+	}
+	return nil
+}
+
+// create starts the next part of a partition path
+func (s *Sink[T]) create(ctx context.Context, p string) (*file[T], error) {
+	s.mu.Lock()
+	s.parts[p]++
+	part := s.parts[p]
+	s.mu.Unlock()
+
+	key := strings.ReplaceAll(p, partMarker, fmt.Sprintf("%04d", part))
+	key = strings.TrimSuffix(s.cfg.Prefix, "/") + "/" + strings.TrimPrefix(key, "/")
+	key = strings.TrimPrefix(key, "/") + s.cfg.Format.Ext() + s.cfg.Compression.Ext()
+
+	uploadCtx, cancel := context.WithCancel(ctx)
+	upload, err := s.cfg.Store.Create(uploadCtx, key)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("objectsink: create %s: %w", s.cfg.Store.URL(key), err)
+	}
+
+	f := &file[T]{key: key, upload: upload, cancel: cancel, count: &encode.CountingWriter{W: upload}}
+	if f.comp, err = encode.Compress(f.count, s.cfg.Compression); err == nil {
+		f.enc, err = encode.NewWriter[T](f.comp, s.cfg.Format, s.cfg.Columns)
+	}
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("objectsink: %w", err)
+	}
+	return f, nil
+}
+
+// write appends records and pushes them through the compressor
+func (f *file[T]) write(items []T) error {
+	if err := f.enc.Write(items); err != nil {
+		return err
+	}
+	if err := f.enc.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := f.comp.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// close ends the file and completes its upload
+func (f *file[T]) close() error {
+	defer f.cancel()
+
+	if err := f.enc.Close(); err != nil {
+		return err
+	}
+	if err := f.comp.Close(); err != nil {
+		return err
+	}
+	return f.upload.Close()
+}
@@ -0,0 +1,115 @@
+package objectsink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memStore keeps completed objects in memory
+type memStore struct {
+	mu      sync.Mutex
+	objects map[string]string
+	fail    func(key string) bool // Fails writes to matching keys
+}
+
+func (s *memStore) Create(ctx context.Context, key string) (io.WriteCloser, error) {
+	return &memUpload{ctx: ctx, store: s, key: key}, nil
+}
+
+func (s *memStore) URL(key string) string { return "mem://" + key }
+
+func (s *memStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+type memUpload struct {
+	ctx   context.Context
+	store *memStore
+	key   string
+	buf   bytes.Buffer
+}
+
+func (u *memUpload) Write(p []byte) (int, error) {
+	if u.store.fail != nil && u.store.fail(u.key) {
+		return 0, errors.New("store unavailable")
+	}
+	return u.buf.Write(p)
+}
+
+func (u *memUpload) Close() error {
+	if err := u.ctx.Err(); err != nil {
+		return err
+	}
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	u.store.objects[u.key] = u.buf.String()
+	return nil
+}
+
+type record struct {
+	Day string `json:"day"`
+	N   int    `json:"n"`
+}
+
+func TestFailedLoadKeepsEarlierBatches(t *testing.T) {
+	store := &memStore{objects: make(map[string]string)}
+	s, err := New(Config[record]{Store: store, Path: "dt={{.Record.Day}}/part-{{.Part}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Load(ctx, []record{{"a", 1}, {"b", 2}}); err != nil {
+		t.Fatal(err)
+	}
+	store.fail = func(key string) bool { return strings.HasPrefix(key, "dt=b/") }
+	if err := s.Load(ctx, []record{{"a", 3}, {"b", 4}}); err == nil {
+		t.Fatal("Load() succeeded writing to a failing store")
+	}
+
+	want := []string{"dt=a/part-0001.jsonl", "dt=a/part-0002.jsonl", "dt=b/part-0001.jsonl"}
+	if got := store.keys(); !slices.Equal(got, want) {
+		t.Fatalf("objects %v, want %v", got, want)
+	}
+	if got := store.objects["dt=b/part-0001.jsonl"]; got != `{"day":"b","n":2}`+"\n" {
+		t.Errorf("first batch of dt=b = %q", got)
+	}
+}
+
+func TestLoadRollsAtMaxFileSize(t *testing.T) {
+	store := &memStore{objects: make(map[string]string)}
+	s, err := New(Config[record]{Store: store, MaxFileSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]record, rollCheckRecords+1)
+	if err := s.Load(context.Background(), items); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := store.keys()
+	if len(keys) != 2 {
+		t.Fatalf("objects %v, want a roll after %d records", keys, rollCheckRecords)
+	}
+	for i, key := range keys {
+		if n := strings.Count(store.objects[key], "\n"); n != []int{rollCheckRecords, 1}[i] {
+			t.Errorf("%s has %d records", key, n)
+		}
+	}
+}
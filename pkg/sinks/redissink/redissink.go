@@ -0,0 +1,90 @@
+// Package redissink writes records to Redis as hashes or strings
+package redissink
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Config configures a Redis sink
+// Exactly one of Hash and String must be set.
+type Config[T any] struct {
+	Client redis.UniversalClient
+
+	// Key names the key each record is written to (required)
+	Key func(T) string
+
+	// Hash writes the record's fields with HSET
+	Hash func(T) map[string]any
+	// String writes the record's value with SET
+	String func(T) (string, error)
+
+	// ReplaceHash deletes the key before HSET, dropping stale fields
+	ReplaceHash bool
+	// TTL expires written keys, 0 for no expiry
+	TTL time.Duration
+}
+
+// Sink writes each batch in one pipeline
+type Sink[T any] struct {
+	cfg Config[T]
+}
+
+// New creates a Redis sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redissink: client is required")
+	}
+	if cfg.Key == nil {
+		return nil, fmt.Errorf("redissink: key function is required")
+	}
+	if (cfg.Hash == nil) == (cfg.String == nil) {
+		return nil, fmt.Errorf("redissink: exactly one of Hash and String must be set")
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load writes a batch
+// When hashes are replaced or given a TTL, the batch runs as a transaction so
+// readers never see a key half-written.
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	pipelined := s.cfg.Client.Pipelined
+	if s.cfg.Hash != nil && (s.cfg.ReplaceHash || s.cfg.TTL > 0) {
+		pipelined = s.cfg.Client.TxPipelined
+	}
+
+	_, err := pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, item := range items {
+			key := s.cfg.Key(item)
+
+			if s.cfg.String != nil {
+				value, err := s.cfg.String(item)
+				if err != nil {
+					return fmt.Errorf("encode %s: %w", key, err)
+				}
+				pipe.Set(ctx, key, value, s.cfg.TTL)
+				continue
+			}
+
+			fields := s.cfg.Hash(item)
+			if len(fields) == 0 {
+				continue
+			}
+			if s.cfg.ReplaceHash {
+				pipe.Del(ctx, key)
+			}
+			pipe.HSet(ctx, key, fields)
+			if s.cfg.TTL > 0 {
+				pipe.Expire(ctx, key, s.cfg.TTL)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redissink: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Package routersink dispatches each record to one of several sinks
+package routersink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Loader is a sink
+type Loader[T any] interface {
+	Load(ctx context.Context, items []T) error
+}
+
+// Route sends the records matching a predicate to a sink
+type Route[T any] struct {
+	Name  string
+	Match func(item T) bool
+	Sink  Loader[T]
+
+	// BatchSize splits the route's share of a batch into Loads of at most
+	// this many records, 0 for one Load
+	BatchSize int
+}
+
+// Config configures a router
+type Config[T any] struct {
+	// Routes are tried in order; a record goes to the first that matches
+	Routes []Route[T]
+
+	// Default receives records no route matched; without it they fail the
+	// batch
+	Default Loader[T]
+
+	// Parallel loads the routes of a batch concurrently instead of in route
+	// order
+	Parallel bool
+}
+
+// UnroutedError reports records no route matched
+type UnroutedError struct {
+	Count int
+}
+
+func (e *UnroutedError) Error() string {
+	return fmt.Sprintf("%d records matched no route", e.Count)
+}
+
+// Sink routes the records of each batch
+// A Load succeeds once every route has loaded its share, so a failing route
+// fails the batch and the routes that succeeded see it again on a retry.
+type Sink[T any] struct {
+	cfg Config[T]
+}
+
+// New creates a router
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if len(cfg.Routes) == 0 {
+		return nil, fmt.Errorf("routersink: at least one route is required")
+	}
+	for i, r := range cfg.Routes {
+		if r.Match == nil || r.Sink == nil {
+			return nil, fmt.Errorf("routersink: route %d (%s) needs Match and Sink", i, r.Name)
+		}
+		if r.Name == "" {
+			cfg.Routes[i].Name = fmt.Sprintf("route %d", i)
+		}
+	}
+	return &Sink[T]{cfg: cfg}, nil
+}
+
+// Load splits a batch by route and loads each share
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	shares := make([][]T, len(s.cfg.Routes))
+	var unrouted []T
+	for _, item := range items {
+		i := slices.IndexFunc(s.cfg.Routes, func(r Route[T]) bool { return r.Match(item) })
+		if i < 0 {
+			unrouted = append(unrouted, item)
+			continue
+		}
+		shares[i] = append(shares[i], item)
+	}
+	if len(unrouted) > 0 && s.cfg.Default == nil {
+		return fmt.Errorf("routersink: %w", &UnroutedError{Count: len(unrouted)})
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	if !s.cfg.Parallel {
+		g.SetLimit(1)
+	}
+	for i, r := range s.cfg.Routes {
+		if len(shares[i]) == 0 {
+			continue
+		}
+		g.Go(func() error {
+			if err := load(gctx, r.Sink, shares[i], r.BatchSize); err != nil {
+				return fmt.Errorf("routersink: %s: %w", r.Name, err)
+			}
+			return nil
+		})
+	}
+	if len(unrouted) > 0 {
+		g.Go(func() error {
+			if err := s.cfg.Default.Load(gctx, unrouted); err != nil {
+				return fmt.Errorf("routersink: default route: %w", err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// Close closes the sinks that have a Close method, once each even when
+// several routes share them
+func (s *Sink[T]) Close() error {
+	var (
+		errs   []error
+		closed = make(map[any]bool)
+	)
+	closeSink := func(name string, sink Loader[T]) {
+		if reflect.TypeOf(sink).Comparable() {
+			if closed[sink] {
+				return
+			}
+			closed[sink] = true
+		}
+		if c, ok := sink.(interface{ Close() error }); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("routersink: close %s: %w", name, err))
+			}
+		}
+	}
+	for _, r := range s.cfg.Routes {
+		closeSink(r.Name, r.Sink)
+	}
+	if s.cfg.Default != nil {
+		closeSink("default route", s.cfg.Default)
+	}
+	return errors.Join(errs...)
+}
+
+// load sends items to sink in chunks of size
+func load[T any](ctx context.Context, sink Loader[T], items []T, size int) error {
+	if size <= 0 {
+		return sink.Load(ctx, items)
+	}
+	for chunk := range slices.Chunk(items, size) {
+		if err := sink.Load(ctx, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package s3sink writes partitioned files to S3 with multipart uploads
+package s3sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/cuong/go-etl/pkg/sinks/objectsink"
+)
+
+// minPartSize is the smallest part S3 accepts, except for the last one
+const minPartSize = 5 << 20
+
+// Client is the subset of *s3.Client the sink uses
+type Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// Config configures an S3 sink
+// The embedded objectsink.Config sets paths, format and rolling; its Store
+// is filled in by New.
+type Config[T any] struct {
+	Client   Client
+	Bucket   string
+	PartSize int64 // Multipart part size (defaults to 8MiB, at least 5MiB)
+
+	objectsink.Config[T]
+}
+
+// New creates an object sink writing to an S3 bucket
+func New[T any](cfg Config[T]) (*objectsink.Sink[T], error) {
+	store, err := NewStore(cfg.Client, cfg.Bucket, cfg.PartSize)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Config.Store = store
+	return objectsink.New(cfg.Config)
+}
+
+// Store is the objectsink.Store of an S3 bucket
+// Objects smaller than a part are sent with a single PutObject, larger ones
+// as a multipart upload that is aborted on failure.
+type Store struct {
+	client   Client
+	bucket   string
+	partSize int64
+}
+
+// NewStore creates a store for bucket
+func NewStore(client Client, bucket string, partSize int64) (*Store, error) {
+	if client == nil || bucket == "" {
+		return nil, fmt.Errorf("s3sink: client and bucket are required")
+	}
+	if partSize <= 0 {
+		partSize = 8 << 20
+	}
+	return &Store{client: client, bucket: bucket, partSize: max(partSize, minPartSize)}, nil
+}
+
+// Create starts an upload
+func (s *Store) Create(ctx context.Context, key string) (io.WriteCloser, error) {
+	u := &upload{ctx: ctx, store: s, key: key}
+	u.stopAbort = context.AfterFunc(ctx, u.abort)
+	return u, nil
+}
+
+// URL returns the s3:// URL of a key
+func (s *Store) URL(key string) string {
+	return "s3://" + s.bucket + "/" + key
+}
+
+// upload buffers an object and sends it in parts
+type upload struct {
+	ctx       context.Context
+	store     *Store
+	key       string
+	stopAbort func() bool
+
+	// mu serializes Write and Close with the abort when ctx is cancelled
+	mu       sync.Mutex
+	err      error // Why the upload failed; it is aborted and never completed
+	buf      bytes.Buffer
+	uploadID *string
+	parts    []types.CompletedPart
+}
+
+func (u *upload) Write(p []byte) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.err != nil {
+		return 0, u.err
+	}
+	n, _ := u.buf.Write(p)
+	for int64(u.buf.Len()) >= u.store.partSize {
+		// The part leaves the buffer either way, so a failed one fails the
+		// upload rather than let Close complete the object without it
+		if err := u.sendPart(u.buf.Next(int(u.store.partSize))); err != nil {
+			u.fail(err)
+			return n, u.err
+		}
+	}
+	return n, nil
+}
+
+// Close sends what is buffered and completes the upload
+func (u *upload) Close() error {
+	if !u.stopAbort() {
+		return fmt.Errorf("upload of %s: aborted: %w", u.store.URL(u.key), context.Cause(u.ctx))
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.err != nil {
+		return u.err
+	}
+	if u.uploadID == nil {
+		_, err := u.store.client.PutObject(u.ctx, &s3.PutObjectInput{
+			Bucket: aws.String(u.store.bucket),
+			Key:    aws.String(u.key),
+			Body:   bytes.NewReader(u.buf.Bytes()),
+		})
+		return err
+	}
+
+	if u.buf.Len() > 0 {
+		if err := u.sendPart(u.buf.Bytes()); err != nil {
+			u.fail(err)
+			return u.err
+		}
+	}
+	_, err := u.store.client.CompleteMultipartUpload(u.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.store.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        u.uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+	})
+	if err != nil {
+		u.fail(err)
+		return u.err
+	}
+	return nil
+}
+
+// sendPart uploads one part, starting the multipart upload if needed
+// Callers hold u.mu.
+func (u *upload) sendPart(data []byte) error {
+	if u.uploadID == nil {
+		out, err := u.store.client.CreateMultipartUpload(u.ctx, &s3.CreateMultipartUploadInput{
+			Bucket: aws.String(u.store.bucket),
+			Key:    aws.String(u.key),
+		})
+		if err != nil {
+			return err
+		}
+		u.uploadID = out.UploadId
+	}
+
+	number := aws.Int32(int32(len(u.parts) + 1))
+	out, err := u.store.client.UploadPart(u.ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(u.store.bucket),
+		Key:        aws.String(u.key),
+		UploadId:   u.uploadID,
+		PartNumber: number,
+		Body:       bytes.NewReader(data),
+	})
+	if err != nil {
+		return err
+	}
+	u.parts = append(u.parts, types.CompletedPart{ETag: out.ETag, PartNumber: number})
+	return nil
+}
+
+// abort aborts the upload once ctx is cancelled, waiting for a Write in
+// progress; later writes fail
+func (u *upload) abort() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.err == nil {
+		u.fail(fmt.Errorf("aborted: %w", context.Cause(u.ctx)))
+	}
+}
+
+// fail records why the upload failed and aborts it
+// Callers hold u.mu.
+func (u *upload) fail(err error) {
+	u.err = fmt.Errorf("upload of %s: %w", u.store.URL(u.key), err)
+	u.abortUpload()
+}
+
+// abortUpload discards the uploaded parts, which S3 would otherwise keep
+// (and bill) until a lifecycle rule removes them
+// Callers hold u.mu.
+func (u *upload) abortUpload() {
+	if u.uploadID == nil {
+		return
+	}
+	// Best effort: the upload already failed and that error is reported
+	_, _ = u.store.client.AbortMultipartUpload(context.WithoutCancel(u.ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.store.bucket),
+		Key:      aws.String(u.key),
+		UploadId: u.uploadID,
+	})
+}
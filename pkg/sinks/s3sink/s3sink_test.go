@@ -0,0 +1,135 @@
+package s3sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeClient records the calls of an upload; uploadPart, if set, decides
+// how each part is answered
+type fakeClient struct {
+	uploadPart func(ctx context.Context, number int32) error
+
+	mu        sync.Mutex
+	parts     int
+	completed bool
+	aborted   int
+}
+
+func (c *fakeClient) PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.completed = true
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (c *fakeClient) CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput, ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload")}, nil
+}
+
+func (c *fakeClient) UploadPart(ctx context.Context, params *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if c.uploadPart != nil {
+		if err := c.uploadPart(ctx, *params.PartNumber); err != nil {
+			return nil, err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.parts++
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (c *fakeClient) CompleteMultipartUpload(context.Context, *s3.CompleteMultipartUploadInput, ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.completed = true
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (c *fakeClient) AbortMultipartUpload(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.aborted++
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestFailedPartFailsUpload(t *testing.T) {
+	client := &fakeClient{uploadPart: func(_ context.Context, number int32) error {
+		if number == 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	}}
+	store, err := NewStore(client, "bucket", minPartSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := store.Create(context.Background(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Write(make([]byte, 2*minPartSize)); err == nil {
+		t.Error("Write() succeeded with a failed part")
+	}
+	if _, err := u.Write([]byte("more")); err == nil {
+		t.Error("Write() succeeded after a failed part")
+	}
+	if err := u.Close(); err == nil {
+		t.Error("Close() completed an upload missing a part")
+	}
+
+	if client.completed || client.aborted != 1 {
+		t.Errorf("completed %v, aborted %d times; want aborted once", client.completed, client.aborted)
+	}
+}
+
+func TestCancelDuringWrite(t *testing.T) {
+	sending := make(chan struct{})
+	client := &fakeClient{uploadPart: func(ctx context.Context, _ int32) error {
+		close(sending)
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	store, err := NewStore(client, "bucket", minPartSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u, err := store.Create(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := make(chan error)
+	go func() {
+		_, err := u.Write(bytes.Repeat([]byte("x"), minPartSize))
+		written <- err
+	}()
+
+	<-sending
+	cancel()
+	if err := <-written; err == nil {
+		t.Error("Write() succeeded after the upload was cancelled")
+	}
+	if err := u.Close(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Close() = %v, want the cancellation", err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.completed || client.aborted != 1 {
+		t.Errorf("completed %v, aborted %d times; want aborted once", client.completed, client.aborted)
+	}
+}
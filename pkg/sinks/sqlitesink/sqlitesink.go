@@ -0,0 +1,171 @@
+// Package sqlitesink writes batches to a SQLite database, producing a
+// single-file artifact analysts can open anywhere
+package sqlitesink
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/internal/dbfields"
+	"github.com/cuong/go-etl/pkg/sinks/sqlsink"
+)
+
+// Config configures a SQLite sink
+// DB is opened by the caller with any SQLite driver, e.g. mattn/go-sqlite3
+// or modernc.org/sqlite.
+type Config[T any] struct {
+	DB    *sql.DB
+	Table string
+
+	// KeyColumns become the primary key of a created table; with them rows
+	// are upserted, without them inserted
+	KeyColumns []string
+
+	BatchSize int               // Rows per INSERT (defaults to 500)
+	Delete    func(item T) bool // Items to delete by key instead of write, e.g. CDC deletes
+}
+
+// Sink creates the table from T if it does not exist and writes each batch
+// in one transaction
+// SQLite allows a single writer, so batches are written one at a time.
+type Sink[T any] struct {
+	db   *sql.DB
+	sink *sqlsink.Sink[T]
+	mu   sync.Mutex
+}
+
+// New puts the database in WAL mode, creates the table and returns the sink
+// WAL mode is stored in the file; the synchronous and busy_timeout pragmas
+// only apply to the pooled connection they run on, so set them in the DSN
+// when the pool holds more than one.
+func New[T any](ctx context.Context, cfg Config[T]) (*Sink[T], error) {
+	if cfg.DB == nil || cfg.Table == "" {
+		return nil, fmt.Errorf("sqlitesink: DB and Table are required")
+	}
+
+	for _, pragma := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA synchronous = NORMAL", // Safe in WAL mode, much faster than FULL
+		"PRAGMA busy_timeout = 5000",
+	} {
+		if _, err := cfg.DB.ExecContext(ctx, pragma); err != nil {
+			return nil, fmt.Errorf("sqlitesink: %s: %w", pragma, err)
+		}
+	}
+
+	ddl, err := createTable[T](cfg.Table, cfg.KeyColumns)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cfg.DB.ExecContext(ctx, ddl); err != nil {
+		return nil, fmt.Errorf("sqlitesink: create table %s: %w", cfg.Table, err)
+	}
+
+	mode := sqlsink.Upsert
+	if len(cfg.KeyColumns) == 0 {
+		mode = sqlsink.Insert
+	}
+	sink, err := sqlsink.New(sqlsink.Config[T]{
+		DB:         cfg.DB,
+		Dialect:    sqlsink.SQLite,
+		Table:      cfg.Table,
+		KeyColumns: cfg.KeyColumns,
+		Mode:       mode,
+		BatchSize:  cfg.BatchSize,
+		Delete:     cfg.Delete,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Sink[T]{db: cfg.DB, sink: sink}, nil
+}
+
+// Load writes a batch in one transaction
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sink.Load(ctx, items)
+}
+
+// Close folds the write-ahead log into the database file and leaves WAL
+// mode, so the file can be shipped on its own
+// It does not close DB.
+func (s *Sink[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pragma := range []string{
+		"PRAGMA wal_checkpoint(TRUNCATE)",
+		"PRAGMA journal_mode = DELETE",
+	} {
+		if _, err := s.db.Exec(pragma); err != nil {
+			return fmt.Errorf("sqlitesink: %s: %w", pragma, err)
+		}
+	}
+	return nil
+}
+
+var (
+	timeType   = reflect.TypeFor[time.Time]()
+	valuerType = reflect.TypeFor[driver.Valuer]()
+)
+
+// createTable returns the CREATE TABLE statement of T's columns
+func createTable[T any](table string, keys []string) (string, error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("sqlitesink: %s is not a struct", t)
+	}
+
+	var cols []string
+	for _, f := range dbfields.Of(t) {
+		col := quote(f.Column)
+		if typ := columnType(t.FieldByIndex(f.Index).Type); typ != "" {
+			col += " " + typ
+		}
+		cols = append(cols, col)
+	}
+	if len(keys) > 0 {
+		quoted := make([]string, len(keys))
+		for i, k := range keys {
+			quoted[i] = quote(k)
+		}
+		cols = append(cols, "PRIMARY KEY ("+strings.Join(quoted, ", ")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quote(table), strings.Join(cols, ", ")), nil
+}
+
+// columnType maps a field type to a SQLite type affinity
+func columnType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return "DATETIME"
+	case t.Kind() == reflect.Bool, t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return "INTEGER"
+	case t.Kind() == reflect.Float32, t.Kind() == reflect.Float64:
+		return "REAL"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return "BLOB"
+	case t.Kind() == reflect.String:
+		return "TEXT"
+	}
+	// Driver values such as sql.NullInt64 carry no affinity
+	if t.Implements(valuerType) || reflect.PointerTo(t).Implements(valuerType) {
+		return ""
+	}
+	return "TEXT"
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
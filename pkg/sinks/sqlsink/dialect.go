@@ -0,0 +1,221 @@
+package sqlsink
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL flavour of the database
+type Dialect int
+
+const (
+	Postgres  Dialect = iota // INSERT ... ON CONFLICT, $n placeholders
+	MySQL                    // INSERT ... ON DUPLICATE KEY UPDATE, ? placeholders
+	SQLite                   // INSERT ... ON CONFLICT, ? placeholders
+	SQLServer                // MERGE, @pn placeholders
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case Postgres:
+		return "postgres"
+	case MySQL:
+		return "mysql"
+	case SQLite:
+		return "sqlite"
+	case SQLServer:
+		return "sqlserver"
+	}
+	return "dialect(" + strconv.Itoa(int(d)) + ")"
+}
+
+// maxParams is the bind parameter limit of one statement
+func (d Dialect) maxParams() int {
+	switch d {
+	case SQLite:
+		return 32766
+	case SQLServer:
+		return 2098 // 2100 minus what drivers may reserve
+	}
+	return 65535
+}
+
+// maxRows is the row limit of one VALUES list
+func (d Dialect) maxRows() int {
+	if d == SQLServer {
+		return 1000
+	}
+	return d.maxParams()
+}
+
+// placeholder returns the n-th (1-based) bind parameter
+func (d Dialect) placeholder(n int) string {
+	switch d {
+	case Postgres:
+		return "$" + strconv.Itoa(n)
+	case SQLServer:
+		return "@p" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// quote quotes a possibly schema-qualified identifier
+func (d Dialect) quote(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		switch d {
+		case MySQL:
+			parts[i] = "`" + strings.ReplaceAll(p, "`", "``") + "`"
+		case SQLServer:
+			parts[i] = "[" + strings.ReplaceAll(p, "]", "]]") + "]"
+		default:
+			parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// statement builds the SQL of one write
+type statement struct {
+	dialect Dialect
+	table   string   // Quoted
+	columns []string // Quoted
+	keys    []string // Quoted
+	updates []string // Quoted columns updated on conflict
+	mode    Mode
+}
+
+// values writes rows groups of placeholders, numbered from 1
+func (s *statement) values(b *strings.Builder, rows, width int) {
+	n := 1
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for c := 0; c < width; c++ {
+			if c > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(s.dialect.placeholder(n))
+			n++
+		}
+		b.WriteByte(')')
+	}
+}
+
+// write returns the statement inserting or upserting rows rows
+func (s *statement) write(rows int) string {
+	var b strings.Builder
+	cols := strings.Join(s.columns, ", ")
+
+	if s.dialect == SQLServer && s.mode != Insert {
+		b.WriteString("MERGE INTO " + s.table + " AS target USING (VALUES ")
+		s.values(&b, rows, len(s.columns))
+		b.WriteString(") AS source (" + cols + ") ON ")
+		for i, k := range s.keys {
+			if i > 0 {
+				b.WriteString(" AND ")
+			}
+			b.WriteString("target." + k + " = source." + k)
+		}
+		if s.mode == Upsert && len(s.updates) > 0 {
+			b.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+			for i, c := range s.updates {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(c + " = source." + c)
+			}
+		}
+		b.WriteString(" WHEN NOT MATCHED THEN INSERT (" + cols + ") VALUES (")
+		for i, c := range s.columns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("source." + c)
+		}
+		b.WriteString(");")
+		return b.String()
+	}
+
+	if s.dialect == MySQL && s.mode == Ignore {
+		b.WriteString("INSERT IGNORE INTO ")
+	} else {
+		b.WriteString("INSERT INTO ")
+	}
+	b.WriteString(s.table + " (" + cols + ") VALUES ")
+	s.values(&b, rows, len(s.columns))
+
+	switch s.mode {
+	case Upsert:
+		updates := s.updates
+		if len(updates) == 0 {
+			// Nothing to update: keep the existing row
+			if s.dialect == MySQL {
+				updates = s.keys[:1] // A no-op assignment, MySQL has no DO NOTHING
+			} else {
+				b.WriteString(" ON CONFLICT (" + strings.Join(s.keys, ", ") + ") DO NOTHING")
+				return b.String()
+			}
+		}
+		if s.dialect == MySQL {
+			b.WriteString(" ON DUPLICATE KEY UPDATE ")
+			for i, c := range updates {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(c + " = VALUES(" + c + ")")
+			}
+		} else {
+			b.WriteString(" ON CONFLICT (" + strings.Join(s.keys, ", ") + ") DO UPDATE SET ")
+			for i, c := range updates {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(c + " = EXCLUDED." + c)
+			}
+		}
+	case Ignore:
+		if s.dialect != MySQL {
+			b.WriteString(" ON CONFLICT DO NOTHING")
+		}
+	}
+	return b.String()
+}
+
+// delete returns the statement deleting rows rows by key
+func (s *statement) delete(rows int) string {
+	var b strings.Builder
+	b.WriteString("DELETE FROM " + s.table + " WHERE ")
+
+	n := 1
+	if len(s.keys) == 1 {
+		b.WriteString(s.keys[0] + " IN (")
+		for r := 0; r < rows; r++ {
+			if r > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(s.dialect.placeholder(n))
+			n++
+		}
+		b.WriteByte(')')
+		return b.String()
+	}
+
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(" OR ")
+		}
+		b.WriteByte('(')
+		for i, k := range s.keys {
+			if i > 0 {
+				b.WriteString(" AND ")
+			}
+			b.WriteString(k + " = " + s.dialect.placeholder(n))
+			n++
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
@@ -0,0 +1,225 @@
+// Package sqlsink writes batches to any database/sql driver with
+// multi-row INSERT, upsert and delete statements in the database's dialect
+package sqlsink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/internal/dbfields"
+)
+
+// Mode is how rows that already exist are handled
+type Mode int
+
+const (
+	Upsert Mode = iota // Update the existing row (requires KeyColumns)
+	Insert             // Plain INSERT, a duplicate key fails the batch
+	Ignore             // Keep the existing row
+)
+
+// Config configures a SQL sink
+// Table and column names are quoted, but must still come from trusted
+// configuration.
+type Config[T any] struct {
+	DB      *sql.DB
+	Dialect Dialect
+	Table   string // Optionally schema-qualified, e.g. "sales.orders"
+
+	// KeyColumns identify a row: the conflict target of upserts (a primary
+	// key or unique index) and the columns deletes match on
+	KeyColumns []string
+	Mode       Mode
+
+	// UpdateColumns are overwritten when a row exists (defaults to every
+	// non-key column)
+	UpdateColumns []string
+
+	// BatchSize is the number of rows per statement (defaults to 500), lowered
+	// to stay within the dialect's bind parameter limit
+	BatchSize int
+
+	// Delete reports whether an item is a deletion, e.g. a CDC delete event;
+	// such items are deleted by their key columns instead of written
+	Delete func(item T) bool
+}
+
+// Sink writes batches of struct T, whose fields map to columns by their
+// `db` tag or snake_cased name
+// Each Load runs in one transaction. Within a batch the last item of a key
+// wins, so a batch of CDC events applies the final state of each row.
+type Sink[T any] struct {
+	cfg       Config[T]
+	stmt      statement
+	fields    []dbfields.Field
+	keyFields [][]int
+	rows      int // Rows per statement
+
+	mu    sync.Mutex
+	cache map[string]string // SQL by kind and row count
+}
+
+// New creates a SQL sink
+func New[T any](cfg Config[T]) (*Sink[T], error) {
+	if cfg.DB == nil || cfg.Table == "" {
+		return nil, fmt.Errorf("sqlsink: DB and Table are required")
+	}
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sqlsink: %s is not a struct", t)
+	}
+	if len(cfg.KeyColumns) == 0 && (cfg.Mode == Upsert || cfg.Delete != nil) {
+		return nil, fmt.Errorf("sqlsink: KeyColumns are required for upserts and deletes")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+
+	fields := dbfields.Of(t)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("sqlsink: %s has no exported fields", t)
+	}
+	byColumn := make(map[string][]int, len(fields))
+	for _, f := range fields {
+		byColumn[f.Column] = f.Index
+	}
+
+	s := &Sink[T]{
+		cfg:    cfg,
+		fields: fields,
+		cache:  make(map[string]string),
+		stmt:   statement{dialect: cfg.Dialect, table: cfg.Dialect.quote(cfg.Table), mode: cfg.Mode},
+	}
+	for _, f := range fields {
+		s.stmt.columns = append(s.stmt.columns, cfg.Dialect.quote(f.Column))
+	}
+	for _, k := range cfg.KeyColumns {
+		index, ok := byColumn[k]
+		if !ok {
+			return nil, fmt.Errorf("sqlsink: %s has no field for key column %s", t, k)
+		}
+		s.keyFields = append(s.keyFields, index)
+		s.stmt.keys = append(s.stmt.keys, cfg.Dialect.quote(k))
+	}
+
+	updates := cfg.UpdateColumns
+	if updates == nil {
+		for _, f := range fields {
+			if !slices.Contains(cfg.KeyColumns, f.Column) {
+				updates = append(updates, f.Column)
+			}
+		}
+	}
+	for _, c := range updates {
+		if _, ok := byColumn[c]; !ok {
+			return nil, fmt.Errorf("sqlsink: %s has no field for update column %s", t, c)
+		}
+		s.stmt.updates = append(s.stmt.updates, cfg.Dialect.quote(c))
+	}
+
+	s.rows = min(cfg.BatchSize, cfg.Dialect.maxRows(), cfg.Dialect.maxParams()/len(fields))
+	return s, nil
+}
+
+// Load writes a batch in one transaction
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	writes, deletes := s.split(items)
+
+	tx, err := s.cfg.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlsink: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	for chunk := range slices.Chunk(deletes, s.rows) {
+		args := make([]any, 0, len(chunk)*len(s.keyFields))
+		for _, v := range chunk {
+			for _, index := range s.keyFields {
+				args = append(args, v.FieldByIndex(index).Interface())
+			}
+		}
+		if _, err := tx.ExecContext(ctx, s.sql("delete", len(chunk)), args...); err != nil {
+			return fmt.Errorf("sqlsink: delete from %s: %w", s.cfg.Table, err)
+		}
+	}
+
+	for chunk := range slices.Chunk(writes, s.rows) {
+		args := make([]any, 0, len(chunk)*len(s.fields))
+		for _, v := range chunk {
+			for _, f := range s.fields {
+				args = append(args, v.FieldByIndex(f.Index).Interface())
+			}
+		}
+		if _, err := tx.ExecContext(ctx, s.sql("write", len(chunk)), args...); err != nil {
+			return fmt.Errorf("sqlsink: write to %s: %w", s.cfg.Table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlsink: commit: %w", err)
+	}
+	return nil
+}
+
+// split separates deletions from writes, keeping only the last item of each
+// key; a single statement cannot touch a row twice
+func (s *Sink[T]) split(items []T) (writes, deletes []reflect.Value) {
+	if len(s.keyFields) == 0 || s.cfg.Mode == Insert && s.cfg.Delete == nil {
+		for i := range items {
+			writes = append(writes, reflect.ValueOf(&items[i]).Elem())
+		}
+		return writes, nil
+	}
+
+	last := make(map[string]int, len(items))
+	var key strings.Builder
+	for i := range items {
+		v := reflect.ValueOf(&items[i]).Elem()
+		key.Reset()
+		for _, index := range s.keyFields {
+			fmt.Fprintf(&key, "%v\x00", v.FieldByIndex(index).Interface())
+		}
+		last[key.String()] = i
+	}
+
+	keep := make([]int, 0, len(last))
+	for _, i := range last {
+		keep = append(keep, i)
+	}
+	slices.Sort(keep)
+
+	for _, i := range keep {
+		v := reflect.ValueOf(&items[i]).Elem()
+		if s.cfg.Delete != nil && s.cfg.Delete(items[i]) {
+			deletes = append(deletes, v)
+		} else {
+			writes = append(writes, v)
+		}
+	}
+	return writes, deletes
+}
+
+// sql returns the statement of kind for rows rows, building it once
+func (s *Sink[T]) sql(kind string, rows int) string {
+	key := fmt.Sprintf("%s/%d", kind, rows)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if q, ok := s.cache[key]; ok {
+		return q
+	}
+	var q string
+	if kind == "delete" {
+		q = s.stmt.delete(rows)
+	} else {
+		q = s.stmt.write(rows)
+	}
+	s.cache[key] = q
+	return q
+}
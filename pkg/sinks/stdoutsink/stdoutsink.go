@@ -0,0 +1,67 @@
+// Package stdoutsink writes records to stdout, stderr or any writer, so
+// pipelines can feed shell pipelines
+package stdoutsink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/sinks/internal/encode"
+)
+
+// Format is the output format
+type Format = encode.Format
+
+const (
+	JSONL = encode.JSONL
+	CSV   = encode.CSV
+)
+
+// Config configures a stdout sink
+type Config struct {
+	Writer io.Writer // Defaults to os.Stdout; use os.Stderr for diagnostics
+	Format Format    // Defaults to JSONL
+
+	// Columns fixes the CSV column order (see the CSV format)
+	Columns []string
+}
+
+// Sink writes each batch and flushes it, so downstream commands see records
+// as soon as they are loaded
+type Sink[T any] struct {
+	mu sync.Mutex
+	w  *encode.Writer[T]
+}
+
+// New creates a stdout sink
+func New[T any](cfg Config) (*Sink[T], error) {
+	if cfg.Writer == nil {
+		cfg.Writer = os.Stdout
+	}
+	if cfg.Format == "" {
+		cfg.Format = JSONL
+	}
+
+	w, err := encode.NewWriter[T](cfg.Writer, cfg.Format, cfg.Columns)
+	if err != nil {
+		return nil, fmt.Errorf("stdoutsink: %w", err)
+	}
+	return &Sink[T]{w: w}, nil
+}
+
+// Load writes a batch
+func (s *Sink[T]) Load(ctx context.Context, items []T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.w.Write(items); err != nil {
+		return fmt.Errorf("stdoutsink: %w", err)
+	}
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("stdoutsink: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package avrosource streams records from Avro object container files
+package avrosource
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hamba/avro/v2/ocf"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures an Avro source
+type Config struct {
+	// Paths are files or glob patterns, read in order
+	Paths []string
+}
+
+// RecordError reports a record or file that could not be decoded
+// The ETL skips a record that could not be decoded (see etl.RecordError),
+// while a file that cannot be opened or has a corrupt block fails the run.
+type RecordError struct {
+	File   string
+	Record int // 0 when the whole file failed
+	Err    error
+
+	skipped bool // The error is about this record alone
+}
+
+func (e *RecordError) Error() string {
+	if e.Record == 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s: record %d: %v", e.File, e.Record, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord reports whether the error is about a single record, whose
+// encoded content is not kept
+func (e *RecordError) RawRecord() ([]byte, bool) {
+	return nil, e.skipped
+}
+
+// Source streams the records of Avro container files decoded into T
+// Each file is decoded with the writer schema embedded in it; struct fields
+// are matched by their `avro` tag.
+type Source[T any] struct {
+	cfg Config
+}
+
+// New creates an Avro source
+func New[T any](cfg Config) (*Source[T], error) {
+	if len(cfg.Paths) == 0 {
+		return nil, fmt.Errorf("avrosource: at least one path is required")
+	}
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract streams the records of every file
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	var files []string
+	for _, pattern := range s.cfg.Paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("avrosource: invalid pattern %q: %w", pattern, err)
+		}
+		if matches == nil {
+			return nil, fmt.Errorf("avrosource: no files match %q", pattern)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+
+	ch := make(chan etl.Payload[T], 100)
+
+	go func() {
+		defer close(ch)
+
+		for _, file := range files {
+			if !s.readFile(ctx, file, ch) {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// readFile streams one file, reporting false if ctx was cancelled
+func (s *Source[T]) readFile(ctx context.Context, file string, ch chan<- etl.Payload[T]) bool {
+	emit := func(p etl.Payload[T]) bool {
+		select {
+		case ch <- p:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return emit(etl.Payload[T]{Err: &RecordError{File: file, Err: err}})
+	}
+	defer f.Close()
+
+	dec, err := ocf.NewDecoder(f)
+	if err != nil {
+		return emit(etl.Payload[T]{Err: &RecordError{File: file, Err: err}})
+	}
+
+	record := 0
+	for dec.HasNext() {
+		record++
+
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			if !emit(etl.Payload[T]{Err: &RecordError{File: file, Record: record, Err: err, skipped: true}}) {
+				return false
+			}
+			continue
+		}
+		if !emit(etl.Payload[T]{Data: item}) {
+			return false
+		}
+	}
+
+	// A corrupt block ends the file; there is no way to resync past it
+	if err := dec.Error(); err != nil {
+		return emit(etl.Payload[T]{Err: &RecordError{File: file, Record: record + 1, Err: err}})
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+// Package azblobsource ingests the blobs under an Azure Blob Storage prefix
+package azblobsource
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/sources/objectsource"
+)
+
+// Config configures an Azure Blob source
+type Config[T any] struct {
+	Client    *azblob.Client
+	Container string
+	Prefix    string
+
+	Decoder        objectsource.Decoder[T]
+	Checkpoints    checkpoint.Store
+	CheckpointName string // Defaults to the URL of the prefix
+}
+
+// New creates an object source reading an Azure Blob prefix
+func New[T any](cfg Config[T]) (*objectsource.Source[T], error) {
+	store, err := NewStore(cfg.Client, cfg.Container)
+	if err != nil {
+		return nil, err
+	}
+	return objectsource.New(objectsource.Config[T]{
+		Store:          store,
+		Prefix:         cfg.Prefix,
+		Decoder:        cfg.Decoder,
+		Checkpoints:    cfg.Checkpoints,
+		CheckpointName: cfg.CheckpointName,
+	})
+}
+
+// Store is the objectsource.ObjectStore of a blob container
+// Object versions are ETags.
+type Store struct {
+	client    *azblob.Client
+	container string
+}
+
+// NewStore creates a store for container
+func NewStore(client *azblob.Client, container string) (*Store, error) {
+	if client == nil || container == "" {
+		return nil, fmt.Errorf("azblobsource: client and container are required")
+	}
+	return &Store{client: client, container: container}, nil
+}
+
+// List lists the blobs under prefix
+func (s *Store) List(ctx context.Context, prefix string, fn func(objectsource.Object) error) error {
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list %s: %w", s.URL(prefix), err)
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			obj := objectsource.Object{Key: *item.Name}
+			if props := item.Properties; props != nil {
+				if props.ContentLength != nil {
+					obj.Size = *props.ContentLength
+				}
+				if props.ETag != nil {
+					obj.Version = string(*props.ETag)
+				}
+			}
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Open reads a blob if its ETag still matches
+func (s *Store) Open(ctx context.Context, obj objectsource.Object) (io.ReadCloser, error) {
+	etag := azcore.ETag(obj.Version)
+	resp, err := s.client.DownloadStream(ctx, s.container, obj.Key, &azblob.DownloadStreamOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: &etag},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// URL returns the blob's URL
+func (s *Store) URL(key string) string {
+	return s.client.URL() + s.container + "/" + key
+}
@@ -0,0 +1,103 @@
+// Package bigquerysource reads BigQuery tables and query results
+package bigquerysource
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// NewClient creates a BigQuery client that reads rows through the Storage
+// Read API, which streams large results in parallel instead of paging
+// through tabledata.list
+func NewClient(ctx context.Context, projectID string, opts ...option.ClientOption) (*bigquery.Client, error) {
+	client, err := bigquery.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.EnableStorageReadClient(ctx, opts...); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("bigquerysource: enable storage read client: %w", err)
+	}
+	return client, nil
+}
+
+// Config configures a BigQuery source
+// Exactly one of Table and Query must be set.
+type Config struct {
+	// Client should come from NewClient to use the Storage Read API
+	Client *bigquery.Client
+
+	// Table is read in full, e.g. client.Dataset("d").Table("t")
+	Table *bigquery.Table
+	// Query is run and its results read
+	Query      string
+	Parameters []bigquery.QueryParameter
+}
+
+// Source streams rows into T, matching columns to the `bigquery` tags or
+// names of T's fields
+type Source[T any] struct {
+	cfg Config
+}
+
+// New creates a BigQuery source
+func New[T any](cfg Config) (*Source[T], error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("bigquerysource: client is required")
+	}
+	if (cfg.Table == nil) == (cfg.Query == "") {
+		return nil, fmt.Errorf("bigquerysource: exactly one of table and query must be set")
+	}
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract reads the table or runs the query and streams its rows
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	var it *bigquery.RowIterator
+	if s.cfg.Table != nil {
+		it = s.cfg.Table.Read(ctx)
+	} else {
+		q := s.cfg.Client.Query(s.cfg.Query)
+		q.Parameters = s.cfg.Parameters
+
+		var err error
+		if it, err = q.Read(ctx); err != nil {
+			return nil, fmt.Errorf("bigquerysource: run query: %w", err)
+		}
+	}
+
+	ch := make(chan etl.Payload[T], 1000)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			var row T
+			err := it.Next(&row)
+			if err == iterator.Done {
+				return
+			}
+
+			p := etl.Payload[T]{Data: row}
+			if err != nil {
+				p = etl.Payload[T]{Err: fmt.Errorf("bigquerysource: read row: %w", err)}
+			}
+			select {
+			case ch <- p:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
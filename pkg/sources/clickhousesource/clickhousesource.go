@@ -0,0 +1,84 @@
+// Package clickhousesource streams query results from ClickHouse over the
+// native protocol
+package clickhousesource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a ClickHouse source
+type Config struct {
+	Conn  driver.Conn
+	Query string
+	Args  []any
+
+	// Settings are applied to the query, e.g. max_block_size or
+	// max_execution_time
+	Settings clickhouse.Settings
+}
+
+// Source streams the rows of a query into T, matching columns to the `ch`
+// tags of T's fields
+// Rows arrive in native blocks, so memory stays bounded by the block size.
+type Source[T any] struct {
+	cfg Config
+}
+
+// New creates a ClickHouse source
+func New[T any](cfg Config) (*Source[T], error) {
+	if cfg.Conn == nil || cfg.Query == "" {
+		return nil, fmt.Errorf("clickhousesource: connection and query are required")
+	}
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract runs the query and streams its rows
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	queryCtx := ctx
+	if len(s.cfg.Settings) > 0 {
+		queryCtx = clickhouse.Context(ctx, clickhouse.WithSettings(s.cfg.Settings))
+	}
+
+	rows, err := s.cfg.Conn.Query(queryCtx, s.cfg.Query, s.cfg.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("clickhousesource: query: %w", err)
+	}
+
+	ch := make(chan etl.Payload[T], 1000)
+
+	go func() {
+		defer close(ch)
+		defer rows.Close()
+
+		send := func(p etl.Payload[T]) bool {
+			select {
+			case ch <- p:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for rows.Next() {
+			var item T
+			if err := rows.ScanStruct(&item); err != nil {
+				send(etl.Payload[T]{Err: fmt.Errorf("clickhousesource: scan: %w", err)})
+				return
+			}
+			if !send(etl.Payload[T]{Data: item}) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			send(etl.Payload[T]{Err: fmt.Errorf("clickhousesource: %w", err)})
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,224 @@
+// Package cqlsource reads Cassandra and ScyllaDB tables by token range
+package cqlsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/gocql/gocql"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a CQL source
+type Config[T any] struct {
+	Session *gocql.Session
+	Table   string // keyspace.table
+
+	// PartitionKey lists the partition key columns, in order (required)
+	PartitionKey []string
+	// Columns are the selected columns (defaults to *)
+	Columns []string
+	// Where adds conditions to every range query; it usually needs ALLOW
+	// FILTERING in Suffix
+	Where  string
+	Args   []any
+	Suffix string
+
+	// Splits is the number of token ranges the ring is cut into (defaults to
+	// 64); Parallelism bounds the ranges read at once (defaults to 4)
+	Splits      int
+	Parallelism int
+	PageSize    int // Defaults to 1000
+	Consistency gocql.Consistency
+
+	// Map converts a row to T; required unless T is map[string]any
+	Map func(row map[string]any) (T, error)
+}
+
+// MapError reports a row that Config.Map could not convert
+// The source carries on with the next row, and the ETL skips it (see
+// etl.RecordError).
+type MapError struct {
+	Err error
+
+	row map[string]any
+}
+
+func (e *MapError) Error() string {
+	return fmt.Sprintf("cqlsource: map row: %v", e.Err)
+}
+
+func (e *MapError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord returns the columns of the row as a JSON object
+func (e *MapError) RawRecord() ([]byte, bool) {
+	raw, _ := json.Marshal(e.row)
+	return raw, true
+}
+
+// Source streams a table by splitting the Murmur3 token ring into ranges and
+// reading several ranges concurrently, each on the replicas that own it
+type Source[T any] struct {
+	cfg   Config[T]
+	query string
+}
+
+// New creates a CQL source
+func New[T any](cfg Config[T]) (*Source[T], error) {
+	if cfg.Session == nil || cfg.Table == "" || len(cfg.PartitionKey) == 0 {
+		return nil, fmt.Errorf("cqlsource: session, table and partition key are required")
+	}
+	if cfg.Map == nil {
+		var zero T
+		if _, ok := any(zero).(map[string]any); !ok {
+			return nil, fmt.Errorf("cqlsource: a map function is required for %T", zero)
+		}
+		cfg.Map = func(row map[string]any) (T, error) {
+			return any(row).(T), nil
+		}
+	}
+	if cfg.Splits == 0 {
+		cfg.Splits = 64
+	}
+	if cfg.Parallelism == 0 {
+		cfg.Parallelism = 4
+	}
+	if cfg.PageSize == 0 {
+		cfg.PageSize = 1000
+	}
+	if cfg.Consistency == 0 {
+		cfg.Consistency = gocql.LocalQuorum
+	}
+
+	columns := "*"
+	if len(cfg.Columns) > 0 {
+		columns = strings.Join(cfg.Columns, ", ")
+	}
+	token := "token(" + strings.Join(cfg.PartitionKey, ", ") + ")"
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s >= ? AND %s <= ?", columns, cfg.Table, token, token)
+	if cfg.Where != "" {
+		query += " AND " + cfg.Where
+	}
+	if cfg.Suffix != "" {
+		query += " " + cfg.Suffix
+	}
+
+	return &Source[T]{cfg: cfg, query: query}, nil
+}
+
+// tokenRange is an inclusive range of Murmur3 tokens
+type tokenRange struct {
+	start, end int64
+}
+
+// splitRing cuts the Murmur3 ring [-2^63, 2^63-1] into n contiguous ranges
+func splitRing(n int) []tokenRange {
+	lo := big.NewInt(math.MinInt64)
+	width := new(big.Int).Sub(big.NewInt(math.MaxInt64), lo)
+	width.Add(width, big.NewInt(1))
+
+	ranges := make([]tokenRange, n)
+	start := lo.Int64()
+	for i := range n {
+		// end = lo + width*(i+1)/n - 1
+		end := new(big.Int).Mul(width, big.NewInt(int64(i+1)))
+		end.Div(end, big.NewInt(int64(n)))
+		end.Add(end, lo)
+		end.Sub(end, big.NewInt(1))
+
+		ranges[i] = tokenRange{start: start, end: end.Int64()}
+		start = end.Int64() + 1
+	}
+	return ranges
+}
+
+// Extract reads every token range
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	ranges := make(chan tokenRange)
+	ch := make(chan etl.Payload[T], s.cfg.PageSize)
+
+	go func() {
+		defer close(ranges)
+		for _, r := range splitRing(s.cfg.Splits) {
+			select {
+			case ranges <- r:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range s.cfg.Parallelism {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range ranges {
+				if !s.readRange(ctx, r, ch) {
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// readRange streams one token range, reporting false if extraction must stop
+func (s *Source[T]) readRange(ctx context.Context, r tokenRange, ch chan<- etl.Payload[T]) bool {
+	send := func(p etl.Payload[T]) bool {
+		select {
+		case ch <- p:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	args := append([]any{r.start, r.end}, s.cfg.Args...)
+	iter := s.cfg.Session.Query(s.query, args...).
+		WithContext(ctx).
+		PageSize(s.cfg.PageSize).
+		Consistency(s.cfg.Consistency).
+		Iter()
+
+	for {
+		row := make(map[string]any)
+		if !iter.MapScan(row) {
+			break
+		}
+		item, err := s.cfg.Map(row)
+		if err != nil {
+			if !send(etl.Payload[T]{Err: &MapError{Err: err, row: row}}) {
+				iter.Close()
+				return false
+			}
+			continue
+		}
+		if !send(etl.Payload[T]{Data: item}) {
+			iter.Close()
+			return false
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		if ctx.Err() == nil {
+			send(etl.Payload[T]{Err: fmt.Errorf("cqlsource: read tokens %d..%d: %w", r.start, r.end, err)})
+		}
+		return false
+	}
+	return true
+}
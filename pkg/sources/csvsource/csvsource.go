@@ -0,0 +1,286 @@
+// Package csvsource streams records from CSV files
+package csvsource
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/sources/internal/rowdecode"
+)
+
+// Config configures a CSV source
+type Config struct {
+	// Paths are files or glob patterns, read in order; the matches of a
+	// pattern are read in lexical order. Files ending in .gz are
+	// decompressed.
+	Paths []string
+	// Reader is read instead of Paths when set; Name labels it in errors
+	// (defaults to "reader")
+	Reader io.Reader
+	Name   string
+
+	Comma            rune // Field delimiter (defaults to ',')
+	Comment          rune // Lines starting with it are skipped, 0 for none
+	LazyQuotes       bool // Accept bare and unescaped quotes inside fields
+	TrimLeadingSpace bool // Ignore leading white space in fields
+
+	// Header names the columns of files without a header row; when empty
+	// the first row of each file is the header
+	Header []string
+}
+
+// RecordError reports a line that could not be read or decoded
+// The source emits it as a Payload error and carries on with the next
+// line; the ETL skips a malformed or undecodable line (see
+// etl.RecordError), while a file that cannot be opened or read fails the
+// run.
+type RecordError struct {
+	File string
+	Line int // 0 when the whole file failed
+	Err  error
+
+	skipped bool   // The error is about this line alone
+	raw     []byte // The line, CSV-encoded, if it could be parsed
+}
+
+func (e *RecordError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord returns the line, if it could be parsed, and false if the error
+// is not about a single line
+func (e *RecordError) RawRecord() ([]byte, bool) {
+	return e.raw, e.skipped
+}
+
+// Source streams CSV records decoded into T, which is either
+// map[string]string or a struct whose fields are matched to columns by
+// their `csv` tag or, case-insensitively, by name
+type Source[T any] struct {
+	cfg     Config
+	decoder *rowdecode.Decoder[T]
+}
+
+// New creates a CSV source
+func New[T any](cfg Config) (*Source[T], error) {
+	if len(cfg.Paths) == 0 && cfg.Reader == nil {
+		return nil, fmt.Errorf("csvsource: a path or reader is required")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "reader"
+	}
+	if cfg.Comma == 0 {
+		cfg.Comma = ','
+	}
+
+	dec, err := rowdecode.New[T]("csv")
+	if err != nil {
+		return nil, fmt.Errorf("csvsource: %w", err)
+	}
+	return &Source[T]{cfg: cfg, decoder: dec}, nil
+}
+
+// Extract streams the records of every file
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	var files []string
+	if s.cfg.Reader == nil {
+		var err error
+		if files, err = s.files(); err != nil {
+			return nil, err
+		}
+	}
+
+	ch := make(chan etl.Payload[T], 100)
+
+	go func() {
+		defer close(ch)
+
+		if s.cfg.Reader != nil {
+			s.read(ctx, s.cfg.Name, s.cfg.Reader, ch)
+			return
+		}
+		for _, file := range files {
+			if !s.readFile(ctx, file, ch) {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// files expands the configured paths
+func (s *Source[T]) files() ([]string, error) {
+	var files []string
+	for _, pattern := range s.cfg.Paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("csvsource: invalid pattern %q: %w", pattern, err)
+		}
+		if matches == nil {
+			return nil, fmt.Errorf("csvsource: no files match %q", pattern)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// readFile streams one file, reporting false if ctx was cancelled
+func (s *Source[T]) readFile(ctx context.Context, file string, ch chan<- etl.Payload[T]) bool {
+	r, closeFile, err := open(file)
+	if err != nil {
+		select {
+		case ch <- etl.Payload[T]{Err: &RecordError{File: file, Err: err}}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	defer closeFile()
+
+	return s.read(ctx, file, r, ch)
+}
+
+// read streams the records of r, reporting false if ctx was cancelled
+func (s *Source[T]) read(ctx context.Context, file string, r io.Reader, ch chan<- etl.Payload[T]) bool {
+	emit := func(p etl.Payload[T]) bool {
+		select {
+		case ch <- p:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	fail := func(line int, err error) bool {
+		return emit(etl.Payload[T]{Err: &RecordError{File: file, Line: line, Err: err}})
+	}
+	skip := func(line int, record []string, err error) bool {
+		recErr := &RecordError{File: file, Line: line, Err: err, skipped: true}
+		if record != nil {
+			var b bytes.Buffer
+			w := csv.NewWriter(&b)
+			w.Comma = s.cfg.Comma
+			_ = w.Write(record)
+			w.Flush()
+			recErr.raw = bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+		}
+		return emit(etl.Payload[T]{Err: recErr})
+	}
+
+	reader := s.newReader(r)
+	header := s.cfg.Header
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return true
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if !skip(parseErr.Line, nil, parseErr.Err) {
+					return false
+				}
+				continue
+			}
+			return fail(line, err)
+		}
+
+		if header == nil {
+			header = append([]string(nil), record...)
+			continue
+		}
+
+		item, err := s.decoder.Decode(header, record)
+		if err != nil {
+			if !skip(line, record, err) {
+				return false
+			}
+			continue
+		}
+		if !emit(etl.Payload[T]{Data: item}) {
+			return false
+		}
+	}
+}
+
+// Columns returns Header, or the header row of the first file, after
+// checking that every file can be opened, without reading any records
+func (s *Source[T]) Columns() ([]string, error) {
+	if s.cfg.Reader != nil {
+		return s.cfg.Header, nil
+	}
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := s.cfg.Header
+	for _, file := range files {
+		r, closeFile, err := open(file)
+		if err != nil {
+			return nil, &RecordError{File: file, Err: err}
+		}
+		if columns == nil {
+			columns, err = s.newReader(r).Read()
+			if err != nil && err != io.EOF {
+				closeFile()
+				return nil, &RecordError{File: file, Line: 1, Err: err}
+			}
+		}
+		closeFile()
+	}
+	return columns, nil
+}
+
+// newReader returns a CSV reader of r with the configured dialect
+func (s *Source[T]) newReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = s.cfg.Comma
+	reader.Comment = s.cfg.Comment
+	reader.LazyQuotes = s.cfg.LazyQuotes
+	reader.TrimLeadingSpace = s.cfg.TrimLeadingSpace
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return reader
+}
+
+// open opens a file, decompressing it if it ends in .gz
+func open(file string) (io.Reader, func(), error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !strings.HasSuffix(file, ".gz") {
+		return f, func() { f.Close() }, nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("gzip: %w", err)
+	}
+	return gz, func() {
+		gz.Close()
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,183 @@
+// Package dynamosource reads a DynamoDB table with a parallel scan
+package dynamosource
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Client is the subset of *dynamodb.Client the source uses
+type Client interface {
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// Config configures a DynamoDB source
+type Config struct {
+	Client Client
+	Table  string
+
+	// Segments is the number of parallel scan segments (defaults to 4)
+	Segments int
+	// Limit caps the items evaluated per Scan page, 0 for DynamoDB's 1MB pages
+	Limit int32
+
+	FilterExpression          string
+	ProjectionExpression      string
+	ExpressionAttributeNames  map[string]string
+	ExpressionAttributeValues map[string]types.AttributeValue
+	ConsistentRead            bool
+
+	// Checkpoints stores each segment's LastEvaluatedKey once its pages are
+	// loaded, so an interrupted scan resumes where it stopped; the
+	// checkpoint is deleted when the scan completes. Optional.
+	Checkpoints checkpoint.Store
+	// CheckpointName defaults to "dynamodb://<table>"
+	CheckpointName string
+}
+
+// Record is a decoded item and the scan page it came from
+type Record[T any] struct {
+	Segment int
+	Page    int64
+	Value   T
+}
+
+// Source streams the items of a table into T, matching attributes to the
+// `dynamodbav` tags of T's fields
+// Checkpoints advance through Commit, which the ETL calls once a batch is
+// loaded (see etl.BatchCommitter), so embed the source in the processor.
+type Source[T any] struct {
+	cfg     Config
+	tracker *tracker
+}
+
+// New creates a DynamoDB source
+func New[T any](cfg Config) (*Source[T], error) {
+	if cfg.Client == nil || cfg.Table == "" {
+		return nil, fmt.Errorf("dynamosource: client and table are required")
+	}
+	if cfg.Segments == 0 {
+		cfg.Segments = 4
+	}
+	if cfg.CheckpointName == "" {
+		cfg.CheckpointName = "dynamodb://" + cfg.Table
+	}
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract scans every segment concurrently
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[Record[T]], error) {
+	t, err := loadTracker(ctx, s.cfg.Checkpoints, s.cfg.CheckpointName, s.cfg.Segments)
+	if err != nil {
+		return nil, fmt.Errorf("dynamosource: %w", err)
+	}
+	s.tracker = t
+
+	ch := make(chan etl.Payload[Record[T]], 1000)
+
+	var wg sync.WaitGroup
+	for segment := 0; segment < s.cfg.Segments; segment++ {
+		startKey, done := t.resumeFrom(segment)
+		if done {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.scanSegment(ctx, segment, startKey, ch)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// scanSegment pages through one segment
+func (s *Source[T]) scanSegment(ctx context.Context, segment int, startKey map[string]types.AttributeValue, ch chan<- etl.Payload[Record[T]]) {
+	send := func(p etl.Payload[Record[T]]) bool {
+		select {
+		case ch <- p:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		input := &dynamodb.ScanInput{
+			TableName:                 aws.String(s.cfg.Table),
+			Segment:                   aws.Int32(int32(segment)),
+			TotalSegments:             aws.Int32(int32(s.cfg.Segments)),
+			ExclusiveStartKey:         startKey,
+			ConsistentRead:            aws.Bool(s.cfg.ConsistentRead),
+			ExpressionAttributeNames:  s.cfg.ExpressionAttributeNames,
+			ExpressionAttributeValues: s.cfg.ExpressionAttributeValues,
+		}
+		if s.cfg.Limit > 0 {
+			input.Limit = aws.Int32(s.cfg.Limit)
+		}
+		if s.cfg.FilterExpression != "" {
+			input.FilterExpression = aws.String(s.cfg.FilterExpression)
+		}
+		if s.cfg.ProjectionExpression != "" {
+			input.ProjectionExpression = aws.String(s.cfg.ProjectionExpression)
+		}
+
+		out, err := s.cfg.Client.Scan(ctx, input)
+		if err != nil {
+			if ctx.Err() == nil {
+				send(etl.Payload[Record[T]]{Err: fmt.Errorf("dynamosource: scan segment %d: %w", segment, err)})
+			}
+			return
+		}
+
+		items := make([]T, len(out.Items))
+		for i, raw := range out.Items {
+			if err := attributevalue.UnmarshalMap(raw, &items[i]); err != nil {
+				send(etl.Payload[Record[T]]{Err: fmt.Errorf("dynamosource: decode item: %w", err)})
+				return
+			}
+		}
+
+		page := s.tracker.startPage(segment, out.LastEvaluatedKey, len(items))
+		for _, item := range items {
+			if !send(etl.Payload[Record[T]]{Data: Record[T]{Segment: segment, Page: page, Value: item}}) {
+				return
+			}
+		}
+		if err := s.tracker.pageRead(ctx, segment, page); err != nil {
+			send(etl.Payload[Record[T]]{Err: fmt.Errorf("dynamosource: %w", err)})
+			return
+		}
+
+		if len(out.LastEvaluatedKey) == 0 {
+			return
+		}
+		startKey = out.LastEvaluatedKey
+	}
+}
+
+// Commit advances the checkpoint past the pages that are now fully loaded
+func (s *Source[T]) Commit(ctx context.Context, records []Record[T]) error {
+	refs := make([]pageRef, len(records))
+	for i, r := range records {
+		refs[i] = pageRef{segment: r.Segment, page: r.Page}
+	}
+	if err := s.tracker.loaded(ctx, refs); err != nil {
+		return fmt.Errorf("dynamosource: %w", err)
+	}
+	return nil
+}
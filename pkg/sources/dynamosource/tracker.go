@@ -0,0 +1,230 @@
+package dynamosource
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// position is the checkpoint of a scan: where each segment resumes
+type position struct {
+	Segments int                  `json:"segments"`
+	Keys     []map[string]keyAttr `json:"keys"` // nil: start of the segment
+	Done     []bool               `json:"done"`
+}
+
+// keyAttr is a key attribute value in JSON form; keys are only ever
+// strings, numbers or binary
+type keyAttr struct {
+	Type  string `json:"t"` // "S", "N" or "B"
+	Value string `json:"v"` // Binary values are base64 encoded
+}
+
+// tracker follows the pages of each segment and moves a segment's
+// checkpoint forward once every page before it is loaded
+type tracker struct {
+	store checkpoint.Store
+	name  string
+
+	mu       sync.Mutex
+	pos      position
+	segments []*segmentPages
+	seq      int64
+}
+
+type segmentPages struct {
+	pages []*page // In scan order, oldest first
+}
+
+type page struct {
+	id       int64
+	lastKey  map[string]types.AttributeValue // nil for the segment's last page
+	unloaded int
+	read     bool
+}
+
+// loadTracker reads the scan position from store, which may be nil
+func loadTracker(ctx context.Context, store checkpoint.Store, name string, segments int) (*tracker, error) {
+	t := &tracker{
+		store:    store,
+		name:     name,
+		pos:      position{Segments: segments, Keys: make([]map[string]keyAttr, segments), Done: make([]bool, segments)},
+		segments: make([]*segmentPages, segments),
+	}
+	for i := range t.segments {
+		t.segments[i] = &segmentPages{}
+	}
+	if store == nil {
+		return t, nil
+	}
+
+	cp, err := store.Get(ctx, name)
+	if errors.Is(err, checkpoint.ErrNotFound) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pos position
+	if err := json.Unmarshal(cp.Position, &pos); err != nil {
+		return nil, fmt.Errorf("decode checkpoint %s: %w", name, err)
+	}
+	if pos.Segments != segments || len(pos.Keys) != segments || len(pos.Done) != segments {
+		return nil, fmt.Errorf("checkpoint %s was written with %d segments, not %d", name, pos.Segments, segments)
+	}
+	t.pos = pos
+	return t, nil
+}
+
+// resumeFrom returns the start key of a segment and whether it is finished
+func (t *tracker) resumeFrom(segment int) (map[string]types.AttributeValue, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pos.Done[segment] {
+		return nil, true
+	}
+	return decodeKey(t.pos.Keys[segment]), false
+}
+
+// startPage registers a page of items and returns its id
+func (t *tracker) startPage(segment int, lastKey map[string]types.AttributeValue, items int) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	t.segments[segment].pages = append(t.segments[segment].pages, &page{id: t.seq, lastKey: lastKey, unloaded: items})
+	return t.seq
+}
+
+// pageRead marks a page as fully extracted
+func (t *tracker) pageRead(ctx context.Context, segment int, id int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range t.segments[segment].pages {
+		if p.id == id {
+			p.read = true
+		}
+	}
+	return t.settle(ctx, segment)
+}
+
+// pageRef identifies the page a loaded record came from
+type pageRef struct {
+	segment int
+	page    int64
+}
+
+// loaded counts one loaded record per ref and settles the touched segments
+func (t *tracker) loaded(ctx context.Context, refs []pageRef) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	touched := make(map[int]bool)
+	for _, ref := range refs {
+		for _, p := range t.segments[ref.segment].pages {
+			if p.id == ref.page {
+				p.unloaded--
+				break
+			}
+		}
+		touched[ref.segment] = true
+	}
+
+	for segment := range touched {
+		if err := t.settle(ctx, segment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle pops the leading pages of a segment that are read and loaded and
+// saves the segment's new position
+// Callers hold t.mu.
+func (t *tracker) settle(ctx context.Context, segment int) error {
+	seg := t.segments[segment]
+
+	advanced := false
+	for len(seg.pages) > 0 && seg.pages[0].read && seg.pages[0].unloaded <= 0 {
+		p := seg.pages[0]
+		seg.pages = seg.pages[1:]
+		if p.lastKey == nil {
+			t.pos.Done[segment] = true
+			t.pos.Keys[segment] = nil
+		} else {
+			t.pos.Keys[segment] = encodeKey(p.lastKey)
+		}
+		advanced = true
+	}
+	if !advanced || t.store == nil {
+		return nil
+	}
+
+	for _, done := range t.pos.Done {
+		if !done {
+			return t.save(ctx)
+		}
+	}
+	// The scan is complete, the next run starts over
+	return t.store.Delete(ctx, t.name)
+}
+
+// save writes the position
+func (t *tracker) save(ctx context.Context) error {
+	data, err := json.Marshal(t.pos)
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, &checkpoint.Checkpoint{
+		Pipeline:  t.name,
+		Position:  data,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// encodeKey converts a LastEvaluatedKey to its JSON form
+func encodeKey(key map[string]types.AttributeValue) map[string]keyAttr {
+	out := make(map[string]keyAttr, len(key))
+	for name, v := range key {
+		switch v := v.(type) {
+		case *types.AttributeValueMemberS:
+			out[name] = keyAttr{Type: "S", Value: v.Value}
+		case *types.AttributeValueMemberN:
+			out[name] = keyAttr{Type: "N", Value: v.Value}
+		case *types.AttributeValueMemberB:
+			out[name] = keyAttr{Type: "B", Value: base64.StdEncoding.EncodeToString(v.Value)}
+		}
+	}
+	return out
+}
+
+// decodeKey converts a stored key back to an ExclusiveStartKey
+func decodeKey(key map[string]keyAttr) map[string]types.AttributeValue {
+	if key == nil {
+		return nil
+	}
+	out := make(map[string]types.AttributeValue, len(key))
+	for name, v := range key {
+		switch v.Type {
+		case "S":
+			out[name] = &types.AttributeValueMemberS{Value: v.Value}
+		case "N":
+			out[name] = &types.AttributeValueMemberN{Value: v.Value}
+		case "B":
+			b, _ := base64.StdEncoding.DecodeString(v.Value)
+			out[name] = &types.AttributeValueMemberB{Value: b}
+		}
+	}
+	return out
+}
@@ -0,0 +1,279 @@
+// Package elasticsource reads an Elasticsearch or OpenSearch index with a
+// point in time and search_after
+package elasticsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Flavor selects the point in time API of the cluster
+type Flavor int
+
+const (
+	Elasticsearch Flavor = iota
+	OpenSearch
+)
+
+// Config configures an Elasticsearch source
+type Config struct {
+	URL        string // e.g. http://localhost:9200
+	Header     http.Header
+	HTTPClient *http.Client // Defaults to a client with a 60s timeout
+	Flavor     Flavor
+
+	Index string
+	// Query is the search query (defaults to match_all)
+	Query json.RawMessage
+	// Sort must end in a unique tiebreaker (defaults to _shard_doc on
+	// Elasticsearch and _id on OpenSearch)
+	Sort json.RawMessage
+	// Source limits the returned fields, e.g. ["id", "name"]
+	Source []string
+
+	PageSize  int           // Defaults to 1000
+	KeepAlive time.Duration // Point in time lifetime between pages (defaults to 5m)
+}
+
+// Hit is one document
+type Hit[T any] struct {
+	Index  string
+	ID     string
+	Source T
+	Sort   []any // Sort values, usable as a search_after position
+}
+
+// DecodeError reports a document whose source could not be unmarshalled
+// into T
+// The source carries on with the next document, and the ETL skips it (see
+// etl.RecordError).
+type DecodeError struct {
+	Index string
+	ID    string
+	Err   error
+
+	source json.RawMessage
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("elasticsource: decode %s/%s: %v", e.Index, e.ID, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord returns the source of the document
+func (e *DecodeError) RawRecord() ([]byte, bool) {
+	return e.source, true
+}
+
+// Source streams every document matching the query
+// A point in time keeps the view consistent while paging, however deep.
+type Source[T any] struct {
+	cfg Config
+}
+
+// New creates an Elasticsearch source
+func New[T any](cfg Config) (*Source[T], error) {
+	if cfg.URL == "" || cfg.Index == "" {
+		return nil, fmt.Errorf("elasticsource: URL and index are required")
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	if cfg.Query == nil {
+		cfg.Query = json.RawMessage(`{"match_all":{}}`)
+	}
+	if cfg.Sort == nil {
+		cfg.Sort = json.RawMessage(`[{"_shard_doc":"asc"}]`)
+		if cfg.Flavor == OpenSearch {
+			cfg.Sort = json.RawMessage(`[{"_id":"asc"}]`)
+		}
+	}
+	if cfg.PageSize == 0 {
+		cfg.PageSize = 1000
+	}
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = 5 * time.Minute
+	}
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract opens a point in time and pages through it
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[Hit[T]], error) {
+	pit, err := s.openPIT(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("elasticsource: open point in time: %w", err)
+	}
+
+	ch := make(chan etl.Payload[Hit[T]], s.cfg.PageSize)
+
+	go func() {
+		defer close(ch)
+		defer s.closePIT(pit)
+
+		send := func(p etl.Payload[Hit[T]]) bool {
+			select {
+			case ch <- p:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var searchAfter []any
+		for {
+			page, err := s.search(ctx, pit, searchAfter)
+			if err != nil {
+				if ctx.Err() == nil {
+					send(etl.Payload[Hit[T]]{Err: fmt.Errorf("elasticsource: search: %w", err)})
+				}
+				return
+			}
+			if page.PitID != "" {
+				pit = page.PitID // The id may change between requests
+			}
+
+			for _, h := range page.Hits.Hits {
+				hit := Hit[T]{Index: h.Index, ID: h.ID, Sort: h.Sort}
+				if err := json.Unmarshal(h.Source, &hit.Source); err != nil {
+					if !send(etl.Payload[Hit[T]]{Err: &DecodeError{Index: h.Index, ID: h.ID, Err: err, source: h.Source}}) {
+						return
+					}
+					continue
+				}
+				if !send(etl.Payload[Hit[T]]{Data: hit}) {
+					return
+				}
+			}
+
+			if len(page.Hits.Hits) < s.cfg.PageSize {
+				return
+			}
+			searchAfter = page.Hits.Hits[len(page.Hits.Hits)-1].Sort
+		}
+	}()
+
+	return ch, nil
+}
+
+type searchResponse struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Hits []struct {
+			Index  string          `json:"_index"`
+			ID     string          `json:"_id"`
+			Source json.RawMessage `json:"_source"`
+			Sort   []any           `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// search fetches the page after searchAfter
+func (s *Source[T]) search(ctx context.Context, pit string, searchAfter []any) (*searchResponse, error) {
+	body := map[string]any{
+		"size":             s.cfg.PageSize,
+		"query":            s.cfg.Query,
+		"sort":             s.cfg.Sort,
+		"pit":              map[string]any{"id": pit, "keep_alive": s.keepAlive()},
+		"track_total_hits": false,
+	}
+	if s.cfg.Source != nil {
+		body["_source"] = s.cfg.Source
+	}
+	if searchAfter != nil {
+		body["search_after"] = searchAfter
+	}
+
+	var resp searchResponse
+	if err := s.do(ctx, http.MethodPost, "/_search", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// openPIT opens a point in time on the index
+func (s *Source[T]) openPIT(ctx context.Context) (string, error) {
+	var resp struct {
+		ID    string `json:"id"`
+		PitID string `json:"pit_id"`
+	}
+
+	index := url.PathEscape(s.cfg.Index)
+	path := "/" + index + "/_pit?keep_alive=" + s.keepAlive()
+	if s.cfg.Flavor == OpenSearch {
+		path = "/" + index + "/_search/point_in_time?keep_alive=" + s.keepAlive()
+	}
+	if err := s.do(ctx, http.MethodPost, path, nil, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.PitID != "" {
+		return resp.PitID, nil
+	}
+	return resp.ID, nil
+}
+
+// closePIT releases a point in time; it expires on its own if this fails
+func (s *Source[T]) closePIT(pit string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if s.cfg.Flavor == OpenSearch {
+		s.do(ctx, http.MethodDelete, "/_search/point_in_time", map[string]any{"pit_id": []string{pit}}, nil)
+		return
+	}
+	s.do(ctx, http.MethodDelete, "/_pit", map[string]any{"id": pit}, nil)
+}
+
+// keepAlive formats KeepAlive as a time unit the cluster accepts
+func (s *Source[T]) keepAlive() string {
+	return fmt.Sprintf("%ds", int(s.cfg.KeepAlive.Seconds()))
+}
+
+// do sends a JSON request and decodes the response into out, if not nil
+func (s *Source[T]) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	for k, v := range s.cfg.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
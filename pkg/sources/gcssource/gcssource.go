@@ -0,0 +1,98 @@
+// Package gcssource ingests the objects under a Google Cloud Storage prefix
+package gcssource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/sources/objectsource"
+)
+
+// Config configures a GCS source
+type Config[T any] struct {
+	Client *storage.Client
+	Bucket string
+	Prefix string
+
+	Decoder        objectsource.Decoder[T]
+	Checkpoints    checkpoint.Store
+	CheckpointName string // Defaults to "gs://<bucket>/<prefix>"
+}
+
+// New creates an object source reading a GCS prefix
+func New[T any](cfg Config[T]) (*objectsource.Source[T], error) {
+	store, err := NewStore(cfg.Client, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return objectsource.New(objectsource.Config[T]{
+		Store:          store,
+		Prefix:         cfg.Prefix,
+		Decoder:        cfg.Decoder,
+		Checkpoints:    cfg.Checkpoints,
+		CheckpointName: cfg.CheckpointName,
+	})
+}
+
+// Store is the objectsource.ObjectStore of a GCS bucket
+// Object versions are generation numbers.
+type Store struct {
+	bucket *storage.BucketHandle
+	name   string
+}
+
+// NewStore creates a store for bucket
+func NewStore(client *storage.Client, bucket string) (*Store, error) {
+	if client == nil || bucket == "" {
+		return nil, fmt.Errorf("gcssource: client and bucket are required")
+	}
+	return &Store{bucket: client.Bucket(bucket), name: bucket}, nil
+}
+
+// List lists the objects under prefix
+func (s *Store) List(ctx context.Context, prefix string, fn func(objectsource.Object) error) error {
+	query := &storage.Query{Prefix: prefix}
+	if err := query.SetAttrSelection([]string{"Name", "Size", "Generation"}); err != nil {
+		return err
+	}
+
+	it := s.bucket.Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("list %s: %w", s.URL(prefix), err)
+		}
+
+		err = fn(objectsource.Object{
+			Key:     attrs.Name,
+			Size:    attrs.Size,
+			Version: strconv.FormatInt(attrs.Generation, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Open reads an object if its generation still matches
+func (s *Store) Open(ctx context.Context, obj objectsource.Object) (io.ReadCloser, error) {
+	generation, err := strconv.ParseInt(obj.Version, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generation %q: %w", obj.Version, err)
+	}
+	return s.bucket.Object(obj.Key).Generation(generation).NewReader(ctx)
+}
+
+// URL returns gs://<bucket>/<key>
+func (s *Store) URL(key string) string {
+	return "gs://" + s.name + "/" + key
+}
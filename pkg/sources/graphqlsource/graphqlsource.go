@@ -0,0 +1,313 @@
+// Package graphqlsource pages through a relay-style GraphQL connection
+package graphqlsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a GraphQL source
+type Config struct {
+	Endpoint   string
+	Header     http.Header  // Sent with every request, e.g. Authorization
+	HTTPClient *http.Client // Defaults to a client with a 30s timeout
+
+	// Query selects one connection and must take the cursor as a variable,
+	// e.g. query($after: String) { repository(...) { issues(first: 100,
+	// after: $after) { edges { node { ... } } pageInfo { hasNextPage
+	// endCursor } } } }
+	Query     string
+	Variables map[string]any
+
+	// CursorVariable names the cursor variable (defaults to "after")
+	CursorVariable string
+	// ConnectionPath is the dot-separated path from data to the connection,
+	// e.g. "repository.issues"
+	ConnectionPath string
+	// StartCursor resumes after a cursor returned by an earlier run
+	StartCursor string
+
+	// MaxRetries bounds the retries of a throttled page (defaults to 5)
+	MaxRetries int
+}
+
+// DecodeError reports a node that could not be unmarshalled into T
+// The source carries on with the next node, and the ETL skips it (see
+// etl.RecordError).
+type DecodeError struct {
+	Err error
+
+	node json.RawMessage
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("graphqlsource: decode node: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord returns the node as received
+func (e *DecodeError) RawRecord() ([]byte, bool) {
+	return e.node, true
+}
+
+// Source streams the nodes of a connection, one page per request
+// Each node is unmarshalled into T. Responses are checked for cost-based
+// rate limits (see wait) and the source sleeps before it would exceed them.
+type Source[T any] struct {
+	cfg  Config
+	path []string
+
+	mu         sync.Mutex
+	lastCursor string
+}
+
+// New creates a GraphQL source
+func New[T any](cfg Config) (*Source[T], error) {
+	if cfg.Endpoint == "" || cfg.Query == "" || cfg.ConnectionPath == "" {
+		return nil, fmt.Errorf("graphqlsource: endpoint, query and connection path are required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.CursorVariable == "" {
+		cfg.CursorVariable = "after"
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	return &Source[T]{cfg: cfg, path: strings.Split(cfg.ConnectionPath, ".")}, nil
+}
+
+// LastCursor returns the end cursor of the last page extracted
+func (s *Source[T]) LastCursor() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastCursor
+}
+
+// connection is a relay connection; either edges or nodes is selected
+type connection struct {
+	Edges []struct {
+		Node   json.RawMessage `json:"node"`
+		Cursor string          `json:"cursor"`
+	} `json:"edges"`
+	Nodes    []json.RawMessage `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+// Extract requests pages until the connection has no next page
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	ch := make(chan etl.Payload[T], 100)
+
+	go func() {
+		defer close(ch)
+
+		send := func(p etl.Payload[T]) bool {
+			select {
+			case ch <- p:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		cursor := s.cfg.StartCursor
+		for {
+			conn, err := s.page(ctx, cursor)
+			if err != nil {
+				if ctx.Err() == nil {
+					send(etl.Payload[T]{Err: fmt.Errorf("graphqlsource: %w", err)})
+				}
+				return
+			}
+
+			nodes := conn.Nodes
+			for _, edge := range conn.Edges {
+				nodes = append(nodes, edge.Node)
+			}
+			for _, node := range nodes {
+				var item T
+				if err := json.Unmarshal(node, &item); err != nil {
+					if !send(etl.Payload[T]{Err: &DecodeError{Err: err, node: node}}) {
+						return
+					}
+					continue
+				}
+				if !send(etl.Payload[T]{Data: item}) {
+					return
+				}
+			}
+
+			if conn.PageInfo.EndCursor != "" {
+				s.mu.Lock()
+				s.lastCursor = conn.PageInfo.EndCursor
+				s.mu.Unlock()
+			}
+			if !conn.PageInfo.HasNextPage {
+				return
+			}
+			if conn.PageInfo.EndCursor == "" || conn.PageInfo.EndCursor == cursor {
+				send(etl.Payload[T]{Err: fmt.Errorf("graphqlsource: hasNextPage without a new endCursor")})
+				return
+			}
+			cursor = conn.PageInfo.EndCursor
+		}
+	}()
+
+	return ch, nil
+}
+
+// response is a GraphQL response envelope
+type response struct {
+	Data       json.RawMessage `json:"data"`
+	Errors     []graphqlError  `json:"errors"`
+	Extensions json.RawMessage `json:"extensions"`
+}
+
+type graphqlError struct {
+	Message    string `json:"message"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+	Type string `json:"type"` // GitHub puts the code here
+}
+
+// page fetches the connection after cursor, waiting out rate limits
+func (s *Source[T]) page(ctx context.Context, cursor string) (*connection, error) {
+	variables := make(map[string]any, len(s.cfg.Variables)+1)
+	for k, v := range s.cfg.Variables {
+		variables[k] = v
+	}
+	if cursor != "" {
+		variables[s.cfg.CursorVariable] = cursor
+	}
+	body, err := json.Marshal(map[string]any{"query": s.cfg.Query, "variables": variables})
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, retryAfter, err := s.post(ctx, body)
+		if err != nil {
+			return nil, err
+		}
+
+		throttled := retryAfter > 0 || resp.throttled()
+		if throttled {
+			if attempt >= s.cfg.MaxRetries {
+				return nil, fmt.Errorf("still throttled after %d retries", attempt)
+			}
+			if retryAfter == 0 {
+				retryAfter = wait(resp)
+			}
+			if retryAfter == 0 {
+				retryAfter = time.Duration(1<<attempt) * time.Second
+			}
+			if err := sleep(ctx, retryAfter); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("query failed: %s", resp.Errors[0].Message)
+		}
+
+		conn, err := s.connection(resp.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		// Pace the next request so it stays within the budget
+		if err := sleep(ctx, wait(resp)); err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// post sends one request; retryAfter is set when the server answered 429
+func (s *Source[T]) post(ctx context.Context, body []byte) (*response, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	for k, v := range s.cfg.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusTooManyRequests {
+		io.Copy(io.Discard, httpResp.Body)
+		return &response{}, retryAfter(httpResp.Header.Get("Retry-After")), nil
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return nil, 0, fmt.Errorf("%s: %s", httpResp.Status, bytes.TrimSpace(data))
+	}
+
+	var resp response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, 0, fmt.Errorf("decode response: %w", err)
+	}
+	return &resp, 0, nil
+}
+
+// connection walks ConnectionPath from data to the connection object
+func (s *Source[T]) connection(data json.RawMessage) (*connection, error) {
+	raw := data
+	for _, field := range s.path {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+			return nil, fmt.Errorf("connection path %s: %q is not an object", s.cfg.ConnectionPath, field)
+		}
+		next, ok := obj[field]
+		if !ok {
+			return nil, fmt.Errorf("connection path %s: no field %q", s.cfg.ConnectionPath, field)
+		}
+		raw = next
+	}
+
+	var conn connection
+	if err := json.Unmarshal(raw, &conn); err != nil {
+		return nil, fmt.Errorf("decode connection: %w", err)
+	}
+	return &conn, nil
+}
+
+// sleep waits for d unless ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
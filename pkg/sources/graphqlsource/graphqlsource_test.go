@@ -0,0 +1,137 @@
+package graphqlsource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type issue struct {
+	Number int `json:"number"`
+}
+
+// pagingServer serves a connection of three pages of two issues, after
+// throttling the first request for the second page; the last node of the
+// last page cannot be decoded into an issue
+func pagingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var (
+		mu        sync.Mutex
+		throttled bool
+	)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page := 0
+		if after, ok := req.Variables["after"].(string); ok {
+			fmt.Sscanf(after, "page-%d", &page)
+		}
+
+		mu.Lock()
+		throttle := page == 1 && !throttled
+		throttled = throttled || throttle
+		mu.Unlock()
+		if throttle {
+			fmt.Fprint(w, `{"errors":[{"message":"Throttled","extensions":{"code":"THROTTLED"}}],
+				"extensions":{"cost":{"requestedQueryCost":1.01,"throttleStatus":{"currentlyAvailable":1,"restoreRate":1}}}}`)
+			return
+		}
+
+		second := fmt.Sprintf(`{"number":%d}`, 2*page+2)
+		if page == 2 {
+			second = `{"number":"six"}`
+		}
+		fmt.Fprintf(w, `{"data":{"repository":{"issues":{
+			"edges":[{"node":{"number":%d}},{"node":%s}],
+			"pageInfo":{"hasNextPage":%t,"endCursor":"page-%d"}}}}}`,
+			2*page+1, second, page < 2, page+1)
+	}))
+}
+
+func TestExtractPages(t *testing.T) {
+	server := pagingServer(t)
+	defer server.Close()
+
+	s, err := New[issue](Config{
+		Endpoint:       server.URL,
+		Query:          "query($after: String) { ... }",
+		ConnectionPath: "repository.issues",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := s.Extract(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		numbers []int
+		decode  int
+	)
+	for p := range ch {
+		// Read while the source pages, for the race detector
+		s.LastCursor()
+
+		var de *DecodeError
+		switch {
+		case errors.As(p.Err, &de):
+			decode++
+		case p.Err != nil:
+			t.Fatal(p.Err)
+		default:
+			numbers = append(numbers, p.Data.Number)
+		}
+	}
+
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(numbers, want) {
+		t.Errorf("extracted %v, want %v", numbers, want)
+	}
+	if decode != 1 {
+		t.Errorf("%d decode errors, want 1", decode)
+	}
+	if got := s.LastCursor(); got != "page-3" {
+		t.Errorf("LastCursor() = %q, want page-3", got)
+	}
+}
+
+func TestExtractStartCursor(t *testing.T) {
+	server := pagingServer(t)
+	defer server.Close()
+
+	s, err := New[issue](Config{
+		Endpoint:       server.URL,
+		Query:          "query($after: String) { ... }",
+		ConnectionPath: "repository.issues",
+		StartCursor:    "page-2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := s.Extract(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var numbers []int
+	for p := range ch {
+		if p.Err == nil {
+			numbers = append(numbers, p.Data.Number)
+		}
+	}
+	if want := []int{5}; !reflect.DeepEqual(numbers, want) {
+		t.Errorf("extracted %v after page-2, want %v", numbers, want)
+	}
+}
@@ -0,0 +1,65 @@
+package graphqlsource
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// costReport is the rate limit information APIs attach to responses
+// GitHub returns it as data.rateLimit when the query selects it; Shopify
+// always returns it as extensions.cost.
+type costReport struct {
+	// GitHub
+	RateLimit *struct {
+		Cost      float64   `json:"cost"`
+		Remaining float64   `json:"remaining"`
+		ResetAt   time.Time `json:"resetAt"`
+	} `json:"rateLimit"`
+
+	// Shopify
+	Cost *struct {
+		RequestedQueryCost float64 `json:"requestedQueryCost"`
+		ThrottleStatus     struct {
+			CurrentlyAvailable float64 `json:"currentlyAvailable"`
+			RestoreRate        float64 `json:"restoreRate"`
+		} `json:"throttleStatus"`
+	} `json:"cost"`
+}
+
+// throttled reports whether the server rejected the query for its cost
+func (r *response) throttled() bool {
+	for _, e := range r.Errors {
+		if e.Extensions.Code == "THROTTLED" || e.Type == "RATE_LIMITED" {
+			return true
+		}
+	}
+	return false
+}
+
+// wait returns how long to pause so the next query of the same cost fits
+// in the remaining budget, or 0 when no budget is reported
+func wait(r *response) time.Duration {
+	var data, ext costReport
+	json.Unmarshal(r.Data, &data)
+	json.Unmarshal(r.Extensions, &ext)
+
+	if rl := data.RateLimit; rl != nil && rl.Remaining < rl.Cost {
+		return time.Until(rl.ResetAt)
+	}
+	if c := ext.Cost; c != nil && c.ThrottleStatus.RestoreRate > 0 {
+		missing := c.RequestedQueryCost - c.ThrottleStatus.CurrentlyAvailable
+		if missing > 0 {
+			return time.Duration(missing / c.ThrottleStatus.RestoreRate * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+// retryAfter parses a Retry-After header given in seconds, defaulting to 1s
+func retryAfter(header string) time.Duration {
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return time.Second
+}
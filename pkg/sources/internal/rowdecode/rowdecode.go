@@ -0,0 +1,175 @@
+// Package rowdecode maps rows of string cells onto structs or
+// map[string]string, for the sources that read tabular text
+package rowdecode
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decoder converts rows into T
+// Struct fields are matched to columns by their tag or, case-insensitively,
+// by name.
+type Decoder[T any] struct {
+	tag    string
+	isMap  bool
+	fields map[string][]int // Lowercased column name -> field index path
+}
+
+// New prepares the column mapping of T, reading field names from tag
+func New[T any](tag string) (*Decoder[T], error) {
+	t := reflect.TypeFor[T]()
+	if t == reflect.TypeFor[map[string]string]() {
+		return &Decoder[T]{isMap: true}, nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is neither a struct nor map[string]string", t)
+	}
+
+	d := &Decoder[T]{tag: tag, fields: make(map[string][]int)}
+	d.collect(t, nil)
+	return d, nil
+}
+
+// collect records the column names of t's fields, descending into embedded
+// structs
+func (d *Decoder[T]) collect(t reflect.Type, path []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		index := append(append([]int(nil), path...), i)
+		tag, _, _ := strings.Cut(f.Tag.Get(d.tag), ",")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && tag == "" {
+			d.collect(f.Type, index)
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+		d.fields[strings.ToLower(name)] = index
+	}
+}
+
+// Decode converts one row; columns without a matching field are ignored
+func (d *Decoder[T]) Decode(header, record []string) (T, error) {
+	var item T
+	if len(record) != len(header) {
+		return item, fmt.Errorf("got %d fields, header has %d", len(record), len(header))
+	}
+
+	if d.isMap {
+		m := make(map[string]string, len(header))
+		for i, name := range header {
+			m[name] = record[i]
+		}
+		reflect.ValueOf(&item).Elem().Set(reflect.ValueOf(m))
+		return item, nil
+	}
+
+	v := reflect.ValueOf(&item).Elem()
+	for i, name := range header {
+		path, ok := d.fields[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			continue
+		}
+		if err := setField(v.FieldByIndex(path), record[i]); err != nil {
+			return item, fmt.Errorf("column %s: %w", name, err)
+		}
+	}
+	return item, nil
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+	textType     = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// setField parses s into v; an empty s leaves v at its zero value
+func setField(v reflect.Value, s string) error {
+	if s == "" && v.Kind() != reflect.String {
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		return setField(v.Elem(), s)
+	}
+	if reflect.PointerTo(v.Type()).Implements(textType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Type() {
+	case timeType:
+		t, err := parseTime(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// timeLayouts are tried in order when parsing time columns
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// parseTime parses s with the first matching layout
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as time", s)
+}
@@ -0,0 +1,240 @@
+// Package jsonlsource streams records from JSON Lines (NDJSON) input
+package jsonlsource
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a JSON Lines source
+type Config struct {
+	// Paths are files or glob patterns, read in order; "-" reads stdin
+	Paths []string
+	// Reader is read instead of Paths when set; Name labels it in errors
+	// (defaults to "reader")
+	Reader io.Reader
+	Name   string
+
+	// MaxLineSize bounds the length of a single line (defaults to 16MiB)
+	MaxLineSize int
+	// DisallowUnknownFields rejects lines with fields T does not have
+	DisallowUnknownFields bool
+}
+
+// LineError reports a line that could not be read or unmarshalled
+// The source emits it as a Payload error and carries on with the next
+// line; the ETL skips a malformed line (see etl.RecordError), while an
+// input that cannot be opened or read fails the run.
+type LineError struct {
+	File string
+	Line int // 0 when the whole input failed
+	Err  error
+
+	raw []byte // The malformed line
+}
+
+func (e *LineError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord returns the malformed line, and false if the error is not about
+// a single line
+func (e *LineError) RawRecord() ([]byte, bool) {
+	return e.raw, e.raw != nil
+}
+
+// Source streams JSON Lines records unmarshalled into T
+// Gzip and zstd input is detected from its magic bytes.
+type Source[T any] struct {
+	cfg Config
+}
+
+// New creates a JSON Lines source
+func New[T any](cfg Config) (*Source[T], error) {
+	if len(cfg.Paths) == 0 && cfg.Reader == nil {
+		return nil, fmt.Errorf("jsonlsource: a path or reader is required")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "reader"
+	}
+	if cfg.MaxLineSize == 0 {
+		cfg.MaxLineSize = 16 << 20
+	}
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract streams the records of every input
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	var inputs []string
+	if s.cfg.Reader == nil {
+		var err error
+		if inputs, err = s.files(); err != nil {
+			return nil, err
+		}
+	}
+
+	ch := make(chan etl.Payload[T], 100)
+
+	go func() {
+		defer close(ch)
+
+		if s.cfg.Reader != nil {
+			s.read(ctx, s.cfg.Name, s.cfg.Reader, ch)
+			return
+		}
+		for _, name := range inputs {
+			if !s.readFile(ctx, name, ch) {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// files expands the configured paths
+func (s *Source[T]) files() ([]string, error) {
+	var files []string
+	for _, pattern := range s.cfg.Paths {
+		if pattern == "-" {
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("jsonlsource: invalid pattern %q: %w", pattern, err)
+		}
+		if matches == nil {
+			return nil, fmt.Errorf("jsonlsource: no files match %q", pattern)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// readFile streams one file, reporting false if ctx was cancelled
+func (s *Source[T]) readFile(ctx context.Context, name string, ch chan<- etl.Payload[T]) bool {
+	if name == "-" {
+		return s.read(ctx, "stdin", os.Stdin, ch)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return send(ctx, ch, etl.Payload[T]{Err: &LineError{File: name, Err: err}})
+	}
+	defer f.Close()
+
+	return s.read(ctx, name, f, ch)
+}
+
+// read streams the lines of r, reporting false if ctx was cancelled
+func (s *Source[T]) read(ctx context.Context, name string, r io.Reader, ch chan<- etl.Payload[T]) bool {
+	fail := func(line int, err error) bool {
+		return send(ctx, ch, etl.Payload[T]{Err: &LineError{File: name, Line: line, Err: err}})
+	}
+	skip := func(line int, data []byte, err error) bool {
+		return send(ctx, ch, etl.Payload[T]{Err: &LineError{File: name, Line: line, Err: err, raw: bytes.Clone(data)}})
+	}
+
+	r, closeReader, err := decompress(r)
+	if err != nil {
+		return fail(0, err)
+	}
+	defer closeReader()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), s.cfg.MaxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var item T
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if s.cfg.DisallowUnknownFields {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(&item); err != nil {
+			if !skip(line, data, err) {
+				return false
+			}
+			continue
+		}
+		if dec.More() {
+			if !skip(line, data, fmt.Errorf("trailing data after JSON value")) {
+				return false
+			}
+			continue
+		}
+
+		if !send(ctx, ch, etl.Payload[T]{Data: item}) {
+			return false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fail(line+1, err)
+	}
+	return true
+}
+
+// send delivers p unless ctx is cancelled first
+func send[T any](ctx context.Context, ch chan<- etl.Payload[T], p etl.Payload[T]) bool {
+	select {
+	case ch <- p:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompress wraps r in a gzip or zstd reader when its magic bytes say so
+func decompress(r io.Reader) (io.Reader, func(), error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gzip: %w", err)
+		}
+		return gz, func() { gz.Close() }, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("zstd: %w", err)
+		}
+		return zr, zr.Close, nil
+	default:
+		return br, func() {}, nil
+	}
+}
@@ -0,0 +1,34 @@
+package kafkasource
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Deserializer decodes a message value
+// A schemaregistry.Deserializer's Deserialize method satisfies it for Avro
+// topics.
+type Deserializer[T any] func(ctx context.Context, value []byte) (T, error)
+
+// JSON decodes message values as JSON
+func JSON[T any]() Deserializer[T] {
+	return func(_ context.Context, value []byte) (T, error) {
+		var v T
+		err := json.Unmarshal(value, &v)
+		return v, err
+	}
+}
+
+// String returns message values as strings
+func String() Deserializer[string] {
+	return func(_ context.Context, value []byte) (string, error) {
+		return string(value), nil
+	}
+}
+
+// Bytes returns message values as they are
+func Bytes() Deserializer[[]byte] {
+	return func(_ context.Context, value []byte) ([]byte, error) {
+		return value, nil
+	}
+}
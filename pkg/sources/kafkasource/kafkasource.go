@@ -0,0 +1,245 @@
+// Package kafkasource consumes Kafka topics as a consumer group member
+package kafkasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/cuong/go-etl/pkg/bucket"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a Kafka source
+type Config[T any] struct {
+	Brokers []string
+	GroupID string
+	Topics  []string
+	Dialer  *kafka.Dialer // TLS and SASL settings, optional
+
+	// Deserializer decodes message values (required)
+	Deserializer Deserializer[T]
+
+	MinBytes    int           // Defaults to 1
+	MaxBytes    int           // Defaults to 10MB
+	MaxWait     time.Duration // Longest a fetch waits for MinBytes (defaults to 500ms)
+	StartOffset int64         // kafka.FirstOffset (default) or kafka.LastOffset for new groups
+
+	// IdleTimeout ends extraction once no message arrived for this long, so
+	// a scheduled run drains the backlog and finishes; 0 consumes until the
+	// context is cancelled
+	IdleTimeout time.Duration
+}
+
+// Record is a decoded message and the position it was read from
+type Record[T any] struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Headers   []kafka.Header
+	Time      time.Time
+	Value     T
+}
+
+// DecodeError reports a message whose value could not be deserialized
+// The ETL skips the message (see etl.RecordError); its offset is committed
+// with the next loaded message of its partition.
+type DecodeError struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Err       error
+
+	value []byte
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s[%d]@%d: %v", e.Topic, e.Partition, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord returns the value of the message
+func (e *DecodeError) RawRecord() ([]byte, bool) {
+	return e.value, true
+}
+
+// Source streams records from a consumer group
+// Offsets are committed only through Commit, which the ETL calls once a
+// batch has been loaded (see etl.BatchCommitter), so embedding the source in
+// a processor gives at-least-once delivery. Its Strategy keeps each
+// partition on one bucket worker, so a partition's batches are loaded and
+// committed in offset order.
+type Source[T any] struct {
+	cfg    Config[T]
+	reader *kafka.Reader
+
+	mu        sync.Mutex
+	committed map[topicPartition]int64
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// New creates a Kafka source; the group is joined on the first Extract
+func New[T any](cfg Config[T]) (*Source[T], error) {
+	if len(cfg.Brokers) == 0 || cfg.GroupID == "" || len(cfg.Topics) == 0 {
+		return nil, fmt.Errorf("kafkasource: brokers, group ID and topics are required")
+	}
+	if cfg.Deserializer == nil {
+		return nil, fmt.Errorf("kafkasource: deserializer is required")
+	}
+	if cfg.MinBytes == 0 {
+		cfg.MinBytes = 1
+	}
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = 10e6
+	}
+	if cfg.MaxWait == 0 {
+		cfg.MaxWait = 500 * time.Millisecond
+	}
+	if cfg.StartOffset == 0 {
+		cfg.StartOffset = kafka.FirstOffset
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		GroupID:     cfg.GroupID,
+		GroupTopics: cfg.Topics,
+		Dialer:      cfg.Dialer,
+		MinBytes:    cfg.MinBytes,
+		MaxBytes:    cfg.MaxBytes,
+		MaxWait:     cfg.MaxWait,
+		StartOffset: cfg.StartOffset,
+	})
+
+	return &Source[T]{
+		cfg:       cfg,
+		reader:    reader,
+		committed: make(map[topicPartition]int64),
+	}, nil
+}
+
+// Extract streams records until the context is cancelled or the source has
+// been idle for IdleTimeout
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[Record[T]], error) {
+	ch := make(chan etl.Payload[Record[T]], 100)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			msg, err := s.fetch(ctx)
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, errIdle) {
+					return
+				}
+				select {
+				case ch <- etl.Payload[Record[T]]{Err: fmt.Errorf("kafkasource: fetch: %w", err)}:
+				case <-ctx.Done():
+				}
+				return
+			}
+
+			var p etl.Payload[Record[T]]
+			value, err := s.cfg.Deserializer(ctx, msg.Value)
+			if err != nil {
+				p.Err = &DecodeError{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset, Err: err, value: msg.Value}
+			} else {
+				p.Data = Record[T]{
+					Topic:     msg.Topic,
+					Partition: msg.Partition,
+					Offset:    msg.Offset,
+					Key:       msg.Key,
+					Headers:   msg.Headers,
+					Time:      msg.Time,
+					Value:     value,
+				}
+			}
+
+			select {
+			case ch <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// errIdle ends extraction after IdleTimeout without messages
+var errIdle = errors.New("idle")
+
+// fetch reads the next message, giving up after IdleTimeout
+func (s *Source[T]) fetch(ctx context.Context) (kafka.Message, error) {
+	if s.cfg.IdleTimeout <= 0 {
+		return s.reader.FetchMessage(ctx)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, s.cfg.IdleTimeout)
+	defer cancel()
+
+	msg, err := s.reader.FetchMessage(fetchCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return msg, errIdle
+	}
+	return msg, err
+}
+
+// Commit commits the highest offset of each partition in records
+// Offsets at or below what was already committed are skipped, so a
+// redelivered batch never moves a partition backwards.
+func (s *Source[T]) Commit(ctx context.Context, records []Record[T]) error {
+	highest := make(map[topicPartition]int64)
+	for _, r := range records {
+		tp := topicPartition{r.Topic, r.Partition}
+		if off, ok := highest[tp]; !ok || r.Offset > off {
+			highest[tp] = r.Offset
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgs := make([]kafka.Message, 0, len(highest))
+	for tp, off := range highest {
+		if done, ok := s.committed[tp]; ok && off <= done {
+			continue
+		}
+		msgs = append(msgs, kafka.Message{Topic: tp.topic, Partition: tp.partition, Offset: off})
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	if err := s.reader.CommitMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("kafkasource: commit: %w", err)
+	}
+	for _, m := range msgs {
+		s.committed[topicPartition{m.Topic, m.Partition}] = m.Offset
+	}
+	return nil
+}
+
+// Strategy batches each partition on a single worker
+func (s *Source[T]) Strategy() bucket.Strategy[Record[T]] {
+	return bucket.PartitionHash(func(r Record[T]) string {
+		return r.Topic + "/" + strconv.Itoa(r.Partition)
+	})
+}
+
+// Close leaves the consumer group
+func (s *Source[T]) Close() error {
+	return s.reader.Close()
+}
@@ -0,0 +1,107 @@
+package mongocdcsource
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Op is the operation type of a change event
+type Op string
+
+const (
+	Insert  Op = "insert"
+	Update  Op = "update"
+	Replace Op = "replace"
+	Delete  Op = "delete"
+	Read    Op = "read" // Document copied by the initial snapshot
+
+	// Collection and database level events, emitted as they come
+	Drop         Op = "drop"
+	Rename       Op = "rename"
+	DropDatabase Op = "dropDatabase"
+	Invalidate   Op = "invalidate"
+)
+
+// Namespace is the database and collection an event happened in
+type Namespace struct {
+	DB   string `bson:"db"`
+	Coll string `bson:"coll"`
+}
+
+// UpdateDescription lists the fields changed by an update
+type UpdateDescription struct {
+	UpdatedFields bson.Raw `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// Event is a change to one document
+// FullDocument is set for inserts and replaces, and for updates with the
+// UpdateLookup full document mode (the default); it is nil for deletes.
+type Event[T any] struct {
+	Op                Op
+	Namespace         Namespace
+	DocumentKey       bson.Raw // The _id (and shard key) of the changed document
+	FullDocument      *T
+	UpdateDescription *UpdateDescription
+	ClusterTime       primitive.Timestamp
+	WallTime          time.Time
+	ResumeToken       bson.Raw
+
+	seq uint64 // Position in the stream, for Commit
+}
+
+// rawEvent is the server representation of a change event
+type rawEvent struct {
+	ID                bson.Raw            `bson:"_id"`
+	OperationType     Op                  `bson:"operationType"`
+	NS                Namespace           `bson:"ns"`
+	DocumentKey       bson.Raw            `bson:"documentKey"`
+	FullDocument      bson.Raw            `bson:"fullDocument"`
+	UpdateDescription *UpdateDescription  `bson:"updateDescription"`
+	ClusterTime       primitive.Timestamp `bson:"clusterTime"`
+	WallTime          time.Time           `bson:"wallTime"`
+}
+
+// DecodeError reports a change event whose document could not be decoded
+type DecodeError struct {
+	Namespace Namespace
+	Op        Op
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s.%s %s: %v", e.Namespace.DB, e.Namespace.Coll, e.Op, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeEvent converts a change stream document into an Event
+func decodeEvent[T any](doc bson.Raw) (Event[T], error) {
+	var raw rawEvent
+	if err := bson.Unmarshal(doc, &raw); err != nil {
+		return Event[T]{}, &DecodeError{Err: err}
+	}
+
+	ev := Event[T]{
+		Op:                raw.OperationType,
+		Namespace:         raw.NS,
+		DocumentKey:       raw.DocumentKey,
+		UpdateDescription: raw.UpdateDescription,
+		ClusterTime:       raw.ClusterTime,
+		WallTime:          raw.WallTime,
+		ResumeToken:       raw.ID,
+	}
+	if len(raw.FullDocument) > 0 {
+		var v T
+		if err := bson.Unmarshal(raw.FullDocument, &v); err != nil {
+			return Event[T]{}, &DecodeError{Namespace: raw.NS, Op: raw.OperationType, Err: err}
+		}
+		ev.FullDocument = &v
+	}
+	return ev, nil
+}
@@ -0,0 +1,285 @@
+// Package mongocdcsource captures changes from MongoDB change streams
+package mongocdcsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a change stream source
+// The stream watches Collection in Database, every collection of Database
+// when Collection is empty, or the whole deployment when Database is empty.
+type Config struct {
+	Client     *mongo.Client
+	Database   string
+	Collection string
+
+	Pipeline     mongo.Pipeline       // Optional stages filtering the events, e.g. a $match on operationType
+	FullDocument options.FullDocument // Defaults to options.UpdateLookup
+	BatchSize    int32                // Events per server round trip, 0 for the driver default
+	MaxAwaitTime time.Duration        // Longest a getMore waits for events (defaults to 1s)
+
+	// Checkpoints stores the resume token of the last loaded event, so a
+	// restarted pipeline continues where it stopped
+	Checkpoints checkpoint.Store
+	// CheckpointName defaults to "mongodb://<database>/<collection>"
+	CheckpointName string
+
+	// StartAtOperationTime starts a stream without a checkpoint at a cluster
+	// time, e.g. one taken before the initial bulk copy so that no change
+	// made during the copy is missed
+	StartAtOperationTime *primitive.Timestamp
+
+	// Snapshot copies the documents of Collection before streaming when the
+	// pipeline has no checkpoint yet; see Read. Requires Collection and
+	// Checkpoints.
+	Snapshot       bool
+	SnapshotFilter any // Optional filter of the copied documents
+
+	// MaxRetries is how many consecutive transient failures the stream is
+	// resubscribed after before extraction fails (defaults to 10)
+	MaxRetries   int
+	RetryBackoff time.Duration // First resubscription delay, doubled up to 30s (defaults to 1s)
+
+	// IdleTimeout ends extraction once no event arrived for this long; 0
+	// watches until the context is cancelled
+	IdleTimeout time.Duration
+}
+
+// Source streams change events with documents decoded into T
+// The resume token is checkpointed only through Commit, which the ETL calls
+// once a batch has been loaded (see etl.BatchCommitter), so embedding the
+// source in a processor gives at-least-once delivery across restarts.
+//
+// With Snapshot, the first run reads the collection and emits its documents
+// as Read events, then streams from the cluster time the copy started, so
+// no change made during the copy is missed. Inserts of copied documents
+// made during the copy are dropped; updates and deletes made during the
+// copy are replayed, so the sink must apply them idempotently. The _id of
+// every copied document is held in memory until the stream passes the end
+// of the copy. The stream position is only saved once the whole copy is
+// loaded: a run that fails before copies the collection again.
+type Source[T any] struct {
+	cfg     Config
+	tracker *tracker
+
+	startAt *primitive.Timestamp // Cluster time a stream without a token starts at
+	overlap *overlap             // Documents copied by a snapshot still being caught up
+}
+
+// New creates a change stream source
+func New[T any](cfg Config) (*Source[T], error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("mongocdcsource: Client is required")
+	}
+	if cfg.Collection != "" && cfg.Database == "" {
+		return nil, fmt.Errorf("mongocdcsource: Collection requires Database")
+	}
+	if cfg.FullDocument == "" {
+		cfg.FullDocument = options.UpdateLookup
+	}
+	if cfg.MaxAwaitTime <= 0 {
+		cfg.MaxAwaitTime = time.Second
+	}
+	if cfg.Snapshot && (cfg.Collection == "" || cfg.Checkpoints == nil) {
+		return nil, fmt.Errorf("mongocdcsource: Snapshot requires Collection and Checkpoints")
+	}
+	if cfg.CheckpointName == "" {
+		cfg.CheckpointName = "mongodb://" + cfg.Database + "/" + cfg.Collection
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 10
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract streams change events, resubscribing after transient errors,
+// until the context is cancelled, the source has been idle for IdleTimeout
+// or the stream is invalidated
+// An invalidate event is emitted before the stream ends.
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[Event[T]], error) {
+	t, point, err := loadTracker(ctx, s.cfg.Checkpoints, s.cfg.CheckpointName)
+	if err != nil {
+		return nil, fmt.Errorf("mongocdcsource: %w", err)
+	}
+	s.tracker = t
+	s.startAt, s.overlap = point.at, nil
+	if s.startAt == nil {
+		s.startAt = s.cfg.StartAtOperationTime
+	}
+	snapshot := s.cfg.Snapshot && point.token == nil && point.at == nil
+
+	ch := make(chan etl.Payload[Event[T]], 100)
+
+	go func() {
+		defer close(ch)
+
+		err := func() error {
+			if snapshot {
+				if err := s.snapshot(ctx, ch); err != nil {
+					return err
+				}
+			}
+			return s.watch(ctx, point.token, ch)
+		}()
+		if err != nil && ctx.Err() == nil {
+			select {
+			case ch <- etl.Payload[Event[T]]{Err: fmt.Errorf("mongocdcsource: %w", err)}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// errIdle ends extraction after IdleTimeout without events
+var errIdle = errors.New("idle")
+
+// watch subscribes from token and resubscribes after transient errors
+func (s *Source[T]) watch(ctx context.Context, token bson.Raw, ch chan<- etl.Payload[Event[T]]) error {
+	backoff := s.cfg.RetryBackoff
+	for failures := 0; ; {
+		received, err := s.stream(ctx, &token, ch)
+		if err == nil || errors.Is(err, errIdle) || ctx.Err() != nil {
+			return nil
+		}
+		if received {
+			failures, backoff = 0, s.cfg.RetryBackoff
+		}
+		failures++
+		if !isTransient(err) || failures > s.cfg.MaxRetries {
+			return err
+		}
+
+		etl.LoggerFromContext(ctx).Warn("Change stream failed, resubscribing",
+			"error", err, "attempt", failures, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// stream reads one subscription until it fails, ends or goes idle
+// token is advanced as events are read, so a resubscription continues after
+// the last event emitted. It reports whether any event was received.
+func (s *Source[T]) stream(ctx context.Context, token *bson.Raw, ch chan<- etl.Payload[Event[T]]) (bool, error) {
+	opts := options.ChangeStream().
+		SetFullDocument(s.cfg.FullDocument).
+		SetMaxAwaitTime(s.cfg.MaxAwaitTime)
+	if s.cfg.BatchSize > 0 {
+		opts.SetBatchSize(s.cfg.BatchSize)
+	}
+	if *token != nil {
+		opts.SetStartAfter(*token)
+	} else if s.startAt != nil {
+		opts.SetStartAtOperationTime(s.startAt)
+	}
+
+	pipeline := s.cfg.Pipeline
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+
+	var (
+		cs  *mongo.ChangeStream
+		err error
+	)
+	switch {
+	case s.cfg.Collection != "":
+		cs, err = s.cfg.Client.Database(s.cfg.Database).Collection(s.cfg.Collection).Watch(ctx, pipeline, opts)
+	case s.cfg.Database != "":
+		cs, err = s.cfg.Client.Database(s.cfg.Database).Watch(ctx, pipeline, opts)
+	default:
+		cs, err = s.cfg.Client.Watch(ctx, pipeline, opts)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer cs.Close(context.WithoutCancel(ctx))
+
+	received := false
+	lastEvent := time.Now()
+	for {
+		if !cs.TryNext(ctx) {
+			if err := cs.Err(); err != nil {
+				return received, fmt.Errorf("change stream error: %w", err)
+			}
+			if cs.ID() == 0 {
+				return received, nil // Closed by the server after an invalidate
+			}
+			if s.cfg.IdleTimeout > 0 && time.Since(lastEvent) >= s.cfg.IdleTimeout {
+				return received, errIdle
+			}
+			continue
+		}
+		received = true
+		lastEvent = time.Now()
+
+		ev, err := decodeEvent[T](cs.Current)
+		if err != nil {
+			return received, err
+		}
+		if s.overlap != nil {
+			if ev.ClusterTime.After(s.overlap.until) {
+				s.overlap = nil // Caught up with the end of the snapshot
+			} else if s.overlap.copied(ev.Op, ev.DocumentKey) {
+				*token = ev.ResumeToken
+				continue
+			}
+		}
+		ev.seq = s.tracker.read(ev.ResumeToken)
+		*token = ev.ResumeToken
+
+		select {
+		case ch <- etl.Payload[Event[T]]{Data: ev}:
+		case <-ctx.Done():
+			return received, ctx.Err()
+		}
+		if ev.Op == Invalidate {
+			return received, nil
+		}
+	}
+}
+
+// Commit checkpoints the resume token once every event up to it is loaded
+func (s *Source[T]) Commit(ctx context.Context, events []Event[T]) error {
+	if s.tracker == nil {
+		return nil
+	}
+	seqs := make([]uint64, len(events))
+	for i, ev := range events {
+		seqs[i] = ev.seq
+	}
+	if err := s.tracker.commit(ctx, seqs); err != nil {
+		return fmt.Errorf("mongocdcsource: checkpoint: %w", err)
+	}
+	return nil
+}
+
+// isTransient reports whether a change stream error is worth resubscribing
+// after; lost history (the token fell off the oplog) is not
+func isTransient(err error) bool {
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorLabel("ResumableChangeStreamError")
+}
@@ -0,0 +1,131 @@
+package mongocdcsource
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// overlap holds the _id of the documents a snapshot copied, to drop the
+// inserts of them the stream replays from before the copy ended
+type overlap struct {
+	until primitive.Timestamp // Cluster time the copy ended at
+	keys  map[string]struct{}
+}
+
+// copied reports whether an event is the insert of a copied document
+func (o *overlap) copied(op Op, documentKey bson.Raw) bool {
+	if op != Insert {
+		return false
+	}
+	id, err := documentKey.LookupErr("_id")
+	if err != nil {
+		return false
+	}
+	_, ok := o.keys[idKey(id)]
+	return ok
+}
+
+// idKey identifies an _id value by its BSON type and bytes
+func idKey(id bson.RawValue) string {
+	return string(rune(id.Type)) + string(id.Value)
+}
+
+// snapshot emits the documents of the collection as Read events and
+// prepares the stream to continue from the cluster time the copy started
+func (s *Source[T]) snapshot(ctx context.Context, ch chan<- etl.Payload[Event[T]]) error {
+	start, err := s.operationTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := s.cfg.SnapshotFilter
+	if filter == nil {
+		filter = bson.D{}
+	}
+	opts := options.Find()
+	if s.cfg.BatchSize > 0 {
+		opts.SetBatchSize(s.cfg.BatchSize)
+	}
+	cur, err := s.cfg.Client.Database(s.cfg.Database).Collection(s.cfg.Collection).Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer cur.Close(context.WithoutCancel(ctx))
+
+	ns := Namespace{DB: s.cfg.Database, Coll: s.cfg.Collection}
+	keys := make(map[string]struct{})
+	for cur.Next(ctx) {
+		id, err := cur.Current.LookupErr("_id")
+		if err != nil {
+			return fmt.Errorf("snapshot: document without _id")
+		}
+		documentKey, err := bson.Marshal(bson.D{{Key: "_id", Value: id}})
+		if err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		var doc T
+		if err := bson.Unmarshal(cur.Current, &doc); err != nil {
+			return &DecodeError{Namespace: ns, Op: Read, Err: err}
+		}
+		keys[idKey(id)] = struct{}{}
+
+		ev := Event[T]{
+			Op:           Read,
+			Namespace:    ns,
+			DocumentKey:  documentKey,
+			FullDocument: &doc,
+			WallTime:     time.Now(),
+			seq:          s.tracker.snapshot(),
+		}
+		select {
+		case ch <- etl.Payload[Event[T]]{Data: ev}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	end, err := s.operationTime(ctx)
+	if err != nil {
+		return err
+	}
+	etl.LoggerFromContext(ctx).Info("Copied collection", "collection", s.cfg.Collection, "documents", len(keys))
+
+	s.startAt = &start
+	s.overlap = &overlap{until: end, keys: keys}
+	if err := s.tracker.commit(ctx, []uint64{s.tracker.snapshotDone(start)}); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// operationTime returns the current cluster time
+func (s *Source[T]) operationTime(ctx context.Context) (primitive.Timestamp, error) {
+	sess, err := s.cfg.Client.StartSession()
+	if err != nil {
+		return primitive.Timestamp{}, fmt.Errorf("start session: %w", err)
+	}
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	err = mongo.WithSession(ctx, sess, func(sc mongo.SessionContext) error {
+		return s.cfg.Client.Database(s.cfg.Database).RunCommand(sc, bson.D{{Key: "ping", Value: 1}}).Err()
+	})
+	if err != nil {
+		return primitive.Timestamp{}, fmt.Errorf("read cluster time: %w", err)
+	}
+	at := sess.OperationTime()
+	if at == nil {
+		return primitive.Timestamp{}, fmt.Errorf("read cluster time: the deployment reports none; change streams require a replica set")
+	}
+	return *at, nil
+}
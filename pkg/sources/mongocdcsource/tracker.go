@@ -0,0 +1,149 @@
+package mongocdcsource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// position is the checkpoint of a change stream
+type position struct {
+	ResumeToken json.RawMessage `json:"resume_token,omitempty"` // Extended JSON
+
+	// OperationTime is the cluster time a completed snapshot streams from
+	// until the first event after it is loaded
+	OperationTime *primitive.Timestamp `json:"operation_time,omitempty"`
+}
+
+// resumePoint is where a stream continues: after a token or at a cluster
+// time; both are empty for a new pipeline
+type resumePoint struct {
+	token bson.Raw
+	at    *primitive.Timestamp
+}
+
+// tracker moves the stored resume token forward once every event before it
+// is loaded, so batches loaded out of order never skip an event on resume
+type tracker struct {
+	store checkpoint.Store
+	name  string
+
+	mu      sync.Mutex
+	next    uint64                 // Sequence number of the next event read
+	settled uint64                 // Every event up to here is loaded
+	pending map[uint64]resumePoint // Resume points after read events, by sequence
+	loaded  map[uint64]bool
+}
+
+// loadTracker reads the stored resume point, if any; store may be nil
+func loadTracker(ctx context.Context, store checkpoint.Store, name string) (*tracker, resumePoint, error) {
+	t := &tracker{
+		store:   store,
+		name:    name,
+		pending: make(map[uint64]resumePoint),
+		loaded:  make(map[uint64]bool),
+	}
+	if store == nil {
+		return t, resumePoint{}, nil
+	}
+
+	cp, err := store.Get(ctx, name)
+	if errors.Is(err, checkpoint.ErrNotFound) {
+		return t, resumePoint{}, nil
+	}
+	if err != nil {
+		return nil, resumePoint{}, err
+	}
+
+	var pos position
+	if err := json.Unmarshal(cp.Position, &pos); err != nil {
+		return nil, resumePoint{}, fmt.Errorf("decode checkpoint %s: %w", name, err)
+	}
+	if pos.ResumeToken == nil {
+		return t, resumePoint{at: pos.OperationTime}, nil
+	}
+	var token bson.Raw
+	if err := bson.UnmarshalExtJSON(pos.ResumeToken, false, &token); err != nil {
+		return nil, resumePoint{}, fmt.Errorf("decode resume token of %s: %w", name, err)
+	}
+	return t, resumePoint{token: token}, nil
+}
+
+// read registers an event and returns its sequence number
+func (t *tracker) read(token bson.Raw) uint64 {
+	return t.add(resumePoint{token: token})
+}
+
+// snapshot registers a document of the initial snapshot
+func (t *tracker) snapshot() uint64 {
+	return t.add(resumePoint{})
+}
+
+// snapshotDone registers the end of the snapshot, after which the stream
+// continues at the cluster time the copy started
+// The returned sequence number is committed by the source itself, as no
+// event carries it.
+func (t *tracker) snapshotDone(at primitive.Timestamp) uint64 {
+	return t.add(resumePoint{at: &at})
+}
+
+func (t *tracker) add(point resumePoint) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.next++
+	t.pending[t.next] = point
+	return t.next
+}
+
+// commit marks events as loaded and saves the token of the last event
+// before which nothing is pending
+func (t *tracker) commit(ctx context.Context, seqs []uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, seq := range seqs {
+		if seq > t.settled {
+			t.loaded[seq] = true
+		}
+	}
+
+	var point resumePoint
+	for t.loaded[t.settled+1] {
+		t.settled++
+		if p := t.pending[t.settled]; p.token != nil || p.at != nil {
+			point = p
+		}
+		delete(t.loaded, t.settled)
+		delete(t.pending, t.settled)
+	}
+	if (point.token == nil && point.at == nil) || t.store == nil {
+		return nil
+	}
+
+	pos := position{OperationTime: point.at}
+	if point.token != nil {
+		data, err := bson.MarshalExtJSON(point.token, false, false)
+		if err != nil {
+			return err
+		}
+		pos.ResumeToken = data
+	}
+	data, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, &checkpoint.Checkpoint{
+		Pipeline:  t.name,
+		Position:  data,
+		UpdatedAt: time.Now(),
+	})
+}
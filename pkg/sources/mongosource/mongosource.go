@@ -0,0 +1,157 @@
+// Package mongosource extracts documents from MongoDB collections
+package mongosource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// cursorNotFound is the server error code of an expired or killed cursor
+const cursorNotFound = 43
+
+// Config configures a MongoDB source
+type Config struct {
+	Database    *mongo.Database
+	Collections []string // Collections read one after another
+
+	Filter     any   // Query filter (defaults to all documents)
+	Projection any   // Optional projection
+	Sort       any   // Sort order (defaults to _id ascending)
+	BatchSize  int32 // Documents per server round trip, 0 for the driver default
+
+	// MaxCursorRetries is how many times a collection scan resumes after
+	// "cursor not found" (defaults to 3). Resuming continues after the last
+	// _id read, so it is only possible with the default sort.
+	MaxCursorRetries int
+}
+
+// Source streams documents decoded into T
+type Source[T any] struct {
+	cfg Config
+}
+
+// New creates a MongoDB source
+func New[T any](cfg Config) (*Source[T], error) {
+	if cfg.Database == nil {
+		return nil, fmt.Errorf("mongosource: Database is required")
+	}
+	if len(cfg.Collections) == 0 {
+		return nil, fmt.Errorf("mongosource: at least one collection is required")
+	}
+	if cfg.Filter == nil {
+		cfg.Filter = bson.M{}
+	}
+	if cfg.MaxCursorRetries <= 0 {
+		cfg.MaxCursorRetries = 3
+	}
+
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract streams the documents of every collection in order
+// A query, cursor or decode error is emitted as a Payload error and ends
+// the stream.
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	ch := make(chan etl.Payload[T], 100)
+
+	go func() {
+		defer close(ch)
+
+		for _, name := range s.cfg.Collections {
+			if err := s.extractCollection(ctx, s.cfg.Database.Collection(name), ch); err != nil {
+				select {
+				case ch <- etl.Payload[T]{Err: fmt.Errorf("collection %s: %w", name, err)}:
+				case <-ctx.Done():
+				}
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// extractCollection streams one collection, resuming after the last _id
+// when the server loses the cursor
+func (s *Source[T]) extractCollection(ctx context.Context, coll *mongo.Collection, ch chan<- etl.Payload[T]) error {
+	var (
+		lastID    any
+		resumable = s.cfg.Sort == nil
+	)
+	seen := func(id any, ok bool) {
+		lastID = id
+		resumable = resumable && ok // _id projected out
+	}
+
+	for retries := 0; ; retries++ {
+		err := s.scan(ctx, coll, lastID, ch, seen)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !isCursorNotFound(err) || !resumable || retries >= s.cfg.MaxCursorRetries {
+			return err
+		}
+	}
+}
+
+// scan runs one query, starting after lastID when set
+func (s *Source[T]) scan(ctx context.Context, coll *mongo.Collection, lastID any, ch chan<- etl.Payload[T], seen func(id any, ok bool)) error {
+	filter := s.cfg.Filter
+	if lastID != nil {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": lastID}}}}
+	}
+
+	opts := options.Find()
+	if s.cfg.Sort != nil {
+		opts.SetSort(s.cfg.Sort)
+	} else {
+		opts.SetSort(bson.D{{Key: "_id", Value: 1}})
+	}
+	if s.cfg.Projection != nil {
+		opts.SetProjection(s.cfg.Projection)
+	}
+	if s.cfg.BatchSize > 0 {
+		opts.SetBatchSize(s.cfg.BatchSize)
+	}
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create cursor: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return fmt.Errorf("failed to decode document: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ch <- etl.Payload[T]{Data: item}:
+		}
+
+		var id any
+		err := cursor.Current.Lookup("_id").Unmarshal(&id)
+		seen(id, err == nil)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+	return nil
+}
+
+// isCursorNotFound reports whether err means the server discarded the cursor
+func isCursorNotFound(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(cursorNotFound)
+}
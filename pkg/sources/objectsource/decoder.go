@@ -0,0 +1,99 @@
+package objectsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/sources/csvsource"
+	"github.com/cuong/go-etl/pkg/sources/jsonlsource"
+)
+
+// Decoder turns the (decompressed) contents of one object into records
+// name identifies the object in errors. The returned channel must be closed
+// once r has been fully read.
+type Decoder[T any] func(ctx context.Context, name string, r io.Reader) (<-chan etl.Payload[T], error)
+
+// CSV decodes objects as CSV; cfg.Paths is ignored
+func CSV[T any](cfg csvsource.Config) Decoder[T] {
+	return func(ctx context.Context, name string, r io.Reader) (<-chan etl.Payload[T], error) {
+		cfg.Paths, cfg.Reader, cfg.Name = nil, r, name
+		src, err := csvsource.New[T](cfg)
+		if err != nil {
+			return nil, err
+		}
+		return src.Extract(ctx)
+	}
+}
+
+// JSONL decodes objects as JSON Lines; cfg.Paths is ignored
+func JSONL[T any](cfg jsonlsource.Config) Decoder[T] {
+	return func(ctx context.Context, name string, r io.Reader) (<-chan etl.Payload[T], error) {
+		cfg.Paths, cfg.Reader, cfg.Name = nil, r, name
+		src, err := jsonlsource.New[T](cfg)
+		if err != nil {
+			return nil, err
+		}
+		return src.Extract(ctx)
+	}
+}
+
+// Parquet decodes objects as Parquet files, matching columns to the
+// `parquet` tags of T
+// Parquet needs random access, so each object is spooled to a temporary file
+// first.
+func Parquet[T any]() Decoder[T] {
+	return func(ctx context.Context, name string, r io.Reader) (<-chan etl.Payload[T], error) {
+		tmp, err := os.CreateTemp("", "objectsource-*.parquet")
+		if err != nil {
+			return nil, err
+		}
+		cleanup := func() {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+		if _, err := io.Copy(tmp, r); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		ch := make(chan etl.Payload[T], 100)
+
+		go func() {
+			defer close(ch)
+			defer cleanup()
+
+			reader := parquet.NewGenericReader[T](tmp)
+			defer reader.Close()
+
+			rows := make([]T, 100)
+			for {
+				n, err := reader.Read(rows)
+				for _, row := range rows[:n] {
+					select {
+					case ch <- etl.Payload[T]{Data: row}:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if errors.Is(err, io.EOF) {
+					return
+				}
+				if err != nil {
+					select {
+					case ch <- etl.Payload[T]{Err: fmt.Errorf("%s: %w", name, err)}:
+					case <-ctx.Done():
+					}
+					return
+				}
+			}
+		}()
+
+		return ch, nil
+	}
+}
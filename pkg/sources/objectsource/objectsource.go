@@ -0,0 +1,198 @@
+// Package objectsource ingests the objects under a prefix of an object store
+// The store specific packages (s3source, gcssource, azblobsource) provide
+// the ObjectStore; listing, decoding and checkpointing are shared.
+package objectsource
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Object describes one stored object
+type Object struct {
+	Key  string
+	Size int64
+	// Version changes whenever the object is overwritten (an ETag or a
+	// generation number)
+	Version string
+}
+
+// ObjectStore lists and reads objects
+type ObjectStore interface {
+	// List calls fn for every object under prefix, in key order
+	List(ctx context.Context, prefix string, fn func(Object) error) error
+
+	// Open reads an object, failing if it no longer has obj.Version
+	Open(ctx context.Context, obj Object) (io.ReadCloser, error)
+
+	// URL names an object in errors and checkpoints, e.g. s3://bucket/key
+	URL(key string) string
+}
+
+// Config configures an object source
+type Config[T any] struct {
+	Store  ObjectStore
+	Prefix string
+
+	// Decoder turns each object into records (required); objects ending in
+	// .gz or .zst are decompressed first
+	Decoder Decoder[T]
+
+	// Checkpoints remembers which objects were ingested, so re-runs skip
+	// them; an object is re-ingested if its version changes. Optional.
+	Checkpoints checkpoint.Store
+	// CheckpointName is the checkpoint the ingested set is stored under
+	// (defaults to the URL of the prefix)
+	CheckpointName string
+}
+
+// Record is a decoded record and the object it came from
+type Record[T any] struct {
+	Object string
+	Value  T
+}
+
+// Source streams the records of every object under a prefix, in key order
+// An object counts as ingested once all of its records have been loaded:
+// the ETL reports that through Commit (see etl.BatchCommitter), so embed the
+// source in the processor for checkpointing to take effect.
+type Source[T any] struct {
+	cfg     Config[T]
+	tracker *tracker
+}
+
+// New creates an object source
+func New[T any](cfg Config[T]) (*Source[T], error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("objectsource: store is required")
+	}
+	if cfg.Decoder == nil {
+		return nil, fmt.Errorf("objectsource: decoder is required")
+	}
+	if cfg.CheckpointName == "" {
+		cfg.CheckpointName = cfg.Store.URL(cfg.Prefix)
+	}
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract lists the prefix and streams the objects not ingested yet
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[Record[T]], error) {
+	t, err := loadTracker(ctx, s.cfg.Checkpoints, s.cfg.CheckpointName)
+	if err != nil {
+		return nil, fmt.Errorf("objectsource: %w", err)
+	}
+	s.tracker = t
+
+	ch := make(chan etl.Payload[Record[T]], 100)
+
+	go func() {
+		defer close(ch)
+
+		err := s.cfg.Store.List(ctx, s.cfg.Prefix, func(obj Object) error {
+			if strings.HasSuffix(obj.Key, "/") || t.ingested(obj.Key, obj.Version) {
+				return nil
+			}
+			return s.readObject(ctx, obj, ch)
+		})
+		if err != nil && !errors.Is(err, errStopped) {
+			send(ctx, ch, etl.Payload[Record[T]]{Err: fmt.Errorf("objectsource: %w", err)})
+		}
+	}()
+
+	return ch, nil
+}
+
+// errStopped ends listing once extraction has stopped
+var errStopped = errors.New("extraction stopped")
+
+// readObject streams one object
+func (s *Source[T]) readObject(ctx context.Context, obj Object, ch chan<- etl.Payload[Record[T]]) error {
+	name := s.cfg.Store.URL(obj.Key)
+
+	body, err := s.cfg.Store.Open(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer body.Close()
+
+	r, closeReader, err := decompress(obj.Key, body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	defer closeReader()
+
+	records, err := s.cfg.Decoder(ctx, name, r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	s.tracker.start(obj.Key, obj.Version)
+	for p := range records {
+		if p.Err == nil {
+			s.tracker.add(obj.Key)
+		}
+		if !send(ctx, ch, etl.Payload[Record[T]]{Data: Record[T]{Object: obj.Key, Value: p.Data}, Err: p.Err}) {
+			return errStopped
+		}
+	}
+	if ctx.Err() != nil {
+		return errStopped
+	}
+
+	if err := s.tracker.finish(ctx, obj.Key); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Commit marks the objects whose records have now all been loaded as
+// ingested
+func (s *Source[T]) Commit(ctx context.Context, records []Record[T]) error {
+	keys := make([]string, len(records))
+	for i, r := range records {
+		keys[i] = r.Object
+	}
+	if err := s.tracker.done(ctx, keys); err != nil {
+		return fmt.Errorf("objectsource: %w", err)
+	}
+	return nil
+}
+
+// send delivers p unless ctx is cancelled first
+func send[T any](ctx context.Context, ch chan<- etl.Payload[T], p etl.Payload[T]) bool {
+	select {
+	case ch <- p:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// decompress wraps r according to the key's extension
+func decompress(key string, r io.Reader) (io.Reader, func(), error) {
+	switch {
+	case strings.HasSuffix(key, ".gz"):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gzip: %w", err)
+		}
+		return gz, func() { gz.Close() }, nil
+	case strings.HasSuffix(key, ".zst"):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("zstd: %w", err)
+		}
+		return zr, zr.Close, nil
+	default:
+		return r, func() {}, nil
+	}
+}
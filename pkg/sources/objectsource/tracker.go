@@ -0,0 +1,134 @@
+package objectsource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// position is the checkpoint of an object source: the version of every object
+// ingested so far
+type position struct {
+	Ingested map[string]string `json:"ingested"`
+}
+
+// tracker counts the unloaded records of each object being read and
+// checkpoints objects once all of their records are loaded
+type tracker struct {
+	store checkpoint.Store
+	name  string
+
+	mu      sync.Mutex
+	pos     position
+	pending map[string]*pendingObject
+}
+
+type pendingObject struct {
+	version  string
+	unloaded int
+	read     bool // All records have been extracted
+}
+
+// loadTracker reads the ingested set from store, which may be nil
+func loadTracker(ctx context.Context, store checkpoint.Store, name string) (*tracker, error) {
+	t := &tracker{
+		store:   store,
+		name:    name,
+		pos:     position{Ingested: make(map[string]string)},
+		pending: make(map[string]*pendingObject),
+	}
+	if store == nil {
+		return t, nil
+	}
+
+	cp, err := store.Get(ctx, name)
+	if errors.Is(err, checkpoint.ErrNotFound) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(cp.Position, &t.pos); err != nil {
+		return nil, fmt.Errorf("decode checkpoint %s: %w", name, err)
+	}
+	if t.pos.Ingested == nil {
+		t.pos.Ingested = make(map[string]string)
+	}
+	return t, nil
+}
+
+// ingested reports whether key was ingested at this version
+func (t *tracker) ingested(key, version string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pos.Ingested[key] == version
+}
+
+// start begins tracking an object
+func (t *tracker) start(key, version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[key] = &pendingObject{version: version}
+}
+
+// add counts a record extracted from key
+func (t *tracker) add(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[key].unloaded++
+}
+
+// finish marks key as fully extracted
+func (t *tracker) finish(ctx context.Context, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[key].read = true
+	return t.settle(ctx, []string{key})
+}
+
+// done counts one loaded record per entry in keys
+func (t *tracker) done(ctx context.Context, keys []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		if p, ok := t.pending[key]; ok {
+			p.unloaded--
+		}
+	}
+	return t.settle(ctx, keys)
+}
+
+// settle checkpoints the objects among keys that are read and fully loaded
+// Callers hold t.mu.
+func (t *tracker) settle(ctx context.Context, keys []string) error {
+	changed := false
+	for _, key := range keys {
+		p, ok := t.pending[key]
+		if !ok || !p.read || p.unloaded > 0 {
+			continue
+		}
+		t.pos.Ingested[key] = p.version
+		delete(t.pending, key)
+		changed = true
+	}
+	if !changed || t.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(t.pos)
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, &checkpoint.Checkpoint{
+		Pipeline:  t.name,
+		Position:  data,
+		UpdatedAt: time.Now(),
+	})
+}
@@ -0,0 +1,57 @@
+package pgcdcsource
+
+import (
+	"fmt"
+	"time"
+)
+
+// LSN is a position in the write-ahead log
+type LSN uint64
+
+// ParseLSN parses the textual form of an LSN, e.g. "16/B374D848"
+func ParseLSN(s string) (LSN, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, fmt.Errorf("invalid LSN %q", s)
+	}
+	return LSN(uint64(hi)<<32 | uint64(lo)), nil
+}
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+// Op is the kind of change
+type Op string
+
+const (
+	Insert   Op = "insert"
+	Update   Op = "update"
+	Delete   Op = "delete"
+	Truncate Op = "truncate"
+	Read     Op = "read" // Row copied by the initial snapshot
+)
+
+// Row maps column names to values decoded into their Go types (int32,
+// string, time.Time, pgtype.Numeric, ...); NULL columns are nil
+type Row map[string]any
+
+// Change is one row change of a committed transaction
+type Change struct {
+	Op     Op
+	Schema string
+	Table  string
+
+	New Row // Inserted or updated row
+	Old Row // Key columns of updated or deleted rows, all columns with REPLICA IDENTITY FULL
+
+	Key       []string // Replica identity columns of the table, when known
+	Unchanged []string // TOASTed columns not sent because an update left them unchanged
+
+	XID        uint32
+	LSN        LSN // Position of the change
+	CommitLSN  LSN // End of the transaction; the slot is confirmed up to here
+	CommitTime time.Time
+
+	seq uint64 // Position in the stream, for Commit
+}
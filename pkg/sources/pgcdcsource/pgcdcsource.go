@@ -0,0 +1,294 @@
+// Package pgcdcsource captures row changes from PostgreSQL logical
+// replication slots
+package pgcdcsource
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Plugin is a logical decoding output plugin
+type Plugin string
+
+const (
+	PgOutput Plugin = "pgoutput" // Built in since PostgreSQL 10, reads publications
+	Wal2JSON Plugin = "wal2json" // Extension, format version 2
+)
+
+// Config configures a logical replication source
+type Config struct {
+	ConnString string // Regular connection string; replication mode is added
+	Slot       string
+	Plugin     Plugin // Defaults to PgOutput
+
+	Publications []string // Publications to stream (required for pgoutput)
+	Tables       []string // Tables to stream with wal2json ("schema.table"), all when empty
+
+	// CreateSlot creates the slot on the first Extract if it does not exist
+	CreateSlot bool
+
+	// Snapshot copies the current rows of the tables before streaming when
+	// the pipeline has no checkpoint yet; see Read. The slot is dropped and
+	// recreated so the stream starts exactly where the copy ends. Requires
+	// Checkpoints.
+	Snapshot bool
+	// SnapshotTables are the tables to copy ("table" or "schema.table");
+	// defaults to Tables, or to the tables of the publications
+	SnapshotTables []string
+
+	// StandbyTimeout is how often the confirmed position is reported to the
+	// server (defaults to 10s)
+	StandbyTimeout time.Duration
+
+	// Checkpoints stores the LSN of the last loaded transaction; the stream
+	// restarts there instead of at the slot's confirmed position
+	Checkpoints checkpoint.Store
+	// CheckpointName defaults to "postgres://slot/<slot>"
+	CheckpointName string
+
+	// IdleTimeout ends extraction once no change arrived for this long; 0
+	// streams until the context is cancelled
+	IdleTimeout time.Duration
+}
+
+// Source streams the row changes of committed transactions
+// Changes are emitted once their transaction commits, so a transaction is
+// held in memory until then. The slot is confirmed, and the checkpoint
+// written, only through Commit, which the ETL calls once a batch has been
+// loaded (see etl.BatchCommitter): the server keeps the WAL of unconfirmed
+// transactions and streams them again after a restart.
+//
+// With Snapshot, the first run reads the tables in the snapshot exported by
+// the new slot and emits their rows as Read changes before streaming. The
+// stream starts at the snapshot's consistent point, so no change is missed
+// or delivered twice; the checkpoint is only written once the whole copy is
+// loaded, and a run that fails before copies the tables again.
+type Source struct {
+	cfg     Config
+	tracker *tracker
+}
+
+// New creates a logical replication source
+func New(cfg Config) (*Source, error) {
+	if cfg.ConnString == "" || cfg.Slot == "" {
+		return nil, fmt.Errorf("pgcdcsource: ConnString and Slot are required")
+	}
+	if cfg.Plugin == "" {
+		cfg.Plugin = PgOutput
+	}
+	switch cfg.Plugin {
+	case PgOutput:
+		if len(cfg.Publications) == 0 {
+			return nil, fmt.Errorf("pgcdcsource: pgoutput requires at least one publication")
+		}
+	case Wal2JSON:
+	default:
+		return nil, fmt.Errorf("pgcdcsource: unsupported plugin %q", cfg.Plugin)
+	}
+	if cfg.StandbyTimeout <= 0 {
+		cfg.StandbyTimeout = 10 * time.Second
+	}
+	if cfg.Snapshot && cfg.Checkpoints == nil {
+		return nil, fmt.Errorf("pgcdcsource: Snapshot requires Checkpoints")
+	}
+	if cfg.CheckpointName == "" {
+		cfg.CheckpointName = "postgres://slot/" + cfg.Slot
+	}
+
+	return &Source{cfg: cfg}, nil
+}
+
+// Extract streams changes until the context is cancelled or the source has
+// been idle for IdleTimeout
+func (s *Source) Extract(ctx context.Context) (<-chan etl.Payload[Change], error) {
+	t, err := loadTracker(ctx, s.cfg.Checkpoints, s.cfg.CheckpointName)
+	if err != nil {
+		return nil, fmt.Errorf("pgcdcsource: %w", err)
+	}
+	s.tracker = t
+
+	conn, err := connectReplication(ctx, s.cfg.ConnString)
+	if err != nil {
+		return nil, fmt.Errorf("pgcdcsource: connect: %w", err)
+	}
+	var snapshot *exportedSlot
+	switch {
+	case s.cfg.Snapshot && t.position() == 0:
+		slot, err := createSnapshotSlot(ctx, conn, s.cfg.Slot, s.cfg.Plugin)
+		if err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("pgcdcsource: create slot: %w", err)
+		}
+		snapshot = &slot
+	case s.cfg.CreateSlot:
+		if err := createSlot(ctx, conn, s.cfg.Slot, s.cfg.Plugin); err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("pgcdcsource: create slot: %w", err)
+		}
+	}
+
+	var dec decoder
+	if s.cfg.Plugin == Wal2JSON {
+		dec = &wal2jsonDecoder{tables: s.cfg.Tables}
+	} else {
+		dec = newPgoutputDecoder(s.cfg.Publications)
+	}
+	if snapshot == nil {
+		if err := startReplication(ctx, conn, s.cfg.Slot, t.position(), dec.options()); err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("pgcdcsource: start replication: %w", err)
+		}
+	}
+
+	ch := make(chan etl.Payload[Change], 1000)
+
+	go func() {
+		defer close(ch)
+		defer conn.Close(context.WithoutCancel(ctx))
+
+		err := func() error {
+			if snapshot == nil {
+				return s.stream(ctx, conn, dec, ch)
+			}
+			if err := s.snapshot(ctx, *snapshot, ch); err != nil {
+				return err
+			}
+			if err := startReplication(ctx, conn, s.cfg.Slot, snapshot.consistentPoint, dec.options()); err != nil {
+				return fmt.Errorf("start replication: %w", err)
+			}
+			return s.stream(ctx, conn, dec, ch)
+		}()
+		if err != nil && ctx.Err() == nil {
+			select {
+			case ch <- etl.Payload[Change]{Err: fmt.Errorf("pgcdcsource: %w", err)}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// stream receives WAL, emits committed transactions and reports the
+// confirmed position every StandbyTimeout
+func (s *Source) stream(ctx context.Context, conn *pgconn.PgConn, dec decoder, ch chan<- etl.Payload[Change]) error {
+	var (
+		txn        []Change
+		nextStatus = time.Now().Add(s.cfg.StandbyTimeout)
+		lastChange = time.Now()
+	)
+
+	for {
+		if !time.Now().Before(nextStatus) {
+			if err := sendStandbyStatus(conn, s.tracker.position()); err != nil {
+				return fmt.Errorf("send standby status: %w", err)
+			}
+			nextStatus = time.Now().Add(s.cfg.StandbyTimeout)
+		}
+
+		deadline := nextStatus
+		if s.cfg.IdleTimeout > 0 && len(txn) == 0 {
+			idleAt := lastChange.Add(s.cfg.IdleTimeout)
+			if !time.Now().Before(idleAt) {
+				return sendStandbyStatus(conn, s.tracker.position())
+			}
+			if idleAt.Before(deadline) {
+				deadline = idleAt
+			}
+		}
+
+		recvCtx, cancel := context.WithDeadline(ctx, deadline)
+		msg, err := conn.ReceiveMessage(recvCtx)
+		cancel()
+		if err != nil {
+			if pgconn.Timeout(err) && ctx.Err() == nil {
+				continue
+			}
+			return fmt.Errorf("receive: %w", err)
+		}
+
+		var data []byte
+		switch msg := msg.(type) {
+		case *pgproto3.CopyData:
+			data = msg.Data
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(msg)
+		default:
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		switch data[0] {
+		case keepaliveByte:
+			ka, err := parseKeepalive(data[1:])
+			if err != nil {
+				return err
+			}
+			if ka.ReplyRequested {
+				nextStatus = time.Time{}
+			}
+
+		case xLogDataByte:
+			xld, err := parseXLogData(data[1:])
+			if err != nil {
+				return err
+			}
+			msgs, err := dec.decode(xld.WALStart, xld.Data)
+			if err != nil {
+				return err
+			}
+
+			for _, m := range msgs {
+				switch m.kind {
+				case beginMessage:
+					txn = nil
+				case changeMessage:
+					txn = append(txn, m.change)
+				case commitMessage:
+					for i := range txn {
+						txn[i].CommitLSN = m.commitLSN
+						txn[i].CommitTime = m.time
+					}
+					if err := s.tracker.transaction(ctx, txn, m.commitLSN); err != nil {
+						return fmt.Errorf("checkpoint: %w", err)
+					}
+					for _, c := range txn {
+						select {
+						case ch <- etl.Payload[Change]{Data: c}:
+						case <-ctx.Done():
+							return ctx.Err()
+						}
+					}
+					if len(txn) > 0 {
+						lastChange = time.Now()
+					}
+					txn = nil
+				}
+			}
+		}
+	}
+}
+
+// Commit confirms the transactions whose changes are all loaded
+func (s *Source) Commit(ctx context.Context, changes []Change) error {
+	if s.tracker == nil {
+		return nil
+	}
+	seqs := make([]uint64, len(changes))
+	for i, c := range changes {
+		seqs[i] = c.seq
+	}
+	if err := s.tracker.commit(ctx, seqs); err != nil {
+		return fmt.Errorf("pgcdcsource: checkpoint: %w", err)
+	}
+	return nil
+}
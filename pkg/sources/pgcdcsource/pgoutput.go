@@ -0,0 +1,299 @@
+package pgcdcsource
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// message is a decoded output plugin message
+type message struct {
+	kind      messageKind
+	xid       uint32
+	commitLSN LSN // End of the transaction, for commits
+	time      time.Time
+	change    Change
+}
+
+type messageKind int
+
+const (
+	beginMessage messageKind = iota
+	commitMessage
+	changeMessage
+)
+
+// decoder turns output plugin data into messages
+type decoder interface {
+	// options returns the plugin options of START_REPLICATION
+	options() []string
+	decode(walStart LSN, data []byte) ([]message, error)
+}
+
+// relation is a table described by a pgoutput Relation message
+type relation struct {
+	schema  string
+	table   string
+	columns []relationColumn
+	key     []string
+}
+
+type relationColumn struct {
+	name string
+	oid  uint32
+}
+
+// pgoutputDecoder decodes the built-in pgoutput plugin, protocol version 1
+type pgoutputDecoder struct {
+	publications []string
+	types        *pgtype.Map
+	relations    map[uint32]*relation
+	xid          uint32
+}
+
+func newPgoutputDecoder(publications []string) *pgoutputDecoder {
+	return &pgoutputDecoder{
+		publications: publications,
+		types:        pgtype.NewMap(),
+		relations:    make(map[uint32]*relation),
+	}
+}
+
+func (d *pgoutputDecoder) options() []string {
+	return []string{
+		"proto_version '1'",
+		"publication_names " + quoteLiteral(strings.Join(d.publications, ",")),
+	}
+}
+
+func (d *pgoutputDecoder) decode(walStart LSN, data []byte) ([]message, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty pgoutput message")
+	}
+	r := &reader{buf: data[1:]}
+
+	switch data[0] {
+	case 'B':
+		r.uint64() // Final LSN of the transaction
+		ts := r.time()
+		d.xid = r.uint32()
+		return []message{{kind: beginMessage, xid: d.xid, time: ts}}, r.err
+
+	case 'C':
+		r.byte()   // Flags
+		r.uint64() // Commit LSN
+		end := LSN(r.uint64())
+		ts := r.time()
+		return []message{{kind: commitMessage, xid: d.xid, commitLSN: end, time: ts}}, r.err
+
+	case 'R':
+		id := r.uint32()
+		rel := &relation{schema: r.string(), table: r.string()}
+		r.byte() // Replica identity setting
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			flags := r.byte()
+			col := relationColumn{name: r.string(), oid: r.uint32()}
+			r.uint32() // Type modifier
+			rel.columns = append(rel.columns, col)
+			if flags&1 != 0 {
+				rel.key = append(rel.key, col.name)
+			}
+		}
+		d.relations[id] = rel
+		return nil, r.err
+
+	case 'I', 'U', 'D':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+		c := Change{Schema: rel.schema, Table: rel.table, Key: rel.key, XID: d.xid, LSN: walStart}
+		switch data[0] {
+		case 'I':
+			c.Op = Insert
+		case 'U':
+			c.Op = Update
+		case 'D':
+			c.Op = Delete
+		}
+
+		for r.err == nil && len(r.buf) > 0 {
+			switch kind := r.byte(); kind {
+			case 'K':
+				// Only the key columns are set, the others are sent as NULL
+				old, _ := d.tuple(r, rel)
+				c.Old = make(Row, len(rel.key))
+				for _, name := range rel.key {
+					c.Old[name] = old[name]
+				}
+			case 'O':
+				c.Old, _ = d.tuple(r, rel)
+			case 'N':
+				c.New, c.Unchanged = d.tuple(r, rel)
+			default:
+				return nil, fmt.Errorf("unexpected tuple type %q in %s.%s", kind, rel.schema, rel.table)
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		return []message{{kind: changeMessage, change: c}}, r.decodeErr
+
+	case 'T':
+		n := int(r.uint32())
+		r.byte() // Options (CASCADE, RESTART IDENTITY)
+		msgs := make([]message, 0, n)
+		for i := 0; i < n && r.err == nil; i++ {
+			rel, err := d.relation(r.uint32())
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, message{kind: changeMessage, change: Change{
+				Op: Truncate, Schema: rel.schema, Table: rel.table, Key: rel.key, XID: d.xid, LSN: walStart,
+			}})
+		}
+		return msgs, r.err
+
+	default:
+		// Origin, Type and logical decoding messages carry no row changes
+		return nil, nil
+	}
+}
+
+func (d *pgoutputDecoder) relation(id uint32) (*relation, error) {
+	rel, ok := d.relations[id]
+	if !ok {
+		return nil, fmt.Errorf("change for unknown relation %d", id)
+	}
+	return rel, nil
+}
+
+// tuple reads a row in text format, returning the decoded columns and the
+// names of unchanged TOAST columns
+func (d *pgoutputDecoder) tuple(r *reader, rel *relation) (Row, []string) {
+	n := int(r.uint16())
+	row := make(Row, n)
+	var unchanged []string
+	for i := 0; i < n && r.err == nil; i++ {
+		name := fmt.Sprintf("col%d", i)
+		var oid uint32
+		if i < len(rel.columns) {
+			name, oid = rel.columns[i].name, rel.columns[i].oid
+		}
+
+		switch kind := r.byte(); kind {
+		case 'n':
+			row[name] = nil
+		case 'u':
+			unchanged = append(unchanged, name)
+		case 't', 'b':
+			data := r.bytes(int(r.uint32()))
+			format := int16(pgtype.TextFormatCode)
+			if kind == 'b' {
+				format = pgtype.BinaryFormatCode
+			}
+			row[name] = d.value(r, oid, format, data)
+		default:
+			r.err = fmt.Errorf("unexpected column type %q", kind)
+		}
+	}
+	return row, unchanged
+}
+
+// value decodes a column into its default Go type; types pgx does not
+// know, such as enums, are kept as strings
+func (d *pgoutputDecoder) value(r *reader, oid uint32, format int16, data []byte) any {
+	typ, ok := d.types.TypeForOID(oid)
+	if !ok {
+		if format == pgtype.TextFormatCode {
+			return string(data)
+		}
+		return data
+	}
+	v, err := typ.Codec.DecodeValue(d.types, oid, format, data)
+	if err != nil && r.decodeErr == nil {
+		r.decodeErr = fmt.Errorf("decode %s value: %w", typ.Name, err)
+	}
+	return v
+}
+
+// reader reads the big-endian fields of a protocol message
+// The first short read sets err and makes every later read return zero.
+type reader struct {
+	buf       []byte
+	err       error
+	decodeErr error // First column value that failed to decode
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = errors.New("truncated pgoutput message")
+		r.buf = nil
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.take(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) bytes(n int) []byte {
+	b := r.take(n)
+	return append([]byte(nil), b...)
+}
+
+// string reads a NUL-terminated string
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.buf {
+		if c == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	r.err = errors.New("unterminated string in pgoutput message")
+	return ""
+}
+
+// time reads a timestamp in microseconds since the Postgres epoch
+func (r *reader) time() time.Time {
+	return postgresEpoch.Add(time.Duration(int64(r.uint64())) * time.Microsecond)
+}
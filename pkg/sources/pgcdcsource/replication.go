@@ -0,0 +1,132 @@
+package pgcdcsource
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// postgresEpoch is the origin of timestamps in the replication protocol
+var postgresEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Replication protocol message types, sent inside CopyData
+const (
+	xLogDataByte        = 'w'
+	keepaliveByte       = 'k'
+	standbyStatusByte   = 'r'
+	duplicateObjectCode = "42710"
+)
+
+// xLogData is a chunk of decoded WAL from the output plugin
+type xLogData struct {
+	WALStart     LSN
+	ServerWALEnd LSN
+	Data         []byte
+}
+
+// keepalive is a server heartbeat
+type keepalive struct {
+	ServerWALEnd   LSN
+	ReplyRequested bool
+}
+
+// connectReplication opens a connection in logical replication mode
+func connectReplication(ctx context.Context, connString string) (*pgconn.PgConn, error) {
+	cfg, err := pgconn.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RuntimeParams["replication"] = "database"
+	return pgconn.ConnectConfig(ctx, cfg)
+}
+
+// createSlot creates a logical slot, doing nothing when it already exists
+func createSlot(ctx context.Context, conn *pgconn.PgConn, slot string, plugin Plugin) error {
+	sql := fmt.Sprintf("CREATE_REPLICATION_SLOT %s LOGICAL %s", quoteIdent(slot), plugin)
+	_, err := conn.Exec(ctx, sql).ReadAll()
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == duplicateObjectCode {
+		return nil
+	}
+	return err
+}
+
+// startReplication switches the connection into streaming mode
+func startReplication(ctx context.Context, conn *pgconn.PgConn, slot string, start LSN, options []string) error {
+	sql := fmt.Sprintf("START_REPLICATION SLOT %s LOGICAL %s", quoteIdent(slot), start)
+	if len(options) > 0 {
+		sql += " (" + strings.Join(options, ", ") + ")"
+	}
+
+	conn.Frontend().SendQuery(&pgproto3.Query{String: sql})
+	if err := conn.Frontend().Flush(); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			return nil
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(msg)
+		case *pgproto3.NoticeResponse, *pgproto3.ParameterStatus:
+		default:
+			return fmt.Errorf("unexpected message %T starting replication", msg)
+		}
+	}
+}
+
+// sendStandbyStatus reports the written, flushed and applied position
+func sendStandbyStatus(conn *pgconn.PgConn, lsn LSN) error {
+	data := make([]byte, 0, 34)
+	data = append(data, standbyStatusByte)
+	data = binary.BigEndian.AppendUint64(data, uint64(lsn)) // Written
+	data = binary.BigEndian.AppendUint64(data, uint64(lsn)) // Flushed
+	data = binary.BigEndian.AppendUint64(data, uint64(lsn)) // Applied
+	data = binary.BigEndian.AppendUint64(data, uint64(time.Since(postgresEpoch).Microseconds()))
+	data = append(data, 0) // No reply requested
+
+	conn.Frontend().Send(&pgproto3.CopyData{Data: data})
+	return conn.Frontend().Flush()
+}
+
+// parseXLogData decodes an XLogData message, without its type byte
+func parseXLogData(buf []byte) (xLogData, error) {
+	if len(buf) < 24 {
+		return xLogData{}, fmt.Errorf("XLogData too short: %d bytes", len(buf))
+	}
+	return xLogData{
+		WALStart:     LSN(binary.BigEndian.Uint64(buf)),
+		ServerWALEnd: LSN(binary.BigEndian.Uint64(buf[8:])),
+		Data:         buf[24:],
+	}, nil
+}
+
+// parseKeepalive decodes a primary keepalive, without its type byte
+func parseKeepalive(buf []byte) (keepalive, error) {
+	if len(buf) < 17 {
+		return keepalive{}, fmt.Errorf("keepalive too short: %d bytes", len(buf))
+	}
+	return keepalive{
+		ServerWALEnd:   LSN(binary.BigEndian.Uint64(buf)),
+		ReplyRequested: buf[16] == 1,
+	}, nil
+}
+
+// quoteIdent quotes an identifier for replication commands
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// quoteLiteral quotes an option value for START_REPLICATION
+func quoteLiteral(s string) string {
+	return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+}
@@ -0,0 +1,111 @@
+package pgcdcsource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier runs statements on a regular connection; *pgx.Conn and
+// *pgxpool.Pool implement it
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Slot describes a logical replication slot
+type Slot struct {
+	Name              string
+	Plugin            string
+	Active            bool
+	RestartLSN        LSN
+	ConfirmedFlushLSN LSN
+	LagBytes          int64 // WAL retained behind the confirmed position
+}
+
+// CreateSlot creates a logical replication slot and returns the LSN its
+// stream starts from
+func CreateSlot(ctx context.Context, db Querier, name string, plugin Plugin) (LSN, error) {
+	var lsn string
+	err := db.QueryRow(ctx, "SELECT lsn::text FROM pg_create_logical_replication_slot($1, $2)", name, string(plugin)).Scan(&lsn)
+	if err != nil {
+		return 0, fmt.Errorf("create slot %s: %w", name, err)
+	}
+	return ParseLSN(lsn)
+}
+
+// DropSlot drops a replication slot, releasing the WAL it retains
+func DropSlot(ctx context.Context, db Querier, name string) error {
+	if _, err := db.Exec(ctx, "SELECT pg_drop_replication_slot($1)", name); err != nil {
+		return fmt.Errorf("drop slot %s: %w", name, err)
+	}
+	return nil
+}
+
+// Slots lists the logical replication slots of the server
+// A slot nobody consumes keeps WAL forever, so LagBytes is worth alerting on.
+func Slots(ctx context.Context, db Querier) ([]Slot, error) {
+	rows, err := db.Query(ctx, `
+		SELECT slot_name, plugin, active,
+			coalesce(restart_lsn, '0/0')::text,
+			coalesce(confirmed_flush_lsn, '0/0')::text,
+			coalesce(pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn), 0)::bigint
+		FROM pg_replication_slots
+		WHERE slot_type = 'logical'
+		ORDER BY slot_name`)
+	if err != nil {
+		return nil, fmt.Errorf("list slots: %w", err)
+	}
+	defer rows.Close()
+
+	var slots []Slot
+	for rows.Next() {
+		var (
+			s                  Slot
+			restart, confirmed string
+		)
+		if err := rows.Scan(&s.Name, &s.Plugin, &s.Active, &restart, &confirmed, &s.LagBytes); err != nil {
+			return nil, fmt.Errorf("list slots: %w", err)
+		}
+		if s.RestartLSN, err = ParseLSN(restart); err != nil {
+			return nil, err
+		}
+		if s.ConfirmedFlushLSN, err = ParseLSN(confirmed); err != nil {
+			return nil, err
+		}
+		slots = append(slots, s)
+	}
+	return slots, rows.Err()
+}
+
+// CreatePublication creates a publication for pgoutput over the given
+// tables ("table" or "schema.table"), or over all tables when none are given
+func CreatePublication(ctx context.Context, db Querier, name string, tables ...string) error {
+	sql := "CREATE PUBLICATION " + pgx.Identifier{name}.Sanitize()
+	if len(tables) == 0 {
+		sql += " FOR ALL TABLES"
+	} else {
+		quoted := make([]string, len(tables))
+		for i, table := range tables {
+			quoted[i] = pgx.Identifier(strings.Split(table, ".")).Sanitize()
+		}
+		sql += " FOR TABLE " + strings.Join(quoted, ", ")
+	}
+
+	if _, err := db.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("create publication %s: %w", name, err)
+	}
+	return nil
+}
+
+// DropPublication drops a publication if it exists
+func DropPublication(ctx context.Context, db Querier, name string) error {
+	if _, err := db.Exec(ctx, "DROP PUBLICATION IF EXISTS "+pgx.Identifier{name}.Sanitize()); err != nil {
+		return fmt.Errorf("drop publication %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package pgcdcsource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+const undefinedObjectCode = "42704"
+
+// exportedSlot is a slot created with an exported snapshot
+type exportedSlot struct {
+	consistentPoint LSN    // The stream starts after the snapshot's state
+	snapshot        string // Name to import with SET TRANSACTION SNAPSHOT
+}
+
+// createSnapshotSlot recreates slot and exports the snapshot it starts
+// from
+// The snapshot stays importable until the next command on conn, so the
+// copy must finish before the stream is started.
+func createSnapshotSlot(ctx context.Context, conn *pgconn.PgConn, slot string, plugin Plugin) (exportedSlot, error) {
+	_, err := conn.Exec(ctx, "DROP_REPLICATION_SLOT "+quoteIdent(slot)).ReadAll()
+	if pgErr, ok := err.(*pgconn.PgError); err != nil && !(ok && pgErr.Code == undefinedObjectCode) {
+		return exportedSlot{}, fmt.Errorf("drop slot: %w", err)
+	}
+
+	sql := fmt.Sprintf("CREATE_REPLICATION_SLOT %s LOGICAL %s EXPORT_SNAPSHOT", quoteIdent(slot), plugin)
+	results, err := conn.Exec(ctx, sql).ReadAll()
+	if err != nil {
+		return exportedSlot{}, err
+	}
+	if len(results) != 1 || len(results[0].Rows) != 1 || len(results[0].Rows[0]) < 3 {
+		return exportedSlot{}, fmt.Errorf("unexpected CREATE_REPLICATION_SLOT result")
+	}
+	row := results[0].Rows[0]
+	lsn, err := ParseLSN(string(row[1]))
+	if err != nil {
+		return exportedSlot{}, err
+	}
+	return exportedSlot{consistentPoint: lsn, snapshot: string(row[2])}, nil
+}
+
+// snapshotTables returns the tables to copy: SnapshotTables, Tables, or the
+// tables of the publications
+func (s *Source) snapshotTables(ctx context.Context, conn *pgx.Conn) ([]string, error) {
+	if len(s.cfg.SnapshotTables) > 0 {
+		return s.cfg.SnapshotTables, nil
+	}
+	if len(s.cfg.Tables) > 0 {
+		return s.cfg.Tables, nil
+	}
+	if s.cfg.Plugin != PgOutput {
+		return nil, fmt.Errorf("a wal2json snapshot requires Tables or SnapshotTables")
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT DISTINCT schemaname, tablename
+		FROM pg_publication_tables
+		WHERE pubname = ANY($1)
+		ORDER BY schemaname, tablename`, s.cfg.Publications)
+	if err != nil {
+		return nil, fmt.Errorf("list publication tables: %w", err)
+	}
+	tables, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (string, error) {
+		var schema, table string
+		err := row.Scan(&schema, &table)
+		return schema + "." + table, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list publication tables: %w", err)
+	}
+	return tables, nil
+}
+
+// snapshot copies the tables as of the exported snapshot, emitting every
+// row as a Read change
+// The rows are numbered ahead of the stream, and the consistent point is
+// confirmed once all of them are loaded, so a crash during the copy starts
+// it over on the next run.
+func (s *Source) snapshot(ctx context.Context, slot exportedSlot, ch chan<- etl.Payload[Change]) error {
+	conn, err := pgx.Connect(ctx, s.cfg.ConnString)
+	if err != nil {
+		return fmt.Errorf("snapshot: connect: %w", err)
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	tables, err := s.snapshotTables(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("snapshot: begin: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+	if _, err := tx.Exec(ctx, "SET TRANSACTION SNAPSHOT "+quoteLiteral(slot.snapshot)); err != nil {
+		return fmt.Errorf("snapshot: import: %w", err)
+	}
+
+	started := time.Now()
+	log := etl.LoggerFromContext(ctx)
+	for _, table := range tables {
+		n, err := s.copyTable(ctx, tx, table, slot.consistentPoint, started, ch)
+		if err != nil {
+			return fmt.Errorf("snapshot %s: %w", table, err)
+		}
+		log.Info("Copied table", "table", table, "rows", n)
+	}
+
+	if err := s.tracker.snapshotDone(ctx, slot.consistentPoint); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// copyTable emits the rows of one table
+func (s *Source) copyTable(ctx context.Context, tx pgx.Tx, table string, lsn LSN, at time.Time, ch chan<- etl.Payload[Change]) (int, error) {
+	ident := pgx.Identifier(strings.Split(table, "."))
+	schema, name := "public", ident[len(ident)-1]
+	if len(ident) > 1 {
+		schema = ident[0]
+	}
+
+	var key []string
+	err := tx.QueryRow(ctx, `
+		SELECT coalesce(array_agg(a.attname::text ORDER BY array_position(i.indkey::int2[], a.attnum)), '{}')
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = $1::regclass AND i.indisprimary`, ident.Sanitize()).Scan(&key)
+	if err != nil {
+		return 0, fmt.Errorf("read primary key: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, "SELECT * FROM "+ident.Sanitize())
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	n := 0
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return n, err
+		}
+		row := make(Row, len(fields))
+		for i, f := range fields {
+			row[f.Name] = values[i]
+		}
+
+		c := Change{
+			Op:         Read,
+			Schema:     schema,
+			Table:      name,
+			New:        row,
+			Key:        key,
+			LSN:        lsn,
+			CommitLSN:  lsn,
+			CommitTime: at,
+		}
+		s.tracker.snapshot(&c)
+		select {
+		case ch <- etl.Payload[Change]{Data: c}:
+		case <-ctx.Done():
+			return n, ctx.Err()
+		}
+		n++
+	}
+	return n, rows.Err()
+}
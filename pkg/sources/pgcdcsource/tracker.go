@@ -0,0 +1,159 @@
+package pgcdcsource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// position is the checkpoint of a replication stream
+type position struct {
+	LSN string `json:"lsn"`
+}
+
+// tracker confirms a transaction once all of its changes, and every change
+// before them, are loaded
+type tracker struct {
+	store checkpoint.Store
+	name  string
+
+	mu        sync.Mutex
+	next      uint64         // Sequence number of the last change read
+	settled   uint64         // Every change up to here is loaded
+	pending   map[uint64]LSN // Commit LSN of the last change of each transaction
+	loaded    map[uint64]bool
+	confirmed LSN
+}
+
+// loadTracker reads the stored LSN, if any; store may be nil
+func loadTracker(ctx context.Context, store checkpoint.Store, name string) (*tracker, error) {
+	t := &tracker{
+		store:   store,
+		name:    name,
+		pending: make(map[uint64]LSN),
+		loaded:  make(map[uint64]bool),
+	}
+	if store == nil {
+		return t, nil
+	}
+
+	cp, err := store.Get(ctx, name)
+	if errors.Is(err, checkpoint.ErrNotFound) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pos position
+	if err := json.Unmarshal(cp.Position, &pos); err != nil {
+		return nil, fmt.Errorf("decode checkpoint %s: %w", name, err)
+	}
+	if t.confirmed, err = ParseLSN(pos.LSN); err != nil {
+		return nil, fmt.Errorf("decode checkpoint %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// transaction registers the changes of a committed transaction and
+// numbers them
+// A transaction without changes is confirmed right away when nothing before
+// it is pending.
+func (t *tracker) transaction(ctx context.Context, changes []Change, commitLSN LSN) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(changes) == 0 {
+		if t.settled == t.next && commitLSN > t.confirmed {
+			t.confirmed = commitLSN
+			return t.save(ctx)
+		}
+		return nil
+	}
+
+	for i := range changes {
+		t.next++
+		changes[i].seq = t.next
+	}
+	t.pending[t.next] = commitLSN
+	return nil
+}
+
+// snapshot numbers a row of the initial snapshot
+func (t *tracker) snapshot(c *Change) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	c.seq = t.next
+}
+
+// snapshotDone confirms the slot's consistent point once every snapshot row
+// is loaded
+func (t *tracker) snapshotDone(ctx context.Context, consistentPoint LSN) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.settled == t.next {
+		t.confirmed = consistentPoint
+		return t.save(ctx)
+	}
+	t.pending[t.next] = consistentPoint
+	return nil
+}
+
+// commit marks changes as loaded and confirms the transactions completed
+// by them
+func (t *tracker) commit(ctx context.Context, seqs []uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, seq := range seqs {
+		if seq > t.settled {
+			t.loaded[seq] = true
+		}
+	}
+
+	advanced := false
+	for t.loaded[t.settled+1] {
+		t.settled++
+		delete(t.loaded, t.settled)
+		if lsn, ok := t.pending[t.settled]; ok {
+			delete(t.pending, t.settled)
+			t.confirmed = lsn
+			advanced = true
+		}
+	}
+	if !advanced {
+		return nil
+	}
+	return t.save(ctx)
+}
+
+// position returns the confirmed LSN
+func (t *tracker) position() LSN {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.confirmed
+}
+
+// save writes the confirmed LSN
+// Callers hold t.mu.
+func (t *tracker) save(ctx context.Context) error {
+	if t.store == nil {
+		return nil
+	}
+	data, err := json.Marshal(position{LSN: t.confirmed.String()})
+	if err != nil {
+		return err
+	}
+	return t.store.Set(ctx, &checkpoint.Checkpoint{
+		Pipeline:  t.name,
+		Position:  data,
+		UpdatedAt: time.Now(),
+	})
+}
@@ -0,0 +1,126 @@
+// Package pgsource streams PostgreSQL query results through a server-side
+// cursor, so large tables are never held in memory at once
+package pgsource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// cursorName is the name of the cursor declared within the source's
+// transaction
+const cursorName = "etl_pgsource_cursor"
+
+// Beginner starts transactions; *pgx.Conn and *pgxpool.Pool implement it
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Config configures a PostgreSQL cursor source
+type Config[T any] struct {
+	DB        Beginner
+	Query     string // SELECT statement to stream
+	Args      []any  // Query arguments
+	FetchSize int    // Rows per FETCH round trip (defaults to 10000)
+
+	// Map converts a row into T; defaults to pgx.RowToStructByNameLax,
+	// which matches columns to fields by name or `db` tag
+	Map pgx.RowToFunc[T]
+}
+
+// Source streams query results in FETCH-sized chunks from a cursor declared
+// in a read-only transaction
+type Source[T any] struct {
+	cfg Config[T]
+}
+
+// New creates a PostgreSQL cursor source
+func New[T any](cfg Config[T]) (*Source[T], error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("pgsource: DB is required")
+	}
+	if cfg.Query == "" {
+		return nil, fmt.Errorf("pgsource: Query is required")
+	}
+	if cfg.FetchSize <= 0 {
+		cfg.FetchSize = 10000
+	}
+	if cfg.Map == nil {
+		cfg.Map = pgx.RowToStructByNameLax[T]
+	}
+
+	return &Source[T]{cfg: cfg}, nil
+}
+
+// Extract streams all rows of the query
+// A query or scan error is emitted as a Payload error and ends the stream.
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	ch := make(chan etl.Payload[T], s.cfg.FetchSize)
+
+	go func() {
+		defer close(ch)
+
+		if err := s.stream(ctx, ch); err != nil && ctx.Err() == nil {
+			select {
+			case ch <- etl.Payload[T]{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// stream declares the cursor and fetches from it until it is exhausted
+func (s *Source[T]) stream(ctx context.Context, ch chan<- etl.Payload[T]) error {
+	tx, err := s.cfg.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// The transaction only reads, so it is always rolled back
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if _, err := tx.Exec(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return fmt.Errorf("set read only: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "DECLARE "+cursorName+" NO SCROLL CURSOR FOR "+s.cfg.Query, s.cfg.Args...); err != nil {
+		return fmt.Errorf("declare cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", s.cfg.FetchSize, cursorName)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("fetch: %w", err)
+		}
+
+		n := 0
+		for rows.Next() {
+			item, err := s.cfg.Map(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("scan row: %w", err)
+			}
+			n++
+
+			select {
+			case ch <- etl.Payload[T]{Data: item}:
+			case <-ctx.Done():
+				rows.Close()
+				return ctx.Err()
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("fetch: %w", err)
+		}
+
+		if n < s.cfg.FetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package redissource scans Redis keys and reads their values
+package redissource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Config configures a Redis source
+type Config struct {
+	Client redis.UniversalClient
+
+	Match string // SCAN pattern (defaults to "*")
+	Count int64  // SCAN page size hint (defaults to 1000)
+
+	// Types restricts which key types are read, e.g. {"hash"}; empty reads
+	// string, hash, zset, list and set keys
+	Types []string
+}
+
+// Entry is one key and its decoded value; only the field matching Type is set
+type Entry struct {
+	Key  string
+	Type string // "string", "hash", "zset", "list" or "set"
+
+	String string
+	Hash   map[string]string
+	ZSet   []redis.Z
+	List   []string // Also holds set members
+}
+
+// Source streams the keys matching a pattern
+// Keys are read page by page with SCAN, so it never blocks the server, and a
+// key may be missed or seen twice if the keyspace changes during the scan.
+type Source struct {
+	cfg   Config
+	types map[string]bool
+}
+
+// New creates a Redis source
+func New(cfg Config) (*Source, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redissource: client is required")
+	}
+	if cfg.Match == "" {
+		cfg.Match = "*"
+	}
+	if cfg.Count == 0 {
+		cfg.Count = 1000
+	}
+	if len(cfg.Types) == 0 {
+		cfg.Types = []string{"string", "hash", "zset", "list", "set"}
+	}
+
+	types := make(map[string]bool, len(cfg.Types))
+	for _, t := range cfg.Types {
+		types[t] = true
+	}
+	return &Source{cfg: cfg, types: types}, nil
+}
+
+// Extract scans the keyspace and streams the matching entries
+func (s *Source) Extract(ctx context.Context) (<-chan etl.Payload[Entry], error) {
+	ch := make(chan etl.Payload[Entry], 100)
+
+	go func() {
+		defer close(ch)
+
+		var cursor uint64
+		for {
+			keys, next, err := s.cfg.Client.Scan(ctx, cursor, s.cfg.Match, s.cfg.Count).Result()
+			if err != nil {
+				s.send(ctx, ch, etl.Payload[Entry]{Err: fmt.Errorf("redissource: scan: %w", err)})
+				return
+			}
+
+			entries, err := s.read(ctx, keys)
+			if err != nil {
+				s.send(ctx, ch, etl.Payload[Entry]{Err: err})
+				return
+			}
+			for _, entry := range entries {
+				if !s.send(ctx, ch, etl.Payload[Entry]{Data: entry}) {
+					return
+				}
+			}
+
+			if next == 0 {
+				return
+			}
+			cursor = next
+		}
+	}()
+
+	return ch, nil
+}
+
+// read fetches the types and then the values of keys, one pipeline each
+func (s *Source) read(ctx context.Context, keys []string) ([]Entry, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	typeCmds := make([]*redis.StatusCmd, len(keys))
+	_, err := s.cfg.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			typeCmds[i] = pipe.Type(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redissource: read types: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(keys))
+	valueCmds := make([]redis.Cmder, 0, len(keys))
+	_, err = s.cfg.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			typ := typeCmds[i].Val()
+			if !s.types[typ] {
+				continue // Filtered out, or deleted since the scan ("none")
+			}
+
+			var cmd redis.Cmder
+			switch typ {
+			case "string":
+				cmd = pipe.Get(ctx, key)
+			case "hash":
+				cmd = pipe.HGetAll(ctx, key)
+			case "zset":
+				cmd = pipe.ZRangeWithScores(ctx, key, 0, -1)
+			case "list":
+				cmd = pipe.LRange(ctx, key, 0, -1)
+			case "set":
+				cmd = pipe.SMembers(ctx, key)
+			default:
+				continue
+			}
+			entries = append(entries, Entry{Key: key, Type: typ})
+			valueCmds = append(valueCmds, cmd)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("redissource: read values: %w", err)
+	}
+
+	out := entries[:0]
+	for i, entry := range entries {
+		switch cmd := valueCmds[i].(type) {
+		case *redis.StringCmd:
+			if cmd.Err() == redis.Nil {
+				continue // Expired since the scan
+			}
+			entry.String = cmd.Val()
+		case *redis.MapStringStringCmd:
+			entry.Hash = cmd.Val()
+		case *redis.ZSliceCmd:
+			entry.ZSet = cmd.Val()
+		case *redis.StringSliceCmd:
+			entry.List = cmd.Val()
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// send delivers p unless ctx is cancelled first
+func (s *Source) send(ctx context.Context, ch chan<- etl.Payload[Entry], p etl.Payload[Entry]) bool {
+	select {
+	case ch <- p:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,104 @@
+// Package s3source ingests the objects under an S3 prefix
+package s3source
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/sources/objectsource"
+)
+
+// Client is the subset of *s3.Client the source uses
+type Client interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// Config configures an S3 source
+type Config[T any] struct {
+	Client Client
+	Bucket string
+	Prefix string
+
+	Decoder        objectsource.Decoder[T]
+	Checkpoints    checkpoint.Store
+	CheckpointName string // Defaults to "s3://<bucket>/<prefix>"
+}
+
+// New creates an object source reading an S3 prefix
+func New[T any](cfg Config[T]) (*objectsource.Source[T], error) {
+	store, err := NewStore(cfg.Client, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	return objectsource.New(objectsource.Config[T]{
+		Store:          store,
+		Prefix:         cfg.Prefix,
+		Decoder:        cfg.Decoder,
+		Checkpoints:    cfg.Checkpoints,
+		CheckpointName: cfg.CheckpointName,
+	})
+}
+
+// Store is the objectsource.ObjectStore of an S3 bucket
+// Object versions are ETags.
+type Store struct {
+	client Client
+	bucket string
+}
+
+// NewStore creates a store for bucket
+func NewStore(client Client, bucket string) (*Store, error) {
+	if client == nil || bucket == "" {
+		return nil, fmt.Errorf("s3source: client and bucket are required")
+	}
+	return &Store{client: client, bucket: bucket}, nil
+}
+
+// List lists the objects under prefix
+func (s *Store) List(ctx context.Context, prefix string, fn func(objectsource.Object) error) error {
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list %s: %w", s.URL(prefix), err)
+		}
+		for _, obj := range page.Contents {
+			err := fn(objectsource.Object{
+				Key:     aws.ToString(obj.Key),
+				Size:    aws.ToInt64(obj.Size),
+				Version: aws.ToString(obj.ETag),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Open reads an object if its ETag still matches
+func (s *Store) Open(ctx context.Context, obj objectsource.Object) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(obj.Key),
+		IfMatch: aws.String(obj.Version),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// URL returns s3://<bucket>/<key>
+func (s *Store) URL(key string) string {
+	return "s3://" + s.bucket + "/" + key
+}
@@ -0,0 +1,183 @@
+// Package sheetsource reads rows from a Google Sheets spreadsheet
+package sheetsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/sources/internal/rowdecode"
+)
+
+// Config configures a Sheets source
+type Config struct {
+	// Service is used as is when set; otherwise one is created from the
+	// service account key in CredentialsFile or CredentialsJSON. Share the
+	// spreadsheet with the service account's email to grant it access.
+	Service         *sheets.Service
+	CredentialsFile string
+	CredentialsJSON []byte
+
+	SpreadsheetID string // The id in the spreadsheet's URL
+	// Range is an A1 range such as "Customers" or "Customers!A1:F"; it
+	// defaults to the first sheet
+	Range string
+
+	// Header names the columns when the range has no header row; when empty
+	// the first row of the range is the header
+	Header []string
+	// Unformatted reads raw cell values (e.g. 1234.5 rather than "$1,234.50")
+	Unformatted bool
+}
+
+// RowError reports a row that could not be decoded
+// The source emits it as a Payload error and carries on with the next row;
+// the ETL skips the row (see etl.RecordError).
+type RowError struct {
+	Row int // 1-based row number within the range
+	Err error
+
+	cells []string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// RawRecord returns the cells of the row as a JSON array
+func (e *RowError) RawRecord() ([]byte, bool) {
+	raw, _ := json.Marshal(e.cells)
+	return raw, true
+}
+
+// Source streams the rows of a range as T, which is either
+// map[string]string or a struct whose fields are matched to columns by their
+// `sheet` tag or, case-insensitively, by name
+type Source[T any] struct {
+	cfg     Config
+	decoder *rowdecode.Decoder[T]
+}
+
+// New creates a Sheets source
+func New[T any](ctx context.Context, cfg Config) (*Source[T], error) {
+	if cfg.SpreadsheetID == "" {
+		return nil, fmt.Errorf("sheetsource: spreadsheet id is required")
+	}
+
+	if cfg.Service == nil {
+		opts := []option.ClientOption{option.WithScopes(sheets.SpreadsheetsReadonlyScope)}
+		switch {
+		case cfg.CredentialsFile != "":
+			opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+		case cfg.CredentialsJSON != nil:
+			opts = append(opts, option.WithCredentialsJSON(cfg.CredentialsJSON))
+		}
+		service, err := sheets.NewService(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("sheetsource: create service: %w", err)
+		}
+		cfg.Service = service
+	}
+
+	dec, err := rowdecode.New[T]("sheet")
+	if err != nil {
+		return nil, fmt.Errorf("sheetsource: %w", err)
+	}
+	return &Source[T]{cfg: cfg, decoder: dec}, nil
+}
+
+// Extract reads the range and streams its rows
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	rng := s.cfg.Range
+	if rng == "" {
+		first, err := s.firstSheet(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rng = first
+	}
+
+	call := s.cfg.Service.Spreadsheets.Values.Get(s.cfg.SpreadsheetID, rng).Context(ctx)
+	if s.cfg.Unformatted {
+		call = call.ValueRenderOption("UNFORMATTED_VALUE").DateTimeRenderOption("FORMATTED_STRING")
+	}
+	values, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("sheetsource: read %s: %w", rng, err)
+	}
+
+	ch := make(chan etl.Payload[T], 100)
+
+	go func() {
+		defer close(ch)
+
+		header := s.cfg.Header
+		for i, cells := range values.Values {
+			row := make([]string, len(cells))
+			for j, cell := range cells {
+				row[j] = fmt.Sprint(cell)
+			}
+
+			if header == nil {
+				header = make([]string, len(row))
+				for j, name := range row {
+					header[j] = strings.TrimSpace(name)
+				}
+				continue
+			}
+			if isEmpty(row) {
+				continue
+			}
+
+			// The API omits trailing empty cells
+			row = append(row, make([]string, max(0, len(header)-len(row)))...)[:len(header)]
+
+			item, err := s.decoder.Decode(header, row)
+			p := etl.Payload[T]{Data: item}
+			if err != nil {
+				p = etl.Payload[T]{Err: &RowError{Row: i + 1, Err: err, cells: row}}
+			}
+			select {
+			case ch <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// firstSheet returns the title of the spreadsheet's first sheet
+func (s *Source[T]) firstSheet(ctx context.Context) (string, error) {
+	spreadsheet, err := s.cfg.Service.Spreadsheets.Get(s.cfg.SpreadsheetID).
+		Fields("sheets.properties.title").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", fmt.Errorf("sheetsource: read spreadsheet: %w", err)
+	}
+	if len(spreadsheet.Sheets) == 0 {
+		return "", fmt.Errorf("sheetsource: spreadsheet has no sheets")
+	}
+	return spreadsheet.Sheets[0].Properties.Title, nil
+}
+
+// isEmpty reports whether every cell of a row is blank
+func isEmpty(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
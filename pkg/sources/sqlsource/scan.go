@@ -0,0 +1,107 @@
+package sqlsource
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/internal/dbfields"
+)
+
+// structScanner scans rows into the fields of struct T
+type structScanner[T any] struct {
+	keyColumn string
+	fields    map[string][]int // Column name -> field index path
+
+	mu      sync.Mutex
+	columns []string // Cached column list of the last result set
+	paths   [][]int  // Field path per column, nil to discard
+	keyIdx  int
+}
+
+// newStructScanner maps the exported fields of T to column names
+func newStructScanner[T any](keyColumn string) (*structScanner[T], error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sqlsource: %s is not a struct, set Config.Map", t)
+	}
+
+	s := &structScanner[T]{keyColumn: keyColumn, fields: make(map[string][]int)}
+	for _, f := range dbfields.Of(t) {
+		s.fields[strings.ToLower(f.Column)] = f.Index
+	}
+	if _, ok := s.fields[strings.ToLower(keyColumn)]; !ok {
+		return nil, fmt.Errorf("sqlsource: %s has no field for key column %s", t, keyColumn)
+	}
+	return s, nil
+}
+
+// field returns a function reading the field of column from an item
+func (s *structScanner[T]) field(column string) (func(item T) any, error) {
+	path, ok := s.fields[strings.ToLower(column)]
+	if !ok {
+		return nil, fmt.Errorf("sqlsource: %s has no field for column %s", reflect.TypeFor[T](), column)
+	}
+	return func(item T) any {
+		return reflect.ValueOf(&item).Elem().FieldByIndex(path).Interface()
+	}, nil
+}
+
+// scan reads the current row into a new T, returning it and its key
+func (s *structScanner[T]) scan(rows *sql.Rows) (T, any, error) {
+	var item T
+
+	paths, keyIdx, err := s.layout(rows)
+	if err != nil {
+		return item, nil, err
+	}
+
+	v := reflect.ValueOf(&item).Elem()
+	dest := make([]any, len(paths))
+	for i, path := range paths {
+		if path == nil {
+			dest[i] = new(any)
+			continue
+		}
+		dest[i] = v.FieldByIndex(path).Addr().Interface()
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return item, nil, err
+	}
+	return item, reflect.ValueOf(dest[keyIdx]).Elem().Interface(), nil
+}
+
+// layout resolves the field of each result column, caching it per column
+// list
+func (s *structScanner[T]) layout(rows *sql.Rows) ([][]int, int, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Equal(columns, s.columns) {
+		return s.paths, s.keyIdx, nil
+	}
+
+	paths := make([][]int, len(columns))
+	keyIdx := -1
+	for i, col := range columns {
+		paths[i] = s.fields[strings.ToLower(col)]
+		if strings.EqualFold(col, s.keyColumn) {
+			keyIdx = i
+		}
+	}
+	if keyIdx < 0 {
+		return nil, 0, fmt.Errorf("key column %s not selected", s.keyColumn)
+	}
+
+	s.columns, s.paths, s.keyIdx = columns, paths, keyIdx
+	return paths, keyIdx, nil
+}
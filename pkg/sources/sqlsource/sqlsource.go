@@ -0,0 +1,266 @@
+// Package sqlsource extracts rows from any database/sql driver using keyset
+// pagination
+package sqlsource
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Placeholder is the bind parameter syntax of the driver
+type Placeholder int
+
+const (
+	PlaceholderDollar   Placeholder = iota // $1, $2 (PostgreSQL)
+	PlaceholderQuestion                    // ?, ? (MySQL, SQLite)
+)
+
+// Config configures a SQL source
+// Table, Columns, KeyColumn and Where are inserted into the query as is and
+// must not come from untrusted input.
+type Config[T any] struct {
+	DB        *sql.DB
+	Table     string
+	Columns   []string // Selected columns (defaults to *)
+	KeyColumn string   // Unique, indexed column to seek on, e.g. id
+	Where     string   // Optional filter, e.g. "deleted_at IS NULL"
+	Args      []any    // Arguments of the placeholders in Where
+
+	PageSize    int         // Rows per query (defaults to 1000)
+	Placeholder Placeholder // Bind parameter syntax of the driver
+	StartAfter  any         // Start after this key, nil to start from the beginning; overridden by Resume
+
+	// Map converts the current row into T; when nil, columns are scanned
+	// into the fields of struct T by their `db` tag or snake_cased name
+	Map func(rows *sql.Rows) (T, error)
+
+	// Key returns the key column value of an item; required with Map
+	Key func(item T) any
+
+	// WatermarkColumn enables incremental extraction: rows are read in
+	// (WatermarkColumn, KeyColumn) order and only those past the last
+	// watermark are selected, e.g. updated_at. Rows with a NULL watermark
+	// are never selected. See Resume for persisting the watermark.
+	WatermarkColumn string
+
+	// Watermark returns the watermark column value of an item; required
+	// with Map and WatermarkColumn
+	Watermark func(item T) any
+
+	// StartWatermark selects rows with a watermark after it, nil to start
+	// from the beginning; overridden by Resume
+	StartWatermark any
+}
+
+// Source extracts rows page by page, each page seeking past the last key
+// of the previous one, so deep pages cost the same as the first
+type Source[T any] struct {
+	cfg       Config[T]
+	mapper    func(rows *sql.Rows) (T, any, error)
+	key       func(item T) any
+	watermark func(item T) any // nil without WatermarkColumn
+
+	mu sync.Mutex
+	// Position every Extract starts after: Config.StartAfter and
+	// Config.StartWatermark, or the position set by Resume
+	startKey       any
+	startWatermark any
+	window         checkpoint.Window // Backfill range set by Resume
+	lastKey        any               // Key of the last row emitted, see LastKey
+}
+
+// New creates a SQL source
+func New[T any](cfg Config[T]) (*Source[T], error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("sqlsource: DB is required")
+	}
+	if cfg.Table == "" || cfg.KeyColumn == "" {
+		return nil, fmt.Errorf("sqlsource: Table and KeyColumn are required")
+	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = 1000
+	}
+
+	s := &Source[T]{cfg: cfg, startKey: cfg.StartAfter, startWatermark: cfg.StartWatermark}
+	if cfg.Map != nil {
+		if cfg.Key == nil {
+			return nil, fmt.Errorf("sqlsource: Key is required with Map")
+		}
+		if cfg.WatermarkColumn != "" && cfg.Watermark == nil {
+			return nil, fmt.Errorf("sqlsource: Watermark is required with Map and WatermarkColumn")
+		}
+		s.key, s.watermark = cfg.Key, cfg.Watermark
+		s.mapper = func(rows *sql.Rows) (T, any, error) {
+			item, err := cfg.Map(rows)
+			if err != nil {
+				return item, nil, err
+			}
+			return item, cfg.Key(item), nil
+		}
+	} else {
+		scanner, err := newStructScanner[T](cfg.KeyColumn)
+		if err != nil {
+			return nil, err
+		}
+		s.mapper = scanner.scan
+		if s.key, err = scanner.field(cfg.KeyColumn); err != nil {
+			return nil, err
+		}
+		if cfg.WatermarkColumn != "" {
+			if s.watermark, err = scanner.field(cfg.WatermarkColumn); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// LastKey returns the key of the last row emitted, e.g. to checkpoint it
+// and resume with Config.StartAfter
+func (s *Source[T]) LastKey() any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastKey
+}
+
+// Extract streams all matching rows in key order, starting after
+// Config.StartAfter and Config.StartWatermark or the resumed position, so a
+// retried attempt reads again the rows the failed one emitted
+// A query or scan error is emitted as a Payload error and ends the stream.
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[T], error) {
+	ch := make(chan etl.Payload[T], s.cfg.PageSize)
+
+	s.mu.Lock()
+	c := &cursor{key: s.startKey, watermark: s.startWatermark, window: s.window}
+	s.lastKey = s.startKey
+	s.mu.Unlock()
+
+	go func() {
+		defer close(ch)
+
+		for {
+			n, err := s.page(ctx, c, ch)
+			if err != nil {
+				select {
+				case ch <- etl.Payload[T]{Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if n < s.cfg.PageSize {
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// cursor is the position of an Extract call: the key and watermark of the
+// last row read
+type cursor struct {
+	key       any
+	watermark any
+	window    checkpoint.Window
+}
+
+// page queries and emits the page after c, returning the number of rows read
+func (s *Source[T]) page(ctx context.Context, c *cursor, ch chan<- etl.Payload[T]) (int, error) {
+	query, args := s.query(c.key, c.watermark, c.window)
+	rows, err := s.cfg.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query %s: %w", s.cfg.Table, err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		item, key, err := s.mapper(rows)
+		if err != nil {
+			return n, fmt.Errorf("scan %s row: %w", s.cfg.Table, err)
+		}
+		n++
+		c.key = key
+		if s.watermark != nil {
+			c.watermark = s.watermark(item)
+		}
+
+		select {
+		case ch <- etl.Payload[T]{Data: item}:
+		case <-ctx.Done():
+			return n, ctx.Err()
+		}
+
+		s.mu.Lock()
+		s.lastKey = key
+		s.mu.Unlock()
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("read %s rows: %w", s.cfg.Table, err)
+	}
+	return n, nil
+}
+
+// query builds the query for the page after lastKey and lastWatermark
+func (s *Source[T]) query(lastKey, lastWatermark any, window checkpoint.Window) (string, []any) {
+	columns := "*"
+	if len(s.cfg.Columns) > 0 {
+		columns = strings.Join(s.cfg.Columns, ", ")
+	}
+
+	args := append([]any(nil), s.cfg.Args...)
+	var conds []string
+	if s.cfg.Where != "" {
+		conds = append(conds, "("+s.cfg.Where+")")
+	}
+	order := s.cfg.KeyColumn
+	if wm := s.cfg.WatermarkColumn; wm != "" {
+		order = wm + ", " + s.cfg.KeyColumn
+		if !window.Since.IsZero() {
+			args = append(args, window.Since)
+			conds = append(conds, fmt.Sprintf("%s >= %s", wm, s.placeholder(len(args))))
+		}
+		if !window.Until.IsZero() {
+			args = append(args, window.Until)
+			conds = append(conds, fmt.Sprintf("%s < %s", wm, s.placeholder(len(args))))
+		}
+		switch {
+		case lastWatermark != nil && lastKey != nil:
+			args = append(args, lastWatermark, lastWatermark, lastKey)
+			n := len(args)
+			conds = append(conds, fmt.Sprintf("(%s > %s OR (%s = %s AND %s > %s))",
+				wm, s.placeholder(n-2), wm, s.placeholder(n-1), s.cfg.KeyColumn, s.placeholder(n)))
+		case lastWatermark != nil:
+			args = append(args, lastWatermark)
+			conds = append(conds, fmt.Sprintf("%s > %s", wm, s.placeholder(len(args))))
+		default:
+			conds = append(conds, wm+" IS NOT NULL")
+		}
+	} else if lastKey != nil {
+		args = append(args, lastKey)
+		conds = append(conds, fmt.Sprintf("%s > %s", s.cfg.KeyColumn, s.placeholder(len(args))))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", columns, s.cfg.Table)
+	if len(conds) > 0 {
+		b.WriteString(" WHERE " + strings.Join(conds, " AND "))
+	}
+	fmt.Fprintf(&b, " ORDER BY %s LIMIT %d", order, s.cfg.PageSize)
+	return b.String(), args
+}
+
+// placeholder returns the bind parameter for argument n (1-based)
+func (s *Source[T]) placeholder(n int) string {
+	if s.cfg.Placeholder == PlaceholderQuestion {
+		return "?"
+	}
+	return fmt.Sprintf("$%d", n)
+}
@@ -0,0 +1,153 @@
+package sqlsource_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/cuong/go-etl/pkg/sources/sqlsource"
+)
+
+// tableDriver serves the rows of a single id column, answering keyset
+// queries by their last argument (the key to seek past) and LIMIT
+type tableDriver struct{ ids []int64 }
+
+var limitRe = regexp.MustCompile(`LIMIT (\d+)`)
+
+func (d *tableDriver) Open(string) (driver.Conn, error) { return d, nil }
+func (d *tableDriver) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+func (d *tableDriver) Close() error              { return nil }
+func (d *tableDriver) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+func (d *tableDriver) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	limit, _ := strconv.Atoi(limitRe.FindStringSubmatch(query)[1])
+	rows := &idRows{}
+	for _, id := range d.ids {
+		if len(args) > 0 && id <= args[len(args)-1].Value.(int64) {
+			continue
+		}
+		if len(rows.ids) == limit {
+			break
+		}
+		rows.ids = append(rows.ids, id)
+	}
+	return rows, nil
+}
+
+type idRows struct{ ids []int64 }
+
+func (r *idRows) Columns() []string { return []string{"id"} }
+func (r *idRows) Close() error      { return nil }
+func (r *idRows) Next(dest []driver.Value) error {
+	if len(r.ids) == 0 {
+		return io.EOF
+	}
+	dest[0], r.ids = r.ids[0], r.ids[1:]
+	return nil
+}
+
+func newSource(t *testing.T, startAfter any) *sqlsource.Source[int64] {
+	t.Helper()
+
+	db := sql.OpenDB(connector{&tableDriver{ids: []int64{1, 2, 3, 4, 5}}})
+	t.Cleanup(func() { db.Close() })
+	s, err := sqlsource.New(sqlsource.Config[int64]{
+		DB:         db,
+		Table:      "items",
+		KeyColumn:  "id",
+		PageSize:   2,
+		StartAfter: startAfter,
+		Map: func(rows *sql.Rows) (int64, error) {
+			var id int64
+			err := rows.Scan(&id)
+			return id, err
+		},
+		Key: func(id int64) any { return id },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+type connector struct{ d *tableDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.d, nil }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+// extract drains an Extract call
+func extract(t *testing.T, s *sqlsource.Source[int64]) []int64 {
+	t.Helper()
+
+	ch, err := s.Extract(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for p := range ch {
+		if p.Err != nil {
+			t.Fatal(p.Err)
+		}
+		ids = append(ids, p.Data)
+	}
+	return ids
+}
+
+func TestExtractRestartsFromStart(t *testing.T) {
+	s := newSource(t, int64(2))
+
+	want := []int64{3, 4, 5}
+	for attempt := 1; attempt <= 2; attempt++ {
+		if got := extract(t, s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("attempt %d extracted %v, want %v", attempt, got, want)
+		}
+	}
+	if got := s.LastKey(); got != int64(5) {
+		t.Errorf("LastKey() = %v, want 5", got)
+	}
+}
+
+func TestExtractAfterCancelledAttempt(t *testing.T) {
+	s := newSource(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := s.Extract(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch // The attempt fails after emitting a row
+	cancel()
+	for range ch {
+	}
+
+	if got, want := extract(t, s), []int64{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("retry extracted %v, want %v", got, want)
+	}
+}
+
+func TestResume(t *testing.T) {
+	s := newSource(t, int64(1))
+	ctx := context.Background()
+
+	if err := s.Resume(ctx, s.Position(3)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := extract(t, s), []int64{4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("resumed after 3, extracted %v, want %v", got, want)
+	}
+
+	// A reset checkpoint starts over from StartAfter
+	if err := s.Resume(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := extract(t, s), []int64{2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("resumed without a position, extracted %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package sqlsource
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/checkpoint"
+)
+
+// position is the checkpoint of an incremental source: the watermark and
+// key of the last row loaded
+type position struct {
+	Watermark *typedValue `json:"watermark,omitempty"`
+	Key       *typedValue `json:"key"`
+}
+
+// typedValue is a column value in JSON form that decodes back to its Go
+// type, so a resumed query binds a timestamp as a timestamp
+type typedValue struct {
+	Type  string `json:"t"` // "time", "int", "uint", "float", "string" or "bytes"
+	Value string `json:"v"`
+}
+
+// Resume continues extraction after a position saved by an earlier run,
+// making a processor that embeds the source an etl.Resumer
+// Without a saved position, extraction starts from Config.StartAfter and
+// Config.StartWatermark again.
+// Under a backfill window (see checkpoint.WithWindow), the stored position
+// is ignored and the rows whose watermark falls in the window are read
+// instead; the ETL does not save positions reached inside a window.
+func (s *Source[T]) Resume(ctx context.Context, pos json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, ok := checkpoint.WindowFromContext(ctx); ok {
+		if s.cfg.WatermarkColumn == "" {
+			return fmt.Errorf("sqlsource: a backfill window requires WatermarkColumn")
+		}
+		s.window, s.startWatermark, s.startKey = w, nil, nil
+		return nil
+	}
+	s.window = checkpoint.Window{}
+	if pos == nil { // Nothing saved yet
+		s.startKey, s.startWatermark = s.cfg.StartAfter, s.cfg.StartWatermark
+		return nil
+	}
+
+	var p position
+	if err := json.Unmarshal(pos, &p); err != nil {
+		return fmt.Errorf("sqlsource: decode position: %w", err)
+	}
+	key, err := p.Key.decode()
+	if err != nil {
+		return fmt.Errorf("sqlsource: decode position key: %w", err)
+	}
+	watermark, err := p.Watermark.decode()
+	if err != nil {
+		return fmt.Errorf("sqlsource: decode position watermark: %w", err)
+	}
+	if (watermark != nil) != (s.cfg.WatermarkColumn != "") {
+		return fmt.Errorf("sqlsource: position %s does not match WatermarkColumn %q", pos, s.cfg.WatermarkColumn)
+	}
+	s.startKey, s.startWatermark = key, watermark
+	return nil
+}
+
+// Position returns the position after item, for etl.Resumer
+func (s *Source[T]) Position(item T) json.RawMessage {
+	p := position{Key: encodeValue(s.key(item))}
+	if s.watermark != nil {
+		p.Watermark = encodeValue(s.watermark(item))
+	}
+	data, _ := json.Marshal(p)
+	return data
+}
+
+// encodeValue converts a scanned column value to its JSON form
+func encodeValue(v any) *typedValue {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+
+	if t, ok := rv.Interface().(time.Time); ok {
+		return &typedValue{Type: "time", Value: t.Format(time.RFC3339Nano)}
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &typedValue{Type: "int", Value: strconv.FormatInt(rv.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &typedValue{Type: "uint", Value: strconv.FormatUint(rv.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return &typedValue{Type: "float", Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return &typedValue{Type: "bytes", Value: base64.StdEncoding.EncodeToString(rv.Bytes())}
+		}
+	}
+	return &typedValue{Type: "string", Value: fmt.Sprint(rv.Interface())}
+}
+
+// decode converts a stored value back to a query argument
+func (v *typedValue) decode() (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch v.Type {
+	case "time":
+		return time.Parse(time.RFC3339Nano, v.Value)
+	case "int":
+		return strconv.ParseInt(v.Value, 10, 64)
+	case "uint":
+		return strconv.ParseUint(v.Value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(v.Value, 64)
+	case "bytes":
+		return base64.StdEncoding.DecodeString(v.Value)
+	case "string":
+		return v.Value, nil
+	default:
+		return nil, fmt.Errorf("unknown value type %q", v.Type)
+	}
+}
@@ -0,0 +1,22 @@
+// Package stdinsource reads records from standard input, so pipelines can
+// sit at the end of a shell pipeline
+package stdinsource
+
+import (
+	"os"
+
+	"github.com/cuong/go-etl/pkg/sources/csvsource"
+	"github.com/cuong/go-etl/pkg/sources/jsonlsource"
+)
+
+// JSONL reads JSON Lines from stdin; cfg.Paths and cfg.Reader are ignored
+func JSONL[T any](cfg jsonlsource.Config) (*jsonlsource.Source[T], error) {
+	cfg.Paths, cfg.Reader, cfg.Name = nil, os.Stdin, "stdin"
+	return jsonlsource.New[T](cfg)
+}
+
+// CSV reads CSV from stdin; cfg.Paths and cfg.Reader are ignored
+func CSV[T any](cfg csvsource.Config) (*csvsource.Source[T], error) {
+	cfg.Paths, cfg.Reader, cfg.Name = nil, os.Stdin, "stdin"
+	return csvsource.New[T](cfg)
+}
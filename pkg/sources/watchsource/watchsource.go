@@ -0,0 +1,349 @@
+// Package watchsource ingests files as they arrive in a directory
+package watchsource
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/cuong/go-etl/pkg/etl"
+	"github.com/cuong/go-etl/pkg/sources/objectsource"
+)
+
+// Config configures a directory watch source
+type Config[T any] struct {
+	Dir string
+	// Pattern filters file names, e.g. "*.csv" (defaults to all files)
+	Pattern string
+
+	// Decoder turns each file into records (required)
+	Decoder objectsource.Decoder[T]
+
+	// SettleTime is how long a file's size must stay unchanged before it is
+	// considered complete (defaults to 2s)
+	SettleTime time.Duration
+	// DoneSuffix switches completion detection to marker files: data.csv is
+	// read once data.csv<DoneSuffix> (e.g. ".done") appears
+	DoneSuffix string
+
+	// ProcessedDir receives files (and their markers) once all of their
+	// records are loaded; without it loaded files stay in place and are
+	// remembered for the lifetime of the source
+	ProcessedDir string
+	// IgnoreExisting skips the files already in Dir when watching starts
+	IgnoreExisting bool
+}
+
+// Record is a decoded record and the file it came from
+type Record[T any] struct {
+	File  string
+	Value T
+}
+
+// Source streams the records of every complete file that lands in a
+// directory, until the context is cancelled
+// Files count as loaded through Commit, which the ETL calls once a batch is
+// loaded (see etl.BatchCommitter), so embed the source in the processor.
+// Watch errors are logged and followed by a rescan of the directory.
+type Source[T any] struct {
+	cfg Config[T]
+
+	mu      sync.Mutex
+	pending map[string]*pendingFile
+	done    map[string]time.Time // Loaded files left in place, by modification time
+}
+
+type pendingFile struct {
+	unloaded int
+	read     bool
+}
+
+// candidate is a file waiting to be complete
+type candidate struct {
+	size    int64
+	changed time.Time
+}
+
+// New creates a directory watch source
+func New[T any](cfg Config[T]) (*Source[T], error) {
+	if cfg.Dir == "" || cfg.Decoder == nil {
+		return nil, fmt.Errorf("watchsource: directory and decoder are required")
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = "*"
+	}
+	if _, err := filepath.Match(cfg.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("watchsource: invalid pattern %q: %w", cfg.Pattern, err)
+	}
+	if cfg.SettleTime == 0 {
+		cfg.SettleTime = 2 * time.Second
+	}
+	if cfg.ProcessedDir != "" {
+		if err := os.MkdirAll(cfg.ProcessedDir, 0o755); err != nil {
+			return nil, fmt.Errorf("watchsource: %w", err)
+		}
+	}
+
+	return &Source[T]{
+		cfg:     cfg,
+		pending: make(map[string]*pendingFile),
+		done:    make(map[string]time.Time),
+	}, nil
+}
+
+// Extract watches the directory and streams files as they complete
+func (s *Source[T]) Extract(ctx context.Context) (<-chan etl.Payload[Record[T]], error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("watchsource: %w", err)
+	}
+	if err := watcher.Add(s.cfg.Dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("watchsource: watch %s: %w", s.cfg.Dir, err)
+	}
+
+	candidates := make(map[string]*candidate)
+	if !s.cfg.IgnoreExisting {
+		if err := s.scan(candidates); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	ch := make(chan etl.Payload[Record[T]], 100)
+
+	go func() {
+		defer close(ch)
+		defer watcher.Close()
+
+		ticker := time.NewTicker(max(s.cfg.SettleTime/4, 10*time.Millisecond))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				switch {
+				case event.Has(fsnotify.Create) || event.Has(fsnotify.Write):
+					if c, ok := candidates[event.Name]; ok {
+						c.changed = time.Now()
+					} else {
+						candidates[event.Name] = &candidate{size: -1, changed: time.Now()}
+					}
+				case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+					delete(candidates, event.Name)
+				}
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				// Events may have been lost, e.g. when the kernel queue
+				// overflowed, so rescan rather than stop watching
+				logger := etl.LoggerFromContext(ctx)
+				logger.Warn("Watch error, rescanning directory", "dir", s.cfg.Dir, "error", err)
+				if err := s.scan(candidates); err != nil {
+					logger.Error("Failed to rescan directory", "dir", s.cfg.Dir, "error", err)
+				}
+
+			case <-ticker.C:
+				for _, path := range s.complete(candidates) {
+					delete(candidates, path)
+					if !s.readFile(ctx, path, ch) {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// scan adds the files in the directory to candidates; files already loaded
+// are dropped again by complete
+func (s *Source[T]) scan(candidates map[string]*candidate) error {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("watchsource: %w", err)
+	}
+	for _, e := range entries {
+		path := filepath.Join(s.cfg.Dir, e.Name())
+		if _, ok := candidates[path]; !ok && !e.IsDir() {
+			candidates[path] = &candidate{size: -1}
+		}
+	}
+	return nil
+}
+
+// complete returns the candidates ready to be read, in name order, and drops
+// the ones that are not data files
+func (s *Source[T]) complete(candidates map[string]*candidate) []string {
+	var ready []string
+	now := time.Now()
+
+	for path, c := range candidates {
+		name := filepath.Base(path)
+		if s.cfg.DoneSuffix != "" && strings.HasSuffix(name, s.cfg.DoneSuffix) {
+			delete(candidates, path)
+			if data := strings.TrimSuffix(path, s.cfg.DoneSuffix); !s.seen(data) {
+				candidates[data] = &candidate{size: -1}
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(s.cfg.Pattern, name); !ok {
+			delete(candidates, path)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || s.seen(path) {
+			delete(candidates, path)
+			continue
+		}
+
+		if s.cfg.DoneSuffix != "" {
+			if _, err := os.Stat(path + s.cfg.DoneSuffix); err == nil {
+				ready = append(ready, path)
+			}
+			continue
+		}
+
+		// Complete once the size has not moved for SettleTime
+		if info.Size() != c.size {
+			c.size, c.changed = info.Size(), now
+			continue
+		}
+		if now.Sub(c.changed) >= s.cfg.SettleTime && now.Sub(info.ModTime()) >= s.cfg.SettleTime {
+			ready = append(ready, path)
+		}
+	}
+
+	sort.Strings(ready)
+	return ready
+}
+
+// seen reports whether path was already loaded and left in place unchanged
+func (s *Source[T]) seen(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pending[path]; ok {
+		return true
+	}
+	modTime, ok := s.done[path]
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.ModTime().Equal(modTime)
+}
+
+// readFile streams one file, reporting false if extraction must stop
+func (s *Source[T]) readFile(ctx context.Context, path string, ch chan<- etl.Payload[Record[T]]) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return send(ctx, ch, etl.Payload[Record[T]]{Err: fmt.Errorf("watchsource: %w", err)})
+	}
+	defer f.Close()
+
+	records, err := s.cfg.Decoder(ctx, path, f)
+	if err != nil {
+		return send(ctx, ch, etl.Payload[Record[T]]{Err: fmt.Errorf("watchsource: %s: %w", path, err)})
+	}
+
+	s.mu.Lock()
+	s.pending[path] = &pendingFile{}
+	s.mu.Unlock()
+
+	for p := range records {
+		if p.Err == nil {
+			s.mu.Lock()
+			s.pending[path].unloaded++
+			s.mu.Unlock()
+		}
+		if !send(ctx, ch, etl.Payload[Record[T]]{Data: Record[T]{File: path, Value: p.Data}, Err: p.Err}) {
+			return false
+		}
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[path].read = true
+	if err := s.settle(path); err != nil {
+		return send(ctx, ch, etl.Payload[Record[T]]{Err: err})
+	}
+	return true
+}
+
+// Commit marks the files whose records have now all been loaded as done
+func (s *Source[T]) Commit(ctx context.Context, records []Record[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	touched := make(map[string]bool)
+	for _, r := range records {
+		if p, ok := s.pending[r.File]; ok {
+			p.unloaded--
+			touched[r.File] = true
+		}
+	}
+	for path := range touched {
+		if err := s.settle(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle finishes a file that is read and loaded: it is moved to
+// ProcessedDir or remembered as done
+// Callers hold s.mu.
+func (s *Source[T]) settle(path string) error {
+	p := s.pending[path]
+	if !p.read || p.unloaded > 0 {
+		return nil
+	}
+	delete(s.pending, path)
+
+	if s.cfg.ProcessedDir == "" {
+		if info, err := os.Stat(path); err == nil {
+			s.done[path] = info.ModTime()
+		}
+		return nil
+	}
+
+	if err := os.Rename(path, filepath.Join(s.cfg.ProcessedDir, filepath.Base(path))); err != nil {
+		return fmt.Errorf("watchsource: move processed file: %w", err)
+	}
+	if s.cfg.DoneSuffix != "" {
+		os.Remove(path + s.cfg.DoneSuffix)
+	}
+	return nil
+}
+
+// send delivers p unless ctx is cancelled first
+func send[T any](ctx context.Context, ch chan<- etl.Payload[T], p etl.Payload[T]) bool {
+	select {
+	case ch <- p:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,200 @@
+package watchsource
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// lines decodes a file into its lines
+func lines(ctx context.Context, _ string, r io.Reader) (<-chan etl.Payload[string], error) {
+	ch := make(chan etl.Payload[string])
+	go func() {
+		defer close(ch)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case ch <- etl.Payload[string]{Data: scanner.Text()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// extract starts s and returns its records, stopping it with the test
+func extract(t *testing.T, s *Source[string]) <-chan etl.Payload[Record[string]] {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := s.Extract(ctx)
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		for range ch {
+		}
+	})
+	return ch
+}
+
+// receive returns the next n records, failing the test after a few seconds
+func receive(t *testing.T, ch <-chan etl.Payload[Record[string]], n int) []Record[string] {
+	t.Helper()
+
+	var records []Record[string]
+	timeout := time.After(5 * time.Second)
+	for len(records) < n {
+		select {
+		case p := <-ch:
+			if p.Err != nil {
+				t.Fatal(p.Err)
+			}
+			records = append(records, p.Data)
+		case <-timeout:
+			t.Fatalf("received %v, want %d records", records, n)
+		}
+	}
+	return records
+}
+
+// expectNothing fails the test if a record arrives within d
+func expectNothing(t *testing.T, ch <-chan etl.Payload[Record[string]], d time.Duration) {
+	t.Helper()
+
+	select {
+	case p := <-ch:
+		t.Fatalf("received %+v early", p)
+	case <-time.After(d):
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestSettleTime(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(Config[string]{Dir: dir, Pattern: "*.txt", Decoder: lines, SettleTime: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := extract(t, s)
+
+	path := filepath.Join(dir, "a.txt")
+	writeFile(t, path, "1\n")
+	writeFile(t, filepath.Join(dir, "ignored.csv"), "x\n")
+
+	// Still being written: the file grows before it settles
+	time.Sleep(100 * time.Millisecond)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("2\n")
+	f.Close()
+	expectNothing(t, ch, 150*time.Millisecond)
+
+	records := receive(t, ch, 2)
+	if records[0] != (Record[string]{File: path, Value: "1"}) || records[1].Value != "2" {
+		t.Errorf("records %v, want both lines of %s", records, path)
+	}
+	expectNothing(t, ch, 300*time.Millisecond)
+}
+
+func TestDoneSuffix(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(Config[string]{Dir: dir, Decoder: lines, SettleTime: 20 * time.Millisecond, DoneSuffix: ".done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := extract(t, s)
+
+	path := filepath.Join(dir, "a.txt")
+	writeFile(t, path, "1\n")
+	expectNothing(t, ch, 200*time.Millisecond)
+
+	writeFile(t, path+".done", "")
+	if records := receive(t, ch, 1); records[0].File != path {
+		t.Errorf("read %s, want %s", records[0].File, path)
+	}
+	expectNothing(t, ch, 100*time.Millisecond)
+}
+
+func TestCommitMovesLoadedFiles(t *testing.T) {
+	dir, processed := t.TempDir(), t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	writeFile(t, a, "1\n2\n")
+	writeFile(t, b, "3\n")
+	writeFile(t, b+".done", "")
+	writeFile(t, a+".done", "")
+
+	s, err := New(Config[string]{Dir: dir, Decoder: lines, DoneSuffix: ".done", ProcessedDir: processed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := receive(t, extract(t, s), 3)
+	ctx := context.Background()
+
+	// a.txt keeps one unloaded record after the first batch
+	if err := s.Commit(ctx, records[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(a) {
+		t.Errorf("%s moved with a record still unloaded", a)
+	}
+
+	if err := s.Commit(ctx, records[1:]); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{a, b} {
+		if exists(path) || exists(path+".done") {
+			t.Errorf("%s or its marker left in place once loaded", path)
+		}
+		if !exists(filepath.Join(processed, filepath.Base(path))) {
+			t.Errorf("%s not moved to the processed directory", path)
+		}
+	}
+}
+
+func TestCommitRemembersLoadedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	writeFile(t, path, "1\n")
+	writeFile(t, path+".done", "")
+
+	s, err := New(Config[string]{Dir: dir, Decoder: lines, SettleTime: 20 * time.Millisecond, DoneSuffix: ".done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := extract(t, s)
+	records := receive(t, ch, 1)
+	if err := s.Commit(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+
+	// Touching the marker again does not reload the unchanged file
+	writeFile(t, path+".done", "")
+	expectNothing(t, ch, 200*time.Millisecond)
+	if !exists(path) {
+		t.Errorf("%s moved without a processed directory", path)
+	}
+}
@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/cuong/go-etl/pkg/convert"
+)
+
+// builtins are registered in every new Registry
+var builtins = map[string]DeriveFunc{
+	"slugify":            slugify,
+	"full_name":          fullName,
+	"geo_hash":           geoHash,
+	"age_from_birthdate": ageFromBirthdate,
+}
+
+// slugify(text) lowercases text, strips accents and joins words with "-"
+func slugify(args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("slugify: expected 1 argument, got %d", len(args))
+	}
+
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFKD.String(convert.String(args[0])) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop combining accents
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			dash = true
+		}
+	}
+	return b.String(), nil
+}
+
+// full_name(first, [middle...], last) joins the non-empty name parts
+func fullName(args ...any) (any, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("full_name: expected at least 1 argument")
+	}
+
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if s := strings.TrimSpace(convert.String(arg)); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+const geoHashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geo_hash(lat, lng, [precision]) encodes coordinates as a geohash
+// Precision defaults to 9 characters (~5m).
+func geoHash(args ...any) (any, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("geo_hash: expected 2 or 3 arguments, got %d", len(args))
+	}
+
+	lat, err := convert.Float64(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("geo_hash: latitude: %w", err)
+	}
+	lng, err := convert.Float64(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("geo_hash: longitude: %w", err)
+	}
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return nil, fmt.Errorf("geo_hash: coordinates out of range: %v, %v", lat, lng)
+	}
+
+	precision := 9
+	if len(args) == 3 {
+		p, err := convert.Float64(args[2])
+		if err != nil || p < 1 || p > 12 {
+			return nil, fmt.Errorf("geo_hash: precision must be between 1 and 12")
+		}
+		precision = int(p)
+	}
+
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	even := true
+	bit, ch := 0, 0
+
+	for len(hash) < precision {
+		// Bits alternate between longitude and latitude
+		rng, value := &latRange, lat
+		if even {
+			rng, value = &lngRange, lng
+		}
+
+		mid := (rng[0] + rng[1]) / 2
+		if value >= mid {
+			ch = ch<<1 | 1
+			rng[0] = mid
+		} else {
+			ch <<= 1
+			rng[1] = mid
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash = append(hash, geoHashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash), nil
+}
+
+// age_from_birthdate(birthdate, [asOf]) returns the age in whole years
+// Dates may be time.Time or strings in RFC 3339 or YYYY-MM-DD form; asOf
+// defaults to now.
+func ageFromBirthdate(args ...any) (any, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("age_from_birthdate: expected 1 or 2 arguments, got %d", len(args))
+	}
+
+	birth, err := convert.Time(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("age_from_birthdate: %w", err)
+	}
+	asOf := time.Now()
+	if len(args) == 2 {
+		if asOf, err = convert.Time(args[1]); err != nil {
+			return nil, fmt.Errorf("age_from_birthdate: %w", err)
+		}
+	}
+
+	age := asOf.Year() - birth.Year()
+	if asOf.Month() < birth.Month() || (asOf.Month() == birth.Month() && asOf.Day() < birth.Day()) {
+		age--
+	}
+	return age, nil
+}
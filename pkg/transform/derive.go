@@ -0,0 +1,117 @@
+// Package transform provides reusable record-level transformations for
+// config-driven pipelines
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Record is a schemaless row flowing through a config-driven pipeline
+type Record = map[string]any
+
+// DeriveFunc computes a derived value from its arguments
+type DeriveFunc func(args ...any) (any, error)
+
+// DerivedField describes a computed column in a mapping config
+// Example: {Target: "slug", Func: "slugify", Args: ["title"]}
+type DerivedField struct {
+	Target string   `json:"target" yaml:"target"` // Field to write
+	Func   string   `json:"func" yaml:"func"`     // Registered function name
+	Args   []string `json:"args" yaml:"args"`     // Fields passed as arguments, in order
+}
+
+// Registry maps names to derive functions
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[string]DeriveFunc
+}
+
+// NewRegistry creates a registry preloaded with the built-in functions
+func NewRegistry() *Registry {
+	r := &Registry{funcs: make(map[string]DeriveFunc)}
+	for name, fn := range builtins {
+		r.funcs[name] = fn
+	}
+	return r
+}
+
+// DefaultRegistry is used by the package-level helpers
+var DefaultRegistry = NewRegistry()
+
+// Register adds fn under name, failing if the name is taken
+func (r *Registry) Register(name string, fn DeriveFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.funcs[name]; exists {
+		return fmt.Errorf("derive function %q already registered", name)
+	}
+	r.funcs[name] = fn
+	return nil
+}
+
+// Lookup returns the function registered under name
+func (r *Registry) Lookup(name string) (DeriveFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fn, ok := r.funcs[name]
+	return fn, ok
+}
+
+// Names returns all registered function names, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Call invokes the named function
+func (r *Registry) Call(name string, args ...any) (any, error) {
+	fn, ok := r.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown derive function %q", name)
+	}
+	return fn(args...)
+}
+
+// Apply computes each derived field from rec and stores it in rec
+// Fields are applied in order, so later fields may use earlier results.
+func (r *Registry) Apply(rec Record, fields []DerivedField) error {
+	for _, field := range fields {
+		args := make([]any, len(field.Args))
+		for i, name := range field.Args {
+			args[i] = rec[name]
+		}
+
+		value, err := r.Call(field.Func, args...)
+		if err != nil {
+			return fmt.Errorf("derive %s: %w", field.Target, err)
+		}
+		rec[field.Target] = value
+	}
+	return nil
+}
+
+// Register adds fn to the default registry
+func Register(name string, fn DeriveFunc) error {
+	return DefaultRegistry.Register(name, fn)
+}
+
+// Call invokes a function from the default registry
+func Call(name string, args ...any) (any, error) {
+	return DefaultRegistry.Call(name, args...)
+}
+
+// Apply computes derived fields using the default registry
+func Apply(rec Record, fields []DerivedField) error {
+	return DefaultRegistry.Apply(rec, fields)
+}
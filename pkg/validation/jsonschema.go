@@ -0,0 +1,93 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// schemaURL names the compiled schema, which cannot reference others
+const schemaURL = "schema.json"
+
+// JSONSchema returns a validator checking the JSON encoding of records
+// against a JSON Schema (draft 4 to 2020-12, 2020-12 unless $schema says
+// otherwise)
+// The rule of a violation is the failing keyword, e.g. {Field: "email",
+// Rule: "format"}; formats are asserted.
+func JSONSchema[T any](schema []byte) (Validator[T], error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("validation: schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(schemaURL, doc); err != nil {
+		return nil, fmt.Errorf("validation: schema: %w", err)
+	}
+	sch, err := c.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("validation: schema: %w", err)
+	}
+
+	printer := message.NewPrinter(language.English)
+	return ValidatorFunc[T](func(record T) []Violation {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return []Violation{{Rule: "json", Message: err.Error()}}
+		}
+		inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			return []Violation{{Rule: "json", Message: err.Error()}}
+		}
+
+		err = sch.Validate(inst)
+		if err == nil {
+			return nil
+		}
+		var ve *jsonschema.ValidationError
+		if !errors.As(err, &ve) {
+			return []Violation{{Rule: "schema", Message: err.Error()}}
+		}
+		return schemaViolations(ve, printer, nil)
+	}), nil
+}
+
+// schemaViolations flattens the leaves of a validation error
+func schemaViolations(ve *jsonschema.ValidationError, p *message.Printer, out []Violation) []Violation {
+	if len(ve.Causes) > 0 {
+		for _, cause := range ve.Causes {
+			out = schemaViolations(cause, p, out)
+		}
+		return out
+	}
+
+	field := strings.Join(ve.InstanceLocation, ".")
+	if required, ok := ve.ErrorKind.(*kind.Required); ok {
+		// Report missing properties on the properties themselves
+		for _, name := range required.Missing {
+			out = append(out, Violation{Field: join(field, name), Rule: "required", Message: "missing property"})
+		}
+		return out
+	}
+
+	rule := "schema"
+	if path := ve.ErrorKind.KeywordPath(); len(path) > 0 {
+		rule = path[0]
+	}
+	return append(out, Violation{Field: field, Rule: rule, Message: ve.ErrorKind.LocalizedString(p)})
+}
+
+// join joins field paths
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,62 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	playground "github.com/go-playground/validator/v10"
+)
+
+var (
+	structOnce     sync.Once
+	structValidate *playground.Validate
+)
+
+// Struct returns a validator checking the `validate` tags of struct
+// records, see github.com/go-playground/validator
+// Fields are named after their json tag, so violations read like the
+// loaded records, e.g. {Field: "address.zip", Rule: "len"}.
+func Struct[T any]() Validator[T] {
+	structOnce.Do(func() {
+		structValidate = playground.New(playground.WithRequiredStructEnabled())
+		structValidate.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			switch name {
+			case "-":
+				return ""
+			case "":
+				return f.Name
+			}
+			return name
+		})
+	})
+
+	return ValidatorFunc[T](func(record T) []Violation {
+		err := structValidate.Struct(record)
+		if err == nil {
+			return nil
+		}
+		var errs playground.ValidationErrors
+		if !errors.As(err, &errs) {
+			return []Violation{{Rule: "struct", Message: err.Error()}}
+		}
+
+		violations := make([]Violation, len(errs))
+		for i, fe := range errs {
+			// Drop the struct name heading the namespace
+			_, field, _ := strings.Cut(fe.Namespace(), ".")
+			violations[i] = Violation{Field: field, Rule: fe.Tag(), Message: tagMessage(fe)}
+		}
+		return violations
+	})
+}
+
+// tagMessage describes a failed tag
+func tagMessage(fe playground.FieldError) string {
+	if fe.Param() == "" {
+		return "failed " + fe.Tag()
+	}
+	return "failed " + fe.Tag() + "=" + fe.Param()
+}
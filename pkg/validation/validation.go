@@ -0,0 +1,221 @@
+// Package validation checks transformed records against struct validation
+// tags or a JSON Schema before they are loaded, failing the run, skipping
+// or quarantining the records that violate them
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cuong/go-etl/pkg/dlq"
+	"github.com/cuong/go-etl/pkg/etl"
+)
+
+// Violation is a rule a record broke
+type Violation struct {
+	Field   string // Dotted path of the field, empty for the whole record
+	Rule    string // Violated constraint, e.g. "required", "max" or "format"
+	Message string
+}
+
+// Key identifies the rule in counters: "field:rule", or the rule alone for
+// the whole record
+func (v Violation) Key() string {
+	if v.Field == "" {
+		return v.Rule
+	}
+	return v.Field + ":" + v.Rule
+}
+
+func (v Violation) String() string {
+	if v.Field == "" {
+		return v.Message
+	}
+	return v.Field + ": " + v.Message
+}
+
+// Validator checks records
+type Validator[T any] interface {
+	// Validate returns the violations of record, none if it is valid
+	Validate(record T) []Violation
+}
+
+// ValidatorFunc adapts a function to the Validator interface
+type ValidatorFunc[T any] func(record T) []Violation
+
+// Validate calls f
+func (f ValidatorFunc[T]) Validate(record T) []Violation {
+	return f(record)
+}
+
+// Policy decides what invalid records do to a run
+type Policy int
+
+const (
+	// Fail stops the run with an *Error at the first invalid record; the
+	// batch holding it is not loaded
+	Fail Policy = iota
+
+	// Skip drops invalid records and loads the rest of their batch
+	Skip
+
+	// Quarantine stores invalid records in Config.Quarantine with their
+	// violations, then drops them as Skip does
+	Quarantine
+)
+
+// Config configures validation
+type Config[T any] struct {
+	Validator Validator[T]
+	Policy    Policy
+
+	// Quarantine keeps the invalid records under the Quarantine policy, as
+	// entries of the pipeline Name. Entries hold transformed records, which
+	// cannot be replayed through the pipeline: use a store other than its
+	// dead letter store.
+	Quarantine dlq.Store
+	Name       string
+}
+
+// Error reports an invalid record under the Fail policy
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return "validation: invalid record: " + strings.Join(msgs, "; ")
+}
+
+// Wrap validates the transformed records of processor before they are
+// loaded
+// Every violation of a rejected record is counted against its rule with
+// etl.RejectRecords, so the run report shows them per rule. Records
+// dropped by Skip and Quarantine are still committed with their batch.
+//
+// The optional interfaces of processor, such as BatchCommitter and Resumer,
+// are kept (see etl.Wrapper).
+func Wrap[E, T any](processor etl.ETLProcessor[E, T], cfg Config[T]) (etl.ETLProcessor[E, T], error) {
+	if cfg.Validator == nil {
+		return nil, fmt.Errorf("validation: Validator is required")
+	}
+	if cfg.Policy == Quarantine && (cfg.Quarantine == nil || cfg.Name == "") {
+		return nil, fmt.Errorf("validation: the Quarantine policy requires Quarantine and Name")
+	}
+	v := &validator[E, T]{processor: processor, cfg: cfg}
+	return v, nil
+}
+
+// validator implements Wrap
+type validator[E, T any] struct {
+	processor etl.ETLProcessor[E, T]
+	cfg       Config[T]
+}
+
+func (v *validator[E, T]) PreProcess(ctx context.Context) error {
+	return v.processor.PreProcess(ctx)
+}
+
+func (v *validator[E, T]) Extract(ctx context.Context) (<-chan etl.Payload[E], error) {
+	return v.processor.Extract(ctx)
+}
+
+func (v *validator[E, T]) Transform(ctx context.Context, e E) T {
+	return v.processor.Transform(ctx, e)
+}
+
+// Load validates data, then loads the valid records
+func (v *validator[E, T]) Load(ctx context.Context, data []T) error {
+	valid := data[:0:0]
+	var invalid []T
+	var violations [][]Violation
+	for i, record := range data {
+		vs := v.cfg.Validator.Validate(record)
+		if len(vs) == 0 {
+			if invalid != nil {
+				valid = append(valid, record)
+			}
+			continue
+		}
+		if v.cfg.Policy == Fail {
+			reject(ctx, vs)
+			return etl.Permanent(&Error{Violations: vs})
+		}
+		if invalid == nil {
+			valid = append(valid, data[:i]...)
+		}
+		invalid = append(invalid, record)
+		violations = append(violations, vs)
+	}
+	if invalid == nil {
+		return v.processor.Load(ctx, data)
+	}
+
+	if v.cfg.Policy == Quarantine {
+		if err := v.quarantine(ctx, invalid, violations); err != nil {
+			return err
+		}
+	}
+	for _, vs := range violations {
+		reject(ctx, vs)
+	}
+	etl.LoggerFromContext(ctx).Warn("Dropped invalid records", "records", len(invalid), "first", violations[0][0].String())
+
+	if len(valid) == 0 {
+		return nil
+	}
+	return v.processor.Load(ctx, valid)
+}
+
+// quarantine stores invalid records with their violations
+func (v *validator[E, T]) quarantine(ctx context.Context, records []T, violations [][]Violation) error {
+	now := time.Now()
+	entries := make([]*dlq.Entry, len(records))
+	for i, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("validation: encode quarantined record: %w", err)
+		}
+		entries[i] = &dlq.Entry{
+			ID:        uuid.NewString(),
+			Pipeline:  v.cfg.Name,
+			Record:    data,
+			Error:     (&Error{Violations: violations[i]}).Error(),
+			CreatedAt: now,
+		}
+	}
+	if err := v.cfg.Quarantine.Add(ctx, entries); err != nil {
+		return fmt.Errorf("validation: quarantine: %w", err)
+	}
+	return nil
+}
+
+func (v *validator[E, T]) PostProcess(ctx context.Context) error {
+	return v.processor.PostProcess(ctx)
+}
+
+// reject counts a rejected record against the rules it violated
+func reject(ctx context.Context, violations []Violation) {
+	rules := make([]string, 0, len(violations))
+	for _, v := range violations {
+		if !slices.Contains(rules, v.Key()) {
+			rules = append(rules, v.Key())
+		}
+	}
+	etl.RejectRecords(ctx, 1, rules...)
+}
+
+// Unwrap returns the wrapped processor, whose optional interfaces are kept
+// (see etl.Wrapper)
+func (v *validator[E, T]) Unwrap() any {
+	return v.processor
+}